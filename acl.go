@@ -0,0 +1,138 @@
+package dns
+
+import (
+	"net"
+	"strings"
+)
+
+// An ACLAction is what an ACL does with a query.
+type ACLAction int
+
+// ACL actions.
+const (
+	ACLAllow  ACLAction = iota // pass the query to the handler
+	ACLDeny                    // drop the query, without response
+	ACLRefuse                  // answer the query with an error, REFUSED unless the rule sets another rcode
+)
+
+// An ACLRule applies Action to the queries from clients in one of Prefixes.
+type ACLRule struct {
+	Prefixes []*net.IPNet
+	Action   ACLAction
+	Rcode    int // rcode of the response with ACLRefuse, defaults to RcodeRefused
+}
+
+// An ACL restricts access to a handler by the address of the client. The
+// first rule whose prefixes hold the address applies, and Default when none
+// does.
+//
+// Zone transfers (AXFR and IXFR queries) and dynamic updates are usually only
+// allowed from a few hosts, Transfer and Update can hold a separate ACL for
+// them. It is checked instead of the rules of this one.
+//
+// An ACL is installed with ACLMiddleware, for a single handler in a ServeMux,
+// or for a whole server by wrapping its Handler.
+type ACL struct {
+	Rules   []ACLRule
+	Default ACLAction // action when no rule applies, allow by default
+	Rcode   int       // rcode of the response when Default is ACLRefuse, defaults to RcodeRefused
+
+	Transfer *ACL // if set, the ACL for AXFR and IXFR queries
+	Update   *ACL // if set, the ACL for UPDATE messages
+}
+
+// ParseACLPrefixes parses the prefixes in CIDR notation, e.g. "192.0.2.0/24",
+// or single addresses, for an ACLRule.
+func ParseACLPrefixes(prefixes ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(prefixes))
+	for _, p := range prefixes {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, &Error{err: "bad ACL address: " + p}
+			}
+			bits := 128
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, &Error{err: "bad ACL prefix: " + p}
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// Check returns the action to take for the query r from the client at ip, and
+// the rcode of the response for ACLRefuse.
+func (acl *ACL) Check(ip net.IP, r *Msg) (ACLAction, int) {
+	switch {
+	case acl.Transfer != nil && r.Opcode == OpcodeQuery && len(r.Question) > 0 &&
+		(r.Question[0].Qtype == TypeAXFR || r.Question[0].Qtype == TypeIXFR):
+		return acl.Transfer.check(ip)
+	case acl.Update != nil && r.Opcode == OpcodeUpdate:
+		return acl.Update.check(ip)
+	}
+	return acl.check(ip)
+}
+
+// check applies the rules of acl to ip.
+func (acl *ACL) check(ip net.IP) (ACLAction, int) {
+	for _, rule := range acl.Rules {
+		for _, n := range rule.Prefixes {
+			if n.Contains(ip) {
+				return rule.Action, aclRcode(rule.Rcode)
+			}
+		}
+	}
+	return acl.Default, aclRcode(acl.Rcode)
+}
+
+func aclRcode(rcode int) int {
+	if rcode == 0 {
+		return RcodeRefused
+	}
+	return rcode
+}
+
+// ACLMiddleware returns a Middleware that restricts the queries that reach the
+// handler it wraps with acl. Queries from clients whose address is unknown are
+// checked as if no rule applies.
+func ACLMiddleware(acl *ACL) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Msg) {
+			action, rcode := acl.Check(addrIP(w.RemoteAddr()), r)
+			switch action {
+			case ACLDeny:
+				return
+			case ACLRefuse:
+				m := new(Msg)
+				m.SetRcode(r, rcode)
+				w.WriteMsg(m)
+				return
+			}
+			next.ServeDNS(w, r)
+		})
+	}
+}
+
+// addrIP returns the IP address of addr, or nil.
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	case nil:
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	return net.ParseIP(host)
+}
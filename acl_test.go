@@ -0,0 +1,97 @@
+package dns
+
+import (
+	"net"
+	"testing"
+)
+
+func mustACLPrefixes(t *testing.T, prefixes ...string) []*net.IPNet {
+	nets, err := ParseACLPrefixes(prefixes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nets
+}
+
+func TestParseACLPrefixes(t *testing.T) {
+	nets := mustACLPrefixes(t, "192.0.2.0/24", "2001:db8::/32", "198.51.100.7", "2001:db8:1::1")
+	for i, want := range []string{"192.0.2.0/24", "2001:db8::/32", "198.51.100.7/32", "2001:db8:1::1/128"} {
+		if nets[i].String() != want {
+			t.Errorf("expected prefix %s, got %s", want, nets[i])
+		}
+	}
+	for _, p := range []string{"192.0.2.0/33", "example.org", ""} {
+		if _, err := ParseACLPrefixes(p); err == nil {
+			t.Errorf("expected error for %q", p)
+		}
+	}
+}
+
+func TestACLCheck(t *testing.T) {
+	acl := &ACL{
+		Rules: []ACLRule{
+			{Prefixes: mustACLPrefixes(t, "192.0.2.1"), Action: ACLDeny},
+			{Prefixes: mustACLPrefixes(t, "192.0.2.0/24", "2001:db8::/32"), Action: ACLAllow},
+			{Prefixes: mustACLPrefixes(t, "198.51.100.0/24"), Action: ACLRefuse, Rcode: RcodeNotAuth},
+		},
+		Default:  ACLRefuse,
+		Transfer: &ACL{Rules: []ACLRule{{Prefixes: mustACLPrefixes(t, "192.0.2.53"), Action: ACLAllow}}, Default: ACLDeny},
+		Update:   &ACL{Default: ACLRefuse},
+	}
+	query := new(Msg).SetQuestion("example.org.", TypeA)
+	axfr := new(Msg).SetAxfr("example.org.")
+	update := new(Msg).SetUpdate("example.org.")
+
+	for _, tc := range []struct {
+		ip     string
+		m      *Msg
+		action ACLAction
+		rcode  int
+	}{
+		{"192.0.2.1", query, ACLDeny, RcodeRefused},
+		{"192.0.2.2", query, ACLAllow, RcodeRefused},
+		{"::ffff:192.0.2.2", query, ACLAllow, RcodeRefused},
+		{"2001:db8::1", query, ACLAllow, RcodeRefused},
+		{"198.51.100.1", query, ACLRefuse, RcodeNotAuth},
+		{"203.0.113.1", query, ACLRefuse, RcodeRefused},
+		{"", query, ACLRefuse, RcodeRefused},
+		{"192.0.2.2", axfr, ACLDeny, RcodeRefused},
+		{"192.0.2.53", axfr, ACLAllow, RcodeRefused},
+		{"192.0.2.2", update, ACLRefuse, RcodeRefused},
+	} {
+		action, rcode := acl.Check(net.ParseIP(tc.ip), tc.m)
+		if action != tc.action || rcode != tc.rcode {
+			t.Errorf("%s %s: expected action %d and rcode %d, got %d and %d",
+				tc.ip, OpcodeToString[tc.m.Opcode], tc.action, tc.rcode, action, rcode)
+		}
+	}
+}
+
+func TestACLMiddleware(t *testing.T) {
+	// recordWriter queries come from 192.0.2.1.
+	handler := HandlerFunc(func(w ResponseWriter, r *Msg) {
+		m := new(Msg)
+		m.SetReply(r)
+		w.WriteMsg(m)
+	})
+	m := new(Msg).SetQuestion("miek.nl.", TypeTXT)
+
+	for _, tc := range []struct {
+		acl   *ACL
+		rcode int // -1 for no response
+	}{
+		{&ACL{}, RcodeSuccess},
+		{&ACL{Rules: []ACLRule{{Prefixes: mustACLPrefixes(t, "192.0.2.0/24"), Action: ACLDeny}}}, -1},
+		{&ACL{Default: ACLRefuse}, RcodeRefused},
+		{&ACL{Default: ACLRefuse, Rcode: RcodeServerFailure}, RcodeServerFailure},
+	} {
+		w := new(recordWriter)
+		Chain(handler, ACLMiddleware(tc.acl)).ServeDNS(w, m)
+		switch {
+		case tc.rcode == -1 && len(w.msgs) != 0:
+			t.Errorf("expected no response, got %v", w.msgs)
+		case tc.rcode != -1 && (len(w.msgs) != 1 || w.msgs[0].Rcode != tc.rcode):
+			t.Errorf("expected response with rcode %d, got %v", tc.rcode, w.msgs)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package dns
+
+import (
+	"strings"
+	"time"
+)
+
+// An ExchangeResult is the outcome of a query sent with ExchangeAsync.
+type ExchangeResult struct {
+	Msg *Msg          // response, as returned by Exchange
+	Rtt time.Duration // round trip time
+	Err error         // error, as returned by Exchange
+}
+
+// ExchangeAsync is like Exchange, but returns at once: the result is sent on
+// the returned channel when the query is done, after which the channel is
+// closed. m must not be modified until then.
+//
+// With Coalesce set, forwarders can send the queries of their clients with
+// ExchangeAsync as they come: the identical ones in flight are sent upstream
+// only once.
+func (c *Client) ExchangeAsync(m *Msg, address string) <-chan ExchangeResult {
+	ch := make(chan ExchangeResult, 1)
+	go func() {
+		r, rtt, err := c.Exchange(m, address)
+		ch <- ExchangeResult{Msg: r, Rtt: rtt, Err: err}
+		close(ch)
+	}()
+	return ch
+}
+
+// exchangeCoalesced performs a single try of a query, shared with the tries
+// in flight of identical queries: with the same question, flags and EDNS0
+// options, to the same address. The response is copied for every query, with
+// its ID.
+func (c *Client) exchangeCoalesced(m *Msg, address string, at attempt) (r *Msg, rtt time.Duration, err error) {
+	key, ok := coalesceKey(m)
+	if !ok {
+		return c.exchange(m, address, at)
+	}
+	var b strings.Builder
+	b.WriteString(key)
+	b.WriteString(address)
+	b.WriteString("|" + at.net)
+	if at.src != nil {
+		b.WriteString("|" + at.src.Addr.String() + "%" + at.src.Device)
+	}
+	r, rtt, err, shared := c.group.Do(b.String(), func() (*Msg, time.Duration, error) {
+		return c.exchange(m, address, at)
+	})
+	if r != nil && shared {
+		r = r.Copy()
+		r.Id = m.Id
+	}
+	return r, rtt, err
+}
+
+// coalesceKey returns the wire format of m without its ID, and whether m can
+// be coalesced with identical queries: signed queries can't.
+func coalesceKey(m *Msg) (string, bool) {
+	if m.IsTsig() != nil {
+		return "", false
+	}
+	mm := *m
+	mm.Id = 0
+	buf, err := mm.Pack()
+	if err != nil {
+		return "", false
+	}
+	return string(buf), true
+}
@@ -0,0 +1,57 @@
+package dns
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExchangeAsyncCoalesce(t *testing.T) {
+	var queries int32
+	HandleFunc("async.example.", func(w ResponseWriter, req *Msg) {
+		atomic.AddInt32(&queries, 1)
+		time.Sleep(100 * time.Millisecond)
+		HelloServer(w, req)
+	})
+	defer HandleRemove("async.example.")
+
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	c := &Client{Coalesce: true}
+	var results []<-chan ExchangeResult
+	var ids []uint16
+	for i := 0; i < 10; i++ {
+		m := new(Msg)
+		m.SetQuestion("async.example.", TypeTXT)
+		results = append(results, c.ExchangeAsync(m, addrstr))
+		ids = append(ids, m.Id)
+	}
+	// A query with other EDNS0 options isn't coalesced with them.
+	m := new(Msg)
+	m.SetQuestion("async.example.", TypeTXT)
+	m.SetEdns0(4096, true)
+	other := c.ExchangeAsync(m, addrstr)
+
+	for i, ch := range results {
+		res := <-ch
+		if res.Err != nil {
+			t.Fatalf("failed to exchange: %v", res.Err)
+		}
+		if res.Msg.Id != ids[i] || len(res.Msg.Extra) == 0 {
+			t.Errorf("expected a response with ID %d, got %v", ids[i], res.Msg)
+		}
+		if _, ok := <-ch; ok {
+			t.Error("expected the channel to be closed")
+		}
+	}
+	if res := <-other; res.Err != nil || res.Msg.Id != m.Id {
+		t.Errorf("unexpected result %v", res)
+	}
+	if n := atomic.LoadInt32(&queries); n != 2 {
+		t.Errorf("expected 2 queries upstream, got %d", n)
+	}
+}
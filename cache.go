@@ -0,0 +1,355 @@
+package dns
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An Exchanger sends the query m to the server at address and returns its
+// response. It is implemented by Client and Cache.
+type Exchanger interface {
+	Exchange(m *Msg, address string) (r *Msg, rtt time.Duration, err error)
+}
+
+// A Cache is an Exchanger that caches the responses of another Exchanger. The
+// responses are cached per query name, type and class, and the DO and CD bits
+// of the query, regardless of the server they came from, for the lowest TTL of their records. Negative
+// responses, NXDOMAIN and NODATA, are cached for the TTL of the SOA record in
+// their authority section, or its MINIMUM when that is lower (RFC 2308). When
+// the Cache is full the least recently used response is evicted.
+//
+// When StaleTTL is set, responses are kept that long after they expired, and
+// are served stale when the Exchanger fails to refresh them (RFC 8767), with
+// a TTL of 30 seconds and an extended DNS error "Stale Answer" if the query
+// has EDNS0. When StaleClientTimeout is set too, the stale response is also
+// served when the refresh takes longer than that, and the refresh continues in
+// the background.
+//
+// When AggressiveNSEC is set, the NSEC and NSEC3 records of negative responses
+// that were validated by the upstream resolver, that have the AD bit set, are
+// kept, and queries for names or types they prove don't exist are answered
+// from them (RFC 8198).
+//
+// A Cache is safe for concurrent use. Its fields should not be changed once
+// it is used.
+type Cache struct {
+	Exchanger      Exchanger     // where the queries not in the cache are sent
+	MaxEntries     int           // maximum number of cached responses, defaults to 10000
+	MaxTTL         time.Duration // maximum time a response is cached, defaults to 1 day
+	MaxNegativeTTL time.Duration // maximum time a negative response is cached, defaults to 3 hours
+
+	StaleTTL           time.Duration // time expired responses are kept to be served stale, 0 disables serving stale
+	StaleClientTimeout time.Duration // if non-zero, wait this long for a refresh before serving stale
+
+	AggressiveNSEC     bool   // if true, synthesize negative responses from cached NSEC and NSEC3 records
+	NSEC3MaxIterations uint16 // responses with NSEC3 records of more iterations are insecure, and not used by AggressiveNSEC, defaults to DefaultNSEC3MaxIterations
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	lru     list.List // of *cacheEntry, most recently used first
+	stats   CacheStats
+	now     func() time.Time // for testing
+
+	nsec      map[string]*nsecZone // by zone name, lower cased
+	nsecCount int                  // number of NSEC and NSEC3 records in nsec
+}
+
+// CacheStats are the statistics of a Cache.
+type CacheStats struct {
+	Hits        uint64 // queries answered from the cache
+	Misses      uint64 // queries not answered from cached responses
+	Evictions   uint64 // responses removed from the cache before they expired
+	Stale       uint64 // expired responses served
+	Synthesized uint64 // negative responses synthesized from NSEC and NSEC3 records
+	Entries     int    // responses in the cache
+}
+
+type cacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+	do     bool // the response has DNSSEC records
+	cd     bool // the response wasn't validated
+}
+
+type cacheEntry struct {
+	key        cacheKey
+	msg        *Msg
+	stored     time.Time
+	expires    time.Time
+	refreshing bool // an expired response is being refreshed
+}
+
+// staleTTL is the TTL of the records in stale responses (RFC 8767, Section 4).
+const staleTTL = 30
+
+// NewCache returns a Cache that sends the queries it can't answer to ex.
+func NewCache(ex Exchanger) *Cache {
+	return &Cache{Exchanger: ex}
+}
+
+func (c *Cache) maxEntries() int {
+	if c.MaxEntries > 0 {
+		return c.MaxEntries
+	}
+	return 10000
+}
+
+func (c *Cache) maxTTL() time.Duration {
+	if c.MaxTTL > 0 {
+		return c.MaxTTL
+	}
+	return 24 * time.Hour
+}
+
+func (c *Cache) maxNegativeTTL() time.Duration {
+	if c.MaxNegativeTTL > 0 {
+		return c.MaxNegativeTTL
+	}
+	return 3 * time.Hour
+}
+
+func (c *Cache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// Exchange returns the cached response to m, with its TTLs decreased by the
+// time it spent in the cache, and a zero rtt. Otherwise it sends m to address
+// with the Exchanger of c, and caches the response. Queries with more than one
+// question, or with TSIG, are never answered from the cache.
+func (c *Cache) Exchange(m *Msg, address string) (r *Msg, rtt time.Duration, err error) {
+	if len(m.Question) != 1 || m.IsTsig() != nil {
+		return c.Exchanger.Exchange(m, address)
+	}
+	q := m.Question[0]
+	key := cacheKey{strings.ToLower(q.Name), q.Qtype, q.Qclass, m.Do(), m.CheckingDisabled}
+
+	r, stale, refresh := c.get(key, m)
+	if r != nil {
+		return r, 0, nil
+	}
+	if stale == nil {
+		if c.AggressiveNSEC {
+			if r := c.synthesize(m); r != nil {
+				c.mu.Lock()
+				c.stats.Synthesized++
+				c.mu.Unlock()
+				return r, 0, nil
+			}
+		}
+		r, rtt, err = c.Exchanger.Exchange(m, address)
+		if err == nil {
+			c.set(key, r)
+		}
+		return r, rtt, err
+	}
+	if !refresh {
+		// Another query is refreshing the response already.
+		return c.stale(stale, m), 0, nil
+	}
+
+	type result struct {
+		r   *Msg
+		rtt time.Duration
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		// m is copied, the refresh can outlive this call.
+		r, rtt, err := c.Exchanger.Exchange(m.Copy(), address)
+		if err == nil {
+			c.set(key, r)
+		}
+		c.mu.Lock()
+		stale.refreshing = false
+		c.mu.Unlock()
+		done <- result{r, rtt, err}
+	}()
+
+	var timeout <-chan time.Time
+	if c.StaleClientTimeout > 0 {
+		t := time.NewTimer(c.StaleClientTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case res := <-done:
+		if res.err == nil && res.r.Rcode != RcodeServerFailure {
+			return res.r, res.rtt, nil
+		}
+	case <-timeout:
+	}
+	return c.stale(stale, m), 0, nil
+}
+
+// Stats returns the statistics of c.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.stats
+	st.Entries = len(c.entries)
+	return st
+}
+
+// Flush removes all responses from c.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	c.entries = nil
+	c.lru.Init()
+	c.nsec, c.nsecCount = nil, 0
+	c.mu.Unlock()
+}
+
+// get returns the cached response for key as a response to m. If it expired
+// but can be served stale, get returns its entry instead, and whether the
+// caller should refresh it.
+func (c *Cache) get(key cacheKey, m *Msg) (r *Msg, stale *cacheEntry, refresh bool) {
+	now := c.clock()
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok {
+		ce := e.Value.(*cacheEntry)
+		switch {
+		case now.Before(ce.expires):
+		case now.Before(ce.expires.Add(c.StaleTTL)):
+			c.stats.Misses++
+			refresh = !ce.refreshing
+			ce.refreshing = true
+			c.mu.Unlock()
+			return nil, ce, refresh
+		default:
+			c.remove(e)
+			ok = false
+		}
+	}
+	if !ok {
+		c.stats.Misses++
+		c.mu.Unlock()
+		return nil, nil, false
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(e)
+	ce := e.Value.(*cacheEntry)
+	c.mu.Unlock()
+
+	r = ce.msg.Copy()
+	r.Id = m.Id
+	r.Question = []Question{m.Question[0]}
+	for _, s := range [][]RR{r.Answer, r.Ns, r.Extra} {
+		DecrementTTL(s, now.Sub(ce.stored))
+	}
+	return r, nil, false
+}
+
+// stale returns the expired response of ce as a response to m.
+func (c *Cache) stale(ce *cacheEntry, m *Msg) *Msg {
+	c.mu.Lock()
+	c.stats.Stale++
+	c.mu.Unlock()
+
+	r := ce.msg.Copy()
+	r.Id = m.Id
+	r.Question = []Question{m.Question[0]}
+	for _, s := range [][]RR{r.Answer, r.Ns, r.Extra} {
+		for _, rr := range s {
+			if h := rr.Header(); h.Rrtype != TypeOPT {
+				h.Ttl = staleTTL
+			}
+		}
+	}
+
+	if qopt := m.IsEdns0(); qopt != nil {
+		opt := r.IsEdns0()
+		if opt == nil {
+			opt = &OPT{Hdr: RR_Header{Name: ".", Rrtype: TypeOPT, Class: qopt.UDPSize()}}
+			r.Extra = append(r.Extra, opt)
+		}
+		opt.Option = append(opt.Option, &EDNS0_EDE{InfoCode: ExtendedErrorCodeStaleAnswer})
+	}
+	return r
+}
+
+// set caches r for key, if it can be cached.
+func (c *Cache) set(key cacheKey, r *Msg) {
+	if c.AggressiveNSEC {
+		c.storeNSEC(r)
+	}
+	ttl, ok := c.ttl(r)
+	if !ok || ttl <= 0 {
+		return
+	}
+	// The records are not served for longer than the response is cached.
+	r = r.Copy()
+	for _, s := range [][]RR{r.Answer, r.Ns, r.Extra} {
+		for _, rr := range s {
+			if h := rr.Header(); h.Rrtype != TypeOPT && time.Duration(h.Ttl)*time.Second > ttl {
+				h.Ttl = uint32(ttl / time.Second)
+			}
+		}
+	}
+	now := c.clock()
+	ce := &cacheEntry{key: key, msg: r, stored: now, expires: now.Add(ttl)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[cacheKey]*list.Element)
+	}
+	if e, ok := c.entries[key]; ok {
+		c.lru.Remove(e)
+	}
+	c.entries[key] = c.lru.PushFront(ce)
+	for len(c.entries) > c.maxEntries() {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove removes e from c, c.mu must be held.
+func (c *Cache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*cacheEntry).key)
+}
+
+// ttl returns the time r can be cached for, or false if it can't be cached.
+func (c *Cache) ttl(r *Msg) (time.Duration, bool) {
+	if r.Truncated || (r.Rcode != RcodeSuccess && r.Rcode != RcodeNameError) {
+		return 0, false
+	}
+
+	if r.Rcode == RcodeNameError || len(r.Answer) == 0 {
+		for _, rr := range r.Ns {
+			if soa, ok := rr.(*SOA); ok {
+				ttl := soa.Hdr.Ttl
+				if soa.Minttl < ttl {
+					ttl = soa.Minttl
+				}
+				return minDuration(time.Duration(ttl)*time.Second, c.maxNegativeTTL()), true
+			}
+		}
+		return 0, false
+	}
+
+	ttl := ^uint32(0)
+	for _, s := range [][]RR{r.Answer, r.Ns, r.Extra} {
+		for _, rr := range s {
+			if h := rr.Header(); h.Rrtype != TypeOPT && h.Ttl < ttl {
+				ttl = h.Ttl
+			}
+		}
+	}
+	return minDuration(time.Duration(ttl)*time.Second, c.maxTTL()), true
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
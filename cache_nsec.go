@@ -0,0 +1,310 @@
+package dns
+
+import (
+	"strings"
+	"time"
+)
+
+// nsecZone holds the NSEC and NSEC3 records of a zone for aggressive negative
+// caching (RFC 8198).
+type nsecZone struct {
+	soa        *SOA
+	soaSigs    []RR
+	soaExpires time.Time
+	nsec       map[string]*nsecEntry // by owner name, lower cased
+}
+
+type nsecEntry struct {
+	rr      RR // *NSEC or *NSEC3
+	sigs    []RR
+	expires time.Time
+}
+
+// storeNSEC stores the NSEC and NSEC3 records of the negative response r, if
+// it was validated.
+func (c *Cache) storeNSEC(r *Msg) {
+	if !r.AuthenticatedData || (r.Rcode != RcodeNameError && (r.Rcode != RcodeSuccess || len(r.Answer) > 0)) {
+		return
+	}
+	var soa *SOA
+	for _, rr := range r.Ns {
+		if s, ok := rr.(*SOA); ok {
+			soa = s
+			break
+		}
+	}
+	if soa == nil {
+		return
+	}
+	for _, rr := range r.Ns {
+		if nsec3, ok := rr.(*NSEC3); ok && NSEC3Insecure(nsec3, c.NSEC3MaxIterations) {
+			return
+		}
+	}
+	zone := strings.ToLower(soa.Hdr.Name)
+	ttl := soa.Hdr.Ttl
+	if soa.Minttl < ttl {
+		ttl = soa.Minttl
+	}
+	maxTTL := minDuration(time.Duration(ttl)*time.Second, c.maxNegativeTTL())
+	now := c.clock()
+
+	sigs := func(name string, t uint16) (s []RR) {
+		for _, rr := range r.Ns {
+			if sig, ok := rr.(*RRSIG); ok && sig.TypeCovered == t && strings.EqualFold(sig.Hdr.Name, name) {
+				s = append(s, sig.copy())
+			}
+		}
+		return s
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nsec == nil {
+		c.nsec = make(map[string]*nsecZone)
+	}
+	z, ok := c.nsec[zone]
+	if !ok {
+		z = &nsecZone{nsec: make(map[string]*nsecEntry)}
+		c.nsec[zone] = z
+	}
+	z.soa = soa.copy().(*SOA)
+	z.soaSigs = sigs(soa.Hdr.Name, TypeSOA)
+	z.soaExpires = now.Add(maxTTL)
+
+	for _, rr := range r.Ns {
+		h := rr.Header()
+		if (h.Rrtype != TypeNSEC && h.Rrtype != TypeNSEC3) || !IsSubDomain(zone, h.Name) {
+			continue
+		}
+		owner := strings.ToLower(h.Name)
+		if _, ok := z.nsec[owner]; !ok {
+			c.nsecCount++
+		}
+		expires := now.Add(minDuration(time.Duration(h.Ttl)*time.Second, maxTTL))
+		z.nsec[owner] = &nsecEntry{rr: rr.copy(), sigs: sigs(h.Name, h.Rrtype), expires: expires}
+	}
+
+	if c.nsecCount <= c.maxEntries() {
+		return
+	}
+	for name, z := range c.nsec {
+		for owner, e := range z.nsec {
+			if !now.Before(e.expires) {
+				delete(z.nsec, owner)
+				c.nsecCount--
+			}
+		}
+		if len(z.nsec) == 0 {
+			delete(c.nsec, name)
+		}
+	}
+	for name, z := range c.nsec {
+		if c.nsecCount <= c.maxEntries() {
+			break
+		}
+		c.nsecCount -= len(z.nsec)
+		delete(c.nsec, name)
+	}
+}
+
+// synthesize returns a negative response to m, from the NSEC or NSEC3 records
+// that prove the query name or type doesn't exist, or nil.
+func (c *Cache) synthesize(m *Msg) *Msg {
+	q := m.Question[0]
+	name := strings.ToLower(q.Name)
+	now := c.clock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	z := c.nsec["."]
+	for off, end := 0, false; !end; off, end = NextLabel(name, off) {
+		if zz, ok := c.nsec[name[off:]]; ok {
+			z = zz
+			break
+		}
+	}
+	if z == nil || !now.Before(z.soaExpires) {
+		return nil
+	}
+
+	var nsec, nsec3 []*nsecEntry
+	for _, e := range z.nsec {
+		if !now.Before(e.expires) {
+			continue
+		}
+		switch e.rr.(type) {
+		case *NSEC:
+			nsec = append(nsec, e)
+		case *NSEC3:
+			nsec3 = append(nsec3, e)
+		}
+	}
+
+	rcode, proof := nsecProof(nsec, name, q.Qtype)
+	if proof == nil {
+		rcode, proof = nsec3Proof(nsec3, name, z.soa.Hdr.Name, q.Qtype)
+	}
+	if proof == nil {
+		return nil
+	}
+
+	r := new(Msg)
+	r.SetReply(m)
+	r.Rcode = rcode
+	r.AuthenticatedData = true
+	r.RecursionAvailable = true
+
+	remaining := func(rr RR, expires time.Time) RR {
+		rr = rr.copy()
+		rr.Header().Ttl = uint32(expires.Sub(now) / time.Second)
+		return rr
+	}
+	r.Ns = append(r.Ns, remaining(z.soa, z.soaExpires))
+
+	qopt := m.IsEdns0()
+	if qopt != nil && qopt.Do() {
+		for _, sig := range z.soaSigs {
+			r.Ns = append(r.Ns, remaining(sig, z.soaExpires))
+		}
+		for _, e := range proof {
+			r.Ns = append(r.Ns, remaining(e.rr, e.expires))
+			for _, sig := range e.sigs {
+				r.Ns = append(r.Ns, remaining(sig, e.expires))
+			}
+		}
+	}
+	if qopt != nil {
+		r.SetEdns0(qopt.UDPSize(), qopt.Do())
+	}
+	return r
+}
+
+// nsecProof returns the NSEC records that prove that name or the type qtype
+// at name don't exist, and the rcode of the response.
+func nsecProof(nsec []*nsecEntry, name string, qtype uint16) (int, []*nsecEntry) {
+	for _, e := range nsec {
+		rr := e.rr.(*NSEC)
+		if rr.Match(name) {
+			if rr.hasType(qtype) || rr.hasType(TypeCNAME) || nsecCut(rr.hasType, qtype) {
+				return 0, nil
+			}
+			return RcodeSuccess, []*nsecEntry{e}
+		}
+	}
+
+	var cover *nsecEntry
+	for _, e := range nsec {
+		if e.rr.(*NSEC).Cover(name) {
+			cover = e
+			break
+		}
+	}
+	if cover == nil {
+		return 0, nil
+	}
+
+	// The closest encloser is the longest ancestor of name that the names
+	// around it share, the wildcard below it must not exist.
+	rr := cover.rr.(*NSEC)
+	labels := CompareDomainName(name, rr.Hdr.Name)
+	if l := CompareDomainName(name, rr.NextDomain); l > labels {
+		labels = l
+	}
+	ce := "."
+	if labels > 0 {
+		idx := Split(name)
+		ce = name[idx[len(idx)-labels]:]
+	}
+	wildcard := "*." + ce
+	if ce == "." {
+		wildcard = "*."
+	}
+	for _, e := range nsec {
+		if e.rr.(*NSEC).Match(wildcard) {
+			return 0, nil
+		}
+	}
+	for _, e := range nsec {
+		if e.rr.(*NSEC).Cover(wildcard) {
+			if e == cover {
+				return RcodeNameError, []*nsecEntry{cover}
+			}
+			return RcodeNameError, []*nsecEntry{cover, e}
+		}
+	}
+	return 0, nil
+}
+
+// nsec3Proof returns the NSEC3 records that prove that name or the type qtype
+// at name don't exist in zone, and the rcode of the response.
+func nsec3Proof(nsec3 []*nsecEntry, name, zone string, qtype uint16) (int, []*nsecEntry) {
+	if len(nsec3) == 0 {
+		return 0, nil
+	}
+	find := func(f func(*NSEC3) bool) *nsecEntry {
+		for _, e := range nsec3 {
+			if f(e.rr.(*NSEC3)) {
+				return e
+			}
+		}
+		return nil
+	}
+	hasType := func(rr *NSEC3, t uint16) bool {
+		for _, b := range rr.TypeBitMap {
+			if b == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	if e := find(func(rr *NSEC3) bool { return rr.Match(name) }); e != nil {
+		rr := e.rr.(*NSEC3)
+		if hasType(rr, qtype) || hasType(rr, TypeCNAME) || nsecCut(func(t uint16) bool { return hasType(rr, t) }, qtype) {
+			return 0, nil
+		}
+		return RcodeSuccess, []*nsecEntry{e}
+	}
+
+	// Closest encloser proof (RFC 5155, Section 7.2.1), without opt-out.
+	next := name
+	for off, end := NextLabel(name, 0); !end && IsSubDomain(zone, name[off:]); off, end = NextLabel(name, off) {
+		ce := name[off:]
+		match := find(func(rr *NSEC3) bool { return rr.Match(ce) })
+		if match == nil {
+			next = ce
+			continue
+		}
+		if rr := match.rr.(*NSEC3); nsecCut(func(t uint16) bool { return hasType(rr, t) }, 0) {
+			return 0, nil
+		}
+		cover := find(func(rr *NSEC3) bool { return rr.Flags&1 == 0 && rr.Cover(next) })
+		wildcard := find(func(rr *NSEC3) bool { return rr.Cover("*." + ce) })
+		if cover == nil || wildcard == nil {
+			return 0, nil
+		}
+		proof := []*nsecEntry{match}
+		if cover != match {
+			proof = append(proof, cover)
+		}
+		if wildcard != match && wildcard != cover {
+			proof = append(proof, wildcard)
+		}
+		return RcodeNameError, proof
+	}
+	return 0, nil
+}
+
+// nsecCut reports whether the NSEC or NSEC3 record whose types has looks up
+// is the parent side record of a delegation, with NS and without SOA, or has
+// a DNAME. Such a record can't deny the names below its owner, nor the types
+// at it other than qtype DS for a delegation (RFC 4035, Section 5.4 and RFC
+// 6840, Section 4.1): they are in another zone, or redirected.
+func nsecCut(has func(uint16) bool, qtype uint16) bool {
+	if has(TypeDNAME) {
+		return true
+	}
+	return has(TypeNS) && !has(TypeSOA) && qtype != TypeDS
+}
@@ -0,0 +1,325 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+// countExchanger answers queries from a fixed response, and counts them.
+type countExchanger struct {
+	r       *Msg
+	queries int
+}
+
+func (e *countExchanger) Exchange(m *Msg, address string) (*Msg, time.Duration, error) {
+	e.queries++
+	r := e.r.Copy()
+	r.SetReply(m)
+	r.Rcode = e.r.Rcode
+	return r, time.Millisecond, nil
+}
+
+func TestCache(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	ex := &countExchanger{r: new(Msg)}
+	a, _ := NewRR("miek.nl. 300 IN A 192.0.2.1")
+	ex.r.Answer = []RR{a}
+	c := NewCache(ex)
+	c.now = func() time.Time { return now }
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	if _, _, err := c.Exchange(m, "192.0.2.53:53"); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(100 * time.Second)
+	m.SetQuestion("MIEK.nl.", TypeA)
+	r, rtt, err := c.Exchange(m, "192.0.2.53:53")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ex.queries != 1 || rtt != 0 || r.Id != m.Id || r.Question[0].Name != "MIEK.nl." {
+		t.Fatalf("expected response from the cache, got %v after %d queries", r, ex.queries)
+	}
+	if ttl := r.Answer[0].Header().Ttl; ttl != 200 {
+		t.Errorf("expected TTL 200, got %d", ttl)
+	}
+
+	now = now.Add(200 * time.Second)
+	c.Exchange(m, "192.0.2.53:53")
+	if ex.queries != 2 {
+		t.Errorf("expected expired response to be fetched again")
+	}
+	if st := c.Stats(); st.Hits != 1 || st.Misses != 2 || st.Entries != 1 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestCacheDoCd(t *testing.T) {
+	ex := &countExchanger{r: new(Msg)}
+	a, _ := NewRR("miek.nl. 300 IN A 192.0.2.1")
+	ex.r.Answer = []RR{a}
+	c := NewCache(ex)
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	c.Exchange(m, "192.0.2.53:53")
+	m.SetEdns0(4096, true)
+	c.Exchange(m, "192.0.2.53:53")
+	m.CheckingDisabled = true
+	c.Exchange(m, "192.0.2.53:53")
+	if ex.queries != 3 {
+		t.Fatalf("expected the DO and CD bits to be part of the key, got %d queries", ex.queries)
+	}
+	c.Exchange(m, "192.0.2.53:53")
+	if ex.queries != 3 {
+		t.Errorf("expected response from the cache, got %d queries", ex.queries)
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	ex := &countExchanger{r: new(Msg)}
+	soa, _ := NewRR("miek.nl. 3600 IN SOA ns. hostmaster. 1 3600 600 86400 60")
+	ex.r.Ns = []RR{soa}
+	ex.r.Rcode = RcodeNameError
+	c := NewCache(ex)
+	c.now = func() time.Time { return now }
+
+	m := new(Msg)
+	m.SetQuestion("nx.miek.nl.", TypeA)
+	c.Exchange(m, "192.0.2.53:53")
+	now = now.Add(59 * time.Second)
+	if r, _, _ := c.Exchange(m, "192.0.2.53:53"); ex.queries != 1 || r.Rcode != RcodeNameError || r.Ns[0].Header().Ttl != 1 {
+		t.Fatalf("expected NXDOMAIN from the cache, got %v after %d queries", r, ex.queries)
+	}
+	// Cached for the SOA MINIMUM of 60 seconds.
+	now = now.Add(time.Second)
+	c.Exchange(m, "192.0.2.53:53")
+	if ex.queries != 2 {
+		t.Errorf("expected expired negative response to be fetched again")
+	}
+
+	// No SOA, no caching.
+	ex.r.Ns = nil
+	m.SetQuestion("nx2.miek.nl.", TypeA)
+	c.Exchange(m, "192.0.2.53:53")
+	c.Exchange(m, "192.0.2.53:53")
+	if ex.queries != 4 {
+		t.Errorf("expected negative response without SOA not to be cached")
+	}
+}
+
+func TestCacheLRU(t *testing.T) {
+	ex := &countExchanger{r: new(Msg)}
+	a, _ := NewRR("miek.nl. 300 IN A 192.0.2.1")
+	ex.r.Answer = []RR{a}
+	c := NewCache(ex)
+	c.MaxEntries = 2
+
+	m := new(Msg)
+	for _, name := range []string{"a.", "b.", "a.", "c.", "a.", "b."} {
+		m.SetQuestion(name, TypeA)
+		c.Exchange(m, "192.0.2.53:53")
+	}
+	// b. was evicted by c., a. was used recently enough to stay.
+	if st := c.Stats(); st.Hits != 2 || st.Misses != 4 || st.Evictions != 2 || st.Entries != 2 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+type exchangerFunc func(m *Msg, address string) (*Msg, time.Duration, error)
+
+func (f exchangerFunc) Exchange(m *Msg, address string) (*Msg, time.Duration, error) {
+	return f(m, address)
+}
+
+func TestCacheServeStale(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	a, _ := NewRR("miek.nl. 60 IN A 192.0.2.1")
+	var ex exchangerFunc
+	c := NewCache(exchangerFunc(func(m *Msg, address string) (*Msg, time.Duration, error) {
+		return ex(m, address)
+	}))
+	c.now = func() time.Time { return now }
+	c.StaleTTL = time.Hour
+	answer := func(m *Msg, address string) (*Msg, time.Duration, error) {
+		r := new(Msg)
+		r.SetReply(m)
+		r.Answer = []RR{a}
+		return r, time.Millisecond, nil
+	}
+	fail := func(m *Msg, address string) (*Msg, time.Duration, error) {
+		return nil, 0, &Error{err: "timeout"}
+	}
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	m.SetEdns0(4096, false)
+	ex = answer
+	c.Exchange(m, "192.0.2.53:53")
+
+	now = now.Add(61 * time.Second)
+	ex = fail
+	r, _, err := c.Exchange(m, "192.0.2.53:53")
+	if err != nil {
+		t.Fatalf("expected stale response, got %v", err)
+	}
+	if ttl := r.Answer[0].Header().Ttl; ttl != staleTTL {
+		t.Errorf("expected stale TTL %d, got %d", staleTTL, ttl)
+	}
+	if opt := r.IsEdns0(); opt == nil || len(opt.Option) != 1 || opt.Option[0].(*EDNS0_EDE).InfoCode != ExtendedErrorCodeStaleAnswer {
+		t.Errorf("expected Stale Answer extended error, got %v", r)
+	}
+
+	ex = answer
+	if r, rtt, _ := c.Exchange(m, "192.0.2.53:53"); rtt == 0 || r.Answer[0].Header().Ttl != 60 {
+		t.Errorf("expected refreshed response, got %v", r)
+	}
+
+	// Serve stale while a slow refresh continues in the background.
+	now = now.Add(61 * time.Second)
+	c.StaleClientTimeout = 10 * time.Millisecond
+	unblock := make(chan struct{})
+	ex = func(m *Msg, address string) (*Msg, time.Duration, error) {
+		<-unblock
+		return answer(m, address)
+	}
+	if r, _, _ := c.Exchange(m, "192.0.2.53:53"); r.Answer[0].Header().Ttl != staleTTL {
+		t.Errorf("expected stale response, got %v", r)
+	}
+	close(unblock)
+	for i := 0; ; i++ {
+		if r, rtt, _ := c.Exchange(m, "192.0.2.53:53"); rtt == 0 && r.Answer[0].Header().Ttl == 60 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("expected response to be refreshed in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st := c.Stats(); st.Stale < 2 {
+		t.Errorf("expected 2 stale responses, got %+v", st)
+	}
+}
+
+func TestCacheAggressiveNSEC(t *testing.T) {
+	var queries int
+	ex := exchangerFunc(func(m *Msg, address string) (*Msg, time.Duration, error) {
+		queries++
+		r := new(Msg)
+		r.SetRcode(m, RcodeNameError)
+		r.AuthenticatedData = true
+		for _, s := range []string{
+			"example. 3600 IN SOA ns. hostmaster. 1 3600 600 86400 300",
+			"b.example. 3600 IN NSEC d.example. A RRSIG NSEC",
+			"example. 3600 IN NSEC b.example. SOA NS RRSIG NSEC DNSKEY",
+		} {
+			rr, _ := NewRR(s)
+			r.Ns = append(r.Ns, rr)
+		}
+		return r, time.Millisecond, nil
+	})
+	c := NewCache(ex)
+	c.AggressiveNSEC = true
+
+	m := new(Msg)
+	m.SetQuestion("c.example.", TypeA)
+	m.SetEdns0(4096, true)
+	c.Exchange(m, "192.0.2.53:53")
+
+	m.SetQuestion("c2.example.", TypeA)
+	r, _, _ := c.Exchange(m, "192.0.2.53:53")
+	if queries != 1 || r.Rcode != RcodeNameError || !r.AuthenticatedData || len(r.Ns) != 3 {
+		t.Fatalf("expected synthesized NXDOMAIN, got %v after %d queries", r, queries)
+	}
+	if ttl := r.Ns[0].Header().Ttl; ttl > 300 {
+		t.Errorf("expected TTL to be at most the SOA MINIMUM, got %d", ttl)
+	}
+
+	m.SetQuestion("b.example.", TypeTXT)
+	r, _, _ = c.Exchange(m, "192.0.2.53:53")
+	if queries != 1 || r.Rcode != RcodeSuccess || len(r.Answer) != 0 || len(r.Ns) != 2 {
+		t.Fatalf("expected synthesized NODATA, got %v after %d queries", r, queries)
+	}
+
+	for _, name := range []string{"b.example.", "e.example."} {
+		m.SetQuestion(name, TypeA)
+		c.Exchange(m, "192.0.2.53:53")
+	}
+	if queries != 3 {
+		t.Errorf("expected queries not proven to not exist to be sent, got %d queries", queries)
+	}
+	if st := c.Stats(); st.Synthesized != 2 {
+		t.Errorf("expected 2 synthesized responses, got %+v", st)
+	}
+}
+
+func TestNsec3Proof(t *testing.T) {
+	// A single NSEC3 record for the apex that covers all other hashes.
+	h := HashName("example.", SHA1, 0, "")
+	rr, _ := NewRR(h + ".example. 300 IN NSEC3 1 0 0 - " + h + " SOA NS")
+	nsec3 := []*nsecEntry{{rr: rr}}
+
+	if rcode, proof := nsec3Proof(nsec3, "x.y.example.", "example.", TypeA); rcode != RcodeNameError || len(proof) != 1 {
+		t.Errorf("expected NXDOMAIN proof, got %d %v", rcode, proof)
+	}
+	if rcode, proof := nsec3Proof(nsec3, "example.", "example.", TypeTXT); rcode != RcodeSuccess || len(proof) != 1 {
+		t.Errorf("expected NODATA proof, got %d %v", rcode, proof)
+	}
+	if _, proof := nsec3Proof(nsec3, "example.", "example.", TypeSOA); proof != nil {
+		t.Errorf("expected no proof for an existing type, got %v", proof)
+	}
+}
+
+func TestNsecProofCut(t *testing.T) {
+	nsec := func(s string) []*nsecEntry {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []*nsecEntry{{rr: rr}}
+	}
+
+	delegation := nsec("sub.example. 3600 IN NSEC sub2.example. NS RRSIG NSEC")
+	if _, proof := nsecProof(delegation, "sub.example.", TypeA); proof != nil {
+		t.Errorf("expected no NODATA proof from a delegation, got %v", proof)
+	}
+	if rcode, proof := nsecProof(delegation, "sub.example.", TypeDS); rcode != RcodeSuccess || len(proof) != 1 {
+		t.Errorf("expected NODATA proof for DS, got %d %v", rcode, proof)
+	}
+	if _, proof := nsecProof(delegation, "www.sub.example.", TypeA); proof != nil {
+		t.Errorf("expected no NXDOMAIN proof below a delegation, got %v", proof)
+	}
+
+	dname := nsec("d.example. 3600 IN NSEC e.example. DNAME RRSIG NSEC")
+	if _, proof := nsecProof(dname, "d.example.", TypeTXT); proof != nil {
+		t.Errorf("expected no NODATA proof from a DNAME, got %v", proof)
+	}
+	if _, proof := nsecProof(dname, "www.d.example.", TypeA); proof != nil {
+		t.Errorf("expected no NXDOMAIN proof below a DNAME, got %v", proof)
+	}
+
+	// NSEC3 records that cover all other hashes.
+	h := HashName("sub.example.", SHA1, 0, "")
+	delegation = nsec(h + ".example. 300 IN NSEC3 1 0 0 - " + h + " NS")
+	if _, proof := nsec3Proof(delegation, "sub.example.", "example.", TypeA); proof != nil {
+		t.Errorf("expected no NSEC3 NODATA proof from a delegation, got %v", proof)
+	}
+	if rcode, proof := nsec3Proof(delegation, "sub.example.", "example.", TypeDS); rcode != RcodeSuccess || len(proof) != 1 {
+		t.Errorf("expected NSEC3 NODATA proof for DS, got %d %v", rcode, proof)
+	}
+	if _, proof := nsec3Proof(delegation, "www.sub.example.", "example.", TypeA); proof != nil {
+		t.Errorf("expected no NSEC3 NXDOMAIN proof below a delegation, got %v", proof)
+	}
+
+	h = HashName("d.example.", SHA1, 0, "")
+	dname = nsec(h + ".example. 300 IN NSEC3 1 0 0 - " + h + " DNAME")
+	if _, proof := nsec3Proof(dname, "d.example.", "example.", TypeTXT); proof != nil {
+		t.Errorf("expected no NSEC3 NODATA proof from a DNAME, got %v", proof)
+	}
+	if _, proof := nsec3Proof(dname, "www.d.example.", "example.", TypeA); proof != nil {
+		t.Errorf("expected no NSEC3 NXDOMAIN proof below a DNAME, got %v", proof)
+	}
+}
@@ -0,0 +1,221 @@
+package dns
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// CatalogVersion is the version of the catalog zone schema of RFC 9432.
+const CatalogVersion = "2"
+
+var errCatalogVersion = &Error{err: "unsupported catalog zone version"}
+
+// A Catalog is a catalog zone (RFC 9432): a zone that lists the member zones a
+// group of name servers serves, so secondaries can provision them from it.
+type Catalog struct {
+	Zone    string          // name of the catalog zone
+	Members []CatalogMember // member zones, ordered by ID
+}
+
+// A CatalogMember is a member zone of a catalog zone, with its properties.
+type CatalogMember struct {
+	ID     string   // unique label of the member under zones.<catalog>
+	Zone   string   // name of the member zone
+	Groups []string // group property, sets of zones that are configured alike
+	COO    string   // change of ownership property, the catalog zone the member moves to, if any
+}
+
+// CatalogMemberID returns a unique ID for the member zone, derived from its
+// name, to be used when adding it to a catalog.
+func CatalogMemberID(zone string) string {
+	h := sha1.Sum([]byte(strings.ToLower(Fqdn(zone))))
+	return hex.EncodeToString(h[:])
+}
+
+// ParseCatalog parses the records of the catalog zone named zone, as they are
+// transferred. Records that aren't part of the schema, like custom properties
+// under ext., are ignored. Members with more than one PTR record, and members
+// for a zone that is already in the catalog with a lower ID, are ignored as
+// RFC 9432, Section 4.1 requires.
+func ParseCatalog(zone string, rrs []RR) (*Catalog, error) {
+	zone = strings.ToLower(Fqdn(zone))
+	zones := "zones." + zone
+	version := ""
+	members := make(map[string]*CatalogMember)
+	ptrs := make(map[string]int)
+	for _, rr := range rrs {
+		h := rr.Header()
+		name := strings.ToLower(Fqdn(h.Name))
+		if h.Class != ClassINET {
+			continue
+		}
+		if name == "version."+zone {
+			if txt, ok := rr.(*TXT); ok && len(txt.Txt) == 1 {
+				version = txt.Txt[0]
+			}
+			continue
+		}
+		if !IsSubDomain(zones, name) || name == zones {
+			continue
+		}
+		labels := SplitDomainName(strings.TrimSuffix(name, zones))
+		id := labels[len(labels)-1]
+		m, ok := members[id]
+		if !ok {
+			m = &CatalogMember{ID: id}
+			members[id] = m
+		}
+		switch {
+		case len(labels) == 1 && h.Rrtype == TypePTR:
+			m.Zone = strings.ToLower(Fqdn(rr.(*PTR).Ptr))
+			ptrs[id]++
+		case len(labels) == 2 && labels[0] == "group" && h.Rrtype == TypeTXT:
+			m.Groups = append(m.Groups, rr.(*TXT).Txt...)
+		case len(labels) == 2 && labels[0] == "coo" && h.Rrtype == TypePTR:
+			m.COO = strings.ToLower(Fqdn(rr.(*PTR).Ptr))
+		}
+	}
+	if version != CatalogVersion {
+		return nil, errCatalogVersion
+	}
+
+	c := &Catalog{Zone: zone}
+	seen := make(map[string]bool)
+	ids := make([]string, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		m := members[id]
+		if ptrs[id] != 1 || seen[m.Zone] {
+			continue
+		}
+		seen[m.Zone] = true
+		c.Members = append(c.Members, *m)
+	}
+	return c, nil
+}
+
+// Member returns the member for zone, or nil if zone isn't in c.
+func (c *Catalog) Member(zone string) *CatalogMember {
+	zone = strings.ToLower(Fqdn(zone))
+	for i := range c.Members {
+		if c.Members[i].Zone == zone {
+			return &c.Members[i]
+		}
+	}
+	return nil
+}
+
+// RRs returns the records of c, starting with soa and ending with the member
+// zones, ordered by ID. Members without an ID get one from CatalogMemberID.
+// The records have a TTL of zero, as catalog zones aren't meant to be queried.
+func (c *Catalog) RRs(soa *SOA) []RR {
+	zone := strings.ToLower(Fqdn(c.Zone))
+	zones := "zones." + zone
+	hdr := func(name string, t uint16) RR_Header {
+		return RR_Header{Name: name, Rrtype: t, Class: ClassINET}
+	}
+
+	rrs := []RR{
+		soa,
+		&NS{Hdr: hdr(zone, TypeNS), Ns: "invalid."},
+		&TXT{Hdr: hdr("version."+zone, TypeTXT), Txt: []string{CatalogVersion}},
+	}
+	members := append([]CatalogMember(nil), c.Members...)
+	for i := range members {
+		if members[i].ID == "" {
+			members[i].ID = CatalogMemberID(members[i].Zone)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	for _, m := range members {
+		owner := m.ID + "." + zones
+		rrs = append(rrs, &PTR{Hdr: hdr(owner, TypePTR), Ptr: Fqdn(m.Zone)})
+		if len(m.Groups) > 0 {
+			rrs = append(rrs, &TXT{Hdr: hdr("group."+owner, TypeTXT), Txt: m.Groups})
+		}
+		if m.COO != "" {
+			rrs = append(rrs, &PTR{Hdr: hdr("coo."+owner, TypePTR), Ptr: Fqdn(m.COO)})
+		}
+	}
+	return rrs
+}
+
+// CatalogEventType is the type of a CatalogEvent.
+type CatalogEventType int
+
+// The types of catalog events.
+const (
+	CatalogAdd    CatalogEventType = iota // a zone was added to the catalog
+	CatalogRemove                         // a zone was removed from the catalog
+	CatalogUpdate                         // the properties of a zone changed
+)
+
+// A CatalogEvent is a change of a member zone of a catalog.
+type CatalogEvent struct {
+	Type   CatalogEventType
+	Member CatalogMember // the member after the change, or before it when it was removed
+}
+
+// A CatalogWatcher turns the successive versions of a catalog zone into the
+// member zones to provision or remove.
+type CatalogWatcher struct {
+	Zone string // name of the catalog zone
+
+	members map[string]CatalogMember // by zone name
+}
+
+// Update parses rrs, the records of a newly transferred version of the catalog
+// zone, and returns the changes since the previous version. The first update
+// adds all member zones. When the ID of a member changes, its zone is removed
+// and added again, which resets its state as RFC 9432, Section 5.6 describes.
+// On error, the previous version is kept.
+func (w *CatalogWatcher) Update(rrs []RR) ([]CatalogEvent, error) {
+	c, err := ParseCatalog(w.Zone, rrs)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []CatalogEvent
+	members := make(map[string]CatalogMember, len(c.Members))
+	for _, m := range c.Members {
+		members[m.Zone] = m
+		old, ok := w.members[m.Zone]
+		switch {
+		case !ok:
+			events = append(events, CatalogEvent{Type: CatalogAdd, Member: m})
+		case old.ID != m.ID:
+			events = append(events, CatalogEvent{Type: CatalogRemove, Member: old}, CatalogEvent{Type: CatalogAdd, Member: m})
+		case old.COO != m.COO || !equalStrings(old.Groups, m.Groups):
+			events = append(events, CatalogEvent{Type: CatalogUpdate, Member: m})
+		}
+	}
+	var removed []CatalogMember
+	for zone, old := range w.members {
+		if _, ok := members[zone]; !ok {
+			removed = append(removed, old)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].ID < removed[j].ID })
+	for _, m := range removed {
+		events = append(events, CatalogEvent{Type: CatalogRemove, Member: m})
+	}
+	w.members = members
+	return events, nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
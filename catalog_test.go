@@ -0,0 +1,112 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+)
+
+const testCatalog = `catalog.invalid. 0 IN SOA invalid. invalid. 1 3600 600 86400 0
+catalog.invalid. 0 IN NS invalid.
+version.catalog.invalid. 0 IN TXT "2"
+m1.zones.catalog.invalid. 0 IN PTR example.com.
+group.m1.zones.catalog.invalid. 0 IN TXT "signed"
+m2.zones.catalog.invalid. 0 IN PTR example.net.
+coo.m2.zones.catalog.invalid. 0 IN PTR other.invalid.
+m3.zones.catalog.invalid. 0 IN PTR example.com.
+m4.zones.catalog.invalid. 0 IN PTR a.example.
+m4.zones.catalog.invalid. 0 IN PTR b.example.
+ext.m1.zones.catalog.invalid. 0 IN TXT "ignored"
+`
+
+func parseTestCatalog(t *testing.T, s string) []RR {
+	var rrs []RR
+	for tok := range ParseZone(strings.NewReader(s), "", "") {
+		if tok.Error != nil {
+			t.Fatal(tok.Error)
+		}
+		rrs = append(rrs, tok.RR)
+	}
+	return rrs
+}
+
+func TestParseCatalog(t *testing.T) {
+	c, err := ParseCatalog("catalog.invalid.", parseTestCatalog(t, testCatalog))
+	if err != nil {
+		t.Fatalf("failed to parse the catalog: %v", err)
+	}
+	// m3 duplicates example.com. and m4 has two PTR records.
+	if len(c.Members) != 2 {
+		t.Fatalf("expected 2 members, got %+v", c.Members)
+	}
+	if m := c.Member("EXAMPLE.COM"); m == nil || m.ID != "m1" || len(m.Groups) != 1 || m.Groups[0] != "signed" {
+		t.Errorf("unexpected member for example.com.: %+v", m)
+	}
+	if m := c.Member("example.net."); m == nil || m.COO != "other.invalid." {
+		t.Errorf("unexpected member for example.net.: %+v", m)
+	}
+
+	soa := testRR("catalog.invalid. 0 IN SOA invalid. invalid. 2 3600 600 86400 0").(*SOA)
+	c.Members = append(c.Members, CatalogMember{Zone: "example.org."})
+	c2, err := ParseCatalog("catalog.invalid.", c.RRs(soa))
+	if err != nil {
+		t.Fatalf("failed to parse the generated catalog: %v", err)
+	}
+	if len(c2.Members) != 3 {
+		t.Fatalf("expected 3 members, got %+v", c2.Members)
+	}
+	if m := c2.Member("example.org."); m == nil || m.ID != CatalogMemberID("example.org.") {
+		t.Errorf("unexpected member for example.org.: %+v", m)
+	}
+
+	if _, err := ParseCatalog("catalog.invalid.", []RR{soa}); err == nil {
+		t.Error("expected an error for a catalog without version")
+	}
+}
+
+func TestCatalogWatcher(t *testing.T) {
+	w := &CatalogWatcher{Zone: "catalog.invalid."}
+	events, err := w.Update(parseTestCatalog(t, testCatalog))
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if len(events) != 2 || events[0].Type != CatalogAdd || events[1].Type != CatalogAdd {
+		t.Fatalf("expected 2 additions, got %+v", events)
+	}
+
+	updated := `catalog.invalid. 0 IN SOA invalid. invalid. 2 3600 600 86400 0
+version.catalog.invalid. 0 IN TXT "2"
+m1.zones.catalog.invalid. 0 IN PTR example.com.
+group.m1.zones.catalog.invalid. 0 IN TXT "unsigned"
+m5.zones.catalog.invalid. 0 IN PTR example.org.
+`
+	events, err = w.Update(parseTestCatalog(t, updated))
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	expected := []struct {
+		typ  CatalogEventType
+		zone string
+	}{
+		{CatalogUpdate, "example.com."},
+		{CatalogAdd, "example.org."},
+		{CatalogRemove, "example.net."},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i, e := range expected {
+		if events[i].Type != e.typ || events[i].Member.Zone != e.zone {
+			t.Errorf("expected event %v for %s, got %+v", e.typ, e.zone, events[i])
+		}
+	}
+
+	// A new ID resets the zone.
+	reset := strings.Replace(updated, "m1.", "m6.", -1)
+	events, err = w.Update(parseTestCatalog(t, reset))
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if len(events) != 2 || events[0].Type != CatalogRemove || events[1].Type != CatalogAdd || events[1].Member.ID != "m6" {
+		t.Errorf("expected example.com. to be removed and added, got %+v", events)
+	}
+}
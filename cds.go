@@ -0,0 +1,177 @@
+package dns
+
+import "strings"
+
+// CDSFromKeys returns the CDS records, as described in RFC 7344, for the key
+// signing keys found in keys: each DNSKEY with the SEP flag set and the
+// REVOKE flag unset yields a CDS record for every digest type in digests.
+// When no digest types are given SHA256 is used.
+func CDSFromKeys(keys []RR, digests ...uint8) []*CDS {
+	if len(digests) == 0 {
+		digests = []uint8{SHA256}
+	}
+
+	var cds []*CDS
+	for _, k := range ksks(keys) {
+		for _, h := range digests {
+			if ds := k.ToDS(h); ds != nil {
+				cds = append(cds, ds.ToCDS())
+			}
+		}
+	}
+	return cds
+}
+
+// CDNSKEYFromKeys returns the CDNSKEY records, as described in RFC 7344,
+// for the key signing keys found in keys.
+func CDNSKEYFromKeys(keys []RR) []*CDNSKEY {
+	var cdnskey []*CDNSKEY
+	for _, k := range ksks(keys) {
+		cdnskey = append(cdnskey, k.ToCDNSKEY())
+	}
+	return cdnskey
+}
+
+// ksks returns the DNSKEYs from keys that have the SEP flag set and are not
+// revoked.
+func ksks(keys []RR) []*DNSKEY {
+	var k1 []*DNSKEY
+	for _, r := range keys {
+		k, ok := r.(*DNSKEY)
+		if !ok || k.Flags&SEP == 0 || k.Flags&REVOKE != 0 {
+			continue
+		}
+		k1 = append(k1, k)
+	}
+	return k1
+}
+
+// DeleteCDS returns the CDS record that signals the parent to remove all DS
+// records for zone, see RFC 8078, section 4.
+func DeleteCDS(zone string) *CDS {
+	return &CDS{DS{Hdr: RR_Header{Name: Fqdn(zone), Rrtype: TypeCDS, Class: ClassINET}, Digest: "00"}}
+}
+
+// DeleteCDNSKEY returns the CDNSKEY record that signals the parent to remove
+// all DS records for zone, see RFC 8078, section 4.
+func DeleteCDNSKEY(zone string) *CDNSKEY {
+	return &CDNSKEY{DNSKEY{Hdr: RR_Header{Name: Fqdn(zone), Rrtype: TypeCDNSKEY, Class: ClassINET}, Protocol: 3, PublicKey: "AA=="}}
+}
+
+// IsDelete returns true if c is the RFC 8078 delete signal.
+func (c *CDS) IsDelete() bool {
+	return c.KeyTag == 0 && c.Algorithm == 0 && c.DigestType == 0 && strings.Trim(c.Digest, "0") == ""
+}
+
+// IsDelete returns true if c is the RFC 8078 delete signal.
+func (c *CDNSKEY) IsDelete() bool {
+	return c.Flags == 0 && c.Protocol == 3 && c.Algorithm == 0 && c.PublicKey == "AA=="
+}
+
+// DSChanges lists the changes the parent should make to its DS RRset to
+// match the CDS or CDNSKEY records published by the child.
+type DSChanges struct {
+	Add    []*DS // DS records to add.
+	Remove []*DS // DS records to remove.
+	Delete bool  // Child requests the removal of all DS records (RFC 8078).
+}
+
+// Empty returns true if no changes are needed.
+func (c *DSChanges) Empty() bool { return len(c.Add) == 0 && len(c.Remove) == 0 && !c.Delete }
+
+// CheckCDS compares the CDS records published by the child with the DS records
+// held by the parent and returns the changes required at the parent. An empty
+// cds slice means the child doesn't signal anything; no changes are returned.
+func CheckCDS(cds []*CDS, ds []*DS) *DSChanges {
+	changes := new(DSChanges)
+	if len(cds) == 0 {
+		return changes
+	}
+	for _, c := range cds {
+		if c.IsDelete() {
+			// RFC 8078, section 4: the delete signal must be the only record.
+			if len(cds) == 1 {
+				changes.Delete = true
+				changes.Remove = append(changes.Remove, ds...)
+			}
+			return changes
+		}
+	}
+
+	want := make([]*DS, len(cds))
+	for i, c := range cds {
+		want[i] = &c.DS
+	}
+	return diffDS(want, ds)
+}
+
+// CheckCDNSKEY compares the CDNSKEY records published by the child with the DS
+// records held by the parent and returns the changes required at the parent.
+// The DS records are computed with the digest types found in ds, SHA256 is
+// used when ds is empty.
+func CheckCDNSKEY(cdnskey []*CDNSKEY, ds []*DS) *DSChanges {
+	changes := new(DSChanges)
+	if len(cdnskey) == 0 {
+		return changes
+	}
+	for _, c := range cdnskey {
+		if c.IsDelete() {
+			if len(cdnskey) == 1 {
+				changes.Delete = true
+				changes.Remove = append(changes.Remove, ds...)
+			}
+			return changes
+		}
+	}
+
+	var digests []uint8
+	seen := make(map[uint8]bool)
+	for _, d := range ds {
+		if !seen[d.DigestType] {
+			seen[d.DigestType] = true
+			digests = append(digests, d.DigestType)
+		}
+	}
+	if len(digests) == 0 {
+		digests = []uint8{SHA256}
+	}
+
+	var want []*DS
+	for _, c := range cdnskey {
+		for _, h := range digests {
+			if d := c.DNSKEY.ToDS(h); d != nil {
+				want = append(want, d)
+			}
+		}
+	}
+	return diffDS(want, ds)
+}
+
+// diffDS returns the records in want that are not in have as additions, and
+// those in have that are not in want as removals.
+func diffDS(want, have []*DS) *DSChanges {
+	changes := new(DSChanges)
+	for _, w := range want {
+		if !containsDS(have, w) {
+			d := *w
+			d.Hdr.Rrtype = TypeDS
+			changes.Add = append(changes.Add, &d)
+		}
+	}
+	for _, h := range have {
+		if !containsDS(want, h) {
+			changes.Remove = append(changes.Remove, h)
+		}
+	}
+	return changes
+}
+
+func containsDS(set []*DS, d *DS) bool {
+	for _, s := range set {
+		if s.KeyTag == d.KeyTag && s.Algorithm == d.Algorithm && s.DigestType == d.DigestType &&
+			strings.EqualFold(s.Digest, d.Digest) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package dns
+
+import "testing"
+
+func TestCDSFromKeys(t *testing.T) {
+	ksk := testRR("example.org. 3600 IN DNSKEY 257 3 15 l02Woi0iS8Aa25FQkUd9RMzZHJpBoRQwAQEX1SxZJA4=").(*DNSKEY)
+	zsk := testRR("example.org. 3600 IN DNSKEY 256 3 15 zPnZ/QwEe7S8C5SPz2OfS5RR40ATk2/rYnE9xHIEijs=").(*DNSKEY)
+	keys := []RR{ksk, zsk}
+
+	cds := CDSFromKeys(keys, SHA256, SHA384)
+	if len(cds) != 2 {
+		t.Fatalf("expected 2 CDS records, got %d", len(cds))
+	}
+	for _, c := range cds {
+		if c.Hdr.Rrtype != TypeCDS || c.KeyTag != ksk.KeyTag() {
+			t.Errorf("unexpected CDS record: %s", c)
+		}
+	}
+	if cdnskey := CDNSKEYFromKeys(keys); len(cdnskey) != 1 || cdnskey[0].Hdr.Rrtype != TypeCDNSKEY {
+		t.Errorf("expected 1 CDNSKEY record, got %v", cdnskey)
+	}
+
+	revoked := ksk.copy().(*DNSKEY)
+	revoked.Flags |= REVOKE
+	if cds := CDSFromKeys([]RR{revoked}); len(cds) != 0 {
+		t.Errorf("expected no CDS records for a revoked key, got %v", cds)
+	}
+}
+
+func TestCheckCDS(t *testing.T) {
+	oldKSK := testRR("example.org. 3600 IN DNSKEY 257 3 15 zPnZ/QwEe7S8C5SPz2OfS5RR40ATk2/rYnE9xHIEijs=").(*DNSKEY)
+	newKSK := testRR("example.org. 3600 IN DNSKEY 257 3 15 l02Woi0iS8Aa25FQkUd9RMzZHJpBoRQwAQEX1SxZJA4=").(*DNSKEY)
+	parent := []*DS{oldKSK.ToDS(SHA256)}
+
+	changes := CheckCDS(CDSFromKeys([]RR{oldKSK}), parent)
+	if !changes.Empty() {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+
+	changes = CheckCDS(CDSFromKeys([]RR{newKSK}), parent)
+	if len(changes.Add) != 1 || changes.Add[0].KeyTag != newKSK.KeyTag() || changes.Add[0].Hdr.Rrtype != TypeDS {
+		t.Errorf("expected new DS to be added, got %+v", changes.Add)
+	}
+	if len(changes.Remove) != 1 || changes.Remove[0].KeyTag != oldKSK.KeyTag() {
+		t.Errorf("expected old DS to be removed, got %+v", changes.Remove)
+	}
+
+	changes = CheckCDNSKEY(CDNSKEYFromKeys([]RR{oldKSK, newKSK}), parent)
+	if len(changes.Add) != 1 || len(changes.Remove) != 0 {
+		t.Errorf("expected one addition, got %+v", changes)
+	}
+
+	changes = CheckCDS([]*CDS{DeleteCDS("example.org")}, parent)
+	if !changes.Delete || len(changes.Remove) != 1 {
+		t.Errorf("expected delete signal, got %+v", changes)
+	}
+	changes = CheckCDNSKEY([]*CDNSKEY{DeleteCDNSKEY("example.org")}, parent)
+	if !changes.Delete {
+		t.Errorf("expected delete signal, got %+v", changes)
+	}
+
+	// The delete signal next to other records is ignored.
+	changes = CheckCDS(append(CDSFromKeys([]RR{newKSK}), DeleteCDS("example.org")), parent)
+	if !changes.Empty() {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+
+	// The delete records survive a round trip through their text form.
+	if c := testRR(DeleteCDS("example.org").String()).(*CDS); !c.IsDelete() {
+		t.Errorf("expected parsed CDS to be the delete signal: %s", c)
+	}
+	if c := testRR(DeleteCDNSKEY("example.org").String()).(*CDNSKEY); !c.IsDelete() {
+		t.Errorf("expected parsed CDNSKEY to be the delete signal: %s", c)
+	}
+}
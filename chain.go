@@ -0,0 +1,386 @@
+package dns
+
+import (
+	"strings"
+	"time"
+)
+
+var (
+	errChainUnsigned = &Error{err: "chain: response isn't signed"}
+	errChainBroken   = &Error{err: "chain: no chain of trust from the trust point"}
+	errChainNoOption = &Error{err: "chain: response has no CHAIN option"}
+)
+
+// SetChain sets the CHAIN option of dns to trustPoint, the closest trust
+// point of a client asking for the DNSSEC chain of the response (RFC 7901),
+// e.g. "." when it only trusts the root key. An OPT record is added, before
+// the TSIG record, if dns has none. The DO bit has to be set for the chain to
+// be returned.
+func (dns *Msg) SetChain(trustPoint string) *Msg {
+	opt := responseOPT(dns)
+	for _, o := range opt.Option {
+		if e, ok := o.(*EDNS0_CHAIN); ok {
+			e.ClosestTrustPoint = Fqdn(trustPoint)
+			return dns
+		}
+	}
+	opt.Option = append(opt.Option, &EDNS0_CHAIN{Code: EDNS0CHAIN, ClosestTrustPoint: Fqdn(trustPoint)})
+	return dns
+}
+
+// ChainTrustPoint returns the closest trust point of the CHAIN option of dns,
+// and whether it has one.
+func (dns *Msg) ChainTrustPoint() (string, bool) {
+	opt := dns.IsEdns0()
+	if opt == nil {
+		return "", false
+	}
+	for _, o := range opt.Option {
+		if e, ok := o.(*EDNS0_CHAIN); ok && e.ClosestTrustPoint != "" {
+			return e.ClosestTrustPoint, true
+		}
+	}
+	return "", false
+}
+
+// removeChain removes the CHAIN option of dns.
+func (dns *Msg) removeChain() {
+	opt := dns.IsEdns0()
+	if opt == nil {
+		return
+	}
+	options := opt.Option[:0]
+	for _, o := range opt.Option {
+		if _, ok := o.(*EDNS0_CHAIN); !ok {
+			options = append(options, o)
+		}
+	}
+	opt.Option = options
+}
+
+// AppendChain adds the DNSSEC chain of the signed response r, from
+// trustPoint down to the zones that signed its answer, to the Authority
+// section of r, as a resolver answering a CHAIN query does (RFC 7901): the
+// DNSKEY RRset of every zone on the way, and the DS RRset of every zone below
+// trustPoint, with their RRSIGs. The records are asked with ex from the
+// resolver at address. The CHAIN option of r is set to trustPoint.
+func AppendChain(r *Msg, trustPoint string, ex Exchanger, address string) error {
+	trustPoint = strings.ToLower(Fqdn(trustPoint))
+	signers := chainSigners(r)
+	if len(signers) == 0 {
+		return errChainUnsigned
+	}
+
+	chain, err := chainRRset(ex, address, trustPoint, TypeDNSKEY)
+	if err != nil {
+		return err
+	}
+	cuts := map[string]bool{trustPoint: true} // names asked, true for the zone cuts
+	for _, signer := range signers {
+		if !IsSubDomain(trustPoint, signer) {
+			return errChainBroken
+		}
+		zone := trustPoint
+		labels := Split(signer)
+		for i := len(labels) - CountLabel(trustPoint) - 1; i >= 0; i-- {
+			name := signer[labels[i]:]
+			cut, asked := cuts[name]
+			if !asked {
+				ds, err := chainRRset(ex, address, name, TypeDS)
+				if err != nil {
+					return err
+				}
+				if cut = len(ds) > 0; cut {
+					keys, err := chainRRset(ex, address, name, TypeDNSKEY)
+					if err != nil {
+						return err
+					}
+					chain = append(append(chain, ds...), keys...)
+				}
+				cuts[name] = cut
+			}
+			if cut {
+				zone = name
+			}
+		}
+		if zone != signer {
+			return errChainBroken
+		}
+	}
+
+	for _, rr := range chain {
+		if !containsRR(r.Ns, rr) {
+			r.Ns = append(r.Ns, rr)
+		}
+	}
+	r.SetChain(trustPoint)
+	return nil
+}
+
+// chainSigners returns the signers of the RRSIGs of the Answer section of r,
+// or of its Authority section if the Answer section has none, lower cased.
+func chainSigners(r *Msg) []string {
+	for _, section := range [][]RR{r.Answer, r.Ns} {
+		var signers []string
+		seen := make(map[string]bool)
+		for _, rr := range section {
+			sig, ok := rr.(*RRSIG)
+			if !ok {
+				continue
+			}
+			signer := strings.ToLower(Fqdn(sig.SignerName))
+			if !seen[signer] {
+				seen[signer] = true
+				signers = append(signers, signer)
+			}
+		}
+		if len(signers) > 0 {
+			return signers
+		}
+	}
+	return nil
+}
+
+// chainRRset returns the signed RRset of name and rrtype, with its RRSIGs,
+// asked with ex from address. It is empty if name has no such RRset.
+func chainRRset(ex Exchanger, address, name string, rrtype uint16) ([]RR, error) {
+	m := new(Msg)
+	m.SetQuestion(name, rrtype)
+	m.SetEdns0(4096, true)
+	r, _, err := ex.Exchange(m, address)
+	if err != nil {
+		return nil, err
+	}
+	if r.Rcode != RcodeSuccess {
+		return nil, &Error{err: "chain: " + Type(rrtype).String() + " query for " + name + " failed with rcode " + RcodeToString[r.Rcode]}
+	}
+	var rrset []RR
+	for _, rr := range r.Answer {
+		h := rr.Header()
+		if !equal(h.Name, name) {
+			continue
+		}
+		if sig, ok := rr.(*RRSIG); h.Rrtype == rrtype || ok && sig.TypeCovered == rrtype {
+			rrset = append(rrset, rr)
+		}
+	}
+	return rrset, nil
+}
+
+// containsRR reports whether rrs holds a record equal to rr.
+func containsRR(rrs []RR, rr RR) bool {
+	for _, r := range rrs {
+		if IsDuplicate(r, rr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChainMiddleware answers CHAIN queries (RFC 7901) for a forwarder: when a
+// query with the DO bit has the CHAIN option, the response of the handler is
+// completed with AppendChain, with the records asked with ex from the
+// resolver at address. When the chain can't be built, the response is sent
+// without it and without the CHAIN option.
+func ChainMiddleware(ex Exchanger, address string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Msg) {
+			tp, ok := r.ChainTrustPoint()
+			if !ok || !r.Do() {
+				next.ServeDNS(w, r)
+				return
+			}
+			next.ServeDNS(&chainWriter{ResponseWriter: w, trustPoint: tp, ex: ex, address: address}, r)
+		})
+	}
+}
+
+// chainWriter adds the chain to the response written by a handler.
+type chainWriter struct {
+	ResponseWriter
+	trustPoint string
+	ex         Exchanger
+	address    string
+}
+
+func (w *chainWriter) WriteMsg(m *Msg) error {
+	if m.Rcode == RcodeSuccess || m.Rcode == RcodeNameError {
+		if err := AppendChain(m, w.trustPoint, w.ex, w.address); err != nil {
+			m.removeChain()
+		}
+	}
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// VerifyChain verifies the response r to a CHAIN query: the DNSKEY RRset of
+// the closest trust point in its CHAIN option must be signed by one of the
+// anchors, e.g. the Anchors of a TrustAnchor, and the DS and DNSKEY RRsets
+// included must lead from it to the keys of the zones that signed the answer.
+// Each RRset of the Answer section must then be signed by the keys of a zone
+// at or above its owner name.
+func VerifyChain(r *Msg, anchors []*DNSKEY, now time.Time) error {
+	tp, ok := r.ChainTrustPoint()
+	if !ok {
+		return errChainNoOption
+	}
+	tp = strings.ToLower(tp)
+	if len(chainSigners(r)) == 0 {
+		return errChainUnsigned
+	}
+
+	rrsets := make(map[chainKey][]RR)
+	sigs := make(map[chainKey][]*RRSIG)
+	for _, section := range [][]RR{r.Answer, r.Ns} {
+		for _, rr := range section {
+			h := rr.Header()
+			if sig, ok := rr.(*RRSIG); ok {
+				k := chainKey{strings.ToLower(h.Name), sig.TypeCovered}
+				sigs[k] = append(sigs[k], sig)
+				continue
+			}
+			k := chainKey{strings.ToLower(h.Name), h.Rrtype}
+			if !containsRR(rrsets[k], rr) {
+				rrsets[k] = append(rrsets[k], rr)
+			}
+		}
+	}
+
+	// The keys of the trust point, signed by an anchor.
+	keySet := rrsets[chainKey{tp, TypeDNSKEY}]
+	if verifyRRset(anchors, keySet, sigs[chainKey{tp, TypeDNSKEY}], tp, now) != nil {
+		return errChainBroken
+	}
+	zoneKeys := map[string][]*DNSKEY{tp: chainKeys(keySet)}
+
+	for k, rrset := range rrsets {
+		if !chainInAnswer(r, k) {
+			continue
+		}
+		if len(sigs[k]) == 0 {
+			return ErrNoSig
+		}
+		err := error(errChainBroken)
+		for _, sig := range sigs[k] {
+			// Only a zone at or above the owner name may sign the RRset.
+			signer := strings.ToLower(Fqdn(sig.SignerName))
+			if !IsSubDomain(signer, k.name) || !IsSubDomain(tp, signer) {
+				continue
+			}
+			keys, ok := chainZoneKeys(zoneKeys, rrsets, sigs, tp, signer, now)
+			if !ok {
+				continue
+			}
+			if err = verifyRRset(keys, rrset, []*RRSIG{sig}, signer, now); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chainZoneKeys returns the keys of the zone signer, following the DS and
+// DNSKEY RRsets of rrsets down the zone cuts from the trust point tp. The
+// keys of the zones verified are kept in zoneKeys. It returns false if
+// there is no chain of trust to signer.
+func chainZoneKeys(zoneKeys map[string][]*DNSKEY, rrsets map[chainKey][]RR, sigs map[chainKey][]*RRSIG, tp, signer string, now time.Time) ([]*DNSKEY, bool) {
+	if keys, ok := zoneKeys[signer]; ok {
+		return keys, true
+	}
+	zone, keys := tp, zoneKeys[tp]
+	labels := Split(signer)
+	for i := len(labels) - CountLabel(tp) - 1; i >= 0 && zone != signer; i-- {
+		name := signer[labels[i]:]
+		if child, ok := zoneKeys[name]; ok {
+			zone, keys = name, child
+			continue
+		}
+		k := chainKey{name, TypeDS}
+		ds := rrsets[k]
+		if len(ds) == 0 {
+			continue
+		}
+		if verifyRRset(keys, ds, sigs[k], zone, now) != nil {
+			return nil, false
+		}
+		k = chainKey{name, TypeDNSKEY}
+		childSet := rrsets[k]
+		child := chainKeys(childSet)
+		if verifyRRset(matchDS(child, ds), childSet, sigs[k], name, now) != nil {
+			return nil, false
+		}
+		zoneKeys[name] = child
+		zone, keys = name, child
+	}
+	return keys, zone == signer
+}
+
+// chainKey is the owner name, lower cased, and the type of an RRset.
+type chainKey struct {
+	name   string
+	rrtype uint16
+}
+
+// chainInAnswer reports whether the RRset of k is in the Answer section of r.
+func chainInAnswer(r *Msg, k chainKey) bool {
+	for _, rr := range r.Answer {
+		h := rr.Header()
+		if h.Rrtype == k.rrtype && equal(h.Name, k.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// chainKeys returns the DNSKEY records of rrset.
+func chainKeys(rrset []RR) []*DNSKEY {
+	var keys []*DNSKEY
+	for _, rr := range rrset {
+		if k, ok := rr.(*DNSKEY); ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// matchDS returns the keys that match one of the DS records of ds.
+func matchDS(keys []*DNSKEY, ds []RR) []*DNSKEY {
+	var match []*DNSKEY
+	for _, k := range keys {
+		for _, rr := range ds {
+			d, ok := rr.(*DS)
+			if !ok || d.KeyTag != k.KeyTag() || d.Algorithm != k.Algorithm {
+				continue
+			}
+			if kd := k.ToDS(d.DigestType); kd != nil && strings.EqualFold(kd.Digest, d.Digest) {
+				match = append(match, k)
+				break
+			}
+		}
+	}
+	return match
+}
+
+// verifyRRset checks that one of sigs is a valid signature of rrset by signer
+// with one of keys at time now.
+func verifyRRset(keys []*DNSKEY, rrset []RR, sigs []*RRSIG, signer string, now time.Time) error {
+	if len(rrset) == 0 {
+		return errChainBroken
+	}
+	if len(sigs) == 0 {
+		return ErrNoSig
+	}
+	for _, sig := range sigs {
+		if !equal(sig.SignerName, signer) || !sig.ValidityPeriod(now) {
+			continue
+		}
+		for _, k := range keys {
+			if sig.KeyTag == k.KeyTag() && sig.Algorithm == k.Algorithm && sig.Verify(k, rrset) == nil {
+				return nil
+			}
+		}
+	}
+	return ErrSig
+}
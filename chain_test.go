@@ -0,0 +1,166 @@
+package dns
+
+import (
+	"crypto"
+	"testing"
+	"time"
+)
+
+// chainZone is a signed zone of the chain tests.
+type chainZone struct {
+	key    *DNSKEY
+	signer crypto.Signer
+}
+
+func newChainZone(t *testing.T, name string) *chainZone {
+	key := &DNSKEY{
+		Hdr:       RR_Header{Name: name, Rrtype: TypeDNSKEY, Class: ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &chainZone{key: key, signer: priv.(crypto.Signer)}
+}
+
+func (z *chainZone) sign(t *testing.T, rrset ...RR) []RR {
+	now := time.Now()
+	sig := &RRSIG{
+		Hdr:        RR_Header{Name: rrset[0].Header().Name, Rrtype: TypeRRSIG, Class: ClassINET, Ttl: 3600},
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.key.Hdr.Name,
+		Algorithm:  z.key.Algorithm,
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(time.Hour).Unix()),
+	}
+	if err := sig.Sign(z.signer, rrset); err != nil {
+		t.Fatal(err)
+	}
+	return append(rrset, sig)
+}
+
+// chainExchanger answers the queries of AppendChain from rrsets.
+type chainExchanger map[chainKey][]RR
+
+func (c chainExchanger) Exchange(m *Msg, address string) (*Msg, time.Duration, error) {
+	r := new(Msg)
+	r.SetReply(m)
+	r.Answer = c[chainKey{m.Question[0].Name, m.Question[0].Qtype}]
+	return r, 0, nil
+}
+
+func TestChainQuery(t *testing.T) {
+	parent, child := newChainZone(t, "example."), newChainZone(t, "sub.example.")
+	ex := chainExchanger{
+		{"example.", TypeDNSKEY}:     parent.sign(t, parent.key),
+		{"sub.example.", TypeDS}:     parent.sign(t, child.key.ToDS(SHA256)),
+		{"sub.example.", TypeDNSKEY}: child.sign(t, child.key),
+	}
+
+	q := new(Msg)
+	q.SetQuestion("www.sub.example.", TypeA)
+	q.SetEdns0(4096, true)
+	q.SetChain("example.")
+	if tp, ok := q.ChainTrustPoint(); !ok || tp != "example." {
+		t.Fatalf("expected the trust point example., got %q", tp)
+	}
+
+	handler := HandlerFunc(func(w ResponseWriter, r *Msg) {
+		m := new(Msg)
+		m.SetReply(r)
+		m.SetEdns0(4096, true)
+		m.Answer = child.sign(t, testRR("www.sub.example. 3600 IN A 192.0.2.1"))
+		w.WriteMsg(m)
+	})
+	rec := &recordWriter{}
+	ChainMiddleware(ex, "192.0.2.53:53")(handler).ServeDNS(rec, q)
+	if len(rec.msgs) != 1 {
+		t.Fatal("expected a response")
+	}
+	r := rec.msgs[0]
+	if len(r.Ns) != 6 {
+		t.Fatalf("expected 3 RRsets with their RRSIGs in the authority section, got %v", r.Ns)
+	}
+
+	// Round trip through the wire format.
+	buf, err := r.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = new(Msg)
+	if err := r.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChain(r, []*DNSKEY{parent.key}, time.Now()); err != nil {
+		t.Errorf("expected the chain to verify, got %v", err)
+	}
+	if err := VerifyChain(r, []*DNSKEY{child.key}, time.Now()); err == nil {
+		t.Error("expected the chain not to verify with another anchor")
+	}
+
+	// A forged answer.
+	r.Answer[0].(*A).A[3] = 2
+	if err := VerifyChain(r, []*DNSKEY{parent.key}, time.Now()); err == nil {
+		t.Error("expected a forged answer not to verify")
+	}
+
+	// A broken chain: the DS record is missing, the response has no CHAIN
+	// option.
+	delete(ex, chainKey{"sub.example.", TypeDS})
+	rec = &recordWriter{}
+	ChainMiddleware(ex, "192.0.2.53:53")(handler).ServeDNS(rec, q)
+	if _, ok := rec.msgs[0].ChainTrustPoint(); ok {
+		t.Error("expected no CHAIN option without the chain")
+	}
+}
+
+func TestChainSigners(t *testing.T) {
+	parent := newChainZone(t, "example.")
+	sub, other := newChainZone(t, "sub.example."), newChainZone(t, "other.example.")
+	ex := chainExchanger{
+		{"example.", TypeDNSKEY}:       parent.sign(t, parent.key),
+		{"sub.example.", TypeDS}:       parent.sign(t, sub.key.ToDS(SHA256)),
+		{"sub.example.", TypeDNSKEY}:   sub.sign(t, sub.key),
+		{"other.example.", TypeDS}:     parent.sign(t, other.key.ToDS(SHA256)),
+		{"other.example.", TypeDNSKEY}: other.sign(t, other.key),
+	}
+
+	chain := func(answer ...[]RR) *Msg {
+		r := new(Msg)
+		r.SetQuestion("www.sub.example.", TypeA)
+		r.SetEdns0(4096, true)
+		for _, rrs := range answer {
+			r.Answer = append(r.Answer, rrs...)
+		}
+		if err := AppendChain(r, "example.", ex, "192.0.2.53:53"); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// A CNAME to another zone: each RRset is verified with the keys of its
+	// own signer.
+	r := chain(
+		sub.sign(t, testRR("www.sub.example. 3600 IN CNAME www.other.example.")),
+		other.sign(t, testRR("www.other.example. 3600 IN A 192.0.2.1")),
+	)
+	if err := VerifyChain(r, []*DNSKEY{parent.key}, time.Now()); err != nil {
+		t.Errorf("expected the chains of both signers to verify, got %v", err)
+	}
+
+	// A zone signing a name it isn't authoritative for.
+	r = chain(
+		sub.sign(t, testRR("www.sub.example. 3600 IN A 192.0.2.1")),
+		other.sign(t, testRR("www.sub.example. 3600 IN AAAA 2001:db8::1")),
+	)
+	if err := VerifyChain(r, []*DNSKEY{parent.key}, time.Now()); err == nil {
+		t.Error("expected an RRset signed by a sibling zone not to verify")
+	}
+	r = chain(other.sign(t, testRR("www.sub.example. 3600 IN A 192.0.2.1")))
+	if err := VerifyChain(r, []*DNSKEY{parent.key}, time.Now()); err == nil {
+		t.Error("expected an answer signed by a sibling zone not to verify")
+	}
+}
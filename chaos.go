@@ -0,0 +1,72 @@
+package dns
+
+import "strings"
+
+// Chaos answers the CH TXT queries servers get asked about themselves:
+// version.bind. and version.server. with Version, hostname.bind. with
+// Hostname, and id.server. with ID, or Hostname when ID is empty (RFC 4892).
+// A name whose string is empty is not answered.
+//
+// As a Handler, Chaos refuses the queries it doesn't answer. Register it for
+// the CHAOS class, or use ChaosMiddleware in front of the other handlers.
+type Chaos struct {
+	Version  string
+	Hostname string
+	ID       string
+	Refuse   bool // if true, ChaosMiddleware refuses the CHAOS queries it doesn't answer, instead of passing them on
+}
+
+// text returns the string to answer the query for name with.
+func (c *Chaos) text(name string) string {
+	switch strings.ToLower(name) {
+	case "version.bind.", "version.server.":
+		return c.Version
+	case "hostname.bind.":
+		return c.Hostname
+	case "id.server.":
+		if c.ID != "" {
+			return c.ID
+		}
+		return c.Hostname
+	}
+	return ""
+}
+
+// answers reports whether c answers the query r.
+func (c *Chaos) answers(r *Msg) bool {
+	return len(r.Question) == 1 && r.Question[0].Qclass == ClassCHAOS && c.text(r.Question[0].Name) != ""
+}
+
+// ServeDNS implements the Handler interface.
+func (c *Chaos) ServeDNS(w ResponseWriter, r *Msg) {
+	m := new(Msg)
+	if !c.answers(r) {
+		m.SetRcode(r, RcodeRefused)
+		w.WriteMsg(m)
+		return
+	}
+	m.SetReply(r)
+	m.Authoritative = true
+	q := r.Question[0]
+	if q.Qtype == TypeTXT || q.Qtype == TypeANY {
+		m.Answer = []RR{&TXT{
+			Hdr: RR_Header{Name: q.Name, Rrtype: TypeTXT, Class: ClassCHAOS},
+			Txt: splitN(c.text(q.Name), 255),
+		}}
+	}
+	w.WriteMsg(m)
+}
+
+// ChaosMiddleware answers the queries c answers, see Chaos, and passes the
+// others on. CHAOS queries are refused instead when c.Refuse is set.
+func ChaosMiddleware(c *Chaos) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Msg) {
+			if c.answers(r) || c.Refuse && len(r.Question) > 0 && r.Question[0].Qclass == ClassCHAOS {
+				c.ServeDNS(w, r)
+				return
+			}
+			next.ServeDNS(w, r)
+		})
+	}
+}
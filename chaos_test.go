@@ -0,0 +1,65 @@
+package dns
+
+import "testing"
+
+func TestChaos(t *testing.T) {
+	c := &Chaos{Version: "1.0", Hostname: "ns1"}
+	for _, tc := range []struct {
+		name  string
+		class uint16
+		txt   string
+		rcode int
+	}{
+		{"version.bind.", ClassCHAOS, "1.0", RcodeSuccess},
+		{"VERSION.server.", ClassCHAOS, "1.0", RcodeSuccess},
+		{"hostname.bind.", ClassCHAOS, "ns1", RcodeSuccess},
+		{"id.server.", ClassCHAOS, "ns1", RcodeSuccess},
+		{"authors.bind.", ClassCHAOS, "", RcodeRefused},
+		{"version.bind.", ClassINET, "", RcodeRefused},
+	} {
+		r := new(Msg)
+		r.SetQuestion(tc.name, TypeTXT)
+		r.Question[0].Qclass = tc.class
+		w := &recordWriter{}
+		c.ServeDNS(w, r)
+		m := w.msgs[0]
+		if m.Rcode != tc.rcode {
+			t.Errorf("%s: expected rcode %d, got %d", tc.name, tc.rcode, m.Rcode)
+			continue
+		}
+		if tc.txt == "" {
+			continue
+		}
+		if len(m.Answer) != 1 || m.Answer[0].Header().Class != ClassCHAOS || m.Answer[0].(*TXT).Txt[0] != tc.txt {
+			t.Errorf("%s: expected TXT %q, got %v", tc.name, tc.txt, m.Answer)
+		}
+	}
+}
+
+func TestChaosMiddleware(t *testing.T) {
+	c := &Chaos{ID: "a"}
+	h := ChaosMiddleware(c)(HandlerFunc(func(w ResponseWriter, r *Msg) {
+		m := new(Msg)
+		m.SetRcode(r, RcodeNotImplemented)
+		w.WriteMsg(m)
+	}))
+	serve := func(name string) int {
+		r := new(Msg)
+		r.SetQuestion(name, TypeTXT)
+		r.Question[0].Qclass = ClassCHAOS
+		w := &recordWriter{}
+		h.ServeDNS(w, r)
+		return w.msgs[0].Rcode
+	}
+
+	if rcode := serve("id.server."); rcode != RcodeSuccess {
+		t.Errorf("expected id.server. answered, got rcode %d", rcode)
+	}
+	if rcode := serve("version.bind."); rcode != RcodeNotImplemented {
+		t.Errorf("expected version.bind. passed on, got rcode %d", rcode)
+	}
+	c.Refuse = true
+	if rcode := serve("version.bind."); rcode != RcodeRefused {
+		t.Errorf("expected version.bind. refused, got rcode %d", rcode)
+	}
+}
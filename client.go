@@ -5,6 +5,7 @@ package dns
 import (
 	"bytes"
 	"context"
+	crand "crypto/rand"
 	"crypto/tls"
 	"encoding/binary"
 	"io"
@@ -23,7 +24,10 @@ type Conn struct {
 	net.Conn                         // a net.Conn holding the connection
 	UDPSize        uint16            // minimum receive buffer for UDP messages
 	TsigSecret     map[string]string // secret(s) for Tsig map[<zonename>]<base64 secret>, zonename must be in canonical form (lowercase, fqdn, see RFC 4034 Section 6.2)
+	TsigProvider   TsigProvider      // An implementation of the TsigProvider interface. If defined it replaces TsigSecret and is used for all TSIG operations.
 	tsigRequestMAC string
+	tap            func(msg []byte, query bool) // logs the messages written and read, if set
+	spoofCheck     bool                         // if true, the source of UDP responses is checked
 }
 
 // A Client defines parameters for a DNS client.
@@ -40,7 +44,18 @@ type Client struct {
 	ReadTimeout    time.Duration     // net.Conn.SetReadTimeout value for connections, defaults to 2 seconds - overridden by Timeout when that value is non-zero
 	WriteTimeout   time.Duration     // net.Conn.SetWriteTimeout value for connections, defaults to 2 seconds - overridden by Timeout when that value is non-zero
 	TsigSecret     map[string]string // secret(s) for Tsig map[<zonename>]<base64 secret>, zonename must be in canonical form (lowercase, fqdn, see RFC 4034 Section 6.2)
+	TsigProvider   TsigProvider      // An implementation of the TsigProvider interface. If defined it replaces TsigSecret and is used for all TSIG operations.
 	SingleInflight bool              // if true suppress multiple outstanding queries for the same Qname, Qtype and Qclass
+	Coalesce       bool              // if true, identical queries in flight to the same address, EDNS0 options included, are sent once, see ExchangeAsync
+	Pool           *ConnPool         // if set, TCP and TLS queries without TSIG are multiplexed over the pooled connections
+	Retry          *RetryPolicy      // if set, Exchange retries failed queries as it describes
+	Randomize0x20  bool              // if true, the case of query names is randomized, and responses must echo it (draft-vixie-dnsext-dns0x20)
+	Dnstap         DnstapSink        // if set, the queries and responses are logged as RESOLVER_QUERY and RESOLVER_RESPONSE messages
+	SocketOptions  *SocketOptions    // if set, the options of the sockets of the queries
+	SpoofCheck     bool              // if true, query IDs are from crypto/rand, and responses must match the source, ID and question of their query, see SpoofError
+	SpoofMonitor   func(*SpoofError) // if set, called with the responses rejected by SpoofCheck
+	HappyEyeballs  bool              // if true, the addresses of a server given by host name are raced, IPv6 first, as ExchangeAddrs does (RFC 8305)
+	FallbackDelay  time.Duration     // delay before trying the next address when racing them, defaults to 250 milliseconds
 	group          singleflight
 }
 
@@ -78,19 +93,81 @@ func (c *Client) writeTimeout() time.Duration {
 	return dnsTimeout
 }
 
+// attempt holds the settings of a single try of a query that differ from the
+// ones of the Client, as set by its RetryPolicy.
+type attempt struct {
+	net     string          // network, overrides Client.Net when set
+	timeout time.Duration   // overrides the dial, read and write timeouts when non-zero
+	src     *Source         // overrides the local address of Client.Dialer when set
+	ctx     context.Context // if set, cancels the try when done, e.g. when racing addresses
+}
+
+// done returns the Done channel of at.ctx, nil without context.
+func (at attempt) done() <-chan struct{} {
+	if at.ctx == nil {
+		return nil
+	}
+	return at.ctx.Done()
+}
+
+// A Source is the local end a query is sent from.
+type Source struct {
+	Addr   net.IP // local address, nil to let the system choose
+	Device string // network interface to send from, see SocketOptions.Device, empty for any
+}
+
+func (c *Client) network(at attempt) string {
+	if at.net != "" {
+		return at.net
+	}
+	if c.Net == "" {
+		return "udp"
+	}
+	return c.Net
+}
+
+func (c *Client) attemptTimeout(at attempt, timeout time.Duration) time.Duration {
+	if at.timeout != 0 {
+		return at.timeout
+	}
+	return c.getTimeoutForRequest(timeout)
+}
+
 // Dial connects to the address on the named network.
 func (c *Client) Dial(address string) (conn *Conn, err error) {
+	return c.dial(address, attempt{})
+}
+
+func (c *Client) dial(address string, at attempt) (conn *Conn, err error) {
 	// create a new dialer with the appropriate timeout
 	var d net.Dialer
 	if c.Dialer == nil {
-		d = net.Dialer{Timeout: c.getTimeoutForRequest(c.dialTimeout())}
+		d = net.Dialer{Timeout: c.attemptTimeout(at, c.dialTimeout())}
 	} else {
 		d = *c.Dialer
+		if at.timeout != 0 && (d.Timeout == 0 || d.Timeout > at.timeout) {
+			d.Timeout = at.timeout
+		}
 	}
 
-	network := c.Net
-	if network == "" {
-		network = "udp"
+	network := c.network(at)
+
+	opts := c.SocketOptions
+	if at.src != nil {
+		d.LocalAddr = at.src.localAddr(network)
+		if at.src.Device != "" {
+			o := SocketOptions{}
+			if opts != nil {
+				o = *opts
+			}
+			o.Device = at.src.Device
+			opts = &o
+		}
+	}
+	if opts != nil {
+		if err := opts.controlDialer(&d); err != nil {
+			return nil, err
+		}
 	}
 
 	useTLS := strings.HasPrefix(network, "tcp") && strings.HasSuffix(network, "-tls")
@@ -99,7 +176,13 @@ func (c *Client) Dial(address string) (conn *Conn, err error) {
 	if useTLS {
 		network = strings.TrimSuffix(network, "-tls")
 
-		conn.Conn, err = tls.DialWithDialer(&d, network, address, c.TLSConfig)
+		if at.ctx != nil {
+			conn.Conn, err = dialTLSContext(at.ctx, &d, network, address, c.TLSConfig)
+		} else {
+			conn.Conn, err = tls.DialWithDialer(&d, network, address, c.TLSConfig)
+		}
+	} else if at.ctx != nil {
+		conn.Conn, err = d.DialContext(at.ctx, network, address)
 	} else {
 		conn.Conn, err = d.Dial(network, address)
 	}
@@ -110,6 +193,46 @@ func (c *Client) Dial(address string) (conn *Conn, err error) {
 	return conn, nil
 }
 
+// dialTLSContext is tls.DialWithDialer, but gives up when ctx is done.
+func dialTLSContext(ctx context.Context, d *net.Dialer, network, address string, config *tls.Config) (net.Conn, error) {
+	raw, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = new(tls.Config)
+	}
+	if config.ServerName == "" {
+		host, _, _ := net.SplitHostPort(address)
+		config = config.Clone()
+		config.ServerName = host
+	}
+	if d.Timeout != 0 {
+		raw.SetDeadline(time.Now().Add(d.Timeout))
+	}
+
+	conn := tls.Client(raw, config)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			raw.Close()
+		case <-done:
+		}
+	}()
+	err = conn.Handshake()
+	close(done)
+	if err != nil || ctx.Err() != nil {
+		raw.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	raw.SetDeadline(time.Time{})
+	return conn, nil
+}
+
 // Exchange performs a synchronous query. It sends the message m to the address
 // contained in a and waits for a reply. Basic use pattern with a *dns.Client:
 //
@@ -117,16 +240,55 @@ func (c *Client) Dial(address string) (conn *Conn, err error) {
 //	in, rtt, err := c.Exchange(message, "127.0.0.1:53")
 //
 // Exchange does not retry a failed query, nor will it fall back to TCP in
-// case of truncation.
+// case of truncation, unless the Client has a RetryPolicy.
 // It is up to the caller to create a message that allows for larger responses to be
 // returned. Specifically this means adding an EDNS0 OPT RR that will advertise a larger
 // buffer, see SetEdns0. Messages without an OPT RR will fallback to the historic limit
 // of 512 bytes
 // To specify a local address or a timeout, the caller has to set the `Client.Dialer`
-// attribute appropriately
+// attribute appropriately, or use ExchangeFrom for the local address of a single query
 func (c *Client) Exchange(m *Msg, address string) (r *Msg, rtt time.Duration, err error) {
+	if c.Retry != nil {
+		return c.Retry.exchange(c, m, address, attempt{})
+	}
+	return c.exchangeShared(m, address, attempt{})
+}
+
+// ExchangeFrom is like Exchange, but sends the query from src instead of the
+// local address of the Client's Dialer, e.g. to test the reachability of a
+// server from each address of a host with a single Client. Queries from a
+// source don't use the Client's Pool.
+func (c *Client) ExchangeFrom(m *Msg, src Source, address string) (r *Msg, rtt time.Duration, err error) {
+	at := attempt{src: &src}
+	if c.Retry != nil {
+		return c.Retry.exchange(c, m, address, at)
+	}
+	return c.exchangeShared(m, address, at)
+}
+
+// localAddr returns the local address of s for network, or nil.
+func (s *Source) localAddr(network string) net.Addr {
+	if s.Addr == nil {
+		return nil
+	}
+	zone := ""
+	if s.Addr.IsLinkLocalUnicast() && s.Addr.To4() == nil {
+		zone = s.Device
+	}
+	if strings.HasPrefix(network, "tcp") {
+		return &net.TCPAddr{IP: s.Addr, Zone: zone}
+	}
+	return &net.UDPAddr{IP: s.Addr, Zone: zone}
+}
+
+// exchangeShared performs a single try of a query, shared with the identical
+// tries in flight if c.Coalesce or c.SingleInflight is set.
+func (c *Client) exchangeShared(m *Msg, address string, at attempt) (r *Msg, rtt time.Duration, err error) {
+	if c.Coalesce {
+		return c.exchangeCoalesced(m, address, at)
+	}
 	if !c.SingleInflight {
-		return c.exchange(m, address)
+		return c.exchange(m, address, at)
 	}
 
 	t := "nop"
@@ -137,8 +299,12 @@ func (c *Client) Exchange(m *Msg, address string) (r *Msg, rtt time.Duration, er
 	if cl1, ok := ClassToString[m.Question[0].Qclass]; ok {
 		cl = cl1
 	}
-	r, rtt, err, shared := c.group.Do(m.Question[0].Name+t+cl, func() (*Msg, time.Duration, error) {
-		return c.exchange(m, address)
+	src := ""
+	if at.src != nil {
+		src = at.src.Addr.String() + "%" + at.src.Device
+	}
+	r, rtt, err, shared := c.group.Do(m.Question[0].Name+t+cl+at.net+src, func() (*Msg, time.Duration, error) {
+		return c.exchange(m, address, at)
 	})
 	if r != nil && shared {
 		r = r.Copy()
@@ -146,15 +312,70 @@ func (c *Client) Exchange(m *Msg, address string) (r *Msg, rtt time.Duration, er
 	return r, rtt, err
 }
 
-func (c *Client) exchange(m *Msg, a string) (r *Msg, rtt time.Duration, err error) {
+func (c *Client) exchange(m *Msg, a string, at attempt) (r *Msg, rtt time.Duration, err error) {
+	if c.HappyEyeballs {
+		addrs, ok, err := c.happyEyeballs(a)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			return c.exchangeRace(m, addrs, at)
+		}
+	}
+	q := m
+	randomized := c.Randomize0x20 && len(m.Question) > 0
+	if randomized {
+		// m is copied as far as needed to leave the caller's query alone.
+		mm := *m
+		mm.Question = append([]Question(nil), m.Question...)
+		mm.Question[0].Name = randomizeCase(m.Question[0].Name)
+		q = &mm
+	}
+	if c.SpoofCheck {
+		mm := *q
+		mm.Id = secureId()
+		q = &mm
+	}
+
+	if c.Pool != nil && strings.HasPrefix(c.network(at), "tcp") && q.IsTsig() == nil && at.src == nil {
+		r, rtt, err = c.Pool.exchange(c, q, a, at)
+	} else {
+		r, rtt, err = c.exchangeConn(q, a, at)
+	}
+
+	if err == nil && randomized {
+		if len(r.Question) == 0 || r.Question[0].Name != q.Question[0].Name {
+			return r, rtt, ErrQuestion
+		}
+		r.Question[0].Name = m.Question[0].Name
+	}
+	if err == nil && c.SpoofCheck {
+		r.Id = m.Id
+	}
+	return r, rtt, err
+}
+
+// exchangeConn sends m to a over a new connection and reads the response.
+func (c *Client) exchangeConn(m *Msg, a string, at attempt) (r *Msg, rtt time.Duration, err error) {
 	var co *Conn
 
-	co, err = c.Dial(a)
+	co, err = c.dial(a, at)
 
 	if err != nil {
 		return nil, 0, err
 	}
 	defer co.Close()
+	if at.ctx != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-at.ctx.Done():
+				co.Close()
+			case <-done:
+			}
+		}()
+	}
 
 	opt := m.IsEdns0()
 	// If EDNS0 is used use that for size.
@@ -166,23 +387,55 @@ func (c *Client) exchange(m *Msg, a string) (r *Msg, rtt time.Duration, err erro
 		co.UDPSize = c.UDPSize
 	}
 
-	co.TsigSecret = c.TsigSecret
+	co.TsigSecret, co.TsigProvider = c.TsigSecret, c.TsigProvider
+	if c.Dnstap != nil {
+		co.tap = c.dnstapTap(co)
+	}
 	t := time.Now()
 	// write with the appropriate write timeout
-	co.SetWriteDeadline(t.Add(c.getTimeoutForRequest(c.writeTimeout())))
+	co.SetWriteDeadline(t.Add(c.attemptTimeout(at, c.writeTimeout())))
 	if err = co.WriteMsg(m); err != nil {
 		return nil, 0, err
 	}
 
-	co.SetReadDeadline(time.Now().Add(c.getTimeoutForRequest(c.readTimeout())))
-	r, err = co.ReadMsg()
-	if err == nil && r.Id != m.Id {
-		err = ErrId
+	co.SetReadDeadline(time.Now().Add(c.attemptTimeout(at, c.readTimeout())))
+	if c.SpoofCheck {
+		r, err = c.readChecked(co, m)
+	} else {
+		r, err = co.ReadMsg()
+		if err == nil && r.Id != m.Id {
+			err = ErrId
+		}
 	}
 	rtt = time.Since(t)
 	return r, rtt, err
 }
 
+// randomizeCase returns name with the case of its letters randomized, so
+// spoofed responses are unlikely to echo it (draft-vixie-dnsext-dns0x20).
+func randomizeCase(name string) string {
+	b := []byte(name)
+	bits := make([]byte, (len(b)+7)/8)
+	if _, err := crand.Read(bits); err != nil {
+		return name
+	}
+	for i, c := range b {
+		if ('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') && bits[i/8]&(1<<uint(i%8)) != 0 {
+			b[i] ^= 0x20
+		}
+	}
+	return string(b)
+}
+
+// tsigProvider returns the TsigProvider of co, or one wrapping its TsigSecret.
+func (co *Conn) tsigProvider() TsigProvider {
+	if co.TsigProvider != nil {
+		return co.TsigProvider
+	}
+	// tsigSecretProvider will return ErrSecret if co.TsigSecret is nil.
+	return tsigSecretProvider(co.TsigSecret)
+}
+
 // ReadMsg reads a message from the connection co.
 // If the received message contains a TSIG record the transaction signature
 // is verified. This method always tries to return the message, however if an
@@ -193,6 +446,9 @@ func (co *Conn) ReadMsg() (*Msg, error) {
 	if err != nil {
 		return nil, err
 	}
+	if co.tap != nil {
+		co.tap(p, false)
+	}
 
 	m := new(Msg)
 	if err := m.Unpack(p); err != nil {
@@ -202,11 +458,8 @@ func (co *Conn) ReadMsg() (*Msg, error) {
 		return m, err
 	}
 	if t := m.IsTsig(); t != nil {
-		if _, ok := co.TsigSecret[t.Hdr.Name]; !ok {
-			return m, ErrSecret
-		}
 		// Need to work on the original message p, as that was used to calculate the tsig.
-		err = TsigVerify(p, co.TsigSecret[t.Hdr.Name], co.tsigRequestMAC, false)
+		err = TsigVerifyWithProvider(p, co.tsigProvider(), co.tsigRequestMAC, false)
 	}
 	return m, err
 }
@@ -325,6 +578,13 @@ func (co *Conn) Read(p []byte) (n int, err error) {
 		return tcpRead(r, p[:l])
 	}
 	// UDP connection
+	if pc, ok := co.Conn.(net.PacketConn); ok && co.spoofCheck {
+		n, from, err := pc.ReadFrom(p)
+		if err != nil {
+			return n, err
+		}
+		return n, co.checkSource(from)
+	}
 	n, err = co.Conn.Read(p)
 	if err != nil {
 		return n, err
@@ -339,10 +599,7 @@ func (co *Conn) WriteMsg(m *Msg) (err error) {
 	var out []byte
 	if t := m.IsTsig(); t != nil {
 		mac := ""
-		if _, ok := co.TsigSecret[t.Hdr.Name]; !ok {
-			return ErrSecret
-		}
-		out, mac, err = TsigGenerate(m, co.TsigSecret[t.Hdr.Name], co.tsigRequestMAC, false)
+		out, mac, err = TsigGenerateWithProvider(m, co.tsigProvider(), co.tsigRequestMAC, false)
 		// Set for the next read, although only used in zone transfers
 		co.tsigRequestMAC = mac
 	} else {
@@ -354,6 +611,9 @@ func (co *Conn) WriteMsg(m *Msg) (err error) {
 	if _, err = co.Write(out); err != nil {
 		return err
 	}
+	if co.tap != nil {
+		co.tap(out, true)
+	}
 	return nil
 }
 
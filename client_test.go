@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"runtime"
 	"strconv"
 	"strings"
 	"testing"
@@ -103,6 +104,32 @@ func TestClientLocalAddress(t *testing.T) {
 	}
 }
 
+func TestClientExchangeFrom(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("127.0.0.2 is only local on Linux")
+	}
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = HandlerFunc(HelloServerEchoAddrPort)
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	c := new(Client)
+	for _, ip := range []string{"127.0.0.2", "127.0.0.3"} {
+		r, _, err := c.ExchangeFrom(m, Source{Addr: net.ParseIP(ip)}, addrstr)
+		if err != nil {
+			t.Fatalf("failed to exchange: %v", err)
+		}
+		if txt := r.Extra[0].(*TXT).Txt[0]; !strings.HasPrefix(txt, ip+":") {
+			t.Errorf("expected the query from %s, got %s", ip, txt)
+		}
+	}
+}
+
 func TestClientTLSSyncV4(t *testing.T) {
 	HandleFunc("miek.nl.", HelloServer)
 	defer HandleRemove("miek.nl.")
@@ -176,7 +203,8 @@ func TestClientSyncBadID(t *testing.T) {
 	m.SetQuestion("miek.nl.", TypeSOA)
 
 	c := new(Client)
-	if _, _, err := c.Exchange(m, addrstr); err != ErrId {
+	_, _, err = c.Exchange(m, addrstr)
+	if err != ErrId {
 		t.Errorf("did not find a bad Id")
 	}
 	// And now with plain Exchange().
@@ -565,3 +593,44 @@ func TestConcurrentExchanges(t *testing.T) {
 		}
 	}
 }
+
+func TestClientRandomize0x20(t *testing.T) {
+	const name = "abcdefghijklmnopqrstuvwxyz.example."
+	seen := make(chan string, 2)
+	HandleFunc("example.", func(w ResponseWriter, req *Msg) {
+		seen <- req.Question[0].Name
+		m := new(Msg)
+		m.SetReply(req)
+		if strings.HasPrefix(strings.ToLower(req.Question[0].Name), "broken.") {
+			m.Question[0].Name = strings.ToLower(req.Question[0].Name)
+		}
+		w.WriteMsg(m)
+	})
+	defer HandleRemove("example.")
+
+	s, addrstr, err := RunLocalUDPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	c := &Client{Randomize0x20: true}
+	m := new(Msg)
+	m.SetQuestion(name, TypeA)
+	r, _, err := c.Exchange(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if sent := <-seen; sent == name || !strings.EqualFold(sent, name) {
+		t.Errorf("expected query name with randomized case, got %s", sent)
+	}
+	if m.Question[0].Name != name || r.Question[0].Name != name {
+		t.Errorf("expected query name to be restored, got %s and %s", m.Question[0].Name, r.Question[0].Name)
+	}
+
+	// A server that doesn't echo the case.
+	m.SetQuestion("broken."+name, TypeA)
+	if _, _, err := c.Exchange(m, addrstr); err != ErrQuestion {
+		t.Errorf("expected ErrQuestion, got %v", err)
+	}
+}
@@ -3,9 +3,12 @@ package dns
 import (
 	"bufio"
 	"io"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // ClientConfig wraps the contents of the /etc/resolv.conf file.
@@ -15,7 +18,18 @@ type ClientConfig struct {
 	Port     string   // what port to use
 	Ndots    int      // number of dots in name to trigger absolute lookup
 	Timeout  int      // seconds before giving up on packet
-	Attempts int      // lost packets before giving up on server, not used in the package dns
+	Attempts int      // lost packets before giving up on server
+
+	Rotate              bool         // "rotate": spread the queries over the servers round robin
+	SingleRequest       bool         // "single-request": send the A and AAAA queries one after the other
+	SingleRequestReopen bool         // "single-request-reopen": send the A and AAAA queries over different sockets
+	UseVC               bool         // "use-vc": send the queries over TCP
+	TrustAD             bool         // "trust-ad": set the AD bit in queries, and trust it in responses
+	NoReload            bool         // "no-reload": don't reload the file when it changes
+	EDNS0               bool         // "edns0": add an OPT record to queries
+	Sortlist            []*net.IPNet // networks to sort the addresses of responses by, from "sortlist"
+
+	rotate uint32 // next server with Rotate
 }
 
 // ClientConfigFromFile parses a resolv.conf(5) like file and returns
@@ -31,14 +45,8 @@ func ClientConfigFromFile(resolvconf string) (*ClientConfig, error) {
 
 // ClientConfigFromReader works like ClientConfigFromFile but takes an io.Reader as argument
 func ClientConfigFromReader(resolvconf io.Reader) (*ClientConfig, error) {
-	c := new(ClientConfig)
+	c := newClientConfig()
 	scanner := bufio.NewScanner(resolvconf)
-	c.Servers = make([]string, 0)
-	c.Search = make([]string, 0)
-	c.Port = "53"
-	c.Ndots = 1
-	c.Timeout = 5
-	c.Attempts = 2
 
 	for scanner.Scan() {
 		if err := scanner.Err(); err != nil {
@@ -73,39 +81,181 @@ func ClientConfigFromReader(resolvconf io.Reader) (*ClientConfig, error) {
 				c.Search[i] = f[i+1]
 			}
 
-		case "options": // magic options
-			for i := 1; i < len(f); i++ {
-				s := f[i]
-				switch {
-				case len(s) >= 6 && s[:6] == "ndots:":
-					n, _ := strconv.Atoi(s[6:])
-					if n < 0 {
-						n = 0
-					} else if n > 15 {
-						n = 15
-					}
-					c.Ndots = n
-				case len(s) >= 8 && s[:8] == "timeout:":
-					n, _ := strconv.Atoi(s[8:])
-					if n < 1 {
-						n = 1
-					}
-					c.Timeout = n
-				case len(s) >= 9 && s[:9] == "attempts:":
-					n, _ := strconv.Atoi(s[9:])
-					if n < 1 {
-						n = 1
-					}
-					c.Attempts = n
-				case s == "rotate":
-					/* not imp */
+		case "sortlist": // address/netmask pairs to sort addresses by
+			for _, p := range f[1:] {
+				if n := parseSortlist(p); n != nil {
+					c.Sortlist = append(c.Sortlist, n)
 				}
 			}
+
+		case "options": // magic options
+			c.parseOptions(f[1:])
 		}
 	}
 	return c, nil
 }
 
+// SystemClientConfig returns the DNS configuration of the system: that of
+// /etc/resolv.conf on Unix, that of the default resolver in the output of
+// "scutil --dns" on macOS, and on Windows the name servers of the network
+// adapters that are up, with the search list of the registry.
+func SystemClientConfig() (*ClientConfig, error) {
+	return systemClientConfig()
+}
+
+// newClientConfig returns a ClientConfig with the defaults of resolv.conf(5).
+func newClientConfig() *ClientConfig {
+	return &ClientConfig{
+		Servers:  make([]string, 0),
+		Search:   make([]string, 0),
+		Port:     "53",
+		Ndots:    1,
+		Timeout:  5,
+		Attempts: 2,
+	}
+}
+
+// parseOptions sets the options of an "options" line of resolv.conf(5).
+func (c *ClientConfig) parseOptions(opts []string) {
+	for _, s := range opts {
+		switch {
+		case len(s) >= 6 && s[:6] == "ndots:":
+			n, _ := strconv.Atoi(s[6:])
+			if n < 0 {
+				n = 0
+			} else if n > 15 {
+				n = 15
+			}
+			c.Ndots = n
+		case len(s) >= 8 && s[:8] == "timeout:":
+			n, _ := strconv.Atoi(s[8:])
+			if n < 1 {
+				n = 1
+			}
+			c.Timeout = n
+		case len(s) >= 9 && s[:9] == "attempts:":
+			n, _ := strconv.Atoi(s[9:])
+			if n < 1 {
+				n = 1
+			}
+			c.Attempts = n
+		case s == "rotate":
+			c.Rotate = true
+		case s == "single-request":
+			c.SingleRequest = true
+		case s == "single-request-reopen":
+			c.SingleRequestReopen = true
+		case s == "use-vc":
+			c.UseVC = true
+		case s == "trust-ad":
+			c.TrustAD = true
+		case s == "no-reload":
+			c.NoReload = true
+		case s == "edns0":
+			c.EDNS0 = true
+		}
+	}
+}
+
+// clientConfigFromScutil returns the configuration of the default resolver
+// in the output of "scutil --dns" on macOS: the first one with name servers
+// and without a domain, which is only used for the names in that domain.
+// Resolvers for scoped queries are ignored.
+func clientConfigFromScutil(r io.Reader) (*ClientConfig, error) {
+	var c *ClientConfig
+	var domain bool
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "resolver #") || strings.HasPrefix(line, "DNS configuration (") {
+			if c != nil && !domain && len(c.Servers) > 0 {
+				return c, nil
+			}
+			if !strings.HasPrefix(line, "resolver #") {
+				// The resolvers for scoped queries follow.
+				break
+			}
+			c, domain = newClientConfig(), false
+			continue
+		}
+		if c == nil {
+			continue
+		}
+
+		i := strings.Index(line, " : ")
+		if i < 0 {
+			continue
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+3:])
+		if j := strings.IndexByte(key, '['); j >= 0 {
+			key = key[:j]
+		}
+		switch key {
+		case "nameserver":
+			c.Servers = append(c.Servers, value)
+		case "search domain":
+			c.Search = append(c.Search, value)
+		case "domain":
+			domain = true
+		case "port":
+			c.Port = value
+		case "timeout":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				c.Timeout = n
+			}
+		case "options":
+			c.parseOptions(strings.Fields(value))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if c != nil && !domain && len(c.Servers) > 0 {
+		return c, nil
+	}
+	return nil, &Error{err: "no default resolver in scutil output"}
+}
+
+// parseSortlist parses a sortlist entry, an address with an optional netmask,
+// e.g. "130.155.160.0/255.255.240.0", or prefix length. Without either, the
+// natural mask of the address is used.
+func parseSortlist(s string) *net.IPNet {
+	addr, mask := s, ""
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		addr, mask = s[:i], s[i+1:]
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	bits := len(ip) * 8
+	n := &net.IPNet{IP: ip}
+	switch {
+	case mask == "":
+		n.Mask = ip.DefaultMask()
+		if n.Mask == nil {
+			n.Mask = net.CIDRMask(bits, bits)
+		}
+	case strings.IndexByte(mask, '.') >= 0:
+		m := net.ParseIP(mask).To4()
+		if m == nil || bits != 32 {
+			return nil
+		}
+		n.Mask = net.IPMask(m)
+	default:
+		l, err := strconv.Atoi(mask)
+		if err != nil || l < 0 || l > bits {
+			return nil
+		}
+		n.Mask = net.CIDRMask(l, bits)
+	}
+	n.IP = n.IP.Mask(n.Mask)
+	return n
+}
+
 // NameList returns all of the names that should be queried based on the
 // config. It is based off of go's net/dns name building, but it does not
 // check the length of the resulting names.
@@ -137,3 +287,99 @@ func (c *ClientConfig) NameList(name string) []string {
 	}
 	return names
 }
+
+// ExchangeWithConfig sends the query m as the stub resolver of the C library
+// does with the configuration c. The name of the question is tried with the
+// suffixes of the search list as NameList orders them, until one of them has
+// records; a response without records is returned if none has, and the last
+// NXDOMAIN response if all names are missing. Each name is sent to the servers
+// in turn, starting at the next one with Rotate, Attempts times, with a
+// timeout of Timeout seconds. A truncated response is retried over TCP.
+//
+// With UseVC, queries are sent over TCP; with TrustAD, they have the AD bit
+// set, and the AD bit of responses is cleared without it; with EDNS0, they get
+// an OPT record if m has none.
+func ExchangeWithConfig(m *Msg, c *ClientConfig) (r *Msg, err error) {
+	if len(m.Question) == 0 {
+		return nil, &Error{err: "query without question"}
+	}
+	if len(c.Servers) == 0 {
+		return nil, &Error{err: "no servers in client config"}
+	}
+	udp := &Client{Net: "udp", Timeout: time.Duration(c.Timeout) * time.Second}
+	tcp := &Client{Net: "tcp", Timeout: udp.Timeout}
+	if c.UseVC {
+		udp = tcp
+	}
+	start := 0
+	if c.Rotate {
+		start = int((atomic.AddUint32(&c.rotate, 1) - 1) % uint32(len(c.Servers)))
+	}
+
+	var nodata *Msg
+	for _, name := range c.NameList(m.Question[0].Name) {
+		q := m.Copy()
+		q.Question[0].Name = name
+		if c.TrustAD {
+			q.AuthenticatedData = true
+		}
+		if c.EDNS0 && q.IsEdns0() == nil {
+			q.SetEdns0(1232, false)
+		}
+		r, err = c.exchange(udp, tcp, q, start)
+		if err != nil {
+			return nil, err
+		}
+		if !c.TrustAD {
+			r.AuthenticatedData = false
+		}
+		switch {
+		case r.Rcode != RcodeSuccess && r.Rcode != RcodeNameError:
+			return r, nil
+		case r.Rcode == RcodeSuccess && len(r.Answer) > 0:
+			return r, nil
+		case r.Rcode == RcodeSuccess && nodata == nil:
+			nodata = r
+		}
+	}
+	if nodata != nil {
+		return nodata, nil
+	}
+	return r, nil
+}
+
+// exchange sends m to the servers of c, starting at start, until one of them
+// answers it. The last response or error is returned if none does.
+func (c *ClientConfig) exchange(udp, tcp *Client, m *Msg, start int) (r *Msg, err error) {
+	port := c.Port
+	if port == "" {
+		port = "53"
+	}
+	attempts := c.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for a := 0; a < attempts; a++ {
+		for i := range c.Servers {
+			address := net.JoinHostPort(c.Servers[(start+i)%len(c.Servers)], port)
+			in, _, qerr := udp.Exchange(m, address)
+			if qerr == nil && in.Truncated && udp != tcp {
+				in, _, qerr = tcp.Exchange(m, address)
+			}
+			if qerr != nil {
+				err = qerr
+				continue
+			}
+			switch in.Rcode {
+			case RcodeServerFailure, RcodeNotImplemented, RcodeRefused:
+				r = in
+				continue
+			}
+			return in, nil
+		}
+	}
+	if r != nil {
+		return r, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,19 @@
+// +build darwin
+
+package dns
+
+import (
+	"bytes"
+	"os/exec"
+)
+
+// systemClientConfig reads the configuration of the default resolver from
+// scutil, as /etc/resolv.conf on macOS is only kept for compatibility, and it
+// falls back to that file when scutil can't be run.
+func systemClientConfig() (*ClientConfig, error) {
+	out, err := exec.Command("scutil", "--dns").Output()
+	if err != nil {
+		return ClientConfigFromFile("/etc/resolv.conf")
+	}
+	return clientConfigFromScutil(bytes.NewReader(out))
+}
@@ -2,9 +2,11 @@ package dns
 
 import (
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -179,3 +181,140 @@ func TestNameListNdots0(t *testing.T) {
 		t.Errorf("NameList didn't return sent domain last: %v", names[1])
 	}
 }
+
+func TestClientConfigOptions(t *testing.T) {
+	const data = `
+nameserver 192.0.2.1
+options rotate single-request single-request-reopen use-vc trust-ad no-reload edns0
+sortlist 130.155.160.0/255.255.240.0 130.155.0.0 10.0.0.0/8 2001:db8::/32 bad
+`
+	cc, err := ClientConfigFromReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("error parsing resolv.conf: %v", err)
+	}
+	if !cc.Rotate || !cc.SingleRequest || !cc.SingleRequestReopen || !cc.UseVC || !cc.TrustAD || !cc.NoReload || !cc.EDNS0 {
+		t.Errorf("options not properly parsed: %+v", cc)
+	}
+	want := []string{"130.155.160.0/20", "130.155.0.0/16", "10.0.0.0/8", "2001:db8::/32"}
+	if len(cc.Sortlist) != len(want) {
+		t.Fatalf("expected sortlist %v, got %v", want, cc.Sortlist)
+	}
+	for i, n := range cc.Sortlist {
+		if n.String() != want[i] {
+			t.Errorf("expected sortlist entry %s, got %s", want[i], n)
+		}
+	}
+}
+
+func TestExchangeWithConfig(t *testing.T) {
+	var queries []string
+	var mu sync.Mutex
+	handler := func(srv *Server) {
+		srv.Handler = HandlerFunc(func(w ResponseWriter, req *Msg) {
+			mu.Lock()
+			queries = append(queries, req.Question[0].Name)
+			mu.Unlock()
+			m := new(Msg)
+			m.SetReply(req)
+			m.AuthenticatedData = true
+			switch req.Question[0].Name {
+			case "www.example.org.":
+				m.Answer = append(m.Answer, testRR("www.example.org. 300 IN A 192.0.2.1"))
+			case "mail.example.net.":
+			case "down.example.org.":
+				m.Rcode = RcodeServerFailure
+			default:
+				m.Rcode = RcodeNameError
+			}
+			w.WriteMsg(m)
+		})
+	}
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan(":0", handler)
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+	host, port, _ := net.SplitHostPort(addrstr)
+
+	cc := &ClientConfig{Servers: []string{host}, Port: port, Search: []string{"example.net", "example.org"}, Ndots: 1, Timeout: 1, Attempts: 2}
+	for _, tc := range []struct {
+		name    string
+		rcode   int
+		answers int
+		queries []string
+	}{
+		{"www", RcodeSuccess, 1, []string{"www.example.net.", "www.example.org."}},
+		{"www.example.org", RcodeSuccess, 1, []string{"www.example.org."}},
+		{"mail", RcodeSuccess, 0, []string{"mail.example.net.", "mail.example.org.", "mail."}},
+		{"nx", RcodeNameError, 0, []string{"nx.example.net.", "nx.example.org.", "nx."}},
+		{"down", RcodeServerFailure, 0, []string{"down.example.net.", "down.example.org.", "down.example.org."}},
+	} {
+		mu.Lock()
+		queries = nil
+		mu.Unlock()
+		r, err := ExchangeWithConfig(new(Msg).SetQuestion(tc.name, TypeA), cc)
+		if err != nil {
+			t.Errorf("%s: failed to exchange: %v", tc.name, err)
+			continue
+		}
+		if r.Rcode != tc.rcode || len(r.Answer) != tc.answers || r.AuthenticatedData {
+			t.Errorf("%s: expected rcode %d with %d answers without AD, got %v", tc.name, tc.rcode, tc.answers, r)
+		}
+		mu.Lock()
+		if strings.Join(queries, " ") != strings.Join(tc.queries, " ") {
+			t.Errorf("%s: expected queries %v, got %v", tc.name, tc.queries, queries)
+		}
+		mu.Unlock()
+	}
+}
+
+const scutilDNS = `
+DNS configuration
+
+resolver #1
+  domain   : corp.example
+  nameserver[0] : 10.0.0.53
+  flags    : Request A records
+  reach    : 0x00000002 (Reachable)
+
+resolver #2
+  search domain[0] : example.org
+  search domain[1] : example.net
+  nameserver[0] : 192.0.2.1
+  nameserver[1] : fe80::1%en0
+  if_index : 6 (en0)
+  flags    : Request A records, Request AAAA records
+  reach    : 0x00020002 (Reachable,Directly Reachable Address)
+  options  : ndots:2 attempts:3
+  timeout  : 3
+
+resolver #3
+  domain   : local
+  options  : mdns
+  timeout  : 5
+  order    : 300000
+
+DNS configuration (for scoped queries)
+
+resolver #1
+  nameserver[0] : 198.51.100.1
+  if_index : 6 (en0)
+`
+
+func TestClientConfigFromScutil(t *testing.T) {
+	cc, err := clientConfigFromScutil(strings.NewReader(scutilDNS))
+	if err != nil {
+		t.Fatalf("error parsing scutil output: %v", err)
+	}
+	if strings.Join(cc.Servers, " ") != "192.0.2.1 fe80::1%en0" || strings.Join(cc.Search, " ") != "example.org example.net" {
+		t.Errorf("unexpected servers %v and search list %v", cc.Servers, cc.Search)
+	}
+	if cc.Port != "53" || cc.Ndots != 2 || cc.Attempts != 3 || cc.Timeout != 3 {
+		t.Errorf("unexpected options %+v", cc)
+	}
+
+	scoped := scutilDNS[strings.Index(scutilDNS, "DNS configuration (for"):]
+	if _, err := clientConfigFromScutil(strings.NewReader(scoped)); err == nil {
+		t.Errorf("expected error without default resolver")
+	}
+}
@@ -0,0 +1,7 @@
+// +build !darwin,!windows
+
+package dns
+
+func systemClientConfig() (*ClientConfig, error) {
+	return ClientConfigFromFile("/etc/resolv.conf")
+}
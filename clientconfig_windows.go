@@ -0,0 +1,150 @@
+// +build windows
+
+package dns
+
+import (
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// systemClientConfig returns the name servers of the network adapters that
+// are up, in the order of the adapters. The search list is the one set by
+// group policy, or in the TCP/IP parameters, or else the primary domain
+// followed by the DNS suffixes of the adapters.
+func systemClientConfig() (*ClientConfig, error) {
+	aas, err := adapterAddresses()
+	if err != nil {
+		return nil, err
+	}
+	c := newClientConfig()
+	var suffixes []string
+	for _, aa := range aas {
+		if aa.OperStatus != windows.IfOperStatusUp {
+			continue
+		}
+		for dns := aa.FirstDnsServerAddress; dns != nil; dns = dns.Next {
+			ip := sockaddrIP(dns.Address.Sockaddr)
+			if ip == nil || isSiteLocalDefault(ip) {
+				continue
+			}
+			if s := ip.String(); !containsString(c.Servers, s) {
+				c.Servers = append(c.Servers, s)
+			}
+		}
+		if s := utf16PtrToString(aa.DnsSuffix); s != "" && !containsString(suffixes, s) {
+			suffixes = append(suffixes, s)
+		}
+	}
+
+	for _, path := range []string{
+		`SOFTWARE\Policies\Microsoft\Windows NT\DNSClient`,
+		`SYSTEM\CurrentControlSet\Services\Tcpip\Parameters`,
+	} {
+		if list := registryString(path, "SearchList"); list != "" {
+			for _, s := range strings.Split(list, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					c.Search = append(c.Search, s)
+				}
+			}
+			return c, nil
+		}
+	}
+	if domain := registryString(`SYSTEM\CurrentControlSet\Services\Tcpip\Parameters`, "Domain"); domain != "" {
+		c.Search = append(c.Search, domain)
+	}
+	for _, s := range suffixes {
+		if !containsString(c.Search, s) {
+			c.Search = append(c.Search, s)
+		}
+	}
+	return c, nil
+}
+
+// adapterAddresses returns the addresses of the network adapters.
+func adapterAddresses() ([]*windows.IpAdapterAddresses, error) {
+	var b []byte
+	l := uint32(15000) // recommended initial size
+	for {
+		b = make([]byte, l)
+		err := windows.GetAdaptersAddresses(syscall.AF_UNSPEC, windows.GAA_FLAG_INCLUDE_PREFIX, 0, (*windows.IpAdapterAddresses)(unsafe.Pointer(&b[0])), &l)
+		if err == nil {
+			if l == 0 {
+				return nil, nil
+			}
+			break
+		}
+		if err.(syscall.Errno) != syscall.ERROR_BUFFER_OVERFLOW || l <= uint32(len(b)) {
+			return nil, os.NewSyscallError("getadaptersaddresses", err)
+		}
+	}
+	var aas []*windows.IpAdapterAddresses
+	for aa := (*windows.IpAdapterAddresses)(unsafe.Pointer(&b[0])); aa != nil; aa = aa.Next {
+		aas = append(aas, aa)
+	}
+	return aas, nil
+}
+
+// sockaddrIP returns the IP address of sa, or nil.
+func sockaddrIP(sa *syscall.RawSockaddrAny) net.IP {
+	if sa == nil {
+		return nil
+	}
+	switch sa.Addr.Family {
+	case syscall.AF_INET:
+		a := (*syscall.RawSockaddrInet4)(unsafe.Pointer(sa)).Addr
+		return net.IPv4(a[0], a[1], a[2], a[3])
+	case syscall.AF_INET6:
+		a := (*syscall.RawSockaddrInet6)(unsafe.Pointer(sa)).Addr
+		return append(net.IP(nil), a[:]...)
+	}
+	return nil
+}
+
+// isSiteLocalDefault reports whether ip is one of the deprecated site-local
+// name servers fec0:0:0:ffff::1 to 3 that Windows lists when IPv6 has no name
+// server configured.
+func isSiteLocalDefault(ip net.IP) bool {
+	return len(ip) == net.IPv6len && ip.To4() == nil &&
+		ip[0] == 0xfe && ip[1] == 0xc0 && ip[6] == 0xff && ip[7] == 0xff && ip[15] >= 1 && ip[15] <= 3
+}
+
+// registryString returns the string value name of the key at path in
+// HKEY_LOCAL_MACHINE, or "".
+func registryString(path, name string) string {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE)
+	if err != nil {
+		return ""
+	}
+	defer k.Close()
+	s, _, err := k.GetStringValue(name)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+func utf16PtrToString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+	n := 0
+	for ptr := unsafe.Pointer(p); *(*uint16)(ptr) != 0; n++ {
+		ptr = unsafe.Pointer(uintptr(ptr) + unsafe.Sizeof(*p))
+	}
+	return syscall.UTF16ToString((*[1 << 29]uint16)(unsafe.Pointer(p))[:n:n])
+}
+
+func containsString(ss []string, s string) bool {
+	for _, s1 := range ss {
+		if strings.EqualFold(s1, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package dns
+
+// CompressPolicy selects the names a message compresses when its Compress
+// field is true.
+type CompressPolicy uint8
+
+const (
+	// CompressAll compresses the owner names, the names of the question and
+	// the names in the rdata of the types RFC 3597, Section 4 allows. It is
+	// the zero value.
+	CompressAll CompressPolicy = iota
+	// CompressOwners compresses only the owner names and the names of the
+	// question, for peers that mishandle pointers in rdata.
+	CompressOwners
+	// CompressNone doesn't compress anything, as if Compress were false.
+	CompressNone
+)
+
+// CompressionSavings returns the number of octets compression saves when dns
+// is packed, following Compress and CompressPolicy: the difference between
+// the uncompressed length of dns and Len.
+func (dns *Msg) CompressionSavings() int {
+	if !dns.compress() {
+		return 0
+	}
+	return msgLenWithCompressionMap(dns, nil) - dns.Len()
+}
+
+// compress returns true if packing dns compresses names.
+func (dns *Msg) compress() bool {
+	return dns.Compress && dns.CompressPolicy != CompressNone && dns.isCompressible()
+}
+
+// packRRPolicy is packRR, compressing only the owner name of rr when policy
+// is CompressOwners.
+func packRRPolicy(rr RR, msg []byte, off int, compression compressionMap, compress bool, policy CompressPolicy) (headerEnd int, off1 int, err error) {
+	if !compress || policy != CompressOwners {
+		return packRR(rr, msg, off, compression, compress)
+	}
+
+	// The record is packed uncompressed, without looking at or filling the
+	// compression map, then its owner name is packed again compressed in
+	// its place. A compressed name is never longer, so it only overwrites
+	// the uncompressed one and the rest of the record, free of pointers,
+	// can be moved back over what is left of it.
+	headerEnd, off1, err = packRR(rr, msg, off, compressionMap{}, false)
+	if err != nil {
+		return headerEnd, off1, err
+	}
+	nameEnd := headerEnd - 10 // rrtype(2) + class(2) + ttl(4) + rdlength(2)
+	compEnd, _, err := packDomainName(rr.Header().Name, msg, off, compression, true)
+	if err != nil {
+		return headerEnd, len(msg), err
+	}
+	if shift := nameEnd - compEnd; shift > 0 {
+		copy(msg[compEnd:], msg[nameEnd:off1])
+		headerEnd -= shift
+		off1 -= shift
+	}
+	return headerEnd, off1, nil
+}
+
+// lenPolicy is rr.len, compressing only the owner name of rr when policy is
+// CompressOwners, as packRRPolicy does.
+func lenPolicy(rr RR, off int, compression map[string]struct{}, policy CompressPolicy) int {
+	if compression == nil || policy != CompressOwners {
+		return rr.len(off, compression)
+	}
+	name := rr.Header().Name
+	return rr.len(off, nil) - domainNameLen(name, off, nil, true) + domainNameLen(name, off, compression, true)
+}
@@ -0,0 +1,87 @@
+package dns
+
+import (
+	"bytes"
+	"testing"
+)
+
+func compressTestMsg() *Msg {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeMX)
+	m.Response = true
+	m.Answer = []RR{
+		testRR("example.org. 3600 IN MX 10 mx1.example.org."),
+		testRR("example.org. 3600 IN MX 20 mx2.example.org."),
+	}
+	m.Ns = []RR{testRR("example.org. 3600 IN NS ns.example.org.")}
+	m.Extra = []RR{
+		testRR("mx1.example.org. 3600 IN A 192.0.2.1"),
+		testRR("mx2.example.org. 3600 IN A 192.0.2.2"),
+	}
+	return m
+}
+
+func TestCompressPolicy(t *testing.T) {
+	uncompressed := compressTestMsg().Len()
+	lens := make(map[CompressPolicy]int)
+	for _, policy := range []CompressPolicy{CompressAll, CompressOwners, CompressNone} {
+		m := compressTestMsg()
+		m.Compress = true
+		m.CompressPolicy = policy
+		buf, err := m.Pack()
+		if err != nil {
+			t.Fatalf("policy %d: failed to pack: %v", policy, err)
+		}
+		if m.Len() != len(buf) {
+			t.Errorf("policy %d: expected Len %d, got %d", policy, len(buf), m.Len())
+		}
+		if saved := m.CompressionSavings(); saved != uncompressed-len(buf) {
+			t.Errorf("policy %d: expected %d octets saved, got %d", policy, uncompressed-len(buf), saved)
+		}
+		r := new(Msg)
+		if err := r.Unpack(buf); err != nil {
+			t.Fatalf("policy %d: failed to unpack: %v", policy, err)
+		}
+		if diff := MsgDiff(m, r); diff != "" {
+			t.Errorf("policy %d: expected the same message, got\n%s", policy, diff)
+		}
+
+		// The name of an MX target is in the rdata of an MX record and is the
+		// owner name of an A record: CompressAll points the latter to the
+		// former, CompressOwners leaves the former uncompressed.
+		target := []byte("\x03mx1\x07example\x03org\x00")
+		want := map[CompressPolicy]int{CompressAll: 0, CompressOwners: 1, CompressNone: 2}[policy]
+		if n := bytes.Count(buf, target); n != want {
+			t.Errorf("policy %d: expected the uncompressed MX target %d times, got %d", policy, want, n)
+		}
+
+		b := NewMsgBuilder(nil, 0)
+		b.MsgHdr = m.MsgHdr
+		b.Compress = true
+		b.CompressPolicy = policy
+		b.Question(m.Question[0])
+		for _, rr := range m.Answer {
+			b.Answer(rr)
+		}
+		for _, rr := range m.Ns {
+			b.Ns(rr)
+		}
+		for _, rr := range m.Extra {
+			b.Extra(rr)
+		}
+		built, err := b.Finish()
+		if err != nil {
+			t.Fatalf("policy %d: failed to build: %v", policy, err)
+		}
+		if !bytes.Equal(built, buf) {
+			t.Errorf("policy %d: expected the builder to pack the same message", policy)
+		}
+		lens[policy] = len(buf)
+	}
+	if !(lens[CompressAll] < lens[CompressOwners] && lens[CompressOwners] < lens[CompressNone]) {
+		t.Errorf("expected less compression from each policy, got %v", lens)
+	}
+	if lens[CompressNone] != uncompressed {
+		t.Errorf("expected %d octets with CompressNone, got %d", uncompressed, lens[CompressNone])
+	}
+}
@@ -6,6 +6,22 @@ import (
 	"crypto/x509"
 	"encoding/hex"
 	"errors"
+	"strings"
+)
+
+// TLSA certificate usages (RFC 7218).
+const (
+	TLSAPKIXTA = 0
+	TLSAPKIXEE = 1
+	TLSADANETA = 2
+	TLSADANEEE = 3
+)
+
+var (
+	// ErrTLSAUnusable is returned by VerifyTLSA when none of the TLSA records can be used.
+	ErrTLSAUnusable error = &Error{err: "no usable TLSA records"}
+	// ErrTLSAMismatch is returned by VerifyTLSA when no TLSA record matches the certificates.
+	ErrTLSAMismatch error = &Error{err: "certificates don't match the TLSA records"}
 )
 
 // CertificateToDANE converts a certificate to a hex string as used in the TLSA or SMIMEA records.
@@ -41,3 +57,96 @@ func CertificateToDANE(selector, matchingType uint8, cert *x509.Certificate) (st
 	}
 	return "", errors.New("dns: bad MatchingType or Selector")
 }
+
+// VerifyTLSA verifies the certificate chain of a TLS server, the server
+// certificate first, against the TLSA records of the server (RFC 6698 and
+// RFC 7671). It returns nil when one of the records matches:
+//
+//   - PKIX-TA(0) and PKIX-EE(1) records require the chain to be valid for
+//     opts, and to include a matching CA certificate or a matching server
+//     certificate respectively;
+//   - DANE-TA(2) records match a certificate of the chain that the server
+//     certificate must chain to, the roots in opts are ignored but the name
+//     and time in opts are checked;
+//   - DANE-EE(3) records match the server certificate, whose name and
+//     validity period are not checked.
+//
+// Records with an unknown usage, selector or matching type are ignored, when
+// all are ErrTLSAUnusable is returned and the client should fall back to
+// PKIX. A DANE-TA(2) trust anchor must be in the chain, trust anchors only
+// published in DNS aren't supported.
+func VerifyTLSA(tlsas []*TLSA, chain []*x509.Certificate, opts x509.VerifyOptions) error {
+	if len(chain) == 0 {
+		return ErrTLSAMismatch
+	}
+	leaf := chain[0]
+	if opts.Intermediates == nil {
+		opts.Intermediates = x509.NewCertPool()
+		for _, c := range chain[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+	}
+
+	usable := false
+	var pkix [][]*x509.Certificate // verified chains, once checked
+	var pkixErr error
+	for _, t := range tlsas {
+		if t.Usage > TLSADANEEE || t.Selector > 1 || t.MatchingType > 2 {
+			continue
+		}
+		usable = true
+		switch t.Usage {
+		case TLSAPKIXTA, TLSAPKIXEE:
+			if pkix == nil && pkixErr == nil {
+				pkix, pkixErr = leaf.Verify(opts)
+			}
+			if pkixErr != nil {
+				continue
+			}
+			for _, vc := range pkix {
+				certs := vc[:1]
+				if t.Usage == TLSAPKIXTA {
+					certs = vc[1:]
+				}
+				if tlsaMatchesAny(t, certs) {
+					return nil
+				}
+			}
+		case TLSADANETA:
+			for _, c := range chain[1:] {
+				if !tlsaMatches(t, c) {
+					continue
+				}
+				o := opts
+				o.Roots = x509.NewCertPool()
+				o.Roots.AddCert(c)
+				if _, err := leaf.Verify(o); err == nil {
+					return nil
+				}
+			}
+		case TLSADANEEE:
+			if tlsaMatches(t, leaf) {
+				return nil
+			}
+		}
+	}
+	if !usable {
+		return ErrTLSAUnusable
+	}
+	return ErrTLSAMismatch
+}
+
+// tlsaMatches reports whether the association data of t matches cert.
+func tlsaMatches(t *TLSA, cert *x509.Certificate) bool {
+	c, err := CertificateToDANE(t.Selector, t.MatchingType, cert)
+	return err == nil && strings.EqualFold(c, t.Certificate)
+}
+
+func tlsaMatchesAny(t *TLSA, certs []*x509.Certificate) bool {
+	for _, c := range certs {
+		if tlsaMatches(t, c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package dns
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCert returns a certificate for name, signed by parent, or self
+// signed when parent is nil.
+func newTestCert(t *testing.T, name string, ca bool, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if !ca {
+		tmpl.DNSNames = []string{name}
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestVerifyTLSA(t *testing.T) {
+	year := time.Now().Add(365 * 24 * time.Hour)
+	ca, caKey := newTestCert(t, "Test CA", true, year, nil, nil)
+	leaf, _ := newTestCert(t, "www.example.org", false, year, ca, caKey)
+	expired, _ := newTestCert(t, "www.example.org", false, time.Now().Add(-time.Hour), ca, caKey)
+	other, _ := newTestCert(t, "other.example.org", false, year, ca, caKey)
+
+	tlsa := func(usage, selector, matchingType int, cert *x509.Certificate) *TLSA {
+		r := new(TLSA)
+		if err := r.Sign(usage, selector, matchingType, cert); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	pkixOpts := x509.VerifyOptions{Roots: roots, DNSName: "www.example.org"}
+	nameOpts := x509.VerifyOptions{DNSName: "www.example.org"}
+
+	tests := []struct {
+		tlsas []*TLSA
+		chain []*x509.Certificate
+		opts  x509.VerifyOptions
+		err   error
+	}{
+		{[]*TLSA{tlsa(TLSADANEEE, 1, 1, leaf)}, []*x509.Certificate{leaf}, nameOpts, nil},
+		{[]*TLSA{tlsa(TLSADANEEE, 0, 2, expired)}, []*x509.Certificate{expired}, nameOpts, nil},
+		{[]*TLSA{tlsa(TLSADANEEE, 1, 1, other)}, []*x509.Certificate{leaf}, nameOpts, ErrTLSAMismatch},
+		{[]*TLSA{tlsa(TLSADANETA, 0, 1, ca)}, []*x509.Certificate{leaf, ca}, nameOpts, nil},
+		{[]*TLSA{tlsa(TLSADANETA, 0, 1, ca)}, []*x509.Certificate{leaf}, nameOpts, ErrTLSAMismatch},
+		{[]*TLSA{tlsa(TLSADANETA, 0, 1, ca)}, []*x509.Certificate{other, ca}, nameOpts, ErrTLSAMismatch},
+		{[]*TLSA{tlsa(TLSADANETA, 0, 1, ca)}, []*x509.Certificate{expired, ca}, nameOpts, ErrTLSAMismatch},
+		{[]*TLSA{tlsa(TLSAPKIXEE, 1, 1, leaf)}, []*x509.Certificate{leaf}, pkixOpts, nil},
+		{[]*TLSA{tlsa(TLSAPKIXEE, 1, 1, leaf)}, []*x509.Certificate{leaf}, nameOpts, ErrTLSAMismatch},
+		{[]*TLSA{tlsa(TLSAPKIXTA, 0, 1, ca)}, []*x509.Certificate{leaf, ca}, pkixOpts, nil},
+		{[]*TLSA{tlsa(TLSAPKIXTA, 0, 1, leaf)}, []*x509.Certificate{leaf, ca}, pkixOpts, ErrTLSAMismatch},
+		{[]*TLSA{{Usage: 4, Certificate: "00"}}, []*x509.Certificate{leaf}, nameOpts, ErrTLSAUnusable},
+		{[]*TLSA{{Usage: 4, Certificate: "00"}, tlsa(TLSADANEEE, 1, 0, leaf)}, []*x509.Certificate{leaf}, nameOpts, nil},
+	}
+	for i, tc := range tests {
+		if err := VerifyTLSA(tc.tlsas, tc.chain, tc.opts); err != tc.err {
+			t.Errorf("test %d: expected %v, got %v", i, tc.err, err)
+		}
+	}
+}
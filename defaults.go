@@ -130,6 +130,28 @@ func (dns *Msg) SetEdns0(udpsize uint16, do bool) *Msg {
 	return dns
 }
 
+// SetExtendedRcode sets the rcode of dns to rcode, whose upper 8 bits go in the
+// OPT record (RFC 6891). An OPT record is added if rcode needs one and dns
+// has none.
+func (dns *Msg) SetExtendedRcode(rcode int) *Msg {
+	dns.Rcode = rcode
+	opt := dns.IsEdns0()
+	if opt == nil && rcode > 0xF {
+		opt = responseOPT(dns)
+	}
+	if opt != nil {
+		opt.SetExtendedRcode(uint16(rcode))
+	}
+	return dns
+}
+
+// Do returns the value of the DO (DNSSEC OK) bit of the OPT record of dns,
+// false when it has none.
+func (dns *Msg) Do() bool {
+	opt := dns.IsEdns0()
+	return opt != nil && opt.Do()
+}
+
 // IsTsig checks if the message has a TSIG record as the last record
 // in the additional section. It returns the TSIG record found or nil.
 func (dns *Msg) IsTsig() *TSIG {
@@ -43,6 +43,9 @@ type RR interface {
 
 	// pack packs an RR into wire format.
 	pack(msg []byte, off int, compression compressionMap, compress bool) (headerEnd int, off1 int, err error)
+
+	// isDuplicate returns whether the two RRs are duplicates, it only compares the rdata.
+	isDuplicate(r2 RR) bool
 }
 
 // RR_Header is the header all DNS resource records share.
@@ -60,6 +63,9 @@ func (h *RR_Header) Header() *RR_Header { return h }
 // Just to implement the RR interface.
 func (h *RR_Header) copy() RR { return nil }
 
+// Just to implement the RR interface.
+func (h *RR_Header) isDuplicate(r2 RR) bool { return false }
+
 func (h *RR_Header) String() string {
 	var s string
 
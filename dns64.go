@@ -0,0 +1,249 @@
+package dns
+
+import (
+	"net"
+	"time"
+)
+
+// DNS64WellKnownPrefix is the Well-Known Prefix of NAT64 (RFC 6052).
+var DNS64WellKnownPrefix = &net.IPNet{IP: net.ParseIP("64:ff9b::"), Mask: net.CIDRMask(96, 128)}
+
+// DNS64 synthesizes AAAA records from A records for names that have no AAAA
+// records, so IPv6-only clients can reach IPv4-only hosts through a NAT64
+// (RFC 6147). The IPv4 addresses are embedded in Prefix as RFC 6052 describes.
+//
+// A DNS64 is used in a server with DNS64Middleware, or in a client as an
+// Exchanger that wraps another one.
+type DNS64 struct {
+	Prefix    *net.IPNet // NAT64 prefix with a length of 32, 40, 48, 56, 64 or 96, defaults to the Well-Known Prefix
+	Exchanger Exchanger  // where queries are sent when DNS64 is used as an Exchanger
+}
+
+func (d *DNS64) prefix() *net.IPNet {
+	if d.Prefix != nil {
+		return d.Prefix
+	}
+	return DNS64WellKnownPrefix
+}
+
+// isDNS64Query reports whether m is a query DNS64 applies to.
+func isDNS64Query(m *Msg) bool {
+	return len(m.Question) == 1 && m.Question[0].Qtype == TypeAAAA && m.Question[0].Qclass == ClassINET
+}
+
+// needsDNS64 reports whether the response r to an AAAA query should be replaced
+// by a synthesized one: it is a NOERROR response without AAAA records.
+func needsDNS64(r *Msg) bool {
+	if r.Rcode != RcodeSuccess {
+		return false
+	}
+	for _, rr := range r.Answer {
+		if rr.Header().Rrtype == TypeAAAA {
+			return false
+		}
+	}
+	return true
+}
+
+// synthesize returns the response to the AAAA query m, made from the response
+// a to the A query for the same name, or nil when there is nothing to
+// synthesize.
+func (d *DNS64) synthesize(m, a *Msg) *Msg {
+	if a == nil || a.Rcode != RcodeSuccess {
+		return nil
+	}
+	var answer []RR
+	for _, rr := range a.Answer {
+		switch rr := rr.(type) {
+		case *A:
+			ip := DNS64Address(d.prefix(), rr.A)
+			if ip == nil {
+				return nil
+			}
+			hdr := rr.Hdr
+			hdr.Rrtype = TypeAAAA
+			answer = append(answer, &AAAA{Hdr: hdr, AAAA: ip})
+		case *CNAME, *DNAME:
+			answer = append(answer, rr)
+		}
+	}
+	if len(answer) == 0 || answer[len(answer)-1].Header().Rrtype != TypeAAAA {
+		return nil
+	}
+
+	r := new(Msg)
+	r.SetReply(m)
+	r.Authoritative = false // synthesized records are never authoritative
+	r.RecursionAvailable = a.RecursionAvailable
+	r.Answer = answer
+	if opt := m.IsEdns0(); opt != nil {
+		r.SetEdns0(opt.UDPSize(), opt.Do())
+	}
+	return r
+}
+
+// Exchange sends m to address with the Exchanger of d. When m is an AAAA query
+// that gets a response without AAAA records, the A records of the name are
+// queried too, and a response with AAAA records synthesized from them is
+// returned if there are any.
+func (d *DNS64) Exchange(m *Msg, address string) (r *Msg, rtt time.Duration, err error) {
+	r, rtt, err = d.Exchanger.Exchange(m, address)
+	if err != nil || !isDNS64Query(m) || !needsDNS64(r) {
+		return r, rtt, err
+	}
+
+	q := m.Copy()
+	q.Id = Id()
+	q.Question[0].Qtype = TypeA
+	a, artt, err := d.Exchanger.Exchange(q, address)
+	if err != nil {
+		return r, rtt, nil
+	}
+	if s := d.synthesize(m, a); s != nil {
+		return s, rtt + artt, nil
+	}
+	return r, rtt, nil
+}
+
+// DNS64Middleware returns a Middleware that synthesizes AAAA records with d for
+// AAAA queries the handler it wraps has no AAAA records for. The A records are
+// looked up by sending an A query to the same handler.
+func DNS64Middleware(d *DNS64) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Msg) {
+			if !isDNS64Query(r) {
+				next.ServeDNS(w, r)
+				return
+			}
+
+			cw := &captureWriter{ResponseWriter: w}
+			next.ServeDNS(cw, r)
+			if cw.msg == nil {
+				return // nothing, or something invalid, was written
+			}
+			if needsDNS64(cw.msg) {
+				q := r.Copy()
+				q.Question[0].Qtype = TypeA
+				aw := &captureWriter{ResponseWriter: w}
+				next.ServeDNS(aw, q)
+				if s := d.synthesize(r, aw.msg); s != nil {
+					w.WriteMsg(s)
+					return
+				}
+			}
+			w.WriteMsg(cw.msg)
+		})
+	}
+}
+
+// captureWriter is a ResponseWriter that keeps the response written to it
+// instead of sending it.
+type captureWriter struct {
+	ResponseWriter
+	msg *Msg
+}
+
+func (w *captureWriter) WriteMsg(m *Msg) error {
+	w.msg = m
+	return nil
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	m := new(Msg)
+	if err := m.Unpack(b); err != nil {
+		return w.ResponseWriter.Write(b)
+	}
+	w.msg = m
+	return len(b), nil
+}
+
+// DNS64Address returns the IPv4 address ip embedded in the IPv6 prefix, as RFC
+// 6052, Section 2.2 describes. It returns nil if ip isn't an IPv4 address, or
+// if prefix doesn't have a valid length.
+func DNS64Address(prefix *net.IPNet, ip net.IP) net.IP {
+	ip4 := ip.To4()
+	ones, bits := prefix.Mask.Size()
+	if ip4 == nil || bits != 128 || !validDNS64PrefixLength(ones) {
+		return nil
+	}
+	ip6 := make(net.IP, net.IPv6len)
+	copy(ip6, prefix.IP.To16())
+	j := ones / 8
+	for _, b := range ip4 {
+		if j == 8 {
+			ip6[j] = 0 // bits 64 to 71 are zero
+			j++
+		}
+		ip6[j] = b
+		j++
+	}
+	for ; j < net.IPv6len; j++ {
+		ip6[j] = 0
+	}
+	return ip6
+}
+
+// DNS64ExtractAddress returns the IPv4 address embedded in ip with a prefix of
+// length prefixLength, or nil if the length isn't valid.
+func DNS64ExtractAddress(ip net.IP, prefixLength int) net.IP {
+	ip6 := ip.To16()
+	if ip6 == nil || !validDNS64PrefixLength(prefixLength) {
+		return nil
+	}
+	ip4 := make(net.IP, net.IPv4len)
+	j := prefixLength / 8
+	for i := range ip4 {
+		if j == 8 {
+			j++
+		}
+		ip4[i] = ip6[j]
+		j++
+	}
+	return ip4
+}
+
+func validDNS64PrefixLength(l int) bool {
+	switch l {
+	case 32, 40, 48, 56, 64, 96:
+		return true
+	}
+	return false
+}
+
+// DiscoverDNS64Prefixes discovers the NAT64 prefixes used by the DNS64 server
+// at address, by looking up the AAAA records of ipv4only.arpa. (RFC 7050). It
+// returns no prefixes and no error when the server doesn't do DNS64.
+func DiscoverDNS64Prefixes(ex Exchanger, address string) ([]*net.IPNet, error) {
+	m := new(Msg)
+	m.SetQuestion("ipv4only.arpa.", TypeAAAA)
+	r, _, err := ex.Exchange(m, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// The well-known IPv4 addresses of ipv4only.arpa.
+	wka := []net.IP{net.IPv4(192, 0, 0, 170), net.IPv4(192, 0, 0, 171)}
+	var prefixes []*net.IPNet
+Answers:
+	for _, rr := range r.Answer {
+		aaaa, ok := rr.(*AAAA)
+		if !ok {
+			continue
+		}
+		for _, l := range []int{96, 64, 56, 48, 40, 32} {
+			ip4 := DNS64ExtractAddress(aaaa.AAAA, l)
+			if !ip4.Equal(wka[0]) && !ip4.Equal(wka[1]) {
+				continue
+			}
+			p := &net.IPNet{IP: aaaa.AAAA.Mask(net.CIDRMask(l, 128)), Mask: net.CIDRMask(l, 128)}
+			for _, q := range prefixes {
+				if q.String() == p.String() {
+					continue Answers
+				}
+			}
+			prefixes = append(prefixes, p)
+			continue Answers
+		}
+	}
+	return prefixes, nil
+}
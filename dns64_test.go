@@ -0,0 +1,136 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDNS64Address(t *testing.T) {
+	// Examples from RFC 6052, Section 2.4.
+	ip4 := net.ParseIP("192.0.2.33")
+	tests := []struct {
+		prefix string
+		ip6    string
+	}{
+		{"2001:db8::/32", "2001:db8:c000:221::"},
+		{"2001:db8:100::/40", "2001:db8:1c0:2:21::"},
+		{"2001:db8:122::/48", "2001:db8:122:c000:2:2100::"},
+		{"2001:db8:122:300::/56", "2001:db8:122:3c0:0:221::"},
+		{"2001:db8:122:344::/64", "2001:db8:122:344:c0:2:2100:0"},
+		{"2001:db8:122:344::/96", "2001:db8:122:344::c000:221"},
+	}
+	for _, tc := range tests {
+		_, prefix, _ := net.ParseCIDR(tc.prefix)
+		ip6 := DNS64Address(prefix, ip4)
+		if !ip6.Equal(net.ParseIP(tc.ip6)) {
+			t.Errorf("%s: expected %s, got %s", tc.prefix, tc.ip6, ip6)
+		}
+		ones, _ := prefix.Mask.Size()
+		if got := DNS64ExtractAddress(ip6, ones); !got.Equal(ip4) {
+			t.Errorf("%s: expected to extract %s, got %s", tc.prefix, ip4, got)
+		}
+	}
+	if ip := DNS64Address(DNS64WellKnownPrefix, net.ParseIP("2001:db8::1")); ip != nil {
+		t.Errorf("expected no address for IPv6 address, got %s", ip)
+	}
+}
+
+// dns64Handler has an A record for v4only.example. and both for dual.example.
+func dns64Handler(w ResponseWriter, req *Msg) {
+	m := new(Msg)
+	m.SetReply(req)
+	q := req.Question[0]
+	switch {
+	case q.Name == "v4only.example." && q.Qtype == TypeA:
+		rr, _ := NewRR("v4only.example. 300 IN A 192.0.2.33")
+		m.Answer = []RR{rr}
+	case q.Name == "alias.example.":
+		c, _ := NewRR("alias.example. 300 IN CNAME v4only.example.")
+		m.Answer = []RR{c}
+		if q.Qtype == TypeA {
+			rr, _ := NewRR("v4only.example. 300 IN A 192.0.2.33")
+			m.Answer = append(m.Answer, rr)
+		}
+	case q.Name == "dual.example." && q.Qtype == TypeAAAA:
+		rr, _ := NewRR("dual.example. 300 IN AAAA 2001:db8::1")
+		m.Answer = []RR{rr}
+	case q.Name == "nx.example.":
+		m.Rcode = RcodeNameError
+	}
+	w.WriteMsg(m)
+}
+
+func TestDNS64Middleware(t *testing.T) {
+	h := Chain(HandlerFunc(dns64Handler), DNS64Middleware(&DNS64{}))
+	query := func(name string) *Msg {
+		m := new(Msg)
+		m.SetQuestion(name, TypeAAAA)
+		w := &recordWriter{}
+		h.ServeDNS(w, m)
+		if len(w.msgs) != 1 {
+			t.Fatalf("%s: expected 1 response, got %d", name, len(w.msgs))
+		}
+		return w.msgs[0]
+	}
+
+	r := query("v4only.example.")
+	if len(r.Answer) != 1 || r.Answer[0].(*AAAA).AAAA.String() != "64:ff9b::c000:221" || r.Answer[0].Header().Name != "v4only.example." {
+		t.Errorf("expected synthesized AAAA, got %v", r.Answer)
+	}
+	r = query("alias.example.")
+	if len(r.Answer) != 2 || r.Answer[0].Header().Rrtype != TypeCNAME || r.Answer[1].Header().Rrtype != TypeAAAA {
+		t.Errorf("expected CNAME and synthesized AAAA, got %v", r.Answer)
+	}
+	r = query("dual.example.")
+	if len(r.Answer) != 1 || r.Answer[0].(*AAAA).AAAA.String() != "2001:db8::1" {
+		t.Errorf("expected real AAAA, got %v", r.Answer)
+	}
+	r = query("nx.example.")
+	if r.Rcode != RcodeNameError || len(r.Answer) != 0 {
+		t.Errorf("expected NXDOMAIN, got %v", r)
+	}
+}
+
+func TestDNS64Exchange(t *testing.T) {
+	ex := exchangerFunc(func(m *Msg, address string) (*Msg, time.Duration, error) {
+		w := &recordWriter{}
+		dns64Handler(w, m)
+		return w.msgs[0], time.Millisecond, nil
+	})
+	_, prefix, _ := net.ParseCIDR("2001:db8:122:344::/96")
+	d := &DNS64{Prefix: prefix, Exchanger: ex}
+
+	m := new(Msg)
+	m.SetQuestion("v4only.example.", TypeAAAA)
+	r, _, err := d.Exchange(m, "192.0.2.53:53")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Answer) != 1 || r.Answer[0].(*AAAA).AAAA.String() != "2001:db8:122:344::c000:221" || r.Id != m.Id {
+		t.Errorf("expected synthesized AAAA, got %v", r)
+	}
+}
+
+func TestDiscoverDNS64Prefixes(t *testing.T) {
+	ex := exchangerFunc(func(m *Msg, address string) (*Msg, time.Duration, error) {
+		r := new(Msg)
+		r.SetReply(m)
+		for _, s := range []string{
+			"ipv4only.arpa. 300 IN AAAA 64:ff9b::c000:aa",
+			"ipv4only.arpa. 300 IN AAAA 64:ff9b::c000:ab",
+			"ipv4only.arpa. 300 IN AAAA 2001:db8:1c0:0:aa::",
+		} {
+			rr, _ := NewRR(s)
+			r.Answer = append(r.Answer, rr)
+		}
+		return r, time.Millisecond, nil
+	})
+	prefixes, err := DiscoverDNS64Prefixes(ex, "192.0.2.53:53")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prefixes) != 2 || prefixes[0].String() != "64:ff9b::/96" || prefixes[1].String() != "2001:db8:100::/40" {
+		t.Errorf("expected 2 prefixes, got %v", prefixes)
+	}
+}
@@ -277,6 +277,22 @@ func BenchmarkPackMsg(b *testing.B) {
 	}
 }
 
+func BenchmarkPackMsgCompression(b *testing.B) {
+	name1 := "12345678901234567890123456789012345.12345678.123."
+	rrMx := testRR(name1 + " 3600 IN MX 10 " + name1)
+	msg := new(Msg)
+	msg.SetQuestion(name1, TypeANY)
+	msg.Answer = []RR{rrMx, rrMx}
+	msg.Compress = true
+	buf := make([]byte, 512)
+	c := new(Compression)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = msg.PackBufferCompression(buf, c)
+	}
+}
+
 func BenchmarkPackMsgMassive(b *testing.B) {
 	makeMsg := func(question string, ans, ns, e []RR) *Msg {
 		msg := new(Msg)
@@ -342,3 +358,15 @@ func BenchmarkIdGeneration(b *testing.B) {
 		_ = id()
 	}
 }
+
+func BenchmarkAppendDomainName(b *testing.B) {
+	name1 := "12345678901234567890123456789012345.12345678.123."
+	buf := make([]byte, len(name1)+1)
+	_, _ = PackDomainName(name1, buf, 0, nil, false)
+	s := make([]byte, 0, maxDomainNamePresentationLength)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = AppendDomainName(s[:0], buf, 0)
+	}
+}
@@ -167,6 +167,15 @@ func TestNoRdataPack(t *testing.T) {
 	}
 }
 
+func TestTypeToUnpack(t *testing.T) {
+	// Every RR type must have a generated unpack function, see msg_generate.go.
+	for typ := range TypeToRR {
+		if _, ok := typeToUnpack[typ]; !ok {
+			t.Errorf("no unpack function for %s", TypeToString[typ])
+		}
+	}
+}
+
 func TestNoRdataUnpack(t *testing.T) {
 	data := make([]byte, 1024)
 	for typ, fn := range TypeToRR {
@@ -0,0 +1,268 @@
+package dnscap
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Link types, see https://www.tcpdump.org/linktypes.html.
+const (
+	linkNull     = 0
+	linkEthernet = 1
+	linkRaw      = 101
+	linkLoop     = 108
+	linkSLL      = 113
+	linkIPv4     = 228
+	linkIPv6     = 229
+	linkSLL2     = 276
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+	etherTypeQinQ = 0x88a8
+
+	protoTCP = 6
+	protoUDP = 17
+)
+
+// decodeLink decodes the frame data of the link type link, captured at t.
+func (r *Reader) decodeLink(t time.Time, link uint32, data []byte) {
+	switch link {
+	case linkNull, linkLoop:
+		if len(data) < 4 {
+			return
+		}
+		r.decodeIP(t, data[4:])
+	case linkEthernet:
+		if len(data) < 14 {
+			return
+		}
+		typ, data := binary.BigEndian.Uint16(data[12:]), data[14:]
+		for (typ == etherTypeVLAN || typ == etherTypeQinQ) && len(data) >= 4 {
+			typ, data = binary.BigEndian.Uint16(data[2:]), data[4:]
+		}
+		if typ == etherTypeIPv4 || typ == etherTypeIPv6 {
+			r.decodeIP(t, data)
+		}
+	case linkRaw, linkIPv4, linkIPv6:
+		r.decodeIP(t, data)
+	case linkSLL:
+		if len(data) < 16 {
+			return
+		}
+		r.decodeIP(t, data[16:])
+	case linkSLL2:
+		if len(data) < 20 {
+			return
+		}
+		r.decodeIP(t, data[20:])
+	}
+}
+
+// decodeIP decodes the IPv4 or IPv6 packet data.
+func (r *Reader) decodeIP(t time.Time, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	var (
+		src, dst net.IP
+		proto    byte
+	)
+	switch data[0] >> 4 {
+	case 4:
+		if len(data) < 20 {
+			return
+		}
+		hl, l := int(data[0]&0x0f)*4, int(binary.BigEndian.Uint16(data[2:]))
+		if hl < 20 || l < hl || l > len(data) {
+			return
+		}
+		if binary.BigEndian.Uint16(data[6:])&0x3fff != 0 { // more fragments, or a fragment offset
+			return
+		}
+		src, dst, proto = net.IP(data[12:16]), net.IP(data[16:20]), data[9]
+		data = data[hl:l]
+	case 6:
+		if len(data) < 40 {
+			return
+		}
+		l := 40 + int(binary.BigEndian.Uint16(data[4:]))
+		if l > len(data) {
+			return
+		}
+		src, dst, proto = net.IP(data[8:24]), net.IP(data[24:40]), data[6]
+		data = data[40:l]
+		for proto == 0 || proto == 43 || proto == 60 { // hop-by-hop, routing and destination options
+			if len(data) < 8 || len(data) < (int(data[1])+1)*8 {
+				return
+			}
+			proto, data = data[0], data[(int(data[1])+1)*8:]
+		}
+	default:
+		return
+	}
+	switch proto {
+	case protoUDP:
+		r.decodeUDP(t, src, dst, data)
+	case protoTCP:
+		r.decodeTCP(t, src, dst, data)
+	}
+}
+
+// decodeUDP decodes the UDP datagram data.
+func (r *Reader) decodeUDP(t time.Time, src, dst net.IP, data []byte) {
+	if len(data) < 8 {
+		return
+	}
+	sport, dport, l := binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:]), int(binary.BigEndian.Uint16(data[4:]))
+	if !r.port(sport) && !r.port(dport) || l < 8 || l > len(data) {
+		return
+	}
+	payload := append([]byte(nil), data[8:l]...)
+	r.queue = append(r.queue, newPacket(t,
+		&net.UDPAddr{IP: copyIP(src), Port: int(sport)},
+		&net.UDPAddr{IP: copyIP(dst), Port: int(dport)},
+		payload))
+}
+
+// maxStreams is the maximum number of TCP streams reassembled at once, and
+// maxPending the maximum number of out of order segments held per stream.
+const (
+	maxStreams = 4096
+	maxPending = 64
+)
+
+const (
+	tcpFIN = 0x01
+	tcpSYN = 0x02
+	tcpRST = 0x04
+)
+
+// A tcpStream is a direction of a TCP connection being reassembled.
+type tcpStream struct {
+	next    uint32            // sequence number of the next byte
+	buf     []byte            // data not yet read as messages
+	pending map[uint32][]byte // segments after a gap, by sequence number
+	fin     bool              // the FIN was seen, possibly before segments missing
+}
+
+// decodeTCP decodes the TCP segment data, and queues the messages it completes.
+func (r *Reader) decodeTCP(t time.Time, src, dst net.IP, data []byte) {
+	if len(data) < 20 {
+		return
+	}
+	sport, dport := binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+	seq, off, flags := binary.BigEndian.Uint32(data[4:]), int(data[12]>>4)*4, data[13]
+	if !r.port(sport) && !r.port(dport) || off < 20 || off > len(data) {
+		return
+	}
+	payload := data[off:]
+
+	key := string(src) + strconv.Itoa(int(sport)) + ">" + string(dst) + strconv.Itoa(int(dport))
+	if r.streams == nil {
+		r.streams = make(map[string]*tcpStream)
+	}
+	s := r.streams[key]
+	switch {
+	case flags&tcpSYN != 0:
+		s = &tcpStream{next: seq + 1}
+		seq++
+	case s == nil && len(payload) > 0:
+		// The capture started in the middle of the connection, assume
+		// the segment starts a message.
+		s = &tcpStream{next: seq}
+	}
+	if s == nil {
+		return
+	}
+	if r.streams[key] == nil {
+		if len(r.streams) >= maxStreams {
+			for k := range r.streams {
+				delete(r.streams, k)
+				break
+			}
+		}
+		r.streams[key] = s
+	}
+	s.fin = s.fin || flags&tcpFIN != 0
+	s.add(seq, payload)
+	if flags&tcpRST != 0 || s.fin && len(s.pending) == 0 {
+		delete(r.streams, key)
+	}
+	for len(s.buf) >= 2 {
+		l := int(binary.BigEndian.Uint16(s.buf))
+		if len(s.buf) < 2+l {
+			break
+		}
+		msg := append([]byte(nil), s.buf[2:2+l]...)
+		s.buf = s.buf[2+l:]
+		r.queue = append(r.queue, newPacket(t,
+			&net.TCPAddr{IP: copyIP(src), Port: int(sport)},
+			&net.TCPAddr{IP: copyIP(dst), Port: int(dport)},
+			msg))
+	}
+}
+
+// add adds the segment data with sequence number seq to s.
+func (s *tcpStream) add(seq uint32, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	if d := int32(seq - s.next); d > 0 {
+		if s.pending == nil {
+			s.pending = make(map[uint32][]byte)
+		}
+		s.pending[seq] = append([]byte(nil), data...)
+		if len(s.pending) > maxPending {
+			s.resync()
+		}
+		return
+	} else if d < 0 { // a retransmission, possibly with new data
+		if int(-d) >= len(data) {
+			return
+		}
+		data = data[-d:]
+	}
+	s.buf = append(s.buf, data...)
+	s.next += uint32(len(data))
+	for len(s.pending) > 0 {
+		data, ok := s.pending[s.next]
+		if !ok {
+			return
+		}
+		delete(s.pending, s.next)
+		s.buf = append(s.buf, data...)
+		s.next += uint32(len(data))
+	}
+}
+
+// resync gives up on the data missing from s, and restarts it at the first
+// pending segment.
+func (s *tcpStream) resync() {
+	var first uint32
+	found := false
+	for seq := range s.pending {
+		if !found || int32(seq-first) < 0 {
+			first, found = seq, true
+		}
+	}
+	s.buf = s.buf[:0]
+	s.next = first
+	pending := s.pending
+	s.pending = nil
+	data := pending[first]
+	delete(pending, first)
+	s.add(first, data)
+	for seq, data := range pending {
+		s.add(seq, data)
+	}
+}
+
+// copyIP returns a copy of ip.
+func copyIP(ip net.IP) net.IP {
+	return append(net.IP(nil), ip...)
+}
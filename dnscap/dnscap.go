@@ -0,0 +1,145 @@
+// Package dnscap reads DNS messages from packet captures. It reads pcap and
+// pcapng files, as written by tcpdump and Wireshark, and captures of DNS over
+// TCP streams, where each message is preceded by its length. TCP segments are
+// reassembled, so messages spanning segments, or segments holding more than
+// one message, are read whole.
+//
+// Basic use pattern:
+//
+//	r, err := dnscap.NewReader(f)
+//	if err != nil {
+//		// not a capture
+//	}
+//	for {
+//		p, err := r.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		if err != nil {
+//			// broken capture
+//		}
+//		if p.Err != nil {
+//			// not a valid DNS message
+//			continue
+//		}
+//		fmt.Println(p.Time, p.Src, p.Dst, p.Msg.Question)
+//	}
+package dnscap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// A Packet is a DNS message read from a capture.
+type Packet struct {
+	Time     time.Time // when it was captured, of the last segment of a message over TCP
+	Src, Dst net.Addr  // *net.UDPAddr or *net.TCPAddr, nil for streams
+	Payload  []byte    // the message in wire format
+	Msg      *dns.Msg  // the message, nil if it can't be unpacked
+	Err      error     // the error unpacking the message
+}
+
+// ErrFormat is returned when a capture is broken, or isn't a capture.
+var ErrFormat = errors.New("dnscap: bad capture format")
+
+// A Reader reads the DNS messages of a capture.
+type Reader struct {
+	// Ports are the ports of the DNS traffic, UDP datagrams and TCP segments
+	// with none of them as their source or destination port are skipped. It
+	// is 53 by default.
+	Ports []uint16
+
+	r       *bufio.Reader
+	next    func() (*Packet, error) // reads the next packet of the capture
+	queue   []*Packet               // messages decoded and not yet returned
+	streams map[string]*tcpStream
+}
+
+// NewReader returns a Reader for the capture r, a pcap or pcapng file.
+func NewReader(r io.Reader) (*Reader, error) {
+	cr := &Reader{Ports: []uint16{53}, r: bufio.NewReader(r)}
+	magic, err := cr.r.Peek(4)
+	if err != nil {
+		return nil, ErrFormat
+	}
+	switch {
+	case binary.BigEndian.Uint32(magic) == pcapngBlockSHB:
+		err = cr.initPcapng()
+	default:
+		err = cr.initPcap()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// NewStreamReader returns a Reader for r, the data of DNS over TCP streams:
+// each message is preceded by its length as a two byte integer.
+func NewStreamReader(r io.Reader) *Reader {
+	cr := &Reader{r: bufio.NewReader(r)}
+	cr.next = cr.readStream
+	return cr
+}
+
+// Next returns the next DNS message of the capture, io.EOF at its end. Packets
+// that aren't DNS over UDP or TCP to or from Ports are skipped, and so are IP
+// fragments.
+func (r *Reader) Next() (*Packet, error) {
+	for len(r.queue) == 0 {
+		p, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+	p := r.queue[0]
+	r.queue[0] = nil
+	r.queue = r.queue[1:]
+	return p, nil
+}
+
+// readStream reads the next message of a stream.
+func (r *Reader) readStream() (*Packet, error) {
+	var l [2]byte
+	if _, err := io.ReadFull(r.r, l[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return newPacket(time.Time{}, nil, nil, buf), nil
+}
+
+// newPacket returns the Packet of the message buf.
+func newPacket(t time.Time, src, dst net.Addr, buf []byte) *Packet {
+	p := &Packet{Time: t, Src: src, Dst: dst, Payload: buf}
+	m := new(dns.Msg)
+	if p.Err = m.Unpack(buf); p.Err == nil {
+		p.Msg = m
+	}
+	return p
+}
+
+// port reports whether p is one of r.Ports.
+func (r *Reader) port(p uint16) bool {
+	for _, q := range r.Ports {
+		if p == q {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package dnscap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func testMsg(t *testing.T, name string) []byte {
+	m := new(dns.Msg)
+	m.SetQuestion(name, dns.TypeA)
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+// ethernetUDP returns an Ethernet frame of an IPv4 UDP datagram.
+func ethernetUDP(src, dst net.IP, sport, dport uint16, payload []byte) []byte {
+	udp := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(udp, sport)
+	binary.BigEndian.PutUint16(udp[2:], dport)
+	binary.BigEndian.PutUint16(udp[4:], uint16(8+len(payload)))
+	udp = append(udp, payload...)
+
+	ip := make([]byte, 20, 20+len(udp))
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+len(udp)))
+	ip[8], ip[9] = 64, protoUDP
+	copy(ip[12:], src.To4())
+	copy(ip[16:], dst.To4())
+	ip = append(ip, udp...)
+
+	frame := make([]byte, 14, 14+len(ip))
+	binary.BigEndian.PutUint16(frame[12:], etherTypeIPv4)
+	return append(frame, ip...)
+}
+
+// rawTCP returns an IPv6 packet of a TCP segment.
+func rawTCP(src, dst net.IP, sport, dport uint16, seq uint32, flags byte, payload []byte) []byte {
+	tcp := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp, sport)
+	binary.BigEndian.PutUint16(tcp[2:], dport)
+	binary.BigEndian.PutUint32(tcp[4:], seq)
+	tcp[12], tcp[13] = 5<<4, flags
+	tcp = append(tcp, payload...)
+
+	ip := make([]byte, 40, 40+len(tcp))
+	ip[0] = 0x60
+	binary.BigEndian.PutUint16(ip[4:], uint16(len(tcp)))
+	ip[6], ip[7] = protoTCP, 64
+	copy(ip[8:], src)
+	copy(ip[24:], dst)
+	return append(ip, tcp...)
+}
+
+func TestReaderPcap(t *testing.T) {
+	query := testMsg(t, "example.org.")
+	src, dst := net.IPv4(192, 0, 2, 1), net.IPv4(192, 0, 2, 53)
+	ts := time.Unix(1500000000, 123456000)
+
+	var b bytes.Buffer
+	h := make([]byte, 24)
+	binary.LittleEndian.PutUint32(h, pcapMagic)
+	binary.LittleEndian.PutUint16(h[4:], 2)
+	binary.LittleEndian.PutUint16(h[6:], 4)
+	binary.LittleEndian.PutUint32(h[16:], 65535)
+	binary.LittleEndian.PutUint32(h[20:], linkEthernet)
+	b.Write(h)
+	for _, frame := range [][]byte{
+		ethernetUDP(src, dst, 4000, 80, query), // not DNS
+		ethernetUDP(src, dst, 4000, 53, query),
+		ethernetUDP(src, dst, 4000, 53, query[:5]),
+	} {
+		rh := make([]byte, 16)
+		binary.LittleEndian.PutUint32(rh, uint32(ts.Unix()))
+		binary.LittleEndian.PutUint32(rh[4:], uint32(ts.Nanosecond()/1000))
+		binary.LittleEndian.PutUint32(rh[8:], uint32(len(frame)))
+		binary.LittleEndian.PutUint32(rh[12:], uint32(len(frame)))
+		b.Write(rh)
+		b.Write(frame)
+	}
+
+	r, err := NewReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Err != nil || p.Msg.Question[0].Name != "example.org." {
+		t.Errorf("expected the query, got %v, %v", p.Msg, p.Err)
+	}
+	if !p.Time.Equal(ts) {
+		t.Errorf("expected time %v, got %v", ts, p.Time)
+	}
+	if p.Src.String() != "192.0.2.1:4000" || p.Dst.String() != "192.0.2.53:53" {
+		t.Errorf("unexpected addresses %v > %v", p.Src, p.Dst)
+	}
+	if p, err = r.Next(); err != nil || p.Err == nil || p.Msg != nil {
+		t.Errorf("expected a packet with an unpack error, got %v, %v", p, err)
+	}
+	if _, err = r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReaderPcapngTCP(t *testing.T) {
+	q1, q2 := testMsg(t, "a.example.org."), testMsg(t, "b.example.org.")
+	var stream []byte
+	for _, q := range [][]byte{q1, q2} {
+		stream = append(stream, byte(len(q)>>8), byte(len(q)))
+		stream = append(stream, q...)
+	}
+	src, dst := net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::53")
+	const isn = 0xfffffff0 // the sequence numbers wrap
+
+	// The two messages in three segments, the last two out of order.
+	cut1, cut2 := 5, len(q1)+4
+	segments := [][]byte{
+		rawTCP(src, dst, 4000, 53, isn, tcpSYN, nil),
+		rawTCP(src, dst, 4000, 53, isn+1, 0, stream[:cut1]),
+		rawTCP(src, dst, 4000, 53, isn+1+uint32(cut2), tcpFIN, stream[cut2:]),
+		rawTCP(src, dst, 4000, 53, isn+1+uint32(cut1), 0, stream[cut1:cut2]),
+	}
+
+	var b bytes.Buffer
+	block := func(typ uint32, body []byte) {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		l := make([]byte, 4)
+		binary.LittleEndian.PutUint32(l, uint32(12+len(body)))
+		binary.Write(&b, binary.LittleEndian, typ)
+		b.Write(l)
+		b.Write(body)
+		b.Write(l)
+	}
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb, pcapngByteOrder)
+	binary.LittleEndian.PutUint16(shb[4:], 1)
+	binary.LittleEndian.PutUint64(shb[8:], ^uint64(0))
+	block(pcapngBlockSHB, shb)
+	idb := make([]byte, 8+8)
+	binary.LittleEndian.PutUint16(idb, linkRaw)
+	binary.LittleEndian.PutUint16(idb[8:], pcapngOptTsresol)
+	binary.LittleEndian.PutUint16(idb[10:], 1)
+	idb[12] = 9 // nanoseconds
+	block(pcapngBlockIDB, idb)
+	for i, s := range segments {
+		epb := make([]byte, 20, 20+len(s))
+		ts := uint64(1500000000e9 + i)
+		binary.LittleEndian.PutUint32(epb[4:], uint32(ts>>32))
+		binary.LittleEndian.PutUint32(epb[8:], uint32(ts))
+		binary.LittleEndian.PutUint32(epb[12:], uint32(len(s)))
+		binary.LittleEndian.PutUint32(epb[16:], uint32(len(s)))
+		block(pcapngBlockEPB, append(epb, s...))
+	}
+
+	r, err := NewReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"a.example.org.", "b.example.org."} {
+		p, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Err != nil || p.Msg.Question[0].Name != name {
+			t.Errorf("expected a query for %s, got %v, %v", name, p.Msg, p.Err)
+		}
+		if want := time.Unix(1500000000, 3); !p.Time.Equal(want) {
+			t.Errorf("%d: expected time %v, got %v", i, want, p.Time)
+		}
+		if p.Src.String() != "[2001:db8::1]:4000" || p.Src.Network() != "tcp" {
+			t.Errorf("unexpected source %v", p.Src)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+	if len(r.streams) != 0 {
+		t.Errorf("expected the stream to be closed, got %d", len(r.streams))
+	}
+}
+
+func TestStreamReader(t *testing.T) {
+	q := testMsg(t, "example.org.")
+	var b bytes.Buffer
+	for i := 0; i < 2; i++ {
+		b.Write([]byte{byte(len(q) >> 8), byte(len(q))})
+		b.Write(q)
+	}
+	b.Write([]byte{0, 12, 0})
+
+	r := NewStreamReader(&b)
+	for i := 0; i < 2; i++ {
+		p, err := r.Next()
+		if err != nil || p.Msg == nil || p.Src != nil {
+			t.Fatalf("expected a message, got %v, %v", p, err)
+		}
+	}
+	if _, err := r.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestNewReaderFormat(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader([]byte("not a capture at all, not even close"))); err != ErrFormat {
+		t.Errorf("expected ErrFormat, got %v", err)
+	}
+}
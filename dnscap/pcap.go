@@ -0,0 +1,197 @@
+package dnscap
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	pcapMagic       = 0xa1b2c3d4 // timestamps in microseconds
+	pcapMagicNano   = 0xa1b23c4d // timestamps in nanoseconds
+	pcapngBlockSHB  = 0x0a0d0d0a // section header block
+	pcapngBlockIDB  = 0x00000001 // interface description block
+	pcapngBlockSPB  = 0x00000003 // simple packet block
+	pcapngBlockEPB  = 0x00000006 // enhanced packet block
+	pcapngByteOrder = 0x1a2b3c4d
+
+	pcapngOptEnd      = 0
+	pcapngOptTsresol  = 9
+	maxPcapRecordSize = 1 << 24
+)
+
+// initPcap reads the header of a pcap file.
+func (r *Reader) initPcap() error {
+	var h [24]byte
+	if _, err := io.ReadFull(r.r, h[:]); err != nil {
+		return ErrFormat
+	}
+	var order binary.ByteOrder
+	var nano bool
+	switch {
+	case binary.BigEndian.Uint32(h[:]) == pcapMagic:
+		order = binary.BigEndian
+	case binary.LittleEndian.Uint32(h[:]) == pcapMagic:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(h[:]) == pcapMagicNano:
+		order, nano = binary.BigEndian, true
+	case binary.LittleEndian.Uint32(h[:]) == pcapMagicNano:
+		order, nano = binary.LittleEndian, true
+	default:
+		return ErrFormat
+	}
+	link := order.Uint32(h[20:]) & 0xffff // the upper bits may hold the FCS length
+	r.next = func() (*Packet, error) {
+		var rh [16]byte
+		if _, err := io.ReadFull(r.r, rh[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return nil, ErrFormat
+			}
+			return nil, err
+		}
+		sec, frac, caplen := order.Uint32(rh[0:]), order.Uint32(rh[4:]), order.Uint32(rh[8:])
+		if caplen > maxPcapRecordSize {
+			return nil, ErrFormat
+		}
+		data := make([]byte, caplen)
+		if _, err := io.ReadFull(r.r, data); err != nil {
+			return nil, ErrFormat
+		}
+		if !nano {
+			frac *= 1000
+		}
+		r.decodeLink(time.Unix(int64(sec), int64(frac)), link, data)
+		return nil, nil
+	}
+	return nil
+}
+
+// pcapngInterface is an interface of a pcapng section.
+type pcapngInterface struct {
+	link    uint32
+	tsresol byte // resolution of the timestamps, as the if_tsresol option
+}
+
+// initPcapng prepares r to read the blocks of a pcapng file.
+func (r *Reader) initPcapng() error {
+	var (
+		order      binary.ByteOrder = binary.BigEndian
+		interfaces []pcapngInterface
+	)
+	// block reads the next block, and returns its type and body.
+	block := func() (uint32, []byte, error) {
+		var h [8]byte
+		if _, err := io.ReadFull(r.r, h[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return 0, nil, ErrFormat
+			}
+			return 0, nil, err
+		}
+		typ := binary.BigEndian.Uint32(h[:]) // the type of a SHB reads the same in both orders
+		if typ == pcapngBlockSHB {
+			var bo [4]byte
+			if _, err := io.ReadFull(r.r, bo[:]); err != nil {
+				return 0, nil, ErrFormat
+			}
+			switch {
+			case binary.BigEndian.Uint32(bo[:]) == pcapngByteOrder:
+				order = binary.BigEndian
+			case binary.LittleEndian.Uint32(bo[:]) == pcapngByteOrder:
+				order = binary.LittleEndian
+			default:
+				return 0, nil, ErrFormat
+			}
+			interfaces = interfaces[:0]
+		} else {
+			typ = order.Uint32(h[:])
+		}
+		l := order.Uint32(h[4:])
+		if l < 12 || l%4 != 0 || l > maxPcapRecordSize {
+			return 0, nil, ErrFormat
+		}
+		body := make([]byte, l-8)
+		n := 0
+		if typ == pcapngBlockSHB {
+			order.PutUint32(body, pcapngByteOrder)
+			n = 4
+		}
+		if _, err := io.ReadFull(r.r, body[n:]); err != nil {
+			return 0, nil, ErrFormat
+		}
+		return typ, body[:len(body)-4], nil // without the trailing length
+	}
+
+	r.next = func() (*Packet, error) {
+		typ, body, err := block()
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case pcapngBlockIDB:
+			if len(body) < 8 {
+				return nil, ErrFormat
+			}
+			iface := pcapngInterface{link: uint32(order.Uint16(body)), tsresol: 6}
+			for opts := body[8:]; len(opts) >= 4; {
+				code, l := order.Uint16(opts), int(order.Uint16(opts[2:]))
+				if code == pcapngOptEnd || 4+l > len(opts) {
+					break
+				}
+				if code == pcapngOptTsresol && l == 1 {
+					iface.tsresol = opts[4]
+				}
+				opts = opts[4+(l+3)&^3:]
+			}
+			interfaces = append(interfaces, iface)
+		case pcapngBlockEPB:
+			if len(body) < 20 {
+				return nil, ErrFormat
+			}
+			id, caplen := order.Uint32(body), order.Uint32(body[12:])
+			if int(id) >= len(interfaces) || 20+int(caplen) > len(body) {
+				return nil, ErrFormat
+			}
+			ts := uint64(order.Uint32(body[4:]))<<32 | uint64(order.Uint32(body[8:]))
+			iface := interfaces[id]
+			r.decodeLink(pcapngTime(ts, iface.tsresol), iface.link, body[20:20+caplen])
+		case pcapngBlockSPB:
+			if len(interfaces) == 0 || len(body) < 4 {
+				return nil, ErrFormat
+			}
+			data := body[4:]
+			if l := order.Uint32(body); int(l) < len(data) {
+				data = data[:l]
+			}
+			r.decodeLink(time.Time{}, interfaces[0].link, data) // simple packet blocks have no timestamp
+		}
+		return nil, nil
+	}
+	return nil
+}
+
+// pcapngTime returns the time of the timestamp ts of resolution tsresol.
+func pcapngTime(ts uint64, tsresol byte) time.Time {
+	exp := uint(tsresol & 0x7f)
+	if tsresol&0x80 != 0 { // a negative power of 2
+		if exp >= 64 {
+			return time.Time{}
+		}
+		frac := ts & (1<<exp - 1)
+		return time.Unix(int64(ts>>exp), int64(float64(frac)/float64(uint64(1)<<exp)*1e9))
+	}
+	if exp > 19 {
+		return time.Time{}
+	}
+	unit := uint64(1)
+	for i := uint(0); i < exp; i++ {
+		unit *= 10
+	}
+	sec, frac := ts/unit, ts%unit
+	for ; exp < 9; exp++ {
+		frac *= 10
+	}
+	for ; exp > 9; exp-- {
+		frac /= 10
+	}
+	return time.Unix(int64(sec), int64(frac))
+}
@@ -0,0 +1,306 @@
+package dns
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// DNSCrypt encryption systems, the ESVersion of a DNSCryptCert.
+const (
+	DNSCryptXSalsa20Poly1305  = 1
+	DNSCryptXChacha20Poly1305 = 2
+)
+
+// dnscryptResolverMagic starts the responses of DNSCrypt resolvers.
+var dnscryptResolverMagic = [8]byte{0x72, 0x36, 0x66, 0x6e, 0x76, 0x57, 0x6a, 0x38}
+
+const (
+	dnscryptCertLen        = 124 // length of a certificate without extensions
+	dnscryptMinQueryLen    = 256 // initial minimum length of padded UDP queries
+	dnscryptQueryHdrLen    = 8 + 32 + 12
+	dnscryptResponseHdrLen = 8 + 24
+)
+
+var (
+	// ErrDNSCryptCert is returned when a DNSCrypt resolver has no valid certificate.
+	ErrDNSCryptCert error = &Error{err: "no valid DNSCrypt certificate"}
+	// ErrDNSCryptResponse is returned when a DNSCrypt response can't be decrypted.
+	ErrDNSCryptResponse error = &Error{err: "bad DNSCrypt response"}
+)
+
+// A DNSCryptCert is a certificate of a DNSCrypt resolver, as published in a
+// TXT record of its provider name.
+type DNSCryptCert struct {
+	ESVersion   uint16   // encryption system
+	ResolverKey [32]byte // X25519 key of the resolver
+	ClientMagic [8]byte  // prefix of the queries encrypted with this certificate
+	Serial      uint32
+	NotBefore   time.Time
+	NotAfter    time.Time
+}
+
+// ParseDNSCryptCert parses the certificate b, and checks that it is signed
+// with providerKey.
+func ParseDNSCryptCert(b []byte, providerKey ed25519.PublicKey) (*DNSCryptCert, error) {
+	if len(b) < dnscryptCertLen || string(b[:4]) != "DNSC" {
+		return nil, &Error{err: "bad DNSCrypt certificate"}
+	}
+	if len(providerKey) != ed25519.PublicKeySize || !ed25519.Verify(providerKey, b[72:], b[8:72]) {
+		return nil, &Error{err: "bad DNSCrypt certificate signature"}
+	}
+	c := &DNSCryptCert{
+		ESVersion: binary.BigEndian.Uint16(b[4:]),
+		Serial:    binary.BigEndian.Uint32(b[112:]),
+		NotBefore: time.Unix(int64(binary.BigEndian.Uint32(b[116:])), 0),
+		NotAfter:  time.Unix(int64(binary.BigEndian.Uint32(b[120:])), 0),
+	}
+	copy(c.ResolverKey[:], b[72:104])
+	copy(c.ClientMagic[:], b[104:112])
+	return c, nil
+}
+
+// valid reports whether c can be used at t.
+func (c *DNSCryptCert) valid(t time.Time) bool {
+	return c.ESVersion == DNSCryptXSalsa20Poly1305 && !t.Before(c.NotBefore) && t.Before(c.NotAfter)
+}
+
+// A DNSCryptClient is an Exchanger that sends queries to DNSCrypt resolvers
+// (version 2 of the protocol, https://dnscrypt.info/protocol). The
+// certificate of a resolver is looked up with a TXT query for ProviderName
+// the first time it is used, and again when it expires.
+//
+// Only the X25519-XSalsa20Poly1305 encryption system is supported. Each query
+// is encrypted with a new key pair.
+type DNSCryptClient struct {
+	Client       *Client           // Net ("udp" or "tcp"), Dialer and timeouts of the queries, defaults to a UDP Client
+	ProviderName string            // name of the certificates, e.g. "2.dnscrypt-cert.example.org."
+	ProviderKey  ed25519.PublicKey // key that signs the certificates
+
+	mu          sync.Mutex
+	certs       map[string]*DNSCryptCert // certificate per resolver address
+	minQueryLen int                      // minimum length of padded UDP queries
+}
+
+// Exchange implements the Exchanger interface. Resolvers truncate the UDP
+// responses larger than the query, the padding of the following UDP queries
+// grows every time that happens.
+func (c *DNSCryptClient) Exchange(m *Msg, address string) (r *Msg, rtt time.Duration, err error) {
+	cert, err := c.cert(address)
+	if err != nil {
+		return nil, 0, err
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pk, sk, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	var shared [32]byte
+	box.Precompute(&shared, &cert.ResolverKey, sk)
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:12]); err != nil {
+		return nil, 0, err
+	}
+
+	cl := c.client()
+	udp := cl.network(attempt{}) == "udp"
+	size := dnscryptPadLen(len(buf), 0)
+	if udp {
+		size = dnscryptPadLen(len(buf), c.queryLen())
+	}
+	if dnscryptQueryHdrLen+box.Overhead+size > MaxMsgSize {
+		return nil, 0, ErrBuf
+	}
+	q := make([]byte, dnscryptQueryHdrLen, dnscryptQueryHdrLen+box.Overhead+size)
+	copy(q, cert.ClientMagic[:])
+	copy(q[8:], pk[:])
+	copy(q[40:], nonce[:12])
+	q = box.SealAfterPrecomputation(q, dnscryptPad(buf, size), &nonce, &shared)
+
+	co, err := cl.Dial(address)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer co.Close()
+
+	t := time.Now()
+	co.SetWriteDeadline(t.Add(cl.getTimeoutForRequest(cl.writeTimeout())))
+	if _, err := co.Write(q); err != nil {
+		return nil, 0, err
+	}
+	co.SetReadDeadline(time.Now().Add(cl.getTimeoutForRequest(cl.readTimeout())))
+	p := make([]byte, MaxMsgSize)
+	n, err := co.Read(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	rtt = time.Since(t)
+
+	p = p[:n]
+	if len(p) < dnscryptResponseHdrLen+box.Overhead || !bytes.Equal(p[:8], dnscryptResolverMagic[:]) || !bytes.Equal(p[8:20], nonce[:12]) {
+		return nil, rtt, ErrDNSCryptResponse
+	}
+	copy(nonce[:], p[8:32])
+	out, ok := box.OpenAfterPrecomputation(nil, p[dnscryptResponseHdrLen:], &nonce, &shared)
+	if !ok {
+		return nil, rtt, ErrDNSCryptResponse
+	}
+	if out, ok = dnscryptUnpad(out); !ok {
+		return nil, rtt, ErrDNSCryptResponse
+	}
+	r = new(Msg)
+	if err := r.Unpack(out); err != nil {
+		return r, rtt, err
+	}
+	if r.Id != m.Id {
+		return r, rtt, ErrId
+	}
+	if udp && r.Truncated {
+		c.growQueryLen()
+	}
+	return r, rtt, nil
+}
+
+// FetchCert looks up the certificates of the resolver at address, and returns
+// the one to use now: the valid certificate with the highest serial.
+func (c *DNSCryptClient) FetchCert(address string) (*DNSCryptCert, error) {
+	m := new(Msg)
+	m.SetQuestion(Fqdn(c.ProviderName), TypeTXT)
+	m.SetEdns0(4096, false)
+	r, _, err := c.client().Exchange(m, address)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var best *DNSCryptCert
+	for _, rr := range r.Answer {
+		txt, ok := rr.(*TXT)
+		if !ok {
+			continue
+		}
+		b, err := txtBytes(txt.Txt)
+		if err != nil {
+			continue
+		}
+		cert, err := ParseDNSCryptCert(b, c.ProviderKey)
+		if err != nil || !cert.valid(now) {
+			continue
+		}
+		if best == nil || cert.Serial > best.Serial {
+			best = cert
+		}
+	}
+	if best == nil {
+		return nil, ErrDNSCryptCert
+	}
+	return best, nil
+}
+
+// cert returns the certificate for the resolver at address, fetching it when
+// there is none or it expired.
+func (c *DNSCryptClient) cert(address string) (*DNSCryptCert, error) {
+	c.mu.Lock()
+	cert := c.certs[address]
+	c.mu.Unlock()
+	if cert != nil && cert.valid(time.Now()) {
+		return cert, nil
+	}
+
+	cert, err := c.FetchCert(address)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.certs == nil {
+		c.certs = make(map[string]*DNSCryptCert)
+	}
+	c.certs[address] = cert
+	c.mu.Unlock()
+	return cert, nil
+}
+
+func (c *DNSCryptClient) client() *Client {
+	if c.Client == nil {
+		return new(Client)
+	}
+	return c.Client
+}
+
+func (c *DNSCryptClient) queryLen() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.minQueryLen == 0 {
+		return dnscryptMinQueryLen
+	}
+	return c.minQueryLen
+}
+
+func (c *DNSCryptClient) growQueryLen() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.minQueryLen == 0 {
+		c.minQueryLen = dnscryptMinQueryLen
+	}
+	if c.minQueryLen+64 <= MaxMsgSize-dnscryptQueryHdrLen-box.Overhead {
+		c.minQueryLen += 64
+	}
+}
+
+// dnscryptPadLen returns the padded length of a message of n bytes: a
+// multiple of 64 bytes, of at least min bytes, with room for the 0x80 byte
+// that starts the padding.
+func dnscryptPadLen(n, min int) int {
+	n = (n + 64) &^ 63
+	if n < min {
+		return min
+	}
+	return n
+}
+
+// dnscryptPad pads buf to size bytes, with 0x80 followed by zeros (ISO/IEC
+// 7816-4).
+func dnscryptPad(buf []byte, size int) []byte {
+	p := make([]byte, size)
+	copy(p, buf)
+	p[len(buf)] = 0x80
+	return p
+}
+
+// dnscryptUnpad removes the padding of buf.
+func dnscryptUnpad(buf []byte) ([]byte, bool) {
+	i := bytes.LastIndexByte(buf, 0x80)
+	if i < 0 {
+		return nil, false
+	}
+	for _, b := range buf[i+1:] {
+		if b != 0 {
+			return nil, false
+		}
+	}
+	return buf[:i], true
+}
+
+// txtBytes returns the concatenated bytes of the character-strings in txt.
+func txtBytes(txt []string) ([]byte, error) {
+	b := make([]byte, txtLen(txt))
+	tmp := make([]byte, 256*4+1)
+	off := 0
+	for _, s := range txt {
+		end, err := packTxtString(s, b, off, tmp)
+		if err != nil {
+			return nil, err
+		}
+		// Drop the length byte.
+		off += copy(b[off:], b[off+1:end])
+	}
+	return b[:off], nil
+}
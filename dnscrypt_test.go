@@ -0,0 +1,147 @@
+package dns
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// dnscryptTestCert returns a certificate for resolverKey, signed with sk.
+func dnscryptTestCert(sk ed25519.PrivateKey, resolverKey *[32]byte, serial uint32, notBefore, notAfter time.Time) []byte {
+	b := make([]byte, dnscryptCertLen)
+	copy(b, "DNSC")
+	binary.BigEndian.PutUint16(b[4:], DNSCryptXSalsa20Poly1305)
+	copy(b[72:], resolverKey[:])
+	copy(b[104:], fmt.Sprintf("magic%03d", serial))
+	binary.BigEndian.PutUint32(b[112:], serial)
+	binary.BigEndian.PutUint32(b[116:], uint32(notBefore.Unix()))
+	binary.BigEndian.PutUint32(b[120:], uint32(notAfter.Unix()))
+	copy(b[8:72], ed25519.Sign(sk, b[72:]))
+	return b
+}
+
+// dnscryptTestResolver serves the certificates certs, and answers the queries
+// encrypted for resolverSK with HelloServer.
+func dnscryptTestResolver(t *testing.T, resolverSK *[32]byte, certs ...[]byte) (string, func()) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	go func() {
+		p := make([]byte, MaxMsgSize)
+		for {
+			n, addr, err := pc.ReadFrom(p)
+			if err != nil {
+				return
+			}
+			q := p[:n]
+			if !bytes.HasPrefix(q, []byte("magic")) {
+				req := new(Msg)
+				if req.Unpack(q) != nil {
+					continue
+				}
+				m := new(Msg)
+				m.SetReply(req)
+				for _, cert := range certs {
+					var txt strings.Builder
+					for _, b := range cert {
+						fmt.Fprintf(&txt, "\\%03d", b)
+					}
+					m.Answer = append(m.Answer, &TXT{Hdr: RR_Header{Name: req.Question[0].Name, Rrtype: TypeTXT, Class: ClassINET, Ttl: 60}, Txt: []string{txt.String()}})
+				}
+				out, _ := m.Pack()
+				pc.WriteTo(out, addr)
+				continue
+			}
+
+			var pk, shared [32]byte
+			var nonce [24]byte
+			copy(pk[:], q[8:40])
+			copy(nonce[:12], q[40:52])
+			box.Precompute(&shared, &pk, resolverSK)
+			buf, ok := box.OpenAfterPrecomputation(nil, q[52:], &nonce, &shared)
+			if !ok || len(buf) < dnscryptMinQueryLen || len(buf)%64 != 0 {
+				t.Errorf("bad query of %d bytes", len(buf))
+				continue
+			}
+			buf, _ = dnscryptUnpad(buf)
+			req := new(Msg)
+			if err := req.Unpack(buf); err != nil {
+				t.Errorf("bad query: %v", err)
+				continue
+			}
+			rw := new(recordWriter)
+			HelloServer(rw, req)
+			out, _ := rw.msgs[0].Pack()
+
+			rand.Read(nonce[12:])
+			r := append(dnscryptResolverMagic[:0:0], dnscryptResolverMagic[:]...)
+			r = append(r, nonce[:]...)
+			r = box.SealAfterPrecomputation(r, dnscryptPad(out, dnscryptPadLen(len(out), 0)), &nonce, &shared)
+			pc.WriteTo(r, addr)
+		}
+	}()
+	return pc.LocalAddr().String(), func() { pc.Close() }
+}
+
+func TestDNSCryptClient(t *testing.T) {
+	providerPK, providerSK, _ := ed25519.GenerateKey(rand.Reader)
+	resolverPK, resolverSK, _ := box.GenerateKey(rand.Reader)
+	_, otherSK, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+	addr, stop := dnscryptTestResolver(t, resolverSK,
+		dnscryptTestCert(providerSK, resolverPK, 1, now.Add(-time.Hour), now.Add(time.Hour)),
+		dnscryptTestCert(providerSK, resolverPK, 3, now.Add(-2*time.Hour), now.Add(-time.Hour)),
+		dnscryptTestCert(otherSK, resolverPK, 4, now.Add(-time.Hour), now.Add(time.Hour)),
+		dnscryptTestCert(providerSK, resolverPK, 2, now.Add(-time.Hour), now.Add(time.Hour)),
+	)
+	defer stop()
+
+	c := &DNSCryptClient{ProviderName: "2.dnscrypt-cert.example.org", ProviderKey: providerPK}
+	cert, err := c.FetchCert(addr)
+	if err != nil {
+		t.Fatalf("failed to fetch certificate: %v", err)
+	}
+	if cert.Serial != 2 || cert.ResolverKey != *resolverPK {
+		t.Errorf("expected valid certificate with serial 2, got %+v", cert)
+	}
+
+	m := new(Msg).SetQuestion("miek.nl.", TypeTXT)
+	r, _, err := c.Exchange(m, addr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.Id != m.Id || len(r.Extra) != 1 || r.Extra[0].(*TXT).Txt[0] != "Hello world" {
+		t.Errorf("unexpected response %v", r)
+	}
+}
+
+func TestDNSCryptPad(t *testing.T) {
+	for _, tc := range []struct{ n, min, want int }{
+		{0, 0, 64},
+		{63, 0, 64},
+		{64, 0, 128},
+		{30, 256, 256},
+		{300, 256, 320},
+	} {
+		if got := dnscryptPadLen(tc.n, tc.min); got != tc.want {
+			t.Errorf("dnscryptPadLen(%d, %d): expected %d, got %d", tc.n, tc.min, tc.want, got)
+		}
+	}
+	buf := []byte{1, 0x80, 0}
+	out, ok := dnscryptUnpad(dnscryptPad(buf, 64))
+	if !ok || !bytes.Equal(out, buf) {
+		t.Errorf("expected %v after padding, got %v", buf, out)
+	}
+	if _, ok := dnscryptUnpad([]byte{1, 0x80, 1}); ok {
+		t.Errorf("expected bad padding")
+	}
+}
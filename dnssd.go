@@ -0,0 +1,289 @@
+package dns
+
+import (
+	"net"
+	"sort"
+	"strings"
+)
+
+var errNoService = &Error{err: "no SRV record for service instance"}
+
+// A ServiceInstance is an instance of a service, as DNS-based Service Discovery
+// (RFC 6763) describes it with a SRV and a TXT record.
+type ServiceInstance struct {
+	Instance string // instance name, any UTF-8 text of at most 63 bytes, e.g. "Printer on 3rd floor"
+	Service  string // service type, e.g. "_ipp._tcp"
+	Domain   string // domain the service is in, e.g. "local." or "example.org."
+
+	Target   string // host name the service runs on
+	Port     uint16 // port the service listens on
+	Priority uint16 // priority of the SRV record
+	Weight   uint16 // weight of the SRV record
+
+	// Text holds the key/value attributes of the TXT record, with lower
+	// cased keys. Attributes without a value ("key" rather than "key=") are
+	// kept with an empty value, and are written back that way.
+	Text map[string]string
+
+	Addrs []net.IP // addresses of Target, if known
+}
+
+// Name returns the name of the service instance, see ServiceInstanceName.
+func (s *ServiceInstance) Name() string {
+	return ServiceInstanceName(s.Instance, s.Service, s.Domain)
+}
+
+// ServiceInstanceName returns the domain name of a service instance: instance,
+// escaped to a single label, followed by service and domain.
+func ServiceInstanceName(instance, service, domain string) string {
+	var b strings.Builder
+	for i := 0; i < len(instance); i++ {
+		writeDomainNameByte(&b, instance[i])
+	}
+	return b.String() + "." + dnssdName(service, domain)
+}
+
+// dnssdName returns the fully qualified name of prefix in domain.
+func dnssdName(prefix, domain string) string {
+	domain = strings.Trim(domain, ".")
+	if domain == "" {
+		return Fqdn(prefix)
+	}
+	return Fqdn(prefix + "." + domain)
+}
+
+// SplitServiceInstanceName splits the name of a service instance in its
+// instance name, with the escapes removed, service type and domain. It
+// returns false if name isn't a service instance name.
+func SplitServiceInstanceName(name string) (instance, service, domain string, ok bool) {
+	labels := SplitDomainName(name)
+	if len(labels) < 3 || !strings.HasPrefix(labels[1], "_") || !strings.HasPrefix(labels[2], "_") {
+		return "", "", "", false
+	}
+	domain = "."
+	if len(labels) > 3 {
+		domain = strings.Join(labels[3:], ".") + "."
+	}
+	return unescapeLabel(labels[0]), labels[1] + "." + labels[2], domain, true
+}
+
+// unescapeLabel returns the label s with its escapes removed.
+func unescapeLabel(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c, n := nextByte(s, i)
+		if n == 0 {
+			break
+		}
+		b.WriteByte(c)
+		i += n
+	}
+	return b.String()
+}
+
+// RRs returns the records that announce s, with a TTL of ttl: the PTR record
+// of the service type that points to the instance, the PTR record for service
+// type enumeration, the SRV and TXT records of the instance, and the address
+// records of its target.
+func (s *ServiceInstance) RRs(ttl uint32) []RR {
+	service := dnssdName(s.Service, s.Domain)
+	name := s.Name()
+	hdr := func(name string, t uint16) RR_Header {
+		return RR_Header{Name: name, Rrtype: t, Class: ClassINET, Ttl: ttl}
+	}
+
+	rrs := []RR{
+		&PTR{Hdr: hdr(service, TypePTR), Ptr: name},
+		&PTR{Hdr: hdr(dnssdName("_services._dns-sd._udp", s.Domain), TypePTR), Ptr: service},
+		&SRV{Hdr: hdr(name, TypeSRV), Priority: s.Priority, Weight: s.Weight, Port: s.Port, Target: Fqdn(s.Target)},
+		&TXT{Hdr: hdr(name, TypeTXT), Txt: ServiceTxt(s.Text)},
+	}
+	for _, ip := range s.Addrs {
+		if ip4 := ip.To4(); ip4 != nil {
+			rrs = append(rrs, &A{Hdr: hdr(Fqdn(s.Target), TypeA), A: ip4})
+		} else {
+			rrs = append(rrs, &AAAA{Hdr: hdr(Fqdn(s.Target), TypeAAAA), AAAA: ip})
+		}
+	}
+	return rrs
+}
+
+// ServiceTxt returns the strings of the TXT record for the attributes in text,
+// sorted by key. Without attributes, it returns a single empty string, as RFC
+// 6763, Section 6.1 requires.
+func ServiceTxt(text map[string]string) []string {
+	if len(text) == 0 {
+		return []string{""}
+	}
+	keys := make([]string, 0, len(text))
+	for k := range text {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	txt := make([]string, 0, len(keys))
+	for _, k := range keys {
+		s := k
+		if v := text[k]; v != "" {
+			s += "=" + v
+		}
+		txt = append(txt, escapeTxt(s))
+	}
+	return txt
+}
+
+// ParseServiceTxt returns the attributes in the strings of a TXT record of a
+// service instance. Keys are lower cased, and only the first attribute with a
+// key is kept (RFC 6763, Section 6.4).
+func ParseServiceTxt(txt []string) map[string]string {
+	text := make(map[string]string)
+	for _, s := range txt {
+		s = unescapeLabel(s)
+		k, v := s, ""
+		if i := strings.IndexByte(s, '='); i >= 0 {
+			k, v = s[:i], s[i+1:]
+		}
+		if k == "" {
+			continue
+		}
+		k = strings.ToLower(k)
+		if _, ok := text[k]; !ok {
+			text[k] = v
+		}
+	}
+	return text
+}
+
+// escapeTxt escapes s like the strings of TXT records are.
+func escapeTxt(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		writeTXTStringByte(&b, s[i])
+	}
+	return b.String()
+}
+
+// A ServiceBrowser discovers services with DNS-based Service Discovery (RFC
+// 6763), by sending queries to a unicast DNS server. Multicast DNS can be used
+// with an Exchanger that sends the queries over it.
+type ServiceBrowser struct {
+	Exchanger Exchanger // where the queries are sent, defaults to a Client
+	Server    string    // address of the server, host:port
+}
+
+// Services returns the service types in domain, e.g. "_http._tcp".
+func (b *ServiceBrowser) Services(domain string) ([]string, error) {
+	rrs, _, err := b.query(dnssdName("_services._dns-sd._udp", domain), TypePTR)
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, rr := range rrs {
+		ptr, ok := rr.(*PTR)
+		if !ok {
+			continue
+		}
+		labels := SplitDomainName(ptr.Ptr)
+		if len(labels) >= 2 && IsSubDomain(Fqdn(domain), ptr.Ptr) {
+			services = append(services, labels[0]+"."+labels[1])
+		}
+	}
+	return services, nil
+}
+
+// Browse returns the names of the instances of service in domain.
+func (b *ServiceBrowser) Browse(service, domain string) ([]string, error) {
+	rrs, _, err := b.query(dnssdName(service, domain), TypePTR)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, rr := range rrs {
+		if ptr, ok := rr.(*PTR); ok {
+			names = append(names, ptr.Ptr)
+		}
+	}
+	return names, nil
+}
+
+// Resolve returns the service instance with the given name, as Browse returns
+// it. The addresses of its target are taken from the additional section of the
+// SRV response, or queried when they aren't there.
+func (b *ServiceBrowser) Resolve(name string) (*ServiceInstance, error) {
+	instance, service, domain, ok := SplitServiceInstanceName(name)
+	if !ok {
+		return nil, &Error{err: "not a service instance name: " + name}
+	}
+	rrs, extra, err := b.query(name, TypeSRV)
+	if err != nil {
+		return nil, err
+	}
+	s := &ServiceInstance{Instance: instance, Service: service, Domain: domain}
+	for _, rr := range rrs {
+		if srv, ok := rr.(*SRV); ok {
+			s.Target, s.Port, s.Priority, s.Weight = srv.Target, srv.Port, srv.Priority, srv.Weight
+			break
+		}
+	}
+	if s.Target == "" {
+		return nil, errNoService
+	}
+
+	if rrs, _, err = b.query(name, TypeTXT); err != nil {
+		return nil, err
+	}
+	s.Text = make(map[string]string)
+	for _, rr := range rrs {
+		if txt, ok := rr.(*TXT); ok {
+			s.Text = ParseServiceTxt(txt.Txt)
+			break
+		}
+	}
+
+	s.Addrs = addrsOf(s.Target, extra)
+	if len(s.Addrs) == 0 {
+		for _, t := range []uint16{TypeA, TypeAAAA} {
+			rrs, _, err := b.query(s.Target, t)
+			if err != nil {
+				return nil, err
+			}
+			s.Addrs = append(s.Addrs, addrsOf(s.Target, rrs)...)
+		}
+	}
+	return s, nil
+}
+
+// addrsOf returns the addresses of name in rrs.
+func addrsOf(name string, rrs []RR) []net.IP {
+	var addrs []net.IP
+	for _, rr := range rrs {
+		if !equal(rr.Header().Name, name) {
+			continue
+		}
+		switch rr := rr.(type) {
+		case *A:
+			addrs = append(addrs, rr.A)
+		case *AAAA:
+			addrs = append(addrs, rr.AAAA)
+		}
+	}
+	return addrs
+}
+
+// query returns the answer and additional section of the response to a query
+// for name and qtype.
+func (b *ServiceBrowser) query(name string, qtype uint16) ([]RR, []RR, error) {
+	ex := b.Exchanger
+	if ex == nil {
+		ex = new(Client)
+	}
+	m := new(Msg)
+	m.SetQuestion(name, qtype)
+	r, _, err := ex.Exchange(m, b.Server)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.Rcode != RcodeSuccess && r.Rcode != RcodeNameError {
+		return nil, nil, &Error{err: "service discovery failed with rcode " + RcodeToString[r.Rcode]}
+	}
+	return r.Answer, r.Extra, nil
+}
@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestServiceInstanceName(t *testing.T) {
+	name := ServiceInstanceName("Printer 3.1", "_ipp._tcp", "example.org")
+	if name != `Printer\ 3\.1._ipp._tcp.example.org.` {
+		t.Errorf("unexpected name %q", name)
+	}
+	instance, service, domain, ok := SplitServiceInstanceName(name)
+	if !ok || instance != "Printer 3.1" || service != "_ipp._tcp" || domain != "example.org." {
+		t.Errorf("failed to split %q: %q %q %q", name, instance, service, domain)
+	}
+	if _, _, _, ok := SplitServiceInstanceName("www.example.org."); ok {
+		t.Error("expected www.example.org. not to be a service instance name")
+	}
+}
+
+func TestServiceTxt(t *testing.T) {
+	txt := ServiceTxt(map[string]string{"path": `/a "b"`, "secure": ""})
+	if !reflect.DeepEqual(txt, []string{`path=/a \"b\"`, "secure"}) {
+		t.Errorf("unexpected TXT strings %q", txt)
+	}
+	text := ParseServiceTxt([]string{"Path=/x", "path=/y", "secure", "=bad"})
+	if !reflect.DeepEqual(text, map[string]string{"path": "/x", "secure": ""}) {
+		t.Errorf("unexpected attributes %v", text)
+	}
+	if txt := ServiceTxt(nil); !reflect.DeepEqual(txt, []string{""}) {
+		t.Errorf("expected a single empty string, got %q", txt)
+	}
+}
+
+func TestServiceBrowser(t *testing.T) {
+	want := &ServiceInstance{
+		Instance: "Web server",
+		Service:  "_http._tcp",
+		Domain:   "example.org.",
+		Target:   "web.example.org.",
+		Port:     8080,
+		Text:     map[string]string{"path": "/index.html"},
+		Addrs:    []net.IP{net.ParseIP("192.0.2.1").To4(), net.ParseIP("2001:db8::1")},
+	}
+	rrs := want.RRs(120)
+
+	ex := exchangerFunc(func(m *Msg, address string) (*Msg, time.Duration, error) {
+		r := new(Msg)
+		r.SetReply(m)
+		q := m.Question[0]
+		for _, rr := range rrs {
+			if equal(rr.Header().Name, q.Name) && rr.Header().Rrtype == q.Qtype {
+				r.Answer = append(r.Answer, rr)
+			}
+		}
+		return r, 0, nil
+	})
+	b := &ServiceBrowser{Exchanger: ex, Server: "192.0.2.53:53"}
+
+	services, err := b.Services("example.org.")
+	if err != nil || !reflect.DeepEqual(services, []string{"_http._tcp"}) {
+		t.Fatalf("unexpected services %v: %v", services, err)
+	}
+	names, err := b.Browse("_http._tcp", "example.org.")
+	if err != nil || len(names) != 1 {
+		t.Fatalf("unexpected instances %v: %v", names, err)
+	}
+	got, err := b.Resolve(names[0])
+	if err != nil {
+		t.Fatalf("failed to resolve %s: %v", names[0], err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	if _, err := b.Resolve(ServiceInstanceName("Other", "_http._tcp", "example.org.")); err != errNoService {
+		t.Errorf("expected errNoService, got %v", err)
+	}
+}
@@ -74,6 +74,7 @@ var StringToAlgorithm = reverseInt8(AlgorithmToString)
 var AlgorithmToHash = map[uint8]crypto.Hash{
 	RSAMD5:           crypto.MD5, // Deprecated in RFC 6725
 	DSA:              crypto.SHA1,
+	DSANSEC3SHA1:     crypto.SHA1,
 	RSASHA1:          crypto.SHA1,
 	RSASHA1NSEC3SHA1: crypto.SHA1,
 	RSASHA256:        crypto.SHA256,
@@ -91,6 +92,8 @@ const (
 	GOST94       // RFC 5933
 	SHA384       // Experimental
 	SHA512       // Experimental
+
+	GOST12 = SHA512 // RFC 9558, GOST R 34.11-2012, not implemented, see RegisterDigest
 )
 
 // HashToString is a map of hash IDs to names.
@@ -179,7 +182,8 @@ func (k *DNSKEY) KeyTag() uint16 {
 	return uint16(keytag)
 }
 
-// ToDS converts a DNSKEY record to a DS record.
+// ToDS converts a DNSKEY record to a DS record with the digest type h. It
+// returns nil when h isn't registered, see RegisterDigest.
 func (k *DNSKEY) ToDS(h uint8) *DS {
 	if k == nil {
 		return nil
@@ -216,21 +220,12 @@ func (k *DNSKEY) ToDS(h uint8) *DS {
 	// "|" denotes concatenation
 	// DNSKEY RDATA = Flags | Protocol | Algorithm | Public Key.
 
-	var hash crypto.Hash
-	switch h {
-	case SHA1:
-		hash = crypto.SHA1
-	case SHA256:
-		hash = crypto.SHA256
-	case SHA384:
-		hash = crypto.SHA384
-	case SHA512:
-		hash = crypto.SHA512
-	default:
+	d, ok := digestAlgorithms[h]
+	if !ok {
 		return nil
 	}
 
-	s := hash.New()
+	s := d.New()
 	s.Write(owner)
 	s.Write(wire)
 	ds.Digest = hex.EncodeToString(s.Sum(nil))
@@ -263,9 +258,39 @@ func (rr *RRSIG) Sign(k crypto.Signer, rrset []RR) error {
 	if k == nil {
 		return ErrPrivKey
 	}
+	data, err := rr.SigningData(rrset)
+	if err != nil {
+		return err
+	}
+
+	alg, ok := signatureAlgorithms[rr.Algorithm]
+	if !ok {
+		return ErrAlg
+	}
+
+	signature, err := alg.sign(k, data)
+	if err != nil {
+		return err
+	}
+
+	rr.Signature = toBase64(signature)
+	return nil
+}
+
+// SigningData returns the data that is signed to make the signature of
+// rrset, the RRSIG RDATA without the signature followed by the RRset in
+// canonical form (RFC 4034, section 3.1.8.1). The signature needs to be filled
+// in as for Sign, the rest is copied from the RRset.
+//
+// SigningData and SetSignature split Sign in two, so that the signing can be
+// done elsewhere, such as on an offline machine holding the key-signing key.
+func (rr *RRSIG) SigningData(rrset []RR) ([]byte, error) {
 	// s.Inception and s.Expiration may be 0 (rollover etc.), the rest must be set
 	if rr.KeyTag == 0 || len(rr.SignerName) == 0 || rr.Algorithm == 0 {
-		return ErrKey
+		return nil, ErrKey
+	}
+	if len(rrset) == 0 {
+		return nil, ErrRRset
 	}
 
 	rr.Hdr.Rrtype = TypeRRSIG
@@ -296,50 +321,45 @@ func (rr *RRSIG) Sign(k crypto.Signer, rrset []RR) error {
 	signdata := make([]byte, DefaultMsgSize)
 	n, err := packSigWire(sigwire, signdata)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	signdata = signdata[:n]
 	wire, err := rawSignatureData(rrset, rr)
 	if err != nil {
-		return err
-	}
-
-	hash, ok := AlgorithmToHash[rr.Algorithm]
-	if !ok {
-		return ErrAlg
+		return nil, err
 	}
+	return append(signdata, wire...), nil
+}
 
+// SetSignature sets the signature of rr to signature, made over the data
+// returned by SigningData. ECDSA signatures may be given ASN.1 encoded, as
+// crypto.Signer returns them, or as the concatenation of r and s that DNSSEC
+// uses; DSA signatures must be in the form of RFC 2536. Verify checks the
+// signature.
+func (rr *RRSIG) SetSignature(signature []byte) error {
 	switch rr.Algorithm {
-	case ED25519:
-		// ed25519 signs the raw message and performs hashing internally.
-		// All other supported signature schemes operate over the pre-hashed
-		// message, and thus ed25519 must be handled separately here.
-		//
-		// The raw message is passed directly into sign and crypto.Hash(0) is
-		// used to signal to the crypto.Signer that the data has not been hashed.
-		signature, err := sign(k, append(signdata, wire...), crypto.Hash(0), rr.Algorithm)
-		if err != nil {
+	case ECDSAP256SHA256, ECDSAP384SHA384:
+		var err error
+		if signature, err = ecdsaSignature(signature, rr.Algorithm); err != nil {
 			return err
 		}
-
-		rr.Signature = toBase64(signature)
-	default:
-		h := hash.New()
-		h.Write(signdata)
-		h.Write(wire)
-
-		signature, err := sign(k, h.Sum(nil), hash, rr.Algorithm)
-		if err != nil {
-			return err
+	case ED25519:
+		if len(signature) != ed25519.SignatureSize {
+			return ErrSig
 		}
-
-		rr.Signature = toBase64(signature)
 	}
-
+	if len(signature) == 0 {
+		return ErrSig
+	}
+	rr.Signature = toBase64(signature)
 	return nil
 }
 
 func sign(k crypto.Signer, hashed []byte, hash crypto.Hash, alg uint8) ([]byte, error) {
+	if err := signerMatchesAlgorithm(k.Public(), alg); err != nil {
+		return nil, err
+	}
+
 	signature, err := k.Sign(rand.Reader, hashed, hash)
 	if err != nil {
 		return nil, err
@@ -350,33 +370,27 @@ func sign(k crypto.Signer, hashed []byte, hash crypto.Hash, alg uint8) ([]byte,
 		return signature, nil
 
 	case ECDSAP256SHA256, ECDSAP384SHA384:
-		ecdsaSignature := &struct {
-			R, S *big.Int
-		}{}
-		if _, err := asn1.Unmarshal(signature, ecdsaSignature); err != nil {
-			return nil, err
+		return ecdsaSignature(signature, alg)
+
+	// There is no defined interface for what a DSA backed crypto.Signer returns,
+	// we assume the ASN.1 encoding that is also used for ECDSA.
+	case DSA, DSANSEC3SHA1:
+		pub, ok := k.Public().(*dsa.PublicKey)
+		if !ok {
+			return nil, ErrKeyAlg
 		}
 
-		var intlen int
-		switch alg {
-		case ECDSAP256SHA256:
-			intlen = 32
-		case ECDSAP384SHA384:
-			intlen = 48
+		r, s, err := unmarshalSignature(signature)
+		if err != nil {
+			return nil, err
 		}
 
-		signature := intToBytes(ecdsaSignature.R, intlen)
-		signature = append(signature, intToBytes(ecdsaSignature.S, intlen)...)
+		t := divRoundUp(divRoundUp(pub.P.BitLen(), 8)-64, 8)
+		signature := []byte{byte(t)}
+		signature = append(signature, intToBytes(r, 20)...)
+		signature = append(signature, intToBytes(s, 20)...)
 		return signature, nil
 
-	// There is no defined interface for what a DSA backed crypto.Signer returns
-	case DSA, DSANSEC3SHA1:
-		// 	t := divRoundUp(divRoundUp(p.PublicKey.Y.BitLen(), 8)-64, 8)
-		// 	signature := []byte{byte(t)}
-		// 	signature = append(signature, intToBytes(r1, 20)...)
-		// 	signature = append(signature, intToBytes(s1, 20)...)
-		// 	rr.Signature = signature
-
 	case ED25519:
 		return signature, nil
 	}
@@ -384,6 +398,81 @@ func sign(k crypto.Signer, hashed []byte, hash crypto.Hash, alg uint8) ([]byte,
 	return nil, ErrAlg
 }
 
+// ecdsaSignature returns the ECDSA signature, ASN.1 encoded or not, as the
+// concatenation of r and s.
+func ecdsaSignature(signature []byte, alg uint8) ([]byte, error) {
+	intlen := 32
+	if alg == ECDSAP384SHA384 {
+		intlen = 48
+	}
+
+	r, s, err := unmarshalSignature(signature)
+	if err != nil {
+		// Some hardware backed signers (PKCS#11 for instance) return the
+		// raw r || s concatenation instead of the ASN.1 encoding.
+		if len(signature) == 2*intlen {
+			return signature, nil
+		}
+		return nil, err
+	}
+
+	signature = intToBytes(r, intlen)
+	signature = append(signature, intToBytes(s, intlen)...)
+	return signature, nil
+}
+
+// unmarshalSignature decodes an ASN.1 encoded (r, s) signature pair as returned
+// by the ECDSA and DSA signers.
+func unmarshalSignature(signature []byte) (r, s *big.Int, err error) {
+	sig := &struct {
+		R, S *big.Int
+	}{}
+	rest, err := asn1.Unmarshal(signature, sig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 {
+		return nil, nil, ErrSig
+	}
+	return sig.R, sig.S, nil
+}
+
+// signerMatchesAlgorithm checks that the public key of a crypto.Signer can be
+// used with the DNSSEC algorithm alg. Public keys of a type not known to this
+// package are accepted as-is, this allows for signers that wrap their keys.
+func signerMatchesAlgorithm(pub crypto.PublicKey, alg uint8) error {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		switch alg {
+		case RSAMD5, RSASHA1, RSASHA1NSEC3SHA1, RSASHA256, RSASHA512:
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		switch alg {
+		case ECDSAP256SHA256:
+			if pub.Curve == elliptic.P256() {
+				return nil
+			}
+		case ECDSAP384SHA384:
+			if pub.Curve == elliptic.P384() {
+				return nil
+			}
+		}
+	case *dsa.PublicKey:
+		switch alg {
+		case DSA, DSANSEC3SHA1:
+			return nil
+		}
+	case ed25519.PublicKey:
+		if alg == ED25519 {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return ErrKeyAlg
+}
+
 // Verify validates an RRSet with the signature and key. This is only the
 // cryptographic test, the signature validity period must be checked separately.
 // This function copies the rdata of some RRs (to lowercase domain names) for the validation to work.
@@ -447,56 +536,12 @@ func (rr *RRSIG) Verify(k *DNSKEY, rrset []RR) error {
 		// remove the domain name and assume its ours?
 	}
 
-	hash, ok := AlgorithmToHash[rr.Algorithm]
+	alg, ok := signatureAlgorithms[rr.Algorithm]
 	if !ok {
 		return ErrAlg
 	}
 
-	switch rr.Algorithm {
-	case RSASHA1, RSASHA1NSEC3SHA1, RSASHA256, RSASHA512, RSAMD5:
-		// TODO(mg): this can be done quicker, ie. cache the pubkey data somewhere??
-		pubkey := k.publicKeyRSA() // Get the key
-		if pubkey == nil {
-			return ErrKey
-		}
-
-		h := hash.New()
-		h.Write(signeddata)
-		h.Write(wire)
-		return rsa.VerifyPKCS1v15(pubkey, hash, h.Sum(nil), sigbuf)
-
-	case ECDSAP256SHA256, ECDSAP384SHA384:
-		pubkey := k.publicKeyECDSA()
-		if pubkey == nil {
-			return ErrKey
-		}
-
-		// Split sigbuf into the r and s coordinates
-		r := new(big.Int).SetBytes(sigbuf[:len(sigbuf)/2])
-		s := new(big.Int).SetBytes(sigbuf[len(sigbuf)/2:])
-
-		h := hash.New()
-		h.Write(signeddata)
-		h.Write(wire)
-		if ecdsa.Verify(pubkey, h.Sum(nil), r, s) {
-			return nil
-		}
-		return ErrSig
-
-	case ED25519:
-		pubkey := k.publicKeyED25519()
-		if pubkey == nil {
-			return ErrKey
-		}
-
-		if ed25519.Verify(pubkey, append(signeddata, wire...), sigbuf) {
-			return nil
-		}
-		return ErrSig
-
-	default:
-		return ErrAlg
-	}
+	return alg.verify(k, append(signeddata, wire...), sigbuf)
 }
 
 // ValidityPeriod uses RFC1982 serial arithmetic to calculate
@@ -665,64 +710,7 @@ func rawSignatureData(rrset []RR, s *RRSIG) (buf []byte, err error) {
 			// Wildcard
 			r1.Header().Name = "*." + strings.Join(labels[len(labels)-int(s.Labels):], ".") + "."
 		}
-		// RFC 4034: 6.2.  Canonical RR Form. (2) - domain name to lowercase
-		r1.Header().Name = strings.ToLower(r1.Header().Name)
-		// 6.2. Canonical RR Form. (3) - domain rdata to lowercase.
-		//   NS, MD, MF, CNAME, SOA, MB, MG, MR, PTR,
-		//   HINFO, MINFO, MX, RP, AFSDB, RT, SIG, PX, NXT, NAPTR, KX,
-		//   SRV, DNAME, A6
-		//
-		// RFC 6840 - Clarifications and Implementation Notes for DNS Security (DNSSEC):
-		//	Section 6.2 of [RFC4034] also erroneously lists HINFO as a record
-		//	that needs conversion to lowercase, and twice at that.  Since HINFO
-		//	records contain no domain names, they are not subject to case
-		//	conversion.
-		switch x := r1.(type) {
-		case *NS:
-			x.Ns = strings.ToLower(x.Ns)
-		case *MD:
-			x.Md = strings.ToLower(x.Md)
-		case *MF:
-			x.Mf = strings.ToLower(x.Mf)
-		case *CNAME:
-			x.Target = strings.ToLower(x.Target)
-		case *SOA:
-			x.Ns = strings.ToLower(x.Ns)
-			x.Mbox = strings.ToLower(x.Mbox)
-		case *MB:
-			x.Mb = strings.ToLower(x.Mb)
-		case *MG:
-			x.Mg = strings.ToLower(x.Mg)
-		case *MR:
-			x.Mr = strings.ToLower(x.Mr)
-		case *PTR:
-			x.Ptr = strings.ToLower(x.Ptr)
-		case *MINFO:
-			x.Rmail = strings.ToLower(x.Rmail)
-			x.Email = strings.ToLower(x.Email)
-		case *MX:
-			x.Mx = strings.ToLower(x.Mx)
-		case *RP:
-			x.Mbox = strings.ToLower(x.Mbox)
-			x.Txt = strings.ToLower(x.Txt)
-		case *AFSDB:
-			x.Hostname = strings.ToLower(x.Hostname)
-		case *RT:
-			x.Host = strings.ToLower(x.Host)
-		case *SIG:
-			x.SignerName = strings.ToLower(x.SignerName)
-		case *PX:
-			x.Map822 = strings.ToLower(x.Map822)
-			x.Mapx400 = strings.ToLower(x.Mapx400)
-		case *NAPTR:
-			x.Replacement = strings.ToLower(x.Replacement)
-		case *KX:
-			x.Exchanger = strings.ToLower(x.Exchanger)
-		case *SRV:
-			x.Target = strings.ToLower(x.Target)
-		case *DNAME:
-			x.Target = strings.ToLower(x.Target)
-		}
+		canonicalize(r1)
 		// 6.2. Canonical RR Form. (5) - origTTL
 		wire := make([]byte, Len(r1)+1) // +1 to be safe(r)
 		off, err1 := PackRR(r1, wire, 0, nil, false)
@@ -742,6 +730,69 @@ func rawSignatureData(rrset []RR, s *RRSIG) (buf []byte, err error) {
 	return buf, nil
 }
 
+// canonicalize lower cases the owner name of r, and the domain names in its
+// rdata, as in the canonical RR form of RFC 4034, Section 6.2.
+func canonicalize(r RR) {
+	// RFC 4034: 6.2.  Canonical RR Form. (2) - domain name to lowercase
+	r.Header().Name = strings.ToLower(r.Header().Name)
+	// 6.2. Canonical RR Form. (3) - domain rdata to lowercase.
+	//   NS, MD, MF, CNAME, SOA, MB, MG, MR, PTR,
+	//   HINFO, MINFO, MX, RP, AFSDB, RT, SIG, PX, NXT, NAPTR, KX,
+	//   SRV, DNAME, A6
+	//
+	// RFC 6840 - Clarifications and Implementation Notes for DNS Security (DNSSEC):
+	//	Section 6.2 of [RFC4034] also erroneously lists HINFO as a record
+	//	that needs conversion to lowercase, and twice at that.  Since HINFO
+	//	records contain no domain names, they are not subject to case
+	//	conversion.
+	switch x := r.(type) {
+	case *NS:
+		x.Ns = strings.ToLower(x.Ns)
+	case *MD:
+		x.Md = strings.ToLower(x.Md)
+	case *MF:
+		x.Mf = strings.ToLower(x.Mf)
+	case *CNAME:
+		x.Target = strings.ToLower(x.Target)
+	case *SOA:
+		x.Ns = strings.ToLower(x.Ns)
+		x.Mbox = strings.ToLower(x.Mbox)
+	case *MB:
+		x.Mb = strings.ToLower(x.Mb)
+	case *MG:
+		x.Mg = strings.ToLower(x.Mg)
+	case *MR:
+		x.Mr = strings.ToLower(x.Mr)
+	case *PTR:
+		x.Ptr = strings.ToLower(x.Ptr)
+	case *MINFO:
+		x.Rmail = strings.ToLower(x.Rmail)
+		x.Email = strings.ToLower(x.Email)
+	case *MX:
+		x.Mx = strings.ToLower(x.Mx)
+	case *RP:
+		x.Mbox = strings.ToLower(x.Mbox)
+		x.Txt = strings.ToLower(x.Txt)
+	case *AFSDB:
+		x.Hostname = strings.ToLower(x.Hostname)
+	case *RT:
+		x.Host = strings.ToLower(x.Host)
+	case *SIG:
+		x.SignerName = strings.ToLower(x.SignerName)
+	case *PX:
+		x.Map822 = strings.ToLower(x.Map822)
+		x.Mapx400 = strings.ToLower(x.Mapx400)
+	case *NAPTR:
+		x.Replacement = strings.ToLower(x.Replacement)
+	case *KX:
+		x.Exchanger = strings.ToLower(x.Exchanger)
+	case *SRV:
+		x.Target = strings.ToLower(x.Target)
+	case *DNAME:
+		x.Target = strings.ToLower(x.Target)
+	}
+}
+
 func packSigWire(sw *rrsigWireFmt, msg []byte) (int, error) {
 	// copied from zmsg.go RRSIG packing
 	off, err := packUint16(sw.TypeCovered, msg, 0)
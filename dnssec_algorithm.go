@@ -0,0 +1,279 @@
+package dns
+
+import (
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"hash"
+	"math/big"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+// SignatureAlgorithm implements a DNSSEC signature algorithm. The algorithms
+// this package supports out of the box are registered as SignatureAlgorithms
+// as well, others (ED448 for instance) can be added with RegisterAlgorithm.
+type SignatureAlgorithm struct {
+	// Name is the mnemonic of the algorithm, e.g. "ED448".
+	Name string
+	// Hash is the hash applied to the signed data before it is given to Sign
+	// and Verify. Algorithms that hash the data themselves (ED25519, ED448)
+	// use crypto.Hash(0) here, they receive the data as-is.
+	Hash crypto.Hash
+	// Sign signs data with k and returns the signature in its wire format.
+	Sign func(k crypto.Signer, data []byte) ([]byte, error)
+	// Verify checks that sig is a valid signature over data made with the
+	// private key that belongs to the public key in k.
+	Verify func(k *DNSKEY, data, sig []byte) error
+	// ReadPrivateKey creates a private key from the key-value pairs of a
+	// BIND private key file, see (*DNSKEY).ReadPrivateKey. It may be nil.
+	ReadPrivateKey func(k *DNSKEY, m map[string]string) (crypto.PrivateKey, error)
+}
+
+// RegisterAlgorithm registers the signature algorithm a under the algorithm
+// number alg. An already registered algorithm with the same number is
+// replaced. The algorithm is used by (*RRSIG).Sign, (*RRSIG).Verify,
+// (*SIG).Sign, (*SIG).Verify and (*DNSKEY).ReadPrivateKey. RegisterAlgorithm
+// is not safe for concurrent use with these functions.
+func RegisterAlgorithm(alg uint8, a *SignatureAlgorithm) {
+	signatureAlgorithms[alg] = a
+	AlgorithmToHash[alg] = a.Hash
+	if a.Name != "" {
+		AlgorithmToString[alg] = a.Name
+		StringToAlgorithm[a.Name] = alg
+	}
+}
+
+// UnregisterAlgorithm removes the signature algorithm registered under the
+// algorithm number alg.
+func UnregisterAlgorithm(alg uint8) {
+	if a, ok := signatureAlgorithms[alg]; ok && a.Name != "" {
+		delete(StringToAlgorithm, a.Name)
+		delete(AlgorithmToString, alg)
+	}
+	delete(signatureAlgorithms, alg)
+	delete(AlgorithmToHash, alg)
+}
+
+// signatureAlgorithms holds the algorithms that can be used for signing and
+// validation.
+var signatureAlgorithms = map[uint8]*SignatureAlgorithm{
+	RSAMD5:           rsaAlgorithm(RSAMD5, crypto.MD5),
+	DSA:              dsaAlgorithm(DSA),
+	RSASHA1:          rsaAlgorithm(RSASHA1, crypto.SHA1),
+	DSANSEC3SHA1:     dsaAlgorithm(DSANSEC3SHA1),
+	RSASHA1NSEC3SHA1: rsaAlgorithm(RSASHA1NSEC3SHA1, crypto.SHA1),
+	RSASHA256:        rsaAlgorithm(RSASHA256, crypto.SHA256),
+	RSASHA512:        rsaAlgorithm(RSASHA512, crypto.SHA512),
+	ECDSAP256SHA256:  ecdsaAlgorithm(ECDSAP256SHA256, crypto.SHA256),
+	ECDSAP384SHA384:  ecdsaAlgorithm(ECDSAP384SHA384, crypto.SHA384),
+	ED25519: {
+		Name: "ED25519",
+		Sign: func(k crypto.Signer, data []byte) ([]byte, error) {
+			// crypto.Hash(0) signals to the crypto.Signer that the data has not been hashed.
+			return sign(k, data, crypto.Hash(0), ED25519)
+		},
+		Verify: func(k *DNSKEY, data, sig []byte) error {
+			pubkey := k.publicKeyED25519()
+			if pubkey == nil {
+				return ErrKey
+			}
+			if ed25519.Verify(pubkey, data, sig) {
+				return nil
+			}
+			return ErrSig
+		},
+		ReadPrivateKey: func(k *DNSKEY, m map[string]string) (crypto.PrivateKey, error) {
+			return readPrivateKeyED25519(m)
+		},
+	},
+}
+
+// DigestAlgorithm implements a DS digest type. The digest types this package
+// supports out of the box are registered as DigestAlgorithms as well, others
+// can be added with RegisterDigest.
+type DigestAlgorithm struct {
+	// Name is the mnemonic of the digest type, e.g. "SHA256".
+	Name string
+	// New returns a hash computing the digest.
+	New func() hash.Hash
+}
+
+// RegisterDigest registers the digest type d under the digest type number h.
+// An already registered digest type with the same number is replaced. The
+// digest type is used by (*DNSKEY).ToDS and the functions built on it.
+// RegisterDigest is not safe for concurrent use with these functions.
+//
+// GOST R 34.11-2012 digests (RFC 9558) aren't implemented by this package, a
+// program can register one, e.g. from the gogost package, in place of SHA512:
+//
+//	dns.RegisterDigest(dns.GOST12, &dns.DigestAlgorithm{
+//		Name: "GOST12",
+//		New:  func() hash.Hash { return gost34112012256.New() },
+//	})
+func RegisterDigest(h uint8, d *DigestAlgorithm) {
+	if d.Name != "" {
+		if name, ok := HashToString[h]; ok {
+			delete(StringToHash, name)
+		}
+		HashToString[h] = d.Name
+		StringToHash[d.Name] = h
+	}
+	digestAlgorithms[h] = d
+}
+
+// UnregisterDigest removes the digest type registered under the digest type
+// number h.
+func UnregisterDigest(h uint8) {
+	if d, ok := digestAlgorithms[h]; ok && d.Name != "" {
+		delete(StringToHash, d.Name)
+		delete(HashToString, h)
+	}
+	delete(digestAlgorithms, h)
+}
+
+// digestAlgorithms holds the digest types of DS records.
+var digestAlgorithms = map[uint8]*DigestAlgorithm{
+	SHA1:   {Name: "SHA1", New: crypto.SHA1.New},
+	SHA256: {Name: "SHA256", New: crypto.SHA256.New},
+	SHA384: {Name: "SHA384", New: crypto.SHA384.New},
+	SHA512: {Name: "SHA512", New: crypto.SHA512.New},
+}
+
+// sign hashes data, when needed, and signs it with k.
+func (a *SignatureAlgorithm) sign(k crypto.Signer, data []byte) ([]byte, error) {
+	if a.Sign == nil {
+		return nil, ErrAlg
+	}
+	if a.Hash == 0 {
+		return a.Sign(k, data)
+	}
+	if !a.Hash.Available() {
+		return nil, ErrAlg
+	}
+	h := a.Hash.New()
+	h.Write(data)
+	return a.Sign(k, h.Sum(nil))
+}
+
+// verify hashes data, when needed, and checks sig against the public key in k.
+func (a *SignatureAlgorithm) verify(k *DNSKEY, data, sig []byte) error {
+	if a.Verify == nil {
+		return ErrAlg
+	}
+	if a.Hash == 0 {
+		return a.Verify(k, data, sig)
+	}
+	if !a.Hash.Available() {
+		return ErrAlg
+	}
+	h := a.Hash.New()
+	h.Write(data)
+	return a.Verify(k, h.Sum(nil), sig)
+}
+
+func rsaAlgorithm(alg uint8, hash crypto.Hash) *SignatureAlgorithm {
+	return &SignatureAlgorithm{
+		Name: AlgorithmToString[alg],
+		Hash: hash,
+		Sign: func(k crypto.Signer, hashed []byte) ([]byte, error) {
+			return sign(k, hashed, hash, alg)
+		},
+		Verify: func(k *DNSKEY, hashed, sig []byte) error {
+			pubkey := k.publicKeyRSA()
+			if pubkey == nil {
+				return ErrKey
+			}
+			return rsa.VerifyPKCS1v15(pubkey, hash, hashed, sig)
+		},
+		ReadPrivateKey: func(k *DNSKEY, m map[string]string) (crypto.PrivateKey, error) {
+			priv, err := readPrivateKeyRSA(m)
+			if err != nil {
+				return nil, err
+			}
+			pub := k.publicKeyRSA()
+			if pub == nil {
+				return nil, ErrKey
+			}
+			priv.PublicKey = *pub
+			return priv, nil
+		},
+	}
+}
+
+func ecdsaAlgorithm(alg uint8, hash crypto.Hash) *SignatureAlgorithm {
+	return &SignatureAlgorithm{
+		Name: AlgorithmToString[alg],
+		Hash: hash,
+		Sign: func(k crypto.Signer, hashed []byte) ([]byte, error) {
+			return sign(k, hashed, hash, alg)
+		},
+		Verify: func(k *DNSKEY, hashed, sig []byte) error {
+			pubkey := k.publicKeyECDSA()
+			if pubkey == nil {
+				return ErrKey
+			}
+
+			// Split sig into the r and s coordinates
+			r := new(big.Int).SetBytes(sig[:len(sig)/2])
+			s := new(big.Int).SetBytes(sig[len(sig)/2:])
+			if ecdsa.Verify(pubkey, hashed, r, s) {
+				return nil
+			}
+			return ErrSig
+		},
+		ReadPrivateKey: func(k *DNSKEY, m map[string]string) (crypto.PrivateKey, error) {
+			priv, err := readPrivateKeyECDSA(m)
+			if err != nil {
+				return nil, err
+			}
+			pub := k.publicKeyECDSA()
+			if pub == nil {
+				return nil, ErrKey
+			}
+			priv.PublicKey = *pub
+			return priv, nil
+		},
+	}
+}
+
+func dsaAlgorithm(alg uint8) *SignatureAlgorithm {
+	return &SignatureAlgorithm{
+		Name: AlgorithmToString[alg],
+		Hash: crypto.SHA1,
+		Sign: func(k crypto.Signer, hashed []byte) ([]byte, error) {
+			return sign(k, hashed, crypto.SHA1, alg)
+		},
+		Verify: func(k *DNSKEY, hashed, sig []byte) error {
+			pubkey := k.publicKeyDSA()
+			if pubkey == nil {
+				return ErrKey
+			}
+			if len(sig) < 1 {
+				return ErrSig
+			}
+
+			// Skip T and split the rest into r and s
+			sig = sig[1:]
+			r := new(big.Int).SetBytes(sig[:len(sig)/2])
+			s := new(big.Int).SetBytes(sig[len(sig)/2:])
+			if dsa.Verify(pubkey, hashed, r, s) {
+				return nil
+			}
+			return ErrSig
+		},
+		ReadPrivateKey: func(k *DNSKEY, m map[string]string) (crypto.PrivateKey, error) {
+			priv, err := readPrivateKeyDSA(m)
+			if err != nil {
+				return nil, err
+			}
+			pub := k.publicKeyDSA()
+			if pub == nil {
+				return nil, ErrKey
+			}
+			priv.PublicKey = *pub
+			return priv, nil
+		},
+	}
+}
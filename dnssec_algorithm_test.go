@@ -0,0 +1,117 @@
+package dns
+
+import (
+	"crypto"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+// testAlgorithm registers a stand-in for an algorithm unknown to this package,
+// it uses ed25519 under the hood.
+var testAlgorithm = &SignatureAlgorithm{
+	Name: "TESTALG",
+	Sign: func(k crypto.Signer, data []byte) ([]byte, error) {
+		return k.Sign(nil, data, crypto.Hash(0))
+	},
+	Verify: func(k *DNSKEY, data, sig []byte) error {
+		pub, err := fromBase64([]byte(k.PublicKey))
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return ErrKey
+		}
+		if ed25519.Verify(ed25519.PublicKey(pub), data, sig) {
+			return nil
+		}
+		return ErrSig
+	},
+	ReadPrivateKey: func(k *DNSKEY, m map[string]string) (crypto.PrivateKey, error) {
+		seed, err := fromBase64([]byte(m["privatekey"]))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, ErrPrivKey
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	},
+}
+
+func TestRegisterAlgorithm(t *testing.T) {
+	const alg = 200 // unassigned
+	RegisterAlgorithm(alg, testAlgorithm)
+	defer UnregisterAlgorithm(alg)
+
+	if StringToAlgorithm["TESTALG"] != alg {
+		t.Fatalf("expected algorithm %d to be known as TESTALG", alg)
+	}
+
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	key := &DNSKEY{
+		Hdr:       RR_Header{Name: "example.org.", Rrtype: TypeDNSKEY, Class: ClassINET, Ttl: 3600},
+		Flags:     ZONE,
+		Protocol:  3,
+		Algorithm: alg,
+		PublicKey: toBase64(priv.Public().(ed25519.PublicKey)),
+	}
+
+	privStr := "Private-key-format: v1.3\nAlgorithm: 200 (TESTALG)\nPrivateKey: " + toBase64(seed) + "\n"
+	pk, err := key.NewPrivateKey(privStr)
+	if err != nil {
+		t.Fatalf("failed to read private key: %v", err)
+	}
+
+	a := testRR("www.example.org. 3600 IN A 192.0.2.1")
+	sig := &RRSIG{
+		Hdr:        RR_Header{Name: "www.example.org.", Rrtype: TypeRRSIG, Class: ClassINET, Ttl: 3600},
+		Inception:  1293942305,
+		Expiration: 1296534305,
+		KeyTag:     key.KeyTag(),
+		SignerName: key.Hdr.Name,
+		Algorithm:  alg,
+	}
+	if err := sig.Sign(pk.(crypto.Signer), []RR{a}); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if err := sig.Verify(key, []RR{a}); err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if !strings.Contains(sig.String(), "200") {
+		t.Errorf("expected algorithm in RRSIG, got %s", sig)
+	}
+
+	UnregisterAlgorithm(alg)
+	if _, ok := AlgorithmToString[alg]; ok {
+		t.Errorf("expected algorithm %d to be unregistered", alg)
+	}
+	if err := sig.Verify(key, []RR{a}); err != ErrAlg {
+		t.Errorf("expected %v after unregistering, got %v", ErrAlg, err)
+	}
+}
+
+func TestRegisterDigest(t *testing.T) {
+	const h = 200 // unassigned
+	RegisterDigest(h, &DigestAlgorithm{Name: "TESTDIGEST", New: crypto.SHA256.New})
+	defer UnregisterDigest(h)
+
+	if StringToHash["TESTDIGEST"] != h || HashToString[h] != "TESTDIGEST" {
+		t.Fatalf("expected digest type %d to be known as TESTDIGEST", h)
+	}
+	key := testRR("example.org. 3600 IN DNSKEY 257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==").(*DNSKEY)
+	ds := key.ToDS(h)
+	if ds == nil {
+		t.Fatal("expected a DS record")
+	}
+	if want := key.ToDS(SHA256).Digest; ds.Digest != want {
+		t.Errorf("expected digest %s, got %s", want, ds.Digest)
+	}
+
+	UnregisterDigest(h)
+	if key.ToDS(h) != nil {
+		t.Error("expected no DS record for an unregistered digest type")
+	}
+	if _, ok := StringToHash["TESTDIGEST"]; ok {
+		t.Error("expected TESTDIGEST to be forgotten")
+	}
+}
@@ -3,7 +3,9 @@ package dns
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/rand"
 	"crypto/rsa"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -856,3 +858,142 @@ func TestParseKeyReadError(t *testing.T) {
 		t.Errorf("expected a nil map, but got %v", m)
 	}
 }
+
+// opaqueSigner hides the concrete private key type, like an HSM or KMS backed
+// crypto.Signer would. If raw is true ECDSA signatures are returned as r || s
+// instead of ASN.1.
+type opaqueSigner struct {
+	k   crypto.Signer
+	raw bool
+}
+
+func (s opaqueSigner) Public() crypto.PublicKey { return s.k.Public() }
+
+func (s opaqueSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if e, ok := s.k.(*ecdsa.PrivateKey); ok && s.raw {
+		r, ss, err := ecdsa.Sign(rand, e, digest)
+		if err != nil {
+			return nil, err
+		}
+		size := (e.Curve.Params().BitSize + 7) / 8
+		return append(intToBytes(r, size), intToBytes(ss, size)...), nil
+	}
+	return s.k.Sign(rand, digest, opts)
+}
+
+func TestSignOpaqueSigner(t *testing.T) {
+	for _, tc := range []struct {
+		alg  uint8
+		bits int
+		raw  bool
+	}{
+		{RSASHA256, 1024, false},
+		{ECDSAP256SHA256, 256, false},
+		{ECDSAP256SHA256, 256, true},
+		{ECDSAP384SHA384, 384, true},
+		{ED25519, 256, false},
+	} {
+		key := &DNSKEY{
+			Hdr:       RR_Header{Name: "miek.nl.", Rrtype: TypeDNSKEY, Class: ClassINET, Ttl: 3600},
+			Flags:     ZONE,
+			Protocol:  3,
+			Algorithm: tc.alg,
+		}
+		priv, err := key.Generate(tc.bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer := opaqueSigner{k: priv.(crypto.Signer), raw: tc.raw}
+
+		soa := getSoa()
+		sig := new(RRSIG)
+		sig.Hdr = RR_Header{"miek.nl.", TypeRRSIG, ClassINET, 14400, 0}
+		sig.Expiration = 1296534305 // date -u '+%s' -d"2011-02-01 04:25:05"
+		sig.Inception = 1293942305  // date -u '+%s' -d"2011-01-02 04:25:05"
+		sig.KeyTag = key.KeyTag()
+		sig.SignerName = key.Hdr.Name
+		sig.Algorithm = tc.alg
+		if err := sig.Sign(signer, []RR{soa}); err != nil {
+			t.Errorf("failed to sign with opaque %s signer: %v", AlgorithmToString[tc.alg], err)
+			continue
+		}
+		if err := sig.Verify(key, []RR{soa}); err != nil {
+			t.Errorf("failed to verify opaque %s signature: %v", AlgorithmToString[tc.alg], err)
+		}
+	}
+}
+
+func TestSignSignerAlgorithmMismatch(t *testing.T) {
+	key := &DNSKEY{
+		Hdr:       RR_Header{Name: "miek.nl.", Rrtype: TypeDNSKEY, Class: ClassINET, Ttl: 3600},
+		Flags:     ZONE,
+		Protocol:  3,
+		Algorithm: ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := new(RRSIG)
+	sig.Hdr = RR_Header{"miek.nl.", TypeRRSIG, ClassINET, 14400, 0}
+	sig.KeyTag = key.KeyTag()
+	sig.SignerName = key.Hdr.Name
+	sig.Algorithm = ECDSAP384SHA384
+	if err := sig.Sign(opaqueSigner{k: priv.(crypto.Signer)}, []RR{getSoa()}); err != ErrKeyAlg {
+		t.Errorf("expected %v, got %v", ErrKeyAlg, err)
+	}
+}
+
+func TestRRSIGSigningData(t *testing.T) {
+	for _, alg := range []uint8{ECDSAP256SHA256, ED25519} {
+		key := &DNSKEY{
+			Hdr:       RR_Header{Name: "example.", Rrtype: TypeDNSKEY, Class: ClassINET, Ttl: 3600},
+			Flags:     257,
+			Protocol:  3,
+			Algorithm: alg,
+		}
+		priv, err := key.Generate(256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rrset := []RR{key}
+
+		sig := &RRSIG{
+			Algorithm:  alg,
+			KeyTag:     key.KeyTag(),
+			SignerName: key.Hdr.Name,
+			Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+			Expiration: uint32(time.Now().Add(time.Hour).Unix()),
+		}
+		data, err := sig.SigningData(rrset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Sign the data elsewhere, as an HSM would.
+		var signature []byte
+		switch priv := priv.(type) {
+		case *ecdsa.PrivateKey:
+			h := crypto.SHA256.New()
+			h.Write(data)
+			signature, err = priv.Sign(rand.Reader, h.Sum(nil), crypto.SHA256) // ASN.1 encoded
+		case ed25519.PrivateKey:
+			signature = ed25519.Sign(priv, data)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sig.SetSignature(signature); err != nil {
+			t.Fatalf("algorithm %d: failed to set the signature: %v", alg, err)
+		}
+		if err := sig.Verify(key, rrset); err != nil {
+			t.Errorf("algorithm %d: failed to verify: %v", alg, err)
+		}
+	}
+
+	sig := &RRSIG{Algorithm: ED25519}
+	if err := sig.SetSignature([]byte{1, 2, 3}); err != ErrSig {
+		t.Errorf("expected %v, got %v", ErrSig, err)
+	}
+}
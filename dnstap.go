@@ -0,0 +1,366 @@
+package dns
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// DnstapType is the type of a dnstap message, it tells who sent or received the
+// DNS message that is logged.
+type DnstapType int
+
+// Dnstap message types, see dnstap.proto.
+const (
+	DnstapAuthQuery         DnstapType = 1
+	DnstapAuthResponse      DnstapType = 2
+	DnstapResolverQuery     DnstapType = 3
+	DnstapResolverResponse  DnstapType = 4
+	DnstapClientQuery       DnstapType = 5
+	DnstapClientResponse    DnstapType = 6
+	DnstapForwarderQuery    DnstapType = 7
+	DnstapForwarderResponse DnstapType = 8
+	DnstapStubQuery         DnstapType = 9
+	DnstapStubResponse      DnstapType = 10
+	DnstapToolQuery         DnstapType = 11
+	DnstapToolResponse      DnstapType = 12
+)
+
+// DnstapMessage is a DNS message logged in dnstap format. The query and response
+// messages are in wire format.
+type DnstapMessage struct {
+	Type            DnstapType
+	Net             string   // "udp", "tcp" or "tcp-tls"
+	QueryAddr       net.Addr // address of the initiator of the query
+	ResponseAddr    net.Addr // address of the responder
+	QueryZone       string   // zone of the query, for resolver messages; optional
+	QueryTime       time.Time
+	QueryMessage    []byte
+	ResponseTime    time.Time
+	ResponseMessage []byte
+}
+
+// A DnstapSink receives the dnstap messages of a Client or Server.
+type DnstapSink interface {
+	// WriteDnstap logs m. It must not retain m, or the messages it holds, after
+	// returning.
+	WriteDnstap(m *DnstapMessage) error
+}
+
+// Frame Streams control frame types and fields.
+const (
+	fstrmControlAccept = 0x01
+	fstrmControlStart  = 0x02
+	fstrmControlStop   = 0x03
+	fstrmControlReady  = 0x04
+	fstrmControlFinish = 0x05
+
+	fstrmFieldContentType = 0x01
+
+	dnstapContentType = "protobuf:dnstap.Dnstap"
+)
+
+// DnstapWriter is a DnstapSink that writes dnstap messages as a Frame Streams
+// stream, as read by dnstap tools. It is safe for concurrent use.
+type DnstapWriter struct {
+	Identity string // name of the server, optional
+	Version  string // version of the server, optional
+
+	mu     sync.Mutex
+	w      *bufio.Writer
+	c      io.Closer
+	r      io.Reader // set for bidirectional streams
+	buf    []byte
+	closed bool
+}
+
+// NewDnstapWriter returns a DnstapWriter that writes a unidirectional Frame
+// Streams stream to w, e.g. a file. Each message is flushed to w when it has
+// been written. Close stops the stream, and closes w if it is an io.Closer.
+func NewDnstapWriter(w io.Writer) (*DnstapWriter, error) {
+	d := &DnstapWriter{w: bufio.NewWriter(w)}
+	if c, ok := w.(io.Closer); ok {
+		d.c = c
+	}
+	if err := d.writeControl(fstrmControlStart); err != nil {
+		return nil, err
+	}
+	return d, d.w.Flush()
+}
+
+// DialDnstap connects to a dnstap collector listening on address, usually a
+// unix socket, and returns a DnstapWriter that writes a bidirectional Frame
+// Streams stream to it.
+func DialDnstap(network, address string) (*DnstapWriter, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newDnstapConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// newDnstapConn performs the bidirectional Frame Streams handshake on conn.
+func newDnstapConn(conn io.ReadWriteCloser) (*DnstapWriter, error) {
+	d := &DnstapWriter{w: bufio.NewWriter(conn), c: conn, r: bufio.NewReader(conn)}
+	if err := d.writeControl(fstrmControlReady); err != nil {
+		return nil, err
+	}
+	if err := d.w.Flush(); err != nil {
+		return nil, err
+	}
+	if err := d.readControl(fstrmControlAccept); err != nil {
+		return nil, err
+	}
+	if err := d.writeControl(fstrmControlStart); err != nil {
+		return nil, err
+	}
+	return d, d.w.Flush()
+}
+
+// WriteDnstap implements the DnstapSink interface.
+func (d *DnstapWriter) WriteDnstap(m *DnstapMessage) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return &Error{err: "dnstap: write after Close"}
+	}
+
+	d.buf = appendDnstap(d.buf[:0], d.Identity, d.Version, m)
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(d.buf)))
+	if _, err := d.w.Write(l[:]); err != nil {
+		return err
+	}
+	if _, err := d.w.Write(d.buf); err != nil {
+		return err
+	}
+	return d.w.Flush()
+}
+
+// Close stops the stream and closes the underlying writer or connection.
+func (d *DnstapWriter) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+
+	err := d.writeControl(fstrmControlStop)
+	if err == nil {
+		err = d.w.Flush()
+	}
+	if err == nil && d.r != nil {
+		err = d.readControl(fstrmControlFinish)
+	}
+	if d.c != nil {
+		if cerr := d.c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// writeControl writes a control frame, the frames that start the stream carry
+// the content type.
+func (d *DnstapWriter) writeControl(typ uint32) error {
+	var f []byte
+	f = appendUint32(f, 0) // escape
+	f = appendUint32(f, 0) // length, set below
+	f = appendUint32(f, typ)
+	if typ == fstrmControlReady || typ == fstrmControlStart {
+		f = appendUint32(f, fstrmFieldContentType)
+		f = appendUint32(f, uint32(len(dnstapContentType)))
+		f = append(f, dnstapContentType...)
+	}
+	binary.BigEndian.PutUint32(f[4:], uint32(len(f)-8))
+	_, err := d.w.Write(f)
+	return err
+}
+
+// readControl reads a control frame of type typ.
+func (d *DnstapWriter) readControl(typ uint32) error {
+	var h [12]byte
+	if _, err := io.ReadFull(d.r, h[:8]); err != nil {
+		return err
+	}
+	l := binary.BigEndian.Uint32(h[4:8])
+	if binary.BigEndian.Uint32(h[:4]) != 0 || l < 4 || l > 512 {
+		return &Error{err: "dnstap: bad control frame"}
+	}
+	f := make([]byte, l)
+	if _, err := io.ReadFull(d.r, f); err != nil {
+		return err
+	}
+	if binary.BigEndian.Uint32(f) != typ {
+		return &Error{err: "dnstap: unexpected control frame"}
+	}
+	return nil
+}
+
+// appendDnstap appends the Dnstap protocol buffer holding m to b.
+func appendDnstap(b []byte, identity, version string, m *DnstapMessage) []byte {
+	var msg []byte
+	msg = appendProtoVarint(msg, 1, uint64(m.Type))
+
+	qip, qport := dnstapAddr(m.QueryAddr)
+	rip, rport := dnstapAddr(m.ResponseAddr)
+	ip := qip
+	if ip == nil {
+		ip = rip
+	}
+	if ip != nil {
+		family := uint64(1) // INET
+		if ip.To4() == nil {
+			family = 2 // INET6
+		}
+		msg = appendProtoVarint(msg, 2, family)
+	}
+	switch m.Net {
+	case "udp", "udp4", "udp6":
+		msg = appendProtoVarint(msg, 3, 1)
+	case "tcp", "tcp4", "tcp6":
+		msg = appendProtoVarint(msg, 3, 2)
+	case "tcp-tls", "tcp4-tls", "tcp6-tls":
+		msg = appendProtoVarint(msg, 3, 3) // DOT
+	}
+	if qip != nil {
+		msg = appendProtoBytes(msg, 4, dnstapIP(qip))
+	}
+	if rip != nil {
+		msg = appendProtoBytes(msg, 5, dnstapIP(rip))
+	}
+	if qip != nil {
+		msg = appendProtoVarint(msg, 6, uint64(qport))
+	}
+	if rip != nil {
+		msg = appendProtoVarint(msg, 7, uint64(rport))
+	}
+	if !m.QueryTime.IsZero() {
+		msg = appendProtoVarint(msg, 8, uint64(m.QueryTime.Unix()))
+		msg = appendProtoFixed32(msg, 9, uint32(m.QueryTime.Nanosecond()))
+	}
+	if m.QueryMessage != nil {
+		msg = appendProtoBytes(msg, 10, m.QueryMessage)
+	}
+	if m.QueryZone != "" {
+		zone := make([]byte, 256)
+		if off, _, err := packDomainName(Fqdn(m.QueryZone), zone, 0, compressionMap{}, false); err == nil {
+			msg = appendProtoBytes(msg, 11, zone[:off])
+		}
+	}
+	if !m.ResponseTime.IsZero() {
+		msg = appendProtoVarint(msg, 12, uint64(m.ResponseTime.Unix()))
+		msg = appendProtoFixed32(msg, 13, uint32(m.ResponseTime.Nanosecond()))
+	}
+	if m.ResponseMessage != nil {
+		msg = appendProtoBytes(msg, 14, m.ResponseMessage)
+	}
+
+	if identity != "" {
+		b = appendProtoBytes(b, 1, []byte(identity))
+	}
+	if version != "" {
+		b = appendProtoBytes(b, 2, []byte(version))
+	}
+	b = appendProtoBytes(b, 14, msg)
+	return appendProtoVarint(b, 15, 1) // MESSAGE
+}
+
+func dnstapAddr(a net.Addr) (net.IP, int) {
+	switch a := a.(type) {
+	case *net.UDPAddr:
+		return a.IP, a.Port
+	case *net.TCPAddr:
+		return a.IP, a.Port
+	}
+	return nil, 0
+}
+
+func dnstapIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// Protocol buffer encoding, only the wire types used by dnstap.
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = appendVarint(b, uint64(field)<<3)
+	return appendVarint(b, v)
+}
+
+func appendProtoFixed32(b []byte, field int, v uint32) []byte {
+	b = appendVarint(b, uint64(field)<<3|5)
+	var f [4]byte
+	binary.LittleEndian.PutUint32(f[:], v)
+	return append(b, f[:]...)
+}
+
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	b = appendVarint(b, uint64(field)<<3|2)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var f [4]byte
+	binary.BigEndian.PutUint32(f[:], v)
+	return append(b, f[:]...)
+}
+
+// dnstapTap returns the function that logs the messages written and read on co
+// as resolver messages.
+func (c *Client) dnstapTap(co *Conn) func(msg []byte, query bool) {
+	var (
+		mu    sync.Mutex // queries and responses may be pooled
+		qtime time.Time
+	)
+	return func(msg []byte, query bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		m := &DnstapMessage{Net: "udp", QueryAddr: co.LocalAddr(), ResponseAddr: co.RemoteAddr()}
+		switch co.Conn.(type) {
+		case *tls.Conn:
+			m.Net = "tcp-tls"
+		case *net.TCPConn:
+			m.Net = "tcp"
+		}
+		if query {
+			qtime = time.Now()
+			m.Type, m.QueryTime, m.QueryMessage = DnstapResolverQuery, qtime, msg
+		} else {
+			m.Type, m.QueryTime, m.ResponseTime, m.ResponseMessage = DnstapResolverResponse, qtime, time.Now(), msg
+		}
+		c.Dnstap.WriteDnstap(m)
+	}
+}
+
+// dnstap logs a query received, or a response sent, by the server.
+func (w *response) dnstap(msg []byte, query bool) {
+	m := &DnstapMessage{Net: w.network(), QueryAddr: w.RemoteAddr(), ResponseAddr: w.LocalAddr()}
+	if query {
+		m.Type, m.QueryTime, m.QueryMessage = DnstapClientQuery, w.queryTime, msg
+	} else {
+		m.Type, m.QueryTime, m.ResponseTime, m.ResponseMessage = DnstapClientResponse, w.queryTime, time.Now(), msg
+	}
+	w.dnstapSink.WriteDnstap(m)
+}
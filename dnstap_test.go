@@ -0,0 +1,237 @@
+package dns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type dnstapRecorder struct {
+	sync.Mutex
+	msgs []DnstapMessage
+}
+
+func (r *dnstapRecorder) WriteDnstap(m *DnstapMessage) error {
+	r.Lock()
+	defer r.Unlock()
+	c := *m
+	c.QueryMessage = append([]byte(nil), m.QueryMessage...)
+	c.ResponseMessage = append([]byte(nil), m.ResponseMessage...)
+	r.msgs = append(r.msgs, c)
+	return nil
+}
+
+func (r *dnstapRecorder) types() []DnstapType {
+	r.Lock()
+	defer r.Unlock()
+	var t []DnstapType
+	for _, m := range r.msgs {
+		t = append(t, m.Type)
+	}
+	return t
+}
+
+// protoFields decodes the top level fields of a protocol buffer, the values of
+// varint and fixed32 fields are returned as their integer value.
+func protoFields(t *testing.T, b []byte) map[int]interface{} {
+	fields := make(map[int]interface{})
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+		switch key & 7 {
+		case 0:
+			v, n := binary.Uvarint(b)
+			fields[int(key>>3)] = v
+			b = b[n:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			fields[int(key>>3)] = b[n : n+int(l)]
+			b = b[n+int(l):]
+		case 5:
+			fields[int(key>>3)] = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return fields
+}
+
+func TestDnstapWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, err := NewDnstapWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Identity = "ns1"
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	query, _ := m.Pack()
+	qtime := time.Unix(1500000000, 42)
+	err = d.WriteDnstap(&DnstapMessage{
+		Type:         DnstapClientQuery,
+		Net:          "udp",
+		QueryAddr:    &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5353},
+		ResponseAddr: &net.UDPAddr{IP: net.ParseIP("192.0.2.53"), Port: 53},
+		QueryTime:    qtime,
+		QueryMessage: query,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteDnstap(&DnstapMessage{}); err == nil {
+		t.Error("expected error writing after Close")
+	}
+
+	b := buf.Bytes()
+	start := append([]byte{0, 0, 0, 0, 0, 0, 0, 0x22, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 0x16}, dnstapContentType...)
+	if !bytes.HasPrefix(b, start) {
+		t.Fatalf("expected START control frame, got % x", b)
+	}
+	b = b[len(start):]
+	l := binary.BigEndian.Uint32(b)
+	frame := b[4 : 4+l]
+	if stop := []byte{0, 0, 0, 0, 0, 0, 0, 4, 0, 0, 0, 3}; !bytes.Equal(b[4+l:], stop) {
+		t.Errorf("expected STOP control frame, got % x", b[4+l:])
+	}
+
+	dt := protoFields(t, frame)
+	if string(dt[1].([]byte)) != "ns1" {
+		t.Errorf("expected identity ns1, got %q", dt[1])
+	}
+	if dt[15].(uint64) != 1 {
+		t.Errorf("expected type MESSAGE, got %d", dt[15])
+	}
+	msg := protoFields(t, dt[14].([]byte))
+	expect := map[int]interface{}{
+		1:  uint64(DnstapClientQuery),
+		2:  uint64(1), // INET
+		3:  uint64(1), // UDP
+		6:  uint64(5353),
+		7:  uint64(53),
+		8:  uint64(1500000000),
+		9:  uint64(42),
+		10: query,
+	}
+	for f, v := range expect {
+		if b, ok := v.([]byte); ok {
+			if !bytes.Equal(msg[f].([]byte), b) {
+				t.Errorf("field %d: expected % x, got % x", f, b, msg[f])
+			}
+		} else if msg[f] != v {
+			t.Errorf("field %d: expected %v, got %v", f, v, msg[f])
+		}
+	}
+	if ip := net.IP(msg[4].([]byte)); !ip.Equal(net.ParseIP("192.0.2.1")) || len(ip) != 4 {
+		t.Errorf("expected query address 192.0.2.1, got %v", ip)
+	}
+	if _, ok := msg[14]; ok {
+		t.Error("unexpected response message in query")
+	}
+}
+
+func TestDialDnstapHandshake(t *testing.T) {
+	c, s := net.Pipe()
+	defer s.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		d, err := newDnstapConn(c)
+		if err == nil {
+			err = d.WriteDnstap(&DnstapMessage{Type: DnstapToolQuery})
+		}
+		if err == nil {
+			err = d.Close()
+		}
+		done <- err
+	}()
+
+	read := func(n int) []byte {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(s, b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	control := func() uint32 {
+		h := read(8)
+		return binary.BigEndian.Uint32(read(int(binary.BigEndian.Uint32(h[4:]))))
+	}
+
+	if typ := control(); typ != fstrmControlReady {
+		t.Fatalf("expected READY, got %d", typ)
+	}
+	s.Write([]byte{0, 0, 0, 0, 0, 0, 0, 4, 0, 0, 0, fstrmControlAccept})
+	if typ := control(); typ != fstrmControlStart {
+		t.Fatalf("expected START, got %d", typ)
+	}
+	l := binary.BigEndian.Uint32(read(4))
+	read(int(l))
+	if typ := control(); typ != fstrmControlStop {
+		t.Fatalf("expected STOP, got %d", typ)
+	}
+	s.Write([]byte{0, 0, 0, 0, 0, 0, 0, 4, 0, 0, 0, fstrmControlFinish})
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDnstapClientServer(t *testing.T) {
+	HandleFunc("miek.nl.", HelloServer)
+	defer HandleRemove("miek.nl.")
+
+	srvTap := new(dnstapRecorder)
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	server := &Server{PacketConn: pc, Dnstap: srvTap}
+	waitLock := sync.Mutex{}
+	waitLock.Lock()
+	server.NotifyStartedFunc = waitLock.Unlock
+	go server.ActivateAndServe()
+	waitLock.Lock()
+	defer server.Shutdown()
+
+	cltTap := new(dnstapRecorder)
+	c := &Client{Dnstap: cltTap}
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	r, _, err := c.Exchange(m, pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if types := cltTap.types(); len(types) != 2 || types[0] != DnstapResolverQuery || types[1] != DnstapResolverResponse {
+		t.Fatalf("expected resolver query and response, got %v", types)
+	}
+	resp := cltTap.msgs[1]
+	if resp.Net != "udp" || resp.ResponseAddr.String() != pc.LocalAddr().String() || resp.QueryTime.After(resp.ResponseTime) {
+		t.Errorf("bad resolver response: %+v", resp)
+	}
+	packed, _ := r.Pack()
+	if !bytes.Equal(resp.ResponseMessage, packed) {
+		t.Error("resolver response differs from the response received")
+	}
+
+	// The server logs the response before writing it.
+	if types := srvTap.types(); len(types) != 2 || types[0] != DnstapClientQuery || types[1] != DnstapClientResponse {
+		t.Fatalf("expected client query and response, got %v", types)
+	}
+	query := srvTap.msgs[0]
+	if !bytes.Equal(query.QueryMessage, cltTap.msgs[0].QueryMessage) {
+		t.Error("client query differs from the query sent")
+	}
+	if query.QueryAddr.String() != cltTap.msgs[0].QueryAddr.String() {
+		t.Errorf("expected query address %s, got %s", cltTap.msgs[0].QueryAddr, query.QueryAddr)
+	}
+}
@@ -0,0 +1,229 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DoHMediaType is the media type of DNS messages sent over HTTPS (RFC 8484).
+const DoHMediaType = "application/dns-message"
+
+// A DoHClient is an Exchanger that sends queries over HTTPS (RFC 8484). The
+// address given to Exchange is the URL of the server, e.g.
+// "https://dns.example/dns-query".
+//
+// Queries go over HTTP/2, or HTTP/1.1, with HTTPClient. When HTTP3 is set,
+// they go over HTTP/3 to the servers that advertise it in an Alt-Svc header
+// (RFC 7838), or to all servers with ForceHTTP3. A query that fails over
+// HTTP/3 is sent once more with HTTPClient, and HTTP/3 isn't used for that
+// server for a while.
+//
+// This package has no HTTP/3 implementation, HTTP3 is a RoundTripper from
+// another one, such as the RoundTripper of quic-go's http3 package.
+type DoHClient struct {
+	HTTPClient *http.Client  // client for HTTP/2 and HTTP/1.1, defaults to http.DefaultClient
+	Method     string        // "POST" or "GET", defaults to "POST"
+	Timeout    time.Duration // timeout of a query, including the HTTP/3 attempt, defaults to 5 seconds
+
+	HTTP3      http.RoundTripper // if set, used for HTTP/3
+	ForceHTTP3 bool              // if true, use HTTP3 without waiting for an Alt-Svc header
+
+	mu  sync.Mutex
+	alt map[string]*dohAlt // HTTP/3 state per origin
+}
+
+// dohAlt is the HTTP/3 state of an origin.
+type dohAlt struct {
+	port   string    // port of the HTTP/3 alternative, "" for the port of the origin
+	expiry time.Time // end of the advertisement, or of the backoff when broken
+	broken bool      // HTTP/3 failed, it isn't used until expiry
+}
+
+// dohBackoff is the time HTTP/3 isn't used for an origin after it failed.
+const dohBackoff = 5 * time.Minute
+
+// Exchange implements the Exchanger interface. The ID of the query is sent
+// as 0, as RFC 8484 recommends for caching, and set back in the response.
+func (c *DoHClient) Exchange(m *Msg, address string) (r *Msg, rtt time.Duration, err error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, 0, err
+	}
+	id := m.Id
+	m.Id = 0
+	buf, err := m.Pack()
+	m.Id = id
+	if err != nil {
+		return nil, 0, err
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	t := time.Now()
+	var resp *http.Response
+	if h3, ok := c.http3URL(u); ok {
+		req, err := c.newRequest(ctx, h3, buf)
+		if err != nil {
+			return nil, 0, err
+		}
+		resp, err = c.HTTP3.RoundTrip(req)
+		if err != nil {
+			c.markBroken(u)
+			resp = nil
+		}
+	}
+	if resp == nil {
+		client := c.HTTPClient
+		if client == nil {
+			client = http.DefaultClient
+		}
+		req, err := c.newRequest(ctx, u, buf)
+		if err != nil {
+			return nil, 0, err
+		}
+		resp, err = client.Do(req)
+		if err != nil {
+			return nil, 0, err
+		}
+		if c.HTTP3 != nil {
+			c.altSvc(u, resp.Header.Get("Alt-Svc"))
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != DoHMediaType {
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, MaxMsgSize))
+		return nil, 0, &Error{err: "unexpected DoH response: " + resp.Status}
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxMsgSize+1))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(body) > MaxMsgSize {
+		return nil, 0, ErrBuf
+	}
+	rtt = time.Since(t)
+	r = new(Msg)
+	if err := r.Unpack(body); err != nil {
+		return r, rtt, err
+	}
+	r.Id = id
+	return r, rtt, nil
+}
+
+// newRequest returns the HTTP request for the query buf to u.
+func (c *DoHClient) newRequest(ctx context.Context, u *url.URL, buf []byte) (*http.Request, error) {
+	var req *http.Request
+	var err error
+	if c.Method == http.MethodGet {
+		q := *u
+		v := q.Query()
+		v.Set("dns", base64.RawURLEncoding.EncodeToString(buf))
+		q.RawQuery = v.Encode()
+		req, err = http.NewRequest(http.MethodGet, q.String(), nil)
+	} else {
+		req, err = http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(buf))
+		if err == nil {
+			req.Header.Set("Content-Type", DoHMediaType)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", DoHMediaType)
+	return req.WithContext(ctx), nil
+}
+
+// http3URL returns the URL to send the queries for u to over HTTP/3, and
+// whether to use HTTP/3.
+func (c *DoHClient) http3URL(u *url.URL) (*url.URL, bool) {
+	if c.HTTP3 == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a := c.alt[u.Host]
+	if a != nil && time.Now().After(a.expiry) {
+		delete(c.alt, u.Host)
+		a = nil
+	}
+	switch {
+	case a == nil && c.ForceHTTP3:
+		return u, true
+	case a == nil || a.broken:
+		return nil, false
+	case a.port == "" || a.port == u.Port():
+		return u, true
+	}
+	h3 := *u
+	h3.Host = net.JoinHostPort(u.Hostname(), a.port)
+	return &h3, true
+}
+
+// markBroken stops the use of HTTP/3 for u for a while.
+func (c *DoHClient) markBroken(u *url.URL) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.alt == nil {
+		c.alt = make(map[string]*dohAlt)
+	}
+	c.alt[u.Host] = &dohAlt{broken: true, expiry: time.Now().Add(dohBackoff)}
+}
+
+// altSvc records the HTTP/3 alternative of u advertised in the Alt-Svc header
+// value v, e.g. `h3=":443"; ma=86400, h2=":443"`. Only alternatives on the
+// same host are used.
+func (c *DoHClient) altSvc(u *url.URL, v string) {
+	if v == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if a := c.alt[u.Host]; a != nil && a.broken && time.Now().Before(a.expiry) {
+		return
+	}
+	if strings.TrimSpace(v) == "clear" {
+		delete(c.alt, u.Host)
+		return
+	}
+	for _, alt := range strings.Split(v, ",") {
+		params := strings.Split(alt, ";")
+		kv := strings.SplitN(strings.TrimSpace(params[0]), "=", 2)
+		if len(kv) != 2 || kv[0] != "h3" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(strings.Trim(kv[1], `"`))
+		if err != nil || (host != "" && host != u.Hostname()) {
+			continue
+		}
+		ma := 24 * time.Hour
+		for _, p := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+			if len(kv) == 2 && kv[0] == "ma" {
+				if s, err := strconv.ParseUint(strings.Trim(kv[1], `"`), 10, 32); err == nil {
+					ma = time.Duration(s) * time.Second
+				}
+			}
+		}
+		if c.alt == nil {
+			c.alt = make(map[string]*dohAlt)
+		}
+		c.alt[u.Host] = &dohAlt{port: port, expiry: time.Now().Add(ma)}
+		return
+	}
+}
@@ -0,0 +1,129 @@
+package dns
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+// dohServer returns a DoH server that answers with HelloServer, and
+// advertises HTTP/3 on its own port if h3 is true.
+func dohServer(t *testing.T, h3 bool) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf []byte
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			buf, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		case http.MethodPost:
+			if r.Header.Get("Content-Type") != DoHMediaType {
+				http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+				return
+			}
+			buf, err = ioutil.ReadAll(r.Body)
+		}
+		req := new(Msg)
+		if err != nil || req.Unpack(buf) != nil || req.Id != 0 {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		rw := new(recordWriter)
+		HelloServer(rw, req)
+		out, _ := rw.msgs[0].Pack()
+		if h3 {
+			_, port, _ := net.SplitHostPort(r.Host)
+			w.Header().Set("Alt-Svc", `h2=":`+port+`", h3=":`+port+`"; ma=3600`)
+		}
+		w.Header().Set("Content-Type", DoHMediaType)
+		w.Write(out)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	return srv
+}
+
+// countingRoundTripper counts the requests it passes to rt, or fails them if
+// rt is nil.
+type countingRoundTripper struct {
+	rt http.RoundTripper
+	n  int32
+}
+
+func (c *countingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.n, 1)
+	if c.rt == nil {
+		return nil, errors.New("no HTTP/3")
+	}
+	return c.rt.RoundTrip(r)
+}
+
+func dohExchange(t *testing.T, c *DoHClient, address string) {
+	t.Helper()
+	m := new(Msg).SetQuestion("miek.nl.", TypeTXT)
+	r, _, err := c.Exchange(m, address)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.Id != m.Id || len(r.Extra) != 1 || r.Extra[0].(*TXT).Txt[0] != "Hello world" {
+		t.Fatalf("unexpected response %v", r)
+	}
+}
+
+func TestDoHClient(t *testing.T) {
+	srv := dohServer(t, false)
+	defer srv.Close()
+
+	for _, method := range []string{http.MethodPost, http.MethodGet} {
+		c := &DoHClient{HTTPClient: srv.Client(), Method: method}
+		dohExchange(t, c, srv.URL+"/dns-query")
+	}
+}
+
+func TestDoHClientAltSvc(t *testing.T) {
+	srv := dohServer(t, true)
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	h3 := &countingRoundTripper{rt: srv.Client().Transport}
+	c := &DoHClient{HTTPClient: srv.Client(), HTTP3: h3}
+
+	dohExchange(t, c, srv.URL)
+	if n := atomic.LoadInt32(&h3.n); n != 0 {
+		t.Fatalf("expected first query over HTTP/2, got %d over HTTP/3", n)
+	}
+	dohExchange(t, c, srv.URL)
+	if n := atomic.LoadInt32(&h3.n); n != 1 {
+		t.Fatalf("expected query over HTTP/3 after Alt-Svc, got %d", n)
+	}
+
+	c.altSvc(u, "clear")
+	if _, ok := c.http3URL(u); ok {
+		t.Errorf("expected HTTP/3 to be cleared")
+	}
+	c.altSvc(u, `h3="other.example:443"`)
+	if _, ok := c.http3URL(u); ok {
+		t.Errorf("expected alternative on other host to be ignored")
+	}
+	c.altSvc(u, `h3=":8443"; ma=60`)
+	if h3u, ok := c.http3URL(u); !ok || h3u.Port() != "8443" {
+		t.Errorf("expected HTTP/3 on port 8443, got %v", h3u)
+	}
+}
+
+func TestDoHClientFallback(t *testing.T) {
+	srv := dohServer(t, false)
+	defer srv.Close()
+	h3 := &countingRoundTripper{}
+	c := &DoHClient{HTTPClient: srv.Client(), HTTP3: h3, ForceHTTP3: true}
+
+	dohExchange(t, c, srv.URL)
+	dohExchange(t, c, srv.URL)
+	if n := atomic.LoadInt32(&h3.n); n != 1 {
+		t.Errorf("expected a single HTTP/3 attempt, got %d", n)
+	}
+}
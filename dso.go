@@ -0,0 +1,514 @@
+package dns
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DSO TLV types (RFC 8490, Section 10.3).
+const (
+	DSOTypeKeepAlive         = 0x0001
+	DSOTypeRetryDelay        = 0x0002
+	DSOTypeEncryptionPadding = 0x0003
+)
+
+var (
+	errDSOMsg     = &Error{err: "bad DSO message"}
+	errDSORcode   = &Error{err: "DSO message rcode too large"}
+	errDSOSession = &Error{err: "DSO session not established"}
+)
+
+// A DSOTLV is a TLV of a DNS Stateful Operations message. TLVs of types that
+// aren't known are unpacked as DSOLocal, types can be added with DSOHandle.
+type DSOTLV interface {
+	// DSOType returns the type of the TLV.
+	DSOType() uint16
+	// Pack returns the data of the TLV.
+	Pack() ([]byte, error)
+	// Unpack sets the TLV from its data.
+	Unpack([]byte) error
+	// String returns the text presentation of the TLV.
+	String() string
+}
+
+var (
+	dsoTypesMu sync.RWMutex
+	dsoTypes   = map[uint16]func() DSOTLV{
+		DSOTypeKeepAlive:         func() DSOTLV { return new(DSOKeepAlive) },
+		DSOTypeRetryDelay:        func() DSOTLV { return new(DSORetryDelay) },
+		DSOTypeEncryptionPadding: func() DSOTLV { return new(DSOPadding) },
+	}
+)
+
+// DSOHandle registers the TLV type t, so TLVs of that type are unpacked with
+// the TLV that f returns.
+func DSOHandle(t uint16, f func() DSOTLV) {
+	dsoTypesMu.Lock()
+	dsoTypes[t] = f
+	dsoTypesMu.Unlock()
+}
+
+// DSOHandleRemove removes the registration of the TLV type t.
+func DSOHandleRemove(t uint16) {
+	dsoTypesMu.Lock()
+	delete(dsoTypes, t)
+	dsoTypesMu.Unlock()
+}
+
+// DSOKeepAlive is the KeepAlive TLV, that establishes a DSO session and sets
+// its timeouts (RFC 8490, Section 7.1).
+type DSOKeepAlive struct {
+	InactivityTimeout uint32 // time the client keeps an idle session open, in milliseconds, 0xFFFFFFFF is infinite
+	KeepaliveInterval uint32 // maximum time between two messages of the client, in milliseconds, 0xFFFFFFFF is infinite
+}
+
+// DSOType implements the DSOTLV interface.
+func (t *DSOKeepAlive) DSOType() uint16 { return DSOTypeKeepAlive }
+
+// Pack implements the DSOTLV interface.
+func (t *DSOKeepAlive) Pack() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, t.InactivityTimeout)
+	binary.BigEndian.PutUint32(b[4:], t.KeepaliveInterval)
+	return b, nil
+}
+
+// Unpack implements the DSOTLV interface.
+func (t *DSOKeepAlive) Unpack(b []byte) error {
+	if len(b) != 8 {
+		return errDSOMsg
+	}
+	t.InactivityTimeout = binary.BigEndian.Uint32(b)
+	t.KeepaliveInterval = binary.BigEndian.Uint32(b[4:])
+	return nil
+}
+
+func (t *DSOKeepAlive) String() string {
+	return "KeepAlive " + strconv.FormatUint(uint64(t.InactivityTimeout), 10) + " " + strconv.FormatUint(uint64(t.KeepaliveInterval), 10)
+}
+
+// DSORetryDelay is the Retry Delay TLV, with which a server asks the client to
+// close the session, and not to reconnect before the delay (RFC 8490, Section
+// 7.2).
+type DSORetryDelay struct {
+	Delay uint32 // in milliseconds
+}
+
+// DSOType implements the DSOTLV interface.
+func (t *DSORetryDelay) DSOType() uint16 { return DSOTypeRetryDelay }
+
+// Pack implements the DSOTLV interface.
+func (t *DSORetryDelay) Pack() ([]byte, error) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, t.Delay)
+	return b, nil
+}
+
+// Unpack implements the DSOTLV interface.
+func (t *DSORetryDelay) Unpack(b []byte) error {
+	if len(b) != 4 {
+		return errDSOMsg
+	}
+	t.Delay = binary.BigEndian.Uint32(b)
+	return nil
+}
+
+func (t *DSORetryDelay) String() string {
+	return "RetryDelay " + strconv.FormatUint(uint64(t.Delay), 10)
+}
+
+// DSOPadding is the Encryption Padding TLV (RFC 8490, Section 7.3).
+type DSOPadding struct {
+	Padding []byte
+}
+
+// DSOType implements the DSOTLV interface.
+func (t *DSOPadding) DSOType() uint16 { return DSOTypeEncryptionPadding }
+
+// Pack implements the DSOTLV interface.
+func (t *DSOPadding) Pack() ([]byte, error) { return t.Padding, nil }
+
+// Unpack implements the DSOTLV interface.
+func (t *DSOPadding) Unpack(b []byte) error {
+	t.Padding = append([]byte(nil), b...)
+	return nil
+}
+
+func (t *DSOPadding) String() string { return "Padding " + hex.EncodeToString(t.Padding) }
+
+// DSOLocal is a TLV of a type that isn't registered.
+type DSOLocal struct {
+	Type uint16
+	Data []byte
+}
+
+// DSOType implements the DSOTLV interface.
+func (t *DSOLocal) DSOType() uint16 { return t.Type }
+
+// Pack implements the DSOTLV interface.
+func (t *DSOLocal) Pack() ([]byte, error) { return t.Data, nil }
+
+// Unpack implements the DSOTLV interface.
+func (t *DSOLocal) Unpack(b []byte) error {
+	t.Data = append([]byte(nil), b...)
+	return nil
+}
+
+func (t *DSOLocal) String() string {
+	return "TLV" + strconv.Itoa(int(t.Type)) + " " + hex.EncodeToString(t.Data)
+}
+
+// A DSOMsg is a DNS Stateful Operations message (RFC 8490). It has the header
+// of a DNS message, with the OpcodeStateful opcode and no records, followed
+// by TLVs. Requests have a non-zero Id, unidirectional messages have an Id of
+// zero and get no response.
+type DSOMsg struct {
+	Id       uint16
+	Response bool
+	Rcode    int      // only the lower 4 bits can be sent
+	TLVs     []DSOTLV // the primary TLV, then the additional TLVs; responses may have no primary TLV
+}
+
+// Pack returns the wire format of m.
+func (m *DSOMsg) Pack() ([]byte, error) {
+	if m.Rcode < 0 || m.Rcode > 0xF {
+		return nil, errDSORcode
+	}
+	b := make([]byte, headerSize, headerSize+8*len(m.TLVs))
+	bits := uint16(OpcodeStateful)<<11 | uint16(m.Rcode)
+	if m.Response {
+		bits |= _QR
+	}
+	binary.BigEndian.PutUint16(b, m.Id)
+	binary.BigEndian.PutUint16(b[2:], bits)
+	for _, t := range m.TLVs {
+		data, err := t.Pack()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > 0xFFFF {
+			return nil, ErrBuf
+		}
+		var tl [4]byte
+		binary.BigEndian.PutUint16(tl[:], t.DSOType())
+		binary.BigEndian.PutUint16(tl[2:], uint16(len(data)))
+		b = append(append(b, tl[:]...), data...)
+	}
+	if len(b) > MaxMsgSize {
+		return nil, ErrBuf
+	}
+	return b, nil
+}
+
+// Unpack sets m from the wire format in b.
+func (m *DSOMsg) Unpack(b []byte) error {
+	dh, off, err := unpackMsgHdr(b, 0)
+	if err != nil {
+		return err
+	}
+	if int(dh.Bits>>11)&0xF != OpcodeStateful || dh.Qdcount != 0 || dh.Ancount != 0 || dh.Nscount != 0 || dh.Arcount != 0 {
+		return errDSOMsg
+	}
+	m.Id = dh.Id
+	m.Response = dh.Bits&_QR != 0
+	m.Rcode = int(dh.Bits & 0xF)
+	m.TLVs = nil
+	for off < len(b) {
+		if off+4 > len(b) {
+			return errDSOMsg
+		}
+		typ := binary.BigEndian.Uint16(b[off:])
+		l := int(binary.BigEndian.Uint16(b[off+2:]))
+		off += 4
+		if off+l > len(b) {
+			return errDSOMsg
+		}
+		dsoTypesMu.RLock()
+		f, ok := dsoTypes[typ]
+		dsoTypesMu.RUnlock()
+		var t DSOTLV
+		if ok {
+			t = f()
+		} else {
+			t = &DSOLocal{Type: typ}
+		}
+		if err := t.Unpack(b[off : off+l]); err != nil {
+			return err
+		}
+		m.TLVs = append(m.TLVs, t)
+		off += l
+	}
+	return nil
+}
+
+// Primary returns the primary TLV of m, or nil if it has none.
+func (m *DSOMsg) Primary() DSOTLV {
+	if len(m.TLVs) == 0 {
+		return nil
+	}
+	return m.TLVs[0]
+}
+
+func (m *DSOMsg) String() string {
+	kind := "request"
+	switch {
+	case m.Response:
+		kind = "response"
+	case m.Id == 0:
+		kind = "unidirectional"
+	}
+	s := ";; DSO " + kind + ", status: " + RcodeToString[m.Rcode] + ", id: " + strconv.Itoa(int(m.Id))
+	for _, t := range m.TLVs {
+		s += "\n" + t.String()
+	}
+	return s
+}
+
+// isDSO reports whether the message with header dh is a DSO message.
+func isDSO(dh Header) bool {
+	return int(dh.Bits>>11)&0xF == OpcodeStateful
+}
+
+// A DSOSession is the client side of a DSO session on a TCP or TLS
+// connection. It isn't safe for concurrent use.
+type DSOSession struct {
+	Conn *Conn
+
+	// The timeouts the server sent, in milliseconds, set once the session
+	// is established.
+	InactivityTimeout uint32
+	KeepaliveInterval uint32
+
+	// RetryDelay is set when the server asked to close the session with a
+	// Retry Delay TLV: the client should not reconnect before it.
+	RetryDelay time.Duration
+
+	// Unidirectional, if not nil, is called with the unidirectional messages
+	// received while waiting for a response that the session doesn't handle
+	// itself.
+	Unidirectional func(m *DSOMsg)
+
+	established bool
+}
+
+// Established reports whether the session is established.
+func (s *DSOSession) Established() bool { return s.established }
+
+// Establish establishes the session with a KeepAlive request, with the
+// timeouts the client would like. The server answers with the timeouts it
+// wants the client to use, which are set in s.
+func (s *DSOSession) Establish(ka *DSOKeepAlive) error {
+	r, err := s.Request(&DSOMsg{TLVs: []DSOTLV{ka}})
+	if err != nil {
+		return err
+	}
+	if r.Rcode != RcodeSuccess {
+		return &Error{err: "DSO session refused with rcode " + RcodeToString[r.Rcode]}
+	}
+	for _, t := range r.TLVs {
+		if t, ok := t.(*DSOKeepAlive); ok {
+			s.InactivityTimeout, s.KeepaliveInterval = t.InactivityTimeout, t.KeepaliveInterval
+			s.established = true
+			return nil
+		}
+	}
+	return errDSOMsg
+}
+
+// Request sends the request m, with a new Id if it has none, and returns the
+// response of the server. Unidirectional messages read while waiting for the
+// response are handled: a KeepAlive updates the timeouts, and a Retry Delay
+// sets RetryDelay, closes the connection and returns an error. Other ones are
+// passed to Unidirectional.
+func (s *DSOSession) Request(m *DSOMsg) (*DSOMsg, error) {
+	if m.Id == 0 {
+		m.Id = Id()
+		for m.Id == 0 {
+			m.Id = Id()
+		}
+	}
+	if err := s.write(m); err != nil {
+		return nil, err
+	}
+	for {
+		r, err := s.Read()
+		if err != nil {
+			return nil, err
+		}
+		if r.Response && r.Id == m.Id {
+			return r, nil
+		}
+		if !r.Response && r.Id == 0 && s.Unidirectional != nil {
+			s.Unidirectional(r)
+		}
+	}
+}
+
+// Send sends the unidirectional message m. The session must be established.
+func (s *DSOSession) Send(m *DSOMsg) error {
+	if !s.established {
+		return errDSOSession
+	}
+	m.Id = 0
+	return s.write(m)
+}
+
+// Read reads the next DSO message from the server. KeepAlive and Retry Delay
+// messages are handled as in Request, and returned.
+func (s *DSOSession) Read() (*DSOMsg, error) {
+	for {
+		p := make([]byte, MaxMsgSize)
+		n, err := s.Conn.Read(p)
+		if err != nil {
+			return nil, err
+		}
+		dh, _, err := unpackMsgHdr(p[:n], 0)
+		if err != nil {
+			return nil, err
+		}
+		if !isDSO(dh) {
+			continue // a response to a query sent on the connection before
+		}
+		m := new(DSOMsg)
+		if err := m.Unpack(p[:n]); err != nil {
+			return nil, err
+		}
+		if m.Response || m.Id != 0 {
+			return m, nil
+		}
+		switch t := m.Primary().(type) {
+		case *DSOKeepAlive:
+			s.InactivityTimeout, s.KeepaliveInterval = t.InactivityTimeout, t.KeepaliveInterval
+		case *DSORetryDelay:
+			s.RetryDelay = time.Duration(t.Delay) * time.Millisecond
+			s.established = false
+			s.Conn.Close()
+			return m, &Error{err: "DSO session closed by the server, retry after " + s.RetryDelay.String()}
+		}
+		return m, nil
+	}
+}
+
+func (s *DSOSession) write(m *DSOMsg) error {
+	b, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	_, err = s.Conn.Write(b)
+	return err
+}
+
+// A DSOHandler handles the DSO messages a Server receives, other than the
+// KeepAlive requests that establish the session, which the server answers
+// itself. Requests with a primary TLV the handler doesn't implement should be
+// answered with RcodeStatefulTypeNotImplemented. Responses are written with
+// WriteDSO.
+type DSOHandler interface {
+	ServeDSO(w ResponseWriter, m *DSOMsg)
+}
+
+// The DSOHandlerFunc type is an adapter to allow the use of ordinary
+// functions as DSO handlers.
+type DSOHandlerFunc func(ResponseWriter, *DSOMsg)
+
+// ServeDSO calls f(w, m).
+func (f DSOHandlerFunc) ServeDSO(w ResponseWriter, m *DSOMsg) {
+	f(w, m)
+}
+
+// WriteDSO writes the DSO message m to w.
+func WriteDSO(w ResponseWriter, m *DSOMsg) error {
+	b, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// dsoState is the state of the DSO session of a TCP connection.
+type dsoState struct {
+	mu          sync.Mutex
+	established bool
+	idle        time.Duration // idle timeout of the connection once established
+}
+
+// isEstablished reports whether the session is established, then the
+// connection isn't closed after MaxTCPQueries.
+func (s *dsoState) isEstablished() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.established
+}
+
+// idleTimeout returns the idle timeout of the connection, def if no session is
+// established.
+func (s *dsoState) idleTimeout(def time.Duration) time.Duration {
+	if s == nil {
+		return def
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.established {
+		return def
+	}
+	return s.idle
+}
+
+// dsoKeepAlive returns the KeepAlive TLV srv answers with.
+func (srv *Server) dsoKeepAlive() *DSOKeepAlive {
+	if srv.DSOKeepAlive != nil {
+		return srv.DSOKeepAlive
+	}
+	return &DSOKeepAlive{InactivityTimeout: 15000, KeepaliveInterval: 3600000}
+}
+
+// serveDSO handles the DSO message in w.
+func (srv *Server) serveDSO(w *response) {
+	m := new(DSOMsg)
+	err := m.Unpack(w.msg)
+	srv.disposeBuffer(w)
+	if err != nil {
+		WriteDSO(w, &DSOMsg{Id: m.Id, Response: true, Rcode: RcodeFormatError})
+		w.Close()
+		return
+	}
+	if m.Response {
+		srv.DSOHandler.ServeDSO(w, m)
+		return
+	}
+	if m.Primary() == nil {
+		WriteDSO(w, &DSOMsg{Id: m.Id, Response: true, Rcode: RcodeFormatError})
+		return
+	}
+
+	switch m.Primary().(type) {
+	case *DSOKeepAlive:
+		if m.Id == 0 {
+			w.Close() // only servers send unidirectional KeepAlive messages
+			return
+		}
+		ka := srv.dsoKeepAlive()
+		// Wait twice the keepalive interval, and at least 5 seconds, for
+		// the next message of the client (RFC 8490, Section 6.5.2).
+		idle := 2 * time.Duration(ka.KeepaliveInterval) * time.Millisecond
+		if idle < 5*time.Second {
+			idle = 5 * time.Second
+		}
+		w.dso.mu.Lock()
+		w.dso.established, w.dso.idle = true, idle
+		w.dso.mu.Unlock()
+		WriteDSO(w, &DSOMsg{Id: m.Id, Response: true, TLVs: []DSOTLV{ka}})
+		return
+	case *DSORetryDelay:
+		w.Close() // only servers send Retry Delay
+		return
+	}
+	srv.DSOHandler.ServeDSO(w, m)
+}
@@ -0,0 +1,118 @@
+package dns
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDSOMsgPack(t *testing.T) {
+	m := &DSOMsg{
+		Id: 1234,
+		TLVs: []DSOTLV{
+			&DSOKeepAlive{InactivityTimeout: 15000, KeepaliveInterval: 60000},
+			&DSOLocal{Type: 0xF901, Data: []byte{1, 2, 3}},
+			&DSOPadding{Padding: make([]byte, 5)},
+		},
+	}
+	b, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != headerSize+4+8+4+3+4+5 {
+		t.Errorf("unexpected length %d", len(b))
+	}
+	m1 := new(DSOMsg)
+	if err := m1.Unpack(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, m1) {
+		t.Errorf("expected %v, got %v", m, m1)
+	}
+
+	// A Msg with records isn't a DSO message.
+	q := new(Msg)
+	q.SetQuestion("example.org.", TypeA)
+	q.Opcode = OpcodeStateful
+	b, _ = q.Pack()
+	if err := m1.Unpack(b); err == nil {
+		t.Error("expected an error for a DSO message with a question")
+	}
+
+	if _, err := (&DSOMsg{Rcode: RcodeBadCookie}).Pack(); err == nil {
+		t.Error("expected an error for an extended rcode")
+	}
+}
+
+type testDSOEcho struct{ DSOLocal }
+
+func TestDSOHandle(t *testing.T) {
+	DSOHandle(0xF901, func() DSOTLV { return &testDSOEcho{DSOLocal{Type: 0xF901}} })
+	defer DSOHandleRemove(0xF901)
+
+	b, _ := (&DSOMsg{Id: 1, TLVs: []DSOTLV{&DSOLocal{Type: 0xF901, Data: []byte("x")}}}).Pack()
+	m := new(DSOMsg)
+	if err := m.Unpack(b); err != nil {
+		t.Fatal(err)
+	}
+	if tlv, ok := m.Primary().(*testDSOEcho); !ok || string(tlv.Data) != "x" {
+		t.Errorf("expected a registered TLV, got %#v", m.Primary())
+	}
+}
+
+func TestDSOSession(t *testing.T) {
+	h := DSOHandlerFunc(func(w ResponseWriter, m *DSOMsg) {
+		switch m.Primary().DSOType() {
+		case 0xF901:
+			WriteDSO(w, &DSOMsg{Id: m.Id, Response: true, TLVs: m.TLVs})
+		case 0xF902:
+			WriteDSO(w, &DSOMsg{TLVs: []DSOTLV{&DSORetryDelay{Delay: 30000}}})
+		default:
+			WriteDSO(w, &DSOMsg{Id: m.Id, Response: true, Rcode: RcodeStatefulTypeNotImplemented})
+		}
+	})
+	s, addr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.DSOHandler = h
+		srv.DSOKeepAlive = &DSOKeepAlive{InactivityTimeout: 10000, KeepaliveInterval: 20000}
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	co, err := (&Client{Net: "tcp"}).Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer co.Close()
+	session := &DSOSession{Conn: co}
+
+	if err := session.Send(&DSOMsg{TLVs: []DSOTLV{&DSOLocal{Type: 0xF901}}}); err == nil {
+		t.Error("expected an error sending before the session is established")
+	}
+	if err := session.Establish(&DSOKeepAlive{InactivityTimeout: 1000, KeepaliveInterval: 1000}); err != nil {
+		t.Fatalf("failed to establish the session: %v", err)
+	}
+	if !session.Established() || session.InactivityTimeout != 10000 || session.KeepaliveInterval != 20000 {
+		t.Errorf("unexpected session timeouts %d %d", session.InactivityTimeout, session.KeepaliveInterval)
+	}
+
+	r, err := session.Request(&DSOMsg{TLVs: []DSOTLV{&DSOLocal{Type: 0xF901, Data: []byte("ping")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlv, ok := r.Primary().(*DSOLocal); !ok || string(tlv.Data) != "ping" {
+		t.Errorf("unexpected response %v", r)
+	}
+	r, err = session.Request(&DSOMsg{TLVs: []DSOTLV{&DSOLocal{Type: 0xF903}}})
+	if err != nil || r.Rcode != RcodeStatefulTypeNotImplemented {
+		t.Errorf("expected DSOTYPENI, got %v: %v", r, err)
+	}
+
+	if _, err := session.Request(&DSOMsg{TLVs: []DSOTLV{&DSOLocal{Type: 0xF902}}}); err == nil {
+		t.Error("expected an error after a Retry Delay")
+	}
+	if session.RetryDelay != 30*time.Second || session.Established() {
+		t.Errorf("expected a retry delay of 30s, got %v", session.RetryDelay)
+	}
+}
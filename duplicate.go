@@ -18,7 +18,7 @@ func IsDuplicate(r1, r2 RR) bool {
 	}
 	// ignore TTL
 
-	return isDuplicateRdata(r1, r2)
+	return r1.isDuplicate(r2)
 }
 
 // isDulicateName checks if the domain names s1 and s2 are equal.
@@ -59,3 +59,22 @@ func TestDuplicateDomain(t *testing.T) {
 		t.Errorf("expected %s/%s to be duplicates, but got false", a1.String(), a2.String())
 	}
 }
+
+func TestDuplicateEmbedded(t *testing.T) {
+	// CDS embeds DS, the types still have to match.
+	ds := testRR("example.org. IN DS 60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118")
+	cds := testRR("example.org. IN CDS 60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118")
+	cds2 := testRR("example.org. IN CDS 60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118")
+	if !IsDuplicate(cds, cds2) {
+		t.Errorf("expected %s/%s to be duplicates, but got false", cds, cds2)
+	}
+	if IsDuplicate(ds, cds) {
+		t.Errorf("expected %s/%s not to be duplicates, but got true", ds, cds)
+	}
+
+	a1 := testRR(`example.org. IN TYPE65534 \# 2 abcd`)
+	a2 := testRR(`example.org. IN TYPE65534 \# 2 abcd`)
+	if !IsDuplicate(a1, a2) {
+		t.Errorf("expected %s/%s to be duplicates, but got false", a1, a2)
+	}
+}
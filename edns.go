@@ -11,20 +11,24 @@ import (
 
 // EDNS0 Option codes.
 const (
-	EDNS0LLQ          = 0x1     // long lived queries: http://tools.ietf.org/html/draft-sekar-dns-llq-01
-	EDNS0UL           = 0x2     // update lease draft: http://files.dns-sd.org/draft-sekar-dns-ul.txt
-	EDNS0NSID         = 0x3     // nsid (See RFC 5001)
-	EDNS0DAU          = 0x5     // DNSSEC Algorithm Understood
-	EDNS0DHU          = 0x6     // DS Hash Understood
-	EDNS0N3U          = 0x7     // NSEC3 Hash Understood
-	EDNS0SUBNET       = 0x8     // client-subnet (See RFC 7871)
-	EDNS0EXPIRE       = 0x9     // EDNS0 expire
-	EDNS0COOKIE       = 0xa     // EDNS0 Cookie
-	EDNS0TCPKEEPALIVE = 0xb     // EDNS0 tcp keep alive (See RFC 7828)
-	EDNS0PADDING      = 0xc     // EDNS0 padding (See RFC 7830)
-	EDNS0LOCALSTART   = 0xFDE9  // Beginning of range reserved for local/experimental use (See RFC 6891)
-	EDNS0LOCALEND     = 0xFFFE  // End of range reserved for local/experimental use (See RFC 6891)
-	_DO               = 1 << 15 // DNSSEC OK
+	EDNS0LLQ           = 0x1     // long lived queries: http://tools.ietf.org/html/draft-sekar-dns-llq-01
+	EDNS0UL            = 0x2     // update lease draft: http://files.dns-sd.org/draft-sekar-dns-ul.txt
+	EDNS0NSID          = 0x3     // nsid (See RFC 5001)
+	EDNS0DAU           = 0x5     // DNSSEC Algorithm Understood
+	EDNS0DHU           = 0x6     // DS Hash Understood
+	EDNS0N3U           = 0x7     // NSEC3 Hash Understood
+	EDNS0SUBNET        = 0x8     // client-subnet (See RFC 7871)
+	EDNS0EXPIRE        = 0x9     // EDNS0 expire
+	EDNS0COOKIE        = 0xa     // EDNS0 Cookie
+	EDNS0TCPKEEPALIVE  = 0xb     // EDNS0 tcp keep alive (See RFC 7828)
+	EDNS0PADDING       = 0xc     // EDNS0 padding (See RFC 7830)
+	EDNS0CHAIN         = 0xd     // EDNS0 chain query (See RFC 7901)
+	EDNS0EDE           = 0xf     // EDNS0 extended DNS errors (See RFC 8914)
+	EDNS0REPORTCHANNEL = 0x12    // EDNS0 report channel (See RFC 9567)
+	EDNS0LOCALSTART    = 0xFDE9  // Beginning of range reserved for local/experimental use (See RFC 6891)
+	EDNS0LOCALEND      = 0xFFFE  // End of range reserved for local/experimental use (See RFC 6891)
+	_DO                = 1 << 15 // DNSSEC OK
+	_CO                = 1 << 14 // Compact answers OK (See draft-ietf-dnsop-compact-denial-of-existence)
 )
 
 // OPT is the EDNS0 RR appended to messages to convey extra (meta) information.
@@ -36,9 +40,14 @@ type OPT struct {
 
 func (rr *OPT) String() string {
 	s := "\n;; OPT PSEUDOSECTION:\n; EDNS: version " + strconv.Itoa(int(rr.Version())) + "; "
-	if rr.Do() {
+	switch {
+	case rr.Do() && rr.Co():
+		s += "flags: do co; "
+	case rr.Do():
 		s += "flags: do; "
-	} else {
+	case rr.Co():
+		s += "flags: co; "
+	default:
 		s += "flags: ; "
 	}
 	s += "udp: " + strconv.Itoa(int(rr.UDPSize()))
@@ -73,19 +82,22 @@ func (rr *OPT) String() string {
 			s += "\n; LOCAL OPT: " + o.String()
 		case *EDNS0_PADDING:
 			s += "\n; PADDING: " + o.String()
+		case *EDNS0_EDE:
+			s += "\n; EDE: " + o.String()
+		case *EDNS0_REPORT_CHANNEL:
+			s += "\n; REPORT-CHANNEL: " + o.String()
+		case *EDNS0_CHAIN:
+			s += "\n; CHAIN: " + o.String()
+		case *EDNS0_PRIVATE:
+			s += "\n; PRIVATE OPT: " + o.String()
 		}
 	}
 	return s
 }
 
-func (rr *OPT) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	for i := 0; i < len(rr.Option); i++ {
-		l += 4 // Account for 2-byte option code and 2-byte option length.
-		lo, _ := rr.Option[i].pack()
-		l += len(lo)
-	}
-	return l
+func (rr *OPT) isDuplicate(r2 RR) bool {
+	// There should only be one OPT record in a message.
+	return false
 }
 
 // return the old value -> delete SetVersion?
@@ -112,6 +124,12 @@ func (rr *OPT) SetExtendedRcode(v uint16) {
 	rr.Hdr.Ttl = rr.Hdr.Ttl&0x00FFFFFF | uint32(v>>4)<<24
 }
 
+// Rcode returns the 12 bit RCODE of a message with the RCODE rcode in its
+// header and rr as OPT record.
+func (rr *OPT) Rcode(rcode int) int {
+	return rr.ExtendedRcode() | rcode&0xF
+}
+
 // UDPSize returns the UDP buffer size.
 func (rr *OPT) UDPSize() uint16 {
 	return rr.Hdr.Class
@@ -142,6 +160,30 @@ func (rr *OPT) SetDo(do ...bool) {
 	}
 }
 
+// Co returns the value of the CO (compact answers OK) bit.
+func (rr *OPT) Co() bool {
+	return rr.Hdr.Ttl&_CO == _CO
+}
+
+// SetCo sets the CO (compact answers OK) bit to co.
+func (rr *OPT) SetCo(co bool) {
+	if co {
+		rr.Hdr.Ttl |= _CO
+	} else {
+		rr.Hdr.Ttl &^= _CO
+	}
+}
+
+// Flags returns the 16 bits of EDNS flags, which hold the DO and CO bits.
+func (rr *OPT) Flags() uint16 {
+	return uint16(rr.Hdr.Ttl)
+}
+
+// SetFlags sets the 16 bits of EDNS flags.
+func (rr *OPT) SetFlags(flags uint16) {
+	rr.Hdr.Ttl = rr.Hdr.Ttl&0xFFFF0000 | uint32(flags)
+}
+
 // EDNS0 defines an EDNS0 Option. An OPT RR can have multiple options appended to it.
 type EDNS0 interface {
 	// Option returns the option code for the option.
@@ -153,6 +195,8 @@ type EDNS0 interface {
 	unpack([]byte) error
 	// String returns the string representation of the option.
 	String() string
+	// copy returns a deep-copy of the option.
+	copy() EDNS0
 }
 
 // EDNS0_NSID option is used to retrieve a nameserver
@@ -182,6 +226,7 @@ func (e *EDNS0_NSID) pack() ([]byte, error) {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_NSID) Option() uint16        { return EDNS0NSID } // Option returns the option code.
+func (e *EDNS0_NSID) copy() EDNS0           { return &EDNS0_NSID{e.Code, e.Nsid} }
 func (e *EDNS0_NSID) unpack(b []byte) error { e.Nsid = hex.EncodeToString(b); return nil }
 func (e *EDNS0_NSID) String() string        { return string(e.Nsid) }
 
@@ -215,6 +260,9 @@ type EDNS0_SUBNET struct {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_SUBNET) Option() uint16 { return EDNS0SUBNET }
+func (e *EDNS0_SUBNET) copy() EDNS0 {
+	return &EDNS0_SUBNET{e.Code, e.Family, e.SourceNetmask, e.SourceScope, copyIP(e.Address)}
+}
 
 func (e *EDNS0_SUBNET) pack() ([]byte, error) {
 	b := make([]byte, 4)
@@ -334,13 +382,14 @@ func (e *EDNS0_COOKIE) pack() ([]byte, error) {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_COOKIE) Option() uint16        { return EDNS0COOKIE }
+func (e *EDNS0_COOKIE) copy() EDNS0           { return &EDNS0_COOKIE{e.Code, e.Cookie} }
 func (e *EDNS0_COOKIE) unpack(b []byte) error { e.Cookie = hex.EncodeToString(b); return nil }
 func (e *EDNS0_COOKIE) String() string        { return e.Cookie }
 
 // The EDNS0_UL (Update Lease) (draft RFC) option is used to tell the server to set
 // an expiration on an update RR. This is helpful for clients that cannot clean
 // up after themselves. This is a draft RFC and more information can be found at
-// http://files.dns-sd.org/draft-sekar-dns-ul.txt
+// https://tools.ietf.org/html/draft-ietf-dnssd-update-lease
 //
 //	o := new(dns.OPT)
 //	o.Hdr.Name = "."
@@ -349,24 +398,45 @@ func (e *EDNS0_COOKIE) String() string        { return e.Cookie }
 //	e.Code = dns.EDNS0UL
 //	e.Lease = 120 // in seconds
 //	o.Option = append(o.Option, e)
+//
+// SetUpdateLease and UpdateLease do the same for a Msg.
 type EDNS0_UL struct {
-	Code  uint16 // Always EDNS0UL
-	Lease uint32
+	Code     uint16 // Always EDNS0UL
+	Lease    uint32
+	KeyLease uint32 // lease of the KEY records of the update, sent when non-zero
 }
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_UL) Option() uint16 { return EDNS0UL }
-func (e *EDNS0_UL) String() string { return strconv.FormatUint(uint64(e.Lease), 10) }
+func (e *EDNS0_UL) copy() EDNS0    { return &EDNS0_UL{e.Code, e.Lease, e.KeyLease} }
+func (e *EDNS0_UL) String() string {
+	s := strconv.FormatUint(uint64(e.Lease), 10)
+	if e.KeyLease != 0 {
+		s += " " + strconv.FormatUint(uint64(e.KeyLease), 10)
+	}
+	return s
+}
 
 // Copied: http://golang.org/src/pkg/net/dnsmsg.go
 func (e *EDNS0_UL) pack() ([]byte, error) {
+	if e.KeyLease != 0 {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint32(b, e.Lease)
+		binary.BigEndian.PutUint32(b[4:], e.KeyLease)
+		return b, nil
+	}
 	b := make([]byte, 4)
 	binary.BigEndian.PutUint32(b, e.Lease)
 	return b, nil
 }
 
 func (e *EDNS0_UL) unpack(b []byte) error {
-	if len(b) < 4 {
+	switch len(b) {
+	case 4:
+		e.KeyLease = 0
+	case 8:
+		e.KeyLease = binary.BigEndian.Uint32(b[4:])
+	default:
 		return ErrBuf
 	}
 	e.Lease = binary.BigEndian.Uint32(b)
@@ -386,6 +456,9 @@ type EDNS0_LLQ struct {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_LLQ) Option() uint16 { return EDNS0LLQ }
+func (e *EDNS0_LLQ) copy() EDNS0 {
+	return &EDNS0_LLQ{e.Code, e.Version, e.Opcode, e.Error, e.Id, e.LeaseLife}
+}
 
 func (e *EDNS0_LLQ) pack() ([]byte, error) {
 	b := make([]byte, 18)
@@ -424,6 +497,7 @@ type EDNS0_DAU struct {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_DAU) Option() uint16        { return EDNS0DAU }
+func (e *EDNS0_DAU) copy() EDNS0           { return &EDNS0_DAU{e.Code, copyBytes(e.AlgCode)} }
 func (e *EDNS0_DAU) pack() ([]byte, error) { return e.AlgCode, nil }
 func (e *EDNS0_DAU) unpack(b []byte) error { e.AlgCode = b; return nil }
 
@@ -447,6 +521,7 @@ type EDNS0_DHU struct {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_DHU) Option() uint16        { return EDNS0DHU }
+func (e *EDNS0_DHU) copy() EDNS0           { return &EDNS0_DHU{e.Code, copyBytes(e.AlgCode)} }
 func (e *EDNS0_DHU) pack() ([]byte, error) { return e.AlgCode, nil }
 func (e *EDNS0_DHU) unpack(b []byte) error { e.AlgCode = b; return nil }
 
@@ -470,6 +545,7 @@ type EDNS0_N3U struct {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_N3U) Option() uint16        { return EDNS0N3U }
+func (e *EDNS0_N3U) copy() EDNS0           { return &EDNS0_N3U{e.Code, copyBytes(e.AlgCode)} }
 func (e *EDNS0_N3U) pack() ([]byte, error) { return e.AlgCode, nil }
 func (e *EDNS0_N3U) unpack(b []byte) error { e.AlgCode = b; return nil }
 
@@ -494,6 +570,7 @@ type EDNS0_EXPIRE struct {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_EXPIRE) Option() uint16 { return EDNS0EXPIRE }
+func (e *EDNS0_EXPIRE) copy() EDNS0    { return &EDNS0_EXPIRE{e.Code, e.Expire} }
 func (e *EDNS0_EXPIRE) String() string { return strconv.FormatUint(uint64(e.Expire), 10) }
 
 func (e *EDNS0_EXPIRE) pack() ([]byte, error) {
@@ -533,6 +610,7 @@ type EDNS0_LOCAL struct {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_LOCAL) Option() uint16 { return e.Code }
+func (e *EDNS0_LOCAL) copy() EDNS0    { return &EDNS0_LOCAL{e.Code, copyBytes(e.Data)} }
 func (e *EDNS0_LOCAL) String() string {
 	return strconv.FormatInt(int64(e.Code), 10) + ":0x" + hex.EncodeToString(e.Data)
 }
@@ -565,36 +643,31 @@ type EDNS0_TCP_KEEPALIVE struct {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_TCP_KEEPALIVE) Option() uint16 { return EDNS0TCPKEEPALIVE }
+func (e *EDNS0_TCP_KEEPALIVE) copy() EDNS0    { return &EDNS0_TCP_KEEPALIVE{e.Code, e.Length, e.Timeout} }
 
 func (e *EDNS0_TCP_KEEPALIVE) pack() ([]byte, error) {
 	if e.Timeout != 0 && e.Length != 2 {
 		return nil, errors.New("dns: timeout specified but length is not 2")
 	}
-	if e.Timeout == 0 && e.Length != 0 {
-		return nil, errors.New("dns: timeout not specified but length is not 0")
+	if e.Length != 0 && e.Length != 2 {
+		return nil, errors.New("dns: length mismatch, want 0/2 but got " + strconv.FormatUint(uint64(e.Length), 10))
 	}
-	b := make([]byte, 4+e.Length)
-	binary.BigEndian.PutUint16(b[0:], e.Code)
-	binary.BigEndian.PutUint16(b[2:], e.Length)
+	b := make([]byte, e.Length)
 	if e.Length == 2 {
-		binary.BigEndian.PutUint16(b[4:], e.Timeout)
+		// A timeout of 0 signals the client to close the connection.
+		binary.BigEndian.PutUint16(b, e.Timeout)
 	}
 	return b, nil
 }
 
 func (e *EDNS0_TCP_KEEPALIVE) unpack(b []byte) error {
-	if len(b) < 4 {
-		return ErrBuf
-	}
-	e.Length = binary.BigEndian.Uint16(b[2:4])
+	e.Code = EDNS0TCPKEEPALIVE
+	e.Length = uint16(len(b))
 	if e.Length != 0 && e.Length != 2 {
 		return errors.New("dns: length mismatch, want 0/2 but got " + strconv.FormatUint(uint64(e.Length), 10))
 	}
 	if e.Length == 2 {
-		if len(b) < 6 {
-			return ErrBuf
-		}
-		e.Timeout = binary.BigEndian.Uint16(b[4:6])
+		e.Timeout = binary.BigEndian.Uint16(b)
 	}
 	return nil
 }
@@ -604,7 +677,7 @@ func (e *EDNS0_TCP_KEEPALIVE) String() (s string) {
 	if e.Length == 0 {
 		s += ", timeout omitted"
 	} else {
-		s += fmt.Sprintf(", timeout %dms", e.Timeout*100)
+		s += fmt.Sprintf(", timeout %dms", int(e.Timeout)*100)
 	}
 	return
 }
@@ -618,6 +691,176 @@ type EDNS0_PADDING struct {
 
 // Option implements the EDNS0 interface.
 func (e *EDNS0_PADDING) Option() uint16        { return EDNS0PADDING }
+func (e *EDNS0_PADDING) copy() EDNS0           { return &EDNS0_PADDING{copyBytes(e.Padding)} }
 func (e *EDNS0_PADDING) pack() ([]byte, error) { return e.Padding, nil }
 func (e *EDNS0_PADDING) unpack(b []byte) error { e.Padding = b; return nil }
 func (e *EDNS0_PADDING) String() string        { return fmt.Sprintf("%0X", e.Padding) }
+
+// Extended DNS Error Codes (RFC 8914).
+const (
+	ExtendedErrorCodeOther uint16 = iota
+	ExtendedErrorCodeUnsupportedDNSKEYAlgorithm
+	ExtendedErrorCodeUnsupportedDSDigestType
+	ExtendedErrorCodeStaleAnswer
+	ExtendedErrorCodeForgedAnswer
+	ExtendedErrorCodeDNSSECIndeterminate
+	ExtendedErrorCodeDNSBogus
+	ExtendedErrorCodeSignatureExpired
+	ExtendedErrorCodeSignatureNotYetValid
+	ExtendedErrorCodeDNSKEYMissing
+	ExtendedErrorCodeRRSIGsMissing
+	ExtendedErrorCodeNoZoneKeyBitSet
+	ExtendedErrorCodeNSECMissing
+	ExtendedErrorCodeCachedError
+	ExtendedErrorCodeNotReady
+	ExtendedErrorCodeBlocked
+	ExtendedErrorCodeCensored
+	ExtendedErrorCodeFiltered
+	ExtendedErrorCodeProhibited
+	ExtendedErrorCodeStaleNXDOMAINAnswer
+	ExtendedErrorCodeNotAuthoritative
+	ExtendedErrorCodeNotSupported
+	ExtendedErrorCodeNoReachableAuthority
+	ExtendedErrorCodeNetworkError
+	ExtendedErrorCodeInvalidData
+	ExtendedErrorCodeSignatureExpiredBeforeValid
+	ExtendedErrorCodeTooEarly
+	ExtendedErrorCodeUnsupportedNSEC3IterValue
+)
+
+// ExtendedErrorCodeToString maps extended error info codes to a human readable
+// description.
+var ExtendedErrorCodeToString = map[uint16]string{
+	ExtendedErrorCodeOther:                       "Other",
+	ExtendedErrorCodeUnsupportedDNSKEYAlgorithm:  "Unsupported DNSKEY Algorithm",
+	ExtendedErrorCodeUnsupportedDSDigestType:     "Unsupported DS Digest Type",
+	ExtendedErrorCodeStaleAnswer:                 "Stale Answer",
+	ExtendedErrorCodeForgedAnswer:                "Forged Answer",
+	ExtendedErrorCodeDNSSECIndeterminate:         "DNSSEC Indeterminate",
+	ExtendedErrorCodeDNSBogus:                    "DNSSEC Bogus",
+	ExtendedErrorCodeSignatureExpired:            "Signature Expired",
+	ExtendedErrorCodeSignatureNotYetValid:        "Signature Not Yet Valid",
+	ExtendedErrorCodeDNSKEYMissing:               "DNSKEY Missing",
+	ExtendedErrorCodeRRSIGsMissing:               "RRSIGs Missing",
+	ExtendedErrorCodeNoZoneKeyBitSet:             "No Zone Key Bit Set",
+	ExtendedErrorCodeNSECMissing:                 "NSEC Missing",
+	ExtendedErrorCodeCachedError:                 "Cached Error",
+	ExtendedErrorCodeNotReady:                    "Not Ready",
+	ExtendedErrorCodeBlocked:                     "Blocked",
+	ExtendedErrorCodeCensored:                    "Censored",
+	ExtendedErrorCodeFiltered:                    "Filtered",
+	ExtendedErrorCodeProhibited:                  "Prohibited",
+	ExtendedErrorCodeStaleNXDOMAINAnswer:         "Stale NXDOMAIN Answer",
+	ExtendedErrorCodeNotAuthoritative:            "Not Authoritative",
+	ExtendedErrorCodeNotSupported:                "Not Supported",
+	ExtendedErrorCodeNoReachableAuthority:        "No Reachable Authority",
+	ExtendedErrorCodeNetworkError:                "Network Error",
+	ExtendedErrorCodeInvalidData:                 "Invalid Data",
+	ExtendedErrorCodeSignatureExpiredBeforeValid: "Signature Expired before Valid",
+	ExtendedErrorCodeTooEarly:                    "Too Early",
+	ExtendedErrorCodeUnsupportedNSEC3IterValue:   "Unsupported NSEC3 Iterations Value",
+}
+
+// EDNS0_EDE option is used to return additional information about the cause
+// of DNS errors. See RFC 8914.
+type EDNS0_EDE struct {
+	InfoCode  uint16
+	ExtraText string
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_EDE) Option() uint16 { return EDNS0EDE }
+func (e *EDNS0_EDE) copy() EDNS0    { return &EDNS0_EDE{e.InfoCode, e.ExtraText} }
+
+func (e *EDNS0_EDE) pack() ([]byte, error) {
+	b := make([]byte, 2+len(e.ExtraText))
+	binary.BigEndian.PutUint16(b, e.InfoCode)
+	copy(b[2:], e.ExtraText)
+	return b, nil
+}
+
+func (e *EDNS0_EDE) unpack(b []byte) error {
+	if len(b) < 2 {
+		return ErrBuf
+	}
+	e.InfoCode = binary.BigEndian.Uint16(b)
+	e.ExtraText = string(b[2:])
+	return nil
+}
+
+func (e *EDNS0_EDE) String() string {
+	info := strconv.Itoa(int(e.InfoCode))
+	if s, ok := ExtendedErrorCodeToString[e.InfoCode]; ok {
+		info += fmt.Sprintf(" (%s)", s)
+	}
+	return fmt.Sprintf("%s: (%s)", info, e.ExtraText)
+}
+
+// EDNS0_REPORT_CHANNEL option is sent by authoritative servers to name the
+// agent domain where resolvers report the errors they find in their zones.
+// See RFC 9567.
+type EDNS0_REPORT_CHANNEL struct {
+	Code        uint16 // Always EDNS0REPORTCHANNEL
+	AgentDomain string
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_REPORT_CHANNEL) Option() uint16 { return EDNS0REPORTCHANNEL }
+func (e *EDNS0_REPORT_CHANNEL) copy() EDNS0    { return &EDNS0_REPORT_CHANNEL{e.Code, e.AgentDomain} }
+func (e *EDNS0_REPORT_CHANNEL) String() string { return sprintName(e.AgentDomain) }
+
+func (e *EDNS0_REPORT_CHANNEL) pack() ([]byte, error) {
+	b := make([]byte, 255)
+	off, err := PackDomainName(Fqdn(e.AgentDomain), b, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return b[:off], nil
+}
+
+func (e *EDNS0_REPORT_CHANNEL) unpack(b []byte) error {
+	name, off, err := UnpackDomainName(b, 0)
+	if err != nil {
+		return err
+	}
+	if off != len(b) {
+		return &Error{err: "bad report channel agent domain"}
+	}
+	e.AgentDomain = name
+	return nil
+}
+
+// EDNS0_CHAIN option asks a resolver for the DNSSEC chain of a response, from
+// the closest trust point of the client down to the zone of the answer. In a
+// response it names the trust point the chain starts at.
+// See RFC 7901.
+type EDNS0_CHAIN struct {
+	Code              uint16 // Always EDNS0CHAIN
+	ClosestTrustPoint string
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_CHAIN) Option() uint16 { return EDNS0CHAIN }
+func (e *EDNS0_CHAIN) copy() EDNS0    { return &EDNS0_CHAIN{e.Code, e.ClosestTrustPoint} }
+func (e *EDNS0_CHAIN) String() string { return sprintName(e.ClosestTrustPoint) }
+
+func (e *EDNS0_CHAIN) pack() ([]byte, error) {
+	b := make([]byte, 255)
+	off, err := PackDomainName(Fqdn(e.ClosestTrustPoint), b, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return b[:off], nil
+}
+
+func (e *EDNS0_CHAIN) unpack(b []byte) error {
+	name, off, err := UnpackDomainName(b, 0)
+	if err != nil {
+		return err
+	}
+	if off != len(b) {
+		return &Error{err: "bad chain closest trust point"}
+	}
+	e.ClosestTrustPoint = name
+	return nil
+}
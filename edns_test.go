@@ -109,3 +109,123 @@ func TestEDNS0_SUBNETUnpack(t *testing.T) {
 		}
 	}
 }
+
+func TestOPTCopy(t *testing.T) {
+	o := &OPT{Hdr: RR_Header{Name: ".", Rrtype: TypeOPT}}
+	o.Option = append(o.Option, &EDNS0_SUBNET{Code: EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()})
+	o.Option = append(o.Option, &EDNS0_LOCAL{Code: EDNS0LOCALSTART, Data: []byte{1, 2}})
+
+	c := o.copy().(*OPT)
+	if c.String() != o.String() {
+		t.Fatalf("expected copy to equal original:\n%s\n%s", c, o)
+	}
+	c.Option[0].(*EDNS0_SUBNET).Address[0] = 10
+	c.Option[1].(*EDNS0_LOCAL).Data[0] = 10
+	if o.Option[0].(*EDNS0_SUBNET).Address[0] != 192 || o.Option[1].(*EDNS0_LOCAL).Data[0] != 1 {
+		t.Error("expected options to be deep copied")
+	}
+}
+
+func TestEDNS0TCPKeepalive(t *testing.T) {
+	for _, e := range []*EDNS0_TCP_KEEPALIVE{
+		{Code: EDNS0TCPKEEPALIVE},
+		{Code: EDNS0TCPKEEPALIVE, Length: 2, Timeout: 300},
+		{Code: EDNS0TCPKEEPALIVE, Length: 2}, // close signal
+	} {
+		m := new(Msg)
+		m.SetQuestion("example.org.", TypeA)
+		m.SetEdns0(4096, false)
+		m.IsEdns0().Option = []EDNS0{e}
+		b, err := m.Pack()
+		if err != nil {
+			t.Fatalf("failed to pack %v: %v", e, err)
+		}
+		m1 := new(Msg)
+		if err := m1.Unpack(b); err != nil {
+			t.Fatalf("failed to unpack %v: %v", e, err)
+		}
+		e1, ok := m1.IsEdns0().Option[0].(*EDNS0_TCP_KEEPALIVE)
+		if !ok || *e1 != *e {
+			t.Errorf("expected %v, got %v", e, m1.IsEdns0().Option[0])
+		}
+	}
+}
+
+func TestEDNS0EDE(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	m.SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &EDNS0_EDE{InfoCode: ExtendedErrorCodeStaleAnswer, ExtraText: "upstream timed out"})
+
+	b, err := m.Pack()
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(b); err != nil {
+		t.Fatalf("failed to unpack: %v", err)
+	}
+	e, ok := m1.IsEdns0().Option[0].(*EDNS0_EDE)
+	if !ok || e.InfoCode != ExtendedErrorCodeStaleAnswer || e.ExtraText != "upstream timed out" {
+		t.Fatalf("expected extended error to survive a round trip, got %v", m1.IsEdns0().Option)
+	}
+	if s := e.String(); s != "3 (Stale Answer): (upstream timed out)" {
+		t.Errorf("unexpected String %q", s)
+	}
+}
+
+func TestOPTFlags(t *testing.T) {
+	opt := &OPT{Hdr: RR_Header{Name: ".", Rrtype: TypeOPT}}
+	opt.SetDo()
+	opt.SetCo(true)
+	if !opt.Co() || !opt.Do() || opt.Flags() != _DO|_CO {
+		t.Errorf("expected DO and CO set, got flags %#x", opt.Flags())
+	}
+	opt.SetExtendedRcode(RcodeBadCookie)
+	opt.SetVersion(1)
+	opt.SetCo(false)
+	if opt.Co() || !opt.Do() {
+		t.Errorf("expected only DO set, got flags %#x", opt.Flags())
+	}
+	opt.SetFlags(_CO)
+	if opt.Do() || !opt.Co() || opt.Version() != 1 || opt.Rcode(RcodeBadCookie&0xF) != RcodeBadCookie {
+		t.Errorf("expected only CO set and the rest kept, got %v", opt)
+	}
+}
+
+func TestMsgSetExtendedRcode(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	m.SetExtendedRcode(RcodeServerFailure)
+	if m.IsEdns0() != nil {
+		t.Errorf("expected no OPT record for %s", RcodeToString[RcodeServerFailure])
+	}
+	if m.Do() {
+		t.Error("expected DO unset without an OPT record")
+	}
+
+	m.SetExtendedRcode(RcodeBadCookie)
+	opt := m.IsEdns0()
+	if opt == nil || opt.Rcode(m.Rcode) != RcodeBadCookie {
+		t.Fatalf("expected an OPT record with the extended rcode, got %v", opt)
+	}
+	opt.SetDo()
+
+	b, err := m.Pack()
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(b); err != nil {
+		t.Fatalf("failed to unpack: %v", err)
+	}
+	if m1.Rcode != RcodeBadCookie || !m1.Do() {
+		t.Errorf("expected rcode %d with DO, got %d", RcodeBadCookie, m1.Rcode)
+	}
+
+	m1.SetExtendedRcode(RcodeNameError)
+	if m1.IsEdns0().ExtendedRcode() != 0 {
+		t.Errorf("expected the extended rcode cleared, got %d", m1.IsEdns0().ExtendedRcode())
+	}
+}
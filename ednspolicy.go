@@ -0,0 +1,113 @@
+package dns
+
+// DefaultEDNSUDPSize is the UDP size servers advertise by default, the one
+// of the DNS flag day 2020, which avoids IP fragmentation on most paths.
+const DefaultEDNSUDPSize = 1232
+
+// An EDNSPolicy is how a Server handles EDNS0 (RFC 6891) for its handlers:
+//
+//   - queries with more than one OPT record get a FORMERR response, and
+//     queries with an EDNS version other than 0 a BADVERS one, without calling
+//     the handler;
+//   - the UDP size of queries is lowered to MaxUDPSize, so handlers that
+//     truncate responses to it don't send more than the server wants;
+//   - responses to queries without EDNS0 have any OPT record removed,
+//     responses to queries with EDNS0 get an OPT record of version 0 with
+//     UDPSize, and the DO bit of the query;
+//   - the AD bit of responses is cleared unless the query had the AD or the
+//     DO bit set (RFC 6840, Section 5.8).
+type EDNSPolicy struct {
+	UDPSize    uint16 // UDP size advertised in responses, defaults to DefaultEDNSUDPSize, at least 512
+	MaxUDPSize uint16 // if non-zero, the maximum UDP size of queries seen by handlers, at least 512
+}
+
+// ednsRequest is the EDNS0 of a query, used to normalize its response.
+type ednsRequest struct {
+	opt *OPT // OPT record of the query, nil if it had none
+	ad  bool // the query had the AD or the DO bit set
+}
+
+func (p *EDNSPolicy) udpSize() uint16 {
+	switch {
+	case p.UDPSize == 0:
+		return DefaultEDNSUDPSize
+	case p.UDPSize < MinMsgSize:
+		return MinMsgSize
+	}
+	return p.UDPSize
+}
+
+// request checks the query r and lowers its UDP size. It returns the EDNS0
+// of r, or the response to send instead of calling the handler.
+func (p *EDNSPolicy) request(r *Msg) (*ednsRequest, *Msg) {
+	var opt *OPT
+	for _, rr := range r.Extra {
+		if o, ok := rr.(*OPT); ok {
+			if opt != nil {
+				m := new(Msg)
+				m.SetRcodeFormatError(r)
+				return nil, m
+			}
+			opt = o
+		}
+	}
+	if opt != nil && opt.Version() != 0 {
+		m := new(Msg)
+		m.SetRcode(r, RcodeBadVers)
+		m.SetEdns0(p.udpSize(), opt.Do())
+		return nil, m
+	}
+
+	if opt != nil && p.MaxUDPSize > 0 {
+		max := p.MaxUDPSize
+		if max < MinMsgSize {
+			max = MinMsgSize
+		}
+		if opt.UDPSize() > max {
+			opt.SetUDPSize(max)
+		}
+	}
+	return &ednsRequest{opt: opt, ad: r.AuthenticatedData || opt != nil && opt.Do()}, nil
+}
+
+// response normalizes the EDNS0 of m, the response to the query of e.
+func (p *EDNSPolicy) response(m *Msg, e *ednsRequest) {
+	normalizeEDNS(m, e.opt, p.udpSize())
+	if !e.ad {
+		m.AuthenticatedData = false
+	}
+}
+
+// normalizeEDNS sets the OPT record of m, the response to a query with the
+// OPT record opt: it is removed if opt is nil, and set to version 0, udpSize
+// and the DO bit of opt otherwise, before the TSIG record if any.
+func normalizeEDNS(m *Msg, opt *OPT, udpSize uint16) {
+	var ropt *OPT
+	extra := m.Extra[:0:0]
+	for _, rr := range m.Extra {
+		if o, ok := rr.(*OPT); ok {
+			if ropt == nil {
+				ropt = o
+			}
+			continue
+		}
+		extra = append(extra, rr)
+	}
+
+	if opt != nil {
+		if ropt == nil {
+			ropt = &OPT{Hdr: RR_Header{Name: ".", Rrtype: TypeOPT}}
+		}
+		ropt.SetVersion(0)
+		ropt.SetUDPSize(udpSize)
+		ropt.SetDo(opt.Do())
+
+		// The OPT record goes before the TSIG record.
+		if t := len(extra) - 1; t >= 0 && extra[t].Header().Rrtype == TypeTSIG {
+			extra = append(extra[:t], ropt, extra[t])
+		} else {
+			extra = append(extra, ropt)
+		}
+	}
+	m.Extra = extra
+}
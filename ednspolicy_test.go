@@ -0,0 +1,73 @@
+package dns
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestServerEDNSPolicy(t *testing.T) {
+	var calls, seen int32
+	HandleFunc("edns.example.", func(w ResponseWriter, r *Msg) {
+		atomic.AddInt32(&calls, 1)
+		if opt := r.IsEdns0(); opt != nil {
+			atomic.StoreInt32(&seen, int32(opt.UDPSize()))
+		}
+		m := new(Msg)
+		m.SetReply(r)
+		m.AuthenticatedData = true
+		m.SetEdns0(4096, false)
+		w.WriteMsg(m)
+	})
+	defer HandleRemove("edns.example.")
+
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.EDNS = &EDNSPolicy{MaxUDPSize: 1400}
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	c := new(Client)
+	m := new(Msg)
+	m.SetQuestion("edns.example.", TypeA)
+	r, _, err := c.Exchange(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.IsEdns0() != nil || r.AuthenticatedData {
+		t.Errorf("expected no OPT record and no AD bit, got %v", r)
+	}
+
+	m.SetEdns0(4096, true)
+	if r, _, err = c.Exchange(m, addrstr); err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if opt := r.IsEdns0(); opt == nil || opt.UDPSize() != DefaultEDNSUDPSize || !opt.Do() || !r.AuthenticatedData {
+		t.Errorf("expected UDP size %d, DO and AD, got %v", DefaultEDNSUDPSize, r)
+	}
+	if n := atomic.LoadInt32(&seen); n != 1400 {
+		t.Errorf("expected the handler to see UDP size 1400, got %d", n)
+	}
+
+	n := atomic.LoadInt32(&calls)
+	m.IsEdns0().SetVersion(1)
+	if r, _, err = c.Exchange(m, addrstr); err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.Rcode != RcodeBadVers || r.IsEdns0() == nil || r.IsEdns0().Version() != 0 {
+		t.Errorf("expected BADVERS, got %v", r)
+	}
+
+	m.IsEdns0().SetVersion(0)
+	m.Extra = append(m.Extra, &OPT{Hdr: RR_Header{Name: ".", Rrtype: TypeOPT}})
+	if r, _, err = c.Exchange(m, addrstr); err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.Rcode != RcodeFormatError {
+		t.Errorf("expected FORMERR for two OPT records, got %v", r)
+	}
+	if atomic.LoadInt32(&calls) != n {
+		t.Error("expected the handler not to be called for rejected queries")
+	}
+}
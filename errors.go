@@ -0,0 +1,179 @@
+package dns
+
+import (
+	"strconv"
+	"strings"
+)
+
+// An ErrorKind classifies the errors of the package, so callers can tell them
+// apart with errors.Is instead of matching their text:
+//
+//	if errors.Is(err, dns.KindTruncated) {
+//		// retry over TCP
+//	}
+//
+// The errors of the package, and the *UnpackError wrapping them, match their
+// kind. An ErrorKind is itself an error.
+type ErrorKind uint8
+
+// Kinds of errors.
+const (
+	KindOther        ErrorKind = iota // not classified
+	KindTruncated                     // the message, or a record in it, is cut short: ErrBuf, ErrShortRead or ErrTruncated
+	KindFormErr                       // the message is malformed
+	KindMismatch                      // the response doesn't match the query: ErrId, ErrQuestion or ErrSpoofSuspected
+	KindBadAlgorithm                  // the algorithm is not supported: ErrAlg or ErrKeyAlg
+	KindBadKey                        // the key is not known or not valid: ErrKey or ErrSecret
+	KindBadSig                        // the signature doesn't verify: ErrSig or ErrNoSig
+	KindBadTime                       // the signature is out of its time window: ErrTime
+	KindBadTrunc                      // the TSIG MAC is truncated too much: ErrBadTrunc
+)
+
+var errorKindToString = map[ErrorKind]string{
+	KindOther:        "other",
+	KindTruncated:    "truncated",
+	KindFormErr:      "format error",
+	KindMismatch:     "mismatch",
+	KindBadAlgorithm: "bad algorithm",
+	KindBadKey:       "bad key",
+	KindBadSig:       "bad signature",
+	KindBadTime:      "bad time",
+	KindBadTrunc:     "bad truncation",
+}
+
+func (k ErrorKind) String() string {
+	if s, ok := errorKindToString[k]; ok {
+		return s
+	}
+	return "kind" + strconv.Itoa(int(k))
+}
+
+func (k ErrorKind) Error() string { return "dns: " + k.String() }
+
+// Rcode returns the rcode a server answers a request failing with an error of
+// kind k with: FORMERR for malformed or truncated requests, the TSIG rcodes of
+// RFC 8945, Section 5.2 for the signature errors, and SERVFAIL for the others.
+func (k ErrorKind) Rcode() int {
+	switch k {
+	case KindTruncated, KindFormErr:
+		return RcodeFormatError
+	case KindBadAlgorithm:
+		return RcodeBadAlg
+	case KindBadKey:
+		return RcodeBadKey
+	case KindBadSig:
+		return RcodeBadSig
+	case KindBadTime:
+		return RcodeBadTime
+	case KindBadTrunc:
+		return RcodeBadTrunc
+	}
+	return RcodeServerFailure
+}
+
+// Is returns true if target is the kind of e.
+func (e *Error) Is(target error) bool {
+	k, ok := target.(ErrorKind)
+	return ok && e != nil && errorKind(e) == k
+}
+
+// errorKind returns the kind of err, KindOther for the errors not classified.
+func errorKind(err error) ErrorKind {
+	switch err {
+	case ErrBuf, ErrShortRead, ErrTruncated:
+		return KindTruncated
+	case ErrId, ErrQuestion, ErrSpoofSuspected:
+		return KindMismatch
+	case ErrAlg, ErrKeyAlg:
+		return KindBadAlgorithm
+	case ErrKey, ErrSecret:
+		return KindBadKey
+	case ErrSig, ErrNoSig:
+		return KindBadSig
+	case ErrTime:
+		return KindBadTime
+	case ErrBadTrunc:
+		return KindBadTrunc
+	case ErrRdata, ErrLongDomain, ErrExtendedRcode, ErrTrailingData, ErrForwardPointer, ErrPointerLoop,
+		ErrRdlength, ErrRdataCompression:
+		return KindFormErr
+	}
+	switch e := err.(type) {
+	case ErrorKind:
+		return e
+	case *Error:
+		// The errors of reading past the end of a message aren't
+		// variables, they name what was being unpacked.
+		if e != nil && strings.HasPrefix(e.err, "overflow") {
+			return KindTruncated
+		}
+	case interface{ Kind() ErrorKind }:
+		return e.Kind()
+	}
+	return KindOther
+}
+
+// An UnpackError is returned by UnpackStrict and UnpackLimited, it identifies
+// the record that was rejected. Unpack returns the error it wraps, as it did
+// before UnpackError existed.
+type UnpackError struct {
+	Section string // "question", "answer", "authority" or "additional", empty for the message itself
+	Index   int    // index of the record in Section
+	Offset  int    // offset in the message of the problem, or of the record that has it
+	Name    string // name of the question of the message, if it was unpacked
+	Type    uint16 // type of the record, 0 if unknown
+	Err     error
+}
+
+func (e *UnpackError) Error() string {
+	s := e.Err.Error() + " at offset " + strconv.Itoa(e.Offset)
+	if e.Section != "" {
+		s += " in " + e.Section + " record " + strconv.Itoa(e.Index)
+		if e.Type != 0 {
+			s += " (" + Type(e.Type).String() + ")"
+		}
+	}
+	return s
+}
+
+// Unwrap returns the error wrapped by e.
+func (e *UnpackError) Unwrap() error { return e.Err }
+
+// Kind returns the kind of e: the kind of the error it wraps, KindFormErr
+// when that is not classified.
+func (e *UnpackError) Kind() ErrorKind {
+	if k := errorKind(e.Err); k != KindOther {
+		return k
+	}
+	return KindFormErr
+}
+
+// Is returns true if target is the kind of e.
+func (e *UnpackError) Is(target error) bool {
+	k, ok := target.(ErrorKind)
+	return ok && k == e.Kind()
+}
+
+// unwrapUnpackError returns the error wrapped by err if it is an
+// *UnpackError, err otherwise.
+func unwrapUnpackError(err error) error {
+	if e, ok := err.(*UnpackError); ok {
+		return e.Err
+	}
+	return err
+}
+
+// unpackError returns err, the error unpacking the record at off in msg,
+// as an *UnpackError.
+func unpackError(err error, msg []byte, section string, i, off int) error {
+	if _, ok := err.(*UnpackError); ok {
+		return err
+	}
+	e := &UnpackError{Section: section, Index: i, Offset: off, Err: err}
+	if section != "question" {
+		// The header is filled as far as it could be unpacked.
+		h, _, _, _ := unpackHeader(msg, off, nil)
+		e.Type = h.Rrtype
+	}
+	return e
+}
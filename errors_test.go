@@ -0,0 +1,91 @@
+//go:build go1.13
+// +build go1.13
+
+package dns
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUnpackErrorKind(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeMX)
+	m.Answer = []RR{
+		testRR("example.org. 3600 IN MX 10 mx.example.org."),
+		testRR("example.org. 3600 IN TXT \"some text\""),
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+
+	// Cut in the rdata of the TXT record.
+	err = new(Msg).UnpackStrict(buf[:len(buf)-3])
+	var e *UnpackError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected an *UnpackError, got %v", err)
+	}
+	if e.Section != "answer" || e.Index != 1 || e.Type != TypeTXT || e.Name != "example.org." {
+		t.Errorf("expected the TXT record of the answer section, got %#v", e)
+	}
+	if !errors.Is(err, KindTruncated) || errors.Is(err, KindFormErr) || e.Kind().Rcode() != RcodeFormatError {
+		t.Errorf("expected a truncated message, got %v", err)
+	}
+
+	// A header too short is the message itself.
+	err = new(Msg).UnpackStrict(buf[:5])
+	if !errors.As(err, &e) || e.Section != "" || !errors.Is(err, KindTruncated) {
+		t.Errorf("expected a truncated header, got %v", err)
+	}
+
+	// A bad compression pointer in the question is malformed.
+	bad := append([]byte(nil), buf[:12]...)
+	bad = append(bad, 0x80, 0, 0, 1, 0, 1)
+	bad[5], bad[7] = 1, 0 // one question, no answer
+	err = new(Msg).UnpackStrict(bad)
+	if !errors.As(err, &e) || e.Section != "question" || !errors.Is(err, KindFormErr) || !errors.Is(err, ErrRdata) {
+		t.Errorf("expected a malformed question, got %v", err)
+	}
+
+	// Unpack returns the error itself, which has its kind too.
+	if err := new(Msg).Unpack(bad); err != ErrRdata || !errors.Is(err, KindFormErr) {
+		t.Errorf("expected ErrRdata, got %v", err)
+	}
+}
+
+func TestTsigErrorKind(t *testing.T) {
+	m := newTsig(HmacSHA256)
+	buf, _, err := TsigGenerate(m, "pRZgBrBvI4NAHZYhxmhs/Q==", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = TsigVerify(buf, "c3VwZXJzZWNyZXQ=", "", false)
+	if err != ErrSig || !errors.Is(err, KindBadSig) || errorKind(err).Rcode() != RcodeBadSig {
+		t.Errorf("expected ErrSig, got %v", err)
+	}
+
+	m = new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	m.SetTsig("example.", HmacSHA256, 300, time.Now().Add(-time.Hour).Unix())
+	buf, _, err = TsigGenerate(m, "pRZgBrBvI4NAHZYhxmhs/Q==", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = TsigVerify(buf, "pRZgBrBvI4NAHZYhxmhs/Q==", "", false)
+	if !errors.Is(err, KindBadTime) || errors.Is(err, KindBadSig) {
+		t.Errorf("expected ErrTime, got %v", err)
+	}
+	if KindBadTime.Error() != "dns: bad time" {
+		t.Errorf("unexpected text for KindBadTime: %q", KindBadTime.Error())
+	}
+}
+
+func TestMismatchErrorKind(t *testing.T) {
+	for _, err := range []error{ErrId, ErrQuestion, ErrSpoofSuspected} {
+		if !errors.Is(err, KindMismatch) || errors.Is(err, KindFormErr) {
+			t.Errorf("expected %v to be a mismatch", err)
+		}
+	}
+}
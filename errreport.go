@@ -0,0 +1,60 @@
+package dns
+
+import "strconv"
+
+// SetReportChannel sets the Report-Channel option of dns to agent, the
+// domain where resolvers report the errors they find in the zones of an
+// authoritative server (RFC 9567). An OPT record is added, before the TSIG
+// record, if dns has none.
+func (dns *Msg) SetReportChannel(agent string) *Msg {
+	opt := responseOPT(dns)
+	for _, o := range opt.Option {
+		if e, ok := o.(*EDNS0_REPORT_CHANNEL); ok {
+			e.AgentDomain = Fqdn(agent)
+			return dns
+		}
+	}
+	opt.Option = append(opt.Option, &EDNS0_REPORT_CHANNEL{Code: EDNS0REPORTCHANNEL, AgentDomain: Fqdn(agent)})
+	return dns
+}
+
+// ReportChannel returns the agent domain of the Report-Channel option of dns,
+// and whether it has one.
+func (dns *Msg) ReportChannel() (string, bool) {
+	opt := dns.IsEdns0()
+	if opt == nil {
+		return "", false
+	}
+	for _, o := range opt.Option {
+		if e, ok := o.(*EDNS0_REPORT_CHANNEL); ok && e.AgentDomain != "" && e.AgentDomain != "." {
+			return e.AgentDomain, true
+		}
+	}
+	return "", false
+}
+
+// ErrorReport returns the query that reports the extended DNS error infoCode,
+// found while resolving the question of the response r, to the agent domain of
+// the Report-Channel option of r (RFC 9567): a TXT query for
+// _er.<qtype>.<qname>.<infoCode>._er.<agent domain>. It returns nil when r has
+// no Report-Channel option or question, or when the name would be too long.
+//
+// The report is resolved like any other query, and the response ignored.
+func ErrorReport(r *Msg, infoCode uint16) *Msg {
+	agent, ok := r.ReportChannel()
+	if !ok || len(r.Question) == 0 {
+		return nil
+	}
+	q := r.Question[0]
+	name := "_er." + strconv.Itoa(int(q.Qtype)) + "."
+	if q.Name != "." {
+		name += Fqdn(q.Name)
+	}
+	name += strconv.Itoa(int(infoCode)) + "._er." + Fqdn(agent)
+	if _, ok := IsDomainName(name); !ok {
+		return nil
+	}
+	m := new(Msg)
+	m.SetQuestion(name, TypeTXT)
+	return m
+}
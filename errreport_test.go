@@ -0,0 +1,43 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReportChannel(t *testing.T) {
+	m := new(Msg)
+	m.SetReply(new(Msg).SetQuestion("broken.test.", TypeA))
+	m.SetReportChannel("a01.agent-domain.example").SetReportChannel("a02.agent-domain.example.")
+
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	r := new(Msg)
+	if err := r.Unpack(buf); err != nil {
+		t.Fatalf("failed to unpack: %v", err)
+	}
+	if opt := r.IsEdns0(); opt == nil || len(opt.Option) != 1 {
+		t.Fatalf("expected a single Report-Channel option, got %v", r)
+	}
+	if agent, ok := r.ReportChannel(); !ok || agent != "a02.agent-domain.example." {
+		t.Errorf("expected agent domain a02.agent-domain.example., got %q", agent)
+	}
+	if !strings.Contains(r.String(), "REPORT-CHANNEL: a02.agent-domain.example.") {
+		t.Errorf("expected Report-Channel in presentation, got %s", r)
+	}
+
+	report := ErrorReport(r, ExtendedErrorCodeDNSKEYMissing)
+	if report == nil || report.Question[0].Name != "_er.1.broken.test.9._er.a02.agent-domain.example." || report.Question[0].Qtype != TypeTXT {
+		t.Errorf("unexpected report %v", report)
+	}
+
+	r.Question[0].Name = strings.Repeat("a23456789.", 24)
+	if report := ErrorReport(r, ExtendedErrorCodeDNSKEYMissing); report != nil {
+		t.Errorf("expected no report for a name too long, got %v", report)
+	}
+	if report := ErrorReport(new(Msg).SetQuestion("broken.test.", TypeA), 6); report != nil {
+		t.Errorf("expected no report without Report-Channel, got %v", report)
+	}
+}
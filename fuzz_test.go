@@ -0,0 +1,158 @@
+//go:build go1.18
+// +build go1.18
+
+package dns
+
+//go:generate go run fuzz_generate.go
+
+import (
+	"strings"
+	"testing"
+)
+
+// fuzzHeader returns the header used for the records of type rrtype that are
+// fuzzed.
+func fuzzHeader(rrtype uint16) RR_Header {
+	return RR_Header{Name: "example.org.", Rrtype: rrtype, Class: ClassINET, Ttl: 3600}
+}
+
+// fuzzSeeds holds example rdata in presentation format, it seeds the corpus of
+// both the wire and the text format fuzz targets.
+var fuzzSeeds = map[uint16][]string{
+	TypeA:          {"192.0.2.1"},
+	TypeAAAA:       {"2001:db8::1"},
+	TypeAFSDB:      {"1 afsdb.example.org."},
+	TypeAMTRELAY:   {"10 0 1 203.0.113.15", "128 1 3 amtrelays.example.org.", "0 0 0 ."},
+	TypeCAA:        {`0 issue "ca.example.net"`},
+	TypeCDNSKEY:    {"257 3 8 AwEAAcNEU67LJI5GEgF9QLNqLO1SMq1EdoQ6E9f85ha0k0ewQGCblyW2836GiVsm6k8Kr5ECIoMJ6fZWf3CQSQ9ycWfTyOHfmI3eQ/1Covhb2y4bAmL/07PhrL7ozWBW3wBfM335Ft9xjtXHPy7ztCbV9qZ4TVDTW/Iyg0PiwgoXVesz"},
+	TypeCDS:        {"42 8 2 76C8E2C8F7F8B8A8E3FF3F0B8C2D8AF96B1C8C43E0A1D1B4BB14C4B5AF6F1B6C"},
+	TypeCERT:       {"PKIX 1 RSASHA1 MIIC9jCCAl+gAwIBAgIBATANBgkqhkiG9w0BAQUFADAO"},
+	TypeCNAME:      {"target.example.org."},
+	TypeCSYNC:      {"66 3 A NS AAAA"},
+	TypeDHCID:      {"AAIBY2/AuCccgoJbsaxcQc9TUapptP69lOjxfNuVAA2kjEA="},
+	TypeDNAME:      {"target.example.org."},
+	TypeDNSKEY:     {"257 3 8 AwEAAcNEU67LJI5GEgF9QLNqLO1SMq1EdoQ6E9f85ha0k0ewQGCblyW2836GiVsm6k8Kr5ECIoMJ6fZWf3CQSQ9ycWfTyOHfmI3eQ/1Covhb2y4bAmL/07PhrL7ozWBW3wBfM335Ft9xjtXHPy7ztCbV9qZ4TVDTW/Iyg0PiwgoXVesz"},
+	TypeDOA:        {`0 1 2 "image/pmp" aHR0cHM6Ly93d3cuZXhhbXBsZS5vcmcv`, `1234567 1 0 "" -`},
+	TypeDS:         {"42 8 2 76C8E2C8F7F8B8A8E3FF3F0B8C2D8AF96B1C8C43E0A1D1B4BB14C4B5AF6F1B6C"},
+	TypeEUI48:      {"00-00-5e-90-01-2a"},
+	TypeEUI64:      {"00-00-5e-ef-10-00-00-2a"},
+	TypeGPOS:       {"-32.6882 116.8652 10.0"},
+	TypeHINFO:      {`"PC-Intel-700mhz" "Redhat Linux 7.1"`},
+	TypeHIP:        {"2 200100107B1A74DF365639CC39F1D578 AwEAAbdxyhNuSutc5EMzxTs9LBPCIkOFH8cIvM4p9+LrV4e19WzK00+CI6zBCQTdtWsuxKbWIy87UOoJTwkUs7lBu+Upr1gsNrut79ryra+bSRGQb1slImA8YVJyuIDsj7kwzG7jnERNqnWxZ48AWkskmdHaVDP4BcelrTI3rMXdXF5D rvs.example.com."},
+	TypeKX:         {"10 kx.example.org."},
+	TypeL32:        {"10 10.1.2.0"},
+	TypeL64:        {"10 2001:0DB8:1140:1000"},
+	TypeLOC:        {"51 30 12.748 N 00 07 39.611 W 0.00m 0.00m 0.00m 0.00m"},
+	TypeLP:         {"10 l64-subnet1.example.com."},
+	TypeMB:         {"mb.example.org."},
+	TypeMINFO:      {"rmail.example.org. email.example.org."},
+	TypeMX:         {"10 mx.example.org."},
+	TypeNAPTR:      {`100 50 "s" "z3950+I2L+I2C" "" _z3950._tcp.gatech.edu.`},
+	TypeNID:        {"10 0014:4fff:ff20:ee64"},
+	TypeNS:         {"ns.example.org."},
+	TypeNSEC:       {"next.example.org. A MX RRSIG NSEC TYPE1234"},
+	TypeNSEC3:      {"1 1 12 AABBCCDD 2VPTU5TIMAMQTTGL4LUU9KG21E0AOR3S A RRSIG"},
+	TypeNSEC3PARAM: {"1 0 12 AABBCCDD"},
+	TypeOPENPGPKEY: {"mQENBFRGP7EBCADTJYdcgsH/rnmHbFnjPQ4+vZ+z3NuQwNmV7cmg14bL"},
+	TypePTR:        {"ptr.example.org."},
+	TypePX:         {"10 map822.example.org. mapx400.example.org."},
+	TypeRP:         {"mbox.example.org. txt.example.org."},
+	TypeRRSIG:      {"A 8 2 3600 20190101000000 20180101000000 12345 example.org. oJB1W6WNGv+ldvQ3WDG0MQkg5IEhjRip8WTrPYGv07h108dUKGMeDPKijVCHX3DDKdfb+v6oB9wfuh3DTJXUAfI/M0zmO/zz8bW0Rznl8O3tGNazPwQKkRN20XPXV6nwwfoXmJQbsLNrLfkGJ5D6fwFm8nN+6pBzeDQfsS3Ap3o="},
+	TypeRT:         {"10 rt.example.org."},
+	TypeSMIMEA:     {"3 1 1 D2ABDE240D7CD3EE6B4B28C54DF034B97983A1D16E8A410E4561CB106618E971"},
+	TypeSOA:        {"ns.example.org. hostmaster.example.org. 2019010101 3600 900 604800 86400"},
+	TypeSPF:        {`"v=spf1 -all"`},
+	TypeSRV:        {"10 20 5060 sip.example.org."},
+	TypeSSHFP:      {"1 2 BC6533CDC95A79078A39A56EA7635984ED655318ADA9B6159E30723665DA95BB"},
+	TypeTA:         {"42 8 2 76C8E2C8F7F8B8A8E3FF3F0B8C2D8AF96B1C8C43E0A1D1B4BB14C4B5AF6F1B6C"},
+	TypeTALINK:     {"h0.example.org. h2.example.org."},
+	TypeTLSA:       {"3 1 1 D2ABDE240D7CD3EE6B4B28C54DF034B97983A1D16E8A410E4561CB106618E971"},
+	TypeTXT:        {`"v=spf1 -all"`, `"a" "b c" "\"quoted\""`},
+	TypeUID:        {"1000"},
+	TypeURI:        {`10 1 "https://www.example.org/"`},
+	TypeX25:        {"311061700956"},
+}
+
+// fuzzWire fuzzes the rdata in wire format of records of type rrtype. Rdata
+// that unpacks must survive a pack and unpack round trip.
+func fuzzWire(f *testing.F, rrtype uint16) {
+	h := fuzzHeader(rrtype)
+	for _, s := range fuzzSeeds[rrtype] {
+		rr, err := NewRR(h.String() + s)
+		if err != nil {
+			f.Fatalf("failed to parse seed %q: %v", s, err)
+		}
+		buf := make([]byte, Len(rr))
+		headerEnd, off, err := packRR(rr, buf, 0, compressionMap{}, false)
+		if err != nil {
+			f.Fatalf("failed to pack seed %q: %v", s, err)
+		}
+		f.Add(buf[headerEnd:off])
+	}
+
+	f.Fuzz(func(t *testing.T, rdata []byte) {
+		// Empty rdata is only used in dynamic updates.
+		if len(rdata) == 0 || len(rdata) > 0xFFFF {
+			return
+		}
+		h := fuzzHeader(rrtype)
+		msg := make([]byte, h.len(0, nil)+len(rdata))
+		_, off, err := h.pack(msg, 0, compressionMap{}, false)
+		if err != nil {
+			t.Fatalf("failed to pack header: %v", err)
+		}
+		packUint16(uint16(len(rdata)), msg, off-2)
+		copy(msg[off:], rdata)
+
+		rr, _, err := UnpackRR(msg, 0)
+		if err != nil || rr == nil {
+			return
+		}
+
+		buf := make([]byte, Len(rr))
+		off, err = PackRR(rr, buf, 0, nil, false)
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", rr, err)
+		}
+		if off != len(buf) {
+			t.Errorf("Len of %s is %d, packed %d octets", rr, len(buf), off)
+		}
+		rr1, _, err := UnpackRR(buf[:off], 0)
+		if err != nil {
+			t.Fatalf("failed to unpack packed %s: %v", rr, err)
+		}
+		if !IsDuplicate(rr, rr1) {
+			t.Errorf("wire round trip changed %s into %s", rr, rr1)
+		}
+	})
+}
+
+// fuzzText fuzzes the rdata in presentation format of records of type rrtype.
+// Rdata that parses must survive a String and parse round trip: the text of
+// the record parsed again is the same. The records themselves may differ,
+// e.g. a TXT record keeps the escapes of its text as they were written.
+func fuzzText(f *testing.F, rrtype uint16) {
+	for _, s := range fuzzSeeds[rrtype] {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, rdata string) {
+		// Only a single record with rdata, no control entries.
+		if strings.TrimSpace(rdata) == "" || strings.ContainsAny(rdata, "\n$") {
+			return
+		}
+		h := fuzzHeader(rrtype)
+		rr, err := NewRR(h.String() + rdata)
+		if err != nil || rr == nil || rr.Header().Rrtype != rrtype {
+			return
+		}
+
+		rr1, err := NewRR(rr.String())
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", rr.String(), err)
+		}
+		if rr.String() != rr1.String() {
+			t.Errorf("text round trip changed %s into %s", rr, rr1)
+		}
+	})
+}
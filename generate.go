@@ -18,6 +18,11 @@ import (
 // * rhs (rdata)
 // But we are lazy here, only the range is parsed *all* occurrences
 // of $ after that are interpreted.
+//
+// The modifiers are ${offset[,width[,base]]}, with base d, o, x, X, n or N
+// as in BIND: n and N print the value in nibble format, the hexadecimal
+// digits in reverse order separated by dots, for use in ip6.arpa names.
+// A $GENERATE can't create more than maxGenerate records.
 func (zp *ZoneParser) generate(l lex) (RR, bool) {
 	token := l.token
 	step := 1
@@ -49,14 +54,18 @@ func (zp *ZoneParser) generate(l lex) (RR, bool) {
 	if err != nil {
 		return zp.setParseError("bad stop in $GENERATE range", l)
 	}
-	if end < 0 || start < 0 || end < start {
+	if end < 0 || start < 0 || end < start || end > 1<<31-1 {
 		return zp.setParseError("bad range in $GENERATE range", l)
 	}
+	if (end-start)/step >= maxGenerate {
+		return zp.setParseError("too many records in $GENERATE range", l)
+	}
 
 	zp.c.Next() // _BLANK
 
 	// Create a complete new string, which we then parse again.
 	var s string
+	first := l // the first token of s, errors are reported relative to it
 	for l, ok := zp.c.Next(); ok; l, ok = zp.c.Next() {
 		if l.err {
 			return zp.setParseError("bad data in $GENERATE directive", l)
@@ -65,6 +74,9 @@ func (zp *ZoneParser) generate(l lex) (RR, bool) {
 			break
 		}
 
+		if s == "" {
+			first = l
+		}
 		s += l.token
 	}
 
@@ -77,14 +89,19 @@ func (zp *ZoneParser) generate(l lex) (RR, bool) {
 		step:  step,
 
 		file: zp.file,
-		lex:  &l,
+		lex:  &first,
 	}
 	zp.sub = NewZoneParser(r, zp.origin, zp.file)
-	zp.sub.includeDepth, zp.sub.includeAllowed = zp.includeDepth, zp.includeAllowed
+	zp.sub.includeDepth, zp.sub.includeLimit, zp.sub.includeAllowed = zp.includeDepth, zp.includeLimit, zp.includeAllowed
+	zp.sub.include, zp.sub.idna = zp.include, zp.idna
 	zp.sub.SetDefaultTTL(defaultTtl)
 	return zp.subNext()
 }
 
+// maxGenerate is the maximum number of records a $GENERATE directive creates,
+// enough for a /16 of reverse names.
+const maxGenerate = 1 << 16
+
 type generateReader struct {
 	s  string
 	si int
@@ -109,7 +126,7 @@ func (r *generateReader) parseError(msg string, end int) *ParseError {
 
 	l := *r.lex
 	l.token = r.s[r.si-1 : end]
-	l.column += r.si // l.column starts one zBLANK before r.s
+	l.column += r.si - 1 // l.column is the column of r.s[0]
 
 	return &ParseError{r.file, msg, l}
 }
@@ -159,7 +176,7 @@ func (r *generateReader) ReadByte() (byte, error) {
 
 		if si >= len(r.s)-1 {
 			// End of the string
-			fmt.Fprintf(&r.mod, mod, r.cur)
+			r.mod.WriteString(formatGenerate(mod, r.cur))
 			return r.mod.ReadByte()
 		}
 
@@ -190,7 +207,7 @@ func (r *generateReader) ReadByte() (byte, error) {
 			r.si += 2 + sep // Jump to it
 		}
 
-		fmt.Fprintf(&r.mod, mod, r.cur+offset)
+		r.mod.WriteString(formatGenerate(mod, r.cur+offset))
 		return r.mod.ReadByte()
 	default:
 		if r.escape { // Pretty useless here
@@ -219,7 +236,7 @@ func modToPrintf(s string) (string, int, string) {
 	}
 
 	switch base {
-	case "o", "d", "x", "X":
+	case "o", "d", "x", "X", "n", "N":
 	default:
 		return "", 0, "bad base in $GENERATE"
 	}
@@ -240,3 +257,33 @@ func modToPrintf(s string) (string, int, string) {
 
 	return "%0" + widthStr + base, offset, ""
 }
+
+// formatGenerate formats v with mod, a format returned by modToPrintf.
+func formatGenerate(mod string, v int) string {
+	base := mod[len(mod)-1]
+	if base != 'n' && base != 'N' {
+		return fmt.Sprintf(mod, v)
+	}
+
+	// The width counts the dots too, as in BIND.
+	width, _ := strconv.Atoi(mod[1 : len(mod)-1])
+	digits := "0123456789abcdef"
+	if base == 'N' {
+		digits = "0123456789ABCDEF"
+	}
+	var b strings.Builder
+	for {
+		b.WriteByte(digits[v&0xf])
+		v >>= 4
+		width--
+		if v == 0 && width <= 0 {
+			break
+		}
+		b.WriteByte('.')
+		width--
+		if v == 0 && width <= 0 {
+			break
+		}
+	}
+	return b.String()
+}
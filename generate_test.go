@@ -172,6 +172,8 @@ func TestGenerateModToPrintf(t *testing.T) {
 		{"0,0,z", "", 0, true},
 		{"0,0,0,d", "", 0, true},
 		{"-100,0,d", "%d", -100, false},
+		{"0,4,n", "%04n", 0, false},
+		{"0,0,N", "%N", 0, false},
 	}
 	for _, test := range tests {
 		gotFmt, gotOffset, errMsg := modToPrintf(test.mod)
@@ -188,6 +190,63 @@ func TestGenerateModToPrintf(t *testing.T) {
 	}
 }
 
+func TestGenerateFormat(t *testing.T) {
+	tests := []struct {
+		mod  string
+		v    int
+		want string
+	}{
+		{"%d", 10, "10"},
+		{"%03o", 8, "010"},
+		{"%n", 0x1a, "a.1"},
+		{"%N", 0x1a, "A.1"},
+		{"%04n", 0x1a, "a.1."},
+		{"%05n", 0x1a, "a.1.0"},
+		{"%03n", 0x123, "3.2.1"},
+		{"%n", 0, "0"},
+	}
+	for _, test := range tests {
+		if got := formatGenerate(test.mod, test.v); got != test.want {
+			t.Errorf("formatGenerate(%q, %d) = %q, want %q", test.mod, test.v, got, test.want)
+		}
+	}
+}
+
+func TestGenerateRecords(t *testing.T) {
+	const zone = `$ORIGIN 0.0.8.b.d.0.1.0.0.2.ip6.arpa.
+$GENERATE 10-11 ${0,3,n} PTR host$.example.
+$GENERATE 1-2 @ TXT "cost $$$ for ${10,3,d}"
+$GENERATE 1-3/2 mx$ MX ${0,0,d} mail\$.example.
+`
+	want := []string{
+		"a.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.\t3600\tIN\tPTR\thost10.example.",
+		"b.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.\t3600\tIN\tPTR\thost11.example.",
+		"0.0.8.b.d.0.1.0.0.2.ip6.arpa.\t3600\tIN\tTXT\t\"cost $1 for 011\"",
+		"0.0.8.b.d.0.1.0.0.2.ip6.arpa.\t3600\tIN\tTXT\t\"cost $2 for 012\"",
+		"mx1.0.0.8.b.d.0.1.0.0.2.ip6.arpa.\t3600\tIN\tMX\t1 mail$.example.",
+		"mx3.0.0.8.b.d.0.1.0.0.2.ip6.arpa.\t3600\tIN\tMX\t3 mail$.example.",
+	}
+	zp := NewZoneParser(strings.NewReader(zone), "", "")
+	var got []string
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		got = append(got, rr.String())
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatalf("failed to parse the zone: %v", err)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	zp = NewZoneParser(strings.NewReader("$GENERATE 0-100000 host$ A 127.0.0.1\n"), "example.", "")
+	for _, ok := zp.Next(); ok; _, ok = zp.Next() {
+		t.Fatal("expected no records for a range that is too large")
+	}
+	if err := zp.Err(); err == nil || !strings.Contains(err.Error(), "too many records") {
+		t.Errorf("expected an error for a range that is too large, got %v", err)
+	}
+}
+
 func BenchmarkGenerate(b *testing.B) {
 	const zone = `@ IN SOA ns.test. hostmaster.test. ( 1 8h 2h 7d 1d )
 $GENERATE 32-158 dhcp-${-32,4,d} A 10.0.0.$
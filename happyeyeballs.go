@@ -0,0 +1,149 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// defaultFallbackDelay is the Connection Attempt Delay of RFC 8305, Section 5.
+const defaultFallbackDelay = 250 * time.Millisecond
+
+var errNoAddrs = &Error{err: "happy eyeballs: no addresses"}
+
+// ExchangeAddrs is like Exchange, but sends m to the server at the addresses
+// addrs, e.g. the IPv6 and IPv4 addresses of an upstream, racing them as RFC
+// 8305 does: the addresses are tried alternating between IPv6 and IPv4, IPv6
+// first, the next one after Client.FallbackDelay or as soon as the previous
+// one fails, and the first response wins. Whole queries are raced, not only
+// connections, so unreachable UDP addresses are passed over too.
+func (c *Client) ExchangeAddrs(m *Msg, addrs []string) (r *Msg, rtt time.Duration, err error) {
+	return c.exchangeRace(m, interleaveAddrs(addrs), attempt{})
+}
+
+// happyEyeballs returns the addresses of the host of address, ordered as RFC
+// 8305 does, and whether address has a host name to resolve, rather than an IP
+// address.
+func (c *Client) happyEyeballs(address string) ([]string, bool, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return nil, false, nil
+	}
+	resolver := net.DefaultResolver
+	if c.Dialer != nil && c.Dialer.Resolver != nil {
+		resolver = c.Dialer.Resolver
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.getTimeoutForRequest(c.dialTimeout()))
+	defer cancel()
+	ips, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, true, err
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		h := ip.IP.String()
+		if ip.Zone != "" {
+			h += "%" + ip.Zone
+		}
+		addrs[i] = net.JoinHostPort(h, port)
+	}
+	return interleaveAddrs(addrs), true, nil
+}
+
+// interleaveAddrs orders addrs alternating between IPv6 and IPv4 addresses,
+// IPv6 first, keeping the order within each family (RFC 8305, Section 4).
+func interleaveAddrs(addrs []string) []string {
+	var v6, v4 []string
+	for _, a := range addrs {
+		host, _, err := net.SplitHostPort(a)
+		if ip := net.ParseIP(host); err == nil && ip != nil && ip.To4() == nil {
+			v6 = append(v6, a)
+			continue
+		}
+		v4 = append(v4, a)
+	}
+	ordered := make([]string, 0, len(addrs))
+	for len(v6) > 0 || len(v4) > 0 {
+		if len(v6) > 0 {
+			ordered, v6 = append(ordered, v6[0]), v6[1:]
+		}
+		if len(v4) > 0 {
+			ordered, v4 = append(ordered, v4[0]), v4[1:]
+		}
+	}
+	return ordered
+}
+
+// raceResult is the outcome of the query to one of the addresses raced.
+type raceResult struct {
+	r   *Msg
+	rtt time.Duration
+	err error
+}
+
+// exchangeRace sends m to addrs in turn, starting the query to the next
+// address after the fallback delay or when the previous one failed, and
+// returns the first response. The queries still in flight are then
+// cancelled. When all fail, the error of the last one is returned.
+func (c *Client) exchangeRace(m *Msg, addrs []string, at attempt) (r *Msg, rtt time.Duration, err error) {
+	if len(addrs) == 0 {
+		return nil, 0, errNoAddrs
+	}
+	if len(addrs) == 1 {
+		return c.exchange(m, addrs[0], at)
+	}
+	delay := c.FallbackDelay
+	if delay <= 0 {
+		delay = defaultFallbackDelay
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	at.ctx = ctx
+
+	results := make(chan raceResult, len(addrs))
+	start := func(a string, q *Msg) {
+		go func() {
+			r, rtt, err := c.exchange(q, a, at)
+			results <- raceResult{r, rtt, err}
+		}()
+	}
+	// Each query gets its own copy of m, as packing sets the extended rcode of
+	// its OPT record.
+	start(addrs[0], m.Copy())
+	next, pending := 1, 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for pending > 0 {
+		select {
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				return res.r, res.rtt, nil
+			}
+			err = res.err
+			if next < len(addrs) {
+				start(addrs[next], m.Copy())
+				next++
+				pending++
+				// The timer may have fired meanwhile, drain it so the
+				// next address isn't started at once.
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(delay)
+			}
+		case <-timer.C:
+			if next < len(addrs) {
+				start(addrs[next], m.Copy())
+				next++
+				pending++
+				timer.Reset(delay)
+			}
+		}
+	}
+	return nil, 0, err
+}
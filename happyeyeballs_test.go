@@ -0,0 +1,113 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInterleaveAddrs(t *testing.T) {
+	addrs := []string{"192.0.2.1:53", "192.0.2.2:53", "[2001:db8::1]:53", "[2001:db8::2]:53", "[2001:db8::3]:53"}
+	want := []string{"[2001:db8::1]:53", "192.0.2.1:53", "[2001:db8::2]:53", "192.0.2.2:53", "[2001:db8::3]:53"}
+	if got := interleaveAddrs(addrs); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestExchangeAddrs(t *testing.T) {
+	HandleFunc("miek.nl.", HelloServer)
+	defer HandleRemove("miek.nl.")
+
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	// A server that never answers, as an upstream behind broken IPv6 is.
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer silent.Close()
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+
+	c := &Client{FallbackDelay: 20 * time.Millisecond, Timeout: 2 * time.Second}
+	start := time.Now()
+	r, _, err := c.ExchangeAddrs(m, []string{silent.LocalAddr().String(), addrstr})
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.Rcode != RcodeSuccess || len(r.Extra) == 0 {
+		t.Errorf("unexpected response %v", r)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expected the second address to answer before the first timed out, took %v", d)
+	}
+
+	if _, _, err := c.ExchangeAddrs(m, nil); err == nil {
+		t.Error("expected an error without addresses")
+	}
+}
+
+func TestClientHappyEyeballs(t *testing.T) {
+	HandleFunc("miek.nl.", HelloServer)
+	defer HandleRemove("miek.nl.")
+
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+	_, port, _ := net.SplitHostPort(addrstr)
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+
+	// localhost may resolve to ::1 too, where nothing listens.
+	c := &Client{HappyEyeballs: true, FallbackDelay: 20 * time.Millisecond}
+	r, _, err := c.Exchange(m, net.JoinHostPort("localhost", port))
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.Rcode != RcodeSuccess {
+		t.Errorf("unexpected response %v", r)
+	}
+}
+
+func TestExchangeContextTLS(t *testing.T) {
+	// A server that never completes the TLS handshake, nor answers over TCP.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	for _, c := range []*Client{{Net: "tcp-tls"}, {Net: "tcp", Pool: new(ConnPool)}} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		start := time.Now()
+		_, _, err := c.exchange(m, l.Addr().String(), attempt{ctx: ctx})
+		cancel()
+		if err == nil {
+			t.Errorf("%s: expected an error", c.Net)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("%s: expected the query to give up with its context, took %v", c.Net, d)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package dns
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Hosts is an Exchanger that answers the A, AAAA and PTR queries for the
+// names and addresses of a hosts file, in the format of /etc/hosts, and sends
+// the other queries to Exchanger. A name of the file has no records of a type
+// when it has no address of that family, the response is then NODATA.
+//
+// When read from a file with NewHosts, the file is checked for changes at
+// most every ReloadInterval, when a query comes, and read again when its size
+// or modification time changed. A file that can't be read leaves the entries
+// as they were.
+//
+// A Hosts is safe for concurrent use.
+type Hosts struct {
+	Exchanger      Exchanger     // where the other queries are sent, if nil they get NXDOMAIN
+	TTL            uint32        // TTL of the records, defaults to 3600
+	ReloadInterval time.Duration // minimum time between checks of the file, defaults to 5 seconds
+
+	path string // file, empty when read from a reader
+
+	mu      sync.RWMutex
+	byName  map[string][]net.IP // by lower cased name
+	byAddr  map[string][]string // by reverse name, lower cased
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+// NewHosts returns a Hosts with the entries of the file at path, which it
+// reloads when it changes.
+func NewHosts(path string) (*Hosts, error) {
+	h := &Hosts{path: path}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.loadFile(fi); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ParseHosts returns a Hosts with the entries read from r.
+func ParseHosts(r io.Reader) (*Hosts, error) {
+	h := new(Hosts)
+	if err := h.Load(r); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Load replaces the entries of h with the ones read from r.
+func (h *Hosts) Load(r io.Reader) error {
+	byName := make(map[string][]net.IP)
+	byAddr := make(map[string][]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		addr := f[0]
+		if i := strings.IndexByte(addr, '%'); i >= 0 {
+			// Zones of link local addresses can't be in records.
+			addr = addr[:i]
+		}
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		arpa, _ := ReverseAddr(ip.String())
+		for _, name := range f[1:] {
+			if _, ok := IsDomainName(name); !ok {
+				continue
+			}
+			name = strings.ToLower(Fqdn(name))
+			byName[name] = appendIP(byName[name], ip)
+			byAddr[arpa] = appendName(byAddr[arpa], name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.byName, h.byAddr = byName, byAddr
+	h.mu.Unlock()
+	return nil
+}
+
+// LookupName returns the addresses of name.
+func (h *Hosts) LookupName(name string) []net.IP {
+	h.reload()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]net.IP(nil), h.byName[strings.ToLower(Fqdn(name))]...)
+}
+
+// LookupAddr returns the names of the address addr, the canonical one first.
+func (h *Hosts) LookupAddr(addr string) []string {
+	arpa, err := ReverseAddr(addr)
+	if err != nil {
+		return nil
+	}
+	h.reload()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]string(nil), h.byAddr[arpa]...)
+}
+
+// Records returns the records of type qtype, A, AAAA or PTR, of name, and
+// whether name is in the hosts file at all.
+func (h *Hosts) Records(name string, qtype uint16) ([]RR, bool) {
+	h.reload()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ttl := h.TTL
+	if ttl == 0 {
+		ttl = 3600
+	}
+	key := strings.ToLower(Fqdn(name))
+	hdr := RR_Header{Name: Fqdn(name), Rrtype: qtype, Class: ClassINET, Ttl: ttl}
+	if names, ok := h.byAddr[key]; ok {
+		if qtype != TypePTR {
+			return nil, true
+		}
+		rrs := make([]RR, len(names))
+		for i, n := range names {
+			rrs[i] = &PTR{Hdr: hdr, Ptr: n}
+		}
+		return rrs, true
+	}
+	ips, ok := h.byName[key]
+	if !ok {
+		return nil, false
+	}
+	var rrs []RR
+	for _, ip := range ips {
+		switch {
+		case qtype == TypeA && len(ip) == net.IPv4len:
+			rrs = append(rrs, &A{Hdr: hdr, A: ip})
+		case qtype == TypeAAAA && len(ip) == net.IPv6len:
+			rrs = append(rrs, &AAAA{Hdr: hdr, AAAA: ip})
+		}
+	}
+	return rrs, true
+}
+
+// Exchange implements the Exchanger interface. The queries answered from the
+// hosts file get authoritative responses, and a zero rtt.
+func (h *Hosts) Exchange(m *Msg, address string) (r *Msg, rtt time.Duration, err error) {
+	if len(m.Question) == 1 && m.Question[0].Qclass == ClassINET {
+		q := m.Question[0]
+		switch q.Qtype {
+		case TypeA, TypeAAAA, TypePTR:
+			if rrs, ok := h.Records(q.Name, q.Qtype); ok {
+				r = new(Msg)
+				r.SetReply(m)
+				r.Authoritative = true
+				r.RecursionAvailable = true
+				r.Answer = rrs
+				return r, 0, nil
+			}
+		}
+	}
+	if h.Exchanger != nil {
+		return h.Exchanger.Exchange(m, address)
+	}
+	r = new(Msg)
+	r.SetRcode(m, RcodeNameError)
+	return r, 0, nil
+}
+
+// reload reads the file of h again if it changed since it was last checked,
+// and that was at least ReloadInterval ago.
+func (h *Hosts) reload() {
+	if h.path == "" {
+		return
+	}
+	interval := h.ReloadInterval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	now := time.Now()
+	h.mu.Lock()
+	if now.Sub(h.checked) < interval {
+		h.mu.Unlock()
+		return
+	}
+	h.checked = now
+	modTime, size := h.modTime, h.size
+	h.mu.Unlock()
+
+	fi, err := os.Stat(h.path)
+	if err != nil || (fi.ModTime().Equal(modTime) && fi.Size() == size) {
+		return
+	}
+	h.loadFile(fi)
+}
+
+// loadFile reads the file of h, whose current information is fi.
+func (h *Hosts) loadFile(fi os.FileInfo) error {
+	f, err := os.Open(h.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := h.Load(f); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.modTime, h.size, h.checked = fi.ModTime(), fi.Size(), time.Now()
+	h.mu.Unlock()
+	return nil
+}
+
+func appendIP(ips []net.IP, ip net.IP) []net.IP {
+	for _, ip1 := range ips {
+		if ip1.Equal(ip) {
+			return ips
+		}
+	}
+	return append(ips, ip)
+}
+
+func appendName(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
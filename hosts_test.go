@@ -0,0 +1,120 @@
+package dns
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const hostsData = `
+# comment
+127.0.0.1	localhost
+::1		localhost ip6-localhost
+192.0.2.1	www.example.org www # web server
+192.0.2.2	www.example.org
+2001:db8::1	www.example.org
+fe80::1%lo0	link.local
+bad		bad.example.org
+`
+
+func TestHostsLookup(t *testing.T) {
+	h, err := ParseHosts(strings.NewReader(hostsData))
+	if err != nil {
+		t.Fatalf("failed to parse hosts: %v", err)
+	}
+	if ips := h.LookupName("WWW.example.org"); len(ips) != 3 {
+		t.Errorf("expected 3 addresses, got %v", ips)
+	}
+	if ips := h.LookupName("link.local."); len(ips) != 1 {
+		t.Errorf("expected 1 address, got %v", ips)
+	}
+	if ips := h.LookupName("bad.example.org"); len(ips) != 0 {
+		t.Errorf("expected no address, got %v", ips)
+	}
+	if names := h.LookupAddr("192.0.2.1"); strings.Join(names, " ") != "www.example.org. www." {
+		t.Errorf("expected www.example.org. and www., got %v", names)
+	}
+	if names := h.LookupAddr("::1"); strings.Join(names, " ") != "localhost. ip6-localhost." {
+		t.Errorf("expected localhost. and ip6-localhost., got %v", names)
+	}
+}
+
+func TestHostsExchange(t *testing.T) {
+	h, _ := ParseHosts(strings.NewReader(hostsData))
+	var forwarded int
+	h.Exchanger = exchangerFunc(func(m *Msg, address string) (*Msg, time.Duration, error) {
+		forwarded++
+		r := new(Msg)
+		r.SetReply(m)
+		return r, 0, nil
+	})
+
+	for _, tc := range []struct {
+		name      string
+		qtype     uint16
+		answers   int
+		forwarded bool
+	}{
+		{"www.example.org.", TypeA, 2, false},
+		{"www.example.org.", TypeAAAA, 1, false},
+		{"localhost.", TypeA, 1, false},
+		{"www.", TypeAAAA, 0, false},
+		{"www.example.org.", TypeMX, 0, true},
+		{"1.2.0.192.in-addr.arpa.", TypePTR, 2, false},
+		{"1.2.0.192.in-addr.arpa.", TypeA, 0, false},
+		{"miek.nl.", TypeA, 0, true},
+	} {
+		forwarded = 0
+		r, _, err := h.Exchange(new(Msg).SetQuestion(tc.name, tc.qtype), "192.0.2.53:53")
+		if err != nil {
+			t.Fatalf("failed to exchange: %v", err)
+		}
+		if len(r.Answer) != tc.answers || (forwarded == 1) != tc.forwarded || r.Authoritative == tc.forwarded {
+			t.Errorf("%s %s: expected %d answers, forwarded %t, got %v", tc.name, TypeToString[tc.qtype], tc.answers, tc.forwarded, r)
+		}
+	}
+
+	h.Exchanger = nil
+	r, _, _ := h.Exchange(new(Msg).SetQuestion("miek.nl.", TypeA), "")
+	if r.Rcode != RcodeNameError {
+		t.Errorf("expected NXDOMAIN without Exchanger, got %v", r)
+	}
+}
+
+func TestHostsReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hosts")
+	if err := ioutil.WriteFile(path, []byte("192.0.2.1 www.example.org\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHosts(path)
+	if err != nil {
+		t.Fatalf("failed to read hosts: %v", err)
+	}
+	h.ReloadInterval = time.Nanosecond
+	if ips := h.LookupName("www.example.org"); len(ips) != 1 || ips[0].String() != "192.0.2.1" {
+		t.Fatalf("expected 192.0.2.1, got %v", ips)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("192.0.2.2 www.example.org\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if ips := h.LookupName("www.example.org"); len(ips) != 1 || ips[0].String() != "192.0.2.2" {
+		t.Errorf("expected 192.0.2.2 after reload, got %v", ips)
+	}
+
+	os.Remove(path)
+	if ips := h.LookupName("www.example.org"); len(ips) != 1 {
+		t.Errorf("expected entries to be kept when the file is gone, got %v", ips)
+	}
+}
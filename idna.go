@@ -0,0 +1,314 @@
+package dns
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// IDNAToASCII converts the Unicode labels of name to A-labels, "xn--" followed
+// by their punycode (RFC 3492), as IDNA2008 (RFC 5891) describes for lookups:
+// the labels are lower cased and validated. Labels that are already ASCII,
+// like "_tcp" or an A-label, are kept as they are.
+//
+// Validation is limited to what can be checked without the Unicode tables of
+// IDNA2008: labels may only hold letters, digits, combining marks and hyphens,
+// can't start with a combining mark, and can't have hyphens in the third and
+// fourth position. Normalization to NFC is left to the caller.
+func IDNAToASCII(name string) (string, error) {
+	return idnaLabels(name, toALabel, isUnicodeLabel)
+}
+
+// IDNAToUnicode converts the A-labels of name to Unicode, with the same
+// validation as IDNAToASCII. Other labels are kept as they are.
+func IDNAToUnicode(name string) (string, error) {
+	return idnaLabels(name, toULabel, isALabel)
+}
+
+// idnaLabels converts the labels of name for which convert returns true.
+func idnaLabels(name string, convert func(string) (string, error), match func(string) bool) (string, error) {
+	labels := SplitDomainName(name)
+	changed := false
+	for i, l := range labels {
+		if strings.IndexByte(l, '\\') >= 0 {
+			l = unescapeLabel(l)
+		}
+		if !match(l) {
+			continue
+		}
+		s, err := convert(l)
+		if err != nil {
+			return "", &Error{err: "bad IDNA label " + labels[i] + ": " + err.(*Error).err}
+		}
+		labels[i], changed = s, true
+	}
+	if !changed {
+		return name, nil
+	}
+	s := strings.Join(labels, ".")
+	if IsFqdn(name) {
+		s += "."
+	}
+	return s, nil
+}
+
+func isUnicodeLabel(l string) bool {
+	for i := 0; i < len(l); i++ {
+		if l[i] >= 0x80 {
+			return true
+		}
+	}
+	return false
+}
+
+func isALabel(l string) bool {
+	return len(l) > 4 && strings.EqualFold(l[:4], "xn--")
+}
+
+var (
+	errPunycode  = &Error{err: "bad punycode"}
+	errIDNALabel = &Error{err: "label not allowed in IDNA2008"}
+)
+
+func toALabel(l string) (string, error) {
+	u := []rune(strings.ToLower(l))
+	if err := validULabel(u); err != nil {
+		return "", err
+	}
+	a := "xn--" + punyEncode(u)
+	if len(a) > 63 {
+		return "", errIDNALabel
+	}
+	return a, nil
+}
+
+func toULabel(l string) (string, error) {
+	p := strings.ToLower(l[4:])
+	u, err := punyDecode(p)
+	if err != nil {
+		return "", err
+	}
+	if err := validULabel(u); err != nil {
+		return "", err
+	}
+	if punyEncode(u) != p {
+		return "", errPunycode
+	}
+	return string(u), nil
+}
+
+// validULabel checks the Unicode label u, see IDNAToASCII.
+func validULabel(u []rune) error {
+	switch {
+	case len(u) == 0, u[0] == '-', u[len(u)-1] == '-', unicode.IsMark(u[0]):
+		return errIDNALabel
+	case len(u) >= 4 && u[2] == '-' && u[3] == '-':
+		return errIDNALabel
+	}
+	for _, r := range u {
+		if r == utf8.RuneError || unicode.IsUpper(r) ||
+			!(r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)) {
+			return errIDNALabel
+		}
+	}
+	return nil
+}
+
+// Parameters of punycode (RFC 3492, Section 5).
+const (
+	punyBase        = 36
+	punyTmin        = 1
+	punyTmax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+)
+
+func punyAdapt(delta, points int, first bool) int {
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+	k := 0
+	for delta > (punyBase-punyTmin)*punyTmax/2 {
+		delta /= punyBase - punyTmin
+		k += punyBase
+	}
+	return k + (punyBase-punyTmin+1)*delta/(delta+punySkew)
+}
+
+func punyThreshold(k, bias int) int {
+	switch t := k - bias; {
+	case t < punyTmin:
+		return punyTmin
+	case t > punyTmax:
+		return punyTmax
+	default:
+		return t
+	}
+}
+
+func punyDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+// punyEncode returns the punycode of u (RFC 3492, Section 6.3). The labels it
+// is used for are too short to overflow.
+func punyEncode(u []rune) string {
+	var out []byte
+	for _, r := range u {
+		if r < 0x80 {
+			out = append(out, byte(r))
+		}
+	}
+	b := len(out)
+	h := b
+	if b > 0 {
+		out = append(out, '-')
+	}
+	n, delta, bias := punyInitialN, 0, punyInitialBias
+	for h < len(u) {
+		m := int(unicode.MaxRune) + 1
+		for _, r := range u {
+			if int(r) >= n && int(r) < m {
+				m = int(r)
+			}
+		}
+		delta += (m - n) * (h + 1)
+		n = m
+		for _, r := range u {
+			if int(r) < n {
+				delta++
+			}
+			if int(r) != n {
+				continue
+			}
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := punyThreshold(k, bias)
+				if q < t {
+					break
+				}
+				out = append(out, punyDigit(t+(q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			out = append(out, punyDigit(q))
+			bias = punyAdapt(delta, h+1, h == b)
+			delta = 0
+			h++
+		}
+		delta++
+		n++
+	}
+	return string(out)
+}
+
+// punyDecode returns the code points of the punycode p (RFC 3492, Section 6.2).
+func punyDecode(p string) ([]rune, error) {
+	var u []rune
+	if i := strings.LastIndexByte(p, '-'); i > 0 {
+		for j := 0; j < i; j++ {
+			if p[j] >= 0x80 {
+				return nil, errPunycode
+			}
+			u = append(u, rune(p[j]))
+		}
+		p = p[i+1:]
+	}
+	n, i, bias := punyInitialN, 0, punyInitialBias
+	for in := 0; in < len(p); {
+		oldi, w := i, 1
+		for k := punyBase; ; k += punyBase {
+			if in >= len(p) {
+				return nil, errPunycode
+			}
+			c := p[in]
+			in++
+			var d int
+			switch {
+			case c >= 'a' && c <= 'z':
+				d = int(c - 'a')
+			case c >= '0' && c <= '9':
+				d = int(c-'0') + 26
+			default:
+				return nil, errPunycode
+			}
+			i += d * w
+			if i > 1<<24 {
+				return nil, errPunycode
+			}
+			t := punyThreshold(k, bias)
+			if d < t {
+				break
+			}
+			w *= punyBase - t
+		}
+		bias = punyAdapt(i-oldi, len(u)+1, oldi == 0)
+		n += i / (len(u) + 1)
+		i %= len(u) + 1
+		if n > unicode.MaxRune {
+			return nil, errPunycode
+		}
+		u = append(u, 0)
+		copy(u[i+1:], u[i:])
+		u[i] = rune(n)
+		i++
+	}
+	return u, nil
+}
+
+// SetQuestionIDNA is like SetQuestion, but z may be a Unicode name, which is
+// converted to A-labels with IDNAToASCII.
+func (dns *Msg) SetQuestionIDNA(z string, t uint16) (*Msg, error) {
+	z, err := IDNAToASCII(z)
+	if err != nil {
+		return dns, err
+	}
+	return dns.SetQuestion(z, t), nil
+}
+
+// idnaRR converts the Unicode names of rr, its owner name and the domain
+// names in its rdata, to A-labels.
+func idnaRR(rr RR) error {
+	return idnaStruct(reflect.ValueOf(rr).Elem())
+}
+
+func idnaStruct(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct {
+			if err := idnaStruct(f); err != nil {
+				return err
+			}
+			continue
+		}
+		if !strings.HasSuffix(t.Field(i).Tag.Get("dns"), "domain-name") {
+			continue
+		}
+		switch f.Kind() {
+		case reflect.String:
+			s, err := IDNAToASCII(f.String())
+			if err != nil {
+				return err
+			}
+			f.SetString(s)
+		case reflect.Slice:
+			for j := 0; j < f.Len(); j++ {
+				s, err := IDNAToASCII(f.Index(j).String())
+				if err != nil {
+					return err
+				}
+				f.Index(j).SetString(s)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIDNA(t *testing.T) {
+	tests := []struct {
+		unicode, ascii string
+	}{
+		{"bücher.example.", "xn--bcher-kva.example."},
+		{"Bücher.example", "xn--bcher-kva.example"},
+		{"_sip._tcp.例え.テスト.", "_sip._tcp.xn--r8jz45g.xn--zckzah."},
+		{"www.example.org.", "www.example.org."},
+	}
+	for _, tc := range tests {
+		ascii, err := IDNAToASCII(tc.unicode)
+		if err != nil || ascii != tc.ascii {
+			t.Errorf("IDNAToASCII(%q) = %q, %v, expected %q", tc.unicode, ascii, err, tc.ascii)
+		}
+	}
+
+	if s, err := IDNAToUnicode("xn--bcher-kva.example."); err != nil || s != "bücher.example." {
+		t.Errorf("expected bücher.example., got %q: %v", s, err)
+	}
+	if _, err := IDNAToASCII("a‍b.example."); err == nil {
+		t.Error("expected an error for a label with a bare ZWJ")
+	}
+
+	m, err := new(Msg).SetQuestionIDNA("bücher.example.", TypeA)
+	if err != nil || m.Question[0].Name != "xn--bcher-kva.example." {
+		t.Errorf("unexpected question %v: %v", m.Question, err)
+	}
+}
+
+func TestPunycode(t *testing.T) {
+	// Samples of RFC 3492, Section 7.1.
+	tests := []struct {
+		unicode, puny string
+	}{
+		{"ليهمابتكلموشعربي؟", "egbpdaj6bu4bxfgehfvwxn"},
+		{"PorquénopuedensimplementehablarenEspañol", "PorqunopuedensimplementehablarenEspaol-fmd56a"},
+		{"3年B組金八先生", "3B-ww4c5e180e575a65lsy2b"},
+	}
+	for _, tc := range tests {
+		if p := punyEncode([]rune(tc.unicode)); p != tc.puny {
+			t.Errorf("punyEncode(%q) = %q, expected %q", tc.unicode, p, tc.puny)
+		}
+		if u, err := punyDecode(tc.puny); err != nil || string(u) != tc.unicode {
+			t.Errorf("punyDecode(%q) = %q, %v, expected %q", tc.puny, string(u), err, tc.unicode)
+		}
+	}
+}
+
+func TestZoneParserIDNA(t *testing.T) {
+	const zone = `$ORIGIN bücher.example.
+@	3600	IN	MX	10 mail.bücher.example.
+www	3600	IN	CNAME	bücher.example.
+`
+	zp := NewZoneParser(strings.NewReader(zone), "", "")
+	zp.SetIDNA(true)
+	var rrs []string
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr.String())
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"xn--bcher-kva.example.\t3600\tIN\tMX\t10 mail.xn--bcher-kva.example.",
+		"www.xn--bcher-kva.example.\t3600\tIN\tCNAME\txn--bcher-kva.example.",
+	}
+	if strings.Join(rrs, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expect, "\n"), strings.Join(rrs, "\n"))
+	}
+}
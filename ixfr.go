@@ -0,0 +1,470 @@
+package dns
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var errZoneDiff = &Error{err: "diff doesn't apply to the serial of the zone"}
+
+// A ZoneDiff is the change of a zone from one serial to the next, as sent in
+// an incremental zone transfer (RFC 1995).
+type ZoneDiff struct {
+	OldSOA  *SOA // SOA record before the change
+	NewSOA  *SOA // SOA record after the change
+	Deleted []RR // records deleted, without the old SOA record
+	Added   []RR // records added, without the new SOA record
+}
+
+// A ZoneJournal keeps the changes of zones, so IXFR queries can be answered
+// with them.
+type ZoneJournal interface {
+	// SOA returns the current SOA record of zone, or nil when zone isn't in
+	// the journal.
+	SOA(zone string) *SOA
+	// Diffs returns the diffs that take zone from serial to its current
+	// serial, oldest first. It returns false when serial isn't in the
+	// journal.
+	Diffs(zone string, serial uint32) ([]ZoneDiff, bool)
+}
+
+// MemoryJournal is a ZoneJournal that keeps the latest diffs of zones in
+// memory. It is safe for concurrent use.
+type MemoryJournal struct {
+	MaxDiffs int // diffs kept per zone, defaults to 100
+
+	mu    sync.Mutex
+	zones map[string]*journalZone
+}
+
+type journalZone struct {
+	soa   *SOA
+	diffs []ZoneDiff
+}
+
+// Add adds diff to the journal of zone. Its old serial must be the current
+// serial of zone, unless zone isn't in the journal yet.
+func (j *MemoryJournal) Add(zone string, diff ZoneDiff) error {
+	if diff.OldSOA == nil || diff.NewSOA == nil {
+		return ErrSoa
+	}
+	zone = strings.ToLower(Fqdn(zone))
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.zones == nil {
+		j.zones = make(map[string]*journalZone)
+	}
+	z, ok := j.zones[zone]
+	if !ok {
+		z = new(journalZone)
+		j.zones[zone] = z
+	} else if z.soa.Serial != diff.OldSOA.Serial {
+		return errZoneDiff
+	}
+	z.soa = diff.NewSOA
+	z.diffs = append(z.diffs, diff)
+
+	max := j.MaxDiffs
+	if max <= 0 {
+		max = 100
+	}
+	if len(z.diffs) > max {
+		z.diffs = append(z.diffs[:0:0], z.diffs[len(z.diffs)-max:]...)
+	}
+	return nil
+}
+
+// SOA implements the ZoneJournal interface.
+func (j *MemoryJournal) SOA(zone string) *SOA {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if z, ok := j.zones[strings.ToLower(zone)]; ok {
+		return z.soa
+	}
+	return nil
+}
+
+// Diffs implements the ZoneJournal interface.
+func (j *MemoryJournal) Diffs(zone string, serial uint32) ([]ZoneDiff, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	z, ok := j.zones[strings.ToLower(zone)]
+	if !ok {
+		return nil, false
+	}
+	if serial == z.soa.Serial {
+		return nil, true
+	}
+	for i, d := range z.diffs {
+		if d.OldSOA.Serial == serial {
+			return append([]ZoneDiff(nil), z.diffs[i:]...), true
+		}
+	}
+	return nil, false
+}
+
+// IXFRHandler is a Handler that answers IXFR queries with the diffs in its
+// Journal (RFC 1995). AXFR queries, and IXFR queries for a serial that isn't
+// in the Journal anymore, are answered with a full transfer of the records
+// Zone returns. Over UDP, IXFR queries are answered with the current SOA
+// record only, which tells the client to retry over TCP.
+//
+// Queries for zones not in the Journal are refused, access control is left
+// to the caller, e.g. with a Middleware. IXFR queries carry an SOA record in
+// their authority section, which the DefaultMsgAcceptFunc rejects, servers
+// with an IXFRHandler should set their MsgAcceptFunc to IXFRMsgAcceptFunc.
+type IXFRHandler struct {
+	Journal ZoneJournal
+	Zone    func(zone string) (RRIterator, error) // the records of zone, starting with its SOA record
+}
+
+// IXFRMsgAcceptFunc is like DefaultMsgAcceptFunc, but accepts queries with one
+// RR in the Authority section, as IXFR queries have (RFC 1995, Section 3).
+var IXFRMsgAcceptFunc MsgAcceptFunc = func(dh Header) MsgAcceptAction {
+	if opcode := int(dh.Bits>>11) & 0xF; opcode == OpcodeQuery && dh.Nscount == 1 {
+		dh.Nscount = 0
+	}
+	return defaultMsgAcceptFunc(dh)
+}
+
+// ServeDNS implements the Handler interface.
+func (h *IXFRHandler) ServeDNS(w ResponseWriter, r *Msg) {
+	if len(r.Question) != 1 || (r.Question[0].Qtype != TypeIXFR && r.Question[0].Qtype != TypeAXFR) {
+		m := new(Msg)
+		m.SetRcode(r, RcodeNotImplemented)
+		w.WriteMsg(m)
+		return
+	}
+	zone := r.Question[0].Name
+	soa := h.Journal.SOA(zone)
+	if soa == nil {
+		m := new(Msg)
+		m.SetRcode(r, RcodeRefused)
+		w.WriteMsg(m)
+		return
+	}
+
+	_, udp := w.RemoteAddr().(*net.UDPAddr)
+	if r.Question[0].Qtype == TypeAXFR {
+		if udp {
+			m := new(Msg)
+			m.SetRcode(r, RcodeFormatError) // AXFR is TCP only (RFC 5936, Section 4.2)
+			w.WriteMsg(m)
+			return
+		}
+		h.axfr(w, r, zone)
+		return
+	}
+
+	var serial uint32
+	var ok bool
+	for _, rr := range r.Ns {
+		if s, isSOA := rr.(*SOA); isSOA {
+			serial, ok = s.Serial, true
+			break
+		}
+	}
+	if !ok {
+		m := new(Msg)
+		m.SetRcode(r, RcodeFormatError)
+		w.WriteMsg(m)
+		return
+	}
+
+	diffs, ok := h.Journal.Diffs(zone, serial)
+	if udp || (ok && len(diffs) == 0) || !serialLess(serial, soa.Serial) {
+		// Up to date, or over UDP.
+		m := new(Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+		m.Answer = []RR{soa}
+		w.WriteMsg(m)
+		return
+	}
+	if !ok {
+		h.axfr(w, r, zone)
+		return
+	}
+
+	x := NewResponseStream(w, r)
+	x.Authoritative = true
+	rrs := []RR{soa}
+	for _, d := range diffs {
+		rrs = append(rrs, d.OldSOA)
+		rrs = append(rrs, d.Deleted...)
+		rrs = append(rrs, d.NewSOA)
+		rrs = append(rrs, d.Added...)
+	}
+	rrs = append(rrs, soa)
+	for _, rr := range rrs {
+		if x.Add(rr) != nil {
+			return
+		}
+	}
+	x.Flush()
+}
+
+// axfr answers r with a full transfer of zone.
+func (h *IXFRHandler) axfr(w ResponseWriter, r *Msg, zone string) {
+	var next RRIterator
+	var err error
+	if h.Zone != nil {
+		next, err = h.Zone(zone)
+	}
+	if next == nil || err != nil {
+		m := new(Msg)
+		m.SetRcode(r, RcodeServerFailure)
+		w.WriteMsg(m)
+		return
+	}
+	TransferOut(w, r, next)
+}
+
+// An IxfrResult is the outcome of an incremental zone transfer.
+type IxfrResult struct {
+	SOA      *SOA       // current SOA record of the zone
+	UpToDate bool       // if true, the zone didn't change since the requested serial
+	Zone     []RR       // the full zone, when the server sent it instead of diffs
+	Diffs    []ZoneDiff // the diffs from the requested serial, oldest first
+}
+
+// Ixfr performs an incremental zone transfer (RFC 1995) with the server in a.
+// The query q is made with SetIxfr, with the serial of the zone the client has.
+// Servers send the diffs from that serial, the current SOA record alone when
+// the zone is up to date, or the full zone as in an AXFR, and the result holds
+// whichever the server sent.
+func (t *Transfer) Ixfr(q *Msg, a string) (*IxfrResult, error) {
+	if len(q.Question) != 1 || q.Question[0].Qtype != TypeIXFR || len(q.Ns) != 1 {
+		return nil, ErrSoa
+	}
+	qsoa, ok := q.Ns[0].(*SOA)
+	if !ok {
+		return nil, ErrSoa
+	}
+	env, err := t.In(q, a)
+	if err != nil {
+		return nil, err
+	}
+	var rrs []RR
+	for e := range env {
+		if e.Error != nil {
+			err = e.Error
+			continue
+		}
+		rrs = append(rrs, e.RR...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseIxfr(qsoa.Serial, rrs)
+}
+
+// parseIxfr parses the records of an IXFR response to a query for serial.
+func parseIxfr(serial uint32, rrs []RR) (*IxfrResult, error) {
+	if len(rrs) == 0 {
+		return nil, ErrSoa
+	}
+	soa, ok := rrs[0].(*SOA)
+	if !ok {
+		return nil, ErrSoa
+	}
+	res := &IxfrResult{SOA: soa}
+	if len(rrs) == 1 {
+		if serialLess(serial, soa.Serial) {
+			return nil, ErrSoa // incomplete
+		}
+		res.UpToDate = true
+		return res, nil
+	}
+	if last, ok := rrs[len(rrs)-1].(*SOA); !ok || last.Serial != soa.Serial {
+		return nil, ErrSoa
+	}
+	rrs = rrs[1 : len(rrs)-1]
+	if len(rrs) == 0 || rrs[0].Header().Rrtype != TypeSOA {
+		// A full zone, starting with its SOA record.
+		res.Zone = append([]RR{soa}, rrs...)
+		return res, nil
+	}
+
+	var d *ZoneDiff
+	for _, rr := range rrs {
+		s, isSOA := rr.(*SOA)
+		switch {
+		case isSOA && (d == nil || d.NewSOA != nil):
+			if d != nil {
+				res.Diffs = append(res.Diffs, *d)
+			}
+			d = &ZoneDiff{OldSOA: s}
+		case isSOA:
+			d.NewSOA = s
+		case d.NewSOA == nil:
+			d.Deleted = append(d.Deleted, rr)
+		default:
+			d.Added = append(d.Added, rr)
+		}
+	}
+	if d.NewSOA == nil || d.NewSOA.Serial != soa.Serial {
+		return nil, ErrSoa
+	}
+	res.Diffs = append(res.Diffs, *d)
+	return res, nil
+}
+
+// Apply updates zone, the records of the zone at the requested serial, with r.
+// It returns zone itself when it is up to date, the transferred zone when the
+// server sent all of it, or zone with the diffs applied.
+func (r *IxfrResult) Apply(zone []RR) ([]RR, error) {
+	switch {
+	case r.UpToDate:
+		return zone, nil
+	case r.Zone != nil:
+		return r.Zone, nil
+	}
+	for _, d := range r.Diffs {
+		var err error
+		if zone, err = applyZoneDiff(zone, d); err != nil {
+			return nil, err
+		}
+	}
+	return zone, nil
+}
+
+// applyZoneDiff returns zone with d applied.
+func applyZoneDiff(zone []RR, d ZoneDiff) ([]RR, error) {
+	deleted := make(map[string]bool, len(d.Deleted))
+	for _, rr := range d.Deleted {
+		deleted[rrKey(rr)] = true
+	}
+	found := false
+	applied := make([]RR, 0, len(zone)+len(d.Added))
+	for _, rr := range zone {
+		if soa, ok := rr.(*SOA); ok {
+			if soa.Serial != d.OldSOA.Serial {
+				return nil, errZoneDiff
+			}
+			found = true
+			applied = append(applied, d.NewSOA)
+			continue
+		}
+		if !deleted[rrKey(rr)] {
+			applied = append(applied, rr)
+		}
+	}
+	if !found {
+		return nil, ErrSoa
+	}
+	return append(applied, d.Added...), nil
+}
+
+// DiffZones returns the changes that take zone old to zone new, with the SOA
+// records of the zones as OldSOA and NewSOA. The deleted and added records are
+// sorted in the canonical order of RFC 4034, Section 6: by owner name, then by
+// type and by rdata in canonical form, so the diff can be sent in an IXFR response, added to a
+// MemoryJournal, or turned into a dynamic update. If ignoreTTL is true, records
+// that only differ in their TTL are considered equal and are left out.
+func DiffZones(old, new []RR, ignoreTTL bool) ZoneDiff {
+	key := func(rr RR) string {
+		if ignoreTTL {
+			return rrKey(rr)
+		}
+		return rrKey(rr) + " " + strconv.FormatInt(int64(rr.Header().Ttl), 10)
+	}
+	keys := func(zone []RR) map[string]bool {
+		m := make(map[string]bool, len(zone))
+		for _, rr := range zone {
+			m[key(rr)] = true
+		}
+		return m
+	}
+
+	var d ZoneDiff
+	oldKeys, newKeys := keys(old), keys(new)
+	for _, rr := range old {
+		if soa, ok := rr.(*SOA); ok {
+			if d.OldSOA == nil {
+				d.OldSOA = soa
+			}
+			continue
+		}
+		k := key(rr)
+		if !newKeys[k] {
+			d.Deleted = append(d.Deleted, rr)
+			newKeys[k] = true // don't delete duplicates twice
+		}
+	}
+	for _, rr := range new {
+		if soa, ok := rr.(*SOA); ok {
+			if d.NewSOA == nil {
+				d.NewSOA = soa
+			}
+			continue
+		}
+		k := key(rr)
+		if !oldKeys[k] {
+			d.Added = append(d.Added, rr)
+			oldKeys[k] = true
+		}
+	}
+	sortCanonical(d.Deleted)
+	sortCanonical(d.Added)
+	return d
+}
+
+// sortCanonical sorts rrs in canonical order: by owner name (RFC 4034, Section
+// 6.1), then by type and by their rdata in canonical form (Section 6.3). RRs
+// that can't be packed sort after the others of their type, by their text.
+func sortCanonical(rrs []RR) {
+	rdata := make([][]byte, len(rrs))
+	for i, rr := range rrs {
+		rdata[i], _ = canonicalRdata(rr)
+	}
+	sort.Stable(zoneSorter{rrs, rdata})
+}
+
+type zoneSorter struct {
+	rrs   []RR
+	rdata [][]byte // nil if the RR can't be packed
+}
+
+func (p zoneSorter) Len() int { return len(p.rrs) }
+func (p zoneSorter) Less(i, j int) bool {
+	hi, hj := p.rrs[i].Header(), p.rrs[j].Header()
+	if c := canonicalNameCompare(hi.Name, hj.Name); c != 0 {
+		return c < 0
+	}
+	if hi.Rrtype != hj.Rrtype {
+		return hi.Rrtype < hj.Rrtype
+	}
+	switch {
+	case p.rdata[i] != nil && p.rdata[j] != nil:
+		return bytes.Compare(p.rdata[i], p.rdata[j]) < 0
+	case p.rdata[i] != nil || p.rdata[j] != nil:
+		return p.rdata[i] != nil
+	}
+	return rrKey(p.rrs[i]) < rrKey(p.rrs[j])
+}
+func (p zoneSorter) Swap(i, j int) {
+	p.rrs[i], p.rrs[j] = p.rrs[j], p.rrs[i]
+	p.rdata[i], p.rdata[j] = p.rdata[j], p.rdata[i]
+}
+
+// rrKey returns the text of rr without its TTL, with the owner name lower
+// cased, so records that only differ in those compare equal.
+func rrKey(rr RR) string {
+	h := *rr.Header()
+	s := strings.TrimPrefix(rr.String(), h.String())
+	h.Name = strings.ToLower(h.Name)
+	h.Ttl = 0
+	return h.String() + s
+}
+
+// serialLess reports whether serial a is lower than b in serial number
+// arithmetic (RFC 1982).
+func serialLess(a, b uint32) bool {
+	return SerialCompare(a, b) < 0
+}
@@ -0,0 +1,256 @@
+package dns
+
+import (
+	"testing"
+)
+
+func testZoneDiff(t *testing.T, from, to uint32, deleted, added []string) ZoneDiff {
+	soa := func(serial uint32) *SOA {
+		rr, err := NewRR("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 60")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr.(*SOA).Serial = serial
+		return rr.(*SOA)
+	}
+	d := ZoneDiff{OldSOA: soa(from), NewSOA: soa(to)}
+	for _, s := range deleted {
+		d.Deleted = append(d.Deleted, testRR(s))
+	}
+	for _, s := range added {
+		d.Added = append(d.Added, testRR(s))
+	}
+	return d
+}
+
+func TestMemoryJournal(t *testing.T) {
+	j := &MemoryJournal{MaxDiffs: 2}
+	for serial := uint32(1); serial < 4; serial++ {
+		if err := j.Add("Example.", testZoneDiff(t, serial, serial+1, nil, nil)); err != nil {
+			t.Fatalf("failed to add diff %d: %v", serial, err)
+		}
+	}
+	if err := j.Add("example.", testZoneDiff(t, 1, 2, nil, nil)); err == nil {
+		t.Error("expected an error for a diff that doesn't apply")
+	}
+
+	if soa := j.SOA("example."); soa == nil || soa.Serial != 4 {
+		t.Fatalf("expected serial 4, got %v", soa)
+	}
+	if _, ok := j.Diffs("example.", 1); ok {
+		t.Error("expected serial 1 to be dropped from the journal")
+	}
+	if d, ok := j.Diffs("example.", 2); !ok || len(d) != 2 {
+		t.Errorf("expected 2 diffs from serial 2, got %d", len(d))
+	}
+	if d, ok := j.Diffs("example.", 4); !ok || len(d) != 0 {
+		t.Errorf("expected no diffs from the current serial, got %d", len(d))
+	}
+}
+
+func TestIXFRHandler(t *testing.T) {
+	j := &MemoryJournal{MaxDiffs: 2}
+	j.Add("example.", testZoneDiff(t, 1, 2, []string{"a.example. 3600 IN A 192.0.2.1"}, []string{"a.example. 3600 IN A 192.0.2.2"}))
+	j.Add("example.", testZoneDiff(t, 2, 3, nil, []string{"b.example. 3600 IN A 192.0.2.3"}))
+	zone := []RR{
+		j.SOA("example."),
+		testRR("a.example. 3600 IN A 192.0.2.2"),
+		testRR("b.example. 3600 IN A 192.0.2.3"),
+	}
+	h := &IXFRHandler{
+		Journal: j,
+		Zone:    func(string) (RRIterator, error) { return SliceRRIterator(zone), nil },
+	}
+	Handle("example.", h)
+	defer HandleRemove("example.")
+
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.MsgAcceptFunc = IXFRMsgAcceptFunc
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	transfer := func(serial uint32) []RR {
+		m := new(Msg)
+		m.SetIxfr("example.", serial, "ns.example.", "hostmaster.example.")
+		ch, err := new(Transfer).In(m, addrstr)
+		if err != nil {
+			t.Fatalf("failed to start transfer: %v", err)
+		}
+		var rrs []RR
+		for env := range ch {
+			if env.Error != nil {
+				t.Fatalf("failed to transfer: %v", env.Error)
+			}
+			rrs = append(rrs, env.RR...)
+		}
+		return rrs
+	}
+
+	// SOA 3, SOA 1, deleted, SOA 2, added, SOA 2, SOA 3, added, SOA 3.
+	rrs := transfer(1)
+	serials := []uint32{3, 1, 0, 2, 0, 2, 3, 0, 3}
+	if len(rrs) != len(serials) {
+		t.Fatalf("expected %d records, got %d: %v", len(serials), len(rrs), rrs)
+	}
+	for i, serial := range serials {
+		soa, ok := rrs[i].(*SOA)
+		if (serial == 0) == ok || (ok && soa.Serial != serial) {
+			t.Errorf("unexpected record %d: %v", i, rrs[i])
+		}
+	}
+
+	j.Add("example.", testZoneDiff(t, 3, 4, nil, nil))
+	j.Add("example.", testZoneDiff(t, 4, 5, nil, nil))
+	zone[0] = j.SOA("example.")
+	if rrs := transfer(1); len(rrs) != len(zone)+1 {
+		t.Errorf("expected a full transfer of %d records, got %d: %v", len(zone)+1, len(rrs), rrs)
+	}
+	if rrs := transfer(5); len(rrs) != 1 {
+		t.Errorf("expected only the SOA record, got %v", rrs)
+	}
+}
+
+func TestTransferIxfr(t *testing.T) {
+	j := &MemoryJournal{MaxDiffs: 2}
+	j.Add("example.", testZoneDiff(t, 1, 2, []string{"a.example. 3600 IN A 192.0.2.1"}, []string{"a.example. 3600 IN A 192.0.2.2"}))
+	j.Add("example.", testZoneDiff(t, 2, 3, nil, []string{"b.example. 3600 IN A 192.0.2.3"}))
+	zone := []RR{
+		j.SOA("example."),
+		testRR("a.example. 3600 IN A 192.0.2.2"),
+		testRR("b.example. 3600 IN A 192.0.2.3"),
+	}
+	Handle("example.", &IXFRHandler{
+		Journal: j,
+		Zone:    func(string) (RRIterator, error) { return SliceRRIterator(zone), nil },
+	})
+	defer HandleRemove("example.")
+
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.MsgAcceptFunc = IXFRMsgAcceptFunc
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	ixfr := func(serial uint32) *IxfrResult {
+		m := new(Msg)
+		m.SetIxfr("example.", serial, "ns.example.", "hostmaster.example.")
+		res, err := new(Transfer).Ixfr(m, addrstr)
+		if err != nil {
+			t.Fatalf("failed to transfer from serial %d: %v", serial, err)
+		}
+		return res
+	}
+
+	old := []RR{
+		testZoneDiff(t, 0, 1, nil, nil).NewSOA,
+		testRR("a.example. 7200 IN A 192.0.2.1"),
+		testRR("c.example. 3600 IN A 192.0.2.4"),
+	}
+	res := ixfr(1)
+	if len(res.Diffs) != 2 || res.Zone != nil || res.UpToDate {
+		t.Fatalf("expected 2 diffs, got %+v", res)
+	}
+	updated, err := res.Apply(old)
+	if err != nil {
+		t.Fatalf("failed to apply the diffs: %v", err)
+	}
+	expected := []string{
+		"example.\t3600\tIN\tSOA\tns.example. hostmaster.example. 3 3600 600 86400 60",
+		"c.example.\t3600\tIN\tA\t192.0.2.4",
+		"a.example.\t3600\tIN\tA\t192.0.2.2",
+		"b.example.\t3600\tIN\tA\t192.0.2.3",
+	}
+	if len(updated) != len(expected) {
+		t.Fatalf("expected %d records, got %v", len(expected), updated)
+	}
+	for i, rr := range updated {
+		if rr.String() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], rr.String())
+		}
+	}
+	if _, err := res.Apply(updated); err == nil {
+		t.Error("expected an error applying the diffs to the wrong serial")
+	}
+
+	if res := ixfr(3); !res.UpToDate || res.SOA.Serial != 3 {
+		t.Errorf("expected the zone to be up to date, got %+v", res)
+	}
+
+	j.Add("example.", testZoneDiff(t, 3, 4, nil, nil))
+	j.Add("example.", testZoneDiff(t, 4, 5, nil, nil))
+	zone[0] = j.SOA("example.")
+	res = ixfr(1)
+	if len(res.Zone) != len(zone) || res.Diffs != nil {
+		t.Fatalf("expected the full zone, got %+v", res)
+	}
+	if updated, _ := res.Apply(old); len(updated) != len(zone) {
+		t.Errorf("expected the transferred zone, got %v", updated)
+	}
+}
+
+func TestDiffZones(t *testing.T) {
+	old := []RR{
+		testRR("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 60"),
+		testRR("example. 3600 IN NS ns.example."),
+		testRR("www.example. 3600 IN A 192.0.2.10"),
+		testRR("www.example. 3600 IN A 192.0.2.2"),
+		testRR("www.example. 3600 IN A 192.0.2.1"),
+		testRR("www.example. 3600 IN A 192.0.2.9"),
+		testRR("b.example. 3600 IN TXT \"old\""),
+		testRR("ns.example. 3600 IN A 192.0.2.53"),
+	}
+	new := []RR{
+		testRR("example. 3600 IN SOA ns.example. hostmaster.example. 2 3600 600 86400 60"),
+		testRR("example. 3600 IN NS ns.example."),
+		testRR("WWW.example. 3600 IN A 192.0.2.1"),
+		testRR("a.b.example. 3600 IN TXT \"new\""),
+		testRR("b.example. 3600 IN A 192.0.2.3"),
+		testRR("ns.example. 300 IN A 192.0.2.53"),
+	}
+
+	d := DiffZones(old, new, false)
+	if d.OldSOA.Serial != 1 || d.NewSOA.Serial != 2 {
+		t.Errorf("expected the serials 1 and 2, got %d and %d", d.OldSOA.Serial, d.NewSOA.Serial)
+	}
+	expect := func(rrs []RR, want ...string) {
+		t.Helper()
+		if len(rrs) != len(want) {
+			t.Fatalf("expected %d records, got %v", len(want), rrs)
+		}
+		for i := range rrs {
+			if rrs[i].String() != testRR(want[i]).String() {
+				t.Errorf("record %d: expected %s, got %s", i, want[i], rrs[i])
+			}
+		}
+	}
+	expect(d.Deleted,
+		"b.example. 3600 IN TXT \"old\"",
+		"ns.example. 3600 IN A 192.0.2.53",
+		"www.example. 3600 IN A 192.0.2.2",
+		"www.example. 3600 IN A 192.0.2.9",
+		"www.example. 3600 IN A 192.0.2.10")
+	expect(d.Added,
+		"b.example. 3600 IN A 192.0.2.3",
+		"a.b.example. 3600 IN TXT \"new\"",
+		"ns.example. 300 IN A 192.0.2.53")
+
+	d = DiffZones(old, new, true)
+	expect(d.Deleted,
+		"b.example. 3600 IN TXT \"old\"",
+		"www.example. 3600 IN A 192.0.2.2",
+		"www.example. 3600 IN A 192.0.2.9",
+		"www.example. 3600 IN A 192.0.2.10")
+
+	zone, err := applyZoneDiff(old, DiffZones(old, new, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := DiffZones(zone, new, false); len(d.Deleted) != 0 || len(d.Added) != 0 {
+		t.Errorf("expected the applied diff to give the new zone, got %v", d)
+	}
+}
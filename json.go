@@ -0,0 +1,360 @@
+package dns
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// JSON encoding of messages and RRs, see RFC 8427. A Msg is encoded as an
+// object with the header fields and the questionRRs, answerRRs, authorityRRs
+// and additionalRRs arrays. An RR is encoded with its header fields, the rdata
+// in wire format (RDATAHEX) and, for types that can be parsed from text, the
+// rdata in presentation format (e.g. rdataMX):
+//
+//	{"NAME": "example.org.", "TYPE": 15, "TYPEname": "MX", "CLASS": 1,
+//	 "CLASSname": "IN", "TTL": 3600, "RDLENGTH": 20,
+//	 "RDATAHEX": "000A026D78076578616D706C65036F726700",
+//	 "rdataMX": "10 mx.example.org."}
+//
+// When decoding, RDATAHEX takes precedence over the presentation format. Flags
+// are encoded as 0 or 1, both numbers and booleans are accepted when decoding.
+
+type msgJSON struct {
+	ID      uint16      `json:"ID"`
+	QR      jsonFlag    `json:"QR"`
+	Opcode  int         `json:"Opcode"`
+	AA      jsonFlag    `json:"AA"`
+	TC      jsonFlag    `json:"TC"`
+	RD      jsonFlag    `json:"RD"`
+	RA      jsonFlag    `json:"RA"`
+	AD      jsonFlag    `json:"AD"`
+	CD      jsonFlag    `json:"CD"`
+	RCODE   int         `json:"RCODE"`
+	QDCOUNT int         `json:"QDCOUNT"`
+	ANCOUNT int         `json:"ANCOUNT"`
+	NSCOUNT int         `json:"NSCOUNT"`
+	ARCOUNT int         `json:"ARCOUNT"`
+	QNAME   string      `json:"QNAME,omitempty"`
+	QTYPE   *uint16     `json:"QTYPE,omitempty"`
+	QTYPEn  string      `json:"QTYPEname,omitempty"`
+	QCLASS  *uint16     `json:"QCLASS,omitempty"`
+	QCLASSn string      `json:"QCLASSname,omitempty"`
+	Qd      []*rrJSON   `json:"questionRRs,omitempty"`
+	An      []rawRRJSON `json:"answerRRs,omitempty"`
+	Ns      []rawRRJSON `json:"authorityRRs,omitempty"`
+	Ar      []rawRRJSON `json:"additionalRRs,omitempty"`
+}
+
+// rrJSON holds the members of an RR, or of a question, except the rdata in
+// presentation format.
+type rrJSON struct {
+	NAME     string  `json:"NAME"`
+	TYPE     *uint16 `json:"TYPE,omitempty"`
+	TYPEn    string  `json:"TYPEname,omitempty"`
+	CLASS    *uint16 `json:"CLASS,omitempty"`
+	CLASSn   string  `json:"CLASSname,omitempty"`
+	TTL      *uint32 `json:"TTL,omitempty"`
+	RDLENGTH *int    `json:"RDLENGTH,omitempty"`
+	RDATAHEX *string `json:"RDATAHEX,omitempty"`
+}
+
+// rawRRJSON is an RR that is encoded and decoded with marshalRRJSON and
+// unmarshalRRJSON.
+type rawRRJSON struct{ RR }
+
+func (r rawRRJSON) MarshalJSON() ([]byte, error) { return marshalRRJSON(r.RR) }
+
+func (r *rawRRJSON) UnmarshalJSON(b []byte) (err error) {
+	r.RR, err = unmarshalRRJSON(b)
+	return err
+}
+
+// jsonFlag is a header flag, encoded as 0 or 1.
+type jsonFlag bool
+
+func (f jsonFlag) MarshalJSON() ([]byte, error) {
+	if f {
+		return []byte("1"), nil
+	}
+	return []byte("0"), nil
+}
+
+func (f *jsonFlag) UnmarshalJSON(b []byte) error {
+	switch string(b) {
+	case "1", "true":
+		*f = true
+	case "0", "false", "null":
+		*f = false
+	default:
+		return &Error{err: "bad JSON flag: " + string(b)}
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the message is encoded as described
+// in RFC 8427.
+func (dns *Msg) MarshalJSON() ([]byte, error) {
+	m := msgJSON{
+		ID:      dns.Id,
+		QR:      jsonFlag(dns.Response),
+		Opcode:  dns.Opcode,
+		AA:      jsonFlag(dns.Authoritative),
+		TC:      jsonFlag(dns.Truncated),
+		RD:      jsonFlag(dns.RecursionDesired),
+		RA:      jsonFlag(dns.RecursionAvailable),
+		AD:      jsonFlag(dns.AuthenticatedData),
+		CD:      jsonFlag(dns.CheckingDisabled),
+		RCODE:   dns.Rcode,
+		QDCOUNT: len(dns.Question),
+		ANCOUNT: len(dns.Answer),
+		NSCOUNT: len(dns.Ns),
+		ARCOUNT: len(dns.Extra),
+	}
+	if len(dns.Question) > 0 {
+		q := dns.Question[0]
+		m.QNAME, m.QTYPE, m.QCLASS = q.Name, &q.Qtype, &q.Qclass
+		m.QTYPEn, m.QCLASSn = Type(q.Qtype).String(), Class(q.Qclass).String()
+	}
+	for _, q := range dns.Question {
+		q := q
+		m.Qd = append(m.Qd, &rrJSON{
+			NAME:   q.Name,
+			TYPE:   &q.Qtype,
+			TYPEn:  Type(q.Qtype).String(),
+			CLASS:  &q.Qclass,
+			CLASSn: Class(q.Qclass).String(),
+		})
+	}
+	m.An = rawRRsJSON(dns.Answer)
+	m.Ns = rawRRsJSON(dns.Ns)
+	m.Ar = rawRRsJSON(dns.Extra)
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the message is decoded as
+// described in RFC 8427. The counts in the header are ignored.
+func (dns *Msg) UnmarshalJSON(b []byte) error {
+	var m msgJSON
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	*dns = Msg{}
+	dns.Id = m.ID
+	dns.Response = bool(m.QR)
+	dns.Opcode = m.Opcode
+	dns.Authoritative = bool(m.AA)
+	dns.Truncated = bool(m.TC)
+	dns.RecursionDesired = bool(m.RD)
+	dns.RecursionAvailable = bool(m.RA)
+	dns.AuthenticatedData = bool(m.AD)
+	dns.CheckingDisabled = bool(m.CD)
+	dns.Rcode = m.RCODE
+
+	for _, q := range m.Qd {
+		rrtype, class, err := q.typeClass()
+		if err != nil {
+			return err
+		}
+		dns.Question = append(dns.Question, Question{Name: q.NAME, Qtype: rrtype, Qclass: class})
+	}
+	if len(m.Qd) == 0 && m.QNAME != "" {
+		q := &rrJSON{NAME: m.QNAME, TYPE: m.QTYPE, TYPEn: m.QTYPEn, CLASS: m.QCLASS, CLASSn: m.QCLASSn}
+		rrtype, class, err := q.typeClass()
+		if err != nil {
+			return err
+		}
+		dns.Question = []Question{{Name: q.NAME, Qtype: rrtype, Qclass: class}}
+	}
+
+	dns.Answer = rrsJSON(m.An)
+	dns.Ns = rrsJSON(m.Ns)
+	dns.Extra = rrsJSON(m.Ar)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the header is encoded as an RR without
+// rdata.
+func (h *RR_Header) MarshalJSON() ([]byte, error) { return marshalRRJSON(h) }
+
+// UnmarshalJSON implements json.Unmarshaler, any rdata is ignored.
+func (h *RR_Header) UnmarshalJSON(b []byte) error {
+	var r rrJSON
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+	hdr, err := r.header()
+	if err != nil {
+		return err
+	}
+	*h = hdr
+	return nil
+}
+
+func rawRRsJSON(rrs []RR) []rawRRJSON {
+	if len(rrs) == 0 {
+		return nil
+	}
+	r := make([]rawRRJSON, len(rrs))
+	for i, rr := range rrs {
+		r[i] = rawRRJSON{rr}
+	}
+	return r
+}
+
+func rrsJSON(r []rawRRJSON) []RR {
+	if len(r) == 0 {
+		return nil
+	}
+	rrs := make([]RR, len(r))
+	for i := range r {
+		rrs[i] = r[i].RR
+	}
+	return rrs
+}
+
+// marshalRRJSON encodes rr as described in RFC 8427.
+func marshalRRJSON(rr RR) ([]byte, error) {
+	h := rr.Header()
+
+	buf := make([]byte, Len(rr))
+	headerEnd, off, err := packRR(rr, buf, 0, compressionMap{}, false)
+	if err != nil {
+		return nil, err
+	}
+	rdata := buf[headerEnd:off]
+
+	m := map[string]interface{}{
+		"NAME":     h.Name,
+		"TYPE":     h.Rrtype,
+		"CLASS":    h.Class,
+		"TTL":      h.Ttl,
+		"RDLENGTH": len(rdata),
+		"RDATAHEX": strings.ToUpper(hex.EncodeToString(rdata)),
+	}
+	if s, ok := TypeToString[h.Rrtype]; ok {
+		m["TYPEname"] = s
+	}
+	if s, ok := ClassToString[h.Class]; ok && h.Rrtype != TypeOPT {
+		m["CLASSname"] = s
+	}
+	if _, ok := rr.(*RR_Header); !ok && len(rdata) > 0 && hasParser(h.Rrtype) {
+		m["rdata"+Type(h.Rrtype).String()] = strings.TrimPrefix(rr.String(), h.String())
+	}
+	return json.Marshal(m)
+}
+
+// hasParser returns true if the rdata of rrtype in presentation format can
+// be parsed back.
+func hasParser(rrtype uint16) bool {
+	if _, ok := typeToparserFunc[rrtype]; ok {
+		return true
+	}
+	_, ok := typeToGeneratedParserFunc[rrtype]
+	return ok
+}
+
+// unmarshalRRJSON decodes an RR encoded as described in RFC 8427. If there is
+// no rdata, an *RR_Header is returned.
+func unmarshalRRJSON(b []byte) (RR, error) {
+	var r rrJSON
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	h, err := r.header()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.RDATAHEX != nil {
+		rdata, err := hex.DecodeString(*r.RDATAHEX)
+		if err != nil {
+			return nil, err
+		}
+		if len(rdata) > 0xFFFF {
+			return nil, ErrRdata
+		}
+		msg := make([]byte, h.len(0, nil)+len(rdata))
+		_, off, err := h.pack(msg, 0, compressionMap{}, false)
+		if err != nil {
+			return nil, err
+		}
+		packUint16(uint16(len(rdata)), msg, off-2)
+		copy(msg[off:], rdata)
+		rr, _, err := UnpackRR(msg, 0)
+		return rr, err
+	}
+
+	var text map[string]json.RawMessage
+	if err := json.Unmarshal(b, &text); err != nil {
+		return nil, err
+	}
+	if raw, ok := text["rdata"+Type(h.Rrtype).String()]; ok {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		rr, err := NewRR(h.String() + s)
+		if err != nil {
+			return nil, err
+		}
+		if rr == nil {
+			return &h, nil
+		}
+		return rr, nil
+	}
+	return &h, nil
+}
+
+// typeClass returns the type and class of r, from the numbers or the
+// mnemonics. The class defaults to IN.
+func (r *rrJSON) typeClass() (uint16, uint16, error) {
+	var rrtype, class uint16 = 0, ClassINET
+	switch {
+	case r.TYPE != nil:
+		rrtype = *r.TYPE
+	case r.TYPEn != "":
+		t, ok := StringToType[strings.ToUpper(r.TYPEn)]
+		if !ok {
+			if t, ok = typeToInt(strings.ToUpper(r.TYPEn)); !ok {
+				return 0, 0, &Error{err: "bad JSON TYPEname: " + r.TYPEn}
+			}
+		}
+		rrtype = t
+	default:
+		return 0, 0, &Error{err: "missing JSON TYPE"}
+	}
+	switch {
+	case r.CLASS != nil:
+		class = *r.CLASS
+	case r.CLASSn != "":
+		c, ok := StringToClass[strings.ToUpper(r.CLASSn)]
+		if !ok {
+			if c, ok = classToInt(strings.ToUpper(r.CLASSn)); !ok {
+				return 0, 0, &Error{err: "bad JSON CLASSname: " + r.CLASSn}
+			}
+		}
+		class = c
+	}
+	return rrtype, class, nil
+}
+
+func (r *rrJSON) header() (RR_Header, error) {
+	if r.NAME == "" {
+		return RR_Header{}, &Error{err: "missing JSON NAME"}
+	}
+	if _, ok := IsDomainName(r.NAME); !ok {
+		return RR_Header{}, &Error{err: "bad JSON NAME: " + strconv.Quote(r.NAME)}
+	}
+	rrtype, class, err := r.typeClass()
+	if err != nil {
+		return RR_Header{}, err
+	}
+	h := RR_Header{Name: Fqdn(r.NAME), Rrtype: rrtype, Class: class}
+	if r.TTL != nil {
+		h.Ttl = *r.TTL
+	}
+	return h, nil
+}
@@ -0,0 +1,84 @@
+package dns
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+func TestMsgJSON(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeMX)
+	m.Response, m.Authoritative = true, true
+	m.Answer = []RR{
+		testRR("example.org. 3600 IN MX 10 mx.example.org."),
+		testRR(`example.org. 3600 IN TXT "v=spf1 -all" "second"`),
+		testRR(`example.org. 3600 IN TYPE65534 \# 2 abcd`),
+	}
+	m.Ns = []RR{&RR_Header{Name: "example.org.", Rrtype: TypeA, Class: ClassANY}}
+	m.SetEdns0(4096, true)
+	m.IsEdns0().Option = append(m.IsEdns0().Option, &EDNS0_SUBNET{Code: EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()})
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+	m1 := new(Msg)
+	if err := json.Unmarshal(b, m1); err != nil {
+		t.Fatalf("failed to unmarshal message: %v", err)
+	}
+	if m.String() != m1.String() {
+		t.Errorf("expected round trip to return the message, got:\n%s\nexpected:\n%s", m1, m)
+	}
+}
+
+func TestMsgJSONRFC8427(t *testing.T) {
+	// Example from RFC 8427, section 4.1.
+	b := []byte(`{ "ID": 19678, "QR": 0, "Opcode": 0,
+	  "AA": 0, "TC": 0, "RD": 0, "RA": 0, "AD": 0, "CD": 0, "RCODE": 0,
+	  "QDCOUNT": 1, "ANCOUNT": 0, "NSCOUNT": 0, "ARCOUNT": 0,
+	  "QNAME": "example.com", "QTYPE": 1, "QCLASS": 1
+	}`)
+	m := new(Msg)
+	if err := json.Unmarshal(b, m); err != nil {
+		t.Fatalf("failed to unmarshal message: %v", err)
+	}
+	if m.Id != 19678 || len(m.Question) != 1 || m.Question[0] != (Question{"example.com", TypeA, ClassINET}) {
+		t.Errorf("unexpected message %s", m)
+	}
+
+	// The presentation format is used when RDATAHEX is absent.
+	b = []byte(`{"ID": 1, "QR": true, "answerRRs": [{"NAME": "example.com.", "TYPEname": "MX", "CLASSname": "IN", "TTL": 60, "rdataMX": "10 mx.example.com."}]}`)
+	if err := json.Unmarshal(b, m); err != nil {
+		t.Fatalf("failed to unmarshal message: %v", err)
+	}
+	if !m.Response || len(m.Answer) != 1 || m.Answer[0].String() != "example.com.\t60\tIN\tMX\t10 mx.example.com." {
+		t.Errorf("unexpected message %s", m)
+	}
+}
+
+func TestRRJSON(t *testing.T) {
+	mx := testRR("example.org. 3600 IN MX 10 mx.example.org.").(*MX)
+	b, err := json.Marshal(mx)
+	if err != nil {
+		t.Fatalf("failed to marshal RR: %v", err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("failed to unmarshal RR: %v", err)
+	}
+	if m["rdataMX"] != "10 mx.example.org." || m["RDATAHEX"] != "000A026D78076578616D706C65036F726700" || m["TYPEname"] != "MX" {
+		t.Errorf("unexpected JSON %s", b)
+	}
+
+	mx1 := new(MX)
+	if err := json.Unmarshal(b, mx1); err != nil {
+		t.Fatalf("failed to unmarshal RR: %v", err)
+	}
+	if !IsDuplicate(mx, mx1) {
+		t.Errorf("expected %s, got %s", mx, mx1)
+	}
+	if err := json.Unmarshal(b, new(A)); err == nil {
+		t.Error("expected error when unmarshaling MX into A")
+	}
+}
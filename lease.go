@@ -0,0 +1,49 @@
+package dns
+
+import "time"
+
+// SetUpdateLease sets the Update Lease option of dns to lease and keyLease
+// (draft-ietf-dnssd-update-lease), rounded down to seconds. A client sets it
+// on an update to ask for the lease of the records it adds, a server on the
+// response, to the lease it granted. A keyLease of zero is left out, the
+// KEY records then get lease too. An OPT record is added if dns has none.
+func (dns *Msg) SetUpdateLease(lease, keyLease time.Duration) *Msg {
+	opt := responseOPT(dns)
+	e := &EDNS0_UL{Code: EDNS0UL, Lease: leaseSeconds(lease), KeyLease: leaseSeconds(keyLease)}
+	for i, o := range opt.Option {
+		if o.Option() == EDNS0UL {
+			opt.Option[i] = e
+			return dns
+		}
+	}
+	opt.Option = append(opt.Option, e)
+	return dns
+}
+
+// UpdateLease returns the lease and key lease of the Update Lease option of
+// dns, and whether it has one. The key lease is the lease when the option
+// has none.
+func (dns *Msg) UpdateLease() (lease, keyLease time.Duration, ok bool) {
+	opt := dns.IsEdns0()
+	if opt == nil {
+		return 0, 0, false
+	}
+	for _, o := range opt.Option {
+		if e, ok := o.(*EDNS0_UL); ok {
+			lease = time.Duration(e.Lease) * time.Second
+			keyLease = lease
+			if e.KeyLease != 0 {
+				keyLease = time.Duration(e.KeyLease) * time.Second
+			}
+			return lease, keyLease, true
+		}
+	}
+	return 0, 0, false
+}
+
+func leaseSeconds(d time.Duration) uint32 {
+	if s := d / time.Second; s < 1<<32 {
+		return uint32(s)
+	}
+	return 1<<32 - 1
+}
@@ -0,0 +1,47 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateLease(t *testing.T) {
+	m := new(Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]RR{testRR("printer._ipp._tcp.example.org. 120 IN SRV 0 0 631 printer.example.org.")})
+	m.SetUpdateLease(2*time.Hour, 0).SetUpdateLease(time.Hour, 7*24*time.Hour)
+	if opt := m.IsEdns0(); opt == nil || len(opt.Option) != 1 {
+		t.Fatalf("expected a single Update Lease option, got %v", m)
+	}
+
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if lease, keyLease, ok := m1.UpdateLease(); !ok || lease != time.Hour || keyLease != 7*24*time.Hour {
+		t.Errorf("expected a lease of 1h and a key lease of 168h, got %v, %v, %v", lease, keyLease, ok)
+	}
+
+	r := new(Msg)
+	r.SetReply(m1)
+	r.SetUpdateLease(30*time.Minute, 0)
+	if buf, err = r.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != 12+len("\x07example\x03org\x00")+4+11+4+4 {
+		t.Errorf("expected a lease option of 4 bytes, got a message of %d bytes", len(buf))
+	}
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if lease, keyLease, ok := m1.UpdateLease(); !ok || lease != 30*time.Minute || keyLease != lease {
+		t.Errorf("expected a lease of 30m, got %v, %v, %v", lease, keyLease, ok)
+	}
+	if _, _, ok := new(Msg).UpdateLease(); ok {
+		t.Error("expected no lease")
+	}
+}
@@ -506,3 +506,118 @@ func TestMsgCompressLengthEscaped(t *testing.T) {
 		t.Fatalf("predicted compressed length is wrong: predicted %d, actual %d", predicted, len(buf))
 	}
 }
+
+func TestMsgLengthExact(t *testing.T) {
+	rrs := []string{
+		`example.org. 3600 IN TXT "a\"b" "\065\066" "" "c\;d"`,
+		`example.org. 3600 IN SPF "v=spf1 -all"`,
+		`example.org. 3600 IN HINFO "PC\032Intel" "Linux"`,
+		`example.org. 3600 IN DNSKEY 257 3 15 l02Woi0iS8Aa25FQkUd9RMzZHJpBoRQwAQEX1SxZJA4=`,
+		`example.org. 3600 IN DS 60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118`,
+		`example.org. 3600 IN NSEC www.example.org. A NS SOA MX TXT AAAA RRSIG NSEC DNSKEY TYPE1234`,
+		`example.org. 3600 IN NSEC3 1 1 12 aabbccdd 2vptu5timamqttgl4luu9kg21e0aor3s A RRSIG`,
+		`example.org. 3600 IN NSEC3 1 1 12 - 2vptu5timamqttgl4luu9kg21e0aor3s A RRSIG`,
+		`example.org. 3600 IN NSEC3PARAM 1 0 10 -`,
+		`example.org. 3600 IN CSYNC 66 3 A NS AAAA`,
+		`example.org. 3600 IN SSHFP 1 2 BC6533CDC95A79078A39A56EA7635984ED655318ADA9B6159E30723665DA95BB`,
+		`example.org. 3600 IN CAA 0 issue "letsencrypt.org"`,
+		`example.org. 3600 IN EUI48 00-00-5e-00-53-2a`,
+		`example.org. 3600 IN TYPE65534 \# 4 0a000001`,
+	}
+	for _, compress := range []bool{false, true} {
+		m := new(Msg)
+		m.SetQuestion("example.org.", TypeANY)
+		m.Compress = compress
+		for _, s := range rrs {
+			m.Answer = append(m.Answer, testRR(s))
+		}
+		m.SetEdns0(4096, true)
+		m.IsEdns0().Option = append(m.IsEdns0().Option, &EDNS0_NSID{Code: EDNS0NSID, Nsid: "abcd"})
+
+		buf, err := m.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := m.Len(); l != len(buf) {
+			t.Errorf("predicted length is wrong (compress: %t): predicted %d, actual %d", compress, l, len(buf))
+		}
+		for _, rr := range m.Answer {
+			buf := make([]byte, MaxMsgSize)
+			off, err := PackRR(rr, buf, 0, nil, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l := Len(rr); l != off {
+				t.Errorf("predicted length is wrong for %s: predicted %d, actual %d", rr, l, off)
+			}
+		}
+	}
+}
+
+func TestMsgLengthNoRdata(t *testing.T) {
+	m := new(Msg)
+	m.SetUpdate("example.org.")
+	m.RemoveRRset([]RR{&A{Hdr: RR_Header{Name: "www.example.org.", Rrtype: TypeA}}})
+	m.RemoveRRset([]RR{&AAAA{Hdr: RR_Header{Name: "www.example.org.", Rrtype: TypeAAAA}}})
+
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := m.Len(); l != len(buf) {
+		t.Errorf("predicted length is wrong: predicted %d, actual %d", l, len(buf))
+	}
+}
+
+func TestMsgLengthEmptyNames(t *testing.T) {
+	// Empty domain names, as in the zero value of most types, are packed as nothing.
+	for typ, f := range TypeToRR {
+		rr := f()
+		*rr.Header() = RR_Header{Name: "example.org.", Rrtype: typ, Class: ClassINET}
+		m := new(Msg)
+		m.SetQuestion("example.org.", TypeANY)
+		m.Answer = []RR{rr}
+		for _, compress := range []bool{false, true} {
+			m.Compress = compress
+			buf, err := m.Pack()
+			if err != nil {
+				continue
+			}
+			if l := m.Len(); l != len(buf) {
+				t.Errorf("predicted length is wrong for %s (compress: %t): predicted %d, actual %d", Type(typ), compress, l, len(buf))
+			}
+		}
+	}
+}
+
+func TestMsgLengthCompressionBoundary(t *testing.T) {
+	// Names around maxCompressionOffset, with escapes, and in rdata that
+	// isn't compressed, but whose suffixes can be used for compression.
+	for pad := maxCompressionOffset - 80; pad < maxCompressionOffset+10; pad++ {
+		m := new(Msg)
+		m.SetQuestion("example.org.", TypeANY)
+		m.Compress = true
+		txt := &TXT{Hdr: RR_Header{Name: "example.org.", Rrtype: TypeTXT, Class: ClassINET}}
+		for n := pad - 12 - 17 - 23; n > 0; n -= 256 {
+			if n > 255 {
+				txt.Txt = append(txt.Txt, strings.Repeat("x", 255))
+			} else {
+				txt.Txt = append(txt.Txt, strings.Repeat("x", n-1))
+			}
+		}
+		m.Answer = append(m.Answer, txt,
+			testRR(`a\.b.c\065d.x\.y.example.net. 3600 IN NSEC c\065d.x\.y.example.net. A`),
+			testRR(`c\065d.x\.y.example.net. 3600 IN NSEC e.c\065d.x\.y.example.net. A`),
+			testRR(`e.c\065d.x\.y.example.net. 3600 IN CNAME f.x\.y.example.net.`),
+			testRR(`f.x\.y.example.net. 3600 IN MX 10 c\065d.x\.y.example.net.`),
+		)
+
+		buf, err := m.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := m.Len(); l != len(buf) {
+			t.Errorf("predicted length is wrong at %d: predicted %d, actual %d", pad, l, len(buf))
+		}
+	}
+}
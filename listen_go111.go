@@ -25,20 +25,26 @@ func reuseportControl(network, address string, c syscall.RawConn) error {
 	return opErr
 }
 
-func listenTCP(network, addr string, reuseport bool) (net.Listener, error) {
+func listenTCP(network, addr string, reuseport bool, opts *SocketOptions) (net.Listener, error) {
 	var lc net.ListenConfig
 	if reuseport {
 		lc.Control = reuseportControl
 	}
+	if opts != nil {
+		lc.Control = chainControl(lc.Control, opts.Control)
+	}
 
 	return lc.Listen(context.Background(), network, addr)
 }
 
-func listenUDP(network, addr string, reuseport bool) (net.PacketConn, error) {
+func listenUDP(network, addr string, reuseport bool, opts *SocketOptions) (net.PacketConn, error) {
 	var lc net.ListenConfig
 	if reuseport {
 		lc.Control = reuseportControl
 	}
+	if opts != nil {
+		lc.Control = chainControl(lc.Control, opts.Control)
+	}
 
 	return lc.ListenPacket(context.Background(), network, addr)
 }
@@ -6,18 +6,24 @@ import "net"
 
 const supportsReusePort = false
 
-func listenTCP(network, addr string, reuseport bool) (net.Listener, error) {
+func listenTCP(network, addr string, reuseport bool, opts *SocketOptions) (net.Listener, error) {
 	if reuseport {
 		// TODO(tmthrgd): return an error?
 	}
+	if opts != nil {
+		return nil, errSocketOptions
+	}
 
 	return net.Listen(network, addr)
 }
 
-func listenUDP(network, addr string, reuseport bool) (net.PacketConn, error) {
+func listenUDP(network, addr string, reuseport bool, opts *SocketOptions) (net.PacketConn, error) {
 	if reuseport {
 		// TODO(tmthrgd): return an error?
 	}
+	if opts != nil {
+		return nil, errSocketOptions
+	}
 
 	return net.ListenPacket(network, addr)
 }
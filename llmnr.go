@@ -0,0 +1,246 @@
+package dns
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+// LLMNRPort is the port of Link-Local Multicast Name Resolution (RFC 4795).
+const LLMNRPort = 5355
+
+// The multicast groups LLMNR queries are sent to.
+var (
+	LLMNRGroupIPv4 = net.IPv4(224, 0, 0, 252)
+	LLMNRGroupIPv6 = net.ParseIP("ff02::1:3")
+)
+
+// ErrLLMNRConflict is returned by LLMNRClient.Lookup when more than one host
+// claims a unique name.
+var ErrLLMNRConflict error = &Error{err: "llmnr name conflict"}
+
+// LLMNR messages have the DNS header, but the AA bit is the C (conflict) bit,
+// and the RD bit is the T (tentative) bit.
+
+// LLMNRConflict reports whether the C bit of the LLMNR message m is set. In a
+// query, it tells a responder that more than one host answered for its name,
+// in a response, it tells the name isn't unique.
+func LLMNRConflict(m *Msg) bool { return m.Authoritative }
+
+// LLMNRTentative reports whether the T bit of the LLMNR response m is set: the
+// responder didn't verify the name is unique yet.
+func LLMNRTentative(m *Msg) bool { return m.RecursionDesired }
+
+// An LLMNRClient sends LLMNR queries to the hosts on a link.
+type LLMNRClient struct {
+	Net       string         // "udp4" or "udp6", defaults to "udp4"
+	Interface *net.Interface // interface the queries are sent on, if nil the default one
+	Timeout   time.Duration  // time responses are waited for, defaults to 1 second (LLMNR_TIMEOUT)
+}
+
+// An LLMNRResponse is a response to an LLMNR query.
+type LLMNRResponse struct {
+	Msg  *Msg
+	From *net.UDPAddr // address of the responder
+}
+
+// Exchange sends the query m to the LLMNR multicast group, and returns the
+// responses received within the timeout. The RD bit of m is cleared.
+func (c *LLMNRClient) Exchange(m *Msg) ([]LLMNRResponse, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return c.exchange(conn, m, []*net.UDPAddr{c.group()})
+}
+
+// Lookup queries the hosts on the link for name and qtype, and returns the
+// records they answer with. When more than one of them claims the name is
+// unique, Lookup tells them about the conflict with a query with the C bit
+// set, as RFC 4795, Section 4.2 describes, and returns ErrLLMNRConflict.
+func (c *LLMNRClient) Lookup(name string, qtype uint16) ([]RR, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	m := new(Msg)
+	m.SetQuestion(Fqdn(name), qtype)
+	rs, err := c.exchange(conn, m, []*net.UDPAddr{c.group()})
+	if err != nil {
+		return nil, err
+	}
+	var (
+		answer []RR
+		unique []*net.UDPAddr
+	)
+	for _, r := range rs {
+		answer = append(answer, r.Msg.Answer...)
+		if !LLMNRConflict(r.Msg) {
+			unique = append(unique, r.From)
+		}
+	}
+	if len(unique) > 1 {
+		m.Id = Id()
+		m.Authoritative = true
+		c.write(conn, m, unique)
+		return nil, ErrLLMNRConflict
+	}
+	return answer, nil
+}
+
+func (c *LLMNRClient) dial() (*net.UDPConn, error) {
+	network := c.Net
+	if network == "" {
+		network = "udp4"
+	}
+	conn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Interface != nil && network == "udp4" {
+		if err := ipv4.NewPacketConn(conn).SetMulticastInterface(c.Interface); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// group returns the address of the multicast group.
+func (c *LLMNRClient) group() *net.UDPAddr {
+	if c.Net == "udp6" {
+		addr := &net.UDPAddr{IP: LLMNRGroupIPv6, Port: LLMNRPort}
+		if c.Interface != nil {
+			addr.Zone = c.Interface.Name
+		}
+		return addr
+	}
+	return &net.UDPAddr{IP: LLMNRGroupIPv4, Port: LLMNRPort}
+}
+
+// write sends m to each of addrs.
+func (c *LLMNRClient) write(conn *net.UDPConn, m *Msg, addrs []*net.UDPAddr) error {
+	m.RecursionDesired = false
+	buf, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if _, err := conn.WriteToUDP(buf, addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *LLMNRClient) exchange(conn *net.UDPConn, m *Msg, addrs []*net.UDPAddr) ([]LLMNRResponse, error) {
+	if err := c.write(conn, m, addrs); err != nil {
+		return nil, err
+	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = time.Second
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	var rs []LLMNRResponse
+	buf := make([]byte, MaxMsgSize)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+				return rs, nil
+			}
+			return rs, err
+		}
+		r := new(Msg)
+		if r.Unpack(buf[:n]) != nil || !r.Response || r.Id != m.Id ||
+			len(r.Question) != 1 || !isQuestion(r.Question[0], m.Question) {
+			continue
+		}
+		rs = append(rs, LLMNRResponse{Msg: r, From: from})
+	}
+}
+
+func isQuestion(q Question, qs []Question) bool {
+	return len(qs) == 1 && equal(q.Name, qs[0].Name) && q.Qtype == qs[0].Qtype && q.Qclass == qs[0].Qclass
+}
+
+// An LLMNRResponder is a Handler that answers the LLMNR queries for the names
+// of a host with its addresses. Queries for other names are ignored, as are
+// queries with the C bit set, which tell that another host answered for the
+// name too.
+type LLMNRResponder struct {
+	Names     []string // names of the host
+	Addrs     []net.IP // addresses of the host
+	TTL       uint32   // TTL of the answers, defaults to 30 seconds
+	Tentative bool     // if true, the names aren't verified to be unique yet, and responses have the T bit set
+
+	// Conflict, if not nil, is called with the queries with the C bit set
+	// for one of Names. The host should then verify the name is unique.
+	Conflict func(name string, from net.Addr)
+}
+
+// ListenAndServe serves r on the LLMNR port, for the multicast group of network,
+// "udp4" or "udp6", on the interface ifi, or the default one if nil.
+func (r *LLMNRResponder) ListenAndServe(network string, ifi *net.Interface) error {
+	group := LLMNRGroupIPv4
+	if network == "udp6" {
+		group = LLMNRGroupIPv6
+	}
+	conn, err := net.ListenMulticastUDP(network, ifi, &net.UDPAddr{IP: group, Port: LLMNRPort})
+	if err != nil {
+		return err
+	}
+	srv := &Server{PacketConn: conn, Handler: r}
+	return srv.ActivateAndServe()
+}
+
+// ServeDNS implements the Handler interface.
+func (r *LLMNRResponder) ServeDNS(w ResponseWriter, req *Msg) {
+	if req.Response || req.Opcode != OpcodeQuery || req.Truncated || len(req.Question) != 1 {
+		return
+	}
+	q := req.Question[0]
+	if !r.isName(q.Name) || (q.Qclass != ClassINET && q.Qclass != ClassANY) {
+		return
+	}
+	if LLMNRConflict(req) {
+		if r.Conflict != nil {
+			r.Conflict(q.Name, w.RemoteAddr())
+		}
+		return
+	}
+
+	ttl := r.TTL
+	if ttl == 0 {
+		ttl = 30
+	}
+	m := new(Msg)
+	m.SetReply(req)
+	m.RecursionDesired = r.Tentative
+	for _, ip := range r.Addrs {
+		hdr := RR_Header{Name: q.Name, Class: ClassINET, Ttl: ttl}
+		if ip4 := ip.To4(); ip4 != nil && (q.Qtype == TypeA || q.Qtype == TypeANY) {
+			hdr.Rrtype = TypeA
+			m.Answer = append(m.Answer, &A{Hdr: hdr, A: ip4})
+		} else if ip4 == nil && (q.Qtype == TypeAAAA || q.Qtype == TypeANY) {
+			hdr.Rrtype = TypeAAAA
+			m.Answer = append(m.Answer, &AAAA{Hdr: hdr, AAAA: ip})
+		}
+	}
+	w.WriteMsg(m)
+}
+
+func (r *LLMNRResponder) isName(name string) bool {
+	for _, n := range r.Names {
+		if equal(Fqdn(n), name) {
+			return true
+		}
+	}
+	return false
+}
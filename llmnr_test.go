@@ -0,0 +1,76 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLLMNRResponder(t *testing.T) {
+	r := &LLMNRResponder{
+		Names: []string{"host"},
+		Addrs: []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")},
+	}
+
+	w := new(recordWriter)
+	m := new(Msg)
+	m.SetQuestion("HOST.", TypeAAAA)
+	m.RecursionDesired = false
+	r.ServeDNS(w, m)
+	if len(w.msgs) != 1 || len(w.msgs[0].Answer) != 1 || w.msgs[0].Answer[0].(*AAAA).AAAA.String() != "2001:db8::1" {
+		t.Fatalf("expected the AAAA record of host, got %v", w.msgs)
+	}
+	if LLMNRConflict(w.msgs[0]) || LLMNRTentative(w.msgs[0]) {
+		t.Error("expected the C and T bits to be clear")
+	}
+
+	m.SetQuestion("other.", TypeA)
+	r.ServeDNS(w, m)
+	if len(w.msgs) != 1 {
+		t.Errorf("expected no response for another name, got %v", w.msgs[1])
+	}
+
+	var conflict string
+	r.Conflict = func(name string, from net.Addr) { conflict = name }
+	m.SetQuestion("host.", TypeA)
+	m.Authoritative = true
+	r.ServeDNS(w, m)
+	if len(w.msgs) != 1 || conflict != "host." {
+		t.Errorf("expected a conflict for host. and no response, got %q and %v", conflict, w.msgs)
+	}
+}
+
+func TestLLMNRClientExchange(t *testing.T) {
+	var addrs []*net.UDPAddr
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		r := &LLMNRResponder{Names: []string{"host."}, Addrs: []net.IP{net.ParseIP(ip)}}
+		s, addr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", func(srv *Server) { srv.Handler = r })
+		if err != nil {
+			t.Fatalf("unable to run test server: %v", err)
+		}
+		defer s.Shutdown()
+		udpAddr, _ := net.ResolveUDPAddr("udp4", addr)
+		addrs = append(addrs, udpAddr)
+	}
+
+	c := &LLMNRClient{Timeout: 500 * time.Millisecond}
+	conn, err := c.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	m := new(Msg)
+	m.SetQuestion("host.", TypeA)
+	rs, err := c.exchange(conn, m, addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(rs))
+	}
+	for _, r := range rs {
+		if len(r.Msg.Answer) != 1 || LLMNRConflict(r.Msg) {
+			t.Errorf("unexpected response from %v: %v", r.From, r.Msg)
+		}
+	}
+}
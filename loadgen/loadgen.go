@@ -0,0 +1,122 @@
+// Package loadgen replays DNS queries against a server at a given rate, and
+// reports its latency and the rcodes of its responses, in the way of dnsperf.
+//
+// Basic use pattern:
+//
+//	queries, err := loadgen.ReadQueries(f) // "www.example.org A" lines
+//	if err != nil {
+//		// bad query file
+//	}
+//	g := &loadgen.Generator{Net: "udp", Address: "192.0.2.53:53", QPS: 1000, Duration: time.Minute}
+//	report := g.Run(context.Background(), queries)
+//	fmt.Println(report)
+package loadgen
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// A Generator sends queries to a server.
+type Generator struct {
+	// Exchanger sends the queries, by default a dns.Client, or a
+	// dns.DoHClient when Net is "https", with Timeout.
+	Exchanger dns.Exchanger
+
+	Net         string        // "udp", "tcp", "tcp-tls" or "https", defaults to "udp"
+	Address     string        // address of the server, a URL for "https"
+	Timeout     time.Duration // timeout of a query, defaults to 2 seconds
+	QPS         float64       // queries sent per second, 0 for as fast as Concurrency allows
+	Concurrency int           // maximum number of queries outstanding, defaults to 100
+	Duration    time.Duration // how long to send queries, looping over them, 0 to send them once
+}
+
+// Run sends the queries to the server, until they are all sent, Duration has
+// passed or ctx is done, and waits for the responses. The queries aren't
+// modified, each is sent as a copy with a new ID.
+func (g *Generator) Run(ctx context.Context, queries []*dns.Msg) *Report {
+	ex := g.exchanger()
+	concurrency := g.Concurrency
+	if concurrency <= 0 {
+		concurrency = 100
+	}
+
+	rep := &Report{Rcodes: make(map[int]int)}
+	if len(queries) == 0 {
+		return rep
+	}
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+	start := time.Now()
+	var end time.Time
+	if g.Duration > 0 {
+		end = start.Add(g.Duration)
+	}
+send:
+	for i := 0; ; i++ {
+		if g.Duration == 0 && i == len(queries) {
+			break
+		}
+		if g.QPS > 0 {
+			at := start.Add(time.Duration(float64(i) / g.QPS * float64(time.Second)))
+			if !end.IsZero() && !at.Before(end) {
+				break
+			}
+			if d := time.Until(at); d > 0 {
+				t := time.NewTimer(d)
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					t.Stop()
+					break send
+				}
+			}
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break send
+		}
+		if !end.IsZero() && !time.Now().Before(end) {
+			<-sem
+			break
+		}
+
+		m := queries[i%len(queries)].Copy()
+		m.Id = dns.Id()
+		rep.Sent++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, rtt, err := ex.Exchange(m, g.Address)
+			<-sem
+			mu.Lock()
+			rep.add(r, rtt, err)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	rep.Duration = time.Since(start)
+	rep.sort()
+	return rep
+}
+
+func (g *Generator) exchanger() dns.Exchanger {
+	if g.Exchanger != nil {
+		return g.Exchanger
+	}
+	timeout := g.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	if g.Net == "https" {
+		return &dns.DoHClient{Timeout: timeout}
+	}
+	return &dns.Client{Net: g.Net, Timeout: timeout}
+}
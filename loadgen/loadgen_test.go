@@ -0,0 +1,100 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// fakeExchanger answers queries for names under nx. with NXDOMAIN, fails the
+// ones for lost. and answers the others.
+type fakeExchanger struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (f *fakeExchanger) Exchange(m *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+	name := m.Question[0].Name
+	f.mu.Lock()
+	f.names = append(f.names, name)
+	f.mu.Unlock()
+	if name == "lost." {
+		return nil, 0, errors.New("i/o timeout")
+	}
+	r := new(dns.Msg)
+	r.SetReply(m)
+	if name == "nx." {
+		r.Rcode = dns.RcodeNameError
+	}
+	return r, time.Millisecond, nil
+}
+
+func TestGeneratorRun(t *testing.T) {
+	queries, err := ReadQueries(strings.NewReader("; queries\nexample.org A\nnx AAAA\n\nlost MX\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 3 || queries[1].Question[0].Qtype != dns.TypeAAAA {
+		t.Fatalf("unexpected queries %v", queries)
+	}
+
+	ex := new(fakeExchanger)
+	g := &Generator{Exchanger: ex, QPS: 100}
+	rep := g.Run(context.Background(), queries)
+	if rep.Sent != 3 || rep.Completed != 2 || rep.Lost != 1 {
+		t.Errorf("expected 3 queries sent, 2 completed and 1 lost, got %d, %d and %d", rep.Sent, rep.Completed, rep.Lost)
+	}
+	if rep.Rcodes[dns.RcodeSuccess] != 1 || rep.Rcodes[dns.RcodeNameError] != 1 || rep.Errors["i/o timeout"] != 1 {
+		t.Errorf("unexpected rcodes %v and errors %v", rep.Rcodes, rep.Errors)
+	}
+	if rep.Duration < 20*time.Millisecond {
+		t.Errorf("expected the queries to be paced at 100 per second, took %v", rep.Duration)
+	}
+	if rep.Latency(50) != time.Millisecond || rep.Latency(100) != time.Millisecond {
+		t.Errorf("unexpected latency %v", rep.Latency(50))
+	}
+	if !strings.Contains(rep.String(), "NXDOMAIN 1") {
+		t.Errorf("expected the rcodes in the summary, got\n%s", rep)
+	}
+	if queries[0].Id == 0 {
+		t.Error("expected the queries not to be modified")
+	}
+}
+
+func TestGeneratorDuration(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	ex := new(fakeExchanger)
+	g := &Generator{Exchanger: ex, QPS: 200, Duration: 100 * time.Millisecond}
+	rep := g.Run(context.Background(), []*dns.Msg{m})
+	if rep.Sent < 10 || rep.Sent > 21 {
+		t.Errorf("expected about 20 queries in 100ms at 200 per second, got %d", rep.Sent)
+	}
+	if rep.Completed != rep.Sent {
+		t.Errorf("expected all %d queries to complete, got %d", rep.Sent, rep.Completed)
+	}
+}
+
+func TestReportLatency(t *testing.T) {
+	rep := &Report{Rcodes: map[int]int{}}
+	for i := 1; i <= 100; i++ {
+		rep.add(new(dns.Msg), time.Duration(i)*time.Millisecond, nil)
+	}
+	rep.sort()
+	for p, want := range map[float64]time.Duration{0: time.Millisecond, 50: 50 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := rep.Latency(p); got != want {
+			t.Errorf("expected p%v of %v, got %v", p, want, got)
+		}
+	}
+}
+
+func TestReadQueriesError(t *testing.T) {
+	if _, err := ReadQueries(strings.NewReader("example.org BOGUS\n")); err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+}
@@ -0,0 +1,59 @@
+package loadgen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/miekg/dns/dnscap"
+)
+
+// ReadQueries reads queries from r in the format of dnsperf: a name and a
+// type per line, e.g. "www.example.org AAAA". Empty lines and lines starting
+// with ';' or '#' are skipped. The queries have the RD bit set.
+func ReadQueries(r io.Reader) ([]*dns.Msg, error) {
+	var queries []*dns.Msg
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("loadgen: line %d: expected a name and a type", n)
+		}
+		qtype, ok := dns.StringToType[strings.ToUpper(fields[1])]
+		if !ok {
+			return nil, fmt.Errorf("loadgen: line %d: unknown type %q", n, fields[1])
+		}
+		if _, ok := dns.IsDomainName(fields[0]); !ok {
+			return nil, fmt.Errorf("loadgen: line %d: bad name %q", n, fields[0])
+		}
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(fields[0]), qtype)
+		queries = append(queries, m)
+	}
+	return queries, s.Err()
+}
+
+// ReadCapture reads the queries of the capture r, e.g. to replay the traffic
+// of a server. Responses, and messages that can't be unpacked, are skipped.
+func ReadCapture(r *dnscap.Reader) ([]*dns.Msg, error) {
+	var queries []*dns.Msg
+	for {
+		p, err := r.Next()
+		if err == io.EOF {
+			return queries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.Msg == nil || p.Msg.Response || len(p.Msg.Question) == 0 {
+			continue
+		}
+		queries = append(queries, p.Msg)
+	}
+}
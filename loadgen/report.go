@@ -0,0 +1,93 @@
+package loadgen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// A Report holds the results of a run of a Generator.
+type Report struct {
+	Sent      int            // queries sent
+	Completed int            // queries answered
+	Lost      int            // queries that failed, e.g. timed out
+	Duration  time.Duration  // time from the first query to the last response
+	Rcodes    map[int]int    // number of responses by rcode
+	Errors    map[string]int // number of lost queries by error
+
+	latencies []time.Duration // of the completed queries, sorted
+}
+
+func (rep *Report) add(r *dns.Msg, rtt time.Duration, err error) {
+	if err != nil || r == nil {
+		rep.Lost++
+		if err != nil {
+			if rep.Errors == nil {
+				rep.Errors = make(map[string]int)
+			}
+			rep.Errors[err.Error()]++
+		}
+		return
+	}
+	rep.Completed++
+	rep.Rcodes[r.Rcode]++
+	rep.latencies = append(rep.latencies, rtt)
+}
+
+func (rep *Report) sort() {
+	sort.Slice(rep.latencies, func(i, j int) bool { return rep.latencies[i] < rep.latencies[j] })
+}
+
+// QPS returns the rate of answered queries per second.
+func (rep *Report) QPS() float64 {
+	if rep.Duration <= 0 {
+		return 0
+	}
+	return float64(rep.Completed) / rep.Duration.Seconds()
+}
+
+// Latency returns the p-th percentile, 0 to 100, of the latency of the
+// answered queries, 0 if there are none.
+func (rep *Report) Latency(p float64) time.Duration {
+	if len(rep.latencies) == 0 {
+		return 0
+	}
+	i := int(p/100*float64(len(rep.latencies))+0.5) - 1
+	switch {
+	case i < 0:
+		i = 0
+	case i >= len(rep.latencies):
+		i = len(rep.latencies) - 1
+	}
+	return rep.latencies[i]
+}
+
+// String returns a summary of rep.
+func (rep *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Queries sent:      %d\n", rep.Sent)
+	fmt.Fprintf(&b, "Queries completed: %d\n", rep.Completed)
+	fmt.Fprintf(&b, "Queries lost:      %d\n", rep.Lost)
+	rcodes := make([]int, 0, len(rep.Rcodes))
+	for rcode := range rep.Rcodes {
+		rcodes = append(rcodes, rcode)
+	}
+	sort.Ints(rcodes)
+	b.WriteString("Response codes:   ")
+	for _, rcode := range rcodes {
+		name, ok := dns.RcodeToString[rcode]
+		if !ok {
+			name = fmt.Sprintf("RCODE%d", rcode)
+		}
+		fmt.Fprintf(&b, " %s %d", name, rep.Rcodes[rcode])
+	}
+	b.WriteByte('\n')
+	fmt.Fprintf(&b, "Run time:          %v\n", rep.Duration)
+	fmt.Fprintf(&b, "Queries per second: %.1f\n", rep.QPS())
+	fmt.Fprintf(&b, "Latency:           p50 %v, p90 %v, p99 %v, max %v\n",
+		rep.Latency(50), rep.Latency(90), rep.Latency(99), rep.Latency(100))
+	return b.String()
+}
@@ -0,0 +1,315 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxCNAME is the default number of CNAME records a Lookup follows.
+const defaultMaxCNAME = 8
+
+var errLookupCNAME = &Error{err: "lookup: too many CNAME records"}
+
+// A Lookup does the lookups of programs that want records, not messages: the
+// names are tried with the suffixes of Search, CNAME records are followed, and
+// truncated responses of a Client are retried over TCP. The lookup methods
+// return the records found, with a LookupInfo describing the response. A name
+// that doesn't exist is a *net.DNSError with IsNotFound set; a name without
+// records of the type asked is an empty result without error.
+//
+//	l := &dns.Lookup{Address: "192.0.2.53:53"}
+//	ips, info, err := l.LookupA(ctx, "www.example.org")
+type Lookup struct {
+	Exchanger Exchanger // sends the queries, defaults to a UDP Client
+	Address   string    // address given to Exchanger, e.g. "192.0.2.53:53" or a DoH URL
+	Search    []string  // suffixes tried for names that aren't fully qualified, as in ClientConfig
+	Ndots     int       // number of dots a name needs to be tried as is before the suffixes, as in ClientConfig
+	DNSSEC    bool      // if true, queries have the DO bit set, and the RRSIGs of the records are returned in LookupInfo.Msg
+	MaxCNAME  int       // maximum number of CNAME records followed, defaults to 8
+}
+
+// LookupInfo describes the response to a lookup.
+type LookupInfo struct {
+	Name              string   // name of the records found, after the search list and the CNAME records
+	CNAME             []string // names followed to reach Name, the name asked first
+	AuthenticatedData bool     // if true, the AD bit was set on all the responses used
+	TTL               uint32   // lowest TTL of the records found and of the CNAME records followed
+	Msg               *Msg     // last response
+}
+
+// Query returns the records of type qtype of name, and the LookupInfo of the
+// response. The typed methods, such as LookupA or LookupMX, are built on it.
+func (l *Lookup) Query(ctx context.Context, name string, qtype uint16) ([]RR, *LookupInfo, error) {
+	var (
+		rrs    []RR
+		info   *LookupInfo
+		err    error
+		nodata *LookupInfo
+	)
+	for _, n := range (&ClientConfig{Search: l.Search, Ndots: l.Ndots}).NameList(name) {
+		rrs, info, err = l.resolve(ctx, n, qtype)
+		if err != nil {
+			if e, ok := err.(*net.DNSError); ok && e.IsNotFound {
+				continue
+			}
+			return nil, info, err
+		}
+		if len(rrs) > 0 {
+			return rrs, info, nil
+		}
+		if nodata == nil {
+			nodata = info
+		}
+	}
+	if nodata != nil {
+		return nil, nodata, nil
+	}
+	return nil, info, err
+}
+
+// resolve returns the records of type qtype of name, following the CNAME
+// records, in the response or with new queries.
+func (l *Lookup) resolve(ctx context.Context, name string, qtype uint16) ([]RR, *LookupInfo, error) {
+	max := l.MaxCNAME
+	if max <= 0 {
+		max = defaultMaxCNAME
+	}
+	info := &LookupInfo{Name: name, AuthenticatedData: true}
+	ttl := ^uint32(0)
+	target := name
+	var r *Msg
+	for {
+		if r == nil {
+			var err error
+			if r, err = l.exchange(ctx, target, qtype); err != nil {
+				return nil, nil, err
+			}
+			info.Msg = r
+			info.AuthenticatedData = info.AuthenticatedData && r.AuthenticatedData
+			switch r.Rcode {
+			case RcodeSuccess:
+			case RcodeNameError:
+				return nil, info, &net.DNSError{Err: "no such host", Name: target, Server: l.Address, IsNotFound: true}
+			default:
+				return nil, info, &net.DNSError{Err: "server misbehaving", Name: target, Server: l.Address,
+					IsTemporary: r.Rcode == RcodeServerFailure}
+			}
+		}
+
+		var rrs []RR
+		var cname *CNAME
+		for _, rr := range r.Answer {
+			h := rr.Header()
+			if !equal(h.Name, target) {
+				continue
+			}
+			if h.Rrtype == qtype {
+				rrs = append(rrs, rr)
+			} else if c, ok := rr.(*CNAME); ok {
+				cname = c
+			}
+		}
+		if len(rrs) > 0 || cname == nil {
+			if len(rrs) == 0 && len(r.Question) > 0 && !equal(r.Question[0].Name, target) {
+				// The chain leaves the response: ask the target itself.
+				r = nil
+				continue
+			}
+			for _, rr := range rrs {
+				if rr.Header().Ttl < ttl {
+					ttl = rr.Header().Ttl
+				}
+			}
+			if ttl != ^uint32(0) {
+				info.TTL = ttl
+			}
+			info.Name = target
+			return rrs, info, nil
+		}
+
+		if len(info.CNAME) >= max {
+			return nil, info, errLookupCNAME
+		}
+		if cname.Hdr.Ttl < ttl {
+			ttl = cname.Hdr.Ttl
+		}
+		info.CNAME = append(info.CNAME, target)
+		target = cname.Target
+	}
+}
+
+// exchange sends the query for name and qtype, and retries it over TCP if the
+// response of a Client is truncated.
+func (l *Lookup) exchange(ctx context.Context, name string, qtype uint16) (*Msg, error) {
+	m := new(Msg)
+	m.SetQuestion(Fqdn(name), qtype)
+	m.SetEdns0(1232, l.DNSSEC)
+	m.AuthenticatedData = true
+
+	ex := l.Exchanger
+	if ex == nil {
+		ex = new(Client)
+	}
+	r, err := (&NetResolver{Exchanger: ex, Address: l.Address}).exchange(ctx, m)
+	if err != nil || !r.Truncated {
+		return r, err
+	}
+	c, ok := ex.(*Client)
+	if !ok || strings.HasPrefix(c.network(attempt{}), "tcp") {
+		return r, nil
+	}
+	return (&NetResolver{Exchanger: tcpExchanger{c}, Address: l.Address}).exchange(ctx, m)
+}
+
+// tcpExchanger sends the queries of a UDP Client over TCP.
+type tcpExchanger struct{ c *Client }
+
+func (t tcpExchanger) Exchange(m *Msg, address string) (*Msg, time.Duration, error) {
+	// Keep udp4 and udp6 their address family.
+	return t.c.exchangeShared(m, address, attempt{net: "tcp" + strings.TrimPrefix(t.c.network(attempt{}), "udp")})
+}
+
+// LookupA returns the IPv4 addresses of name.
+func (l *Lookup) LookupA(ctx context.Context, name string) ([]net.IP, *LookupInfo, error) {
+	rrs, info, err := l.Query(ctx, name, TypeA)
+	var ips []net.IP
+	for _, rr := range rrs {
+		if a, ok := rr.(*A); ok {
+			ips = append(ips, a.A)
+		}
+	}
+	return ips, info, err
+}
+
+// LookupAAAA returns the IPv6 addresses of name.
+func (l *Lookup) LookupAAAA(ctx context.Context, name string) ([]net.IP, *LookupInfo, error) {
+	rrs, info, err := l.Query(ctx, name, TypeAAAA)
+	var ips []net.IP
+	for _, rr := range rrs {
+		if aaaa, ok := rr.(*AAAA); ok {
+			ips = append(ips, aaaa.AAAA)
+		}
+	}
+	return ips, info, err
+}
+
+// LookupMX returns the MX records of name, sorted by preference.
+func (l *Lookup) LookupMX(ctx context.Context, name string) ([]*MX, *LookupInfo, error) {
+	rrs, info, err := l.Query(ctx, name, TypeMX)
+	var mxs []*MX
+	for _, rr := range rrs {
+		if mx, ok := rr.(*MX); ok {
+			mxs = append(mxs, mx)
+		}
+	}
+	sort.SliceStable(mxs, func(i, j int) bool { return mxs[i].Preference < mxs[j].Preference })
+	return mxs, info, err
+}
+
+// LookupSRV returns the SRV records of _service._proto.name, or of name if
+// service and proto are empty, sorted by priority and then by weight, the
+// heaviest first.
+func (l *Lookup) LookupSRV(ctx context.Context, service, proto, name string) ([]*SRV, *LookupInfo, error) {
+	if service != "" || proto != "" {
+		name = "_" + service + "._" + proto + "." + name
+	}
+	rrs, info, err := l.Query(ctx, name, TypeSRV)
+	var srvs []*SRV
+	for _, rr := range rrs {
+		if srv, ok := rr.(*SRV); ok {
+			srvs = append(srvs, srv)
+		}
+	}
+	sort.SliceStable(srvs, func(i, j int) bool {
+		if srvs[i].Priority != srvs[j].Priority {
+			return srvs[i].Priority < srvs[j].Priority
+		}
+		return srvs[i].Weight > srvs[j].Weight
+	})
+	return srvs, info, err
+}
+
+// LookupTXT returns the TXT records of name, the character-strings of each
+// joined together.
+func (l *Lookup) LookupTXT(ctx context.Context, name string) ([]string, *LookupInfo, error) {
+	rrs, info, err := l.Query(ctx, name, TypeTXT)
+	var txts []string
+	for _, rr := range rrs {
+		t, ok := rr.(*TXT)
+		if !ok {
+			continue
+		}
+		b, berr := txtBytes(t.Txt)
+		if berr != nil {
+			return nil, info, berr
+		}
+		txts = append(txts, string(b))
+	}
+	return txts, info, err
+}
+
+// LookupTLSA returns the TLSA records of the service at port and proto, e.g.
+// "tcp", of name (RFC 6698, Section 3).
+func (l *Lookup) LookupTLSA(ctx context.Context, port uint16, proto, name string) ([]*TLSA, *LookupInfo, error) {
+	rrs, info, err := l.Query(ctx, "_"+strconv.Itoa(int(port))+"._"+proto+"."+name, TypeTLSA)
+	var tlsas []*TLSA
+	for _, rr := range rrs {
+		if tlsa, ok := rr.(*TLSA); ok {
+			tlsas = append(tlsas, tlsa)
+		}
+	}
+	return tlsas, info, err
+}
+
+// LookupHTTPS returns the HTTPS records of name (RFC 9460). This package has
+// no rdata type for them yet, so they are returned as unknown records, with
+// their rdata in hex.
+func (l *Lookup) LookupHTTPS(ctx context.Context, name string) ([]*RFC3597, *LookupInfo, error) {
+	rrs, info, err := l.Query(ctx, name, TypeHTTPS)
+	var https []*RFC3597
+	for _, rr := range rrs {
+		if h, ok := rr.(*RFC3597); ok {
+			https = append(https, h)
+		}
+	}
+	return https, info, err
+}
+
+// LookupNAPTR returns the NAPTR records of name, sorted by order and then by
+// preference.
+func (l *Lookup) LookupNAPTR(ctx context.Context, name string) ([]*NAPTR, *LookupInfo, error) {
+	rrs, info, err := l.Query(ctx, name, TypeNAPTR)
+	var naptrs []*NAPTR
+	for _, rr := range rrs {
+		if naptr, ok := rr.(*NAPTR); ok {
+			naptrs = append(naptrs, naptr)
+		}
+	}
+	sort.SliceStable(naptrs, func(i, j int) bool {
+		if naptrs[i].Order != naptrs[j].Order {
+			return naptrs[i].Order < naptrs[j].Order
+		}
+		return naptrs[i].Preference < naptrs[j].Preference
+	})
+	return naptrs, info, err
+}
+
+// LookupPTR returns the names ip points to, from its PTR records.
+func (l *Lookup) LookupPTR(ctx context.Context, ip net.IP) ([]string, *LookupInfo, error) {
+	name, err := ReverseAddr(ip.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	rrs, info, err := l.Query(ctx, name, TypePTR)
+	var ptrs []string
+	for _, rr := range rrs {
+		if ptr, ok := rr.(*PTR); ok {
+			ptrs = append(ptrs, ptr.Ptr)
+		}
+	}
+	return ptrs, info, err
+}
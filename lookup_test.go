@@ -0,0 +1,138 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lookupZone answers queries from the records of zone, setting the AD bit.
+func lookupZone(t *testing.T, zone ...string) Exchanger {
+	var rrs []RR
+	for _, s := range zone {
+		rrs = append(rrs, testRR(s))
+	}
+	return exchangerFunc(func(m *Msg, address string) (*Msg, time.Duration, error) {
+		r := new(Msg)
+		r.SetReply(m)
+		r.AuthenticatedData = true
+		q := m.Question[0]
+		exists := false
+		for _, rr := range rrs {
+			h := rr.Header()
+			if !equal(h.Name, q.Name) {
+				continue
+			}
+			exists = true
+			if h.Rrtype == q.Qtype || h.Rrtype == TypeCNAME {
+				r.Answer = append(r.Answer, rr)
+			}
+		}
+		if !exists {
+			r.Rcode = RcodeNameError
+		}
+		return r, 0, nil
+	})
+}
+
+func TestLookup(t *testing.T) {
+	l := &Lookup{
+		Exchanger: lookupZone(t,
+			"www.example.org. 300 IN CNAME web.example.net.",
+			"web.example.net. 60 IN A 192.0.2.1",
+			"web.example.net. 60 IN A 192.0.2.2",
+			"example.org. 3600 IN MX 20 mx2.example.org.",
+			"example.org. 3600 IN MX 10 mx1.example.org.",
+			`example.org. 3600 IN TXT "v=spf1 " "-all"`,
+			"_443._tcp.www.example.net. 3600 IN TLSA 3 1 1 0123456789abcdef",
+			`www.example.net. 3600 IN TYPE65 \# 3 000100`,
+			"loop.example.org. 3600 IN CNAME loop.example.org.",
+		),
+		Search: []string{"example.org."},
+		Ndots:  1,
+	}
+	ctx := context.Background()
+
+	ips, info, err := l.LookupA(ctx, "www")
+	if err != nil {
+		t.Fatalf("failed to look up: %v", err)
+	}
+	if len(ips) != 2 || !ips[0].Equal(net.ParseIP("192.0.2.1")) {
+		t.Errorf("unexpected addresses %v", ips)
+	}
+	if info.Name != "web.example.net." || len(info.CNAME) != 1 || info.CNAME[0] != "www.example.org." || info.TTL != 60 || !info.AuthenticatedData {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	mxs, _, err := l.LookupMX(ctx, "example.org.")
+	if err != nil || len(mxs) != 2 || mxs[0].Mx != "mx1.example.org." {
+		t.Errorf("unexpected MX records %v, %v", mxs, err)
+	}
+	txts, _, err := l.LookupTXT(ctx, "example.org.")
+	if err != nil || len(txts) != 1 || txts[0] != "v=spf1 -all" {
+		t.Errorf("unexpected TXT records %q, %v", txts, err)
+	}
+	tlsas, _, err := l.LookupTLSA(ctx, 443, "tcp", "www.example.net.")
+	if err != nil || len(tlsas) != 1 {
+		t.Errorf("unexpected TLSA records %v, %v", tlsas, err)
+	}
+	https, _, err := l.LookupHTTPS(ctx, "www.example.net.")
+	if err != nil || len(https) != 1 || https[0].Rdata != "000100" {
+		t.Errorf("unexpected HTTPS records %v, %v", https, err)
+	}
+
+	// NODATA is no error.
+	if ips, info, err := l.LookupAAAA(ctx, "web.example.net."); err != nil || len(ips) != 0 || info == nil {
+		t.Errorf("expected no addresses without error, got %v, %v", ips, err)
+	}
+	if _, _, err := l.LookupA(ctx, "nx.example.org."); err == nil || !err.(*net.DNSError).IsNotFound {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	if _, _, err := l.LookupA(ctx, "loop.example.org."); err != errLookupCNAME {
+		t.Errorf("expected %v, got %v", errLookupCNAME, err)
+	}
+}
+
+func TestLookupTruncated(t *testing.T) {
+	HandleFunc("example.org.", func(w ResponseWriter, req *Msg) {
+		m := new(Msg)
+		m.SetReply(req)
+		if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+			m.Truncated = true
+		} else {
+			m.Answer = append(m.Answer, testRR("example.org. 300 IN A 192.0.2.1"))
+		}
+		w.WriteMsg(m)
+	})
+	defer HandleRemove("example.org.")
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		t.Skipf("unable to listen on the same TCP port: %v", err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	udp := &Server{PacketConn: pc, NotifyStartedFunc: wg.Done}
+	tcp := &Server{Listener: l, NotifyStartedFunc: wg.Done}
+	go udp.ActivateAndServe()
+	go tcp.ActivateAndServe()
+	wg.Wait()
+	defer udp.Shutdown()
+	defer tcp.Shutdown()
+
+	lk := &Lookup{Exchanger: &Client{Timeout: time.Second}, Address: pc.LocalAddr().String()}
+	ips, _, err := lk.LookupA(context.Background(), "example.org.")
+	if err != nil {
+		t.Fatalf("failed to look up: %v", err)
+	}
+	if len(ips) != 1 {
+		t.Errorf("expected the address over TCP, got %v", ips)
+	}
+}
@@ -0,0 +1,81 @@
+package dns
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// A ServerMetrics collects the metrics of a Server, e.g. for Prometheus, set
+// as Server.Metrics. Its methods are called for every query and response, from
+// many goroutines, they must be safe for concurrent use and should not block.
+type ServerMetrics interface {
+	// QueryReceived is called for every query read, before it is handled.
+	QueryReceived(q *QueryMetric)
+	// ResponseSent is called for every response written.
+	ResponseSent(r *ResponseMetric)
+}
+
+// A QueryMetric describes a query received by a Server. It must not be
+// retained after the call.
+type QueryMetric struct {
+	Net    string // "udp", "tcp" or "tcp-tls"
+	Size   int    // size of the query in bytes
+	Opcode int
+	Qtype  uint16 // type of the first question, 0 without one
+}
+
+// A ResponseMetric describes a response sent by a Server. It must not be
+// retained after the call.
+type ResponseMetric struct {
+	Net       string        // "udp", "tcp" or "tcp-tls"
+	Size      int           // size of the response in bytes
+	Rcode     int           // rcode, with the extended bits of the OPT record when written with WriteMsg
+	Truncated bool          // TC bit
+	Duration  time.Duration // time since the query was read
+}
+
+// network returns the transport of w, for dnstap and metrics.
+func (w *response) network() string {
+	if w.tcp == nil {
+		return "udp"
+	}
+	if _, ok := w.tcp.(*tls.Conn); ok {
+		return "tcp-tls"
+	}
+	return "tcp"
+}
+
+// queryMetric reports the query in w.msg to the metrics of the server.
+func (w *response) queryMetric(dh Header, off int) {
+	q := &QueryMetric{Net: w.network(), Size: len(w.msg), Opcode: int(dh.Bits>>11) & 0xF}
+	if dh.Qdcount > 0 {
+		if question, _, err := unpackQuestion(w.msg, off, nil); err == nil {
+			q.Qtype = question.Qtype
+		}
+	}
+	w.srv.Metrics.QueryReceived(q)
+}
+
+// responseRcode returns the rcode of the response msg, with the extended bits
+// when WriteMsg is writing it.
+func (w *response) responseRcode(msg []byte) int {
+	rcode := RcodeSuccess
+	if len(msg) >= headerSize {
+		rcode = int(msg[3] & 0xF)
+	}
+	if w.rcode&0xF == rcode {
+		rcode = w.rcode
+	}
+	w.rcode = 0
+	return rcode
+}
+
+// responseMetric reports the response msg, with rcode, to the metrics of the
+// server.
+func (w *response) responseMetric(msg []byte, rcode int) {
+	r := &ResponseMetric{Net: w.network(), Size: len(msg), Rcode: rcode, Duration: time.Since(w.queryTime)}
+	if len(msg) >= headerSize {
+		r.Truncated = msg[2]&0x2 != 0
+	}
+	w.srv.Metrics.ResponseSent(r)
+}
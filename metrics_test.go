@@ -0,0 +1,84 @@
+package dns
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	mu        sync.Mutex
+	queries   []QueryMetric
+	responses []ResponseMetric
+}
+
+func (m *testMetrics) QueryReceived(q *QueryMetric) {
+	m.mu.Lock()
+	m.queries = append(m.queries, *q)
+	m.mu.Unlock()
+}
+
+func (m *testMetrics) ResponseSent(r *ResponseMetric) {
+	m.mu.Lock()
+	m.responses = append(m.responses, *r)
+	m.mu.Unlock()
+}
+
+func TestServerMetrics(t *testing.T) {
+	handler := HandlerFunc(func(w ResponseWriter, req *Msg) {
+		m := new(Msg)
+		m.SetRcode(req, RcodeNameError)
+		if req.Question[0].Qtype == TypeMX {
+			m.SetEdns0(4096, false)
+			m.Rcode = RcodeBadCookie
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.WriteMsg(m)
+	})
+
+	for _, network := range []string{"udp", "tcp"} {
+		t.Run(network, func(t *testing.T) {
+			metrics := new(testMetrics)
+			run := RunLocalUDPServerWithFinChan
+			if network == "tcp" {
+				run = RunLocalTCPServerWithFinChan
+			}
+			s, addrstr, _, err := run("127.0.0.1:0", func(srv *Server) {
+				srv.Handler = handler
+				srv.Metrics = metrics
+			})
+			if err != nil {
+				t.Fatalf("unable to run test server: %v", err)
+			}
+			defer s.Shutdown()
+
+			c := &Client{Net: network}
+			for _, qtype := range []uint16{TypeA, TypeMX} {
+				m := new(Msg)
+				m.SetQuestion("miek.nl.", qtype)
+				m.SetEdns0(4096, false)
+				if _, _, err := c.Exchange(m, addrstr); err != nil {
+					t.Fatalf("failed to exchange: %v", err)
+				}
+			}
+
+			metrics.mu.Lock()
+			defer metrics.mu.Unlock()
+			if len(metrics.queries) != 2 || len(metrics.responses) != 2 {
+				t.Fatalf("expected 2 queries and responses, got %d and %d", len(metrics.queries), len(metrics.responses))
+			}
+			for i, want := range []struct {
+				qtype uint16
+				rcode int
+			}{{TypeA, RcodeNameError}, {TypeMX, RcodeBadCookie}} {
+				q, r := metrics.queries[i], metrics.responses[i]
+				if q.Net != network || q.Qtype != want.qtype || q.Opcode != OpcodeQuery || q.Size == 0 {
+					t.Errorf("unexpected query metric %+v", q)
+				}
+				if r.Net != network || r.Rcode != want.rcode || r.Truncated || r.Size == 0 || r.Duration < 10*time.Millisecond {
+					t.Errorf("unexpected response metric %+v", r)
+				}
+			}
+		})
+	}
+}
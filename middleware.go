@@ -0,0 +1,181 @@
+package dns
+
+import (
+	"crypto/tls"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// A Middleware wraps a Handler, and returns a Handler that does some work before
+// and/or after calling it.
+type Middleware func(Handler) Handler
+
+// Chain returns h wrapped by the middlewares mw. The first middleware is the
+// outermost one, it sees the request first and the response last.
+func Chain(h Handler, mw ...Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// ResponseRecorder is a ResponseWriter that passes everything to the
+// ResponseWriter it wraps, and records the response that was written.
+// Middlewares use it to inspect the response of the handler they wrap.
+type ResponseRecorder struct {
+	ResponseWriter
+	Msg   *Msg // message written with WriteMsg, nil when nothing or a raw buffer was written
+	Rcode int  // rcode of the response, -1 when nothing was written
+	Len   int  // length of the response in octets
+	Start time.Time
+}
+
+// NewResponseRecorder returns a ResponseRecorder wrapping w.
+func NewResponseRecorder(w ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w, Rcode: -1, Start: time.Now()}
+}
+
+// Written reports whether a response has been written.
+func (r *ResponseRecorder) Written() bool { return r.Rcode != -1 }
+
+// WriteMsg records m and writes it to the wrapped ResponseWriter.
+func (r *ResponseRecorder) WriteMsg(m *Msg) error {
+	r.Msg, r.Rcode, r.Len = m, m.Rcode, m.Len()
+	return r.ResponseWriter.WriteMsg(m)
+}
+
+// Write records the rcode and length of the raw response m and writes it to the
+// wrapped ResponseWriter.
+func (r *ResponseRecorder) Write(m []byte) (int, error) {
+	r.Rcode, r.Len = RcodeSuccess, len(m)
+	if dh, _, err := unpackMsgHdr(m, 0); err == nil {
+		r.Rcode = int(dh.Bits & 0xF)
+	}
+	return r.ResponseWriter.Write(m)
+}
+
+// ConnectionState returns the TLS connection state of the wrapped
+// ResponseWriter, or nil when it has none.
+func (r *ResponseRecorder) ConnectionState() *tls.ConnectionState {
+	if cs, ok := r.ResponseWriter.(ConnectionStater); ok {
+		return cs.ConnectionState()
+	}
+	return nil
+}
+
+// MaxMsgSize returns the maximum message size of the wrapped ResponseWriter,
+// see MsgStreamer.
+func (r *ResponseRecorder) MaxMsgSize() int {
+	size, _ := msgStreamer(r.ResponseWriter)
+	return size
+}
+
+// CanStream reports whether the wrapped ResponseWriter can write a response
+// in several messages, see MsgStreamer.
+func (r *ResponseRecorder) CanStream() bool {
+	_, stream := msgStreamer(r.ResponseWriter)
+	return stream
+}
+
+// LoggingMiddleware logs every request to l, one line with the client, the
+// question, the rcode of the response and the time it took.
+func LoggingMiddleware(l *log.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Msg) {
+			rec := NewResponseRecorder(w)
+			next.ServeDNS(rec, r)
+
+			q := "-"
+			if len(r.Question) > 0 {
+				q = Class(r.Question[0].Qclass).String() + " " + Type(r.Question[0].Qtype).String() + " " + r.Question[0].Name
+			}
+			rcode := "-"
+			if rec.Written() {
+				rcode = RcodeToString[rec.Rcode]
+			}
+			l.Printf("%s %d %s %s %d %s", w.RemoteAddr(), r.Id, q, rcode, rec.Len, time.Since(rec.Start))
+		})
+	}
+}
+
+// RecoveryMiddleware recovers from panics in the handler. The panic is logged
+// to l, with a stack trace, and when the handler did not write a response yet
+// SERVFAIL is returned. If l is nil the panic is not logged.
+func RecoveryMiddleware(l *log.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Msg) {
+			rec := NewResponseRecorder(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if l != nil {
+					l.Printf("dns: panic serving %s: %v\n%s", w.RemoteAddr(), v, debug.Stack())
+				}
+				if !rec.Written() {
+					HandleFailed(w, r)
+				}
+			}()
+			next.ServeDNS(rec, r)
+		})
+	}
+}
+
+// EDNSMiddleware normalizes the EDNS0 in responses (RFC 6891). Requests with an
+// unsupported EDNS version get a BADVERS response, without calling the handler.
+// Responses to requests without EDNS0 have any OPT record removed, responses to
+// requests with EDNS0 get an OPT record with an advertised UDP size of udpSize,
+// and the DO bit copied from the request.
+func EDNSMiddleware(udpSize uint16) Middleware {
+	if udpSize < MinMsgSize {
+		udpSize = MinMsgSize
+	}
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Msg) {
+			opt := r.IsEdns0()
+			if opt != nil && opt.Version() != 0 {
+				m := new(Msg)
+				m.SetRcode(r, RcodeBadVers)
+				m.SetEdns0(udpSize, opt.Do())
+				w.WriteMsg(m)
+				return
+			}
+			next.ServeDNS(&ednsWriter{ResponseWriter: w, opt: opt, udpSize: udpSize}, r)
+		})
+	}
+}
+
+type ednsWriter struct {
+	ResponseWriter
+	opt     *OPT // of the request
+	udpSize uint16
+}
+
+// WriteMsg normalizes the OPT record in m before writing it.
+func (w *ednsWriter) WriteMsg(m *Msg) error {
+	normalizeEDNS(m, w.opt, w.udpSize)
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// ConnectionState returns the TLS connection state of the wrapped
+// ResponseWriter, or nil when it has none.
+func (w *ednsWriter) ConnectionState() *tls.ConnectionState {
+	if cs, ok := w.ResponseWriter.(ConnectionStater); ok {
+		return cs.ConnectionState()
+	}
+	return nil
+}
+
+// MaxMsgSize returns the maximum message size of the wrapped ResponseWriter.
+func (w *ednsWriter) MaxMsgSize() int {
+	size, _ := msgStreamer(w.ResponseWriter)
+	return size
+}
+
+// CanStream reports whether the wrapped ResponseWriter can stream responses.
+func (w *ednsWriter) CanStream() bool {
+	_, stream := msgStreamer(w.ResponseWriter)
+	return stream
+}
@@ -0,0 +1,170 @@
+package dns
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"strings"
+	"testing"
+)
+
+// recordWriter is a ResponseWriter that keeps the messages written to it.
+type recordWriter struct {
+	msgs []*Msg
+}
+
+func (w *recordWriter) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53}
+}
+func (w *recordWriter) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5353}
+}
+func (w *recordWriter) WriteMsg(m *Msg) error       { w.msgs = append(w.msgs, m); return nil }
+func (w *recordWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *recordWriter) Close() error                { return nil }
+func (w *recordWriter) TsigStatus() error           { return nil }
+func (w *recordWriter) TsigTimersOnly(bool)         {}
+func (w *recordWriter) Hijack()                     {}
+
+func TestChain(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(w ResponseWriter, r *Msg) {
+				order = append(order, name)
+				next.ServeDNS(w, r)
+				order = append(order, "/"+name)
+			})
+		}
+	}
+
+	mux := NewServeMux()
+	mux.HandleFunc("miek.nl.", func(w ResponseWriter, r *Msg) { order = append(order, "handler") })
+	mux.Use(mw("a"), mw("b"))
+	mux.Use(mw("c"))
+
+	m := new(Msg)
+	m.SetQuestion("www.miek.nl.", TypeA)
+	mux.ServeDNS(new(recordWriter), m)
+
+	if s := strings.Join(order, " "); s != "a b c handler /c /b /a" {
+		t.Errorf("unexpected middleware order: %s", s)
+	}
+
+	// The middlewares also wrap the SERVFAIL handler.
+	order = nil
+	w := new(recordWriter)
+	m.SetQuestion("example.org.", TypeA)
+	mux.ServeDNS(w, m)
+	if s := strings.Join(order, " "); s != "a b c /c /b /a" {
+		t.Errorf("unexpected middleware order: %s", s)
+	}
+	if len(w.msgs) != 1 || w.msgs[0].Rcode != RcodeServerFailure {
+		t.Errorf("expected SERVFAIL, got %v", w.msgs)
+	}
+}
+
+func TestChainBuiltOnce(t *testing.T) {
+	built := 0
+	mw := func(next Handler) Handler {
+		built++
+		return next
+	}
+
+	mux := NewServeMux()
+	mux.Use(mw)
+	mux.HandleFunc("miek.nl.", HelloServer)
+
+	m := new(Msg)
+	m.SetQuestion("www.miek.nl.", TypeTXT)
+	for i := 0; i < 3; i++ {
+		mux.ServeDNS(new(recordWriter), m)
+	}
+	m.SetQuestion("example.org.", TypeTXT)
+	mux.ServeDNS(new(recordWriter), m)
+
+	// Once for the SERVFAIL handler on Use, once for the handler on HandleFunc.
+	if built != 2 {
+		t.Errorf("expected the chain to be built 2 times, got %d", built)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := Chain(HandlerFunc(HelloServer), LoggingMiddleware(log.New(buf, "", 0)))
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	m.Id = 1234
+	h.ServeDNS(new(recordWriter), m)
+
+	if l := buf.String(); !strings.HasPrefix(l, "192.0.2.1:5353 1234 IN TXT miek.nl. NOERROR ") {
+		t.Errorf("unexpected log line %q", l)
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := Chain(HandlerFunc(func(w ResponseWriter, r *Msg) { panic("boom") }), RecoveryMiddleware(log.New(buf, "", 0)))
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	w := new(recordWriter)
+	h.ServeDNS(w, m)
+
+	if len(w.msgs) != 1 || w.msgs[0].Rcode != RcodeServerFailure {
+		t.Errorf("expected SERVFAIL, got %v", w.msgs)
+	}
+	if !strings.Contains(buf.String(), "panic serving 192.0.2.1:5353: boom") {
+		t.Errorf("panic not logged: %q", buf.String())
+	}
+
+	// No second response when the handler already wrote one.
+	h = Chain(HandlerFunc(func(w ResponseWriter, r *Msg) {
+		HelloServer(w, r)
+		panic("boom")
+	}), RecoveryMiddleware(nil))
+	w = new(recordWriter)
+	h.ServeDNS(w, m)
+	if len(w.msgs) != 1 || w.msgs[0].Rcode != RcodeSuccess {
+		t.Errorf("expected a single NOERROR response, got %v", w.msgs)
+	}
+}
+
+func TestEDNSMiddleware(t *testing.T) {
+	h := Chain(HandlerFunc(func(w ResponseWriter, r *Msg) {
+		m := new(Msg)
+		m.SetReply(r)
+		m.SetEdns0(65535, false)
+		m.SetEdns0(4096, false)
+		m.SetTsig("axfr.", HmacMD5, 300, 0)
+		w.WriteMsg(m)
+	}), EDNSMiddleware(1232))
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	w := new(recordWriter)
+	h.ServeDNS(w, m)
+	if r := w.msgs[0]; r.IsEdns0() != nil || len(r.Extra) != 1 {
+		t.Errorf("expected OPT to be removed, got %v", r.Extra)
+	}
+
+	m.SetEdns0(4096, true)
+	w = new(recordWriter)
+	h.ServeDNS(w, m)
+	r := w.msgs[0]
+	if len(r.Extra) != 2 || r.IsTsig() == nil {
+		t.Fatalf("expected OPT and TSIG, got %v", r.Extra)
+	}
+	if opt := r.IsEdns0(); opt.UDPSize() != 1232 || !opt.Do() {
+		t.Errorf("expected UDP size 1232 and DO, got %s", opt)
+	}
+
+	m.IsEdns0().SetVersion(1)
+	w = new(recordWriter)
+	h.ServeDNS(w, m)
+	r = w.msgs[0]
+	if r.Rcode != RcodeBadVers || r.IsEdns0() == nil || r.IsEdns0().Version() != 0 {
+		t.Errorf("expected BADVERS, got %v", r)
+	}
+}
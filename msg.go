@@ -52,11 +52,13 @@ const (
 var (
 	ErrAlg           error = &Error{err: "bad algorithm"}                  // ErrAlg indicates an error with the (DNSSEC) algorithm.
 	ErrAuth          error = &Error{err: "bad authentication"}             // ErrAuth indicates an error in the TSIG authentication.
+	ErrBadTrunc      error = &Error{err: "bad truncation"}                 // ErrBadTrunc indicates a TSIG MAC that is truncated too much.
 	ErrBuf           error = &Error{err: "buffer size too small"}          // ErrBuf indicates that the buffer used is too small for the message.
 	ErrConnEmpty     error = &Error{err: "conn has no connection"}         // ErrConnEmpty indicates a connection is being used before it is initialized.
 	ErrExtendedRcode error = &Error{err: "bad extended rcode"}             // ErrExtendedRcode ...
 	ErrFqdn          error = &Error{err: "domain must be fully qualified"} // ErrFqdn indicates that a domain name does not have a closing dot.
 	ErrId            error = &Error{err: "id mismatch"}                    // ErrId indicates there is a mismatch with the message's ID.
+	ErrQuestion      error = &Error{err: "question mismatch"}              // ErrQuestion indicates the question of a response doesn't match the query's.
 	ErrKeyAlg        error = &Error{err: "bad key algorithm"}              // ErrKeyAlg indicates that the algorithm in the key is not valid.
 	ErrKey           error = &Error{err: "bad key"}
 	ErrKeySize       error = &Error{err: "bad key size"}
@@ -67,10 +69,12 @@ var (
 	ErrRdata         error = &Error{err: "bad rdata"}
 	ErrRRset         error = &Error{err: "bad rrset"}
 	ErrSecret        error = &Error{err: "no secrets defined"}
+	ErrSectionDone   error = &Error{err: "parsing of section is done"} // ErrSectionDone indicates that all records of a section have been parsed.
 	ErrShortRead     error = &Error{err: "short read"}
-	ErrSig           error = &Error{err: "bad signature"} // ErrSig indicates that a signature can not be cryptographically validated.
-	ErrSoa           error = &Error{err: "no SOA"}        // ErrSOA indicates that no SOA RR was seen when doing zone transfers.
-	ErrTime          error = &Error{err: "bad time"}      // ErrTime indicates a timing error in TSIG authentication.
+	ErrSig           error = &Error{err: "bad signature"}     // ErrSig indicates that a signature can not be cryptographically validated.
+	ErrSoa           error = &Error{err: "no SOA"}            // ErrSOA indicates that no SOA RR was seen when doing zone transfers.
+	ErrTime          error = &Error{err: "bad time"}          // ErrTime indicates a timing error in TSIG authentication.
+	ErrTruncated     error = &Error{err: "message truncated"} // ErrTruncated indicates that a record doesn't fit in the message size.
 )
 
 // Id by default, returns a 16 bits random number to be used as a
@@ -140,11 +144,12 @@ type MsgHdr struct {
 // Msg contains the layout of a DNS message.
 type Msg struct {
 	MsgHdr
-	Compress bool       `json:"-"` // If true, the message will be compressed when converted to wire format.
-	Question []Question // Holds the RR(s) of the question section.
-	Answer   []RR       // Holds the RR(s) of the answer section.
-	Ns       []RR       // Holds the RR(s) of the authority section.
-	Extra    []RR       // Holds the RR(s) of the additional section.
+	Compress       bool           `json:"-"` // If true, the message will be compressed when converted to wire format.
+	CompressPolicy CompressPolicy `json:"-"` // Names compressed when Compress is true, all of them by default.
+	Question       []Question     // Holds the RR(s) of the question section.
+	Answer         []RR           // Holds the RR(s) of the answer section.
+	Ns             []RR           // Holds the RR(s) of the authority section.
+	Extra          []RR           // Holds the RR(s) of the additional section.
 }
 
 // ClassToString is a maps Classes to strings for each CLASS wire type.
@@ -159,27 +164,29 @@ var ClassToString = map[uint16]string{
 
 // OpcodeToString maps Opcodes to strings.
 var OpcodeToString = map[int]string{
-	OpcodeQuery:  "QUERY",
-	OpcodeIQuery: "IQUERY",
-	OpcodeStatus: "STATUS",
-	OpcodeNotify: "NOTIFY",
-	OpcodeUpdate: "UPDATE",
+	OpcodeQuery:    "QUERY",
+	OpcodeIQuery:   "IQUERY",
+	OpcodeStatus:   "STATUS",
+	OpcodeNotify:   "NOTIFY",
+	OpcodeUpdate:   "UPDATE",
+	OpcodeStateful: "DSO",
 }
 
 // RcodeToString maps Rcodes to strings.
 var RcodeToString = map[int]string{
-	RcodeSuccess:        "NOERROR",
-	RcodeFormatError:    "FORMERR",
-	RcodeServerFailure:  "SERVFAIL",
-	RcodeNameError:      "NXDOMAIN",
-	RcodeNotImplemented: "NOTIMP",
-	RcodeRefused:        "REFUSED",
-	RcodeYXDomain:       "YXDOMAIN", // See RFC 2136
-	RcodeYXRrset:        "YXRRSET",
-	RcodeNXRrset:        "NXRRSET",
-	RcodeNotAuth:        "NOTAUTH",
-	RcodeNotZone:        "NOTZONE",
-	RcodeBadSig:         "BADSIG", // Also known as RcodeBadVers, see RFC 6891
+	RcodeSuccess:                    "NOERROR",
+	RcodeFormatError:                "FORMERR",
+	RcodeServerFailure:              "SERVFAIL",
+	RcodeNameError:                  "NXDOMAIN",
+	RcodeNotImplemented:             "NOTIMP",
+	RcodeRefused:                    "REFUSED",
+	RcodeYXDomain:                   "YXDOMAIN", // See RFC 2136
+	RcodeYXRrset:                    "YXRRSET",
+	RcodeNXRrset:                    "NXRRSET",
+	RcodeNotAuth:                    "NOTAUTH",
+	RcodeNotZone:                    "NOTZONE",
+	RcodeStatefulTypeNotImplemented: "DSOTYPENI",
+	RcodeBadSig:                     "BADSIG", // Also known as RcodeBadVers, see RFC 6891
 	//	RcodeBadVers:        "BADVERS",
 	RcodeBadKey:    "BADKEY",
 	RcodeBadTime:   "BADTIME",
@@ -271,6 +278,7 @@ func packDomainName(s string, msg []byte, off int, compression compressionMap, c
 		compOff   int
 		bs        []byte
 		wasDot    bool
+		stack     [256]byte // holds bs for most escaped names, to not allocate
 	)
 loop:
 	for i := 0; i < ls; i++ {
@@ -288,7 +296,11 @@ loop:
 			}
 
 			if bs == nil {
-				bs = []byte(s)
+				if ls <= len(stack) {
+					bs = stack[:copy(stack[:], s)]
+				} else {
+					bs = []byte(s)
+				}
 			}
 
 			// check for \DDD
@@ -415,7 +427,23 @@ func isRootLabel(s string, bs []byte, off, end int) bool {
 // When an error is encountered, the unpacked name will be discarded
 // and len(msg) will be returned as the offset.
 func UnpackDomainName(msg []byte, off int) (string, int, error) {
-	s := make([]byte, 0, maxDomainNamePresentationLength)
+	var buf [maxDomainNamePresentationLength]byte
+	s, off1, err := AppendDomainName(buf[:0], msg, off)
+	if err != nil {
+		return "", off1, err
+	}
+	if len(s) == 1 {
+		return ".", off1, nil
+	}
+	return string(s), off1, nil
+}
+
+// AppendDomainName is like UnpackDomainName, but appends the name to buf
+// instead of returning it as a string. Unpacking in a reused buffer doesn't
+// allocate.
+func AppendDomainName(buf, msg []byte, off int) ([]byte, int, error) {
+	s := buf
+	start := len(buf)
 	off1 := 0
 	lenmsg := len(msg)
 	budget := maxDomainNameWireOctets
@@ -423,7 +451,7 @@ func UnpackDomainName(msg []byte, off int) (string, int, error) {
 Loop:
 	for {
 		if off >= lenmsg {
-			return "", lenmsg, ErrBuf
+			return buf, lenmsg, ErrBuf
 		}
 		c := int(msg[off])
 		off++
@@ -435,11 +463,11 @@ Loop:
 			}
 			// literal string
 			if off+c > lenmsg {
-				return "", lenmsg, ErrBuf
+				return buf, lenmsg, ErrBuf
 			}
 			budget -= c + 1 // +1 for the label separator
 			if budget <= 0 {
-				return "", lenmsg, ErrLongDomain
+				return buf, lenmsg, ErrLongDomain
 			}
 			for j := off; j < off+c; j++ {
 				switch b := msg[j]; b {
@@ -464,7 +492,7 @@ Loop:
 			// also, don't follow too many pointers --
 			// maybe there's a loop.
 			if off >= lenmsg {
-				return "", lenmsg, ErrBuf
+				return buf, lenmsg, ErrBuf
 			}
 			c1 := msg[off]
 			off++
@@ -472,7 +500,7 @@ Loop:
 				off1 = off
 			}
 			if ptr++; ptr > maxCompressionPointers {
-				return "", lenmsg, &Error{err: "too many compression pointers"}
+				return buf, lenmsg, &Error{err: "too many compression pointers"}
 			}
 			// pointer should guarantee that it advances and points forwards at least
 			// but the condition on previous three lines guarantees that it's
@@ -480,16 +508,16 @@ Loop:
 			off = (c^0xC0)<<8 | int(c1)
 		default:
 			// 0x80 and 0x40 are reserved
-			return "", lenmsg, ErrRdata
+			return buf, lenmsg, ErrRdata
 		}
 	}
 	if ptr == 0 {
 		off1 = off
 	}
-	if len(s) == 0 {
-		return ".", off1, nil
+	if len(s) == start {
+		s = append(s, '.')
 	}
-	return string(s), off1, nil
+	return s, off1, nil
 }
 
 func packTxt(txt []string, msg []byte, offset int, tmp []byte) (int, error) {
@@ -650,7 +678,12 @@ func packRR(rr RR, msg []byte, off int, compression compressionMap, compress boo
 
 // UnpackRR unpacks msg[off:] into an RR.
 func UnpackRR(msg []byte, off int) (rr RR, off1 int, err error) {
-	h, off, msg, err := unpackHeader(msg, off)
+	return unpackRR(msg, off, nil)
+}
+
+// unpackRR is UnpackRR, with the owner name looked up in names.
+func unpackRR(msg []byte, off int, names *nameCache) (rr RR, off1 int, err error) {
+	h, off, msg, err := unpackHeader(msg, off, names)
 	if err != nil {
 		return nil, len(msg), err
 	}
@@ -677,13 +710,21 @@ func UnpackRRWithHeader(h RR_Header, msg []byte, off int) (rr RR, off1 int, err
 // unpackRRslice unpacks msg[off:] into an []RR.
 // If we cannot unpack the whole array, then it will return nil
 func unpackRRslice(l int, msg []byte, off int) (dst1 []RR, off1 int, err error) {
-	var r RR
 	// Don't pre-allocate, l may be under attacker control
-	var dst []RR
+	return appendRRslice(nil, l, msg, off, nil, "")
+}
+
+// appendRRslice is like unpackRRslice, but appends the records to dst. The
+// owner names are looked up in names, which may be nil.
+func appendRRslice(dst []RR, l int, msg []byte, off int, names *nameCache, section string) (dst1 []RR, off1 int, err error) {
+	var r RR
 	for i := 0; i < l; i++ {
 		off1 := off
-		r, off, err = UnpackRR(msg, off)
+		r, off, err = unpackRR(msg, off, names)
 		if err != nil {
+			if section != "" {
+				err = unpackError(err, msg, section, i, off1)
+			}
 			off = len(msg)
 			break
 		}
@@ -745,7 +786,8 @@ func (h *MsgHdr) String() string {
 }
 
 // Pack packs a Msg: it is converted to to wire format.
-// If the dns.Compress is true the message will be in compressed wire format.
+// If the dns.Compress is true the message will be in compressed wire format,
+// the names compressed being selected by dns.CompressPolicy.
 func (dns *Msg) Pack() (msg []byte, err error) {
 	return dns.PackBuffer(nil)
 }
@@ -754,7 +796,7 @@ func (dns *Msg) Pack() (msg []byte, err error) {
 func (dns *Msg) PackBuffer(buf []byte) (msg []byte, err error) {
 	// If this message can't be compressed, avoid filling the
 	// compression map and creating garbage.
-	if dns.Compress && dns.isCompressible() {
+	if dns.compress() {
 		compression := make(map[string]uint16) // Compression pointer mappings.
 		return dns.packBufferWithCompressionMap(buf, compressionMap{int: compression}, true)
 	}
@@ -762,6 +804,38 @@ func (dns *Msg) PackBuffer(buf []byte) (msg []byte, err error) {
 	return dns.packBufferWithCompressionMap(buf, compressionMap{}, false)
 }
 
+// A Compression holds the compression pointers of a message being packed. A
+// server packing many responses can reuse one with PackBufferCompression, its
+// memory is then allocated once instead of for every message. A Compression
+// is not safe for concurrent use, but can be kept in a sync.Pool.
+//
+// The zero Compression is ready for use.
+type Compression struct {
+	m map[string]uint16
+}
+
+// Reset empties c, dropping its references to the names of the last message
+// packed.
+func (c *Compression) Reset() {
+	for name := range c.m {
+		delete(c.m, name)
+	}
+}
+
+// PackBufferCompression is like PackBuffer, but uses c for the compression
+// pointers, it is reset first.
+func (dns *Msg) PackBufferCompression(buf []byte, c *Compression) (msg []byte, err error) {
+	if !dns.compress() {
+		return dns.packBufferWithCompressionMap(buf, compressionMap{}, false)
+	}
+	if c.m == nil {
+		c.m = make(map[string]uint16)
+	} else {
+		c.Reset()
+	}
+	return dns.packBufferWithCompressionMap(buf, compressionMap{int: c.m}, true)
+}
+
 // packBufferWithCompressionMap packs a Msg, using the given buffer buf.
 func (dns *Msg) packBufferWithCompressionMap(buf []byte, compression compressionMap, compress bool) (msg []byte, err error) {
 	if dns.Rcode < 0 || dns.Rcode > 0xFFF {
@@ -780,32 +854,7 @@ func (dns *Msg) packBufferWithCompressionMap(buf []byte, compression compression
 	// Convert convenient Msg into wire-like Header.
 	var dh Header
 	dh.Id = dns.Id
-	dh.Bits = uint16(dns.Opcode)<<11 | uint16(dns.Rcode&0xF)
-	if dns.Response {
-		dh.Bits |= _QR
-	}
-	if dns.Authoritative {
-		dh.Bits |= _AA
-	}
-	if dns.Truncated {
-		dh.Bits |= _TC
-	}
-	if dns.RecursionDesired {
-		dh.Bits |= _RD
-	}
-	if dns.RecursionAvailable {
-		dh.Bits |= _RA
-	}
-	if dns.Zero {
-		dh.Bits |= _Z
-	}
-	if dns.AuthenticatedData {
-		dh.Bits |= _AD
-	}
-	if dns.CheckingDisabled {
-		dh.Bits |= _CD
-	}
-
+	dh.Bits = dns.MsgHdr.bits()
 	dh.Qdcount = uint16(len(dns.Question))
 	dh.Ancount = uint16(len(dns.Answer))
 	dh.Nscount = uint16(len(dns.Ns))
@@ -831,19 +880,19 @@ func (dns *Msg) packBufferWithCompressionMap(buf []byte, compression compression
 		}
 	}
 	for _, r := range dns.Answer {
-		_, off, err = packRR(r, msg, off, compression, compress)
+		_, off, err = packRRPolicy(r, msg, off, compression, compress, dns.CompressPolicy)
 		if err != nil {
 			return nil, err
 		}
 	}
 	for _, r := range dns.Ns {
-		_, off, err = packRR(r, msg, off, compression, compress)
+		_, off, err = packRRPolicy(r, msg, off, compression, compress, dns.CompressPolicy)
 		if err != nil {
 			return nil, err
 		}
 	}
 	for _, r := range dns.Extra {
-		_, off, err = packRR(r, msg, off, compression, compress)
+		_, off, err = packRRPolicy(r, msg, off, compression, compress, dns.CompressPolicy)
 		if err != nil {
 			return nil, err
 		}
@@ -851,26 +900,33 @@ func (dns *Msg) packBufferWithCompressionMap(buf []byte, compression compression
 	return msg[:off], nil
 }
 
-func (dns *Msg) unpack(dh Header, msg []byte, off int) (err error) {
+// unpack unpacks the sections of msg. If reuse is true the records are appended
+// to the (emptied) existing section slices, instead of newly allocated ones.
+func (dns *Msg) unpack(dh Header, msg []byte, off int, reuse bool) (err error) {
+	// reset sections before unpacking
+	if reuse {
+		dns.Question, dns.Answer, dns.Ns, dns.Extra = dns.Question[:0], clearRRs(dns.Answer), clearRRs(dns.Ns), clearRRs(dns.Extra)
+	} else {
+		dns.Question, dns.Answer, dns.Ns, dns.Extra = nil, nil, nil, nil
+	}
+
 	// If we are at the end of the message we should return *just* the
 	// header. This can still be useful to the caller. 9.9.9.9 sends these
 	// when responding with REFUSED for instance.
 	if off == len(msg) {
-		// reset sections before returning
-		dns.Question, dns.Answer, dns.Ns, dns.Extra = nil, nil, nil, nil
 		return nil
 	}
 
 	// Qdcount, Ancount, Nscount, Arcount can't be trusted, as they are
 	// attacker controlled. This means we can't use them to pre-allocate
 	// slices.
-	dns.Question = nil
+	names := new(nameCache)
 	for i := 0; i < int(dh.Qdcount); i++ {
 		off1 := off
 		var q Question
-		q, off, err = unpackQuestion(msg, off)
+		q, off, err = unpackQuestion(msg, off, names)
 		if err != nil {
-			return err
+			return unpackError(err, msg, "question", i, off1)
 		}
 		if off1 == off { // Offset does not increase anymore, dh.Qdcount is a lie!
 			dh.Qdcount = uint16(i)
@@ -879,16 +935,16 @@ func (dns *Msg) unpack(dh Header, msg []byte, off int) (err error) {
 		dns.Question = append(dns.Question, q)
 	}
 
-	dns.Answer, off, err = unpackRRslice(int(dh.Ancount), msg, off)
+	dns.Answer, off, err = appendRRslice(dns.Answer, int(dh.Ancount), msg, off, names, "answer")
 	// The header counts might have been wrong so we need to update it
 	dh.Ancount = uint16(len(dns.Answer))
 	if err == nil {
-		dns.Ns, off, err = unpackRRslice(int(dh.Nscount), msg, off)
+		dns.Ns, off, err = appendRRslice(dns.Ns, int(dh.Nscount), msg, off, names, "authority")
 	}
 	// The header counts might have been wrong so we need to update it
 	dh.Nscount = uint16(len(dns.Ns))
 	if err == nil {
-		dns.Extra, off, err = unpackRRslice(int(dh.Arcount), msg, off)
+		dns.Extra, off, err = appendRRslice(dns.Extra, int(dh.Arcount), msg, off, names, "additional")
 	}
 	// The header counts might have been wrong so we need to update it
 	dh.Arcount = uint16(len(dns.Extra))
@@ -903,19 +959,60 @@ func (dns *Msg) unpack(dh Header, msg []byte, off int) (err error) {
 		// use PackOpt to let people tell how detailed the error reporting should be?
 		// println("dns: extra bytes in dns packet", off, "<", len(msg))
 	}
+	if e, ok := err.(*UnpackError); ok && len(dns.Question) > 0 {
+		e.Name = dns.Question[0].Name
+	}
 	return err
 
 }
 
 // Unpack unpacks a binary message to a Msg structure.
 func (dns *Msg) Unpack(msg []byte) (err error) {
+	return unwrapUnpackError(dns.unpackMsg(msg, false))
+}
+
+// UnpackBuffer is like Unpack, but reuses the section slices of dns when
+// they have enough capacity, instead of allocating new ones. Together with
+// Reset this allows messages to be kept in a sync.Pool. The records themselves
+// are always newly allocated, so records held from a previous use of dns stay
+// valid.
+func (dns *Msg) UnpackBuffer(msg []byte) error {
+	return unwrapUnpackError(dns.unpackMsg(msg, true))
+}
+
+// unpackMsg is Unpack, or UnpackBuffer if reuse is true, but returns the
+// errors as an *UnpackError telling where they are.
+func (dns *Msg) unpackMsg(msg []byte, reuse bool) error {
 	dh, off, err := unpackMsgHdr(msg, 0)
 	if err != nil {
-		return err
+		return &UnpackError{Err: err}
 	}
 
 	dns.setHdr(dh)
-	return dns.unpack(dh, msg, off)
+	return dns.unpack(dh, msg, off, reuse)
+}
+
+// Reset clears dns, so it can be reused. The section slices are kept, with
+// their length set to zero, to avoid allocations when the message is filled
+// again. A typical use is a server keeping its messages in a sync.Pool:
+//
+//	m := pool.Get().(*dns.Msg)
+//	defer func() { m.Reset(); pool.Put(m) }()
+//	if err := m.UnpackBuffer(buf); err != nil {
+//		...
+//	}
+func (dns *Msg) Reset() {
+	question, answer, ns, extra := dns.Question[:0], clearRRs(dns.Answer), clearRRs(dns.Ns), clearRRs(dns.Extra)
+	*dns = Msg{Question: question, Answer: answer, Ns: ns, Extra: extra}
+}
+
+// clearRRs returns s with length zero. The records are set to nil so they can
+// be garbage collected.
+func clearRRs(s []RR) []RR {
+	for i := range s {
+		s[i] = nil
+	}
+	return s[:0]
 }
 
 // Convert a complete message to a string with dig-like output.
@@ -969,13 +1066,16 @@ func (dns *Msg) isCompressible() bool {
 }
 
 // Len returns the message length when in (un)compressed wire format.
-// If dns.Compress is true compression it is taken into account. Len()
+// If dns.Compress is true compression it is taken into account, following
+// dns.CompressPolicy. Len()
 // is provided to be a faster way to get the size of the resulting packet,
-// than packing it, measuring the size and discarding the buffer.
+// than packing it, measuring the size and discarding the buffer. It follows
+// the compression done by Pack, and returns exactly the length of the packed
+// message.
 func (dns *Msg) Len() int {
 	// If this message can't be compressed, avoid filling the
 	// compression map and creating garbage.
-	if dns.Compress && dns.isCompressible() {
+	if dns.compress() {
 		compression := make(map[string]struct{})
 		return msgLenWithCompressionMap(dns, compression)
 	}
@@ -991,17 +1091,17 @@ func msgLenWithCompressionMap(dns *Msg, compression map[string]struct{}) int {
 	}
 	for _, r := range dns.Answer {
 		if r != nil {
-			l += r.len(l, compression)
+			l += lenPolicy(r, l, compression, dns.CompressPolicy)
 		}
 	}
 	for _, r := range dns.Ns {
 		if r != nil {
-			l += r.len(l, compression)
+			l += lenPolicy(r, l, compression, dns.CompressPolicy)
 		}
 	}
 	for _, r := range dns.Extra {
 		if r != nil {
-			l += r.len(l, compression)
+			l += lenPolicy(r, l, compression, dns.CompressPolicy)
 		}
 	}
 
@@ -1009,16 +1109,23 @@ func msgLenWithCompressionMap(dns *Msg, compression map[string]struct{}) int {
 }
 
 func domainNameLen(s string, off int, compression map[string]struct{}, compress bool) int {
-	if s == "" || s == "." {
+	if s == "" {
+		// Packed as nothing, as the rdata of update RRs.
+		return 0
+	}
+	if s == "." {
 		return 1
 	}
 
 	escaped := strings.Contains(s, "\\")
 
 	if compression != nil && (compress || off < maxCompressionOffset) {
-		// compressionLenSearch will insert the entry into the compression
-		// map if it doesn't contain it.
-		if l, ok := compressionLenSearch(compression, s, off); ok && compress {
+		// Like packDomainName, the suffixes of s that aren't in the
+		// compression map are inserted in it, and the name is
+		// compressed at the first one that is, when compress is true.
+		if !compress {
+			compressionLenInsert(compression, s, off)
+		} else if l, ok := compressionLenSearch(compression, s, off); ok {
 			if escaped {
 				return escapedNameLen(s[:l]) + 2
 			}
@@ -1053,13 +1160,17 @@ func escapedNameLen(s string) int {
 	return nameLen
 }
 
+// compressionLenSearch returns the offset in s of its longest suffix in c,
+// inserting the longer ones that start before maxCompressionOffset, msgOff
+// being the offset of s in the message.
 func compressionLenSearch(c map[string]struct{}, s string, msgOff int) (int, bool) {
+	escaped := strings.Contains(s, "\\")
 	for off, end := 0, false; !end; off, end = NextLabel(s, off) {
 		if _, ok := c[s[off:]]; ok {
 			return off, true
 		}
 
-		if msgOff+off < maxCompressionOffset {
+		if compressionLenOff(s, off, escaped)+msgOff < maxCompressionOffset {
 			c[s[off:]] = struct{}{}
 		}
 	}
@@ -1067,6 +1178,30 @@ func compressionLenSearch(c map[string]struct{}, s string, msgOff int) (int, boo
 	return 0, false
 }
 
+// compressionLenInsert inserts the suffixes of s that are not in c and start
+// before maxCompressionOffset, as packDomainName does for names it may not
+// compress.
+func compressionLenInsert(c map[string]struct{}, s string, msgOff int) {
+	escaped := strings.Contains(s, "\\")
+	for off, end := 0, false; !end; off, end = NextLabel(s, off) {
+		if _, ok := c[s[off:]]; ok {
+			continue
+		}
+
+		if compressionLenOff(s, off, escaped)+msgOff < maxCompressionOffset {
+			c[s[off:]] = struct{}{}
+		}
+	}
+}
+
+// compressionLenOff returns the wire format offset of the label at off in s.
+func compressionLenOff(s string, off int, escaped bool) int {
+	if escaped {
+		return escapedNameLen(s[:off])
+	}
+	return off
+}
+
 // Copy returns a new RR which is a deep-copy of r.
 func Copy(r RR) RR { r1 := r.copy(); return r1 }
 
@@ -1080,6 +1215,7 @@ func (dns *Msg) Copy() *Msg { return dns.CopyTo(new(Msg)) }
 func (dns *Msg) CopyTo(r1 *Msg) *Msg {
 	r1.MsgHdr = dns.MsgHdr
 	r1.Compress = dns.Compress
+	r1.CompressPolicy = dns.CompressPolicy
 
 	if len(dns.Question) > 0 {
 		r1.Question = make([]Question, len(dns.Question))
@@ -1135,12 +1271,14 @@ func (q *Question) pack(msg []byte, off int, compression compressionMap, compres
 	return off, nil
 }
 
-func unpackQuestion(msg []byte, off int) (Question, int, error) {
+// unpackQuestion unpacks a question, with its name looked up in names, which
+// may be nil.
+func unpackQuestion(msg []byte, off int, names *nameCache) (Question, int, error) {
 	var (
 		q   Question
 		err error
 	)
-	q.Name, off, err = UnpackDomainName(msg, off)
+	q.Name, off, err = names.unpack(msg, off)
 	if err != nil {
 		return q, off, err
 	}
@@ -1186,6 +1324,37 @@ func (dh *Header) pack(msg []byte, off int, compression compressionMap, compress
 	return off, err
 }
 
+// bits returns the flags, opcode and (the lower 4 bits of the) rcode as found
+// in the second 16 bit word of the wire header.
+func (h *MsgHdr) bits() uint16 {
+	bits := uint16(h.Opcode)<<11 | uint16(h.Rcode&0xF)
+	if h.Response {
+		bits |= _QR
+	}
+	if h.Authoritative {
+		bits |= _AA
+	}
+	if h.Truncated {
+		bits |= _TC
+	}
+	if h.RecursionDesired {
+		bits |= _RD
+	}
+	if h.RecursionAvailable {
+		bits |= _RA
+	}
+	if h.Zero {
+		bits |= _Z
+	}
+	if h.AuthenticatedData {
+		bits |= _AD
+	}
+	if h.CheckingDisabled {
+		bits |= _CD
+	}
+	return bits
+}
+
 func unpackMsgHdr(msg []byte, off int) (Header, int, error) {
 	var (
 		dh  Header
@@ -0,0 +1,204 @@
+package dns
+
+// Sections of a message, in the order they are written by a MsgBuilder.
+const (
+	sectionQuestion = iota
+	sectionAnswer
+	sectionNs
+	sectionExtra
+)
+
+// MsgBuilder packs a message directly into a buffer while records are added
+// to it, instead of building a Msg and packing it afterwards. The sections
+// must be filled in order: first the questions, then the answer, authority
+// and additional records.
+//
+// Each record is checked against the size budget of the message, a record
+// that doesn't fit is not added and ErrTruncated is returned. The builder is
+// still usable after that, e.g. to add an OPT record or to set the
+// Truncated bit before calling Finish:
+//
+//	b := dns.NewMsgBuilder(buf, size)
+//	b.MsgHdr = hdr
+//	b.Compress = true
+//	b.Question(q)
+//	for _, rr := range answer {
+//		if err := b.Answer(rr); err == dns.ErrTruncated {
+//			b.Truncated = true
+//			break
+//		}
+//	}
+//	msg, err := b.Finish()
+type MsgBuilder struct {
+	// The header is written by Finish, it may be changed until then. When an
+	// OPT record is added the extended rcode is set in it.
+	MsgHdr
+	Compress       bool           // If true, the message will be compressed.
+	CompressPolicy CompressPolicy // Names compressed when Compress is true, all of them by default.
+
+	buf         []byte
+	off         int
+	size        int
+	section     int
+	count       [4]uint16
+	compression compressionMap
+	opt         int // offset of the TTL of the OPT record, 0 if there is none
+}
+
+// NewMsgBuilder returns a builder that packs a message of at most size octets
+// in buf. If buf is too small a new buffer is allocated. If size is not
+// positive, MaxMsgSize is used.
+func NewMsgBuilder(buf []byte, size int) *MsgBuilder {
+	if size <= 0 || size > MaxMsgSize {
+		size = MaxMsgSize
+	}
+	b := &MsgBuilder{buf: buf, size: size}
+	b.grow(headerSize)
+	b.off = headerSize
+	return b
+}
+
+// Len returns the length of the message built so far.
+func (b *MsgBuilder) Len() int { return b.off }
+
+// Question adds q to the question section.
+func (b *MsgBuilder) Question(q Question) error {
+	if err := b.startSection(sectionQuestion); err != nil {
+		return err
+	}
+	start := b.off
+	b.grow(domainNameLen(q.Name, b.off, nil, false) + 4)
+	off, err := q.pack(b.buf, b.off, b.compressionMap(), b.compress())
+	if err != nil {
+		b.rollback(start)
+		return err
+	}
+	return b.commit(sectionQuestion, start, off)
+}
+
+// Answer adds rr to the answer section.
+func (b *MsgBuilder) Answer(rr RR) error { return b.add(sectionAnswer, rr) }
+
+// Ns adds rr to the authority section.
+func (b *MsgBuilder) Ns(rr RR) error { return b.add(sectionNs, rr) }
+
+// Extra adds rr to the additional section.
+func (b *MsgBuilder) Extra(rr RR) error { return b.add(sectionExtra, rr) }
+
+func (b *MsgBuilder) add(section int, rr RR) error {
+	if err := b.startSection(section); err != nil {
+		return err
+	}
+	if rr == nil {
+		return &Error{err: "nil rr"}
+	}
+	if _, ok := rr.(*OPT); ok && b.opt != 0 {
+		return &Error{err: "more than one OPT record"}
+	}
+
+	start := b.off
+	b.grow(rr.len(b.off, nil))
+	headerEnd, off, err := packRRPolicy(rr, b.buf, b.off, b.compressionMap(), b.compress(), b.CompressPolicy)
+	if err != nil {
+		b.rollback(start)
+		return err
+	}
+	if err := b.commit(section, start, off); err != nil {
+		return err
+	}
+	if _, ok := rr.(*OPT); ok {
+		// The TTL precedes the RDLENGTH, the last field of the header.
+		b.opt = headerEnd - 6
+	}
+	return nil
+}
+
+// Finish writes the header and returns the message. The builder must not be
+// used afterwards.
+func (b *MsgBuilder) Finish() ([]byte, error) {
+	if b.Rcode < 0 || b.Rcode > 0xFFF {
+		return nil, ErrRcode
+	}
+	if b.opt != 0 {
+		// Upper 8 bits of the extended rcode, see (*OPT).SetExtendedRcode.
+		b.buf[b.opt] = uint8(b.Rcode >> 4)
+	} else if b.Rcode > 0xF {
+		return nil, ErrExtendedRcode
+	}
+
+	dh := Header{
+		Id:      b.Id,
+		Bits:    b.MsgHdr.bits(),
+		Qdcount: b.count[sectionQuestion],
+		Ancount: b.count[sectionAnswer],
+		Nscount: b.count[sectionNs],
+		Arcount: b.count[sectionExtra],
+	}
+	if _, err := dh.pack(b.buf, 0, compressionMap{}, false); err != nil {
+		return nil, err
+	}
+	return b.buf[:b.off], nil
+}
+
+func (b *MsgBuilder) startSection(section int) error {
+	if section < b.section {
+		return &Error{err: "message sections out of order"}
+	}
+	b.section = section
+	return nil
+}
+
+// commit accepts the data packed from start to off, unless it exceeds the size
+// budget.
+func (b *MsgBuilder) commit(section, start, off int) error {
+	if off > b.size || b.count[section] == 0xFFFF {
+		b.rollback(start)
+		return ErrTruncated
+	}
+	b.off = off
+	b.count[section]++
+	return nil
+}
+
+// rollback drops the compression pointers to data packed after start.
+func (b *MsgBuilder) rollback(start int) {
+	for name, pos := range b.compression.int {
+		if int(pos) >= start {
+			delete(b.compression.int, name)
+		}
+	}
+}
+
+// compress returns true if the builder compresses names.
+func (b *MsgBuilder) compress() bool { return b.Compress && b.CompressPolicy != CompressNone }
+
+func (b *MsgBuilder) compressionMap() compressionMap {
+	if b.compress() && b.compression.int == nil {
+		b.compression.int = make(map[string]uint16)
+	}
+	return b.compression
+}
+
+// grow makes sure n more octets can be packed into the buffer. Packing a
+// record may fail, or be truncated by the size budget, only after it has been
+// written; the buffer must therefore hold the uncompressed record in full.
+func (b *MsgBuilder) grow(n int) {
+	need := b.off + n + 1
+	if need <= len(b.buf) {
+		return
+	}
+	if need <= cap(b.buf) {
+		b.buf = b.buf[:cap(b.buf)]
+		return
+	}
+	c := 2 * cap(b.buf)
+	if c < MinMsgSize {
+		c = MinMsgSize
+	}
+	if c < need {
+		c = need
+	}
+	buf := make([]byte, c)
+	copy(buf, b.buf[:b.off])
+	b.buf = buf
+}
@@ -0,0 +1,120 @@
+package dns
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMsgBuilder(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeMX)
+	m.Response = true
+	m.Compress = true
+	m.Answer = []RR{
+		testRR("miek.nl. 3600 IN MX 10 mx1.miek.nl."),
+		testRR("miek.nl. 3600 IN MX 20 mx2.miek.nl."),
+	}
+	m.Ns = []RR{testRR("miek.nl. 3600 IN NS ns1.miek.nl.")}
+	m.Extra = []RR{testRR("mx1.miek.nl. 3600 IN A 127.0.0.1")}
+	m.SetEdns0(4096, true)
+
+	want, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewMsgBuilder(nil, 0)
+	b.MsgHdr = m.MsgHdr
+	b.Compress = true
+	if err := b.Question(m.Question[0]); err != nil {
+		t.Fatal(err)
+	}
+	for _, rr := range m.Answer {
+		if err := b.Answer(rr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rr := range m.Ns {
+		if err := b.Ns(rr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rr := range m.Extra {
+		if err := b.Extra(rr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("expected builder output to equal packed message:\n%x\n%x", got, want)
+	}
+
+	if err := b.Answer(m.Answer[0]); err == nil {
+		t.Error("expected error when adding to an earlier section")
+	}
+}
+
+func TestMsgBuilderTruncated(t *testing.T) {
+	q := Question{Name: "example.org.", Qtype: TypeTXT, Qclass: ClassINET}
+	b := NewMsgBuilder(make([]byte, 0, 64), 100)
+	b.Compress = true
+	if err := b.Question(q); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Answer(testRR("example.org. 3600 IN TXT \"short\"")); err != nil {
+		t.Fatal(err)
+	}
+	// Uses a new name, whose compression pointer must not survive the truncation.
+	long := testRR("www.example.org. 3600 IN TXT \"" + string(bytes.Repeat([]byte{'x'}, 100)) + "\"")
+	if err := b.Answer(long); err != ErrTruncated {
+		t.Fatalf("expected %v, got %v", ErrTruncated, err)
+	}
+	b.Truncated = true
+	if err := b.Answer(testRR("www.example.org. 3600 IN A 127.0.0.1")); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) > 100 {
+		t.Errorf("expected message of at most 100 octets, got %d", len(buf))
+	}
+
+	m := new(Msg)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Truncated || len(m.Answer) != 2 || m.Answer[1].Header().Name != "www.example.org." {
+		t.Errorf("unexpected message:\n%s", m)
+	}
+}
+
+func TestMsgBuilderExtendedRcode(t *testing.T) {
+	b := NewMsgBuilder(nil, 0)
+	b.Rcode = RcodeBadCookie
+	if _, err := b.Finish(); err != ErrExtendedRcode {
+		t.Fatalf("expected %v, got %v", ErrExtendedRcode, err)
+	}
+
+	b = NewMsgBuilder(nil, 0)
+	opt := &OPT{Hdr: RR_Header{Name: ".", Rrtype: TypeOPT}}
+	if err := b.Extra(opt); err != nil {
+		t.Fatal(err)
+	}
+	b.Rcode = RcodeBadCookie
+	buf, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(Msg)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if m.Rcode != RcodeBadCookie {
+		t.Errorf("expected rcode %d, got %d", RcodeBadCookie, m.Rcode)
+	}
+}
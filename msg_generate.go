@@ -3,7 +3,9 @@
 // msg_generate.go is meant to run with go generate. It will use
 // go/{importer,types} to track down all the RR struct types. Then for each type
 // it will generate pack/unpack methods based on the struct tags. The generated source is
-// written to zmsg.go, and is meant to be checked into git.
+// written to zmsg.go, and is meant to be checked into git. The len methods, which
+// return the exact packed length of a type, are generated from the same tags and
+// written to zlen.go.
 package main
 
 import (
@@ -24,6 +26,15 @@ package dns
 
 `
 
+var lenPackageHdr = `
+// Code generated by "go run msg_generate.go"; DO NOT EDIT.
+
+package dns
+
+import "net"
+
+`
+
 // getTypeStruct will take a type and the package scope, and return the
 // (innermost) struct if the type is considered a RR type (currently defined as
 // those structs beginning with a RR_Header, could be redefined as implementing
@@ -104,6 +115,8 @@ return headerEnd, off, err
 					o("off, err = packDataOpt(rr.%s, msg, off)\n")
 				case `dns:"nsec"`:
 					o("off, err = packDataNsec(rr.%s, msg, off)\n")
+				case `dns:"cdomain-name"`:
+					o("off, err = packDataDomainNames(rr.%s, msg, off, compression, compress)\n")
 				case `dns:"domain-name"`:
 					o("off, err = packDataDomainNames(rr.%s, msg, off, compression, false)\n")
 				default:
@@ -156,6 +169,8 @@ if rr.%s != "-" {
 
 			case st.Tag(i) == `dns:"octet"`:
 				o("off, err = packStringOctet(rr.%s, msg, off)\n")
+			case st.Tag(i) == `dns:"amtrelayhost"`:
+				o("off, err = packAMTRELAYGateway(rr.GatewayType, rr.GatewayAddr, rr.%s, msg, off, compression)\n")
 			case st.Tag(i) == "":
 				switch st.Field(i).Type().(*types.Basic).Kind() {
 				case types.Uint8:
@@ -233,6 +248,8 @@ return rr, off, err
 					o("rr.%s, off, err = unpackDataOpt(msg, off)\n")
 				case `dns:"nsec"`:
 					o("rr.%s, off, err = unpackDataNsec(msg, off)\n")
+				case `dns:"cdomain-name"`:
+					fallthrough
 				case `dns:"domain-name"`:
 					o("rr.%s, off, err = unpackDataDomainNames(msg, off, rdStart + int(rr.Hdr.Rdlength))\n")
 				default:
@@ -263,6 +280,8 @@ return rr, off, err
 				o("rr.%s, off, err = unpackStringHex(msg, off, rdStart + int(rr.Hdr.Rdlength))\n")
 			case `dns:"octet"`:
 				o("rr.%s, off, err = unpackStringOctet(msg, off)\n")
+			case `dns:"amtrelayhost"`:
+				o("rr.GatewayAddr, rr.%s, off, err = unpackAMTRELAYGateway(rr.GatewayType, msg, off)\n")
 			case "":
 				switch st.Field(i).Type().(*types.Basic).Kind() {
 				case types.Uint8:
@@ -281,8 +300,10 @@ return rr, off, err
 			default:
 				log.Fatalln(name, st.Field(i).Name(), st.Tag(i))
 			}
-			// If we've hit len(msg) we return without error.
-			if i < st.NumFields()-1 {
+			// If we've hit len(msg) we return without error. Ignored fields have
+			// nothing to check and the AMTRELAY relay must see its type validated
+			// even when no relay follows it.
+			if i < st.NumFields()-1 && st.Tag(i) != `dns:"-"` && !nextTag(st, i, `dns:"amtrelayhost"`) {
 				fmt.Fprintf(b, `if off == len(msg) {
 return rr, off, nil
 	}
@@ -301,6 +322,98 @@ return rr, off, nil
 	}
 	fmt.Fprintln(b, "}\n")
 
+	writeSource("zmsg.go", b)
+
+	b = &bytes.Buffer{}
+	b.WriteString(lenPackageHdr)
+	fmt.Fprint(b, "// len() functions\n\n")
+	for _, name := range namedTypes {
+		o := scope.Lookup(name)
+		st, isEmbedded := getTypeStruct(o.Type(), scope)
+		if isEmbedded {
+			continue
+		}
+
+		fmt.Fprintf(b, "func (rr *%s) len(off int, compression map[string]struct{}) int {\n", name)
+		fmt.Fprint(b, "l := rr.Hdr.len(off, compression)\n")
+		for i := 1; i < st.NumFields(); i++ {
+			o := func(s string) { fmt.Fprintf(b, s, st.Field(i).Name()) }
+
+			if _, ok := st.Field(i).Type().(*types.Slice); ok {
+				switch st.Tag(i) {
+				case `dns:"-"`: // ignored
+				case `dns:"cdomain-name"`:
+					o("for _, x := range rr.%s { l += domainNameLen(x, off+l, compression, true) }\n")
+				case `dns:"domain-name"`:
+					o("for _, x := range rr.%s { l += domainNameLen(x, off+l, compression, false) }\n")
+				case `dns:"txt"`:
+					o("l += txtLen(rr.%s)\n")
+				case `dns:"opt"`:
+					o("l += optionsLen(rr.%s)\n")
+				case `dns:"nsec"`:
+					o("l += typeBitMapLen(rr.%s)\n")
+				default:
+					log.Fatalln(name, st.Field(i).Name(), st.Tag(i))
+				}
+				continue
+			}
+
+			switch {
+			case st.Tag(i) == `dns:"-"`: // ignored
+			case st.Tag(i) == `dns:"cdomain-name"`:
+				o("l += domainNameLen(rr.%s, off+l, compression, true)\n")
+			case st.Tag(i) == `dns:"domain-name"`:
+				o("l += domainNameLen(rr.%s, off+l, compression, false)\n")
+			case st.Tag(i) == `dns:"a"`:
+				o("if len(rr.%s) != 0 { l += net.IPv4len }\n")
+			case st.Tag(i) == `dns:"aaaa"`:
+				o("if len(rr.%s) != 0 { l += net.IPv6len }\n")
+			case st.Tag(i) == `dns:"uint48"`:
+				o("l += 6 // %s\n")
+			case st.Tag(i) == `dns:"txt"`:
+				o("l += octetStringLen(rr.%s) + 1\n")
+			case strings.HasPrefix(st.Tag(i), `dns:"size-base32`):
+				fallthrough
+			case st.Tag(i) == `dns:"base32"`:
+				o("l += base32Len(rr.%s)\n")
+			case strings.HasPrefix(st.Tag(i), `dns:"size-base64`):
+				fallthrough
+			case st.Tag(i) == `dns:"base64"`:
+				o("l += base64Len(rr.%s)\n")
+			case strings.HasPrefix(st.Tag(i), `dns:"size-hex`): // a salt of "-" is empty, and has length 0 as well
+				fallthrough
+			case st.Tag(i) == `dns:"hex"`:
+				o("l += len(rr.%s) / 2\n")
+			case st.Tag(i) == `dns:"octet"`:
+				o("l += octetStringLen(rr.%s)\n")
+			case st.Tag(i) == `dns:"amtrelayhost"`:
+				o("l += amtrelayGatewayLen(rr.GatewayType, rr.%s, off+l, compression)\n")
+			case st.Tag(i) == "":
+				switch st.Field(i).Type().(*types.Basic).Kind() {
+				case types.Uint8:
+					o("l++ // %s\n")
+				case types.Uint16:
+					o("l += 2 // %s\n")
+				case types.Uint32:
+					o("l += 4 // %s\n")
+				case types.Uint64:
+					o("l += 8 // %s\n")
+				case types.String:
+					o("l += octetStringLen(rr.%s) + 1\n")
+				default:
+					log.Fatalln(name, st.Field(i).Name())
+				}
+			default:
+				log.Fatalln(name, st.Field(i).Name(), st.Tag(i))
+			}
+		}
+		fmt.Fprint(b, "return l }\n\n")
+	}
+	writeSource("zlen.go", b)
+}
+
+// writeSource formats the source in b and writes it to the file name.
+func writeSource(name string, b *bytes.Buffer) {
 	// gofmt
 	res, err := format.Source(b.Bytes())
 	if err != nil {
@@ -309,7 +422,7 @@ return rr, off, nil
 	}
 
 	// write result
-	f, err := os.Create("zmsg.go")
+	f, err := os.Create(name)
 	fatalIfErr(err)
 	defer f.Close()
 	f.Write(res)
@@ -338,6 +451,16 @@ func structTag(s string) string {
 	return fields[1][len("\"size-"):]
 }
 
+// nextTag reports whether the first field after field i that isn't ignored has the tag tag.
+func nextTag(st *types.Struct, i int, tag string) bool {
+	for i++; i < st.NumFields(); i++ {
+		if st.Tag(i) != `dns:"-"` {
+			return st.Tag(i) == tag
+		}
+	}
+	return false
+}
+
 func fatalIfErr(err error) {
 	if err != nil {
 		log.Fatal(err)
@@ -25,12 +25,12 @@ func unpackDataA(msg []byte, off int) (net.IP, int, error) {
 }
 
 func packDataA(a net.IP, msg []byte, off int) (int, error) {
-	// It must be a slice of 4, even if it is 16, we encode only the first 4
-	if off+net.IPv4len > len(msg) {
-		return len(msg), &Error{err: "overflow packing a"}
-	}
 	switch len(a) {
 	case net.IPv4len, net.IPv6len:
+		// It must be a slice of 4, even if it is 16, we encode only the first 4
+		if off+net.IPv4len > len(msg) {
+			return len(msg), &Error{err: "overflow packing a"}
+		}
 		copy(msg[off:], a.To4())
 		off += net.IPv4len
 	case 0:
@@ -51,12 +51,11 @@ func unpackDataAAAA(msg []byte, off int) (net.IP, int, error) {
 }
 
 func packDataAAAA(aaaa net.IP, msg []byte, off int) (int, error) {
-	if off+net.IPv6len > len(msg) {
-		return len(msg), &Error{err: "overflow packing aaaa"}
-	}
-
 	switch len(aaaa) {
 	case net.IPv6len:
+		if off+net.IPv6len > len(msg) {
+			return len(msg), &Error{err: "overflow packing aaaa"}
+		}
 		copy(msg[off:], aaaa)
 		off += net.IPv6len
 	case 0:
@@ -69,13 +68,13 @@ func packDataAAAA(aaaa net.IP, msg []byte, off int) (int, error) {
 
 // unpackHeader unpacks an RR header, returning the offset to the end of the header and a
 // re-sliced msg according to the expected length of the RR.
-func unpackHeader(msg []byte, off int) (rr RR_Header, off1 int, truncmsg []byte, err error) {
+func unpackHeader(msg []byte, off int, names *nameCache) (rr RR_Header, off1 int, truncmsg []byte, err error) {
 	hdr := RR_Header{}
 	if off == len(msg) {
 		return hdr, off, msg, nil
 	}
 
-	hdr.Name, off, err = UnpackDomainName(msg, off)
+	hdr.Name, off, err = names.unpack(msg, off)
 	if err != nil {
 		return hdr, len(msg), msg, err
 	}
@@ -99,6 +98,39 @@ func unpackHeader(msg []byte, off int) (rr RR_Header, off1 int, truncmsg []byte,
 	return hdr, off, msg, err
 }
 
+// nameCache holds the last names unpacked from a message by their offset, so
+// that the names compressed to a pointer to them are unpacked without
+// allocating. This is the common case for owner names.
+type nameCache struct {
+	off  [8]int
+	name [8]string
+	n    int // number of names added
+}
+
+// unpack unpacks the name at off in msg, like UnpackDomainName. The cache c
+// may be nil.
+func (c *nameCache) unpack(msg []byte, off int) (string, int, error) {
+	if c == nil || off+1 >= len(msg) {
+		return UnpackDomainName(msg, off)
+	}
+	start := off
+	if msg[off]&0xC0 == 0xC0 {
+		start = int(msg[off]^0xC0)<<8 | int(msg[off+1])
+		for i := 0; i < c.n && i < len(c.off); i++ {
+			if c.off[i] == start {
+				return c.name[i], off + 2, nil
+			}
+		}
+	}
+	name, off1, err := UnpackDomainName(msg, off)
+	if err == nil && name != "." {
+		i := c.n % len(c.off)
+		c.off[i], c.name[i] = start, name
+		c.n++
+	}
+	return name, off1, err
+}
+
 // pack packs an RR header, returning the offset to the end of the header.
 // See PackDomainName for documentation about the compression.
 func (hdr RR_Header) pack(msg []byte, off int, compression compressionMap, compress bool) (int, int, error) {
@@ -156,6 +188,9 @@ func fromBase32(s []byte) (buf []byte, err error) {
 	return
 }
 
+// base32Len returns the length of the base32 encoded s once decoded.
+func base32Len(s string) int { return base32HexNoPadEncoding.DecodedLen(len(s)) }
+
 func toBase32(b []byte) string {
 	return base32HexNoPadEncoding.EncodeToString(b)
 }
@@ -168,6 +203,15 @@ func fromBase64(s []byte) (buf []byte, err error) {
 	return
 }
 
+// base64Len returns the length of the base64 encoded s once decoded.
+func base64Len(s string) int {
+	l := base64.StdEncoding.DecodedLen(len(s))
+	for i := len(s) - 1; i >= 0 && s[i] == '='; i-- {
+		l--
+	}
+	return l
+}
+
 func toBase64(b []byte) string { return base64.StdEncoding.EncodeToString(b) }
 
 // dynamicUpdate returns true if the Rdlength is zero.
@@ -380,98 +424,65 @@ func packStringTxt(s []string, msg []byte, off int) (int, error) {
 	return off, nil
 }
 
+// txtLen returns the length of the packed character-strings in txt.
+func txtLen(txt []string) int {
+	l := 0
+	for _, s := range txt {
+		l += octetStringLen(s) + 1
+	}
+	return l
+}
+
+// optionToEDNS0 has the constructors of the EDNS0 options that are unpacked
+// into their own type, the others are EDNS0_LOCAL options unless registered
+// with PrivateOptionHandle.
+var optionToEDNS0 = map[uint16]func() EDNS0{
+	EDNS0NSID:          func() EDNS0 { return new(EDNS0_NSID) },
+	EDNS0SUBNET:        func() EDNS0 { return new(EDNS0_SUBNET) },
+	EDNS0COOKIE:        func() EDNS0 { return new(EDNS0_COOKIE) },
+	EDNS0UL:            func() EDNS0 { return new(EDNS0_UL) },
+	EDNS0LLQ:           func() EDNS0 { return new(EDNS0_LLQ) },
+	EDNS0DAU:           func() EDNS0 { return new(EDNS0_DAU) },
+	EDNS0DHU:           func() EDNS0 { return new(EDNS0_DHU) },
+	EDNS0N3U:           func() EDNS0 { return new(EDNS0_N3U) },
+	EDNS0TCPKEEPALIVE:  func() EDNS0 { return new(EDNS0_TCP_KEEPALIVE) },
+	EDNS0PADDING:       func() EDNS0 { return new(EDNS0_PADDING) },
+	EDNS0EDE:           func() EDNS0 { return new(EDNS0_EDE) },
+	EDNS0REPORTCHANNEL: func() EDNS0 { return new(EDNS0_REPORT_CHANNEL) },
+	EDNS0CHAIN:         func() EDNS0 { return new(EDNS0_CHAIN) },
+}
+
+// makeEDNS0 returns a new option of the type of code.
+func makeEDNS0(code uint16) EDNS0 {
+	if gen, ok := privateOptions[code]; ok {
+		return &EDNS0_PRIVATE{Code: code, Data: gen()}
+	}
+	if mk, ok := optionToEDNS0[code]; ok {
+		return mk()
+	}
+	return &EDNS0_LOCAL{Code: code}
+}
+
 func unpackDataOpt(msg []byte, off int) ([]EDNS0, int, error) {
 	var edns []EDNS0
-Option:
-	code := uint16(0)
-	if off+4 > len(msg) {
-		return nil, len(msg), &Error{err: "overflow unpacking opt"}
-	}
-	code = binary.BigEndian.Uint16(msg[off:])
-	off += 2
-	optlen := binary.BigEndian.Uint16(msg[off:])
-	off += 2
-	if off+int(optlen) > len(msg) {
-		return nil, len(msg), &Error{err: "overflow unpacking opt"}
-	}
-	switch code {
-	case EDNS0NSID:
-		e := new(EDNS0_NSID)
-		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
-			return nil, len(msg), err
-		}
-		edns = append(edns, e)
-		off += int(optlen)
-	case EDNS0SUBNET:
-		e := new(EDNS0_SUBNET)
-		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
-			return nil, len(msg), err
-		}
-		edns = append(edns, e)
-		off += int(optlen)
-	case EDNS0COOKIE:
-		e := new(EDNS0_COOKIE)
-		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
-			return nil, len(msg), err
-		}
-		edns = append(edns, e)
-		off += int(optlen)
-	case EDNS0UL:
-		e := new(EDNS0_UL)
-		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
-			return nil, len(msg), err
-		}
-		edns = append(edns, e)
-		off += int(optlen)
-	case EDNS0LLQ:
-		e := new(EDNS0_LLQ)
-		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
-			return nil, len(msg), err
-		}
-		edns = append(edns, e)
-		off += int(optlen)
-	case EDNS0DAU:
-		e := new(EDNS0_DAU)
-		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
-			return nil, len(msg), err
-		}
-		edns = append(edns, e)
-		off += int(optlen)
-	case EDNS0DHU:
-		e := new(EDNS0_DHU)
-		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
-			return nil, len(msg), err
-		}
-		edns = append(edns, e)
-		off += int(optlen)
-	case EDNS0N3U:
-		e := new(EDNS0_N3U)
-		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
-			return nil, len(msg), err
+	for off < len(msg) {
+		if off+4 > len(msg) {
+			return nil, len(msg), &Error{err: "overflow unpacking opt"}
 		}
-		edns = append(edns, e)
-		off += int(optlen)
-	case EDNS0PADDING:
-		e := new(EDNS0_PADDING)
-		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
-			return nil, len(msg), err
+		code := binary.BigEndian.Uint16(msg[off:])
+		off += 2
+		optlen := binary.BigEndian.Uint16(msg[off:])
+		off += 2
+		if off+int(optlen) > len(msg) {
+			return nil, len(msg), &Error{err: "overflow unpacking opt"}
 		}
-		edns = append(edns, e)
-		off += int(optlen)
-	default:
-		e := new(EDNS0_LOCAL)
-		e.Code = code
+		e := makeEDNS0(code)
 		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
 			return nil, len(msg), err
 		}
 		edns = append(edns, e)
 		off += int(optlen)
 	}
-
-	if off < len(msg) {
-		goto Option
-	}
-
 	return edns, off, nil
 }
 
@@ -496,6 +507,16 @@ func packDataOpt(options []EDNS0, msg []byte, off int) (int, error) {
 	return off, nil
 }
 
+// optionsLen returns the length of the packed options.
+func optionsLen(options []EDNS0) int {
+	l := 0
+	for _, el := range options {
+		b, _ := el.pack()
+		l += 4 + len(b) // option code, length and data
+	}
+	return l
+}
+
 func unpackStringOctet(msg []byte, off int) (string, int, error) {
 	s := string(msg[off:])
 	return s, len(msg), nil
@@ -510,6 +531,26 @@ func packStringOctet(s string, msg []byte, off int) (int, error) {
 	return off, nil
 }
 
+// octetStringLen returns the length of s with its escapes resolved, as done
+// by packOctetString and packTxtString.
+func octetStringLen(s string) int {
+	l := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			if i == len(s) {
+				break
+			}
+			// check for \DDD
+			if i+2 < len(s) && isDigit(s[i]) && isDigit(s[i+1]) && isDigit(s[i+2]) {
+				i += 2
+			}
+		}
+		l++
+	}
+	return l
+}
+
 func unpackDataNsec(msg []byte, off int) ([]uint16, int, error) {
 	var nsec []uint16
 	length, window, lastwindow := 0, 0, -1
@@ -602,6 +643,28 @@ func packDataNsec(bitmap []uint16, msg []byte, off int) (int, error) {
 	return off, nil
 }
 
+// typeBitMapLen returns the length of the packed type bitmap, see packDataNsec.
+func typeBitMapLen(bitmap []uint16) int {
+	if len(bitmap) == 0 {
+		return 0
+	}
+	var l int
+	var lastwindow, lastlength uint16
+	for _, t := range bitmap {
+		window := t / 256
+		length := (t-window*256)/8 + 1
+		if window > lastwindow && lastlength != 0 { // New window
+			l += int(lastlength) + 2
+			lastlength = 0
+		}
+		if window == lastwindow && length < lastlength {
+			continue
+		}
+		lastwindow, lastlength = window, length
+	}
+	return l + int(lastlength) + 2
+}
+
 func unpackDataDomainNames(msg []byte, off, end int) ([]string, int, error) {
 	var (
 		servers []string
@@ -631,3 +694,58 @@ func packDataDomainNames(names []string, msg []byte, off int, compression compre
 	}
 	return off, nil
 }
+
+// unpackAMTRELAYGateway unpacks the relay of an AMTRELAY RR, whose form
+// depends on the relay type in typ.
+func unpackAMTRELAYGateway(typ uint8, msg []byte, off int) (net.IP, string, int, error) {
+	switch typ &^ amtrelayDiscovery {
+	case AMTRELAYNone:
+		return nil, "", off, nil
+	case AMTRELAYIPv4:
+		addr, off, err := unpackDataA(msg, off)
+		return addr, "", off, err
+	case AMTRELAYIPv6:
+		addr, off, err := unpackDataAAAA(msg, off)
+		return addr, "", off, err
+	case AMTRELAYHost:
+		host, off, err := UnpackDomainName(msg, off)
+		return nil, host, off, err
+	}
+	return nil, "", len(msg), &Error{err: "bad AMTRELAY relay type"}
+}
+
+// packAMTRELAYGateway packs the relay of an AMTRELAY RR, addr or host
+// depending on the relay type in typ. The domain name is never compressed.
+func packAMTRELAYGateway(typ uint8, addr net.IP, host string, msg []byte, off int, compression compressionMap) (int, error) {
+	switch typ &^ amtrelayDiscovery {
+	case AMTRELAYNone:
+		return off, nil
+	case AMTRELAYIPv4:
+		if addr.To4() == nil {
+			return len(msg), &Error{err: "bad AMTRELAY relay address"}
+		}
+		return packDataA(addr.To4(), msg, off)
+	case AMTRELAYIPv6:
+		if len(addr) != net.IPv6len {
+			return len(msg), &Error{err: "bad AMTRELAY relay address"}
+		}
+		return packDataAAAA(addr, msg, off)
+	case AMTRELAYHost:
+		off, _, err := packDomainName(host, msg, off, compression, false)
+		return off, err
+	}
+	return len(msg), &Error{err: "bad AMTRELAY relay type"}
+}
+
+// amtrelayGatewayLen returns the length of the relay of an AMTRELAY RR.
+func amtrelayGatewayLen(typ uint8, host string, off int, compression map[string]struct{}) int {
+	switch typ &^ amtrelayDiscovery {
+	case AMTRELAYIPv4:
+		return net.IPv4len
+	case AMTRELAYIPv6:
+		return net.IPv6len
+	case AMTRELAYHost:
+		return domainNameLen(host, off, compression, false)
+	}
+	return 0
+}
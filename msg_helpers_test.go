@@ -121,6 +121,27 @@ func TestUnpackString(t *testing.T) {
 	}
 }
 
+func TestUnpackAMTRELAYGateway(t *testing.T) {
+	tests := []struct {
+		rdata string
+		ok    bool
+	}{
+		{"\x0a\x00", true},                  // no relay
+		{"\x0a\x01\xcb\x00\x71\x0f", true},  // IPv4 relay
+		{"\x0a\x30", false},                 // bad relay type, without a relay
+		{"\x0a\x01", false},                 // IPv4 relay type, without a relay
+		{"\x0a\x83\x08amtrelay\x00", true},  // domain name relay, D bit set
+		{"\x0a\x05\xcb\x00\x71\x0f", false}, // bad relay type
+	}
+	for _, tc := range tests {
+		h := RR_Header{Name: "example.org.", Rrtype: TypeAMTRELAY, Class: ClassINET, Rdlength: uint16(len(tc.rdata))}
+		_, _, err := UnpackRRWithHeader(h, []byte(tc.rdata), 0)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("rdata %q: expected success %t, got error %v", tc.rdata, tc.ok, err)
+		}
+	}
+}
+
 func BenchmarkUnpackString(b *testing.B) {
 	msg := []byte("\x00abcdef\x0f\\\"ghi\x04mmm")
 	msg[0] = byte(len(msg) - 1)
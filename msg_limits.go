@@ -0,0 +1,154 @@
+package dns
+
+// ErrLimit is the error of an UnpackError for a message beyond UnpackLimits.
+var ErrLimit error = &Error{err: "message exceeds unpack limits"}
+
+// UnpackLimits bound the work of unpacking a message, for servers exposed to
+// hostile traffic. The length of names is always limited to 255 octets. A zero
+// field is no limit.
+type UnpackLimits struct {
+	MaxLabels     int // maximum number of labels of a name, of the owner names and of the names in the rdata of RFC 1035 types
+	MaxRRs        int // maximum number of records of a section, the question section included
+	MaxOptions    int // maximum number of options of an OPT record
+	MaxTXTStrings int // maximum number of strings of a TXT, SPF or AVC record
+}
+
+// UnpackLimited is like Unpack, but rejects messages beyond the limits l with
+// an *UnpackError holding ErrLimit. The counts of the header are checked
+// before anything is unpacked, and the rest of the message before the records
+// are allocated.
+func (dns *Msg) UnpackLimited(msg []byte, l *UnpackLimits) error {
+	if err := checkLimits(msg, l); err != nil {
+		return err
+	}
+	return dns.unpackMsg(msg, false)
+}
+
+// checkLimits walks the message msg and returns an *UnpackError for the
+// first limit of l it exceeds. Malformed messages are left to Unpack.
+func checkLimits(msg []byte, l *UnpackLimits) error {
+	dh, off, err := unpackMsgHdr(msg, 0)
+	if err != nil {
+		return nil
+	}
+	sections := []struct {
+		name  string
+		count uint16
+	}{{"question", dh.Qdcount}, {"answer", dh.Ancount}, {"authority", dh.Nscount}, {"additional", dh.Arcount}}
+	if l.MaxRRs > 0 {
+		for i, s := range sections {
+			if int(s.count) > l.MaxRRs {
+				return &UnpackError{Section: s.name, Index: l.MaxRRs, Offset: 4 + 2*i, Err: ErrLimit}
+			}
+		}
+	}
+	for i := 0; i < int(dh.Qdcount); i++ {
+		if off, err = limitName(msg, off, l); err != nil {
+			return limitError("question", i, err)
+		}
+		off += 4
+	}
+	for _, s := range sections[1:] {
+		for i := 0; i < int(s.count); i++ {
+			if off, err = limitRR(msg, off, l); err != nil {
+				return limitError(s.name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// limitError returns the *UnpackError of err, or nil if err isn't about a
+// limit.
+func limitError(section string, i int, err error) error {
+	if e, ok := err.(*strictOffsetError); ok {
+		return &UnpackError{Section: section, Index: i, Offset: e.off, Err: e.err}
+	}
+	return nil
+}
+
+// limitRR checks the record at off, and returns the offset after it.
+func limitRR(msg []byte, off int, l *UnpackLimits) (int, error) {
+	off, err := limitName(msg, off, l)
+	if err != nil {
+		return off, err
+	}
+	if off+10 > len(msg) {
+		return off, ErrBuf
+	}
+	rrtype := uint16(msg[off])<<8 | uint16(msg[off+1])
+	rdata := off + 10
+	end := rdata + (int(msg[off+8])<<8 | int(msg[off+9]))
+	if end > len(msg) {
+		return off, ErrBuf
+	}
+
+	switch rrtype {
+	case TypeOPT:
+		if l.MaxOptions > 0 {
+			n := 0
+			for o := rdata; o+4 <= end; o += 4 + (int(msg[o+2])<<8 | int(msg[o+3])) {
+				if n++; n > l.MaxOptions {
+					return end, &strictOffsetError{o, ErrLimit}
+				}
+			}
+		}
+	case TypeTXT, TypeSPF, TypeAVC:
+		if l.MaxTXTStrings > 0 {
+			n := 0
+			for o := rdata; o < end; o += 1 + int(msg[o]) {
+				if n++; n > l.MaxTXTStrings {
+					return end, &strictOffsetError{o, ErrLimit}
+				}
+			}
+		}
+	case TypeNS, TypeCNAME, TypeMB, TypeMG, TypeMR, TypeMD, TypeMF, TypePTR:
+		_, err = limitName(msg, rdata, l)
+	case TypeMX:
+		_, err = limitName(msg, rdata+2, l)
+	case TypeSOA, TypeMINFO:
+		if off, err = limitName(msg, rdata, l); err == nil {
+			_, err = limitName(msg, off, l)
+		}
+	}
+	return end, err
+}
+
+// limitName checks the number of labels of the name at off, and returns the
+// offset after it.
+func limitName(msg []byte, off int, l *UnpackLimits) (int, error) {
+	labels, ptrs := 0, 0
+	end := -1
+	for {
+		if off >= len(msg) {
+			return len(msg), ErrBuf
+		}
+		c := int(msg[off])
+		switch c & 0xC0 {
+		case 0x00:
+			if c == 0 {
+				if end < 0 {
+					end = off + 1
+				}
+				return end, nil
+			}
+			if labels++; l.MaxLabels > 0 && labels > l.MaxLabels {
+				return end, &strictOffsetError{off, ErrLimit}
+			}
+			off += 1 + c
+		case 0xC0:
+			if off+1 >= len(msg) {
+				return len(msg), ErrBuf
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			if ptrs++; ptrs > maxCompressionPointers {
+				return end, ErrRdata
+			}
+			off = (c^0xC0)<<8 | int(msg[off+1])
+		default:
+			return end, ErrRdata
+		}
+	}
+}
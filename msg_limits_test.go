@@ -0,0 +1,71 @@
+package dns
+
+import "testing"
+
+func TestUnpackLimited(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("a.b.c.example.org.", TypeTXT)
+	m.Answer = []RR{
+		testRR(`a.b.c.example.org. 3600 IN TXT "1" "2" "3"`),
+		testRR("a.b.c.example.org. 3600 IN CNAME x.y.z.w.example.org."),
+	}
+	m.SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &EDNS0_NSID{Code: EDNS0NSID}, &EDNS0_COOKIE{Code: EDNS0COOKIE, Cookie: "0102030405060708"})
+	m.Compress = true
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := &UnpackLimits{MaxLabels: 6, MaxRRs: 2, MaxOptions: 2, MaxTXTStrings: 3}
+	if err := new(Msg).UnpackLimited(buf, ok); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		limits  UnpackLimits
+		section string
+		index   int
+	}{
+		{"labels", UnpackLimits{MaxLabels: 4}, "question", 0},
+		{"rdata labels", UnpackLimits{MaxLabels: 5}, "answer", 1}, // the compressed CNAME target
+		{"records", UnpackLimits{MaxRRs: 1}, "answer", 1},
+		{"options", UnpackLimits{MaxOptions: 1}, "additional", 0},
+		{"strings", UnpackLimits{MaxTXTStrings: 2}, "answer", 0},
+	}
+	for _, tc := range tests {
+		err := new(Msg).UnpackLimited(buf, &tc.limits)
+		e, ok := err.(*UnpackError)
+		if !ok || e.Err != ErrLimit || e.Section != tc.section || e.Index != tc.index {
+			t.Errorf("%s: expected ErrLimit in %s record %d, got %v", tc.name, tc.section, tc.index, err)
+		}
+	}
+
+	// Malformed messages get the error of Unpack.
+	if err := new(Msg).UnpackLimited(buf[:len(buf)-3], ok); err == nil || err == ErrLimit {
+		t.Errorf("expected an unpack error, got %v", err)
+	}
+}
+
+func TestServerUnpackLimits(t *testing.T) {
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = HandlerFunc(HelloServer)
+		srv.UnpackLimits = &UnpackLimits{MaxLabels: 2}
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	if r, err := Exchange(m, addrstr); err != nil || r.Rcode != RcodeSuccess {
+		t.Fatalf("expected an answer, got %v, %v", r, err)
+	}
+	m.SetQuestion("www.miek.nl.", TypeTXT)
+	if r, err := Exchange(m, addrstr); err != nil || r.Rcode != RcodeFormatError {
+		t.Fatalf("expected FORMERR, got %v, %v", r, err)
+	}
+}
@@ -0,0 +1,249 @@
+package dns
+
+// Parser parses a message in wire format incrementally: questions and
+// records are only unpacked when asked for, and sections, or single records,
+// can be skipped without unpacking them. This is cheaper than Msg.Unpack
+// when only part of a message is of interest:
+//
+//	var p dns.Parser
+//	hdr, err := p.Start(buf)
+//	...
+//	for {
+//		rr, err := p.Answer()
+//		if err == dns.ErrSectionDone {
+//			break
+//		}
+//		...
+//	}
+//
+// The sections are parsed in order. Asking for a record of a later section
+// skips whatever is left of the current section. Once a section has been left
+// its records can't be parsed any more and ErrSectionDone is returned.
+type Parser struct {
+	msg     []byte
+	off     int
+	section int
+	index   int // index of the next record in the current section
+	count   [4]uint16
+
+	hdr      RR_Header // header of the next record, if hdrValid
+	hdrEnd   int
+	hdrValid bool
+}
+
+// Start parses the header of msg and prepares p for parsing its sections.
+// The returned header holds the lower 4 bits of the rcode only, the upper
+// bits are found in the OPT record of the additional section.
+func (p *Parser) Start(msg []byte) (MsgHdr, error) {
+	*p = Parser{msg: msg}
+	dh, off, err := unpackMsgHdr(msg, 0)
+	if err != nil {
+		p.msg = nil
+		return MsgHdr{}, err
+	}
+	p.off = off
+	p.count = [4]uint16{dh.Qdcount, dh.Ancount, dh.Nscount, dh.Arcount}
+
+	m := new(Msg)
+	m.setHdr(dh)
+	return m.MsgHdr, nil
+}
+
+// Question parses the next question.
+func (p *Parser) Question() (Question, error) {
+	if err := p.next(sectionQuestion); err != nil {
+		return Question{}, err
+	}
+	q, off, err := unpackQuestion(p.msg, p.off, nil)
+	if err != nil {
+		return q, err
+	}
+	p.off = off
+	p.index++
+	return q, nil
+}
+
+// SkipQuestion skips the next question.
+func (p *Parser) SkipQuestion() error {
+	if err := p.next(sectionQuestion); err != nil {
+		return err
+	}
+	off, err := skipDomainName(p.msg, p.off)
+	if err != nil {
+		return err
+	}
+	if off+4 > len(p.msg) {
+		return ErrBuf
+	}
+	p.off = off + 4
+	p.index++
+	return nil
+}
+
+// SkipAllQuestions skips the remaining questions.
+func (p *Parser) SkipAllQuestions() error { return p.skipAll(sectionQuestion) }
+
+// AnswerHeader parses the header of the next record in the answer section.
+// It may be followed by a call to Answer or SkipAnswer.
+func (p *Parser) AnswerHeader() (RR_Header, error) { return p.rrHeader(sectionAnswer) }
+
+// Answer parses the next record in the answer section.
+func (p *Parser) Answer() (RR, error) { return p.rr(sectionAnswer) }
+
+// SkipAnswer skips the next record in the answer section.
+func (p *Parser) SkipAnswer() error { return p.skipRR(sectionAnswer) }
+
+// SkipAllAnswers skips the remaining records in the answer section.
+func (p *Parser) SkipAllAnswers() error { return p.skipAll(sectionAnswer) }
+
+// NsHeader parses the header of the next record in the authority section.
+// It may be followed by a call to Ns or SkipNs.
+func (p *Parser) NsHeader() (RR_Header, error) { return p.rrHeader(sectionNs) }
+
+// Ns parses the next record in the authority section.
+func (p *Parser) Ns() (RR, error) { return p.rr(sectionNs) }
+
+// SkipNs skips the next record in the authority section.
+func (p *Parser) SkipNs() error { return p.skipRR(sectionNs) }
+
+// SkipAllNs skips the remaining records in the authority section.
+func (p *Parser) SkipAllNs() error { return p.skipAll(sectionNs) }
+
+// ExtraHeader parses the header of the next record in the additional section.
+// It may be followed by a call to Extra or SkipExtra.
+func (p *Parser) ExtraHeader() (RR_Header, error) { return p.rrHeader(sectionExtra) }
+
+// Extra parses the next record in the additional section.
+func (p *Parser) Extra() (RR, error) { return p.rr(sectionExtra) }
+
+// SkipExtra skips the next record in the additional section.
+func (p *Parser) SkipExtra() error { return p.skipRR(sectionExtra) }
+
+// SkipAllExtra skips the remaining records in the additional section.
+func (p *Parser) SkipAllExtra() error { return p.skipAll(sectionExtra) }
+
+// next moves the parser to section and checks there is a record left in it.
+func (p *Parser) next(section int) error {
+	if p.msg == nil {
+		return &Error{err: "parser not started"}
+	}
+	if section < p.section {
+		return ErrSectionDone
+	}
+	for p.section < section {
+		if err := p.skipAll(p.section); err != nil {
+			return err
+		}
+		p.section++
+		p.index = 0
+	}
+	if p.index >= int(p.count[section]) {
+		return ErrSectionDone
+	}
+	return nil
+}
+
+func (p *Parser) rrHeader(section int) (RR_Header, error) {
+	if err := p.next(section); err != nil {
+		return RR_Header{}, err
+	}
+	if p.hdrValid {
+		return p.hdr, nil
+	}
+	if p.off >= len(p.msg) {
+		return RR_Header{}, ErrBuf
+	}
+	h, off, _, err := unpackHeader(p.msg, p.off, nil)
+	if err != nil {
+		return h, err
+	}
+	p.hdr, p.hdrEnd, p.hdrValid = h, off, true
+	return h, nil
+}
+
+func (p *Parser) rr(section int) (RR, error) {
+	h, err := p.rrHeader(section)
+	if err != nil {
+		return nil, err
+	}
+	end := p.hdrEnd + int(h.Rdlength)
+	rr, _, err := UnpackRRWithHeader(h, p.msg[:end], p.hdrEnd)
+	if err != nil {
+		return nil, err
+	}
+	p.off, p.hdrValid = end, false
+	p.index++
+	return rr, nil
+}
+
+func (p *Parser) skipRR(section int) error {
+	if err := p.next(section); err != nil {
+		return err
+	}
+	if p.hdrValid {
+		p.off, p.hdrValid = p.hdrEnd+int(p.hdr.Rdlength), false
+		p.index++
+		return nil
+	}
+	off, err := skipDomainName(p.msg, p.off)
+	if err != nil {
+		return err
+	}
+	// TYPE, CLASS, TTL and RDLENGTH.
+	if off+10 > len(p.msg) {
+		return ErrBuf
+	}
+	rdlength, _, _ := unpackUint16(p.msg, off+8)
+	off += 10 + int(rdlength)
+	if off > len(p.msg) {
+		return ErrBuf
+	}
+	p.off = off
+	p.index++
+	return nil
+}
+
+func (p *Parser) skipAll(section int) error {
+	for {
+		var err error
+		if section == sectionQuestion {
+			err = p.SkipQuestion()
+		} else {
+			err = p.skipRR(section)
+		}
+		if err == ErrSectionDone {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// skipDomainName returns the offset just past the domain name at msg[off:],
+// without unpacking it.
+func skipDomainName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return len(msg), ErrBuf
+		}
+		c := int(msg[off])
+		off++
+		switch c & 0xC0 {
+		case 0x00:
+			if c == 0x00 {
+				return off, nil
+			}
+			off += c
+		case 0xC0:
+			// A pointer ends the name.
+			if off >= len(msg) {
+				return len(msg), ErrBuf
+			}
+			return off + 1, nil
+		default:
+			// 0x80 and 0x40 are reserved
+			return len(msg), ErrRdata
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package dns
+
+import "testing"
+
+func testParserMsg(t *testing.T) []byte {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeMX)
+	m.Response = true
+	m.Compress = true
+	m.Answer = []RR{
+		testRR("miek.nl. 3600 IN MX 10 mx1.miek.nl."),
+		testRR("miek.nl. 3600 IN MX 20 mx2.miek.nl."),
+	}
+	m.Ns = []RR{testRR("miek.nl. 3600 IN NS ns1.miek.nl.")}
+	m.Extra = []RR{testRR("mx1.miek.nl. 3600 IN A 127.0.0.1")}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestParser(t *testing.T) {
+	buf := testParserMsg(t)
+	want := new(Msg)
+	if err := want.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var p Parser
+	hdr, err := p.Start(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr != want.MsgHdr {
+		t.Errorf("expected header %v, got %v", want.MsgHdr, hdr)
+	}
+
+	// Parsing the answer section skips the question.
+	h, err := p.AnswerHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Rrtype != TypeMX || h.Name != "miek.nl." {
+		t.Errorf("unexpected header: %v", h)
+	}
+	for i := 0; ; i++ {
+		rr, err := p.Answer()
+		if err == ErrSectionDone {
+			if i != len(want.Answer) {
+				t.Errorf("expected %d answers, got %d", len(want.Answer), i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsDuplicate(rr, want.Answer[i]) {
+			t.Errorf("expected %s, got %s", want.Answer[i], rr)
+		}
+	}
+
+	// The authority section is skipped.
+	rr, err := p.Extra()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsDuplicate(rr, want.Extra[0]) {
+		t.Errorf("expected %s, got %s", want.Extra[0], rr)
+	}
+	if _, err := p.Extra(); err != ErrSectionDone {
+		t.Errorf("expected %v, got %v", ErrSectionDone, err)
+	}
+	if _, err := p.Answer(); err != ErrSectionDone {
+		t.Errorf("expected %v for a previous section, got %v", ErrSectionDone, err)
+	}
+}
+
+func TestParserSkip(t *testing.T) {
+	buf := testParserMsg(t)
+
+	var p Parser
+	if _, err := p.Start(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SkipQuestion(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.AnswerHeader(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SkipAnswer(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SkipAllAnswers(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SkipAllNs(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SkipExtra(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SkipExtra(); err != ErrSectionDone {
+		t.Errorf("expected %v, got %v", ErrSectionDone, err)
+	}
+}
+
+func TestParserTruncated(t *testing.T) {
+	buf := testParserMsg(t)
+	for i := range buf {
+		var p Parser
+		if _, err := p.Start(buf[:i]); err != nil {
+			continue
+		}
+		// Must not panic; the truncated message has to result in an error.
+		var err error
+		for err == nil {
+			_, err = p.Extra()
+		}
+		if err == ErrSectionDone {
+			t.Errorf("expected error for message truncated at %d octets", i)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package dns
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Errors of UnpackStrict, found in the Err field of an UnpackError.
+var (
+	// ErrTrailingData is returned when bytes follow the last record of a message.
+	ErrTrailingData error = &Error{err: "trailing data after the last record"}
+	// ErrForwardPointer is returned for a compression pointer that doesn't point backward.
+	ErrForwardPointer error = &Error{err: "forward compression pointer"}
+	// ErrPointerLoop is returned for compression pointers that form a loop.
+	ErrPointerLoop error = &Error{err: "compression pointer loop"}
+	// ErrRdlength is returned when the rdata of a record isn't as long as its rdlength.
+	ErrRdlength error = &Error{err: "rdata length mismatch"}
+	// ErrRdataCompression is returned for a compressed name in rdata of a type that forbids it.
+	ErrRdataCompression error = &Error{err: "compressed name in rdata"}
+)
+
+// UnpackStrict is like Unpack, but rejects messages that Unpack tolerates:
+// bytes after the last record, compression pointers that don't point to an
+// earlier offset, records whose rdata is shorter or longer than their
+// rdlength, and compressed names in the rdata of types other than the ones of
+// RFC 1035, which RFC 3597 forbids. These are returned as an *UnpackError.
+// The errors of Unpack are returned as an *UnpackError too.
+func (dns *Msg) UnpackStrict(msg []byte) error {
+	if err := dns.unpackMsg(msg, false); err != nil {
+		return err
+	}
+	return checkStrict(msg)
+}
+
+// checkStrict walks the message msg, which Unpack accepted, and returns an
+// *UnpackError for the first problem UnpackStrict rejects.
+func checkStrict(msg []byte) error {
+	dh, off, err := unpackMsgHdr(msg, 0)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(dh.Qdcount); i++ {
+		if off, err = strictName(msg, off); err != nil {
+			return strictError("question", i, err)
+		}
+		off += 4
+	}
+	for _, s := range []struct {
+		name  string
+		count uint16
+	}{{"answer", dh.Ancount}, {"authority", dh.Nscount}, {"additional", dh.Arcount}} {
+		for i := 0; i < int(s.count); i++ {
+			if off, err = strictRR(msg, off); err != nil {
+				return strictError(s.name, i, err)
+			}
+		}
+	}
+	if off < len(msg) {
+		return &UnpackError{Offset: off, Err: ErrTrailingData}
+	}
+	return nil
+}
+
+// strictOffsetError is an error at an offset, strictError adds the record.
+type strictOffsetError struct {
+	off int
+	err error
+}
+
+func (e *strictOffsetError) Error() string { return e.err.Error() }
+
+func strictError(section string, i int, err error) error {
+	if e, ok := err.(*strictOffsetError); ok {
+		return &UnpackError{Section: section, Index: i, Offset: e.off, Err: e.err}
+	}
+	return err
+}
+
+// strictRR checks the record at off, and returns the offset after it.
+func strictRR(msg []byte, off int) (int, error) {
+	start := off
+	off, err := strictName(msg, off)
+	if err != nil {
+		return off, err
+	}
+	if off+10 > len(msg) {
+		return off, ErrBuf
+	}
+	rrtype := uint16(msg[off])<<8 | uint16(msg[off+1])
+	rdlength := int(msg[off+8])<<8 | int(msg[off+9])
+	rdata := off + 10
+	end := rdata + rdlength
+	if end > len(msg) {
+		return off, &strictOffsetError{off + 8, ErrRdlength}
+	}
+
+	rr, off1, err := UnpackRR(msg, start)
+	if err != nil || off1 != end {
+		return end, &strictOffsetError{off + 8, ErrRdlength}
+	}
+	if !strictNoCompression(rrtype) {
+		return end, strictRdataNames(msg, rrtype, rdata)
+	}
+	// A compressed name makes the rdata shorter than when packed without
+	// compression.
+	buf := make([]byte, Len(rr)+1)
+	if _, err := PackRR(rr, buf, 0, nil, false); err != nil {
+		return end, err
+	}
+	if int(rr.Header().Rdlength) != rdlength {
+		return end, &strictOffsetError{rdata, ErrRdataCompression}
+	}
+	return end, nil
+}
+
+// strictRdataNames checks the compressible names in the rdata at off of the
+// RFC 1035 type rrtype.
+func strictRdataNames(msg []byte, rrtype uint16, off int) (err error) {
+	switch rrtype {
+	case TypeNS, TypeCNAME, TypeMB, TypeMG, TypeMR, TypeMD, TypeMF, TypePTR:
+		_, err = strictName(msg, off)
+	case TypeMX:
+		_, err = strictName(msg, off+2)
+	case TypeSOA, TypeMINFO:
+		if off, err = strictName(msg, off); err == nil {
+			_, err = strictName(msg, off)
+		}
+	}
+	return err
+}
+
+// strictName checks the name at off, and returns the offset after it.
+func strictName(msg []byte, off int) (int, error) {
+	var visited []int // pointers followed
+	end := -1
+	for {
+		if off >= len(msg) {
+			return len(msg), ErrBuf
+		}
+		c := int(msg[off])
+		switch c & 0xC0 {
+		case 0x00:
+			if c == 0 {
+				if end < 0 {
+					end = off + 1
+				}
+				return end, nil
+			}
+			off += 1 + c
+		case 0xC0:
+			if off+1 >= len(msg) {
+				return len(msg), ErrBuf
+			}
+			ptr := (c^0xC0)<<8 | int(msg[off+1])
+			if end < 0 {
+				end = off + 2
+			}
+			for _, v := range append(visited, off) {
+				if v == ptr {
+					return end, &strictOffsetError{off, ErrPointerLoop}
+				}
+			}
+			if ptr >= off {
+				return end, &strictOffsetError{off, ErrForwardPointer}
+			}
+			visited = append(visited, off)
+			off = ptr
+		default:
+			// Unpack rejects the other label types.
+			return end, ErrRdata
+		}
+	}
+}
+
+var (
+	strictOnce  sync.Once
+	strictTypes map[uint16]bool // types with names in rdata that must not be compressed
+)
+
+// strictNoCompression reports whether the rdata of rrtype has domain names
+// that must not be compressed.
+func strictNoCompression(rrtype uint16) bool {
+	strictOnce.Do(func() {
+		strictTypes = make(map[uint16]bool)
+		for t, f := range TypeToRR {
+			if hasDomainNameField(reflect.TypeOf(f()).Elem()) {
+				strictTypes[t] = true
+			}
+		}
+	})
+	return strictTypes[rrtype]
+}
+
+// hasDomainNameField reports whether the struct t has a field tagged as a
+// domain name that isn't compressible.
+func hasDomainNameField(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Type != reflect.TypeOf(RR_Header{}) {
+			if hasDomainNameField(f.Type) {
+				return true
+			}
+			continue
+		}
+		if f.Tag.Get("dns") == "domain-name" {
+			return true
+		}
+	}
+	return false
+}
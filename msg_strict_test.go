@@ -0,0 +1,71 @@
+package dns
+
+import "testing"
+
+func TestUnpackStrict(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeMX)
+	m.Answer = []RR{testRR("example.org. 3600 IN MX 10 mx.example.org.")}
+	m.Compress = true
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := new(Msg).UnpackStrict(buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		msg     []byte
+		err     error
+		section string
+	}{
+		{"trailing data", append(append([]byte(nil), buf...), 0), ErrTrailingData, ""},
+		{"forward pointer", []byte{
+			0, 1, 0x81, 0x80, 0, 1, 0, 1, 0, 0, 0, 0,
+			0xC0, 18, 0, 15, 0, 1, // question, the name points to the answer
+			7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'o', 'r', 'g', 0, 0, 15, 0, 1, 0, 0, 14, 16, 0, 4, 0, 10, 0xC0, 18,
+		}, ErrForwardPointer, "question"},
+	}
+	for _, tc := range tests {
+		err := new(Msg).UnpackStrict(tc.msg)
+		e, ok := err.(*UnpackError)
+		if !ok || e.Err != tc.err || e.Section != tc.section {
+			t.Errorf("%s: expected %v in %q, got %v", tc.name, tc.err, tc.section, err)
+		}
+	}
+}
+
+func TestUnpackStrictLoop(t *testing.T) {
+	// A question name pointing to itself.
+	msg := []byte{0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0xC0, 12, 0, 1, 0, 1}
+	if err := checkStrict(msg); err == nil || err.(*UnpackError).Err != ErrPointerLoop {
+		t.Errorf("expected a pointer loop, got %v", err)
+	}
+}
+
+func TestUnpackStrictRdataCompression(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeSRV)
+	m.Answer = []RR{testRR("example.org. 3600 IN SRV 0 0 53 example.org.")}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := new(Msg).UnpackStrict(buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Compress the target of the SRV record, pointing to the question name.
+	n := len(buf) - 13
+	buf = append(buf[:n], 0xC0, 12)
+	buf[n-7] -= 11 // rdlength
+	if err := new(Msg).Unpack(buf); err != nil {
+		t.Fatalf("expected Unpack to accept the record, got %v", err)
+	}
+	err = new(Msg).UnpackStrict(buf)
+	if e, ok := err.(*UnpackError); !ok || e.Err != ErrRdataCompression || e.Section != "answer" || e.Offset != n-6 {
+		t.Errorf("expected a compressed name in the rdata of answer 0, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"bytes"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -306,3 +307,118 @@ func TestPackUnpackManyCompressionPointers(t *testing.T) {
 		}
 	}
 }
+
+func TestMsgReset(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeMX)
+	m.Answer = []RR{testRR("miek.nl. 3600 IN MX 10 mx1.miek.nl."), testRR("miek.nl. 3600 IN MX 20 mx2.miek.nl.")}
+	m.Compress = true
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	answer := m.Answer
+	m.Reset()
+	if m.Id != 0 || m.Compress || len(m.Question) != 0 || len(m.Answer) != 0 {
+		t.Fatalf("expected empty message, got %v", m)
+	}
+	if answer[0] != nil {
+		t.Error("expected records to be cleared")
+	}
+
+	if err := m.UnpackBuffer(buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Answer) != 2 || &m.Answer[0] != &answer[0] {
+		t.Error("expected answer section to be reused")
+	}
+	if m.Answer[0].(*MX).Mx != "mx1.miek.nl." || m.Question[0].Name != "miek.nl." {
+		t.Errorf("unexpected message:\n%s", m)
+	}
+}
+
+func TestAppendDomainName(t *testing.T) {
+	buf := make([]byte, 0, 256)
+	msg := make([]byte, 64)
+	off, _ := PackDomainName("www.example.org.", msg, 0, nil, false)
+	root, _ := PackDomainName(".", msg, off, nil, false)
+
+	buf = append(buf, "name: "...)
+	buf, off1, err := AppendDomainName(buf, msg, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "name: www.example.org." || off1 != off {
+		t.Errorf("unexpected name %q at %d", buf, off1)
+	}
+	buf, _, err = AppendDomainName(buf[:0], msg, off)
+	if err != nil || string(buf) != "." || root != off+1 {
+		t.Errorf("expected the root name, got %q, %v", buf, err)
+	}
+	if n := testing.AllocsPerRun(10, func() { AppendDomainName(buf[:0], msg, 0) }); n != 0 {
+		t.Errorf("expected no allocations, got %v", n)
+	}
+}
+
+func TestUnpackCompressedOwnerNames(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	for i := 0; i < 20; i++ {
+		m.Answer = append(m.Answer, testRR(fmt.Sprintf("example.org. 3600 IN A 192.0.2.%d", i)))
+		m.Ns = append(m.Ns, testRR(fmt.Sprintf("ns%d.Example.org. 3600 IN A 192.0.2.%d", i, i)))
+	}
+	m.Compress = true
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	for i := range m.Answer {
+		if m1.Answer[i].String() != m.Answer[i].String() || m1.Ns[i].String() != m.Ns[i].String() {
+			t.Errorf("expected %s and %s, got %s and %s", m.Answer[i], m.Ns[i], m1.Answer[i], m1.Ns[i])
+		}
+	}
+}
+
+func TestPackBufferCompression(t *testing.T) {
+	c := new(Compression)
+	buf := make([]byte, 512)
+	for _, name := range []string{"example.org.", "www.example.net.", "example.org."} {
+		m := new(Msg)
+		m.SetQuestion(name, TypeMX)
+		m.Answer = []RR{testRR(name + " 3600 IN MX 10 mx." + name), testRR(name + " 3600 IN MX 20 mx2." + name)}
+		m.Compress = true
+		want, err := m.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := m.PackBufferCompression(buf, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: expected %x, got %x", name, want, got)
+		}
+	}
+
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeMX)
+	m.Answer = []RR{testRR("example.org. 3600 IN MX 10 mx.example.org.")}
+	m.Compress = true
+	n := testing.AllocsPerRun(10, func() { m.PackBufferCompression(buf, c) })
+	if n0 := testing.AllocsPerRun(10, func() { m.PackBuffer(buf) }); n >= n0 {
+		t.Errorf("expected fewer allocations than PackBuffer's %v, got %v", n0, n)
+	}
+
+	// Names, escaped or not, are packed without allocating.
+	m.Answer = append(m.Answer, testRR(`a\.b\000.example.org. 3600 IN MX 10 mx\.1.example.org.`))
+	c.Reset()
+	m.PackBufferCompression(buf, c)
+	if n := testing.AllocsPerRun(10, func() { m.PackBufferCompression(buf, c) }); n != 0 {
+		t.Errorf("expected no allocations, got %v", n)
+	}
+}
@@ -0,0 +1,94 @@
+package dns
+
+// Truncate shrinks dns to fit in size bytes, as Len computes it, by removing
+// whole RRsets: those of the additional section first, then those of the
+// authority section, then those at the end of the answer section. An RRset is
+// never split, and the RRSIG records that follow an RRset go with it. The OPT
+// and TSIG records are kept.
+//
+// The TC bit is set when answer or authority records were removed, it is not
+// when only additional records were (RFC 2181, Section 9). A size below 512,
+// the size every client accepts, is taken as 512. Truncate doesn't do anything
+// when dns already fits.
+func (dns *Msg) Truncate(size int) {
+	if size < MinMsgSize {
+		size = MinMsgSize
+	}
+	if dns.Len() <= size {
+		return
+	}
+
+	var extra, pseudo []RR
+	for _, rr := range dns.Extra {
+		if t := rr.Header().Rrtype; t == TypeOPT || t == TypeTSIG {
+			pseudo = append(pseudo, rr)
+		} else {
+			extra = append(extra, rr)
+		}
+	}
+
+	t := &Msg{MsgHdr: dns.MsgHdr, Compress: dns.Compress, CompressPolicy: dns.CompressPolicy, Question: dns.Question, Extra: pseudo}
+	answer, full := truncateRRsets(dns.Answer, func(rrs []RR) bool {
+		t.Answer = rrs
+		return t.Len() <= size
+	})
+	var ns []RR
+	if full {
+		ns, full = truncateRRsets(dns.Ns, func(rrs []RR) bool {
+			t.Ns = rrs
+			return t.Len() <= size
+		})
+	}
+	if full {
+		extra, _ = truncateRRsets(extra, func(rrs []RR) bool {
+			t.Extra = append(rrs[:len(rrs):len(rrs)], pseudo...)
+			return t.Len() <= size
+		})
+	} else {
+		dns.Truncated = true
+		extra = nil
+	}
+
+	dns.Answer, dns.Ns = answer, ns
+	dns.Extra = append(extra[:len(extra):len(extra)], pseudo...)
+}
+
+// truncateRRsets returns the longest run of whole RRsets at the start of rrs
+// for which fits returns true, and whether that is all of rrs. It leaves fits
+// called with that run.
+func truncateRRsets(rrs []RR, fits func([]RR) bool) ([]RR, bool) {
+	for i := 0; i < len(rrs); {
+		j := rrsetEnd(rrs, i)
+		if !fits(rrs[:j]) {
+			fits(rrs[:i])
+			return rrs[:i], false
+		}
+		i = j
+	}
+	return rrs, true
+}
+
+// rrsetEnd returns the end of the RRset that starts at rrs[i], including the
+// RRSIG records covering it that follow.
+func rrsetEnd(rrs []RR, i int) int {
+	h := rrs[i].Header()
+	j := i + 1
+	for j < len(rrs) {
+		h2 := rrs[j].Header()
+		if h2.Rrtype != h.Rrtype || h2.Class != h.Class || !equal(h2.Name, h.Name) {
+			break
+		}
+		j++
+	}
+	if h.Rrtype == TypeRRSIG {
+		return j
+	}
+	for j < len(rrs) {
+		sig, ok := rrs[j].(*RRSIG)
+		if !ok || sig.TypeCovered != h.Rrtype || !equal(sig.Hdr.Name, h.Name) {
+			break
+		}
+		j++
+	}
+	return j
+}
@@ -0,0 +1,72 @@
+package dns
+
+import (
+	"fmt"
+	"testing"
+)
+
+func truncateTestMsg() *Msg {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	m.Response = true
+	for i := 0; i < 40; i++ {
+		m.Answer = append(m.Answer, testRR(fmt.Sprintf("example.org. 3600 IN A 192.0.2.%d", i)))
+	}
+	m.Answer = append(m.Answer, testRR("example.org. 3600 IN RRSIG A 8 2 3600 20190101000000 20180101000000 12345 example.org. dGVzdA=="))
+	for i := 0; i < 10; i++ {
+		m.Answer = append(m.Answer, testRR(fmt.Sprintf("www.example.org. 3600 IN TXT \"text %02d for the second RRset\"", i)))
+	}
+	for i := 0; i < 4; i++ {
+		m.Ns = append(m.Ns, testRR(fmt.Sprintf("example.org. 3600 IN NS ns%d.example.org.", i)))
+	}
+	for i := 0; i < 4; i++ {
+		m.Extra = append(m.Extra, testRR(fmt.Sprintf("ns%d.example.org. 3600 IN A 192.0.2.%d", i, 53+i)))
+	}
+	m.SetEdns0(4096, true)
+	return m
+}
+
+func TestMsgTruncate(t *testing.T) {
+	full := truncateTestMsg()
+	noExtra := truncateTestMsg()
+	noExtra.Extra = noExtra.Extra[len(noExtra.Extra)-1:]
+	firstSet := truncateTestMsg()
+	firstSet.Answer, firstSet.Ns = firstSet.Answer[:41], nil
+	firstSet.Extra = firstSet.Extra[len(firstSet.Extra)-1:]
+
+	for _, tc := range []struct {
+		size              int
+		answer, ns, extra int
+		truncated         bool
+	}{
+		{full.Len(), 51, 4, 5, false},
+		{full.Len() - 1, 51, 4, 4, false},
+		{noExtra.Len(), 51, 4, 1, false},
+		{noExtra.Len() - 1, 51, 0, 1, true},
+		{firstSet.Len(), 41, 0, 1, true},
+		{firstSet.Len() - 1, 0, 0, 1, true},
+		{0, 0, 0, 1, true},
+	} {
+		m := truncateTestMsg()
+		m.Truncate(tc.size)
+		if len(m.Answer) != tc.answer || len(m.Ns) != tc.ns || len(m.Extra) != tc.extra || m.Truncated != tc.truncated {
+			t.Errorf("size %d: expected %d/%d/%d records and TC %t, got %d/%d/%d and %t", tc.size,
+				tc.answer, tc.ns, tc.extra, tc.truncated, len(m.Answer), len(m.Ns), len(m.Extra), m.Truncated)
+		}
+		if m.IsEdns0() == nil {
+			t.Errorf("size %d: expected OPT record to be kept", tc.size)
+		}
+		if tc.size >= MinMsgSize && m.Len() > tc.size {
+			t.Errorf("size %d: message of %d bytes doesn't fit", tc.size, m.Len())
+		}
+	}
+}
+
+func TestMsgTruncateTSIG(t *testing.T) {
+	m := truncateTestMsg()
+	m.SetTsig("axfr.", HmacSHA256, 300, 0)
+	m.Truncate(MinMsgSize)
+	if !m.Truncated || len(m.Extra) != 2 || m.IsTsig() == nil || m.IsEdns0() == nil {
+		t.Errorf("expected TC with OPT and TSIG records, got %v", m)
+	}
+}
@@ -0,0 +1,141 @@
+package dns
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Normalize returns a copy of dns in a canonical form, so messages can be
+// compared by their meaning, e.g. in tests: the ID is zeroed, names are lower
+// cased as in the canonical form of RFC 4034, Section 6.2, and the records of
+// each section are sorted by name in canonical order, then by type and rdata,
+// with the OPT and TSIG records last in the Additional section.
+func (dns *Msg) Normalize() *Msg {
+	m := dns.Copy()
+	m.Id = 0
+	m.Compress = false
+	for i := range m.Question {
+		m.Question[i].Name = strings.ToLower(m.Question[i].Name)
+	}
+	for _, section := range [][]RR{m.Answer, m.Ns, m.Extra} {
+		for _, rr := range section {
+			canonicalize(rr)
+		}
+		sortCanonical(section)
+		sort.SliceStable(section, func(i, j int) bool { return pseudoOrder(section[i]) < pseudoOrder(section[j]) })
+	}
+	return m
+}
+
+// pseudoOrder is the order of the pseudo records at the end of the
+// Additional section, after the other records.
+func pseudoOrder(rr RR) int {
+	switch rr.Header().Rrtype {
+	case TypeOPT:
+		return 1
+	case TypeTSIG, TypeSIG:
+		return 2
+	}
+	return 0
+}
+
+// MsgDiff compares the messages a and b once normalized with Normalize, and
+// returns their differences, one per line, or "" when they mean the same.
+// Header fields are shown as "rcode: NOERROR -> NXDOMAIN", and records as in
+// a unified diff, "-" for the ones only in a and "+" for the ones only in b,
+// under the name of their section:
+//
+//	if diff := dns.MsgDiff(got, want); diff != "" {
+//		t.Errorf("unexpected response:\n%s", diff)
+//	}
+func MsgDiff(a, b *Msg) string {
+	na, nb := a.Normalize(), b.Normalize()
+	var d strings.Builder
+
+	field := func(name, va, vb string) {
+		if va != vb {
+			d.WriteString(name + ": " + va + " -> " + vb + "\n")
+		}
+	}
+	flag := func(name string, fa, fb bool) {
+		field(name, strconv.FormatBool(fa), strconv.FormatBool(fb))
+	}
+	field("opcode", opcodeString(na.Opcode), opcodeString(nb.Opcode))
+	field("rcode", rcodeString(na.Rcode), rcodeString(nb.Rcode))
+	flag("qr", na.Response, nb.Response)
+	flag("aa", na.Authoritative, nb.Authoritative)
+	flag("tc", na.Truncated, nb.Truncated)
+	flag("rd", na.RecursionDesired, nb.RecursionDesired)
+	flag("ra", na.RecursionAvailable, nb.RecursionAvailable)
+	flag("z", na.Zero, nb.Zero)
+	flag("ad", na.AuthenticatedData, nb.AuthenticatedData)
+	flag("cd", na.CheckingDisabled, nb.CheckingDisabled)
+
+	var qa, qb []string
+	for _, q := range na.Question {
+		qa = append(qa, q.String())
+	}
+	for _, q := range nb.Question {
+		qb = append(qb, q.String())
+	}
+	diffLines(&d, "QUESTION", qa, qb)
+	diffLines(&d, "ANSWER", rrStrings(na.Answer), rrStrings(nb.Answer))
+	diffLines(&d, "AUTHORITY", rrStrings(na.Ns), rrStrings(nb.Ns))
+	diffLines(&d, "ADDITIONAL", rrStrings(na.Extra), rrStrings(nb.Extra))
+	return d.String()
+}
+
+func opcodeString(opcode int) string {
+	if s, ok := OpcodeToString[opcode]; ok {
+		return s
+	}
+	return strconv.Itoa(opcode)
+}
+
+func rcodeString(rcode int) string {
+	if s, ok := RcodeToString[rcode]; ok {
+		return s
+	}
+	return strconv.Itoa(rcode)
+}
+
+// rrStrings returns the text of rrs, on a single line each.
+func rrStrings(rrs []RR) []string {
+	s := make([]string, len(rrs))
+	for i, rr := range rrs {
+		s[i] = strings.Replace(rr.String(), "\n", " ", -1)
+	}
+	return s
+}
+
+// diffLines writes to d the lines only in a, prefixed with "-", and the lines
+// only in b, prefixed with "+", under a line with the name of their section.
+func diffLines(d *strings.Builder, section string, a, b []string) {
+	used := make([]bool, len(b))
+	var lines []string
+	for _, la := range a {
+		found := false
+		for j, lb := range b {
+			if !used[j] && la == lb {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			lines = append(lines, "- "+la)
+		}
+	}
+	for j, lb := range b {
+		if !used[j] {
+			lines = append(lines, "+ "+lb)
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	d.WriteString(";; " + section + " SECTION:\n")
+	for _, l := range lines {
+		d.WriteString(l + "\n")
+	}
+}
@@ -0,0 +1,49 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMsgDiff(t *testing.T) {
+	a := new(Msg)
+	a.SetQuestion("Example.ORG.", TypeA)
+	a.Answer = []RR{
+		testRR("www.example.org. 300 IN A 192.0.2.2"),
+		testRR("WWW.example.org. 300 IN A 192.0.2.1"),
+	}
+	a.SetEdns0(1232, true)
+	a.Extra = append([]RR{testRR("ns.example.org. 300 IN A 192.0.2.53")}, a.Extra...)
+
+	b := new(Msg)
+	b.SetQuestion("example.org.", TypeA)
+	b.Answer = []RR{
+		testRR("www.example.org. 300 IN A 192.0.2.1"),
+		testRR("www.example.org. 300 IN A 192.0.2.2"),
+	}
+	b.SetEdns0(1232, true)
+	b.Extra = append(b.Extra, testRR("ns.example.org. 300 IN A 192.0.2.53"))
+
+	if diff := MsgDiff(a, b); diff != "" {
+		t.Errorf("expected no difference, got\n%s", diff)
+	}
+	if n := a.Normalize(); n.Id != 0 || n.Extra[1].Header().Rrtype != TypeOPT || n.Answer[0].(*A).A.String() != "192.0.2.1" {
+		t.Errorf("unexpected normalized message\n%s", n)
+	}
+	if a.Answer[0].Header().Name != "www.example.org." {
+		t.Error("expected the message not to be modified")
+	}
+
+	b.Rcode = RcodeNameError
+	b.Answer = b.Answer[:1]
+	b.Ns = []RR{testRR("example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 60")}
+	want := "rcode: NOERROR -> NXDOMAIN\n" +
+		";; ANSWER SECTION:\n- www.example.org.\t300\tIN\tA\t192.0.2.2\n" +
+		";; AUTHORITY SECTION:\n+ example.org.\t300\tIN\tSOA\tns.example.org. hostmaster.example.org. 1 3600 600 86400 60\n"
+	if diff := MsgDiff(a, b); diff != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, diff)
+	}
+	if !strings.HasPrefix(MsgDiff(a, a.Copy().SetEdns0(4096, false)), ";; ADDITIONAL SECTION:\n") {
+		t.Error("expected the OPT records to differ")
+	}
+}
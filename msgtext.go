@@ -0,0 +1,342 @@
+package dns
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseMsgText parses a message in the text form of dig, drill or
+// Msg.String, e.g. a response recorded from a real server, so it can be used
+// as a test fixture or replayed through a server. See ReadMsgText.
+func ParseMsgText(s string) (*Msg, error) {
+	msgs, err := ReadMsgText(strings.NewReader(s))
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) != 1 {
+		return nil, &Error{err: "msg text: expected one message, got " + strconv.Itoa(len(msgs))}
+	}
+	return msgs[0], nil
+}
+
+// ReadMsgText reads the messages in the text form of dig, drill or
+// Msg.String from r, e.g. a file of recorded responses. Each message starts
+// with its header line, holding its opcode, status and ID, followed by the
+// flags line, the sections, each under its marker line such as
+// ";; ANSWER SECTION:", and the OPT pseudosection. The EDNS version, flags,
+// UDP size, and the COOKIE, NSID and EDE options are read from the latter.
+// Other comments, such as ";; Query time: 1 msec", are ignored, and the
+// record counts of the flags line aren't checked.
+func ReadMsgText(r io.Reader) ([]*Msg, error) {
+	var (
+		msgs    []*Msg
+		m       *Msg
+		opt     *OPT
+		section *[]RR
+		inOPT   bool
+		pending string // record continued on the next lines, in parentheses
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), MaxMsgSize*2)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if pending != "" {
+			// Keep the line breaks: comments in the record end at them.
+			line = pending + "\n" + line
+			pending = ""
+		}
+		lineErr := func(err string) error {
+			return &Error{err: "msg text: line " + strconv.Itoa(n) + ": " + err}
+		}
+
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, ";") && strings.Contains(line, "opcode:") && strings.Contains(line, "status:"):
+			if m != nil {
+				msgs = append(msgs, finishMsgText(m, opt))
+			}
+			m, opt, section, inOPT = new(Msg), nil, nil, false
+			if err := parseMsgTextHeader(m, line); err != "" {
+				return nil, lineErr(err)
+			}
+			continue
+		case m == nil:
+			continue // e.g. the command line printed by dig
+		case strings.HasPrefix(line, ";; flags:"):
+			parseMsgTextFlags(m, strings.TrimPrefix(line, ";; flags:"))
+			continue
+		case strings.HasPrefix(line, ";;") && strings.HasSuffix(line, "SECTION:"):
+			inOPT = strings.Contains(line, "OPT PSEUDOSECTION")
+			switch {
+			case inOPT:
+				if opt == nil {
+					opt = &OPT{Hdr: RR_Header{Name: ".", Rrtype: TypeOPT}}
+				}
+			case strings.Contains(line, "QUESTION"):
+				section = nil
+			case strings.Contains(line, "ANSWER"):
+				section = &m.Answer
+			case strings.Contains(line, "AUTHORITY"):
+				section = &m.Ns
+			case strings.Contains(line, "ADDITIONAL"):
+				section = &m.Extra
+			default:
+				return nil, lineErr("unknown section " + line)
+			}
+			continue
+		case inOPT && strings.HasPrefix(line, ";"):
+			if err := parseMsgTextOPT(opt, strings.TrimSpace(strings.TrimPrefix(line, ";"))); err != "" {
+				return nil, lineErr(err)
+			}
+			continue
+		case section == nil && !inOPT && strings.HasPrefix(line, ";") && !strings.HasPrefix(line, ";;"):
+			q, err := parseMsgTextQuestion(strings.TrimPrefix(line, ";"))
+			if err != "" {
+				return nil, lineErr(err)
+			}
+			m.Question = append(m.Question, q)
+			continue
+		case strings.HasPrefix(line, ";"):
+			continue
+		}
+
+		if inOPT || section == nil {
+			return nil, lineErr("record outside of a section: " + line)
+		}
+		if parenOpen(line) {
+			pending = line
+			continue
+		}
+		rr, err := NewRR(line)
+		if err != nil {
+			return nil, lineErr(err.Error())
+		}
+		if rr != nil {
+			*section = append(*section, rr)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if pending != "" {
+		return nil, &Error{err: "msg text: unbalanced parentheses"}
+	}
+	if m != nil {
+		msgs = append(msgs, finishMsgText(m, opt))
+	}
+	return msgs, nil
+}
+
+// parenOpen reports whether the record s, in presentation format and maybe
+// over several lines, has a parenthesis left open. Parentheses that are
+// escaped, quoted or in a comment don't count.
+func parenOpen(s string) bool {
+	depth, quoted, comment := 0, false, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case comment:
+			comment = c != '\n'
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == ';':
+			comment = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// finishMsgText adds opt, if any, to the Additional section of m, before
+// its TSIG record.
+func finishMsgText(m *Msg, opt *OPT) *Msg {
+	if opt == nil {
+		return m
+	}
+	if t := len(m.Extra) - 1; t >= 0 && m.Extra[t].Header().Rrtype == TypeTSIG {
+		m.Extra = append(m.Extra[:t], opt, m.Extra[t])
+	} else {
+		m.Extra = append(m.Extra, opt)
+	}
+	return m
+}
+
+// parseMsgTextHeader parses the header line, e.g.
+// ";; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 4711".
+func parseMsgTextHeader(m *Msg, line string) string {
+	for _, f := range strings.Split(line[strings.Index(line, "opcode:"):], ",") {
+		kv := strings.SplitN(strings.TrimSpace(f), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		v := strings.TrimSpace(kv[1])
+		switch kv[0] {
+		case "opcode":
+			op, ok := StringToOpcode[v]
+			if !ok {
+				return "unknown opcode " + v
+			}
+			m.Opcode = op
+		case "status":
+			rcode, ok := StringToRcode[v]
+			if v == "BADVERS" {
+				rcode, ok = RcodeBadVers, true
+			}
+			if !ok {
+				return "unknown status " + v
+			}
+			m.Rcode = rcode
+		case "id":
+			id, err := strconv.ParseUint(v, 10, 16)
+			if err != nil {
+				return "bad id " + v
+			}
+			m.Id = uint16(id)
+		}
+	}
+	return ""
+}
+
+// parseMsgTextFlags parses the flags of the flags line, e.g.
+// " qr rd ra; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 1".
+func parseMsgTextFlags(m *Msg, line string) {
+	if i := strings.Index(line, ";"); i >= 0 {
+		line = line[:i]
+	}
+	for _, f := range strings.Fields(line) {
+		switch f {
+		case "qr":
+			m.Response = true
+		case "aa":
+			m.Authoritative = true
+		case "tc":
+			m.Truncated = true
+		case "rd":
+			m.RecursionDesired = true
+		case "ra":
+			m.RecursionAvailable = true
+		case "z":
+			m.Zero = true
+		case "ad":
+			m.AuthenticatedData = true
+		case "cd":
+			m.CheckingDisabled = true
+		}
+	}
+}
+
+// parseMsgTextQuestion parses a line of the question section, without its
+// leading ";", e.g. "example.org.	IN	A".
+func parseMsgTextQuestion(line string) (Question, string) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return Question{}, "bad question " + line
+	}
+	q := Question{Name: fields[0], Qclass: ClassINET}
+	for _, f := range fields[1:] {
+		f = strings.ToUpper(f)
+		if c, ok := StringToClass[f]; ok {
+			q.Qclass = c
+		} else if t, ok := StringToType[f]; ok {
+			q.Qtype = t
+		} else if t, ok := stringToTypeGeneric(f); ok {
+			q.Qtype = t
+		} else {
+			return Question{}, "bad question " + line
+		}
+	}
+	return q, ""
+}
+
+// stringToTypeGeneric parses a type in the generic form of RFC 3597, e.g.
+// "TYPE65534".
+func stringToTypeGeneric(s string) (uint16, bool) {
+	if !strings.HasPrefix(s, "TYPE") {
+		return 0, false
+	}
+	t, err := strconv.ParseUint(s[4:], 10, 16)
+	return uint16(t), err == nil
+}
+
+// parseMsgTextOPT parses a line of the OPT pseudosection, without its leading
+// ";", e.g. "EDNS: version: 0, flags: do; udp: 1232" as dig prints it, or
+// "EDNS: version 0; flags: do; udp: 1232" as OPT.String does.
+func parseMsgTextOPT(opt *OPT, line string) string {
+	kv := strings.SplitN(line, ":", 2)
+	if len(kv) != 2 {
+		return ""
+	}
+	v := strings.TrimSpace(kv[1])
+	switch kv[0] {
+	case "EDNS":
+		for _, f := range strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == ',' }) {
+			f = strings.TrimSpace(f)
+			switch {
+			case strings.HasPrefix(f, "version"):
+				ver, err := strconv.ParseUint(strings.TrimSpace(strings.TrimLeft(f[len("version"):], ": ")), 10, 8)
+				if err != nil {
+					return "bad EDNS version " + f
+				}
+				opt.SetVersion(uint8(ver))
+			case strings.HasPrefix(f, "flags:"):
+				for _, flag := range strings.Fields(f[len("flags:"):]) {
+					switch flag {
+					case "do":
+						opt.SetDo()
+					case "co":
+						opt.SetCo(true)
+					}
+				}
+			case strings.HasPrefix(f, "udp:"):
+				size, err := strconv.ParseUint(strings.TrimSpace(f[len("udp:"):]), 10, 16)
+				if err != nil {
+					return "bad EDNS UDP size " + f
+				}
+				opt.SetUDPSize(uint16(size))
+			}
+		}
+	case "COOKIE":
+		opt.Option = append(opt.Option, &EDNS0_COOKIE{Code: EDNS0COOKIE, Cookie: firstField(v)})
+	case "NSID":
+		// dig prints the NSID as spaced hex followed by its text in
+		// parentheses, OPT.String as hex followed by its characters in
+		// parentheses.
+		nsid := v
+		if i := strings.Index(v, "("); i >= 0 {
+			nsid = v[:i]
+		}
+		nsid = strings.Replace(nsid, " ", "", -1)
+		if _, err := hex.DecodeString(nsid); err != nil {
+			nsid = hex.EncodeToString([]byte(v))
+		}
+		opt.Option = append(opt.Option, &EDNS0_NSID{Code: EDNS0NSID, Nsid: nsid})
+	case "EDE":
+		code, err := strconv.ParseUint(firstField(strings.TrimSuffix(firstField(v), ":")), 10, 16)
+		if err != nil {
+			return "bad EDE " + v
+		}
+		e := &EDNS0_EDE{InfoCode: uint16(code)}
+		if i, j := strings.LastIndex(v, "("), strings.LastIndex(v, ")"); i >= 0 && j > i && strings.Contains(v, ": (") {
+			e.ExtraText = strings.Trim(v[i+1:j], "\"")
+		}
+		opt.Option = append(opt.Option, e)
+	}
+	return ""
+}
+
+// firstField returns the first field of s.
+func firstField(s string) string {
+	if f := strings.Fields(s); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+)
+
+const digOutput = `
+; <<>> DiG 9.18.18 <<>> @192.0.2.53 example.org MX +dnssec
+; (1 server found)
+;; global options: +cmd
+;; Got answer:
+;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 36049
+;; flags: qr rd ra ad; QUERY: 1, ANSWER: 2, AUTHORITY: 0, ADDITIONAL: 1
+
+;; OPT PSEUDOSECTION:
+; EDNS: version: 0, flags: do; udp: 1232
+; COOKIE: 0102030405060708e1e2e3e4e5e6e7e8 (good)
+; NSID: 67 70 64 6e 73 ("gpdns")
+; EDE: 3 (Stale Answer): (served stale)
+;; QUESTION SECTION:
+;example.org.			IN	MX
+
+;; ANSWER SECTION:
+example.org.		3600	IN	MX	10 mx1.example.org.
+example.org.		3600	IN	SOA	ns.example.org. hostmaster.example.org. (
+				2024010101 ; serial
+				3600 600 86400 60 )
+
+;; Query time: 12 msec
+;; SERVER: 192.0.2.53#53(192.0.2.53) (UDP)
+;; MSG SIZE  rcvd: 152
+`
+
+func TestParseMsgTextDig(t *testing.T) {
+	m, err := ParseMsgText(digOutput)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if m.Id != 36049 || !m.Response || !m.RecursionDesired || !m.AuthenticatedData || m.Authoritative {
+		t.Errorf("unexpected header %v", m.MsgHdr)
+	}
+	if len(m.Question) != 1 || m.Question[0].Qtype != TypeMX || len(m.Answer) != 2 {
+		t.Fatalf("unexpected sections\n%s", m)
+	}
+	if soa, ok := m.Answer[1].(*SOA); !ok || soa.Serial != 2024010101 || soa.Minttl != 60 {
+		t.Errorf("unexpected SOA record %v", m.Answer[1])
+	}
+	opt := m.IsEdns0()
+	if opt == nil || !opt.Do() || opt.UDPSize() != 1232 || len(opt.Option) != 3 {
+		t.Fatalf("unexpected OPT record %v", opt)
+	}
+	if nsid := opt.Option[1].(*EDNS0_NSID); nsid.Nsid != "6770646e73" {
+		t.Errorf("unexpected NSID %q", nsid.Nsid)
+	}
+	if ede := opt.Option[2].(*EDNS0_EDE); ede.InfoCode != ExtendedErrorCodeStaleAnswer || ede.ExtraText != "served stale" {
+		t.Errorf("unexpected EDE %v", ede)
+	}
+	if _, err := m.Pack(); err != nil {
+		t.Errorf("failed to pack: %v", err)
+	}
+}
+
+func TestReadMsgTextRoundTrip(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	m.Response, m.Authoritative, m.Rcode = true, true, RcodeNameError
+	m.Ns = []RR{testRR("example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 60")}
+	m.Extra = []RR{testRR("ns.example.org. 300 IN A 192.0.2.53")}
+	m.SetEdns0(4096, true)
+	m.IsEdns0().Option = append(m.IsEdns0().Option, &EDNS0_NSID{Code: EDNS0NSID, Nsid: "6770646e73"})
+
+	q := new(Msg)
+	q.SetQuestion("example.net.", TypeTXT)
+
+	msgs, err := ReadMsgText(strings.NewReader(m.String() + "\n" + q.String()))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(msgs))
+	}
+	if diff := MsgDiff(m, msgs[0]); diff != "" || msgs[0].Id != m.Id {
+		t.Errorf("expected the same message, got\n%s", diff)
+	}
+	if diff := MsgDiff(q, msgs[1]); diff != "" {
+		t.Errorf("expected the same message, got\n%s", diff)
+	}
+
+	if _, err := ParseMsgText(";; opcode: QUERY, status: BOGUS, id: 1\n"); err == nil {
+		t.Error("expected an error for an unknown status")
+	}
+}
+
+func TestReadMsgTextParens(t *testing.T) {
+	text := `;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 1
+;; flags: qr; QUERY: 1, ANSWER: 3, AUTHORITY: 0, ADDITIONAL: 0
+
+;; QUESTION SECTION:
+;example.org.	IN	TXT
+
+;; ANSWER SECTION:
+example.org.	3600	IN	TXT	"a (smiley :-("
+example.org.	3600	IN	TXT	"b" ; a comment (
+example.org.	3600	IN	TXT	( "c :-)"
+				"d" )
+`
+	m, err := ParseMsgText(text)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if len(m.Answer) != 3 {
+		t.Fatalf("expected 3 records, got\n%s", m)
+	}
+	if txt := m.Answer[2].(*TXT).Txt; len(txt) != 2 || txt[0] != "c :-)" {
+		t.Errorf("unexpected TXT record %v", m.Answer[2])
+	}
+}
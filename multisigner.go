@@ -0,0 +1,212 @@
+package dns
+
+import (
+	"strconv"
+	"time"
+)
+
+// A MultiSigner is one of the providers signing a zone in a multi-signer
+// setup (RFC 8901), each with its own keys: the DNSKEY RRset it serves must
+// hold the zone signing keys of all the providers, so resolvers can validate
+// the signatures of any of them.
+type MultiSigner struct {
+	Name   string // name of the provider, for errors
+	DNSKEY []RR   // DNSKEY RRset served by the provider, with its RRSIGs
+}
+
+// A MultiSignerError is a problem found by CheckMultiSigner in the DNSKEY
+// RRset of a provider.
+type MultiSignerError struct {
+	Signer  string    // name of the provider
+	Missing []*DNSKEY // zone signing keys of the other providers missing from the RRset
+	Err     error     // error verifying the RRset with the key signing keys of the provider, if any
+}
+
+func (e *MultiSignerError) Error() string {
+	s := "multi-signer: " + e.Signer + ":"
+	if len(e.Missing) > 0 {
+		s += " " + strconv.Itoa(len(e.Missing)) + " zone signing keys missing"
+	}
+	if e.Err != nil {
+		if len(e.Missing) > 0 {
+			s += ","
+		}
+		s += " " + e.Err.Error()
+	}
+	return s
+}
+
+// MergeDNSKEY returns the DNSKEY records of the sets, without duplicates.
+// Other records, e.g. RRSIGs, are skipped.
+func MergeDNSKEY(sets ...[]RR) []*DNSKEY {
+	var keys []*DNSKEY
+	for _, set := range sets {
+		for _, rr := range set {
+			if k, ok := rr.(*DNSKEY); ok && !containsKey(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// MergeCDS returns the CDS records of the sets, without duplicates, that every
+// provider must publish so the parent has DS records for the key signing keys
+// of all of them. Delete signals (RFC 8078) are only returned when all the
+// sets are one.
+func MergeCDS(sets ...[]RR) []*CDS {
+	var cds []*CDS
+	deletes := 0
+	for _, set := range sets {
+		for _, rr := range set {
+			c, ok := rr.(*CDS)
+			if !ok {
+				continue
+			}
+			if c.IsDelete() {
+				deletes++
+				continue
+			}
+			if !containsDS(cdsToDS(cds), &c.DS) {
+				cds = append(cds, c)
+			}
+		}
+	}
+	if len(cds) == 0 && deletes > 0 && deletes == len(sets) {
+		return []*CDS{DeleteCDS(firstOwner(sets))}
+	}
+	return cds
+}
+
+// MergeCDNSKEY is like MergeCDS, for CDNSKEY records.
+func MergeCDNSKEY(sets ...[]RR) []*CDNSKEY {
+	var keys []*CDNSKEY
+	deletes := 0
+	for _, set := range sets {
+		for _, rr := range set {
+			c, ok := rr.(*CDNSKEY)
+			if !ok {
+				continue
+			}
+			if c.IsDelete() {
+				deletes++
+				continue
+			}
+			dup := false
+			for _, k := range keys {
+				dup = dup || sameKey(&k.DNSKEY, &c.DNSKEY)
+			}
+			if !dup {
+				keys = append(keys, c)
+			}
+		}
+	}
+	if len(keys) == 0 && deletes > 0 && deletes == len(sets) {
+		return []*CDNSKEY{DeleteCDNSKEY(firstOwner(sets))}
+	}
+	return keys
+}
+
+// MultiSignerImports returns, by provider name, the zone signing keys of the
+// other providers each provider must add to its DNSKEY RRset.
+func MultiSignerImports(signers []MultiSigner) map[string][]*DNSKEY {
+	zsks := multiSignerZSKs(signers)
+	imports := make(map[string][]*DNSKEY)
+	for _, s := range signers {
+		have := MergeDNSKEY(s.DNSKEY)
+		for _, k := range zsks {
+			if !containsKey(have, k) {
+				imports[s.Name] = append(imports[s.Name], k)
+			}
+		}
+	}
+	return imports
+}
+
+// CheckMultiSigner checks the DNSKEY RRsets of the providers at time now: each
+// must hold the zone signing keys of all the providers, and be signed by one
+// of the key signing keys of the provider. It returns the problems found, nil
+// when there are none.
+func CheckMultiSigner(signers []MultiSigner, now time.Time) []*MultiSignerError {
+	zsks := multiSignerZSKs(signers)
+	var errs []*MultiSignerError
+	for _, s := range signers {
+		e := &MultiSignerError{Signer: s.Name}
+		keys, sigs := splitKeySet(s.DNSKEY)
+		for _, k := range zsks {
+			if !containsKey(keys, k) {
+				e.Missing = append(e.Missing, k)
+			}
+		}
+
+		rrset := make([]RR, len(keys))
+		for i, k := range keys {
+			rrset[i] = k
+		}
+		switch {
+		case len(keys) == 0:
+			e.Err = ErrKey
+		case len(sigs) == 0:
+			e.Err = ErrNoSig
+		default:
+			e.Err = ErrSig
+			for _, k := range ksks(rrset) {
+				if signedBy(k, rrset, sigs, now) {
+					e.Err = nil
+					break
+				}
+			}
+		}
+		if len(e.Missing) > 0 || e.Err != nil {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
+
+// multiSignerZSKs returns the zone signing keys of the providers: their keys
+// without the SEP flag.
+func multiSignerZSKs(signers []MultiSigner) []*DNSKEY {
+	var zsks []*DNSKEY
+	for _, s := range signers {
+		for _, k := range MergeDNSKEY(s.DNSKEY) {
+			if k.Flags&SEP == 0 && k.Flags&REVOKE == 0 && !containsKey(zsks, k) {
+				zsks = append(zsks, k)
+			}
+		}
+	}
+	return zsks
+}
+
+// containsKey reports whether keys holds k.
+func containsKey(keys []*DNSKEY, k *DNSKEY) bool {
+	for _, k1 := range keys {
+		if sameKey(k1, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameKey reports whether a and b are the same key, with the same flags.
+func sameKey(a, b *DNSKEY) bool {
+	return a.Flags == b.Flags && a.Protocol == b.Protocol && a.Algorithm == b.Algorithm && a.PublicKey == b.PublicKey
+}
+
+func cdsToDS(cds []*CDS) []*DS {
+	ds := make([]*DS, len(cds))
+	for i, c := range cds {
+		ds[i] = &c.DS
+	}
+	return ds
+}
+
+// firstOwner returns the owner name of the first record of sets.
+func firstOwner(sets [][]RR) string {
+	for _, set := range sets {
+		if len(set) > 0 {
+			return set[0].Header().Name
+		}
+	}
+	return "."
+}
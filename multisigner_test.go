@@ -0,0 +1,112 @@
+package dns
+
+import (
+	"crypto"
+	"testing"
+	"time"
+)
+
+// multiSignerProvider holds the keys of a provider of the multi-signer tests.
+type multiSignerProvider struct {
+	ksk, zsk             *DNSKEY
+	kskSigner, zskSigner crypto.Signer
+	otherZSKs            []*DNSKEY // ZSKs of the other providers in the DNSKEY RRset
+	signWithZSK          bool      // sign the DNSKEY RRset with the ZSK instead of the KSK
+}
+
+func newMultiSignerProvider(t *testing.T) *multiSignerProvider {
+	p := new(multiSignerProvider)
+	for _, k := range []struct {
+		key    **DNSKEY
+		signer *crypto.Signer
+		flags  uint16
+	}{{&p.ksk, &p.kskSigner, ZONE | SEP}, {&p.zsk, &p.zskSigner, ZONE}} {
+		key := &DNSKEY{
+			Hdr:       RR_Header{Name: "example.", Rrtype: TypeDNSKEY, Class: ClassINET, Ttl: 3600},
+			Flags:     k.flags,
+			Protocol:  3,
+			Algorithm: ECDSAP256SHA256,
+		}
+		priv, err := key.Generate(256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		*k.key, *k.signer = key, priv.(crypto.Signer)
+	}
+	return p
+}
+
+// dnskey returns the signed DNSKEY RRset of p.
+func (p *multiSignerProvider) dnskey(t *testing.T) []RR {
+	rrset := []RR{p.ksk, p.zsk}
+	for _, k := range p.otherZSKs {
+		rrset = append(rrset, k)
+	}
+	key, signer := p.ksk, p.kskSigner
+	if p.signWithZSK {
+		key, signer = p.zsk, p.zskSigner
+	}
+	now := time.Now()
+	sig := &RRSIG{
+		Hdr:        RR_Header{Name: "example.", Rrtype: TypeRRSIG, Class: ClassINET, Ttl: 3600},
+		KeyTag:     key.KeyTag(),
+		SignerName: "example.",
+		Algorithm:  key.Algorithm,
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(time.Hour).Unix()),
+	}
+	if err := sig.Sign(signer, rrset); err != nil {
+		t.Fatal(err)
+	}
+	return append(rrset, sig)
+}
+
+func TestMultiSigner(t *testing.T) {
+	a, b := newMultiSignerProvider(t), newMultiSignerProvider(t)
+	b.otherZSKs = []*DNSKEY{a.zsk}
+	signers := func() []MultiSigner {
+		return []MultiSigner{{Name: "a", DNSKEY: a.dnskey(t)}, {Name: "b", DNSKEY: b.dnskey(t)}}
+	}
+
+	if keys := MergeDNSKEY(a.dnskey(t), b.dnskey(t)); len(keys) != 4 {
+		t.Errorf("expected 4 keys merged, got %d", len(keys))
+	}
+
+	imports := MultiSignerImports(signers())
+	if len(imports["a"]) != 1 || !sameKey(imports["a"][0], b.zsk) || len(imports["b"]) != 0 {
+		t.Fatalf("expected a to import the ZSK of b, got %v", imports)
+	}
+	errs := CheckMultiSigner(signers(), time.Now())
+	if len(errs) != 1 || errs[0].Signer != "a" || len(errs[0].Missing) != 1 || errs[0].Err != nil {
+		t.Fatalf("expected a ZSK missing at a, got %v", errs)
+	}
+
+	a.otherZSKs = imports["a"]
+	if errs := CheckMultiSigner(signers(), time.Now()); len(errs) != 0 {
+		t.Errorf("expected no problems, got %v", errs)
+	}
+
+	b.signWithZSK = true
+	errs = CheckMultiSigner(signers(), time.Now())
+	if len(errs) != 1 || errs[0].Signer != "b" || errs[0].Err != ErrSig {
+		t.Errorf("expected the DNSKEY RRset of b not to be signed by its KSK, got %v", errs)
+	}
+}
+
+func TestMergeCDS(t *testing.T) {
+	a, b := newMultiSignerProvider(t), newMultiSignerProvider(t)
+	cdsA := []RR{a.ksk.ToDS(SHA256).ToCDS(), b.ksk.ToDS(SHA256).ToCDS()}
+	cdsB := []RR{b.ksk.ToDS(SHA256).ToCDS()}
+	if cds := MergeCDS(cdsA, cdsB); len(cds) != 2 {
+		t.Errorf("expected 2 CDS records, got %v", cds)
+	}
+	if cds := MergeCDS(cdsA, []RR{DeleteCDS("example.")}); len(cds) != 2 {
+		t.Errorf("expected the delete signal of a single provider to be ignored, got %v", cds)
+	}
+	if cds := MergeCDS([]RR{DeleteCDS("example.")}, []RR{DeleteCDS("example.")}); len(cds) != 1 || !cds[0].IsDelete() {
+		t.Errorf("expected a delete signal, got %v", cds)
+	}
+	if keys := MergeCDNSKEY([]RR{a.ksk.ToCDNSKEY()}, []RR{a.ksk.ToCDNSKEY(), b.ksk.ToCDNSKEY()}); len(keys) != 2 {
+		t.Errorf("expected 2 CDNSKEY records, got %v", keys)
+	}
+}
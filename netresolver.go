@@ -0,0 +1,247 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// A NetResolver sends the queries of applications to Address with an
+// Exchanger, such as a Client, a Cache, a DoHClient or a DNSCryptClient.
+//
+// Resolver returns it as a net.Resolver, so code written against the net
+// package uses it unchanged. LookupHost, LookupSRV and LookupTXT do the most
+// common lookups directly, without the search list of the system.
+type NetResolver struct {
+	Exchanger Exchanger // sends the queries, defaults to a UDP Client
+	Address   string    // address given to Exchanger, e.g. "192.0.2.53:53" or a DoH URL
+}
+
+// Resolver returns a net.Resolver that sends its queries with r. The
+// net.Resolver still applies the search list and ndots option of the system,
+// but the name servers of the system are ignored.
+func (r *NetResolver) Resolver() *net.Resolver {
+	return &net.Resolver{PreferGo: true, Dial: r.Dial}
+}
+
+// Dial can be used as the Dial function of a net.Resolver. It returns a
+// connection on which the queries written are sent with r to r.Address,
+// whatever network and address are given.
+func (r *NetResolver) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	return &netResolverConn{r: r, ctx: ctx}, nil
+}
+
+// LookupHost returns the IPv4 and IPv6 addresses of host.
+func (r *NetResolver) LookupHost(ctx context.Context, host string) ([]net.IP, error) {
+	type result struct {
+		in  *Msg
+		err error
+	}
+	results := make(chan result, 2)
+	for _, qtype := range []uint16{TypeA, TypeAAAA} {
+		go func(qtype uint16) {
+			in, err := r.lookup(ctx, host, qtype)
+			results <- result{in, err}
+		}(qtype)
+	}
+
+	var ips []net.IP
+	var err error
+	for i := 0; i < 2; i++ {
+		res := <-results
+		if res.err != nil {
+			err = res.err
+			continue
+		}
+		for _, rr := range res.in.Answer {
+			switch rr := rr.(type) {
+			case *A:
+				ips = append(ips, rr.A)
+			case *AAAA:
+				ips = append(ips, rr.AAAA)
+			}
+		}
+	}
+	if len(ips) == 0 && err != nil {
+		return nil, err
+	}
+	return ips, nil
+}
+
+// LookupSRV returns the SRV records of _service._proto.name, or of name if
+// service and proto are empty, sorted by priority and then by weight, the
+// heaviest first. It is Lookup.LookupSRV, with the records as net.SRVs.
+func (r *NetResolver) LookupSRV(ctx context.Context, service, proto, name string) ([]*net.SRV, error) {
+	rrs, _, err := r.lookuper().LookupSRV(ctx, service, proto, name)
+	if err != nil {
+		return nil, err
+	}
+	var srvs []*net.SRV
+	for _, srv := range rrs {
+		srvs = append(srvs, &net.SRV{Target: srv.Target, Port: srv.Port, Priority: srv.Priority, Weight: srv.Weight})
+	}
+	return srvs, nil
+}
+
+// LookupTXT returns the TXT records of name, the character-strings of each
+// joined together. It is Lookup.LookupTXT.
+func (r *NetResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	txts, _, err := r.lookuper().LookupTXT(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return txts, nil
+}
+
+// lookuper returns the Lookup that sends its queries as r does.
+func (r *NetResolver) lookuper() *Lookup {
+	return &Lookup{Exchanger: r.Exchanger, Address: r.Address}
+}
+
+// lookup queries the records of type qtype of name, and turns error rcodes
+// into errors.
+func (r *NetResolver) lookup(ctx context.Context, name string, qtype uint16) (*Msg, error) {
+	m := new(Msg)
+	m.SetQuestion(Fqdn(name), qtype)
+	m.SetEdns0(4096, false)
+	in, err := r.exchange(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	switch in.Rcode {
+	case RcodeSuccess:
+		return in, nil
+	case RcodeNameError:
+		return nil, &net.DNSError{Err: "no such host", Name: name, Server: r.Address}
+	}
+	return nil, &net.DNSError{Err: "server misbehaving", Name: name, Server: r.Address,
+		IsTemporary: in.Rcode == RcodeServerFailure}
+}
+
+// exchange sends m with r.Exchanger, and gives up when ctx is done.
+func (r *NetResolver) exchange(ctx context.Context, m *Msg) (*Msg, error) {
+	ex := r.Exchanger
+	if ex == nil {
+		ex = new(Client)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		in  *Msg
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		in, _, err := ex.Exchange(m, r.Address)
+		done <- result{in, err}
+	}()
+	select {
+	case res := <-done:
+		return res.in, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// netResolverConn is the connection NetResolver.Dial returns. It acts as a
+// TCP connection: the queries written and the responses read are prefixed
+// with their length. A query is sent as soon as it is written in full.
+type netResolverConn struct {
+	r   *NetResolver
+	ctx context.Context
+
+	mu       sync.Mutex
+	wbuf     bytes.Buffer // queries written
+	rbuf     bytes.Buffer // responses to read
+	deadline time.Time
+	closed   bool
+}
+
+func (c *netResolverConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	c.wbuf.Write(b)
+	deadline := c.deadline
+	c.mu.Unlock()
+
+	ctx := c.ctx
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	for {
+		c.mu.Lock()
+		p := c.wbuf.Bytes()
+		if len(p) < 2 || len(p) < 2+int(binary.BigEndian.Uint16(p)) {
+			c.mu.Unlock()
+			return len(b), nil
+		}
+		q := make([]byte, binary.BigEndian.Uint16(p))
+		c.wbuf.Next(2)
+		c.wbuf.Read(q)
+		c.mu.Unlock()
+
+		m := new(Msg)
+		if err := m.Unpack(q); err != nil {
+			return 0, err
+		}
+		in, err := c.r.exchange(ctx, m)
+		if err != nil {
+			return 0, err
+		}
+		out, err := in.Pack()
+		if err != nil {
+			return 0, err
+		}
+		c.mu.Lock()
+		c.rbuf.Write([]byte{byte(len(out) >> 8), byte(len(out))})
+		c.rbuf.Write(out)
+		c.mu.Unlock()
+	}
+}
+
+func (c *netResolverConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return c.rbuf.Read(b)
+}
+
+func (c *netResolverConn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *netResolverConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *netResolverConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *netResolverConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
+
+func (c *netResolverConn) LocalAddr() net.Addr  { return netResolverAddr{} }
+func (c *netResolverConn) RemoteAddr() net.Addr { return netResolverAddr{} }
+
+// netResolverAddr is the address of both ends of a netResolverConn.
+type netResolverAddr struct{}
+
+func (netResolverAddr) Network() string { return "tcp" }
+func (netResolverAddr) String() string  { return "dns" }
@@ -0,0 +1,79 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// netResolverZone answers the queries sent to "zone" from a few records.
+func netResolverZone(t *testing.T) Exchanger {
+	records := []RR{
+		testRR("www.example.org. 300 IN A 192.0.2.1"),
+		testRR("www.example.org. 300 IN AAAA 2001:db8::1"),
+		testRR("_sip._udp.example.org. 300 IN SRV 20 10 5060 b.example.org."),
+		testRR("_sip._udp.example.org. 300 IN SRV 10 10 5060 c.example.org."),
+		testRR("_sip._udp.example.org. 300 IN SRV 10 50 5060 a.example.org."),
+		testRR(`example.org. 300 IN TXT "v=spf1 " "-all"`),
+		testRR(`example.org. 300 IN TXT "a\"b\255"`),
+	}
+	return exchangerFunc(func(m *Msg, address string) (*Msg, time.Duration, error) {
+		if address != "zone" {
+			t.Errorf("expected query to zone, got %s", address)
+		}
+		r := new(Msg)
+		r.SetReply(m)
+		r.Rcode = RcodeNameError
+		q := m.Question[0]
+		for _, rr := range records {
+			if !equal(rr.Header().Name, q.Name) {
+				continue
+			}
+			r.Rcode = RcodeSuccess
+			if rr.Header().Rrtype == q.Qtype {
+				r.Answer = append(r.Answer, rr)
+			}
+		}
+		return r, 0, nil
+	})
+}
+
+func TestNetResolverLookup(t *testing.T) {
+	r := &NetResolver{Exchanger: netResolverZone(t), Address: "zone"}
+	ctx := context.Background()
+
+	ips, err := r.LookupHost(ctx, "www.example.org")
+	if err != nil || len(ips) != 2 {
+		t.Errorf("expected 2 addresses, got %v, %v", ips, err)
+	}
+	_, err = r.LookupHost(ctx, "nx.example.org")
+	if dnsErr, ok := err.(*net.DNSError); !ok || dnsErr.Err != "no such host" {
+		t.Errorf("expected no such host, got %v", err)
+	}
+
+	srvs, err := r.LookupSRV(ctx, "sip", "udp", "example.org.")
+	if err != nil || len(srvs) != 3 || srvs[0].Target != "a.example.org." || srvs[1].Target != "c.example.org." {
+		t.Errorf("expected SRV records in order a, c, b, got %v, %v", srvs, err)
+	}
+
+	txts, err := r.LookupTXT(ctx, "example.org")
+	if err != nil || len(txts) != 2 || txts[0] != "v=spf1 -all" || txts[1] != "a\"b\xff" {
+		t.Errorf("unexpected TXT records %q, %v", txts, err)
+	}
+}
+
+func TestNetResolverResolver(t *testing.T) {
+	r := &NetResolver{Exchanger: netResolverZone(t), Address: "zone"}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addrs, err := r.Resolver().LookupHost(ctx, "www.example.org.")
+	if err != nil || len(addrs) != 2 {
+		t.Errorf("expected 2 addresses, got %v, %v", addrs, err)
+	}
+	_, srvs, err := r.Resolver().LookupSRV(ctx, "sip", "udp", "example.org.")
+	if err != nil || len(srvs) != 3 {
+		t.Errorf("expected 3 SRV records, got %v, %v", srvs, err)
+	}
+}
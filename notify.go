@@ -0,0 +1,225 @@
+package dns
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A TsigKey is a TSIG key: its name, algorithm and secret.
+type TsigKey struct {
+	Name      string // name of the key, fully qualified
+	Algorithm string // algorithm of the key, e.g. HmacSHA256
+	Secret    string // base64 encoded secret
+}
+
+// A Notifier sends NOTIFY messages (RFC 1996) to tell secondary name servers a
+// zone changed. A NOTIFY is retried until the target acknowledges it, or until
+// it refuses it.
+type Notifier struct {
+	Net      string        // network of the NOTIFY messages, defaults to UDP
+	Timeout  time.Duration // timeout of an attempt, defaults to 2 seconds
+	Attempts int           // maximum number of attempts per target, defaults to 5
+	Interval time.Duration // wait before the first retry, doubled for the next ones, defaults to 1 second
+}
+
+// A NotifyError is returned by Notifier.Send when targets didn't acknowledge
+// the NOTIFY.
+type NotifyError struct {
+	Errors map[string]error // error of each target that failed, by target
+}
+
+func (e *NotifyError) Error() string {
+	targets := make([]string, 0, len(e.Errors))
+	for t := range e.Errors {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+	for i, t := range targets {
+		targets[i] = t + ": " + e.Errors[t].Error()
+	}
+	return "dns: notify failed: " + strings.Join(targets, ", ")
+}
+
+// SendNotify sends a NOTIFY for zone to each of targets, with the defaults of
+// Notifier. See Notifier.Send.
+func SendNotify(zone string, targets []string, tsig *TsigKey) error {
+	return new(Notifier).Send(zone, nil, targets, tsig)
+}
+
+// Send sends a NOTIFY for zone to each of targets, addresses in host:port form,
+// in parallel. If soa isn't nil it is sent in the answer section, so targets
+// know the new serial. If tsig isn't nil, the messages are signed with it. Send
+// returns once all targets acknowledged the NOTIFY or failed, the error is a
+// *NotifyError when some of them failed.
+func (n *Notifier) Send(zone string, soa *SOA, targets []string, tsig *TsigKey) error {
+	c := &Client{Net: n.Net, Timeout: n.Timeout}
+	if tsig != nil {
+		c.TsigProvider = tsigHMACProvider(tsig.Secret)
+	}
+
+	var (
+		mu   sync.Mutex
+		errs = make(map[string]error)
+		wg   sync.WaitGroup
+	)
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t string) {
+			defer wg.Done()
+			if err := n.notify(c, zone, soa, t, tsig); err != nil {
+				mu.Lock()
+				errs[t] = err
+				mu.Unlock()
+			}
+		}(t)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return &NotifyError{Errors: errs}
+	}
+	return nil
+}
+
+// notify sends a NOTIFY for zone to target until it is acknowledged.
+func (n *Notifier) notify(c *Client, zone string, soa *SOA, target string, tsig *TsigKey) error {
+	attempts := n.Attempts
+	if attempts <= 0 {
+		attempts = 5
+	}
+	wait := n.Interval
+	if wait <= 0 {
+		wait = time.Second
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+		m := new(Msg)
+		m.SetNotify(zone)
+		if soa != nil {
+			m.Answer = []RR{soa}
+		}
+		if tsig != nil {
+			m.SetTsig(tsig.Name, tsig.Algorithm, 300, time.Now().Unix())
+		}
+
+		var r *Msg
+		r, _, err = c.Exchange(m, target)
+		if err != nil {
+			continue
+		}
+		switch {
+		case r.Opcode != OpcodeNotify:
+			err = &Error{err: "notify response with bad opcode"}
+		case r.Rcode == RcodeSuccess:
+			return nil
+		case r.Rcode == RcodeServerFailure:
+			err = &Error{err: "notify failed with rcode " + RcodeToString[r.Rcode]}
+		default:
+			// The target won't accept the NOTIFY, don't retry.
+			return &Error{err: "notify failed with rcode " + RcodeToString[r.Rcode]}
+		}
+	}
+	return err
+}
+
+// A NotifyHandler is a Handler that accepts the NOTIFY messages of a primary
+// name server. Valid NOTIFY messages are acknowledged, and Notify is called
+// with them. Other messages are passed to Next.
+//
+// Messages are refused when their source isn't in Allow, or when they aren't
+// signed and RequireTsig is set. Messages with an invalid TSIG are answered
+// with NOTAUTH. A NOTIFY can carry an SOA record in its answer section, which
+// the DefaultMsgAcceptFunc rejects, servers with a NotifyHandler should set
+// their MsgAcceptFunc to NotifyMsgAcceptFunc.
+type NotifyHandler struct {
+	Allow       []*net.IPNet // sources NOTIFY messages are accepted from, any source if empty
+	RequireTsig bool         // if true, only NOTIFY messages with a valid TSIG are accepted
+
+	// Notify is called with the zone of an accepted NOTIFY message, and the
+	// SOA record in its answer section, that has the new serial of the zone,
+	// or nil if there is none. It is called after the NOTIFY is acknowledged.
+	Notify func(zone string, soa *SOA)
+
+	Next Handler // handler of the messages that aren't NOTIFY, they are answered with NOTIMP if nil
+}
+
+// NotifyMsgAcceptFunc is like DefaultMsgAcceptFunc, but accepts NOTIFY messages
+// with one RR in the Answer section (RFC 1996, Section 3.7).
+var NotifyMsgAcceptFunc MsgAcceptFunc = func(dh Header) MsgAcceptAction {
+	if opcode := int(dh.Bits>>11) & 0xF; opcode == OpcodeNotify && dh.Ancount == 1 {
+		dh.Ancount = 0
+	}
+	return defaultMsgAcceptFunc(dh)
+}
+
+// ServeDNS implements the Handler interface.
+func (h *NotifyHandler) ServeDNS(w ResponseWriter, r *Msg) {
+	if r.Opcode != OpcodeNotify {
+		if h.Next != nil {
+			h.Next.ServeDNS(w, r)
+			return
+		}
+		m := new(Msg)
+		m.SetRcode(r, RcodeNotImplemented)
+		w.WriteMsg(m)
+		return
+	}
+
+	m := new(Msg)
+	switch {
+	case len(r.Question) != 1 || r.Question[0].Qtype != TypeSOA:
+		m.SetRcode(r, RcodeFormatError)
+	case !h.allowed(w.RemoteAddr()):
+		m.SetRcode(r, RcodeRefused)
+	case r.IsTsig() != nil && w.TsigStatus() != nil:
+		m.SetRcode(r, RcodeNotAuth)
+	case r.IsTsig() == nil && h.RequireTsig:
+		m.SetRcode(r, RcodeRefused)
+	default:
+		m.SetReply(r)
+	}
+	m.Authoritative = true
+	if t := r.IsTsig(); t != nil && w.TsigStatus() == nil {
+		m.SetTsig(t.Hdr.Name, t.Algorithm, t.Fudge, time.Now().Unix())
+	}
+	w.WriteMsg(m)
+	if m.Rcode != RcodeSuccess || h.Notify == nil {
+		return
+	}
+
+	zone := r.Question[0].Name
+	var soa *SOA
+	for _, rr := range r.Answer {
+		if s, ok := rr.(*SOA); ok && strings.EqualFold(s.Hdr.Name, zone) {
+			soa = s
+			break
+		}
+	}
+	h.Notify(zone, soa)
+}
+
+func (h *NotifyHandler) allowed(addr net.Addr) bool {
+	if len(h.Allow) == 0 {
+		return true
+	}
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	}
+	for _, n := range h.Allow {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package dns
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSendNotify(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		zones  []string
+		serial uint32
+	)
+	h := &NotifyHandler{
+		RequireTsig: true,
+		Notify: func(zone string, soa *SOA) {
+			mu.Lock()
+			defer mu.Unlock()
+			zones = append(zones, zone)
+			if soa != nil {
+				serial = soa.Serial
+			}
+		},
+	}
+	Handle("example.", h)
+	defer HandleRemove("example.")
+
+	key := &TsigKey{Name: "notify.", Algorithm: HmacSHA256, Secret: "so6ZGir4GPAqINNh9U5c3A=="}
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.TsigSecret = map[string]string{key.Name: key.Secret}
+		srv.MsgAcceptFunc = NotifyMsgAcceptFunc
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	soa := testRR("example. 3600 IN SOA ns.example. hostmaster.example. 42 3600 600 86400 60").(*SOA)
+	n := &Notifier{Attempts: 2, Interval: 10 * time.Millisecond}
+	if err := n.Send("example.", soa, []string{addrstr}, key); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+	mu.Lock()
+	if len(zones) != 1 || zones[0] != "example." || serial != 42 {
+		t.Errorf("expected a notify for example. with serial 42, got %v and %d", zones, serial)
+	}
+	mu.Unlock()
+
+	// Refused notifies aren't retried.
+	err = SendNotify("example.", []string{addrstr}, nil)
+	nerr, ok := err.(*NotifyError)
+	if !ok || len(nerr.Errors) != 1 || nerr.Errors[addrstr] == nil {
+		t.Errorf("expected the notify to be refused, got %v", err)
+	}
+
+	_, network, _ := net.ParseCIDR("192.0.2.0/24")
+	Handle("example.org.", &NotifyHandler{Allow: []*net.IPNet{network}, Notify: h.Notify})
+	defer HandleRemove("example.org.")
+	if err := n.Send("example.org.", nil, []string{addrstr}, key); err == nil {
+		t.Error("expected a notify from a source that isn't allowed to fail")
+	}
+	mu.Lock()
+	if len(zones) != 1 {
+		t.Errorf("expected no more notifies, got %v", zones)
+	}
+	mu.Unlock()
+}
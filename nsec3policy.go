@@ -0,0 +1,84 @@
+package dns
+
+// DefaultNSEC3MaxIterations is the default number of NSEC3 iterations above
+// which validators treat responses as insecure, the limit common resolvers
+// apply (RFC 9276, Section 3.2).
+const DefaultNSEC3MaxIterations = 150
+
+// Errors of CheckNSEC3Param, for NSEC3 parameters against the guidance of
+// RFC 9276, Section 3.1.
+var (
+	// ErrNSEC3Iterations is returned for NSEC3 parameters with additional iterations.
+	ErrNSEC3Iterations error = &Error{err: "NSEC3 iterations should be 0"}
+	// ErrNSEC3Salt is returned for NSEC3 parameters with a salt.
+	ErrNSEC3Salt error = &Error{err: "NSEC3 salt should be empty"}
+)
+
+// CheckNSEC3Param checks the parameters of rr, an NSEC3 or NSEC3PARAM record,
+// against the guidance of RFC 9276 for zone publishers: no additional
+// iterations and no salt, which cost resolvers CPU without making the zone
+// harder to enumerate. It returns ErrNSEC3Iterations, ErrNSEC3Salt, or nil
+// when the parameters follow it or rr is of another type.
+func CheckNSEC3Param(rr RR) error {
+	var (
+		iterations uint16
+		salt       string
+	)
+	switch rr := rr.(type) {
+	case *NSEC3:
+		iterations, salt = rr.Iterations, rr.Salt
+	case *NSEC3PARAM:
+		iterations, salt = rr.Iterations, rr.Salt
+	default:
+		return nil
+	}
+	switch {
+	case iterations > 0:
+		return ErrNSEC3Iterations
+	case salt != "" && salt != "-":
+		return ErrNSEC3Salt
+	}
+	return nil
+}
+
+// CheckNSEC3Zone checks the parameters of the NSEC3 and NSEC3PARAM records of
+// rrs, e.g. of a zone just parsed, with CheckNSEC3Param. It returns the
+// records with parameters that don't follow RFC 9276, one per set of
+// parameters.
+func CheckNSEC3Zone(rrs []RR) []RR {
+	type param struct {
+		iterations uint16
+		salt       string
+	}
+	seen := make(map[param]bool)
+	var bad []RR
+	for _, rr := range rrs {
+		if CheckNSEC3Param(rr) == nil {
+			continue
+		}
+		var p param
+		switch rr := rr.(type) {
+		case *NSEC3:
+			p = param{rr.Iterations, rr.Salt}
+		case *NSEC3PARAM:
+			p = param{rr.Iterations, rr.Salt}
+		}
+		if !seen[p] {
+			seen[p] = true
+			bad = append(bad, rr)
+		}
+	}
+	return bad
+}
+
+// NSEC3Insecure reports whether a validator should treat a response proven by
+// rr as insecure, instead of hashing names with it (RFC 9276, Section 3.2):
+// rr has more than max iterations, DefaultNSEC3MaxIterations when max is 0,
+// or an unknown hash algorithm. Such responses should carry the extended DNS
+// error ExtendedErrorCodeUnsupportedNSEC3IterValue.
+func NSEC3Insecure(rr *NSEC3, max uint16) bool {
+	if max == 0 {
+		max = DefaultNSEC3MaxIterations
+	}
+	return rr.Hash != SHA1 || rr.Iterations > max
+}
@@ -0,0 +1,76 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckNSEC3Param(t *testing.T) {
+	tests := []struct {
+		rr  string
+		err error
+	}{
+		{"example. 0 IN NSEC3PARAM 1 0 0 -", nil},
+		{"example. 0 IN NSEC3PARAM 1 0 10 -", ErrNSEC3Iterations},
+		{"example. 0 IN NSEC3PARAM 1 0 0 CAFE", ErrNSEC3Salt},
+		{"0p9mhaveqvm6t7vbl5lop2u3t2rp3tom.example. 0 IN NSEC3 1 1 0 - 0P9MHAVEQVM6T7VBL5LOP2U3T2RP3TOM A", nil},
+		{"0p9mhaveqvm6t7vbl5lop2u3t2rp3tom.example. 0 IN NSEC3 1 1 5 AB 0P9MHAVEQVM6T7VBL5LOP2U3T2RP3TOM A", ErrNSEC3Iterations},
+		{"example. 0 IN A 192.0.2.1", nil},
+	}
+	for _, tc := range tests {
+		if err := CheckNSEC3Param(testRR(tc.rr)); err != tc.err {
+			t.Errorf("%s: expected %v, got %v", tc.rr, tc.err, err)
+		}
+	}
+
+	rrs := []RR{
+		testRR("example. 0 IN NSEC3PARAM 1 0 10 AB"),
+		testRR("a.example. 0 IN NSEC3 1 0 10 AB 0P9MHAVEQVM6T7VBL5LOP2U3T2RP3TOM A"),
+		testRR("b.example. 0 IN NSEC3 1 0 10 AB 0P9MHAVEQVM6T7VBL5LOP2U3T2RP3TOM A"),
+	}
+	if bad := CheckNSEC3Zone(rrs); len(bad) != 1 {
+		t.Errorf("expected a single set of bad parameters, got %v", bad)
+	}
+}
+
+func TestNSEC3Insecure(t *testing.T) {
+	rr := testRR("a.example. 0 IN NSEC3 1 0 150 - 0P9MHAVEQVM6T7VBL5LOP2U3T2RP3TOM A").(*NSEC3)
+	if NSEC3Insecure(rr, 0) {
+		t.Error("expected 150 iterations to be secure by default")
+	}
+	if !NSEC3Insecure(rr, 100) {
+		t.Error("expected 150 iterations to be insecure with a cap of 100")
+	}
+	rr.Hash = 2
+	if !NSEC3Insecure(rr, 0) {
+		t.Error("expected an unknown hash algorithm to be insecure")
+	}
+}
+
+func TestCacheNSEC3MaxIterations(t *testing.T) {
+	var queries int
+	h := HashName("example.", SHA1, 500, "")
+	ex := exchangerFunc(func(m *Msg, address string) (*Msg, time.Duration, error) {
+		queries++
+		r := new(Msg)
+		r.SetRcode(m, RcodeNameError)
+		r.AuthenticatedData = true
+		r.Ns = []RR{
+			testRR("example. 3600 IN SOA ns. hostmaster. 1 3600 600 86400 300"),
+			testRR(h + ".example. 300 IN NSEC3 1 0 500 - " + h + " SOA NS"),
+		}
+		return r, time.Millisecond, nil
+	})
+	c := NewCache(ex)
+	c.AggressiveNSEC = true
+
+	m := new(Msg)
+	m.SetQuestion("x.example.", TypeA)
+	m.SetEdns0(4096, true)
+	c.Exchange(m, "192.0.2.53:53")
+	m.SetQuestion("y.example.", TypeA)
+	c.Exchange(m, "192.0.2.53:53")
+	if queries != 2 {
+		t.Errorf("expected NSEC3 records of 500 iterations not to be used, got %d queries", queries)
+	}
+}
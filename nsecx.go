@@ -93,3 +93,52 @@ func (rr *NSEC3) Match(name string) bool {
 	}
 	return false
 }
+
+// Cover returns true if a name is covered by the NSEC record: it sorts between
+// the owner name and the next domain name in canonical order, and isn't below
+// a delegation or a DNAME at the owner name.
+func (rr *NSEC) Cover(name string) bool {
+	owner, next := rr.Hdr.Name, rr.NextDomain
+	if canonicalNameCompare(owner, name) >= 0 {
+		return false
+	}
+	if IsSubDomain(owner, name) && (rr.hasType(TypeDNAME) || rr.hasType(TypeNS) && !rr.hasType(TypeSOA)) {
+		return false
+	}
+	if canonicalNameCompare(owner, next) >= 0 { // last NSEC of the zone, next is the apex
+		return IsSubDomain(next, name)
+	}
+	return canonicalNameCompare(name, next) < 0
+}
+
+// Match returns true if a name matches the NSEC record
+func (rr *NSEC) Match(name string) bool {
+	return strings.EqualFold(rr.Hdr.Name, name)
+}
+
+func (rr *NSEC) hasType(t uint16) bool {
+	for _, b := range rr.TypeBitMap {
+		if b == t {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalNameCompare compares the domain names a and b in the canonical
+// order of RFC 4034, Section 6.1, and returns -1, 0 or 1.
+func canonicalNameCompare(a, b string) int {
+	la, lb := SplitDomainName(strings.ToLower(a)), SplitDomainName(strings.ToLower(b))
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(la[i], lb[j]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(la) < len(lb):
+		return -1
+	case len(la) > len(lb):
+		return 1
+	}
+	return 0
+}
@@ -168,3 +168,35 @@ func BenchmarkHashName(b *testing.B) {
 		})
 	}
 }
+
+func TestNsecCover(t *testing.T) {
+	nsec := func(s string) *NSEC {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rr.(*NSEC)
+	}
+	tests := []struct {
+		rr    *NSEC
+		name  string
+		cover bool
+	}{
+		{nsec("b.example. NSEC d.example. A"), "c.example.", true},
+		{nsec("b.example. NSEC d.example. A"), "x.b.example.", true},
+		{nsec("b.example. NSEC d.example. A"), "d.example.", false},
+		{nsec("b.example. NSEC d.example. A"), "b.example.", false},
+		{nsec("b.example. NSEC d.example. A"), "a.example.", false},
+		{nsec("b.example. NSEC d.example. NS"), "x.b.example.", false}, // delegation
+		{nsec("b.example. NSEC d.example. NS"), "c.example.", true},
+		{nsec("example. NSEC b.example. SOA NS"), "*.example.", true},
+		{nsec("example. NSEC b.example. SOA NS"), "x.a.example.", true},
+		{nsec("z.example. NSEC example. A"), "zz.example.", true}, // last in zone
+		{nsec("z.example. NSEC example. A"), "zz.other.", false},
+	}
+	for _, tc := range tests {
+		if got := tc.rr.Cover(tc.name); got != tc.cover {
+			t.Errorf("%s covering %s: expected %t, got %t", tc.rr, tc.name, tc.cover, got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package dns
+
+import "encoding/hex"
+
+// RequestNSID adds an empty NSID option to dns, to ask the server for its
+// identifier (RFC 5001). An OPT record is added if dns has none.
+func (dns *Msg) RequestNSID() *Msg {
+	opt := dns.IsEdns0()
+	if opt == nil {
+		dns.SetEdns0(DefaultMsgSize, false)
+		opt = dns.IsEdns0()
+	}
+	for _, o := range opt.Option {
+		if o.Option() == EDNS0NSID {
+			return dns
+		}
+	}
+	opt.Option = append(opt.Option, &EDNS0_NSID{Code: EDNS0NSID})
+	return dns
+}
+
+// NSID returns the server identifier of the NSID option of dns, decoded from
+// hex, and whether dns has a non-empty one.
+func (dns *Msg) NSID() (string, bool) {
+	opt := dns.IsEdns0()
+	if opt == nil {
+		return "", false
+	}
+	for _, o := range opt.Option {
+		if e, ok := o.(*EDNS0_NSID); ok && e.Nsid != "" {
+			b, err := hex.DecodeString(e.Nsid)
+			if err != nil {
+				return "", false
+			}
+			return string(b), true
+		}
+	}
+	return "", false
+}
+
+// setNSID sets the NSID option of the response m to id, unless it has one.
+// An OPT record is added if m has none.
+func setNSID(m *Msg, id string) {
+	opt := responseOPT(m)
+	for _, o := range opt.Option {
+		if o.Option() == EDNS0NSID {
+			return
+		}
+	}
+	opt.Option = append(opt.Option, &EDNS0_NSID{Code: EDNS0NSID, Nsid: hex.EncodeToString([]byte(id))})
+}
+
+// responseOPT returns the OPT record of the response m, after adding one,
+// before the TSIG record, if m has none.
+func responseOPT(m *Msg) *OPT {
+	if opt := m.IsEdns0(); opt != nil {
+		return opt
+	}
+	opt := &OPT{Hdr: RR_Header{Name: ".", Rrtype: TypeOPT}}
+	opt.SetUDPSize(DefaultMsgSize)
+	if t := m.IsTsig(); t != nil {
+		m.Extra = append(m.Extra[:len(m.Extra)-1:len(m.Extra)-1], opt, t)
+	} else {
+		m.Extra = append(m.Extra, opt)
+	}
+	return opt
+}
@@ -0,0 +1,48 @@
+package dns
+
+import "testing"
+
+func TestServerNSID(t *testing.T) {
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan(":0", func(srv *Server) {
+		srv.Handler = HandlerFunc(HelloServer)
+		srv.NSID = "node-1"
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	m := new(Msg).SetQuestion("miek.nl.", TypeTXT)
+	r, err := Exchange(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if _, ok := r.NSID(); ok || r.IsEdns0() != nil {
+		t.Errorf("expected no NSID without request, got %v", r)
+	}
+
+	m.RequestNSID().RequestNSID()
+	if opt := m.IsEdns0(); opt == nil || len(opt.Option) != 1 {
+		t.Fatalf("expected a single NSID option, got %v", m)
+	}
+	r, err = Exchange(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if nsid, ok := r.NSID(); !ok || nsid != "node-1" {
+		t.Errorf("expected NSID node-1, got %q in %v", nsid, r)
+	}
+}
+
+func TestSetNSID(t *testing.T) {
+	m := new(Msg).SetQuestion("miek.nl.", TypeTXT)
+	m.SetTsig("axfr.", HmacSHA256, 300, 0)
+	setNSID(m, "node-1")
+	if len(m.Extra) != 2 || m.IsEdns0() == nil || m.IsTsig() == nil {
+		t.Fatalf("expected OPT record before TSIG, got %v", m.Extra)
+	}
+	setNSID(m, "node-2")
+	if nsid, _ := m.NSID(); nsid != "node-1" {
+		t.Errorf("expected NSID of handler to be kept, got %q", nsid)
+	}
+}
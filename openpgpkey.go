@@ -0,0 +1,56 @@
+package dns
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// OPENPGPKEYName returns the ownername of an OPENPGPKEY resource record for
+// the local-part email of an address in domain, see RFC 7929, Section 3.
+func OPENPGPKEYName(email, domain string) (string, error) {
+	h := sha256.Sum256([]byte(email))
+	return hex.EncodeToString(h[:28]) + "._openpgpkey." + domain, nil
+}
+
+// OPENPGPKEYAddressName returns the ownername of the OPENPGPKEY resource
+// records of the email address, e.g. "hugh@example.com".
+func OPENPGPKEYAddressName(address string) (string, error) {
+	local, domain, err := splitAddress(address)
+	if err != nil {
+		return "", err
+	}
+	return OPENPGPKEYName(local, domain)
+}
+
+// NewOPENPGPKEY returns the OPENPGPKEY record of the OpenPGP transferable
+// public key for the email address. The key is either in binary form or
+// ASCII armored.
+func NewOPENPGPKEY(address string, key []byte) (*OPENPGPKEY, error) {
+	name, err := OPENPGPKEYAddressName(address)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(key), []byte("-----BEGIN ")) {
+		block, err := armor.Decode(bytes.NewReader(key))
+		if err != nil {
+			return nil, err
+		}
+		if block.Type != "PGP PUBLIC KEY BLOCK" {
+			return nil, &Error{err: "not an OpenPGP public key: " + block.Type}
+		}
+		if key, err = ioutil.ReadAll(block.Body); err != nil {
+			return nil, err
+		}
+	}
+	if len(key) == 0 {
+		return nil, &Error{err: "empty OpenPGP public key"}
+	}
+	return &OPENPGPKEY{
+		Hdr:       RR_Header{Name: name, Rrtype: TypeOPENPGPKEY, Class: ClassINET},
+		PublicKey: toBase64(key),
+	}, nil
+}
@@ -0,0 +1,43 @@
+package dns
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func TestOPENPGPKEYName(t *testing.T) {
+	// Example of RFC 7929, Section 7.
+	name, err := OPENPGPKEYAddressName("hugh@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._openpgpkey.example.com."; name != want {
+		t.Errorf("expected %s, got %s", want, name)
+	}
+	if _, err := OPENPGPKEYAddressName("example.com"); err == nil {
+		t.Error("expected an error for an address without local-part")
+	}
+}
+
+func TestNewOPENPGPKEY(t *testing.T) {
+	key := []byte{0x99, 0x00, 0x01, 0x04} // not a real key, only its bytes matter
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, "PGP PUBLIC KEY BLOCK", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(key)
+	w.Close()
+
+	for _, k := range [][]byte{key, buf.Bytes()} {
+		rr, err := NewOPENPGPKEY("hugh@example.com", k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rr.PublicKey != toBase64(key) || rr.Hdr.Name != "c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._openpgpkey.example.com." {
+			t.Errorf("unexpected record %s", rr)
+		}
+	}
+}
@@ -640,14 +640,13 @@ b1slImA8YVJyuIDsj7kwzG7jnERNqnWxZ48AWkskmdHaVDP4BcelrTI3rMXdXF5D
 // Test with no known RR on the line
 func TestLineNumberError2(t *testing.T) {
 	tests := map[string]string{
-		"example.com. 1000 SO master.example.com. admin.example.com. 1 4294967294 4294967293 4294967295 100": "dns: expecting RR type or class, not this...: \"SO\" at line: 1:21",
-		"example.com 1000 IN TALINK a.example.com. b..example.com.":                                          "dns: bad TALINK NextName: \"b..example.com.\" at line: 1:57",
-		"example.com 1000 IN TALINK ( a.example.com. b..example.com. )":                                      "dns: bad TALINK NextName: \"b..example.com.\" at line: 1:60",
+		"example.com. 1000 SO master.example.com. admin.example.com. 1 4294967294 4294967293 4294967295 100": "dns: expecting RR type or class, not this...: \"SO\" at line: 1:19",
+		"example.com 1000 IN TALINK a.example.com. b..example.com.":                                          "dns: bad TALINK NextName: \"b..example.com.\" at line: 1:43",
+		"example.com 1000 IN TALINK ( a.example.com. b..example.com. )":                                      "dns: bad TALINK NextName: \"b..example.com.\" at line: 1:45",
 		`example.com 1000 IN TALINK ( a.example.com.
-	bb..example.com. )`: "dns: bad TALINK NextName: \"bb..example.com.\" at line: 2:18",
-		// This is a bug, it should report an error on line 1, but the new is already processed.
+	bb..example.com. )`: "dns: bad TALINK NextName: \"bb..example.com.\" at line: 2:2",
 		`example.com 1000 IN TALINK ( a.example.com.  b...example.com.
-	)`: "dns: bad TALINK NextName: \"b...example.com.\" at line: 2:1"}
+	)`: "dns: bad TALINK NextName: \"b...example.com.\" at line: 1:46"}
 
 	for in, errStr := range tests {
 		_, err := NewRR(in)
@@ -999,6 +998,94 @@ func TestEUIxx(t *testing.T) {
 	}
 }
 
+func TestNicheRRs(t *testing.T) {
+	tests := map[string]string{
+		"host.example. IN EUI48 00-00-5E-90-01-2A":                      "host.example.\t3600\tIN\tEUI48\t00-00-5e-90-01-2a",
+		"host.example. IN GPOS -32.6882 116.8652 10.0":                  "host.example.\t3600\tIN\tGPOS\t-32.6882 116.8652 10.0",
+		"host.example. IN GPOS +90 -180 -10":                            "host.example.\t3600\tIN\tGPOS\t+90 -180 -10",
+		`host.example. IN NINFO "ok" "status"`:                          "host.example.\t3600\tIN\tNINFO\t\"ok\" \"status\"",
+		`host.example. IN AVC "app-name:WOLFGANG|app-class:OAM"`:        "host.example.\t3600\tIN\tAVC\t\"app-name:WOLFGANG|app-class:OAM\"",
+		`host.example. IN DOA 0 1 2 "image/pmp" aHR0cHM6Ly93d3c=`:       "host.example.\t3600\tIN\tDOA\t0 1 2 \"image/pmp\" aHR0cHM6Ly93d3c=",
+		`host.example. IN DOA 1234567 1 0 "" -`:                         "host.example.\t3600\tIN\tDOA\t1234567 1 0 \"\" -",
+		"host.example. IN AMTRELAY 0 0 0 .":                             "host.example.\t3600\tIN\tAMTRELAY\t0 0 0 .",
+		"host.example. IN AMTRELAY 10 0 1 203.0.113.15":                 "host.example.\t3600\tIN\tAMTRELAY\t10 0 1 203.0.113.15",
+		"host.example. IN AMTRELAY 10 1 2 2001:db8::15":                 "host.example.\t3600\tIN\tAMTRELAY\t10 1 2 2001:db8::15",
+		"$ORIGIN example.\nhost.example. IN AMTRELAY 128 1 3 amtrelays": "host.example.\t3600\tIN\tAMTRELAY\t128 1 3 amtrelays.example.",
+	}
+	for i, o := range tests {
+		r, err := NewRR(i)
+		if err != nil {
+			t.Errorf("failed to parse %s: %v", i, err)
+			continue
+		}
+		if r.String() != o {
+			t.Errorf("want %s, got %s", o, r.String())
+		}
+		buf := make([]byte, Len(r))
+		off, err := PackRR(r, buf, 0, nil, false)
+		if err != nil {
+			t.Errorf("failed to pack %s: %v", i, err)
+			continue
+		}
+		r1, _, err := UnpackRR(buf[:off], 0)
+		if err != nil {
+			t.Errorf("failed to unpack %s: %v", i, err)
+			continue
+		}
+		if !IsDuplicate(r, r1) {
+			t.Errorf("want %s after a wire round trip, got %s", r, r1)
+		}
+	}
+
+	bad := []string{
+		"host.example. IN EUI48 00-00-5e-90-01",
+		"host.example. IN EUI48 00-00-5e-90-01-2a-",
+		"host.example. IN EUI48 00:00:5e:90:01:2a",
+		"host.example. IN EUI48 00-00-5e-90-01-+a",
+		"host.example. IN EUI48 00-00-5e-90-01-2a 00",
+		"host.example. IN EUI64 00-00-5e-ef-00-00-00-2g",
+		"host.example. IN EUI64 0000-5e-ef-00-00-00-2a-",
+		"host.example. IN GPOS -32.6882 116.8652",
+		"host.example. IN GPOS 91 116.8652 10.0",
+		"host.example. IN GPOS -32.6882 -180.5 10.0",
+		"host.example. IN GPOS 1e1 116.8652 10.0",
+		"host.example. IN GPOS NaN 116.8652 10.0",
+		"host.example. IN GPOS -32.6882 116.8652 Inf",
+		"host.example. IN GPOS -32.6882 116.8652 1.2.3",
+		"host.example. IN GPOS -32.6882 116.8652 -",
+		`host.example. IN NINFO "ok`,
+		`host.example. IN DOA 0 1 2 "image/pmp"`,
+		`host.example. IN DOA 0 1 256 "image/pmp" -`,
+		`host.example. IN DOA 0 1 2 "image/pmp" not*base64`,
+		`host.example. IN DOA 4294967296 1 2 "image/pmp" -`,
+		"host.example. IN AMTRELAY 10 2 1 203.0.113.15",
+		"host.example. IN AMTRELAY 10 0 4 203.0.113.15",
+		"host.example. IN AMTRELAY 10 0 0 203.0.113.15",
+		"host.example. IN AMTRELAY 10 0 1 2001:db8::15",
+		"host.example. IN AMTRELAY 10 0 2 203.0.113.15",
+		"host.example. IN AMTRELAY 10 0 1 amtrelays.example.",
+		"host.example. IN AMTRELAY 256 0 1 203.0.113.15",
+		"host.example. IN AMTRELAY 10 0 1",
+	}
+	for _, s := range bad {
+		if _, err := NewRR(s); err == nil {
+			t.Errorf("expected an error for %s", s)
+		}
+	}
+
+	// A relay type without a form can't be unpacked.
+	r := testRR("host.example. IN AMTRELAY 10 0 1 203.0.113.15")
+	buf := make([]byte, Len(r))
+	off, err := PackRR(r, buf, 0, nil, false)
+	if err != nil {
+		t.Fatalf("failed to pack %s: %v", r, err)
+	}
+	buf[off-5] = 5 // GatewayType
+	if _, _, err := UnpackRR(buf[:off], 0); err == nil {
+		t.Error("expected an error unpacking an AMTRELAY relay type of 5")
+	}
+}
+
 func TestUserRR(t *testing.T) {
 	tests := map[string]string{
 		"host.example. IN UID 1234":              "host.example.\t3600\tIN\tUID\t1234",
@@ -1567,3 +1654,34 @@ func TestBad(t *testing.T) {
 		}
 	}
 }
+
+func TestParseGenerated(t *testing.T) {
+	for rrtype := range typeToGeneratedParserFunc {
+		if _, ok := typeToparserFunc[rrtype]; ok {
+			t.Errorf("type %s has both a generated and a handwritten parser", TypeToString[rrtype])
+		}
+	}
+
+	tests := map[string]string{
+		"example.org. 3600 IN SRV 10 20 5060 sip.example.org.":     "example.org.\t3600\tIN\tSRV\t10 20 5060 sip.example.org.",
+		"example.org. 3600 IN PX 10 map822.org. mapx400.org.":      "example.org.\t3600\tIN\tPX\t10 map822.org. mapx400.org.",
+		"example.org. 3600 IN SSHFP 1 2 ( 123456 789abc )":         "example.org.\t3600\tIN\tSSHFP\t1 2 123456789ABC",
+		"example.org. 3600 IN L32 10 10.1.2.3":                     "example.org.\t3600\tIN\tL32\t10 10.1.2.3",
+		`example.org. 3600 IN NINFO "first" "second"`:              "example.org.\t3600\tIN\tNINFO\t\"first\" \"second\"",
+		"example.org. 3600 IN MINFO rmail.example.org. email.org.": "example.org.\t3600\tIN\tMINFO\trmail.example.org. email.org.",
+	}
+	for i, o := range tests {
+		rr, err := NewRR(i)
+		if err != nil {
+			t.Errorf("failed to parse RR %q: %v", i, err)
+			continue
+		}
+		if rr.String() != o {
+			t.Errorf("expected %q, got %q", o, rr.String())
+		}
+	}
+
+	if _, err := NewRR("example.org. IN SRV 10 70000 5060 sip.example.org."); err == nil || !strings.Contains(err.Error(), "bad SRV Weight") {
+		t.Errorf("expected bad SRV Weight, got %v", err)
+	}
+}
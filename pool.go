@@ -0,0 +1,408 @@
+package dns
+
+import (
+	"crypto/tls"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A ConnPool keeps a few long-lived TCP or TLS connections per server, and
+// multiplexes the queries of the Clients using it over them (RFC 7766, Section
+// 6.2.1.1). Responses are matched to queries by ID and question. Connections
+// that are closed by the server, or that fail, are replaced transparently.
+//
+// With Keepalive set, queries with an OPT record carry the edns-tcp-keepalive
+// option (RFC 7828), and the idle timeout the server advertises in its
+// responses replaces IdleTimeout for the connection. A connection the server
+// advertises a zero timeout on isn't used for new queries, and is closed once
+// the outstanding ones are answered.
+//
+// A ConnPool is used by setting Client.Pool, it is safe for concurrent use by
+// many Clients. The zero ConnPool is ready for use.
+type ConnPool struct {
+	MaxConns    int           // maximum number of connections per server, defaults to 2
+	MaxInflight int           // outstanding queries per connection before another one is opened, defaults to 100
+	IdleTimeout time.Duration // connections without outstanding queries are closed after this time, defaults to 10 seconds
+	Keepalive   bool          // if true, negotiate the idle timeout with edns-tcp-keepalive
+
+	mu      sync.Mutex
+	conns   map[poolKey][]*poolConn
+	dialing map[poolKey]*poolDial
+}
+
+// poolKey is the key of the connections of a ConnPool. Clients that dial
+// differently don't share connections.
+type poolKey struct {
+	network string
+	address string
+	tls     *tls.Config
+	dialer  *net.Dialer
+}
+
+// poolDial is a connection of a ConnPool being dialed, the queries that need
+// it wait until done is closed.
+type poolDial struct {
+	done chan struct{}
+	err  error
+}
+
+// poolConn is a connection of a ConnPool.
+type poolConn struct {
+	co      *Conn
+	pool    *ConnPool
+	key     poolKey
+	writeMu sync.Mutex // serializes the writes of queries
+
+	mu       sync.Mutex // protects the fields below
+	inflight map[uint16]*poolQuery
+	idle     time.Duration // idle timeout advertised by the server, if not zero
+	draining bool          // server advertised a zero idle timeout
+	closed   bool
+}
+
+// poolQuery is an outstanding query on a poolConn.
+type poolQuery struct {
+	q  Question
+	ch chan poolResult // buffered, receives a single result
+}
+
+type poolResult struct {
+	r   *Msg
+	err error
+}
+
+// errPoolConnClosed is returned to the queries outstanding on a connection that
+// was closed, they are retried on a new connection.
+var errPoolConnClosed = &Error{err: "pooled connection closed"}
+
+// poolTimeout is the error returned when no response arrived in time.
+type poolTimeout struct{}
+
+func (poolTimeout) Error() string   { return "dns: pooled query timed out" }
+func (poolTimeout) Timeout() bool   { return true }
+func (poolTimeout) Temporary() bool { return true }
+
+func (p *ConnPool) maxConns() int {
+	if p.MaxConns > 0 {
+		return p.MaxConns
+	}
+	return 2
+}
+
+func (p *ConnPool) maxInflight() int {
+	if p.MaxInflight > 0 {
+		return p.MaxInflight
+	}
+	return 100
+}
+
+func (p *ConnPool) idleTimeout() time.Duration {
+	if p.IdleTimeout > 0 {
+		return p.IdleTimeout
+	}
+	return 10 * time.Second
+}
+
+// Close closes all the connections of the pool. Queries outstanding on them
+// are retried on new connections, the pool can still be used afterwards.
+func (p *ConnPool) Close() error {
+	p.mu.Lock()
+	var conns []*poolConn
+	for _, cs := range p.conns {
+		conns = append(conns, cs...)
+	}
+	p.conns = nil
+	p.mu.Unlock()
+
+	for _, pc := range conns {
+		pc.close()
+	}
+	return nil
+}
+
+// exchange sends m to address over a pooled connection, and waits for the
+// response. A query that was outstanding on a connection that closed is sent
+// once more on a new connection.
+func (p *ConnPool) exchange(c *Client, m *Msg, address string, at attempt) (r *Msg, rtt time.Duration, err error) {
+	for i := 0; i < 2; i++ {
+		r, rtt, err = p.exchangeOnce(c, m, address, at)
+		if err != errPoolConnClosed {
+			break
+		}
+	}
+	return r, rtt, err
+}
+
+func (p *ConnPool) exchangeOnce(c *Client, m *Msg, address string, at attempt) (*Msg, time.Duration, error) {
+	pc, err := p.get(c, address, at)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Queries on a connection need different IDs, m is copied when its ID
+	// is already in use.
+	q := &poolQuery{ch: make(chan poolResult, 1)}
+	if len(m.Question) > 0 {
+		q.q = m.Question[0]
+	}
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		return nil, 0, errPoolConnClosed
+	}
+	id := m.Id
+	for _, ok := pc.inflight[id]; ok; _, ok = pc.inflight[id] {
+		id = Id()
+	}
+	pc.inflight[id] = q
+	pc.mu.Unlock()
+
+	out := m
+	if id != m.Id {
+		out = m.Copy()
+		out.Id = id
+	}
+	if p.Keepalive {
+		out = withKeepalive(out, out != m)
+	}
+
+	t := time.Now()
+	pc.writeMu.Lock()
+	pc.co.SetWriteDeadline(t.Add(c.attemptTimeout(at, c.writeTimeout())))
+	err = pc.co.WriteMsg(out)
+	pc.writeMu.Unlock()
+	if err != nil {
+		pc.close()
+		return nil, 0, errPoolConnClosed
+	}
+
+	timer := time.NewTimer(c.attemptTimeout(at, c.readTimeout()))
+	defer timer.Stop()
+	select {
+	case res := <-q.ch:
+		if res.r != nil {
+			res.r.Id = m.Id
+		}
+		return res.r, time.Since(t), res.err
+	case <-timer.C:
+		err = poolTimeout{}
+	case <-at.done():
+		err = at.ctx.Err()
+	}
+	pc.mu.Lock()
+	delete(pc.inflight, id)
+	pc.mu.Unlock()
+	return nil, time.Since(t), err
+}
+
+// get returns the least loaded connection to address, it dials a new one when
+// they all have too many outstanding queries. The pool isn't locked while a
+// connection is dialed, the queries that need the same connection wait for it.
+func (p *ConnPool) get(c *Client, address string, at attempt) (*poolConn, error) {
+	key := poolKey{c.network(at), address, c.TLSConfig, c.Dialer}
+
+	p.mu.Lock()
+	var best *poolConn
+	for {
+		var bestLoad int
+		best, bestLoad = p.leastLoaded(key)
+		d := p.dialing[key]
+		n := len(p.conns[key])
+		if d != nil {
+			n++
+		}
+		if best != nil && (bestLoad < p.maxInflight() || n >= p.maxConns()) {
+			p.mu.Unlock()
+			return best, nil
+		}
+		if d == nil {
+			break
+		}
+
+		p.mu.Unlock()
+		select {
+		case <-d.done:
+		case <-at.done():
+			return nil, at.ctx.Err()
+		}
+		if d.err != nil {
+			if best != nil {
+				return best, nil
+			}
+			return nil, d.err
+		}
+		p.mu.Lock()
+	}
+
+	d := &poolDial{done: make(chan struct{})}
+	if p.dialing == nil {
+		p.dialing = make(map[poolKey]*poolDial)
+	}
+	p.dialing[key] = d
+	p.mu.Unlock()
+
+	co, err := c.dial(address, at)
+
+	p.mu.Lock()
+	delete(p.dialing, key)
+	d.err = err
+	var pc *poolConn
+	if err == nil {
+		if c.Dnstap != nil {
+			co.tap = c.dnstapTap(co)
+		}
+		pc = &poolConn{co: co, pool: p, key: key, inflight: make(map[uint16]*poolQuery)}
+		if p.conns == nil {
+			p.conns = make(map[poolKey][]*poolConn)
+		}
+		p.conns[key] = append(p.conns[key], pc)
+		go pc.read()
+	}
+	p.mu.Unlock()
+	close(d.done)
+
+	if err != nil {
+		if best != nil {
+			return best, nil
+		}
+		return nil, err
+	}
+	return pc, nil
+}
+
+// leastLoaded returns the open connection for key with the fewest outstanding
+// queries, and their number. The caller holds p.mu.
+func (p *ConnPool) leastLoaded(key poolKey) (*poolConn, int) {
+	var best *poolConn
+	bestLoad := 0
+	for _, pc := range p.conns[key] {
+		pc.mu.Lock()
+		load, closed := len(pc.inflight), pc.closed || pc.draining
+		pc.mu.Unlock()
+		if !closed && (best == nil || load < bestLoad) {
+			best, bestLoad = pc, load
+		}
+	}
+	return best, bestLoad
+}
+
+// read reads the responses on pc and hands them to the queries waiting for
+// them, until the connection fails or has been idle for too long.
+func (pc *poolConn) read() {
+	for {
+		pc.mu.Lock()
+		if len(pc.inflight) == 0 {
+			if pc.draining {
+				pc.mu.Unlock()
+				pc.close()
+				return
+			}
+			idle := pc.idle
+			if idle == 0 {
+				idle = pc.pool.idleTimeout()
+			}
+			pc.co.SetReadDeadline(time.Now().Add(idle))
+		} else {
+			pc.co.SetReadDeadline(time.Time{})
+		}
+		pc.mu.Unlock()
+
+		r, err := pc.co.ReadMsg()
+		if r == nil {
+			if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+				pc.mu.Lock()
+				n := len(pc.inflight)
+				pc.mu.Unlock()
+				if n > 0 {
+					continue // a query was sent just before the deadline
+				}
+			}
+			pc.close()
+			return
+		}
+
+		pc.mu.Lock()
+		if pc.pool.Keepalive {
+			pc.keepalive(r)
+		}
+		q, ok := pc.inflight[r.Id]
+		if ok && (len(r.Question) == 0 || q.q.Qtype == r.Question[0].Qtype && q.q.Qclass == r.Question[0].Qclass && strings.EqualFold(q.q.Name, r.Question[0].Name)) {
+			delete(pc.inflight, r.Id)
+			q.ch <- poolResult{r, err}
+		}
+		pc.mu.Unlock()
+	}
+}
+
+// keepalive takes the idle timeout advertised in the edns-tcp-keepalive
+// option of r, if any. The caller holds pc.mu.
+func (pc *poolConn) keepalive(r *Msg) {
+	opt := r.IsEdns0()
+	if opt == nil {
+		return
+	}
+	for _, o := range opt.Option {
+		if e, ok := o.(*EDNS0_TCP_KEEPALIVE); ok && e.Length == 2 {
+			if e.Timeout == 0 {
+				pc.draining = true
+			} else {
+				pc.idle = time.Duration(e.Timeout) * 100 * time.Millisecond
+			}
+			return
+		}
+	}
+}
+
+// withKeepalive returns m with an edns-tcp-keepalive option without timeout
+// added to its OPT record, m is copied first unless copied is true. Messages
+// without OPT record, or with the option already, are returned as they are.
+func withKeepalive(m *Msg, copied bool) *Msg {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return m
+	}
+	for _, o := range opt.Option {
+		if o.Option() == EDNS0TCPKEEPALIVE {
+			return m
+		}
+	}
+	if !copied {
+		m = m.Copy()
+		opt = m.IsEdns0()
+	}
+	opt.Option = append(opt.Option, &EDNS0_TCP_KEEPALIVE{Code: EDNS0TCPKEEPALIVE})
+	return m
+}
+
+// close closes pc, removes it from its pool, and fails the queries outstanding
+// on it with errPoolConnClosed.
+func (pc *poolConn) close() {
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		return
+	}
+	pc.closed = true
+	for id, q := range pc.inflight {
+		q.ch <- poolResult{nil, errPoolConnClosed}
+		delete(pc.inflight, id)
+	}
+	pc.mu.Unlock()
+	pc.co.Close()
+
+	p := pc.pool
+	p.mu.Lock()
+	conns := p.conns[pc.key]
+	for i, c := range conns {
+		if c == pc {
+			p.conns[pc.key] = append(conns[:i:i], conns[i+1:]...)
+			break
+		}
+	}
+	if len(p.conns[pc.key]) == 0 {
+		delete(p.conns, pc.key)
+	}
+	p.mu.Unlock()
+}
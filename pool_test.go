@@ -0,0 +1,183 @@
+package dns
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnPool(t *testing.T) {
+	HandleFunc("miek.nl.", HelloServer)
+	defer HandleRemove("miek.nl.")
+
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.TCPConcurrency = 8
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	pool := &ConnPool{MaxConns: 1}
+	defer pool.Close()
+	c := &Client{Net: "tcp", Pool: pool}
+
+	const queries = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, queries)
+	for i := 0; i < queries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// All queries have the same ID, the pool gives them unique ones.
+			m := new(Msg)
+			m.SetQuestion("miek.nl.", TypeTXT)
+			m.Id = 1234
+			r, _, err := c.Exchange(m, addrstr)
+			if err == nil && (r.Id != 1234 || len(r.Extra) != 1) {
+				err = &Error{err: "bad response " + r.String()}
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	var conns uint64
+	for _, st := range s.SocketStats() {
+		conns += st.Connections
+	}
+	if conns != 1 {
+		t.Errorf("expected queries to share 1 connection, got %d", conns)
+	}
+}
+
+func TestConnPoolDialer(t *testing.T) {
+	HandleFunc("miek.nl.", HelloServer)
+	defer HandleRemove("miek.nl.")
+
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	pool := &ConnPool{MaxConns: 1}
+	defer pool.Close()
+
+	// Clients that dial differently don't share connections.
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	for _, c := range []*Client{{Net: "tcp", Pool: pool}, {Net: "tcp", Pool: pool, Dialer: &net.Dialer{Timeout: time.Second}}} {
+		if _, _, err := c.Exchange(m, addrstr); err != nil {
+			t.Fatalf("failed to exchange: %v", err)
+		}
+	}
+
+	var conns uint64
+	for _, st := range s.SocketStats() {
+		conns += st.Connections
+	}
+	if conns != 2 {
+		t.Errorf("expected 2 connections, got %d", conns)
+	}
+}
+
+func TestConnPoolReconnect(t *testing.T) {
+	HandleFunc("miek.nl.", HelloServer)
+	defer HandleRemove("miek.nl.")
+
+	// The server closes the connection after every query.
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.MaxTCPQueries = 1
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	pool := new(ConnPool)
+	defer pool.Close()
+	c := &Client{Net: "tcp", Pool: pool}
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	for i := 0; i < 5; i++ {
+		if _, _, err := c.Exchange(m, addrstr); err != nil {
+			t.Fatalf("query %d: failed to exchange: %v", i, err)
+		}
+	}
+}
+
+func TestConnPoolKeepalive(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		timeout = 30 * time.Second
+		sawKa   bool
+	)
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = HandlerFunc(func(w ResponseWriter, req *Msg) {
+			mu.Lock()
+			if opt := req.IsEdns0(); opt != nil && len(opt.Option) == 1 {
+				_, sawKa = opt.Option[0].(*EDNS0_TCP_KEEPALIVE)
+			}
+			mu.Unlock()
+			m := new(Msg)
+			m.SetReply(req)
+			m.SetEdns0(4096, false)
+			w.WriteMsg(m)
+		})
+		srv.TCPKeepalive = func(int) time.Duration {
+			mu.Lock()
+			defer mu.Unlock()
+			return timeout
+		}
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	pool := &ConnPool{MaxConns: 1, Keepalive: true}
+	defer pool.Close()
+	c := &Client{Net: "tcp", Pool: pool}
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	m.SetEdns0(4096, false)
+	for i := 0; i < 2; i++ {
+		if _, _, err := c.Exchange(m, addrstr); err != nil {
+			t.Fatalf("query %d: failed to exchange: %v", i, err)
+		}
+	}
+	if len(m.IsEdns0().Option) != 0 {
+		t.Errorf("expected query not to be modified, got %v", m.IsEdns0())
+	}
+	mu.Lock()
+	if !sawKa {
+		t.Error("expected query to carry the keepalive option")
+	}
+	// A zero timeout tells the pool to stop using the connection.
+	timeout = 0
+	mu.Unlock()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := c.Exchange(m, addrstr); err != nil {
+			t.Fatalf("query %d: failed to exchange: %v", i, err)
+		}
+	}
+
+	var conns uint64
+	for _, st := range s.SocketStats() {
+		conns += st.Connections
+	}
+	if conns != 2 {
+		t.Errorf("expected queries to use 2 connections, got %d", conns)
+	}
+}
@@ -0,0 +1,72 @@
+package dns
+
+import "strconv"
+
+// PrivateOption is the data of an EDNS0 option registered with
+// PrivateOptionHandle, such as an experimental or vendor option, so it is
+// unpacked into a type of the caller instead of an EDNS0_LOCAL.
+type PrivateOption interface {
+	// Pack returns the data of the option.
+	Pack() ([]byte, error)
+	// Unpack sets the option from its data.
+	Unpack([]byte) error
+	// String returns the text presentation of the option.
+	String() string
+	// Copy returns a deep copy of the option.
+	Copy() PrivateOption
+}
+
+// EDNS0_PRIVATE is an EDNS0 option whose code was registered with
+// PrivateOptionHandle. Data holds the PrivateOption the generator returned,
+// an option with a nil Data is empty.
+//
+//	o := &dns.EDNS0_PRIVATE{Code: 65001, Data: &myOption{...}}
+//	opt.Option = append(opt.Option, o)
+type EDNS0_PRIVATE struct {
+	Code uint16
+	Data PrivateOption
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_PRIVATE) Option() uint16 { return e.Code }
+func (e *EDNS0_PRIVATE) copy() EDNS0 {
+	if e.Data == nil {
+		return &EDNS0_PRIVATE{e.Code, nil}
+	}
+	return &EDNS0_PRIVATE{e.Code, e.Data.Copy()}
+}
+
+func (e *EDNS0_PRIVATE) String() string {
+	s := strconv.FormatInt(int64(e.Code), 10) + ":"
+	if e.Data == nil {
+		return s
+	}
+	return s + e.Data.String()
+}
+
+func (e *EDNS0_PRIVATE) pack() ([]byte, error) {
+	if e.Data == nil {
+		return nil, nil
+	}
+	return e.Data.Pack()
+}
+
+func (e *EDNS0_PRIVATE) unpack(b []byte) error { return e.Data.Unpack(b) }
+
+// privateOptions has the generators of the registered options.
+var privateOptions = map[uint16]func() PrivateOption{}
+
+// PrivateOptionHandle registers the EDNS0 option code, so the options with
+// that code are unpacked into an EDNS0_PRIVATE holding what generator
+// returns. A code that is unpacked into an option type of this package can
+// be registered too, to replace it. Like PrivateHandle, it should be called
+// before messages are unpacked, e.g. in an init function.
+func PrivateOptionHandle(code uint16, generator func() PrivateOption) {
+	privateOptions[code] = generator
+}
+
+// PrivateOptionHandleRemove removes the registration of the EDNS0 option
+// code.
+func PrivateOptionHandleRemove(code uint16) {
+	delete(privateOptions, code)
+}
@@ -0,0 +1,99 @@
+package dns
+
+import (
+	"encoding/binary"
+	"strconv"
+	"testing"
+)
+
+// vendorOption is a private option with a site number and a flag.
+type vendorOption struct {
+	Site uint16
+	Flag bool
+}
+
+func (o *vendorOption) Pack() ([]byte, error) {
+	b := make([]byte, 3)
+	binary.BigEndian.PutUint16(b, o.Site)
+	if o.Flag {
+		b[2] = 1
+	}
+	return b, nil
+}
+
+func (o *vendorOption) Unpack(b []byte) error {
+	if len(b) != 3 {
+		return ErrBuf
+	}
+	o.Site, o.Flag = binary.BigEndian.Uint16(b), b[2] == 1
+	return nil
+}
+
+func (o *vendorOption) String() string {
+	return "site " + strconv.Itoa(int(o.Site)) + " flag " + strconv.FormatBool(o.Flag)
+}
+
+func (o *vendorOption) Copy() PrivateOption { o1 := *o; return &o1 }
+
+const vendorOptionCode = EDNS0LOCALSTART + 1
+
+func TestPrivateOption(t *testing.T) {
+	PrivateOptionHandle(vendorOptionCode, func() PrivateOption { return new(vendorOption) })
+	defer PrivateOptionHandleRemove(vendorOptionCode)
+
+	m := new(Msg).SetQuestion("miek.nl.", TypeA).SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option,
+		&EDNS0_PRIVATE{Code: vendorOptionCode, Data: &vendorOption{Site: 42, Flag: true}},
+		&EDNS0_NSID{Code: EDNS0NSID})
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatalf("failed to unpack: %v", err)
+	}
+	opt = m1.IsEdns0()
+	if len(opt.Option) != 2 {
+		t.Fatalf("expected 2 options, got %v", opt)
+	}
+	p, ok := opt.Option[0].(*EDNS0_PRIVATE)
+	if !ok {
+		t.Fatalf("expected EDNS0_PRIVATE, got %T", opt.Option[0])
+	}
+	if v, ok := p.Data.(*vendorOption); !ok || v.Site != 42 || !v.Flag {
+		t.Errorf("expected site 42 with flag, got %v", p.Data)
+	}
+	if _, ok := opt.Option[1].(*EDNS0_NSID); !ok {
+		t.Errorf("expected EDNS0_NSID, got %T", opt.Option[1])
+	}
+	if s := p.String(); s != "65002:site 42 flag true" {
+		t.Errorf("unexpected presentation %q", s)
+	}
+
+	c := m1.Copy().IsEdns0().Option[0].(*EDNS0_PRIVATE)
+	c.Data.(*vendorOption).Site = 7
+	if p.Data.(*vendorOption).Site != 42 {
+		t.Errorf("expected copy to be deep")
+	}
+
+	PrivateOptionHandleRemove(vendorOptionCode)
+	m1 = new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatalf("failed to unpack: %v", err)
+	}
+	if _, ok := m1.IsEdns0().Option[0].(*EDNS0_LOCAL); !ok {
+		t.Errorf("expected EDNS0_LOCAL once removed, got %T", m1.IsEdns0().Option[0])
+	}
+
+	// An option without Data is empty.
+	m.IsEdns0().Option = []EDNS0{&EDNS0_PRIVATE{Code: vendorOptionCode}}
+	if s := m.Copy().IsEdns0().Option[0].String(); s != "65002:" {
+		t.Errorf("unexpected presentation %q", s)
+	}
+	if _, err := m.Pack(); err != nil {
+		t.Errorf("failed to pack an empty option: %v", err)
+	}
+}
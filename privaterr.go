@@ -1,7 +1,9 @@
 package dns
 
 import (
+	"bytes"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -31,6 +33,20 @@ type PrivateRR struct {
 	Data PrivateRdata
 }
 
+// privateGenerators holds the generators of the registered private RR types.
+var privateGenerators = map[uint16]func(rrtype, class uint16) PrivateRdata{}
+
+// newPrivateRR returns a PrivateRR with the header h, and the rdata of its
+// type and class.
+func newPrivateRR(h RR_Header) *PrivateRR {
+	if generator, ok := privateGenerators[h.Rrtype]; ok {
+		return &PrivateRR{h, generator(h.Rrtype, h.Class)}
+	}
+	rr := mkPrivateRR(h.Rrtype)
+	rr.Hdr = h
+	return rr
+}
+
 func mkPrivateRR(rrtype uint16) *PrivateRR {
 	// Panics if RR is not an instance of PrivateRR.
 	rrfunc, ok := TypeToRR[rrtype]
@@ -60,8 +76,7 @@ func (r *PrivateRR) len(off int, compression map[string]struct{}) int {
 
 func (r *PrivateRR) copy() RR {
 	// make new RR like this:
-	rr := mkPrivateRR(r.Hdr.Rrtype)
-	rr.Hdr = r.Hdr
+	rr := newPrivateRR(r.Hdr)
 
 	err := r.Data.Copy(rr.Data)
 	if err != nil {
@@ -70,6 +85,32 @@ func (r *PrivateRR) copy() RR {
 	return rr
 }
 
+func (r *PrivateRR) isDuplicate(_r2 RR) bool {
+	r2, ok := _r2.(*PrivateRR)
+	if !ok {
+		return false
+	}
+	b1, err := packPrivateRdata(r.Data)
+	if err != nil {
+		return false
+	}
+	b2, err := packPrivateRdata(r2.Data)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(b1, b2)
+}
+
+// packPrivateRdata returns the rdata d packs into.
+func packPrivateRdata(d PrivateRdata) ([]byte, error) {
+	buf := make([]byte, d.Len())
+	n, err := d.Pack(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
 func (r *PrivateRR) pack(msg []byte, off int, compression compressionMap, compress bool) (int, int, error) {
 	headerEnd, off, err := r.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
@@ -86,9 +127,18 @@ func (r *PrivateRR) pack(msg []byte, off int, compression compressionMap, compre
 // PrivateHandle registers a private resource record type. It requires
 // string and numeric representation of private RR type and generator function as argument.
 func PrivateHandle(rtypestr string, rtype uint16, generator func() PrivateRdata) {
+	PrivateHandleClass(rtypestr, rtype, func(rrtype, class uint16) PrivateRdata { return generator() })
+}
+
+// PrivateHandleClass is like PrivateHandle, but generator is given the type
+// and class of the RR, so the rdata of a type can differ per class. The class
+// is ClassINET for the PrivateRRs made by TypeToRR, before their class is
+// known.
+func PrivateHandleClass(rtypestr string, rtype uint16, generator func(rrtype, class uint16) PrivateRdata) {
 	rtypestr = strings.ToUpper(rtypestr)
 
-	TypeToRR[rtype] = func() RR { return &PrivateRR{RR_Header{}, generator()} }
+	privateGenerators[rtype] = generator
+	TypeToRR[rtype] = func() RR { return &PrivateRR{RR_Header{}, generator(rtype, ClassINET)} }
 	TypeToString[rtype] = rtypestr
 	StringToType[rtypestr] = rtype
 
@@ -96,12 +146,13 @@ func PrivateHandle(rtypestr string, rtype uint16, generator func() PrivateRdata)
 		if noRdata(h) {
 			return &h, off, nil
 		}
-		var err error
-
-		rr := mkPrivateRR(h.Rrtype)
-		rr.Hdr = h
+		end := off + int(h.Rdlength)
+		if end > len(msg) {
+			return &h, len(msg), ErrBuf
+		}
 
-		off1, err := rr.Data.Unpack(msg[off:])
+		rr := newPrivateRR(h)
+		off1, err := rr.Data.Unpack(msg[off:end])
 		off += off1
 		if err != nil {
 			return rr, off, err
@@ -110,8 +161,7 @@ func PrivateHandle(rtypestr string, rtype uint16, generator func() PrivateRdata)
 	}
 
 	setPrivateRR := func(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-		rr := mkPrivateRR(h.Rrtype)
-		rr.Hdr = h
+		rr := newPrivateRR(h)
 
 		var l lex
 		text := make([]string, 0, 2) // could be 0..N elements, median is probably 1
@@ -138,6 +188,15 @@ func PrivateHandle(rtypestr string, rtype uint16, generator func() PrivateRdata)
 	typeToparserFunc[rtype] = parserFunc{setPrivateRR, true}
 }
 
+// PrivateHandleRange registers the private RR types lo to hi, inclusive, with
+// PrivateHandleClass. The mnemonic of a type is prefix followed by its number,
+// e.g. "X65280".
+func PrivateHandleRange(prefix string, lo, hi uint16, generator func(rrtype, class uint16) PrivateRdata) {
+	for t := int(lo); t <= int(hi); t++ {
+		PrivateHandleClass(prefix+strconv.Itoa(t), uint16(t), generator)
+	}
+}
+
 // PrivateHandleRemove removes definitions required to support private RR type.
 func PrivateHandleRemove(rtype uint16) {
 	rtypestr, ok := TypeToString[rtype]
@@ -147,5 +206,13 @@ func PrivateHandleRemove(rtype uint16) {
 		delete(typeToparserFunc, rtype)
 		delete(StringToType, rtypestr)
 		delete(typeToUnpack, rtype)
+		delete(privateGenerators, rtype)
+	}
+}
+
+// PrivateHandleRemoveRange removes the private RR types lo to hi, inclusive.
+func PrivateHandleRemoveRange(lo, hi uint16) {
+	for t := int(lo); t <= int(hi); t++ {
+		PrivateHandleRemove(uint16(t))
 	}
 }
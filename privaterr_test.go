@@ -1,6 +1,7 @@
 package dns_test
 
 import (
+	"net"
 	"strings"
 	"testing"
 
@@ -164,3 +165,87 @@ func TestPrivateZoneParser(t *testing.T) {
 		}
 	}
 }
+
+// LOC2 is a private RR type prototyped with PrivateStruct.
+type LOC2 struct {
+	Priority uint16
+	Target   string `dns:"domain-name"`
+	Addr     net.IP
+	Note     string
+	Extra    []byte
+}
+
+func TestPrivateStruct(t *testing.T) {
+	const TypeLOC2 = 0xFF02
+	dns.PrivateHandle("LOC2", TypeLOC2, dns.NewPrivateStruct(LOC2{}))
+	defer dns.PrivateHandleRemove(TypeLOC2)
+
+	const s = "example.org.\t3600\tIN\tLOC2\t10 target.example.org. 192.0.2.1 \"a note\" CAFE"
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := rr.(*dns.PrivateRR).Data.(*dns.PrivateStruct).Value.(*LOC2)
+	if loc.Priority != 10 || loc.Target != "target.example.org." || !loc.Addr.Equal(net.IPv4(192, 0, 2, 1)) || loc.Note != "a note" {
+		t.Fatalf("unexpected rdata %+v", loc)
+	}
+	if rr.String() != s {
+		t.Errorf("expected %q, got %q", s, rr.String())
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", TypeLOC2)
+	m.Answer = []dns.RR{rr, rr}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1 := new(dns.Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(m1.Answer) != 2 || m1.Answer[0].String() != s || m1.Answer[1].String() != s {
+		t.Errorf("expected the records to survive a round trip, got %v", m1.Answer)
+	}
+	if l, l1 := m.Len(), len(buf); l != l1 {
+		t.Errorf("expected a length of %d, got %d", l1, l)
+	}
+	if c := dns.Copy(rr); c.String() != s {
+		t.Errorf("expected a copy of %q, got %q", s, c.String())
+	}
+	if !dns.IsDuplicate(rr, m1.Answer[0]) {
+		t.Errorf("expected %s to be a duplicate of itself", rr)
+	}
+	rr1, _ := dns.NewRR("example.org. 3600 IN LOC2 20 target.example.org. 192.0.2.1 \"a note\" CAFE")
+	if dns.IsDuplicate(rr, rr1) {
+		t.Errorf("expected %s/%s not to be duplicates", rr, rr1)
+	}
+}
+
+func TestPrivateHandleRange(t *testing.T) {
+	dns.PrivateHandleRange("X", 0xFF10, 0xFF11, func(rrtype, class uint16) dns.PrivateRdata {
+		if class == dns.ClassCHAOS {
+			return NewVersion()
+		}
+		return NewISBN()
+	})
+	defer dns.PrivateHandleRemoveRange(0xFF10, 0xFF11)
+
+	rr, err := dns.NewRR("example.org. 3600 IN X65297 1-2-3")
+	if err != nil || rr.Header().Rrtype != 0xFF11 {
+		t.Fatalf("expected an X65297 record, got %v, %v", rr, err)
+	}
+	if _, ok := rr.(*dns.PrivateRR).Data.(*ISBN); !ok {
+		t.Errorf("expected ISBN rdata in class IN, got %T", rr.(*dns.PrivateRR).Data)
+	}
+	rr, err = dns.NewRR("example.org. 3600 CH X65296 1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rr.(*dns.PrivateRR).Data.(*VERSION); !ok {
+		t.Errorf("expected VERSION rdata in class CH, got %T", rr.(*dns.PrivateRR).Data)
+	}
+	if _, ok := dns.Copy(rr).(*dns.PrivateRR).Data.(*VERSION); !ok {
+		t.Error("expected the copy to keep the rdata of its class")
+	}
+}
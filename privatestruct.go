@@ -0,0 +1,258 @@
+package dns
+
+import (
+	"encoding/hex"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PrivateStruct is a PrivateRdata whose rdata are the fields of a struct, so a
+// private RR type can be prototyped without writing its String, Parse, Pack
+// and Unpack methods. Value is a pointer to the struct.
+//
+// The fields are in the rdata in order, and can be:
+//
+//	uint8, uint16, uint32  in decimal
+//	string                 a character-string, quoted in text, escaped as the strings of TXT
+//	string                 an uncompressed domain name, when tagged `dns:"domain-name"`
+//	net.IP                 an IPv4 address, or an IPv6 one when tagged `dns:"aaaa"`
+//	[]byte                 the rest of the rdata, in hex, as the last field
+//
+// Other fields are ignored.
+type PrivateStruct struct {
+	Value interface{}
+}
+
+// NewPrivateStruct returns a generator for PrivateHandle of PrivateStructs
+// holding new values of the struct type of v, which is a struct or a pointer
+// to one.
+func NewPrivateStruct(v interface{}) func() PrivateRdata {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic("dns: NewPrivateStruct of a non-struct type " + t.String())
+	}
+	return func() PrivateRdata { return &PrivateStruct{Value: reflect.New(t).Interface()} }
+}
+
+// privateField is a field of a PrivateStruct.
+type privateField struct {
+	v   reflect.Value
+	tag string
+}
+
+func (p *PrivateStruct) fields() []privateField {
+	v := reflect.ValueOf(p.Value).Elem()
+	var fields []privateField
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		switch v.Field(i).Interface().(type) {
+		case uint8, uint16, uint32, string, net.IP, []byte:
+			fields = append(fields, privateField{v.Field(i), f.Tag.Get("dns")})
+		}
+	}
+	return fields
+}
+
+// String implements PrivateRdata.
+func (p *PrivateStruct) String() string {
+	var s []string
+	for _, f := range p.fields() {
+		switch x := f.v.Interface().(type) {
+		case uint8, uint16, uint32:
+			s = append(s, strconv.FormatUint(f.v.Uint(), 10))
+		case string:
+			if f.tag == "domain-name" {
+				s = append(s, sprintName(x))
+			} else {
+				s = append(s, sprintTxt([]string{x}))
+			}
+		case net.IP:
+			s = append(s, x.String())
+		case []byte:
+			s = append(s, strings.ToUpper(hex.EncodeToString(x)))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+// Parse implements PrivateRdata.
+func (p *PrivateStruct) Parse(txt []string) error {
+	for _, f := range p.fields() {
+		if len(txt) == 0 {
+			return ErrRdata
+		}
+		switch f.v.Interface().(type) {
+		case uint8, uint16, uint32:
+			i, err := strconv.ParseUint(txt[0], 10, f.v.Type().Bits())
+			if err != nil {
+				return ErrRdata
+			}
+			f.v.SetUint(i)
+		case string:
+			if f.tag == "domain-name" {
+				if _, ok := IsDomainName(txt[0]); !ok {
+					return ErrRdata
+				}
+			}
+			f.v.SetString(txt[0])
+		case net.IP:
+			ip := net.ParseIP(txt[0])
+			if ip == nil || (ip.To4() == nil) != (f.tag == "aaaa") {
+				return ErrRdata
+			}
+			f.v.Set(reflect.ValueOf(ip))
+		case []byte:
+			b, err := hex.DecodeString(strings.Join(txt, ""))
+			if err != nil {
+				return ErrRdata
+			}
+			f.v.SetBytes(b)
+			txt = nil
+			continue
+		}
+		txt = txt[1:]
+	}
+	if len(txt) > 0 {
+		return ErrRdata
+	}
+	return nil
+}
+
+// Pack implements PrivateRdata.
+func (p *PrivateStruct) Pack(buf []byte) (int, error) {
+	off := 0
+	var err error
+	for _, f := range p.fields() {
+		switch x := f.v.Interface().(type) {
+		case uint8:
+			off, err = packUint8(x, buf, off)
+		case uint16:
+			off, err = packUint16(x, buf, off)
+		case uint32:
+			off, err = packUint32(x, buf, off)
+		case string:
+			if f.tag == "domain-name" {
+				off, err = PackDomainName(x, buf, off, nil, false)
+			} else {
+				off, err = packString(x, buf, off)
+			}
+		case net.IP:
+			if f.tag == "aaaa" {
+				off, err = packDataAAAA(x, buf, off)
+			} else {
+				off, err = packDataA(x, buf, off)
+			}
+		case []byte:
+			if off+len(x) > len(buf) {
+				return len(buf), ErrBuf
+			}
+			off += copy(buf[off:], x)
+		}
+		if err != nil {
+			return off, err
+		}
+	}
+	return off, nil
+}
+
+// Unpack implements PrivateRdata.
+func (p *PrivateStruct) Unpack(buf []byte) (int, error) {
+	off := 0
+	var err error
+	for _, f := range p.fields() {
+		switch f.v.Interface().(type) {
+		case uint8:
+			var i uint8
+			i, off, err = unpackUint8(buf, off)
+			f.v.SetUint(uint64(i))
+		case uint16:
+			var i uint16
+			i, off, err = unpackUint16(buf, off)
+			f.v.SetUint(uint64(i))
+		case uint32:
+			var i uint32
+			i, off, err = unpackUint32(buf, off)
+			f.v.SetUint(uint64(i))
+		case string:
+			var s string
+			if f.tag == "domain-name" {
+				s, off, err = UnpackDomainName(buf, off)
+			} else {
+				s, off, err = unpackString(buf, off)
+			}
+			f.v.SetString(s)
+		case net.IP:
+			var ip net.IP
+			if f.tag == "aaaa" {
+				ip, off, err = unpackDataAAAA(buf, off)
+			} else {
+				ip, off, err = unpackDataA(buf, off)
+			}
+			f.v.Set(reflect.ValueOf(ip))
+		case []byte:
+			f.v.SetBytes(append([]byte(nil), buf[off:]...))
+			off = len(buf)
+		}
+		if err != nil {
+			return off, err
+		}
+	}
+	return off, nil
+}
+
+// Copy implements PrivateRdata.
+func (p *PrivateStruct) Copy(dest PrivateRdata) error {
+	d, ok := dest.(*PrivateStruct)
+	if !ok || reflect.TypeOf(d.Value) != reflect.TypeOf(p.Value) {
+		return ErrRdata
+	}
+	dv := reflect.ValueOf(d.Value).Elem()
+	dv.Set(reflect.ValueOf(p.Value).Elem())
+	for _, f := range d.fields() {
+		switch x := f.v.Interface().(type) {
+		case net.IP:
+			f.v.Set(reflect.ValueOf(copyIP(x)))
+		case []byte:
+			f.v.SetBytes(append([]byte(nil), x...))
+		}
+	}
+	return nil
+}
+
+// Len implements PrivateRdata.
+func (p *PrivateStruct) Len() int {
+	l := 0
+	for _, f := range p.fields() {
+		switch x := f.v.Interface().(type) {
+		case uint8:
+			l++
+		case uint16:
+			l += 2
+		case uint32:
+			l += 4
+		case string:
+			if f.tag == "domain-name" {
+				l += domainNameLen(x, 0, nil, false)
+			} else {
+				l += octetStringLen(x) + 1
+			}
+		case net.IP:
+			if f.tag == "aaaa" {
+				l += net.IPv6len
+			} else {
+				l += net.IPv4len
+			}
+		case []byte:
+			l += len(x)
+		}
+	}
+	return l
+}
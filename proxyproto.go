@@ -0,0 +1,216 @@
+package dns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The PROXY protocol lets a load balancer or proxy that forwards connections
+// pass the address of the client it accepted them from: it sends a header
+// before the data of the client. Version 1 is a line of text, version 2 is
+// binary and can be used over UDP too, the header then starts each datagram.
+
+// proxyV2Sig is the signature version 2 headers start with.
+var proxyV2Sig = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	proxyV1MaxLen = 107 // longest version 1 header, with the CRLF
+	proxyV2HdrLen = 16  // fixed part of a version 2 header
+)
+
+var (
+	errProxyHeader  = &Error{err: "bad PROXY protocol header"}
+	errProxyTrusted = &Error{err: "ProxyTrusted is required with the PROXY protocol"}
+)
+
+// NewProxyListener returns a listener whose connections from the proxies of
+// trusted must start with a PROXY protocol header, version 1 or 2. The header
+// is read and removed on the first Read, connections without a valid one fail
+// with an error. The RemoteAddr of the connections returns the client address
+// of the header, unless it has none, as a health check of the proxy, then the
+// address of the proxy itself. Connections from other peers are returned as
+// they are: anyone could send a header.
+//
+// Server.ProxyProtocol uses it, it can be used directly for a DNS over TLS
+// listener given to ActivateAndServe, the header precedes the TLS handshake:
+//
+//	l = tls.NewListener(dns.NewProxyListener(l, trusted), config)
+func NewProxyListener(l net.Listener, trusted []*net.IPNet) net.Listener {
+	return &proxyListener{Listener: l, trusted: trusted}
+}
+
+type proxyListener struct {
+	net.Listener
+	trusted []*net.IPNet
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !proxyTrusted(l.trusted, c.RemoteAddr()) {
+		return c, nil
+	}
+	return &proxyConn{Conn: c}, nil
+}
+
+// proxyTrusted reports whether the peer at addr is in one of the networks of
+// trusted.
+func proxyTrusted(trusted []*net.IPNet, addr net.Addr) bool {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr:
+		ip = a.IP
+	default:
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyConn is a connection of a proxyListener. The header is read from it
+// the first time it is read, so the read deadline applies, or its RemoteAddr
+// is asked.
+type proxyConn struct {
+	net.Conn
+
+	once   sync.Once
+	remote net.Addr // client address of the header, if any
+	err    error    // error reading the header, returned by Read
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyConn) readHeader() {
+	ip, port, err := readProxyHeader(c.Conn)
+	if err != nil {
+		c.err = err
+		return
+	}
+	if ip != nil {
+		c.remote = &net.TCPAddr{IP: ip, Port: port}
+	}
+}
+
+// readProxyHeader reads a version 1 or 2 header from r, without reading
+// beyond it, and returns the client address it holds.
+func readProxyHeader(r io.Reader) (net.IP, int, error) {
+	b := make([]byte, len(proxyV2Sig), proxyV1MaxLen)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, 0, err
+	}
+	if bytes.Equal(b, proxyV2Sig) {
+		b = append(b, make([]byte, proxyV2HdrLen-len(b))...)
+		if _, err := io.ReadFull(r, b[len(proxyV2Sig):]); err != nil {
+			return nil, 0, err
+		}
+		b = append(b, make([]byte, binary.BigEndian.Uint16(b[14:]))...)
+		if _, err := io.ReadFull(r, b[proxyV2HdrLen:]); err != nil {
+			return nil, 0, err
+		}
+		ip, port, _, err := parseProxyV2(b)
+		return ip, port, err
+	}
+	if !bytes.HasPrefix(b, []byte("PROXY ")) {
+		return nil, 0, errProxyHeader
+	}
+	c := make([]byte, 1)
+	for b[len(b)-1] != '\n' {
+		if len(b) == proxyV1MaxLen {
+			return nil, 0, errProxyHeader
+		}
+		if _, err := io.ReadFull(r, c); err != nil {
+			return nil, 0, err
+		}
+		b = append(b, c[0])
+	}
+	return parseProxyV1(b)
+}
+
+// parseProxyV1 parses the version 1 header b, a line ending in CRLF, e.g.
+// "PROXY TCP4 192.0.2.1 192.0.2.2 56324 53\r\n". The address is nil for the
+// UNKNOWN protocol.
+func parseProxyV1(b []byte) (net.IP, int, error) {
+	if !bytes.HasSuffix(b, []byte("\r\n")) {
+		return nil, 0, errProxyHeader
+	}
+	f := strings.Split(string(b[:len(b)-2]), " ")
+	if len(f) >= 2 && f[0] == "PROXY" && f[1] == "UNKNOWN" {
+		return nil, 0, nil
+	}
+	if len(f) != 6 || f[0] != "PROXY" || (f[1] != "TCP4" && f[1] != "TCP6") {
+		return nil, 0, errProxyHeader
+	}
+	ip := net.ParseIP(f[2])
+	if ip == nil || net.ParseIP(f[3]) == nil || (ip.To4() != nil) != (f[1] == "TCP4") {
+		return nil, 0, errProxyHeader
+	}
+	port, err := strconv.ParseUint(f[4], 10, 16)
+	if err != nil {
+		return nil, 0, errProxyHeader
+	}
+	if _, err := strconv.ParseUint(f[5], 10, 16); err != nil {
+		return nil, 0, errProxyHeader
+	}
+	return ip, int(port), nil
+}
+
+// parseProxyV2 parses the version 2 header at the start of b, and returns the
+// client address and the length of the header. The address is nil for the
+// LOCAL command and for families other than IPv4 and IPv6.
+func parseProxyV2(b []byte) (net.IP, int, int, error) {
+	if len(b) < proxyV2HdrLen || !bytes.Equal(b[:len(proxyV2Sig)], proxyV2Sig) || b[12]>>4 != 2 {
+		return nil, 0, 0, errProxyHeader
+	}
+	n := proxyV2HdrLen + int(binary.BigEndian.Uint16(b[14:]))
+	if len(b) < n {
+		return nil, 0, 0, errProxyHeader
+	}
+	switch b[12] & 0xF {
+	case 0: // LOCAL
+		return nil, 0, n, nil
+	case 1: // PROXY
+	default:
+		return nil, 0, 0, errProxyHeader
+	}
+	a := b[proxyV2HdrLen:n]
+	switch b[13] >> 4 {
+	case 1: // AF_INET
+		if len(a) < 12 {
+			return nil, 0, 0, errProxyHeader
+		}
+		return net.IP(append([]byte(nil), a[:4]...)), int(binary.BigEndian.Uint16(a[8:])), n, nil
+	case 2: // AF_INET6
+		if len(a) < 36 {
+			return nil, 0, 0, errProxyHeader
+		}
+		return net.IP(append([]byte(nil), a[:16]...)), int(binary.BigEndian.Uint16(a[32:])), n, nil
+	}
+	return nil, 0, n, nil
+}
@@ -0,0 +1,244 @@
+package dns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// proxyV2Header returns a version 2 PROXY header for src and dst, with proto
+// 1 for TCP and 2 for UDP.
+func proxyV2Header(src, dst *net.TCPAddr, proto byte) []byte {
+	b := append([]byte(nil), proxyV2Sig...)
+	b = append(b, 0x21, 0x10|proto, 0, 12)
+	b = append(b, src.IP.To4()...)
+	b = append(b, dst.IP.To4()...)
+	b = append(b, byte(src.Port>>8), byte(src.Port), byte(dst.Port>>8), byte(dst.Port))
+	return b
+}
+
+// proxyTestTrusted trusts the proxies on the loopback network the test
+// servers listen on.
+var proxyTestTrusted = []*net.IPNet{{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)}}
+
+func remoteAddrServer(addrs chan<- net.Addr) func(*Server) {
+	return func(srv *Server) {
+		srv.Handler = HandlerFunc(func(w ResponseWriter, req *Msg) {
+			addrs <- w.RemoteAddr()
+			m := new(Msg)
+			m.SetReply(req)
+			w.WriteMsg(m)
+		})
+	}
+}
+
+func TestServerProxyProtocolTCP(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		header []byte
+		want   string
+	}{
+		{"v1", []byte("PROXY TCP4 192.0.2.1 192.0.2.2 56324 53\r\n"), "192.0.2.1:56324"},
+		{"v1 ipv6", []byte("PROXY TCP6 2001:db8::1 2001:db8::2 56324 53\r\n"), "[2001:db8::1]:56324"},
+		{"v2", proxyV2Header(&net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 56324}, &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 53}, 1), "192.0.2.1:56324"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			addrs := make(chan net.Addr, 1)
+			s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", remoteAddrServer(addrs), func(srv *Server) {
+				srv.ProxyProtocol = true
+				srv.ProxyTrusted = proxyTestTrusted
+			})
+			if err != nil {
+				t.Fatalf("unable to run test server: %v", err)
+			}
+			defer s.Shutdown()
+
+			co, err := DialTimeout("tcp", addrstr, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer co.Close()
+			co.SetDeadline(time.Now().Add(5 * time.Second))
+			if _, err := co.Conn.Write(tc.header); err != nil {
+				t.Fatal(err)
+			}
+
+			m := new(Msg)
+			m.SetQuestion("miek.nl.", TypeTXT)
+			for i := 0; i < 2; i++ {
+				if err := co.WriteMsg(m); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := co.ReadMsg(); err != nil {
+					t.Fatalf("query %d: %v", i, err)
+				}
+				if addr := (<-addrs).String(); addr != tc.want {
+					t.Errorf("query %d: expected remote address %s, got %s", i, tc.want, addr)
+				}
+			}
+		})
+	}
+}
+
+func TestServerProxyProtocolTCPMissing(t *testing.T) {
+	addrs := make(chan net.Addr, 1)
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", remoteAddrServer(addrs), func(srv *Server) {
+		srv.ProxyProtocol = true
+		srv.ProxyTrusted = proxyTestTrusted
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	c := &Client{Net: "tcp"}
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	if _, _, err := c.Exchange(m, addrstr); err == nil {
+		t.Error("expected query without PROXY header to fail")
+	}
+}
+
+func TestServerProxyProtocolUntrusted(t *testing.T) {
+	_, documentation, _ := net.ParseCIDR("192.0.2.0/24")
+	for _, tc := range []struct {
+		net   string
+		proxy func(*Server)
+	}{
+		{"tcp", func(srv *Server) { srv.ProxyProtocol = true }},
+		{"udp", func(srv *Server) { srv.ProxyProtocolUDP = true }},
+	} {
+		t.Run(tc.net, func(t *testing.T) {
+			// ProxyTrusted is required.
+			srv := &Server{Addr: "127.0.0.1:0", Net: tc.net}
+			tc.proxy(srv)
+			if err := srv.ListenAndServe(); err != errProxyTrusted {
+				t.Fatalf("expected %v, got %v", errProxyTrusted, err)
+			}
+
+			run := RunLocalTCPServerWithFinChan
+			if tc.net == "udp" {
+				run = RunLocalUDPServerWithFinChan
+			}
+			addrs := make(chan net.Addr, 1)
+			s, addrstr, _, err := run("127.0.0.1:0", remoteAddrServer(addrs), tc.proxy, func(srv *Server) {
+				srv.ProxyTrusted = []*net.IPNet{documentation}
+			})
+			if err != nil {
+				t.Fatalf("unable to run test server: %v", err)
+			}
+			defer s.Shutdown()
+
+			// A client that isn't a trusted proxy is served without header.
+			c := &Client{Net: tc.net}
+			m := new(Msg)
+			m.SetQuestion("miek.nl.", TypeTXT)
+			if _, _, err := c.Exchange(m, addrstr); err != nil {
+				t.Fatal(err)
+			}
+			if ip := (<-addrs).String(); !strings.HasPrefix(ip, "127.0.0.1:") {
+				t.Errorf("expected the address of the client, got %s", ip)
+			}
+		})
+	}
+}
+
+func TestServerProxyProtocolUDP(t *testing.T) {
+	addrs := make(chan net.Addr, 1)
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", remoteAddrServer(addrs), func(srv *Server) {
+		srv.ProxyProtocolUDP = true
+		srv.ProxyTrusted = proxyTestTrusted
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	co, err := DialTimeout("udp", addrstr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer co.Close()
+	co.SetDeadline(time.Now().Add(5 * time.Second))
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	buf, _ := m.Pack()
+	hdr := proxyV2Header(&net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 56324}, &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 53}, 2)
+	// A query without header is dropped.
+	if _, err := co.Conn.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := co.Conn.Write(append(hdr, buf...)); err != nil {
+		t.Fatal(err)
+	}
+	r, err := co.ReadMsg()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Id != m.Id {
+		t.Errorf("expected response %d, got %d", m.Id, r.Id)
+	}
+	if addr := (<-addrs).String(); addr != "192.0.2.1:56324" {
+		t.Errorf("expected remote address 192.0.2.1:56324, got %s", addr)
+	}
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	v2 := proxyV2Header(&net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1}, &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 2}, 1)
+	local := append([]byte(nil), proxyV2Sig...)
+	local = append(local, 0x20, 0, 0, 0)
+	for _, tc := range []struct {
+		header []byte
+		want   string // client address, "" when the header holds none
+		err    bool
+	}{
+		{[]byte("PROXY UNKNOWN\r\n"), "", false},
+		{[]byte("PROXY UNKNOWN 192.0.2.1 192.0.2.2 1 2\r\n"), "", false},
+		{[]byte("PROXY TCP4 192.0.2.1 192.0.2.2 1 2\r\n"), "192.0.2.1:1", false},
+		{v2, "192.0.2.1:1", false},
+		{local, "", false},
+		{[]byte("PROXY TCP4 2001:db8::1 192.0.2.2 1 2\r\n"), "", true},
+		{[]byte("PROXY TCP4 192.0.2.1 192.0.2.2 1 65536\r\n"), "", true},
+		{[]byte("PROXY TCP4 192.0.2.1 192.0.2.2 1\r\n"), "", true},
+		{[]byte("PROXY TCP4 192.0.2.1 192.0.2.2 1 2\n"), "", true},
+		{append([]byte("PROXY UNKNOWN "), bytes.Repeat([]byte("x"), 100)...), "", true},
+		{[]byte("GET / HTTP/1.1\r\n"), "", true},
+		{v2[:20], "", true},
+	} {
+		// The data following the header is left unread.
+		r := bytes.NewReader(append(append([]byte(nil), tc.header...), "data"...))
+		ip, port, err := readProxyHeader(r)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tc.header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.header, err)
+			continue
+		}
+		addr := ""
+		if ip != nil {
+			addr = (&net.TCPAddr{IP: ip, Port: port}).String()
+		}
+		if addr != tc.want {
+			t.Errorf("%q: expected address %q, got %q", tc.header, tc.want, addr)
+		}
+		if r.Len() != 4 {
+			t.Errorf("%q: expected data after the header to be left, %d bytes are", tc.header, r.Len())
+		}
+	}
+
+	// A version 2 header with TLVs after the addresses.
+	b := append([]byte(nil), v2...)
+	binary.BigEndian.PutUint16(b[14:], 12+4)
+	b = append(b, 0x04, 0, 1, 0) // PP2_TYPE_NOOP
+	if _, _, n, err := parseProxyV2(append(b, "data"...)); err != nil || n != len(b) {
+		t.Errorf("expected header of %d bytes, got %d: %v", len(b), n, err)
+	}
+}
@@ -0,0 +1,77 @@
+package dns
+
+import (
+	"encoding/binary"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// A QueryLogger logs the queries of a Server, set as Server.QueryLog. LogQuery
+// is called once the handler of a query returned, from many goroutines, it
+// must be safe for concurrent use.
+type QueryLogger interface {
+	LogQuery(e *QueryLogEntry)
+}
+
+// The QueryLogFunc type is an adapter to allow the use of ordinary functions
+// as query loggers.
+type QueryLogFunc func(*QueryLogEntry)
+
+// LogQuery calls f(e).
+func (f QueryLogFunc) LogQuery(e *QueryLogEntry) { f(e) }
+
+// A QueryLogEntry describes a query and the response the server wrote for it.
+// It must not be retained after the call.
+type QueryLogEntry struct {
+	Time   time.Time // time the query was read
+	Client net.Addr
+	Net    string // "udp", "tcp" or "tcp-tls"
+	Id     uint16
+	Opcode int
+	Qname  string // name of the first question, "" without one
+	Qtype  uint16
+	Qclass uint16
+
+	Rcode    int           // rcode of the response, with the extended bits when written with WriteMsg, -1 when none was written
+	Answers  int           // number of records in the answer section, summed over the messages of a multi-message response
+	Size     int           // size of the response in bytes, summed likewise
+	Duration time.Duration // time the query took to handle
+
+	// TsigStatus is the result of the TSIG verification of the query, nil if
+	// it wasn't signed or verified fine.
+	TsigStatus error
+}
+
+// sampleQuery reports whether the next query must be logged.
+func (srv *Server) sampleQuery() bool {
+	n := srv.QueryLogSample
+	if n <= 1 {
+		return true
+	}
+	return atomic.AddUint32(&srv.queryLogCount, 1)%uint32(n) == 0
+}
+
+// logResponse adds the response msg, with rcode, to the query log entry of w.
+func (w *response) logResponse(msg []byte, rcode int) {
+	w.queryLog.Rcode = rcode
+	w.queryLog.Size += len(msg)
+	if len(msg) >= headerSize {
+		w.queryLog.Answers += int(binary.BigEndian.Uint16(msg[6:]))
+	}
+}
+
+// logQuery logs req, with the response written for it, to the query log of
+// the server.
+func (w *response) logQuery(req *Msg) {
+	e := w.queryLog
+	w.queryLog = nil
+	e.Time, e.Client, e.Net = w.queryTime, w.RemoteAddr(), w.network()
+	e.Id, e.Opcode = req.Id, req.Opcode
+	if len(req.Question) > 0 {
+		e.Qname, e.Qtype, e.Qclass = req.Question[0].Name, req.Question[0].Qtype, req.Question[0].Qclass
+	}
+	e.Duration = time.Since(w.queryTime)
+	e.TsigStatus = w.tsigStatus
+	w.srv.QueryLog.LogQuery(e)
+}
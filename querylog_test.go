@@ -0,0 +1,83 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServerQueryLog(t *testing.T) {
+	entries := make(chan QueryLogEntry, 10)
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = HandlerFunc(func(w ResponseWriter, req *Msg) {
+			m := new(Msg)
+			m.SetReply(req)
+			if req.Question[0].Qtype == TypeA {
+				m.Answer = append(m.Answer, testRR("miek.nl. 3600 IN A 127.0.0.1"), testRR("miek.nl. 3600 IN A 127.0.0.2"))
+			} else {
+				m.Rcode = RcodeRefused
+			}
+			w.WriteMsg(m)
+		})
+		srv.QueryLog = QueryLogFunc(func(e *QueryLogEntry) { entries <- *e })
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	c := new(Client)
+	for _, qtype := range []uint16{TypeA, TypeMX} {
+		m := new(Msg)
+		m.SetQuestion("miek.nl.", qtype)
+		if _, _, err := c.Exchange(m, addrstr); err != nil {
+			t.Fatalf("failed to exchange: %v", err)
+		}
+		var e QueryLogEntry
+		select {
+		case e = <-entries:
+		case <-time.After(5 * time.Second):
+			t.Fatal("query not logged")
+		}
+		if e.Id != m.Id || e.Qname != "miek.nl." || e.Qtype != qtype || e.Qclass != ClassINET || e.Net != "udp" {
+			t.Errorf("unexpected query in log entry %+v", e)
+		}
+		if e.Client == nil || e.Time.IsZero() || e.Duration <= 0 || e.TsigStatus != nil || e.Size == 0 {
+			t.Errorf("unexpected log entry %+v", e)
+		}
+		if qtype == TypeA && (e.Rcode != RcodeSuccess || e.Answers != 2) {
+			t.Errorf("expected NOERROR with 2 answers, got %+v", e)
+		}
+		if qtype == TypeMX && (e.Rcode != RcodeRefused || e.Answers != 0) {
+			t.Errorf("expected REFUSED without answers, got %+v", e)
+		}
+	}
+}
+
+func TestServerQueryLogSample(t *testing.T) {
+	HandleFunc("miek.nl.", HelloServer)
+	defer HandleRemove("miek.nl.")
+
+	entries := make(chan QueryLogEntry, 10)
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.QueryLog = QueryLogFunc(func(e *QueryLogEntry) { entries <- *e })
+		srv.QueryLogSample = 3
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	c := new(Client)
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	for i := 0; i < 6; i++ {
+		if _, _, err := c.Exchange(m, addrstr); err != nil {
+			t.Fatalf("failed to exchange: %v", err)
+		}
+	}
+	// Queries are logged after their response is written, give the last one time.
+	time.Sleep(50 * time.Millisecond)
+	if len(entries) != 2 {
+		t.Errorf("expected 2 of 6 queries to be logged, got %d", len(entries))
+	}
+}
@@ -0,0 +1,296 @@
+package dns
+
+import (
+	"crypto"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The defaults of a Resigner.
+const (
+	DefaultSignatureValidity = 30 * 24 * time.Hour
+	DefaultSignatureRefresh  = 7 * 24 * time.Hour
+)
+
+// ResignChanges lists the RRSIGs replaced by a Resigner.
+type ResignChanges struct {
+	Add    []*RRSIG // New signatures.
+	Remove []*RRSIG // Signatures replaced by the new ones.
+}
+
+// Empty returns true if no signatures were replaced.
+func (c *ResignChanges) Empty() bool { return len(c.Add) == 0 && len(c.Remove) == 0 }
+
+// A Resigner keeps the signatures of a signed zone fresh. Feed it the RRsets
+// of the zone and their RRSIGs with Add; an RRset is re-signed with Key when
+// its signature expires within Refresh, minus a random Jitter so that not all
+// of the zone is signed again at once. The changes made are returned by
+// Resign, or given to the function passed to Run.
+//
+// The NS RRsets of delegations and the glue below them aren't authoritative
+// and are never signed (RFC 4035, Section 2.2), the zone is the one of Key.
+//
+// A Resigner signs with a single key, use one per key of the zone.
+type Resigner struct {
+	Key      *DNSKEY
+	Signer   crypto.Signer // private key of Key
+	Validity time.Duration // lifetime of the new signatures, defaults to DefaultSignatureValidity
+	Refresh  time.Duration // re-sign when the signature expires within Refresh, defaults to DefaultSignatureRefresh
+	Jitter   time.Duration // maximum random time to re-sign earlier, defaults to none
+	Skew     time.Duration // time the inception is set back for clocks that lag, defaults to 1 hour
+
+	mu   sync.Mutex
+	sets map[resignKey]*resignSet
+	wake chan struct{} // signals Run that the sets changed
+}
+
+type resignKey struct {
+	name   string // lower cased
+	rrtype uint16
+	class  uint16
+}
+
+type resignSet struct {
+	rrset []RR
+	sig   *RRSIG    // signature made with Key, nil when there is none yet
+	due   time.Time // when to re-sign
+}
+
+// Add adds the RRsets in rrs, replacing the ones of the same name, type and
+// class. The RRSIGs in rrs made with Key are taken as the current signatures,
+// an RRset without one is signed by the next call to Resign. Other RRSIGs are
+// ignored.
+func (r *Resigner) Add(rrs []RR) {
+	var sigs []*RRSIG
+	sets := make(map[resignKey][]RR)
+	for _, rr := range rrs {
+		h := rr.Header()
+		if sig, ok := rr.(*RRSIG); ok {
+			if r.signedWithKey(sig) {
+				sigs = append(sigs, sig)
+			}
+			continue
+		}
+		k := resignKey{strings.ToLower(h.Name), h.Rrtype, h.Class}
+		sets[k] = append(sets[k], rr)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sets == nil {
+		r.sets = make(map[resignKey]*resignSet)
+	}
+	for k, rrset := range sets {
+		r.sets[k] = &resignSet{rrset: rrset, due: time.Unix(0, 0)} // due at once until a signature is seen
+	}
+	for _, sig := range sigs {
+		s := r.sets[resignKey{strings.ToLower(sig.Hdr.Name), sig.TypeCovered, sig.Hdr.Class}]
+		if s == nil {
+			continue
+		}
+		// Keep the signature that lasts the longest.
+		if s.sig == nil || serialLess(s.sig.Expiration, sig.Expiration) {
+			s.sig = sig
+			s.due = r.dueTime(sig)
+		}
+	}
+	r.notify()
+}
+
+// Remove removes the RRset of name and rrtype, of any class.
+func (r *Resigner) Remove(name string, rrtype uint16) {
+	name = strings.ToLower(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k := range r.sets {
+		if k.name == name && k.rrtype == rrtype {
+			delete(r.sets, k)
+		}
+	}
+	r.notify()
+}
+
+// Signatures returns the current signatures of the RRsets.
+func (r *Resigner) Signatures() []*RRSIG {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sigs []*RRSIG
+	for _, s := range r.sets {
+		if s.sig != nil {
+			sigs = append(sigs, s.sig)
+		}
+	}
+	return sigs
+}
+
+// Next returns the time the next RRset is due to be re-signed, or the zero
+// time if there are no RRsets.
+func (r *Resigner) Next() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var next time.Time
+	for k, s := range r.sets {
+		if r.delegated(k) {
+			continue
+		}
+		if next.IsZero() || s.due.Before(next) {
+			next = s.due
+		}
+	}
+	return next
+}
+
+// Resign signs the RRsets that are due at now again, and returns the
+// signatures it replaced. On error, the signatures made so far are returned
+// with it, and the RRset that failed stays due.
+func (r *Resigner) Resign(now time.Time) (*ResignChanges, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []resignKey
+	for k, s := range r.sets {
+		if !s.due.After(now) && !r.delegated(k) {
+			due = append(due, k)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if c := canonicalNameCompare(due[i].name, due[j].name); c != 0 {
+			return c < 0
+		}
+		return due[i].rrtype < due[j].rrtype
+	})
+
+	c := new(ResignChanges)
+	for _, k := range due {
+		s := r.sets[k]
+		sig, err := r.sign(s.rrset, now)
+		if err != nil {
+			return c, err
+		}
+		if s.sig != nil {
+			c.Remove = append(c.Remove, s.sig)
+		}
+		c.Add = append(c.Add, sig)
+		s.sig = sig
+		s.due = r.dueTime(sig)
+	}
+	return c, nil
+}
+
+// Run calls Resign each time RRsets are due, and f with its results when
+// anything changed or it failed, until done is closed. After a failure,
+// Resign is called again a minute later.
+func (r *Resigner) Run(done <-chan struct{}, f func(*ResignChanges, error)) {
+	r.mu.Lock()
+	if r.wake == nil {
+		r.wake = make(chan struct{}, 1)
+	}
+	wake := r.wake
+	r.mu.Unlock()
+
+	var retry time.Time
+	for {
+		next := r.Next()
+		if !retry.IsZero() && retry.After(next) {
+			next = retry
+		}
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			fire = timer.C
+		}
+		select {
+		case <-done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-wake:
+			if timer != nil {
+				timer.Stop()
+			}
+			continue
+		case <-fire:
+		}
+		c, err := r.Resign(time.Now())
+		retry = time.Time{}
+		if err != nil {
+			retry = time.Now().Add(time.Minute)
+		}
+		if err != nil || !c.Empty() {
+			f(c, err)
+		}
+	}
+}
+
+// sign returns a new signature of rrset.
+func (r *Resigner) sign(rrset []RR, now time.Time) (*RRSIG, error) {
+	validity := r.Validity
+	if validity == 0 {
+		validity = DefaultSignatureValidity
+	}
+	skew := r.Skew
+	if skew == 0 {
+		skew = time.Hour
+	}
+	sig := &RRSIG{
+		Hdr:        RR_Header{Ttl: rrset[0].Header().Ttl},
+		Algorithm:  r.Key.Algorithm,
+		KeyTag:     r.Key.KeyTag(),
+		SignerName: r.Key.Hdr.Name,
+		Inception:  uint32(now.Add(-skew).Unix()),
+		Expiration: uint32(now.Add(validity).Unix()),
+	}
+	if err := sig.Sign(r.Signer, rrset); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+// dueTime returns when to re-sign the RRset signed with sig.
+func (r *Resigner) dueTime(sig *RRSIG) time.Time {
+	refresh := r.Refresh
+	if refresh == 0 {
+		refresh = DefaultSignatureRefresh
+	}
+	due := time.Unix(int64(sig.Expiration), 0).Add(-refresh)
+	if r.Jitter > 0 {
+		due = due.Add(-time.Duration(rand.Int63n(int64(r.Jitter))))
+	}
+	return due
+}
+
+// delegated reports whether the RRset of k is the NS RRset of a delegation, or
+// is at or below one, except for the DS and NSEC RRsets of the delegation,
+// which are signed. r.mu must be held.
+func (r *Resigner) delegated(k resignKey) bool {
+	apex := strings.ToLower(r.Key.Hdr.Name)
+	for off, end := 0, false; !end; off, end = NextLabel(k.name, off) {
+		name := k.name[off:]
+		if name == apex {
+			return false
+		}
+		if _, ok := r.sets[resignKey{name, TypeNS, k.class}]; ok {
+			return off > 0 || k.rrtype != TypeDS && k.rrtype != TypeNSEC
+		}
+	}
+	return false
+}
+
+// signedWithKey reports whether sig was made with the key of r.
+func (r *Resigner) signedWithKey(sig *RRSIG) bool {
+	return sig.Algorithm == r.Key.Algorithm && sig.KeyTag == r.Key.KeyTag() &&
+		strings.EqualFold(sig.SignerName, r.Key.Hdr.Name)
+}
+
+// notify wakes Run up, r.mu must be held.
+func (r *Resigner) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,114 @@
+package dns
+
+import (
+	"crypto"
+	"testing"
+	"time"
+)
+
+func newResigner(t *testing.T) *Resigner {
+	key := &DNSKEY{
+		Hdr:       RR_Header{Name: "example.", Rrtype: TypeDNSKEY, Class: ClassINET, Ttl: 3600},
+		Flags:     256,
+		Protocol:  3,
+		Algorithm: ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Resigner{Key: key, Signer: priv.(crypto.Signer)}
+}
+
+func TestResigner(t *testing.T) {
+	r := newResigner(t)
+	now := time.Now()
+
+	a := testRR("www.example. 3600 IN A 192.0.2.1")
+	mx := testRR("example. 3600 IN MX 10 mail.example.")
+	txt := testRR("example. 3600 IN TXT \"hello\"")
+	soon, _ := r.sign([]RR{a}, now.Add(-29*24*time.Hour)) // expires in a day
+	later, _ := r.sign([]RR{mx}, now)                     // expires in 30 days
+	other := &RRSIG{Hdr: RR_Header{Name: "example."}, TypeCovered: TypeTXT, Algorithm: RSASHA256, KeyTag: 1, SignerName: "example."}
+
+	r.Add([]RR{a, soon, mx, later, txt, other})
+	c, err := r.Resign(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Add) != 2 || len(c.Remove) != 1 || c.Remove[0] != soon {
+		t.Fatalf("expected the A and TXT RRsets to be signed, got %v", c)
+	}
+	// Sorted in canonical order.
+	if c.Add[0].TypeCovered != TypeTXT || c.Add[1].TypeCovered != TypeA {
+		t.Errorf("expected TXT, then A signatures, got %v", c.Add)
+	}
+	if err := c.Add[1].Verify(r.Key, []RR{a}); err != nil {
+		t.Errorf("failed to verify the new signature: %v", err)
+	}
+	if !c.Add[1].ValidityPeriod(now) {
+		t.Errorf("expected the new signature to be valid now")
+	}
+	if len(r.Signatures()) != 3 {
+		t.Errorf("expected 3 signatures, got %d", len(r.Signatures()))
+	}
+
+	next := r.Next()
+	if want := now.Add(DefaultSignatureValidity - DefaultSignatureRefresh); next.Before(want.Add(-time.Second)) || next.After(want) {
+		t.Errorf("expected the next re-signing at %v, got %v", want, next)
+	}
+	if c, _ := r.Resign(now.Add(time.Hour)); !c.Empty() {
+		t.Errorf("expected no changes, got %v", c)
+	}
+
+	r.Remove("EXAMPLE.", TypeMX)
+	r.Remove("example.", TypeTXT)
+	r.Remove("www.example.", TypeA)
+	if !r.Next().IsZero() {
+		t.Errorf("expected no RRsets left")
+	}
+}
+
+func TestResignerDelegation(t *testing.T) {
+	r := newResigner(t)
+	r.Add([]RR{
+		testRR("example. 3600 IN NS ns.example."),
+		testRR("sub.example. 3600 IN NS ns.sub.example."),
+		testRR("sub.example. 3600 IN DS 42 13 2 76C8E2C8F7F8B8A8E3FF3F0B8C2D8AF96B1C8C43E0A1D1B4BB14C4B5AF6F1B6C"),
+		testRR("ns.sub.example. 3600 IN A 192.0.2.53"),
+	})
+	c, err := r.Resign(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Add) != 2 || c.Add[0].TypeCovered != TypeNS || c.Add[0].Hdr.Name != "example." || c.Add[1].TypeCovered != TypeDS {
+		t.Errorf("expected the apex NS and the DS RRsets signed, got %v", c.Add)
+	}
+	if next := r.Next(); next.Before(time.Now().Add(time.Hour)) {
+		t.Errorf("expected the delegation and the glue never to be due, got %v", next)
+	}
+}
+
+func TestResignerRun(t *testing.T) {
+	r := newResigner(t)
+	changes := make(chan *ResignChanges, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go r.Run(done, func(c *ResignChanges, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		changes <- c
+	})
+
+	a := testRR("www.example. 3600 IN A 192.0.2.1")
+	r.Add([]RR{a})
+	select {
+	case c := <-changes:
+		if len(c.Add) != 1 {
+			t.Errorf("expected a signature, got %v", c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RRset not signed")
+	}
+}
@@ -0,0 +1,361 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// rootHints are the IPv4 addresses of the root name servers a.root-servers.net
+// to m.root-servers.net.
+var rootHints = []string{
+	"198.41.0.4", "170.247.170.2", "192.33.4.12", "199.7.91.13",
+	"192.203.230.10", "192.5.5.241", "192.112.36.4", "198.97.190.53",
+	"192.36.148.17", "192.58.128.30", "193.0.14.129", "199.7.83.42",
+	"202.12.27.33",
+}
+
+// Limits on a single resolution.
+const (
+	resolverMaxQueries  = 64 // default of Resolver.MaxQueries
+	resolverMaxCNAMEs   = 16 // length of a CNAME chain
+	resolverMaxDepth    = 4  // nested resolutions of the names of name servers without glue
+	resolverMaxMinimize = 10 // minimized queries in a zone before the full name is sent (RFC 9156, Section 2.3)
+)
+
+var (
+	errResolverLoop       = &Error{err: "resolution loop"}
+	errResolverMaxQueries = &Error{err: "too many queries for resolution"}
+	errResolverNoServers  = &Error{err: "no name server answered"}
+	errResolverLame       = &Error{err: "lame delegation"}
+)
+
+// A Resolver resolves names iteratively, as a recursive name server does: it
+// starts at the root name servers, follows their referrals down to the
+// authoritative name servers of a name, and chases CNAMEs. Name servers are
+// queried over IPv4, and over TCP when a UDP response is truncated. The
+// records name servers send for names outside of their zone are ignored, see
+// Sanitize.
+//
+// The zero Resolver is ready for use, it is safe for concurrent use.
+type Resolver struct {
+	Client      *Client           // client used to send queries, a zero Client is used when nil
+	RootServers []string          // addresses of the root name servers in host:port form, defaults to the root hints
+	MaxQueries  int               // maximum number of queries sent for a single resolution, defaults to 64
+	Validator   ResolverValidator // if set, the responses are validated with it, and queries set the DO bit
+
+	// QNAMEMinimization makes the Resolver send each name server only the
+	// labels of the name it needs to find the next referral, one label at a
+	// time, with queries of type A (RFC 9156). When name servers don't handle
+	// these queries, the full name is sent.
+	QNAMEMinimization bool
+
+	port string // port of name servers, for testing
+}
+
+// A ResolverValidator validates the responses a Resolver gets, e.g. by
+// checking their DNSSEC signatures.
+type ResolverValidator interface {
+	// Validate is called with every response the Resolver uses, answers and
+	// referrals, and the zone of the name server that sent it. A non-nil
+	// error aborts the resolution.
+	Validate(zone string, m *Msg) error
+}
+
+// resolution is the state of a single call to Resolve.
+type resolution struct {
+	queries int // queries left
+}
+
+// Resolve resolves the records of type qtype of name, and returns them in a
+// response as a recursive name server would send it: the Answer section has
+// the CNAME chain followed, if any, and the records; the Ns section and the
+// rcode come from the response of the last name in the chain.
+func (r *Resolver) Resolve(ctx context.Context, name string, qtype uint16) (*Msg, error) {
+	if !IsFqdn(name) {
+		return nil, ErrFqdn
+	}
+	st := &resolution{queries: r.maxQueries()}
+	answer, in, err := r.resolve(ctx, st, name, qtype, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	m := new(Msg)
+	m.SetQuestion(name, qtype)
+	m.Response = true
+	m.RecursionAvailable = true
+	m.Rcode = in.Rcode
+	m.Answer = answer
+	m.Ns = in.Ns
+	return m, nil
+}
+
+func (r *Resolver) maxQueries() int {
+	if r.MaxQueries > 0 {
+		return r.MaxQueries
+	}
+	return resolverMaxQueries
+}
+
+func (r *Resolver) rootServers() []string {
+	if len(r.RootServers) > 0 {
+		return r.RootServers
+	}
+	servers := make([]string, len(rootHints))
+	for i, ip := range rootHints {
+		servers[i] = r.addr(ip)
+	}
+	return servers
+}
+
+func (r *Resolver) addr(ip string) string {
+	port := r.port
+	if port == "" {
+		port = "53"
+	}
+	return net.JoinHostPort(ip, port)
+}
+
+// resolve resolves qname and follows the CNAME chain. It returns the chain and
+// the records at its end, and the last response.
+func (r *Resolver) resolve(ctx context.Context, st *resolution, qname string, qtype uint16, depth int) ([]RR, *Msg, error) {
+	var chain []RR
+	seen := map[string]bool{strings.ToLower(qname): true}
+	for {
+		in, err := r.lookup(ctx, st, qname, qtype, depth)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Follow the CNAME chain as far as the response goes.
+		target := qname
+		for qtype != TypeCNAME {
+			var next string
+			for _, rr := range in.Answer {
+				if c, ok := rr.(*CNAME); ok && strings.EqualFold(c.Hdr.Name, target) {
+					chain = append(chain, c)
+					next = c.Target
+					break
+				}
+			}
+			if next == "" {
+				break
+			}
+			if seen[strings.ToLower(next)] || len(seen) > resolverMaxCNAMEs {
+				return nil, nil, errResolverLoop
+			}
+			seen[strings.ToLower(next)] = true
+			target = next
+		}
+
+		var answer []RR
+		for _, rr := range in.Answer {
+			h := rr.Header()
+			if strings.EqualFold(h.Name, target) && (h.Rrtype == qtype || qtype == TypeANY) {
+				answer = append(answer, rr)
+			}
+		}
+		if len(answer) > 0 || strings.EqualFold(target, qname) || in.Rcode != RcodeSuccess {
+			return append(chain, answer...), in, nil
+		}
+		// The response ends with a CNAME to a name outside of its zone.
+		qname = target
+	}
+}
+
+// lookup queries qname, starting at the root name servers and following the
+// referrals, and returns the first response that isn't a referral, with the
+// records outside of the zone of its name server removed.
+func (r *Resolver) lookup(ctx context.Context, st *resolution, qname string, qtype uint16, depth int) (*Msg, error) {
+	zone := "."
+	servers := r.rootServers()
+	minimize := r.QNAMEMinimization
+	labels, steps := 1, 0 // labels of the minimized name, and queries for it in zone
+	for {
+		qn, qt := qname, qtype
+		if minimize && labels < CountLabel(qname) && steps < resolverMaxMinimize {
+			idx := Split(qname)
+			qn, qt = qname[idx[len(idx)-labels]:], TypeA
+		}
+
+		in, err := r.query(ctx, st, servers, qn, qt)
+		if err != nil {
+			if qn != qname && err != errResolverMaxQueries && ctx.Err() == nil {
+				minimize = false // broken server, retry with the full name
+				continue
+			}
+			return nil, err
+		}
+		// Only records in zone are used, others may be forged by the server
+		// (they are out of bailiwick): a CNAME target outside of zone is
+		// resolved from the root again.
+		Sanitize(in, zone)
+		if r.Validator != nil {
+			if err := r.Validator.Validate(zone, in); err != nil {
+				return nil, err
+			}
+		}
+
+		child, ns := referral(in, zone, qname)
+		if qn != qname && child == "" {
+			switch in.Rcode {
+			case RcodeSuccess:
+				// qn is in zone, or an empty non-terminal, look one
+				// label further.
+				labels++
+				steps++
+			default:
+				// NXDOMAIN may be sent for empty non-terminals by
+				// broken servers (RFC 9156, Section 2.3).
+				minimize = false
+			}
+			continue
+		}
+		if in.Rcode != RcodeSuccess || len(in.Answer) > 0 || in.Authoritative {
+			return in, nil
+		}
+		if child == "" {
+			for _, rr := range in.Ns {
+				if rr.Header().Rrtype == TypeSOA {
+					return in, nil // no data
+				}
+			}
+			return nil, errResolverLame
+		}
+
+		servers = r.nameServers(ctx, st, zone, child, ns, in.Extra, depth)
+		if len(servers) == 0 {
+			return nil, errResolverNoServers
+		}
+		zone = child
+		labels, steps = CountLabel(zone)+1, 0
+	}
+}
+
+// referral returns the zone in, from a name server of zone, delegates qname
+// to, and the names of its name servers. The zone must be below zone, so
+// referrals always make progress.
+func referral(in *Msg, zone, qname string) (string, []string) {
+	var child string
+	var ns []string
+	for _, rr := range in.Ns {
+		n, ok := rr.(*NS)
+		if !ok {
+			continue
+		}
+		owner := n.Hdr.Name
+		if child == "" {
+			if !IsSubDomain(zone, owner) || CountLabel(owner) <= CountLabel(zone) || !IsSubDomain(owner, qname) {
+				continue
+			}
+			child = owner
+		}
+		if strings.EqualFold(owner, child) {
+			ns = append(ns, n.Ns)
+		}
+	}
+	return child, ns
+}
+
+// nameServers returns the addresses of the name servers ns of child, that was
+// delegated to by zone. It uses the glue in extra that is within zone, and
+// resolves the names of the name servers otherwise.
+func (r *Resolver) nameServers(ctx context.Context, st *resolution, zone, child string, ns []string, extra []RR, depth int) []string {
+	var servers []string
+	for _, n := range ns {
+		if !IsSubDomain(zone, n) {
+			continue
+		}
+		for _, rr := range extra {
+			if a, ok := rr.(*A); ok && strings.EqualFold(a.Hdr.Name, n) {
+				servers = append(servers, r.addr(a.A.String()))
+			}
+		}
+	}
+	if len(servers) > 0 || depth >= resolverMaxDepth {
+		return servers
+	}
+
+	for _, n := range ns {
+		if IsSubDomain(child, n) {
+			continue // can't be resolved without glue
+		}
+		answer, _, err := r.resolve(ctx, st, n, TypeA, depth+1)
+		if err != nil {
+			continue
+		}
+		for _, rr := range answer {
+			if a, ok := rr.(*A); ok {
+				servers = append(servers, r.addr(a.A.String()))
+			}
+		}
+		if len(servers) > 0 {
+			break
+		}
+	}
+	return servers
+}
+
+// query sends a query for qname to servers in turn, until one of them sends a
+// usable response.
+func (r *Resolver) query(ctx context.Context, st *resolution, servers []string, qname string, qtype uint16) (*Msg, error) {
+	m := new(Msg)
+	m.SetQuestion(qname, qtype)
+	m.RecursionDesired = false
+	m.SetEdns0(4096, r.Validator != nil)
+
+	var err error = errResolverNoServers
+	for _, a := range servers {
+		if st.queries <= 0 {
+			return nil, errResolverMaxQueries
+		}
+		st.queries--
+
+		in, qerr := r.exchange(ctx, m, a)
+		if qerr != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			err = qerr
+			continue
+		}
+		switch in.Rcode {
+		case RcodeServerFailure, RcodeRefused, RcodeNotImplemented:
+			continue
+		}
+		return in, nil
+	}
+	return nil, err
+}
+
+// exchange sends m to the name server at a within the deadline of ctx, and
+// retries over TCP when the response is truncated.
+func (r *Resolver) exchange(ctx context.Context, m *Msg, a string) (*Msg, error) {
+	c := r.Client
+	if c == nil {
+		c = new(Client)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var at attempt
+	if deadline, ok := ctx.Deadline(); ok {
+		at.timeout = time.Until(deadline)
+		if at.timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		if t := c.getTimeoutForRequest(c.readTimeout()); t < at.timeout {
+			at.timeout = t
+		}
+	}
+
+	in, _, err := c.exchangeShared(m, a, at)
+	if err == nil && in.Truncated && !strings.HasPrefix(c.network(at), "tcp") {
+		at.net = "tcp"
+		in, _, err = c.exchangeShared(m, a, at)
+	}
+	return in, err
+}
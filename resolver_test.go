@@ -0,0 +1,228 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// authServer returns a handler that answers authoritatively for zone from rrs,
+// and refers queries below the delegations in rrs.
+func authServer(zone string, rrs ...string) HandlerFunc {
+	var data []RR
+	for _, s := range rrs {
+		rr, err := NewRR(s)
+		if err != nil {
+			panic(err)
+		}
+		data = append(data, rr)
+	}
+	soa, _ := NewRR(zone + " 3600 IN SOA ns. hostmaster. 1 3600 600 86400 60")
+
+	return func(w ResponseWriter, req *Msg) {
+		m := new(Msg)
+		m.SetReply(req)
+		q := req.Question[0]
+
+		// Referral to a delegation between zone and the query name.
+		for _, rr := range data {
+			h := rr.Header()
+			if h.Rrtype == TypeNS && !strings.EqualFold(h.Name, zone) && IsSubDomain(h.Name, q.Name) {
+				for _, ns := range data {
+					if ns.Header().Rrtype == TypeNS && strings.EqualFold(ns.Header().Name, h.Name) {
+						m.Ns = append(m.Ns, ns)
+						for _, glue := range data {
+							if glue.Header().Rrtype == TypeA && strings.EqualFold(glue.Header().Name, ns.(*NS).Ns) {
+								m.Extra = append(m.Extra, glue)
+							}
+						}
+					}
+				}
+				w.WriteMsg(m)
+				return
+			}
+		}
+
+		m.Authoritative = true
+		exists := false
+		for _, rr := range data {
+			h := rr.Header()
+			exists = exists || IsSubDomain(q.Name, h.Name) // empty non-terminals exist too
+			if !strings.EqualFold(h.Name, q.Name) {
+				continue
+			}
+			if h.Rrtype == q.Qtype || h.Rrtype == TypeCNAME {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+		if len(m.Answer) == 0 {
+			m.Ns = []RR{soa}
+			if !exists {
+				m.Rcode = RcodeNameError
+			}
+		}
+		w.WriteMsg(m)
+	}
+}
+
+// runResolverServers runs a root, the example. zone with glue, and the other.
+// zone whose name server is only in example.; all on their own loopback
+// address. It returns the address of the root, and a channel that receives the
+// queries the servers get.
+func runResolverServers(t *testing.T) (string, chan string, func()) {
+	queries := make(chan string, 100)
+	logged := func(name string, h HandlerFunc) HandlerFunc {
+		return func(w ResponseWriter, req *Msg) {
+			queries <- name + " " + req.Question[0].Name
+			h(w, req)
+		}
+	}
+	root := authServer(".",
+		"example. NS ns1.example.",
+		"ns1.example. A 127.0.0.2",
+		"other. NS ns.example.",
+	)
+	exampleAuth := authServer("example.",
+		"ns1.example. A 127.0.0.2",
+		"ns.example. A 127.0.0.3",
+		"www.example. CNAME host.other.",
+		"loop1.example. CNAME loop2.example.",
+		"loop2.example. CNAME loop1.example.",
+		"a.example. CNAME b.example.",
+		"b.example. A 192.0.2.2",
+		"host.deep.sub.example. A 192.0.2.3",
+	)
+	otherAuth := authServer("other.",
+		"host.other. A 192.0.2.1",
+		"a.b.other. A 192.0.2.4",
+	)
+	// The example. server tries to poison the cache with an address for a
+	// name in other.
+	example := func(w ResponseWriter, req *Msg) {
+		if req.Question[0].Name == "poison.example." {
+			m := new(Msg)
+			m.SetReply(req)
+			m.Authoritative = true
+			m.Answer = []RR{
+				testRR("poison.example. 3600 IN CNAME host.other."),
+				testRR("host.other. 3600 IN A 192.0.2.66"),
+			}
+			w.WriteMsg(m)
+			return
+		}
+		exampleAuth(w, req)
+	}
+	// A broken server, that doesn't know about empty non-terminals.
+	other := func(w ResponseWriter, req *Msg) {
+		if req.Question[0].Name == "b.other." {
+			m := new(Msg)
+			m.SetRcode(req, RcodeNameError)
+			w.WriteMsg(m)
+			return
+		}
+		otherAuth(w, req)
+	}
+
+	var servers []*Server
+	shutdown := func() {
+		for _, s := range servers {
+			s.Shutdown()
+		}
+	}
+	s, addr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", func(srv *Server) { srv.Handler = logged("root", root) })
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	servers = append(servers, s)
+	_, port, _ := net.SplitHostPort(addr)
+	for ip, h := range map[string]HandlerFunc{"127.0.0.2": logged("example", example), "127.0.0.3": logged("other", other)} {
+		s, _, _, err := RunLocalUDPServerWithFinChan(net.JoinHostPort(ip, port), func(srv *Server) { srv.Handler = h })
+		if err != nil {
+			shutdown()
+			t.Skipf("unable to run test server on %s: %v", ip, err)
+		}
+		servers = append(servers, s)
+	}
+	return addr, queries, shutdown
+}
+
+func TestResolver(t *testing.T) {
+	addr, _, shutdown := runResolverServers(t)
+	defer shutdown()
+	_, port, _ := net.SplitHostPort(addr)
+
+	res := &Resolver{RootServers: []string{addr}, port: port}
+	ctx := context.Background()
+
+	m, err := res.Resolve(ctx, "www.example.", TypeA)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+	if len(m.Answer) != 2 || m.Answer[0].Header().Rrtype != TypeCNAME || m.Answer[1].(*A).A.String() != "192.0.2.1" {
+		t.Errorf("expected CNAME and A, got %v", m.Answer)
+	}
+
+	m, err = res.Resolve(ctx, "a.example.", TypeA)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+	if len(m.Answer) != 2 || m.Answer[1].(*A).A.String() != "192.0.2.2" {
+		t.Errorf("expected in-zone CNAME and A, got %v", m.Answer)
+	}
+
+	// Records out of the zone of the server are ignored, the CNAME target is
+	// resolved.
+	m, err = res.Resolve(ctx, "poison.example.", TypeA)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+	if len(m.Answer) != 2 || m.Answer[1].(*A).A.String() != "192.0.2.1" {
+		t.Errorf("expected CNAME and A from other., got %v", m.Answer)
+	}
+
+	m, err = res.Resolve(ctx, "nx.other.", TypeA)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+	if m.Rcode != RcodeNameError || len(m.Ns) != 1 {
+		t.Errorf("expected NXDOMAIN, got %v", m)
+	}
+
+	if _, err = res.Resolve(ctx, "loop1.example.", TypeA); err != errResolverLoop {
+		t.Errorf("expected CNAME loop, got %v", err)
+	}
+}
+
+func TestResolverQNAMEMinimization(t *testing.T) {
+	addr, queries, shutdown := runResolverServers(t)
+	defer shutdown()
+	_, port, _ := net.SplitHostPort(addr)
+
+	res := &Resolver{RootServers: []string{addr}, QNAMEMinimization: true, port: port}
+	m, err := res.Resolve(context.Background(), "host.deep.sub.example.", TypeA)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+	if len(m.Answer) != 1 {
+		t.Fatalf("expected an answer, got %v", m)
+	}
+	var got []string
+	for len(queries) > 0 {
+		got = append(got, <-queries)
+	}
+	expect := []string{"root example.", "example sub.example.", "example deep.sub.example.", "example host.deep.sub.example."}
+	if strings.Join(got, ", ") != strings.Join(expect, ", ") {
+		t.Errorf("expected queries %v, got %v", expect, got)
+	}
+
+	// Falls back to the full name when the server says the empty
+	// non-terminal b.other. doesn't exist.
+	m, err = res.Resolve(context.Background(), "a.b.other.", TypeA)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+	if len(m.Answer) != 1 || m.Rcode != RcodeSuccess {
+		t.Errorf("expected an answer, got %v", m)
+	}
+}
@@ -0,0 +1,83 @@
+package dns
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// RetryCondition is a set of conditions on which a RetryPolicy retries a query.
+type RetryCondition uint
+
+// Conditions on which queries are retried.
+const (
+	RetryOnTimeout  RetryCondition = 1 << iota // no response arrived in time
+	RetryOnNetError                            // other network errors, e.g. the connection was refused
+	RetryOnServFail                            // the response has rcode SERVFAIL
+	RetryOnRefused                             // the response has rcode REFUSED
+)
+
+// A RetryPolicy tells a Client how to retry a failed query. Every try of the
+// query is sent to the same address.
+type RetryPolicy struct {
+	Attempts       int            // total number of tries, including the first one, retries are disabled when less than 2
+	PerTryTimeout  time.Duration  // when non-zero, the dial, write and read timeout of each try instead of the Client's
+	Backoff        time.Duration  // wait before the first retry, doubled for each next one
+	MaxBackoff     time.Duration  // maximum wait between tries, unlimited when 0
+	RetryOn        RetryCondition // the conditions to retry on, defaults to RetryOnTimeout when 0
+	TCPOnTruncated bool           // if true, a truncated UDP response is retried over TCP right away, this doesn't count as a try
+}
+
+// exchange performs the query m with c, and retries it as p says. The tries
+// are made as at, with the timeout of p.
+func (p *RetryPolicy) exchange(c *Client, m *Msg, address string, at attempt) (r *Msg, rtt time.Duration, err error) {
+	at.timeout = p.PerTryTimeout
+	backoff := p.Backoff
+	for i := 0; ; i++ {
+		r, rtt, err = c.exchangeShared(m, address, at)
+		if err == nil && r.Truncated && p.TCPOnTruncated && !strings.HasPrefix(c.network(at), "tcp") {
+			tcp := at
+			tcp.net = "tcp" + strings.TrimPrefix(c.network(at), "udp") // keep udp4 and udp6 their address family
+			r, rtt, err = c.exchangeShared(m, address, tcp)
+		}
+
+		if i+1 >= p.Attempts || !p.retry(r, err) {
+			return r, rtt, err
+		}
+
+		if backoff > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+				backoff = p.MaxBackoff
+			}
+		}
+	}
+}
+
+// retry reports whether the result of a try matches the conditions of p.
+func (p *RetryPolicy) retry(r *Msg, err error) bool {
+	on := p.RetryOn
+	if on == 0 {
+		on = RetryOnTimeout
+	}
+
+	if err != nil {
+		nerr, ok := err.(net.Error)
+		switch {
+		case ok && nerr.Timeout():
+			return on&RetryOnTimeout != 0
+		case ok:
+			return on&RetryOnNetError != 0
+		}
+		return false
+	}
+
+	switch r.Rcode {
+	case RcodeServerFailure:
+		return on&RetryOnServFail != 0
+	case RcodeRefused:
+		return on&RetryOnRefused != 0
+	}
+	return false
+}
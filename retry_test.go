@@ -0,0 +1,124 @@
+package dns
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var queries int32
+	HandleFunc("retry.example.", func(w ResponseWriter, req *Msg) {
+		// Fail the first two queries.
+		if atomic.AddInt32(&queries, 1) <= 2 {
+			m := new(Msg)
+			m.SetRcode(req, RcodeServerFailure)
+			w.WriteMsg(m)
+			return
+		}
+		HelloServer(w, req)
+	})
+	defer HandleRemove("retry.example.")
+
+	s, addrstr, err := RunLocalUDPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	m := new(Msg)
+	m.SetQuestion("retry.example.", TypeTXT)
+
+	c := &Client{Retry: &RetryPolicy{Attempts: 2, RetryOn: RetryOnServFail}}
+	r, _, err := c.Exchange(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.Rcode != RcodeServerFailure || atomic.LoadInt32(&queries) != 2 {
+		t.Fatalf("expected SERVFAIL after 2 tries, got %s after %d", RcodeToString[r.Rcode], queries)
+	}
+
+	atomic.StoreInt32(&queries, 0)
+	c.Retry.Attempts = 3
+	r, _, err = c.Exchange(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.Rcode != RcodeSuccess || atomic.LoadInt32(&queries) != 3 {
+		t.Fatalf("expected NOERROR after 3 tries, got %s after %d", RcodeToString[r.Rcode], queries)
+	}
+
+	// SERVFAIL is not retried by default.
+	atomic.StoreInt32(&queries, 0)
+	c.Retry.RetryOn = 0
+	if r, _, err = c.Exchange(m, addrstr); err != nil || r.Rcode != RcodeServerFailure || atomic.LoadInt32(&queries) != 1 {
+		t.Fatalf("expected SERVFAIL after 1 try, got %v, %v after %d", r, err, queries)
+	}
+}
+
+func TestRetryPolicyTimeout(t *testing.T) {
+	// Nothing answers on this socket.
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer l.Close()
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	c := &Client{Retry: &RetryPolicy{Attempts: 3, PerTryTimeout: 50 * time.Millisecond}}
+	_, _, err = c.Exchange(m, l.LocalAddr().String())
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+
+	buf := make([]byte, MinMsgSize)
+	tries := 0
+	for {
+		l.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+		if _, _, err := l.ReadFrom(buf); err != nil {
+			break
+		}
+		tries++
+	}
+	if tries != 3 {
+		t.Errorf("expected 3 tries, got %d", tries)
+	}
+}
+
+func TestRetryPolicyTCPOnTruncated(t *testing.T) {
+	HandleFunc("tc.example.", func(w ResponseWriter, req *Msg) {
+		if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+			m := new(Msg)
+			m.SetReply(req)
+			m.Truncated = true
+			w.WriteMsg(m)
+			return
+		}
+		HelloServer(w, req)
+	})
+	defer HandleRemove("tc.example.")
+
+	s, addrstr, err := RunLocalUDPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+	st, _, err := RunLocalTCPServer(addrstr)
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer st.Shutdown()
+
+	m := new(Msg)
+	m.SetQuestion("tc.example.", TypeTXT)
+	c := &Client{Retry: &RetryPolicy{TCPOnTruncated: true}}
+	r, _, err := c.Exchange(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.Truncated || len(r.Extra) != 1 {
+		t.Errorf("expected full response over TCP, got %v", r)
+	}
+}
@@ -0,0 +1,46 @@
+package dns
+
+import (
+	"encoding/hex"
+	"strconv"
+)
+
+// ToRR returns the record rr holds as a record of the type of its header, with
+// its rdata unpacked, e.g. once the type is supported by this package or
+// registered with PrivateHandle. The rdata must not have compressed names.
+func (rr *RFC3597) ToRR() (RR, error) {
+	if _, ok := typeToUnpack[rr.Hdr.Rrtype]; !ok {
+		return nil, &Error{err: "unknown RR type " + strconv.Itoa(int(rr.Hdr.Rrtype))}
+	}
+	rdata, err := hex.DecodeString(rr.Rdata)
+	if err != nil {
+		return nil, err
+	}
+	h := rr.Hdr
+	h.Rdlength = uint16(len(rdata))
+	r, off, err := UnpackRRWithHeader(h, rdata, 0)
+	if err != nil {
+		return nil, err
+	}
+	if off != len(rdata) {
+		return nil, ErrRdata
+	}
+	return r, nil
+}
+
+// ToRFC3597 returns rr in the generic representation of RFC 3597, with its
+// rdata packed without compression.
+func ToRFC3597(rr RR) (*RFC3597, error) {
+	buf := make([]byte, Len(rr)+1)
+	off, err := PackRR(rr, buf, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	_, rdata, _, err := unpackHeader(buf[:off], 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	h := *rr.Header()
+	h.Rdlength = uint16(off - rdata)
+	return &RFC3597{Hdr: h, Rdata: hex.EncodeToString(buf[rdata:off])}, nil
+}
@@ -0,0 +1,40 @@
+package dns
+
+import "testing"
+
+func TestRFC3597RoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"example. 3600 IN MX 10 mail.example.",
+		"example. 3600 IN TXT \"hello\" \"world\"",
+		"example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 300",
+		"example. 3600 IN AAAA 2001:db8::1",
+	} {
+		rr := testRR(s)
+		generic, err := ToRFC3597(rr)
+		if err != nil {
+			t.Errorf("%s: failed to convert: %v", s, err)
+			continue
+		}
+		if generic.Hdr.Rrtype != rr.Header().Rrtype || int(generic.Hdr.Rdlength) != len(generic.Rdata)/2 {
+			t.Errorf("%s: unexpected generic record %v", s, generic)
+		}
+		back, err := generic.ToRR()
+		if err != nil {
+			t.Errorf("%s: failed to convert back: %v", s, err)
+			continue
+		}
+		if back.String() != rr.String() {
+			t.Errorf("expected %s, got %s", rr, back)
+		}
+	}
+
+	rr := testRR("example. 3600 IN TYPE65280 \\# 4 0A000001").(*RFC3597)
+	if _, err := rr.ToRR(); err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+	rr = &RFC3597{Hdr: RR_Header{Name: "example.", Rrtype: TypeA, Class: ClassINET, Ttl: 3600}, Rdata: "c0000201"}
+	a, err := rr.ToRR()
+	if err != nil || a.(*A).A.String() != "192.0.2.1" {
+		t.Errorf("expected the A record of 192.0.2.1, got %v, %v", a, err)
+	}
+}
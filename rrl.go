@@ -0,0 +1,203 @@
+package dns
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RRL implements Response Rate Limiting for UDP responses, as done by
+// authoritative servers to make them useless in amplification attacks.
+// Responses are accounted per client network prefix, query name, query type
+// and kind of response (answer, NXDOMAIN or error) in a token bucket. When a
+// bucket is empty the response is dropped, except for every Slip'th dropped
+// response which is sent truncated, so legitimate clients retry over TCP.
+// TCP responses are never limited.
+//
+// An RRL is installed with RRLMiddleware. The zero value is not usable, the
+// ResponsesPerSecond must be set.
+type RRL struct {
+	ResponsesPerSecond int // maximum rate of responses per bucket, also the burst size
+	Slip               int // send every Slip'th dropped response truncated, 0 never does, defaults to 2 with NewRRL
+	IPv4PrefixLength   int // prefix length that groups IPv4 clients, defaults to 24 when 0
+	IPv6PrefixLength   int // prefix length that groups IPv6 clients, defaults to 56 when 0
+
+	mu        sync.Mutex
+	buckets   map[rrlKey]*rrlBucket
+	lastSweep time.Time
+	now       func() time.Time // for testing
+}
+
+// NewRRL returns an RRL that allows responsesPerSecond responses per second and
+// per bucket, and slips every second dropped response.
+func NewRRL(responsesPerSecond int) *RRL {
+	return &RRL{ResponsesPerSecond: responsesPerSecond, Slip: 2}
+}
+
+// The action RRL takes on a response.
+const (
+	rrlSend = iota
+	rrlDrop
+	rrlSlip
+)
+
+type rrlKey struct {
+	prefix string
+	qname  string
+	qtype  uint16
+	kind   int // rcode, or -1 for NOERROR responses with answers
+}
+
+type rrlBucket struct {
+	tokens  float64
+	updated time.Time
+	dropped int
+}
+
+// rrlSweepInterval is the interval at which buckets that are full, and thus
+// the same as new ones, are removed.
+const rrlSweepInterval = 10 * time.Second
+
+// account debits the bucket of response m to the client at ip, and returns
+// the action to take.
+func (rrl *RRL) account(ip net.IP, m *Msg) int {
+	if rrl.ResponsesPerSecond <= 0 {
+		return rrlSend
+	}
+
+	key := rrlKey{prefix: rrl.prefix(ip), kind: m.Rcode}
+	if len(m.Question) > 0 {
+		key.qname, key.qtype = strings.ToLower(m.Question[0].Name), m.Question[0].Qtype
+	}
+	if m.Rcode == RcodeSuccess && len(m.Answer) > 0 {
+		key.kind = -1
+	}
+
+	now := time.Now()
+	if rrl.now != nil {
+		now = rrl.now()
+	}
+	rate := float64(rrl.ResponsesPerSecond)
+
+	rrl.mu.Lock()
+	defer rrl.mu.Unlock()
+
+	if rrl.buckets == nil {
+		rrl.buckets = make(map[rrlKey]*rrlBucket)
+		rrl.lastSweep = now
+	}
+	if now.Sub(rrl.lastSweep) >= rrlSweepInterval {
+		for k, b := range rrl.buckets {
+			if b.tokens+now.Sub(b.updated).Seconds()*rate >= rate {
+				delete(rrl.buckets, k)
+			}
+		}
+		rrl.lastSweep = now
+	}
+
+	b, ok := rrl.buckets[key]
+	if !ok {
+		b = &rrlBucket{tokens: rate, updated: now}
+		rrl.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.updated).Seconds() * rate
+	if b.tokens > rate {
+		b.tokens = rate
+	}
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.dropped = 0
+		return rrlSend
+	}
+	b.dropped++
+	if rrl.Slip > 0 && b.dropped%rrl.Slip == 0 {
+		return rrlSlip
+	}
+	return rrlDrop
+}
+
+// prefix returns the network prefix of ip, as configured in rrl.
+func (rrl *RRL) prefix(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		l := rrl.IPv4PrefixLength
+		if l == 0 {
+			l = 24
+		}
+		return ip4.Mask(net.CIDRMask(l, 32)).String()
+	}
+	l := rrl.IPv6PrefixLength
+	if l == 0 {
+		l = 56
+	}
+	return ip.Mask(net.CIDRMask(l, 128)).String()
+}
+
+// RRLMiddleware returns a Middleware that rate limits the UDP responses of the
+// handler it wraps with rrl.
+func RRLMiddleware(rrl *RRL) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Msg) {
+			addr, ok := w.RemoteAddr().(*net.UDPAddr)
+			if !ok {
+				next.ServeDNS(w, r)
+				return
+			}
+			next.ServeDNS(&rrlWriter{ResponseWriter: w, rrl: rrl, ip: addr.IP}, r)
+		})
+	}
+}
+
+type rrlWriter struct {
+	ResponseWriter
+	rrl *RRL
+	ip  net.IP
+}
+
+// WriteMsg writes m, a truncated version of m, or nothing, depending on the
+// rate of responses.
+func (w *rrlWriter) WriteMsg(m *Msg) error {
+	switch w.rrl.account(w.ip, m) {
+	case rrlDrop:
+		return nil
+	case rrlSlip:
+		return w.ResponseWriter.WriteMsg(rrlTruncate(m))
+	}
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// Write writes the raw response m, a truncated version of it, or nothing,
+// depending on the rate of responses.
+func (w *rrlWriter) Write(m []byte) (int, error) {
+	msg := new(Msg)
+	if err := msg.Unpack(m); err != nil {
+		return w.ResponseWriter.Write(m)
+	}
+	switch w.rrl.account(w.ip, msg) {
+	case rrlDrop:
+		return len(m), nil
+	case rrlSlip:
+		b, err := rrlTruncate(msg).Pack()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := w.ResponseWriter.Write(b); err != nil {
+			return 0, err
+		}
+		return len(m), nil
+	}
+	return w.ResponseWriter.Write(m)
+}
+
+// rrlTruncate returns m with only its header, question and OPT record, and the
+// TC bit set.
+func rrlTruncate(m *Msg) *Msg {
+	t := &Msg{MsgHdr: m.MsgHdr, Compress: m.Compress, CompressPolicy: m.CompressPolicy, Question: m.Question}
+	t.Truncated = true
+	if opt := m.IsEdns0(); opt != nil {
+		t.Extra = []RR{opt}
+	}
+	return t
+}
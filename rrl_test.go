@@ -0,0 +1,75 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// addrWriter is a recordWriter with a configurable remote address.
+type addrWriter struct {
+	recordWriter
+	addr net.Addr
+}
+
+func (w *addrWriter) RemoteAddr() net.Addr { return w.addr }
+
+func TestRRL(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	rrl := NewRRL(2)
+	rrl.now = func() time.Time { return now }
+	h := Chain(HandlerFunc(HelloServer), RRLMiddleware(rrl))
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	query := func(addr net.Addr) *addrWriter {
+		w := &addrWriter{addr: addr}
+		h.ServeDNS(w, m)
+		return w
+	}
+	client := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5353}
+	neighbour := &net.UDPAddr{IP: net.ParseIP("192.0.2.200"), Port: 5353}
+	other := &net.UDPAddr{IP: net.ParseIP("198.51.100.1"), Port: 5353}
+
+	// sent, sent, dropped, slipped, dropped
+	expect := []int{rrlSend, rrlSend, rrlDrop, rrlSlip, rrlDrop}
+	for i, e := range expect {
+		addr := net.Addr(client)
+		if i%2 == 1 {
+			addr = neighbour // same /24, same bucket
+		}
+		w := query(addr)
+		switch e {
+		case rrlSend:
+			if len(w.msgs) != 1 || w.msgs[0].Truncated || len(w.msgs[0].Extra) == 0 {
+				t.Errorf("query %d: expected full response, got %v", i, w.msgs)
+			}
+		case rrlDrop:
+			if len(w.msgs) != 0 {
+				t.Errorf("query %d: expected response to be dropped, got %v", i, w.msgs)
+			}
+		case rrlSlip:
+			if len(w.msgs) != 1 || !w.msgs[0].Truncated || len(w.msgs[0].Extra) != 0 || len(w.msgs[0].Question) != 1 {
+				t.Errorf("query %d: expected truncated response, got %v", i, w.msgs)
+			}
+		}
+	}
+
+	if w := query(other); len(w.msgs) != 1 {
+		t.Error("expected response to a client in another network")
+	}
+	if w := query(&net.TCPAddr{IP: client.IP, Port: 5353}); len(w.msgs) != 1 {
+		t.Error("expected TCP responses not to be limited")
+	}
+
+	now = now.Add(time.Second)
+	if w := query(client); len(w.msgs) != 1 || w.msgs[0].Truncated {
+		t.Error("expected bucket to be refilled")
+	}
+
+	now = now.Add(rrlSweepInterval)
+	query(other)
+	if len(rrl.buckets) != 1 {
+		t.Errorf("expected idle buckets to be removed, got %d buckets", len(rrl.buckets))
+	}
+}
@@ -0,0 +1,160 @@
+package dns
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// An RRset is a set of RRs of the same owner name, type and class. The
+// methods of RRset don't check that; IsRRset does.
+type RRset []RR
+
+// rrsetKey identifies an RRset, the name is lower cased.
+type rrsetKey struct {
+	name   string
+	rrtype uint16
+	class  uint16
+}
+
+// SplitRRsets groups rrs into RRsets, in the order of the first RR of each.
+// Owner names are compared without regard to case. RRSIGs are grouped with
+// the other RRSIGs of their owner name, not with the RRset they cover.
+func SplitRRsets(rrs []RR) []RRset {
+	var sets []RRset
+	index := make(map[rrsetKey]int)
+	for _, rr := range rrs {
+		h := rr.Header()
+		k := rrsetKey{strings.ToLower(h.Name), h.Rrtype, h.Class}
+		i, ok := index[k]
+		if !ok {
+			i = len(sets)
+			index[k] = i
+			sets = append(sets, nil)
+		}
+		sets[i] = append(sets[i], rr)
+	}
+	return sets
+}
+
+// Canonical returns copies of the RRs of s in the canonical form of RFC 4034,
+// Section 6.2: the owner names, and the domain names in the rdata of the types
+// listed there, lower cased.
+func (s RRset) Canonical() RRset {
+	c := make(RRset, len(s))
+	for i, rr := range s {
+		c[i] = rr.copy()
+		canonicalize(c[i])
+	}
+	return c
+}
+
+// Sort sorts s in the canonical order of RFC 4034, Section 6.3: by the rdata
+// of the RRs in canonical form, compared as unsigned octets. Sort returns an
+// error, and leaves s as it was, if an RR can't be packed.
+func (s RRset) Sort() error {
+	rdata := make([][]byte, len(s))
+	for i, rr := range s {
+		b, err := canonicalRdata(rr)
+		if err != nil {
+			return err
+		}
+		rdata[i] = b
+	}
+	sort.Stable(rrsetSorter{s, rdata})
+	return nil
+}
+
+type rrsetSorter struct {
+	rrs   RRset
+	rdata [][]byte
+}
+
+func (p rrsetSorter) Len() int           { return len(p.rrs) }
+func (p rrsetSorter) Less(i, j int) bool { return bytes.Compare(p.rdata[i], p.rdata[j]) < 0 }
+func (p rrsetSorter) Swap(i, j int) {
+	p.rrs[i], p.rrs[j] = p.rrs[j], p.rrs[i]
+	p.rdata[i], p.rdata[j] = p.rdata[j], p.rdata[i]
+}
+
+// NormalizeTTL sets the TTLs of the RRs of s to the lowest of them, as RFC
+// 2181, Section 5.2 requires of an RRset, and returns it.
+func (s RRset) NormalizeTTL() uint32 {
+	if len(s) == 0 {
+		return 0
+	}
+	ttl := s[0].Header().Ttl
+	for _, rr := range s[1:] {
+		if rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+	for _, rr := range s {
+		rr.Header().Ttl = ttl
+	}
+	return ttl
+}
+
+// Dedup removes the RRs of s that are equal to an earlier one in canonical
+// form, ignoring the TTL, and keeps the lowest TTL of the duplicates. The
+// order of s is preserved, and s is modified. RRs that can't be packed are
+// kept.
+func (s RRset) Dedup() RRset {
+	seen := make(map[string]RR, len(s))
+	j := 0
+	for _, rr := range s {
+		b, err := canonicalWire(rr)
+		if err == nil {
+			if rr1, ok := seen[string(b)]; ok {
+				if rr.Header().Ttl < rr1.Header().Ttl {
+					rr1.Header().Ttl = rr.Header().Ttl
+				}
+				continue
+			}
+			seen[string(b)] = rr
+		}
+		s[j] = rr
+		j++
+	}
+	return s[:j]
+}
+
+// CompareRdata compares the rdata of a and b in canonical form, as the
+// canonical order of RFC 4034, Section 6.3 does, and returns -1, 0 or 1.
+func CompareRdata(a, b RR) (int, error) {
+	ra, err := canonicalRdata(a)
+	if err != nil {
+		return 0, err
+	}
+	rb, err := canonicalRdata(b)
+	if err != nil {
+		return 0, err
+	}
+	return bytes.Compare(ra, rb), nil
+}
+
+// canonicalWire returns rr in canonical form in wire format, with a zero TTL.
+func canonicalWire(rr RR) ([]byte, error) {
+	rr = rr.copy()
+	canonicalize(rr)
+	rr.Header().Ttl = 0
+	buf := make([]byte, Len(rr)+1)
+	off, err := PackRR(rr, buf, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:off], nil
+}
+
+// canonicalRdata returns the rdata of rr in canonical form in wire format.
+func canonicalRdata(rr RR) ([]byte, error) {
+	buf, err := canonicalWire(rr)
+	if err != nil {
+		return nil, err
+	}
+	_, off, err := UnpackDomainName(buf, 0)
+	if err != nil {
+		return nil, err
+	}
+	return buf[off+10:], nil
+}
@@ -0,0 +1,65 @@
+package dns
+
+import "testing"
+
+func TestSplitRRsets(t *testing.T) {
+	rrs := []RR{
+		testRR("example.org. 3600 IN A 192.0.2.1"),
+		testRR("example.org. 3600 IN MX 10 mx.example.org."),
+		testRR("Example.ORG. 3600 IN A 192.0.2.2"),
+		testRR("www.example.org. 3600 IN A 192.0.2.3"),
+		testRR("example.org. 3600 CH A 192.0.2.4"),
+	}
+	sets := SplitRRsets(rrs)
+	if len(sets) != 4 {
+		t.Fatalf("expected 4 RRsets, got %d", len(sets))
+	}
+	if len(sets[0]) != 2 || sets[0][1] != rrs[2] {
+		t.Errorf("expected the A records of example.org. together, got %v", sets[0])
+	}
+	if len(sets[1]) != 1 || len(sets[2]) != 1 || len(sets[3]) != 1 || sets[3][0] != rrs[4] {
+		t.Errorf("expected the other records apart, got %v", sets[1:])
+	}
+}
+
+func TestRRsetSort(t *testing.T) {
+	s := RRset{
+		testRR("example.org. 3600 IN MX 20 b.example.org."),
+		testRR("example.org. 3600 IN MX 10 B.example.org."),
+		testRR("example.org. 3600 IN MX 10 a.example.org."),
+	}
+	if err := s.Sort(); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"a.example.org.", "B.example.org.", "b.example.org."} {
+		if mx := s[i].(*MX).Mx; mx != want {
+			t.Errorf("%d: expected %s, got %s", i, want, mx)
+		}
+	}
+	if c, err := CompareRdata(s[1], s[0]); err != nil || c != 1 {
+		t.Errorf("expected 1, got %d, %v", c, err)
+	}
+	if c, err := CompareRdata(s[1], testRR("example.org. 1 IN MX 10 b.EXAMPLE.org.")); err != nil || c != 0 {
+		t.Errorf("expected 0, got %d, %v", c, err)
+	}
+
+	c := s.Canonical()
+	if c[1].Header().Name != "example.org." || c[1].(*MX).Mx != "b.example.org." || s[1].(*MX).Mx != "B.example.org." {
+		t.Errorf("expected a lower cased copy, got %v", c[1])
+	}
+}
+
+func TestRRsetDedup(t *testing.T) {
+	s := RRset{
+		testRR("example.org. 3600 IN NS a.example.org."),
+		testRR("example.org. 3600 IN NS b.example.org."),
+		testRR("EXAMPLE.org. 300 IN NS A.example.org."),
+	}
+	s = s.Dedup()
+	if len(s) != 2 || s[0].(*NS).Ns != "a.example.org." || s[0].Header().Ttl != 300 {
+		t.Errorf("expected a.example.org. with TTL 300 and b.example.org., got %v", s)
+	}
+	if ttl := s.NormalizeTTL(); ttl != 300 || s[1].Header().Ttl != 300 {
+		t.Errorf("expected TTLs of 300, got %d and %v", ttl, s)
+	}
+}
@@ -83,3 +83,172 @@ func normalizedString(r RR) string {
 	cut := ttlEnd - ttlStart
 	return string(b[:len(b)-cut])
 }
+
+// Sanitize removes the records of the response m that a resolver shouldn't
+// trust, as a defense against cache poisoning, and returns how many it
+// removed. The query was sent to the servers of the zone zoneCut, only
+// records at or below it are kept (they are in bailiwick). Furthermore:
+//
+//   - answers are kept when they are for the question name, or for the names
+//     the CNAMEs and DNAMEs in the answer lead to, and of the question type,
+//     CNAME, DNAME or RRSIG;
+//   - in the authority section, NS and SOA records are kept when the name in
+//     the answer is at or below their owner name, and NSEC, NSEC3, DS and
+//     RRSIG records are kept;
+//   - in the additional section, A and AAAA records, and their RRSIGs, are
+//     kept when they are for a target of the NS, MX or SRV records kept, and
+//     the first OPT, TSIG and SIG(0) records are kept.
+//
+// Records of another class than the question's are removed. A response
+// without a single question has its answer and authority sections emptied.
+func Sanitize(m *Msg, zoneCut string) int {
+	n := len(m.Answer) + len(m.Ns) + len(m.Extra)
+	if len(m.Question) != 1 {
+		m.Answer, m.Ns = nil, nil
+		m.Extra = sanitizeExtra(m.Extra, nil, zoneCut, 0)
+		return n - len(m.Extra)
+	}
+	q := m.Question[0]
+	inZone := func(rr RR) bool {
+		h := rr.Header()
+		return h.Class == q.Qclass && IsSubDomain(zoneCut, h.Name)
+	}
+
+	names := sanitizeChain(m.Answer, q, inZone)
+	answer := m.Answer[:0]
+	for _, rr := range m.Answer {
+		h := rr.Header()
+		if !inZone(rr) {
+			continue
+		}
+		switch rrtype := rrSanitizeType(rr); {
+		case rrtype == TypeDNAME:
+			for _, name := range names {
+				if IsSubDomain(h.Name, name) && !equal(h.Name, name) {
+					answer = append(answer, rr)
+					break
+				}
+			}
+		case !sanitizeHasName(names, h.Name):
+		case rrtype == q.Qtype || q.Qtype == TypeANY || rrtype == TypeCNAME:
+			answer = append(answer, rr)
+		}
+	}
+	m.Answer = answer
+
+	last := names[len(names)-1]
+	ns := m.Ns[:0]
+	for _, rr := range m.Ns {
+		if !inZone(rr) {
+			continue
+		}
+		switch rrSanitizeType(rr) {
+		case TypeNS, TypeSOA:
+			if IsSubDomain(rr.Header().Name, last) {
+				ns = append(ns, rr)
+			}
+		case TypeNSEC, TypeNSEC3, TypeDS:
+			ns = append(ns, rr)
+		}
+	}
+	m.Ns = ns
+
+	var targets []string
+	for _, rrs := range [][]RR{m.Answer, m.Ns} {
+		for _, rr := range rrs {
+			switch rr := rr.(type) {
+			case *NS:
+				targets = append(targets, rr.Ns)
+			case *MX:
+				targets = append(targets, rr.Mx)
+			case *SRV:
+				targets = append(targets, rr.Target)
+			}
+		}
+	}
+	m.Extra = sanitizeExtra(m.Extra, targets, zoneCut, q.Qclass)
+	return n - len(m.Answer) - len(m.Ns) - len(m.Extra)
+}
+
+// sanitizeChain returns the question name of q and the names the CNAMEs and
+// DNAMEs in answer lead to, in order.
+func sanitizeChain(answer []RR, q Question, inZone func(RR) bool) []string {
+	names := []string{q.Name}
+	for len(names) <= len(answer) {
+		name := names[len(names)-1]
+		next := ""
+		for _, rr := range answer {
+			if !inZone(rr) {
+				continue
+			}
+			switch rr := rr.(type) {
+			case *CNAME:
+				if q.Qtype != TypeCNAME && equal(rr.Hdr.Name, name) {
+					next = rr.Target
+				}
+			case *DNAME:
+				if IsSubDomain(rr.Hdr.Name, name) && !equal(rr.Hdr.Name, name) {
+					next = name[:len(name)-len(rr.Hdr.Name)] + rr.Target
+				}
+			}
+			if next != "" {
+				break
+			}
+		}
+		if next == "" || sanitizeHasName(names, next) {
+			break
+		}
+		names = append(names, next)
+	}
+	return names
+}
+
+// sanitizeExtra returns the records of extra to keep, see Sanitize.
+func sanitizeExtra(extra []RR, targets []string, zoneCut string, class uint16) []RR {
+	keep := extra[:0]
+	opt := false
+	for _, rr := range extra {
+		h := rr.Header()
+		switch rr.(type) {
+		case *OPT:
+			if !opt {
+				keep = append(keep, rr)
+			}
+			opt = true
+			continue
+		case *TSIG:
+			keep = append(keep, rr)
+			continue
+		case *SIG:
+			if h.Name == "." {
+				keep = append(keep, rr)
+			}
+			continue
+		}
+		if h.Class != class || !IsSubDomain(zoneCut, h.Name) || !sanitizeHasName(targets, h.Name) {
+			continue
+		}
+		switch rrSanitizeType(rr) {
+		case TypeA, TypeAAAA:
+			keep = append(keep, rr)
+		}
+	}
+	return keep
+}
+
+// rrSanitizeType returns the type of rr, or the type it covers for an RRSIG.
+func rrSanitizeType(rr RR) uint16 {
+	if sig, ok := rr.(*RRSIG); ok {
+		return sig.TypeCovered
+	}
+	return rr.Header().Rrtype
+}
+
+func sanitizeHasName(names []string, name string) bool {
+	for _, n := range names {
+		if equal(n, name) {
+			return true
+		}
+	}
+	return false
+}
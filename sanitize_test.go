@@ -73,3 +73,58 @@ func TestNormalizedString(t *testing.T) {
 		}
 	}
 }
+
+func TestSanitize(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("www.example.org.", TypeA)
+	m.Answer = []RR{
+		testRR("www.example.org. 300 IN CNAME web.example.org."),
+		testRR("web.example.org. 300 IN A 192.0.2.1"),
+		testRR("web.example.org. 300 IN RRSIG A 8 3 300 20300101000000 20200101000000 1 example.org. AAAA"),
+		testRR("web.example.org. 300 IN MX 10 mx.example.org."), // other type
+		testRR("www.example.com. 300 IN A 192.0.2.2"),           // out of bailiwick
+		testRR("other.example.org. 300 IN A 192.0.2.3"),         // unsolicited
+		testRR("web.example.org. 300 CH A 192.0.2.4"),           // other class
+	}
+	m.Ns = []RR{
+		testRR("example.org. 300 IN NS ns.example.org."),
+		testRR("example.org. 300 IN NS ns.example.com."),
+		testRR("org. 300 IN NS ns.org."),                         // out of bailiwick
+		testRR("sub.example.org. 300 IN NS ns.sub.example.org."), // not above the answer
+	}
+	m.Extra = []RR{
+		testRR("ns.example.org. 300 IN A 192.0.2.53"),
+		testRR("ns.example.com. 300 IN A 192.0.2.54"), // out of bailiwick
+		testRR("mx.example.org. 300 IN A 192.0.2.25"), // not a target
+		testRR("ns.example.org. 300 IN TXT \"glue\""), // not an address
+	}
+	m.SetEdns0(4096, false)
+	m.SetEdns0(512, false)
+
+	if n := Sanitize(m, "example.org."); n != 10 {
+		t.Errorf("expected 10 records removed, got %d", n)
+	}
+	if len(m.Answer) != 3 || len(m.Ns) != 2 || len(m.Extra) != 2 {
+		t.Fatalf("expected 3 answers, 2 authority and 2 additional records, got %v", m)
+	}
+	if m.Extra[0].(*A).A.String() != "192.0.2.53" || m.Extra[1].(*OPT).UDPSize() != 4096 {
+		t.Errorf("expected the glue and the first OPT, got %v", m.Extra)
+	}
+}
+
+func TestSanitizeDNAME(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("www.example.org.", TypeA)
+	m.Answer = []RR{
+		testRR("example.org. 300 IN DNAME example.net."),
+		testRR("www.example.org. 300 IN CNAME www.example.net."),
+		testRR("www.example.net. 300 IN A 192.0.2.1"),
+	}
+	if n := Sanitize(m, "org."); n != 1 || len(m.Answer) != 2 {
+		t.Errorf("expected the record in example.net. removed, got %d, %v", n, m.Answer)
+	}
+	m.Answer = append(m.Answer, testRR("www.example.net. 300 IN A 192.0.2.1"))
+	if n := Sanitize(m, "."); n != 0 || len(m.Answer) != 3 {
+		t.Errorf("expected all records kept, got %d, %v", n, m.Answer)
+	}
+}
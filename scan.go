@@ -12,8 +12,8 @@ import (
 
 const maxTok = 2048 // Largest token we can return.
 
-// The maximum depth of $INCLUDE directives supported by the
-// ZoneParser API.
+// The default maximum depth of $INCLUDE directives supported by the
+// ZoneParser API, see ZoneParser.SetMaxIncludeDepth.
 const maxIncludeDepth = 7
 
 // Tokinize a RFC 1035 zone file. The tokenizer will normalize it:
@@ -78,6 +78,13 @@ func (e *ParseError) Error() (s string) {
 	return
 }
 
+// Position returns the file, line and column of the token that caused the
+// error. Lines and columns start at 1, the column is the one of the first
+// character of the token.
+func (e *ParseError) Position() (file string, line, column int) {
+	return e.file, e.lex.line, e.lex.column
+}
+
 type lex struct {
 	token   string // text of the token
 	err     bool   // when true, token text has lexer error
@@ -242,13 +249,18 @@ type ZoneParser struct {
 	// Next, by calling subNext, forwards the resulting RRs from this
 	// sub parser to the calling code.
 	sub    *ZoneParser
-	osFile *os.File
+	osFile io.Closer // the file sub reads from, if it is an $INCLUDE
+
+	include func(name string) (io.ReadCloser, error) // opens $INCLUDE files, os.Open if nil
 
 	com string
 
 	includeDepth uint8
+	includeLimit int // maximum includeDepth, maxIncludeDepth if zero and none if negative
 
 	includeAllowed bool
+
+	idna bool // convert Unicode names to A-labels
 }
 
 // NewZoneParser returns an RFC 1035 style zonefile parser that reads
@@ -294,6 +306,43 @@ func (zp *ZoneParser) SetIncludeAllowed(v bool) {
 	zp.includeAllowed = v
 }
 
+// SetIncludeFunc sets the function that opens the files of $INCLUDE
+// directives, instead of os.Open. It is called with the name of the file,
+// relative to the directory of the file that has the directive when it isn't
+// absolute, as os.Open would be. Setting it allows $INCLUDE directives, they
+// can only read what open returns.
+func (zp *ZoneParser) SetIncludeFunc(open func(name string) (io.ReadCloser, error)) {
+	zp.include = open
+	zp.includeAllowed = open != nil
+}
+
+// SetMaxIncludeDepth sets the maximum depth of nested $INCLUDE directives,
+// it defaults to 7. With a depth of zero, $INCLUDE directives are errors even
+// when they are allowed.
+func (zp *ZoneParser) SetMaxIncludeDepth(depth int) {
+	if depth <= 0 {
+		depth = -1
+	}
+	zp.includeLimit = depth
+}
+
+func (zp *ZoneParser) includeMax() int {
+	switch {
+	case zp.includeLimit < 0:
+		return 0
+	case zp.includeLimit > 0:
+		return zp.includeLimit
+	}
+	return maxIncludeDepth
+}
+
+// SetIDNA sets whether Unicode names, owner names and domain names in the
+// rdata, are accepted and converted to A-labels with IDNAToASCII. They aren't
+// by default, and are then kept as UTF-8 octets.
+func (zp *ZoneParser) SetIDNA(v bool) {
+	zp.idna = v
+}
+
 // Err returns the first non-EOF error that was encountered by the
 // ZoneParser.
 func (zp *ZoneParser) Err() error {
@@ -465,7 +514,7 @@ func (zp *ZoneParser) Next() (RR, bool) {
 			if !zp.includeAllowed {
 				return zp.setParseError("$INCLUDE directive not allowed", l)
 			}
-			if zp.includeDepth >= maxIncludeDepth {
+			if int(zp.includeDepth) >= zp.includeMax() {
 				return zp.setParseError("too deeply nested $INCLUDE", l)
 			}
 
@@ -475,7 +524,13 @@ func (zp *ZoneParser) Next() (RR, bool) {
 				includePath = filepath.Join(filepath.Dir(zp.file), includePath)
 			}
 
-			r1, e1 := os.Open(includePath)
+			var r1 io.ReadCloser
+			var e1 error
+			if zp.include != nil {
+				r1, e1 = zp.include(includePath)
+			} else {
+				r1, e1 = os.Open(includePath)
+			}
 			if e1 != nil {
 				var as string
 				if !filepath.IsAbs(l.token) {
@@ -488,6 +543,7 @@ func (zp *ZoneParser) Next() (RR, bool) {
 
 			zp.sub = NewZoneParser(r1, neworigin, includePath)
 			zp.sub.defttl, zp.sub.includeDepth, zp.sub.osFile = zp.defttl, zp.includeDepth+1, r1
+			zp.sub.includeLimit, zp.sub.include, zp.sub.idna = zp.includeLimit, zp.include, zp.idna
 			zp.sub.SetIncludeAllowed(true)
 			return zp.subNext()
 		case zExpectDirTTLBl:
@@ -660,6 +716,12 @@ func (zp *ZoneParser) Next() (RR, bool) {
 				return nil, false
 			}
 
+			if zp.idna {
+				if err := idnaRR(r); err != nil {
+					return zp.setParseError(err.(*Error).err, l)
+				}
+			}
+
 			zp.com = c1
 			return r, true
 		}
@@ -773,7 +835,10 @@ func (zl *zlexer) Next() (lex, bool) {
 	}
 
 	for x, ok := zl.readByte(); ok; x, ok = zl.readByte() {
-		l.line, l.column = zl.line, zl.column
+		if stri == 0 {
+			// Tokens are at the position of their first character.
+			l.line, l.column = zl.line, zl.column
+		}
 		l.comment = ""
 
 		if stri >= len(str) {
@@ -874,6 +939,7 @@ func (zl *zlexer) Next() (lex, bool) {
 			if !zl.space {
 				zl.space = true
 
+				l.line, l.column = zl.line, zl.column
 				l.value = zBlank
 				l.token = " "
 
@@ -975,6 +1041,7 @@ func (zl *zlexer) Next() (lex, bool) {
 					retL = *l
 				}
 
+				l.line, l.column = zl.line, zl.column
 				l.value = zNewline
 				l.token = "\n"
 				l.comment = zl.com
@@ -1039,6 +1106,7 @@ func (zl *zlexer) Next() (lex, bool) {
 			}
 
 			// send quote itself as separate token
+			l.line, l.column = zl.line, zl.column
 			l.value = zQuote
 			l.token = "\""
 
@@ -1148,14 +1216,14 @@ func classToInt(token string) (uint16, bool) {
 	return uint16(class), true
 }
 
-// Extract the rr number from TYPExxx
+// Extract the rr number from TYPExxx, type 0 is reserved
 func typeToInt(token string) (uint16, bool) {
 	offset := 4
-	if len(token) < offset+1 {
+	if len(token) < offset+1 || !strings.EqualFold(token[:offset], "TYPE") {
 		return 0, false
 	}
 	typ, err := strconv.ParseUint(token[offset:], 10, 16)
-	if err != nil {
+	if err != nil || typ == 0 {
 		return 0, false
 	}
 	return uint16(typ), true
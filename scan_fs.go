@@ -0,0 +1,22 @@
+// +build go1.16
+
+package dns
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SetIncludeFS makes $INCLUDE directives read their files from fsys, instead
+// of the file system of the operating system. The names of the files are
+// resolved as with SetIncludeFunc, and then made relative to the root of fsys.
+// Files outside of fsys, like ../zone.db, can't be included.
+func (zp *ZoneParser) SetIncludeFS(fsys fs.FS) {
+	zp.SetIncludeFunc(func(name string) (io.ReadCloser, error) {
+		name = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+		return fsys.Open(name)
+	})
+}
@@ -0,0 +1,42 @@
+// +build go1.16
+
+package dns
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestZoneParserIncludeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zones/example.org.db": {Data: []byte("$INCLUDE hosts.db\nwww\tIN\tA\t127.0.0.1\n")},
+		"zones/hosts.db":       {Data: []byte("$INCLUDE /records/mail.db\nhost\tIN\tA\t127.0.0.2\n")},
+		"records/mail.db":      {Data: []byte("mail\tIN\tA\t127.0.0.3\n")},
+	}
+	f, _ := fsys.Open("zones/example.org.db")
+	defer f.Close()
+
+	zp := NewZoneParser(f, "example.org.", "zones/example.org.db")
+	zp.SetIncludeFS(fsys)
+	var names []string
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		names = append(names, rr.Header().Name)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatalf("failed to parse the zone: %v", err)
+	}
+	if got := strings.Join(names, " "); got != "mail.example.org. host.example.org. www.example.org." {
+		t.Errorf("unexpected records: %s", got)
+	}
+
+	for _, include := range []string{"../../etc/passwd", "/etc/passwd"} {
+		zp := NewZoneParser(strings.NewReader("$INCLUDE "+include+"\n"), "example.org.", "zones/example.org.db")
+		zp.SetIncludeFS(fsys)
+		for _, ok := zp.Next(); ok; _, ok = zp.Next() {
+		}
+		if err := zp.Err(); err == nil || !strings.Contains(err.Error(), "failed to open") {
+			t.Errorf("expected %s to fail to open, got %v", include, err)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+//+build ignore
+
+// scan_generate.go is meant to run with go generate. It will use go/{parser,ast}
+// to track down all the RR struct types. Then for each type whose rdata can be
+// read token by token based on the struct tags, it will generate the zone file
+// parse function (setX). Types with a more involved presentation format have a
+// handwritten set function, e.g. in scan_rr.go, and are skipped. The generated
+// source is written to zscan.go, and is meant to be checked into git.
+//
+// The package is parsed rather than imported: the export data of a compiled
+// package doesn't have the unexported set functions.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var packageHdr = `
+// Code generated by "go run scan_generate.go"; DO NOT EDIT.
+
+package dns
+
+`
+
+// errorNames holds the names used in the parse errors of the fields, and of
+// the types, whose error message predates the generator.
+var errorNames = map[string]string{
+	"KX.Preference":     "Pref",
+	"L32.Locator32":     "Locator",
+	"MX.Preference":     "Pref",
+	"NSAPPTR":           "NSAP-PTR",
+	"SSHFP.FingerPrint": "Fingerprint",
+}
+
+// errorName returns the name of the type, or of the field "type.field", in
+// the parse errors.
+func errorName(name string) string {
+	if n, ok := errorNames[name]; ok {
+		return n
+	}
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// pkgDecls holds the declarations of the package needed to generate the parse
+// functions.
+type pkgDecls struct {
+	structs map[string]*ast.StructType // struct types by name
+	consts  map[string]bool            // names of the constants
+	funcs   map[string]bool            // names of the functions, not the methods
+}
+
+// parsePackage parses the Go files of the package in dir, except the tests and
+// the output of this generator.
+func parsePackage(dir, output string) *pkgDecls {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != output
+	}, 0)
+	fatalIfErr(err)
+	pkg, ok := pkgs["dns"]
+	if !ok {
+		log.Fatal("package dns not found")
+	}
+
+	d := &pkgDecls{structs: make(map[string]*ast.StructType), consts: make(map[string]bool), funcs: make(map[string]bool)}
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					d.funcs[decl.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if st, ok := spec.Type.(*ast.StructType); ok {
+							d.structs[spec.Name.Name] = st
+						}
+					case *ast.ValueSpec:
+						if decl.Tok == token.CONST {
+							for _, n := range spec.Names {
+								d.consts[n.Name] = true
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return d
+}
+
+// getTypeStruct returns the (innermost) struct of the type name if it is
+// considered a RR type: a struct beginning with a RR_Header, or embedding
+// one. The bool return value indicates if embedded structs were resolved.
+func (d *pkgDecls) getTypeStruct(name string) (*ast.StructType, bool) {
+	st, ok := d.structs[name]
+	if !ok || len(st.Fields.List) == 0 {
+		return nil, false
+	}
+	f := st.Fields.List[0]
+	id, ok := f.Type.(*ast.Ident)
+	if !ok {
+		return nil, false
+	}
+	if id.Name == "RR_Header" && len(f.Names) == 1 {
+		return st, false
+	}
+	if len(f.Names) == 0 {
+		st, _ := d.getTypeStruct(id.Name)
+		return st, true
+	}
+	return nil, false
+}
+
+// The kinds of rdata fields that can be parsed from the struct tags.
+const (
+	fieldUint   = iota // decimal number
+	fieldName          // domain name, relative to the origin
+	fieldIP            // IPv4 or IPv6 address
+	fieldString        // hex or base64, the remainder of the rdata
+	fieldTxt           // character-strings, the remainder of the rdata
+)
+
+type field struct {
+	name string
+	kind int
+	typ  string // Go type, for fieldUint
+}
+
+// structField is a field of a struct with its tag.
+type structField struct {
+	name string
+	typ  ast.Expr
+	tag  string
+}
+
+// structFields returns the fields of st, one for each name.
+func structFields(st *ast.StructType) []structField {
+	var fields []structField
+	for _, f := range st.Fields.List {
+		tag := ""
+		if f.Tag != nil {
+			tag, _ = strconv.Unquote(f.Tag.Value)
+		}
+		if len(f.Names) == 0 {
+			fields = append(fields, structField{typ: f.Type, tag: tag})
+			continue
+		}
+		for _, n := range f.Names {
+			fields = append(fields, structField{name: n.Name, typ: f.Type, tag: tag})
+		}
+	}
+	return fields
+}
+
+// scanFields returns the fields of st when each of them can be parsed from its
+// struct tag. Only the last field may span the remainder of the rdata.
+func scanFields(st *ast.StructType) ([]field, bool) {
+	var fields []field
+	sf := structFields(st)
+	for i := 1; i < len(sf); i++ {
+		f := field{name: sf[i].name}
+		tag := sf[i].tag
+		last := i == len(sf)-1
+
+		switch t := sf[i].typ.(type) {
+		case *ast.Ident:
+			switch {
+			case t.Name != "string" && t.Name != "uint8" && t.Name != "uint16" && t.Name != "uint32":
+				return nil, false
+			case t.Name == "string" && (tag == `dns:"domain-name"` || tag == `dns:"cdomain-name"`):
+				f.kind = fieldName
+			case t.Name == "string" && (tag == `dns:"hex"` || tag == `dns:"base64"`) && last:
+				f.kind = fieldString
+			case t.Name != "string" && tag == "":
+				f.kind, f.typ = fieldUint, t.Name
+			default:
+				return nil, false
+			}
+		case *ast.ArrayType:
+			if t.Len != nil || tag != `dns:"txt"` || !last {
+				return nil, false
+			}
+			f.kind = fieldTxt
+		default:
+			if tag != `dns:"a"` && tag != `dns:"aaaa"` {
+				return nil, false
+			}
+			f.kind = fieldIP
+		}
+		fields = append(fields, f)
+	}
+	return fields, len(fields) > 0
+}
+
+func main() {
+	output := flag.String("o", "zscan.go", "file to write the generated source to")
+	flag.Parse()
+
+	d := parsePackage(".", "zscan.go")
+
+	// Collect actual types (*X) that don't have a handwritten set function.
+	var namedTypes []string
+	for name := range d.structs {
+		if !ast.IsExported(name) {
+			continue
+		}
+		if st, _ := d.getTypeStruct(name); st == nil {
+			continue
+		}
+		if !d.consts["Type"+name] || d.funcs["set"+name] {
+			continue
+		}
+
+		namedTypes = append(namedTypes, name)
+	}
+	sort.Strings(namedTypes)
+
+	b := &bytes.Buffer{}
+	variable := make(map[string]bool)
+	var useNet, useStrconv bool
+
+	for _, name := range namedTypes {
+		st, _ := d.getTypeStruct(name)
+		fields, ok := scanFields(st)
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintf(b, "func set%s(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {\n", name)
+		fmt.Fprintf(b, "rr := new(%s)\nrr.Hdr = h\n\n", name)
+
+		declared := make(map[string]bool)
+		assign := func(v string) string {
+			if declared[v] {
+				return "="
+			}
+			declared[v] = true
+			return ":="
+		}
+		tail := false
+		for i, fd := range fields {
+			errstr := fmt.Sprintf("%q", "bad "+errorName(name)+" "+errorName(name+"."+fd.name))
+
+			switch fd.kind {
+			case fieldString, fieldTxt:
+				ending := "endingToString"
+				if fd.kind == fieldTxt {
+					ending = "endingToTxtSlice"
+				}
+				fmt.Fprintf(b, "s, e1, c1 := %s(c, %s, f)\nif e1 != nil {\nreturn nil, e1, c1\n}\n", ending, errstr)
+				fmt.Fprintf(b, "rr.%s = s\nreturn rr, nil, c1\n", fd.name)
+				tail = true
+				continue
+			}
+
+			if i == 0 {
+				fmt.Fprint(b, "l, _ := c.Next()\nif len(l.token) == 0 { // dynamic update rr.\nreturn rr, nil, \"\"\n}\n\n")
+			} else {
+				fmt.Fprint(b, "\nc.Next()        // zBlank\nl, _ = c.Next() // zString\n")
+			}
+
+			switch fd.kind {
+			case fieldUint:
+				bits := map[string]int{"uint8": 8, "uint16": 16, "uint32": 32}[fd.typ]
+				fmt.Fprintf(b, "i, e %s strconv.ParseUint(l.token, 10, %d)\n", assign("i"), bits)
+				fmt.Fprintf(b, "if e != nil || l.err {\nreturn nil, &ParseError{f, %s, l}, \"\"\n}\n", errstr)
+				fmt.Fprintf(b, "rr.%s = %s(i)\n", fd.name, fd.typ)
+				useStrconv = true
+			case fieldName:
+				fmt.Fprintf(b, "name, nameOk %s toAbsoluteName(l.token, o)\n", assign("name"))
+				fmt.Fprintf(b, "if l.err || !nameOk {\nreturn nil, &ParseError{f, %s, l}, \"\"\n}\n", errstr)
+				fmt.Fprintf(b, "rr.%s = name\n", fd.name)
+			case fieldIP:
+				fmt.Fprintf(b, "rr.%s = net.ParseIP(l.token)\n", fd.name)
+				fmt.Fprintf(b, "if rr.%s == nil || l.err {\nreturn nil, &ParseError{f, %s, l}, \"\"\n}\n", fd.name, errstr)
+				useNet = true
+			}
+		}
+		if !tail {
+			fmt.Fprint(b, "return rr, nil, \"\"\n")
+		}
+		fmt.Fprint(b, "}\n\n")
+		variable[name] = tail
+	}
+
+	fmt.Fprint(b, "var typeToGeneratedParserFunc = map[uint16]parserFunc{\n")
+	for _, name := range namedTypes {
+		if v, ok := variable[name]; ok {
+			fmt.Fprintf(b, "Type%s: {set%s, %t},\n", name, name, v)
+		}
+	}
+	fmt.Fprint(b, "}\n")
+
+	src := &bytes.Buffer{}
+	src.WriteString(packageHdr)
+	switch {
+	case useNet && useStrconv:
+		src.WriteString("import (\n\"net\"\n\"strconv\"\n)\n\n")
+	case useNet:
+		src.WriteString("import \"net\"\n\n")
+	case useStrconv:
+		src.WriteString("import \"strconv\"\n\n")
+	}
+	b.WriteTo(src)
+
+	// gofmt
+	res, err := format.Source(src.Bytes())
+	if err != nil {
+		src.WriteTo(os.Stderr)
+		log.Fatal(err)
+	}
+
+	// write result
+	f, err := os.Create(*output)
+	fatalIfErr(err)
+	defer f.Close()
+	f.Write(res)
+}
+
+func fatalIfErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,7 +1,10 @@
 package dns
 
+//go:generate go run scan_generate.go
+
 import (
 	"encoding/base64"
+	"math"
 	"net"
 	"strconv"
 	"strings"
@@ -25,6 +28,9 @@ type parserFunc struct {
 // an *ParseError: garbage after rdata.
 func setRR(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	parserfunc, ok := typeToparserFunc[h.Rrtype]
+	if !ok {
+		parserfunc, ok = typeToGeneratedParserFunc[h.Rrtype]
+	}
 	if ok {
 		r, e, cm := parserfunc.Func(h, c, o, f)
 		if parserfunc.Variable {
@@ -85,20 +91,8 @@ func endingToTxtSlice(c *zlexer, errstr, f string) ([]string, *ParseError, strin
 		case zString:
 			empty = false
 			if len(l.token) > 255 {
-				// split up tokens that are larger than 255 into 255-chunks
-				sx := []string{}
-				p, i := 0, 255
-				for {
-					if i <= len(l.token) {
-						sx = append(sx, l.token[p:i])
-					} else {
-						sx = append(sx, l.token[p:])
-						break
-
-					}
-					p, i = p+255, i+255
-				}
-				s = append(s, sx...)
+				// split up tokens that are larger than 255 octets into 255-chunks
+				s = append(s, splitTxtToken(l.token)...)
 				break
 			}
 
@@ -125,173 +119,26 @@ func endingToTxtSlice(c *zlexer, errstr, f string) ([]string, *ParseError, strin
 	return s, nil, l.comment
 }
 
-func setA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(A)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	rr.A = net.ParseIP(l.token)
-	if rr.A == nil || l.err {
-		return nil, &ParseError{f, "bad A A", l}, ""
-	}
-	return rr, nil, ""
-}
-
-func setAAAA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(AAAA)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	rr.AAAA = net.ParseIP(l.token)
-	if rr.AAAA == nil || l.err {
-		return nil, &ParseError{f, "bad AAAA AAAA", l}, ""
-	}
-	return rr, nil, ""
-}
-
-func setNS(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(NS)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Ns = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad NS Ns", l}, ""
-	}
-	rr.Ns = name
-	return rr, nil, ""
-}
-
-func setPTR(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(PTR)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Ptr = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad PTR Ptr", l}, ""
-	}
-	rr.Ptr = name
-	return rr, nil, ""
-}
-
-func setNSAPPTR(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(NSAPPTR)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Ptr = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad NSAP-PTR Ptr", l}, ""
-	}
-	rr.Ptr = name
-	return rr, nil, ""
-}
-
-func setRP(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(RP)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Mbox = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	mbox, mboxOk := toAbsoluteName(l.token, o)
-	if l.err || !mboxOk {
-		return nil, &ParseError{f, "bad RP Mbox", l}, ""
-	}
-	rr.Mbox = mbox
-
-	c.Next() // zBlank
-	l, _ = c.Next()
-	rr.Txt = l.token
-
-	txt, txtOk := toAbsoluteName(l.token, o)
-	if l.err || !txtOk {
-		return nil, &ParseError{f, "bad RP Txt", l}, ""
-	}
-	rr.Txt = txt
-
-	return rr, nil, ""
-}
-
-func setMR(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(MR)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Mr = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad MR Mr", l}, ""
-	}
-	rr.Mr = name
-	return rr, nil, ""
-}
-
-func setMB(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(MB)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Mb = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad MB Mb", l}, ""
-	}
-	rr.Mb = name
-	return rr, nil, ""
-}
-
-func setMG(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(MG)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Mg = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad MG Mg", l}, ""
+// splitTxtToken splits the text of a character-string into chunks of at most
+// 255 octets, without cutting its escapes: \DDD and \X are one octet each.
+func splitTxtToken(token string) []string {
+	var sx []string
+	p, n := 0, 0
+	for i := 0; i < len(token); n++ {
+		if n == 255 {
+			sx = append(sx, token[p:i])
+			p, n = i, 0
+		}
+		switch {
+		case token[i] != '\\' || i+1 == len(token):
+			i++
+		case i+3 < len(token) && isDigit(token[i+1]) && isDigit(token[i+2]) && isDigit(token[i+3]):
+			i += 4
+		default:
+			i += 2
+		}
 	}
-	rr.Mg = name
-	return rr, nil, ""
+	return append(sx, token[p:])
 }
 
 func setHINFO(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
@@ -320,154 +167,6 @@ func setHINFO(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, ""
 }
 
-func setMINFO(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(MINFO)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Rmail = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	rmail, rmailOk := toAbsoluteName(l.token, o)
-	if l.err || !rmailOk {
-		return nil, &ParseError{f, "bad MINFO Rmail", l}, ""
-	}
-	rr.Rmail = rmail
-
-	c.Next() // zBlank
-	l, _ = c.Next()
-	rr.Email = l.token
-
-	email, emailOk := toAbsoluteName(l.token, o)
-	if l.err || !emailOk {
-		return nil, &ParseError{f, "bad MINFO Email", l}, ""
-	}
-	rr.Email = email
-
-	return rr, nil, ""
-}
-
-func setMF(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(MF)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Mf = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad MF Mf", l}, ""
-	}
-	rr.Mf = name
-	return rr, nil, ""
-}
-
-func setMD(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(MD)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Md = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad MD Md", l}, ""
-	}
-	rr.Md = name
-	return rr, nil, ""
-}
-
-func setMX(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(MX)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	i, e := strconv.ParseUint(l.token, 10, 16)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad MX Pref", l}, ""
-	}
-	rr.Preference = uint16(i)
-
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	rr.Mx = l.token
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad MX Mx", l}, ""
-	}
-	rr.Mx = name
-
-	return rr, nil, ""
-}
-
-func setRT(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(RT)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	i, e := strconv.ParseUint(l.token, 10, 16)
-	if e != nil {
-		return nil, &ParseError{f, "bad RT Preference", l}, ""
-	}
-	rr.Preference = uint16(i)
-
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	rr.Host = l.token
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad RT Host", l}, ""
-	}
-	rr.Host = name
-
-	return rr, nil, ""
-}
-
-func setAFSDB(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(AFSDB)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	i, e := strconv.ParseUint(l.token, 10, 16)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad AFSDB Subtype", l}, ""
-	}
-	rr.Subtype = uint16(i)
-
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	rr.Hostname = l.token
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad AFSDB Hostname", l}, ""
-	}
-	rr.Hostname = name
-	return rr, nil, ""
-}
-
 func setX25(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	rr := new(X25)
 	rr.Hdr = h
@@ -484,69 +183,6 @@ func setX25(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, ""
 }
 
-func setKX(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(KX)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	i, e := strconv.ParseUint(l.token, 10, 16)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad KX Pref", l}, ""
-	}
-	rr.Preference = uint16(i)
-
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	rr.Exchanger = l.token
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad KX Exchanger", l}, ""
-	}
-	rr.Exchanger = name
-	return rr, nil, ""
-}
-
-func setCNAME(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(CNAME)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Target = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad CNAME Target", l}, ""
-	}
-	rr.Target = name
-	return rr, nil, ""
-}
-
-func setDNAME(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(DNAME)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.Target = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad DNAME Target", l}, ""
-	}
-	rr.Target = name
-	return rr, nil, ""
-}
-
 func setSOA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	rr := new(SOA)
 	rr.Hdr = h
@@ -617,49 +253,6 @@ func setSOA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, ""
 }
 
-func setSRV(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(SRV)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	i, e := strconv.ParseUint(l.token, 10, 16)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SRV Priority", l}, ""
-	}
-	rr.Priority = uint16(i)
-
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	i, e = strconv.ParseUint(l.token, 10, 16)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SRV Weight", l}, ""
-	}
-	rr.Weight = uint16(i)
-
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	i, e = strconv.ParseUint(l.token, 10, 16)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SRV Port", l}, ""
-	}
-	rr.Port = uint16(i)
-
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	rr.Target = l.token
-
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad SRV Target", l}, ""
-	}
-	rr.Target = name
-	return rr, nil, ""
-}
-
 func setNAPTR(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	rr := new(NAPTR)
 	rr.Hdr = h
@@ -753,35 +346,6 @@ func setNAPTR(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, ""
 }
 
-func setTALINK(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(TALINK)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	rr.PreviousName = l.token
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	previousName, previousNameOk := toAbsoluteName(l.token, o)
-	if l.err || !previousNameOk {
-		return nil, &ParseError{f, "bad TALINK PreviousName", l}, ""
-	}
-	rr.PreviousName = previousName
-
-	c.Next() // zBlank
-	l, _ = c.Next()
-	rr.NextName = l.token
-
-	nextName, nextNameOk := toAbsoluteName(l.token, o)
-	if l.err || !nextNameOk {
-		return nil, &ParseError{f, "bad TALINK NextName", l}, ""
-	}
-	rr.NextName = nextName
-
-	return rr, nil, ""
-}
-
 func setLOC(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	rr := new(LOC)
 	rr.Hdr = h
@@ -816,10 +380,11 @@ func setLOC(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 
 	c.Next() // zBlank
 	l, _ = c.Next()
-	if i, e := strconv.ParseFloat(l.token, 32); e != nil || l.err {
+	if i, e := strconv.ParseFloat(l.token, 64); e != nil || l.err || i < 0 || i >= 60 {
 		return nil, &ParseError{f, "bad LOC Latitude seconds", l}, ""
 	} else {
-		rr.Latitude += uint32(1000 * i)
+		// Rounded to the millisecond, as String prints it.
+		rr.Latitude += uint32(1000*i + 0.5)
 	}
 	c.Next() // zBlank
 	// Either number, 'N' or 'S'
@@ -852,10 +417,11 @@ East:
 	}
 	c.Next() // zBlank
 	l, _ = c.Next()
-	if i, e := strconv.ParseFloat(l.token, 32); e != nil || l.err {
+	if i, e := strconv.ParseFloat(l.token, 64); e != nil || l.err || i < 0 || i >= 60 {
 		return nil, &ParseError{f, "bad LOC Longitude seconds", l}, ""
 	} else {
-		rr.Longitude += uint32(1000 * i)
+		// Rounded to the millisecond, as String prints it.
+		rr.Longitude += uint32(1000*i + 0.5)
 	}
 	c.Next() // zBlank
 	// Either number, 'E' or 'W'
@@ -1012,18 +578,6 @@ func setCERT(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, c1
 }
 
-func setOPENPGPKEY(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(OPENPGPKEY)
-	rr.Hdr = h
-
-	s, e, c1 := endingToString(c, "bad OPENPGPKEY PublicKey", f)
-	if e != nil {
-		return nil, e, c1
-	}
-	rr.PublicKey = s
-	return rr, nil, c1
-}
-
 func setCSYNC(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	rr := new(CSYNC)
 	rr.Hdr = h
@@ -1343,24 +897,8 @@ func setEUI48(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 		return rr, nil, ""
 	}
 
-	if len(l.token) != 17 || l.err {
-		return nil, &ParseError{f, "bad EUI48 Address", l}, ""
-	}
-	addr := make([]byte, 12)
-	dash := 0
-	for i := 0; i < 10; i += 2 {
-		addr[i] = l.token[i+dash]
-		addr[i+1] = l.token[i+1+dash]
-		dash++
-		if l.token[i+1+dash] != '-' {
-			return nil, &ParseError{f, "bad EUI48 Address", l}, ""
-		}
-	}
-	addr[10] = l.token[15]
-	addr[11] = l.token[16]
-
-	i, e := strconv.ParseUint(string(addr), 16, 48)
-	if e != nil {
+	i, ok := parseEUI(l.token, 48)
+	if !ok || l.err {
 		return nil, &ParseError{f, "bad EUI48 Address", l}, ""
 	}
 	rr.Address = i
@@ -1376,58 +914,33 @@ func setEUI64(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 		return rr, nil, ""
 	}
 
-	if len(l.token) != 23 || l.err {
+	i, ok := parseEUI(l.token, 64)
+	if !ok || l.err {
 		return nil, &ParseError{f, "bad EUI64 Address", l}, ""
 	}
-	addr := make([]byte, 16)
-	dash := 0
-	for i := 0; i < 14; i += 2 {
-		addr[i] = l.token[i+dash]
-		addr[i+1] = l.token[i+1+dash]
-		dash++
-		if l.token[i+1+dash] != '-' {
-			return nil, &ParseError{f, "bad EUI64 Address", l}, ""
-		}
-	}
-	addr[14] = l.token[21]
-	addr[15] = l.token[22]
-
-	i, e := strconv.ParseUint(string(addr), 16, 64)
-	if e != nil {
-		return nil, &ParseError{f, "bad EUI68 Address", l}, ""
-	}
-	rr.Address = uint64(i)
+	rr.Address = i
 	return rr, nil, ""
 }
 
-func setSSHFP(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(SSHFP)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	i, e := strconv.ParseUint(l.token, 10, 8)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SSHFP Algorithm", l}, ""
-	}
-	rr.Algorithm = uint8(i)
-	c.Next() // zBlank
-	l, _ = c.Next()
-	i, e = strconv.ParseUint(l.token, 10, 8)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SSHFP Type", l}, ""
+// parseEUI parses the EUI-48 or EUI-64 address s, written as 6 or 8 groups of
+// two hexadecimal digits separated by hyphens (RFC 7043, Section 3.2).
+func parseEUI(s string, bits int) (uint64, bool) {
+	groups := bits / 8
+	if len(s) != 3*groups-1 {
+		return 0, false
 	}
-	rr.Type = uint8(i)
-	c.Next() // zBlank
-	s, e1, c1 := endingToString(c, "bad SSHFP Fingerprint", f)
-	if e1 != nil {
-		return nil, e1, c1
+	var eui uint64
+	for i := 0; i < groups; i++ {
+		if i > 0 && s[3*i-1] != '-' {
+			return 0, false
+		}
+		b, err := strconv.ParseUint(s[3*i:3*i+2], 16, 8)
+		if err != nil {
+			return 0, false
+		}
+		eui = eui<<8 | b
 	}
-	rr.FingerPrint = s
-	return rr, nil, ""
+	return eui, true
 }
 
 func setDNSKEYs(h RR_Header, c *zlexer, o, f, typ string) (RR, *ParseError, string) {
@@ -1523,28 +1036,6 @@ func setRKEY(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, c1
 }
 
-func setEID(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(EID)
-	rr.Hdr = h
-	s, e, c1 := endingToString(c, "bad EID Endpoint", f)
-	if e != nil {
-		return nil, e, c1
-	}
-	rr.Endpoint = s
-	return rr, nil, c1
-}
-
-func setNIMLOC(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(NIMLOC)
-	rr.Hdr = h
-	s, e, c1 := endingToString(c, "bad NIMLOC Locator", f)
-	if e != nil {
-		return nil, e, c1
-	}
-	rr.Locator = s
-	return rr, nil, c1
-}
-
 func setGPOS(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	rr := new(GPOS)
 	rr.Hdr = h
@@ -1554,28 +1045,54 @@ func setGPOS(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 		return rr, nil, ""
 	}
 
-	_, e := strconv.ParseFloat(l.token, 64)
-	if e != nil || l.err {
+	// RFC 1712 swaps the names: its longitude is north of the equator, in
+	// -90..90, and its latitude east of the prime meridian, in -180..180.
+	if !gposFloat(l.token, 90) || l.err {
 		return nil, &ParseError{f, "bad GPOS Longitude", l}, ""
 	}
 	rr.Longitude = l.token
 	c.Next() // zBlank
 	l, _ = c.Next()
-	_, e = strconv.ParseFloat(l.token, 64)
-	if e != nil || l.err {
+	if !gposFloat(l.token, 180) || l.err {
 		return nil, &ParseError{f, "bad GPOS Latitude", l}, ""
 	}
 	rr.Latitude = l.token
 	c.Next() // zBlank
 	l, _ = c.Next()
-	_, e = strconv.ParseFloat(l.token, 64)
-	if e != nil || l.err {
+	if !gposFloat(l.token, 0) || l.err {
 		return nil, &ParseError{f, "bad GPOS Altitude", l}, ""
 	}
 	rr.Altitude = l.token
 	return rr, nil, ""
 }
 
+// gposFloat returns true if s is a GPOS coordinate: a decimal number, with an
+// optional sign and fraction, of at most 255 characters, not larger than max
+// in absolute value if max is not zero (RFC 1712, Section 3). Exponents,
+// infinities and NaN, which strconv.ParseFloat accepts, are rejected.
+func gposFloat(s string, max float64) bool {
+	if len(s) > 255 {
+		return false
+	}
+	digits, dot := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		case (c == '-' || c == '+') && i == 0:
+		default:
+			return false
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return err == nil && (max == 0 || math.Abs(v) <= max)
+}
+
 func setDSs(h RR_Header, c *zlexer, o, f, typ string) (RR, *ParseError, string) {
 	rr := new(DS)
 	rr.Hdr = h
@@ -1679,80 +1196,6 @@ func setTA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, c1
 }
 
-func setTLSA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(TLSA)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, l.comment
-	}
-
-	i, e := strconv.ParseUint(l.token, 10, 8)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad TLSA Usage", l}, ""
-	}
-	rr.Usage = uint8(i)
-	c.Next() // zBlank
-	l, _ = c.Next()
-	i, e = strconv.ParseUint(l.token, 10, 8)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad TLSA Selector", l}, ""
-	}
-	rr.Selector = uint8(i)
-	c.Next() // zBlank
-	l, _ = c.Next()
-	i, e = strconv.ParseUint(l.token, 10, 8)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad TLSA MatchingType", l}, ""
-	}
-	rr.MatchingType = uint8(i)
-	// So this needs be e2 (i.e. different than e), because...??t
-	s, e2, c1 := endingToString(c, "bad TLSA Certificate", f)
-	if e2 != nil {
-		return nil, e2, c1
-	}
-	rr.Certificate = s
-	return rr, nil, c1
-}
-
-func setSMIMEA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(SMIMEA)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, l.comment
-	}
-
-	i, e := strconv.ParseUint(l.token, 10, 8)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SMIMEA Usage", l}, ""
-	}
-	rr.Usage = uint8(i)
-	c.Next() // zBlank
-	l, _ = c.Next()
-	i, e = strconv.ParseUint(l.token, 10, 8)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SMIMEA Selector", l}, ""
-	}
-	rr.Selector = uint8(i)
-	c.Next() // zBlank
-	l, _ = c.Next()
-	i, e = strconv.ParseUint(l.token, 10, 8)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SMIMEA MatchingType", l}, ""
-	}
-	rr.MatchingType = uint8(i)
-	// So this needs be e2 (i.e. different than e), because...??t
-	s, e2, c1 := endingToString(c, "bad SMIMEA Certificate", f)
-	if e2 != nil {
-		return nil, e2, c1
-	}
-	rr.Certificate = s
-	return rr, nil, c1
-}
-
 func setRFC3597(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	rr := new(RFC3597)
 	rr.Hdr = h
@@ -1780,56 +1223,6 @@ func setRFC3597(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, c1
 }
 
-func setSPF(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(SPF)
-	rr.Hdr = h
-
-	s, e, c1 := endingToTxtSlice(c, "bad SPF Txt", f)
-	if e != nil {
-		return nil, e, ""
-	}
-	rr.Txt = s
-	return rr, nil, c1
-}
-
-func setAVC(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(AVC)
-	rr.Hdr = h
-
-	s, e, c1 := endingToTxtSlice(c, "bad AVC Txt", f)
-	if e != nil {
-		return nil, e, ""
-	}
-	rr.Txt = s
-	return rr, nil, c1
-}
-
-func setTXT(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(TXT)
-	rr.Hdr = h
-
-	// no zBlank reading here, because all this rdata is TXT
-	s, e, c1 := endingToTxtSlice(c, "bad TXT Txt", f)
-	if e != nil {
-		return nil, e, ""
-	}
-	rr.Txt = s
-	return rr, nil, c1
-}
-
-// identical to setTXT
-func setNINFO(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(NINFO)
-	rr.Hdr = h
-
-	s, e, c1 := endingToTxtSlice(c, "bad NINFO ZSData", f)
-	if e != nil {
-		return nil, e, ""
-	}
-	rr.ZSData = s
-	return rr, nil, c1
-}
-
 func setURI(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	rr := new(URI)
 	rr.Hdr = h
@@ -1864,19 +1257,6 @@ func setURI(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, c1
 }
 
-func setDHCID(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	// awesome record to parse!
-	rr := new(DHCID)
-	rr.Hdr = h
-
-	s, e, c1 := endingToString(c, "bad DHCID Digest", f)
-	if e != nil {
-		return nil, e, c1
-	}
-	rr.Digest = s
-	return rr, nil, c1
-}
-
 func setNID(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	rr := new(NID)
 	rr.Hdr = h
@@ -1901,56 +1281,6 @@ func setNID(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, ""
 }
 
-func setL32(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(L32)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	i, e := strconv.ParseUint(l.token, 10, 16)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad L32 Preference", l}, ""
-	}
-	rr.Preference = uint16(i)
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	rr.Locator32 = net.ParseIP(l.token)
-	if rr.Locator32 == nil || l.err {
-		return nil, &ParseError{f, "bad L32 Locator", l}, ""
-	}
-	return rr, nil, ""
-}
-
-func setLP(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(LP)
-	rr.Hdr = h
-
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
-	}
-
-	i, e := strconv.ParseUint(l.token, 10, 16)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad LP Preference", l}, ""
-	}
-	rr.Preference = uint16(i)
-
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	rr.Fqdn = l.token
-	name, nameOk := toAbsoluteName(l.token, o)
-	if l.err || !nameOk {
-		return nil, &ParseError{f, "bad LP Fqdn", l}, ""
-	}
-	rr.Fqdn = name
-
-	return rr, nil, ""
-}
-
 func setL64(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	rr := new(L64)
 	rr.Hdr = h
@@ -1975,123 +1305,208 @@ func setL64(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 	return rr, nil, ""
 }
 
-func setUID(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(UID)
+func setUINFO(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(UINFO)
 	rr.Hdr = h
 
-	l, _ := c.Next()
-	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
+	s, e, c1 := endingToTxtSlice(c, "bad UINFO Uinfo", f)
+	if e != nil {
+		return nil, e, c1
 	}
-
-	i, e := strconv.ParseUint(l.token, 10, 32)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad UID Uid", l}, ""
+	if ln := len(s); ln == 0 {
+		return rr, nil, c1
 	}
-	rr.Uid = uint32(i)
-	return rr, nil, ""
+	rr.Uinfo = s[0] // silently discard anything after the first character-string
+	return rr, nil, c1
 }
 
-func setGID(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(GID)
+func setCAA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(CAA)
 	rr.Hdr = h
 
 	l, _ := c.Next()
 	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
+		return rr, nil, l.comment
 	}
 
-	i, e := strconv.ParseUint(l.token, 10, 32)
-	if e != nil || l.err {
-		return nil, &ParseError{f, "bad GID Gid", l}, ""
+	i, err := strconv.ParseUint(l.token, 10, 8)
+	if err != nil || l.err {
+		return nil, &ParseError{f, "bad CAA Flag", l}, ""
 	}
-	rr.Gid = uint32(i)
-	return rr, nil, ""
-}
+	rr.Flag = uint8(i)
 
-func setUINFO(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(UINFO)
-	rr.Hdr = h
+	l, _ = c.Next() // zBlank
+	if l.value != zBlank {
+		return nil, &ParseError{f, "bad CAA Tag", l}, ""
+	}
+	l, _ = c.Next() // zString
+	if l.value != zString || !isCAATag(l.token) {
+		return nil, &ParseError{f, "bad CAA Tag", l}, ""
+	}
+	rr.Tag = l.token
 
-	s, e, c1 := endingToTxtSlice(c, "bad UINFO Uinfo", f)
+	c.Next() // zBlank
+	s, e, c1 := endingToTxtSlice(c, "bad CAA Value", f)
 	if e != nil {
-		return nil, e, c1
+		return nil, e, ""
 	}
-	if ln := len(s); ln == 0 {
-		return rr, nil, c1
+	if len(s) != 1 {
+		return nil, &ParseError{f, "bad CAA Value", l}, ""
 	}
-	rr.Uinfo = s[0] // silently discard anything after the first character-string
+	rr.Value = s[0]
 	return rr, nil, c1
 }
 
-func setPX(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(PX)
+// isCAATag reports whether tag is a property tag, a string of ASCII letters
+// and digits (RFC 8659, Section 4.1).
+func isCAATag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for i := 0; i < len(tag); i++ {
+		if b := tag[i]; !isDigit(b) && (b|0x20 < 'a' || b|0x20 > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+func setDOA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(DOA)
 	rr.Hdr = h
 
 	l, _ := c.Next()
 	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, ""
+		return rr, nil, l.comment
 	}
 
-	i, e := strconv.ParseUint(l.token, 10, 16)
+	i, e := strconv.ParseUint(l.token, 10, 32)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad PX Preference", l}, ""
+		return nil, &ParseError{f, "bad DOA Enterprise", l}, ""
 	}
-	rr.Preference = uint16(i)
-
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	rr.Map822 = l.token
-	map822, map822Ok := toAbsoluteName(l.token, o)
-	if l.err || !map822Ok {
-		return nil, &ParseError{f, "bad PX Map822", l}, ""
+	rr.Enterprise = uint32(i)
+	c.Next() // zBlank
+	l, _ = c.Next()
+	i, e = strconv.ParseUint(l.token, 10, 32)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad DOA Type", l}, ""
+	}
+	rr.Type = uint32(i)
+	c.Next() // zBlank
+	l, _ = c.Next()
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad DOA Location", l}, ""
 	}
-	rr.Map822 = map822
+	rr.Location = uint8(i)
 
+	// The media type is a character-string, quoted or not.
 	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	rr.Mapx400 = l.token
-	mapx400, mapx400Ok := toAbsoluteName(l.token, o)
-	if l.err || !mapx400Ok {
-		return nil, &ParseError{f, "bad PX Mapx400", l}, ""
+	l, _ = c.Next() // Either String or Quote
+	switch l.value {
+	case zString:
+		rr.MediaType = l.token
+	case zQuote:
+		l, _ = c.Next()
+		if l.value == zString {
+			rr.MediaType = l.token
+			l, _ = c.Next()
+		}
+		if l.value != zQuote {
+			return nil, &ParseError{f, "bad DOA MediaType", l}, ""
+		}
+	default:
+		return nil, &ParseError{f, "bad DOA MediaType", l}, ""
+	}
+	if l.err || len(rr.MediaType) > 255 {
+		return nil, &ParseError{f, "bad DOA MediaType", l}, ""
 	}
-	rr.Mapx400 = mapx400
 
-	return rr, nil, ""
+	// The data is base64, or "-" when empty.
+	l, _ = c.Next() // zBlank
+	if l.value != zBlank {
+		return nil, &ParseError{f, "bad DOA Data", l}, ""
+	}
+	s, e1, c1 := endingToString(c, "bad DOA Data", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	switch s {
+	case "":
+		return nil, &ParseError{f, "bad DOA Data", l}, ""
+	case "-":
+	default:
+		if _, err := fromBase64([]byte(s)); err != nil {
+			return nil, &ParseError{f, "bad DOA Data", l}, ""
+		}
+		rr.Data = s
+	}
+	return rr, nil, c1
 }
 
-func setCAA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
-	rr := new(CAA)
+func setAMTRELAY(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(AMTRELAY)
 	rr.Hdr = h
 
 	l, _ := c.Next()
 	if len(l.token) == 0 { // dynamic update rr.
-		return rr, nil, l.comment
+		return rr, nil, ""
 	}
 
-	i, err := strconv.ParseUint(l.token, 10, 8)
-	if err != nil || l.err {
-		return nil, &ParseError{f, "bad CAA Flag", l}, ""
+	i, e := strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad AMTRELAY Precedence", l}, ""
 	}
-	rr.Flag = uint8(i)
+	rr.Precedence = uint8(i)
 
-	c.Next()        // zBlank
-	l, _ = c.Next() // zString
-	if l.value != zString {
-		return nil, &ParseError{f, "bad CAA Tag", l}, ""
+	c.Next() // zBlank
+	l, _ = c.Next()
+	switch {
+	case l.err:
+		return nil, &ParseError{f, "bad AMTRELAY Discovery", l}, ""
+	case l.token == "1":
+		rr.GatewayType = amtrelayDiscovery
+	case l.token != "0":
+		return nil, &ParseError{f, "bad AMTRELAY Discovery", l}, ""
 	}
-	rr.Tag = l.token
 
 	c.Next() // zBlank
-	s, e, c1 := endingToTxtSlice(c, "bad CAA Value", f)
-	if e != nil {
-		return nil, e, ""
+	l, _ = c.Next()
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err || i > AMTRELAYHost {
+		return nil, &ParseError{f, "bad AMTRELAY GatewayType", l}, ""
 	}
-	if len(s) != 1 {
-		return nil, &ParseError{f, "bad CAA Value", l}, ""
+	rr.GatewayType |= uint8(i)
+
+	c.Next() // zBlank
+	l, _ = c.Next()
+	if l.err || l.value != zString {
+		return nil, &ParseError{f, "bad AMTRELAY Relay", l}, ""
 	}
-	rr.Value = s[0]
-	return rr, nil, c1
+	switch i {
+	case AMTRELAYNone:
+		if l.token != "." {
+			return nil, &ParseError{f, "bad AMTRELAY Relay", l}, ""
+		}
+	case AMTRELAYIPv4:
+		rr.GatewayAddr = net.ParseIP(l.token)
+		if rr.GatewayAddr == nil || strings.Contains(l.token, ":") {
+			return nil, &ParseError{f, "bad AMTRELAY Relay", l}, ""
+		}
+		rr.GatewayAddr = rr.GatewayAddr.To4()
+	case AMTRELAYIPv6:
+		rr.GatewayAddr = net.ParseIP(l.token)
+		if rr.GatewayAddr == nil || !strings.Contains(l.token, ":") {
+			return nil, &ParseError{f, "bad AMTRELAY Relay", l}, ""
+		}
+	case AMTRELAYHost:
+		name, nameOk := toAbsoluteName(l.token, o)
+		if !nameOk {
+			return nil, &ParseError{f, "bad AMTRELAY Relay", l}, ""
+		}
+		rr.GatewayHost = name
+	}
+	return rr, nil, ""
 }
 
 func setTKEY(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
@@ -2140,68 +1555,34 @@ func setTKEY(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
 }
 
 var typeToparserFunc = map[uint16]parserFunc{
-	TypeAAAA:       {setAAAA, false},
-	TypeAFSDB:      {setAFSDB, false},
-	TypeA:          {setA, false},
+	TypeAMTRELAY:   {setAMTRELAY, false},
 	TypeCAA:        {setCAA, true},
 	TypeCDS:        {setCDS, true},
 	TypeCDNSKEY:    {setCDNSKEY, true},
 	TypeCERT:       {setCERT, true},
-	TypeCNAME:      {setCNAME, false},
 	TypeCSYNC:      {setCSYNC, true},
-	TypeDHCID:      {setDHCID, true},
 	TypeDLV:        {setDLV, true},
-	TypeDNAME:      {setDNAME, false},
 	TypeKEY:        {setKEY, true},
 	TypeDNSKEY:     {setDNSKEY, true},
+	TypeDOA:        {setDOA, true},
 	TypeDS:         {setDS, true},
-	TypeEID:        {setEID, true},
 	TypeEUI48:      {setEUI48, false},
 	TypeEUI64:      {setEUI64, false},
-	TypeGID:        {setGID, false},
 	TypeGPOS:       {setGPOS, false},
 	TypeHINFO:      {setHINFO, true},
 	TypeHIP:        {setHIP, true},
-	TypeKX:         {setKX, false},
-	TypeL32:        {setL32, false},
 	TypeL64:        {setL64, false},
 	TypeLOC:        {setLOC, true},
-	TypeLP:         {setLP, false},
-	TypeMB:         {setMB, false},
-	TypeMD:         {setMD, false},
-	TypeMF:         {setMF, false},
-	TypeMG:         {setMG, false},
-	TypeMINFO:      {setMINFO, false},
-	TypeMR:         {setMR, false},
-	TypeMX:         {setMX, false},
 	TypeNAPTR:      {setNAPTR, false},
 	TypeNID:        {setNID, false},
-	TypeNIMLOC:     {setNIMLOC, true},
-	TypeNINFO:      {setNINFO, true},
-	TypeNSAPPTR:    {setNSAPPTR, false},
 	TypeNSEC3PARAM: {setNSEC3PARAM, false},
 	TypeNSEC3:      {setNSEC3, true},
 	TypeNSEC:       {setNSEC, true},
-	TypeNS:         {setNS, false},
-	TypeOPENPGPKEY: {setOPENPGPKEY, true},
-	TypePTR:        {setPTR, false},
-	TypePX:         {setPX, false},
 	TypeSIG:        {setSIG, true},
 	TypeRKEY:       {setRKEY, true},
-	TypeRP:         {setRP, false},
 	TypeRRSIG:      {setRRSIG, true},
-	TypeRT:         {setRT, false},
-	TypeSMIMEA:     {setSMIMEA, true},
 	TypeSOA:        {setSOA, false},
-	TypeSPF:        {setSPF, true},
-	TypeAVC:        {setAVC, true},
-	TypeSRV:        {setSRV, false},
-	TypeSSHFP:      {setSSHFP, true},
-	TypeTALINK:     {setTALINK, false},
 	TypeTA:         {setTA, true},
-	TypeTLSA:       {setTLSA, true},
-	TypeTXT:        {setTXT, true},
-	TypeUID:        {setUID, false},
 	TypeUINFO:      {setUINFO, true},
 	TypeURI:        {setURI, true},
 	TypeX25:        {setX25, false},
@@ -1,10 +1,13 @@
 package dns
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -121,6 +124,60 @@ func TestZoneParserIncludeDisallowed(t *testing.T) {
 	}
 }
 
+func TestZoneParserIncludeDepth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dns")
+	if err != nil {
+		t.Fatalf("could not create tmpdir for test: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each file includes the next one, the last one has an error.
+	for i := 0; i < 3; i++ {
+		content := fmt.Sprintf("host%d\tIN\tA\t127.0.0.1\n$INCLUDE %d.db\n", i, i+1)
+		if i == 2 {
+			content = "host2\tIN\tA\t127.0.0.1\n\nbad IN  A 127.0.0.x\n"
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.db", i)), []byte(content), 0644); err != nil {
+			t.Fatalf("unable to write content to tmpfile: %s", err)
+		}
+	}
+	parse := func(depth int) (int, error) {
+		f, err := os.Open(filepath.Join(dir, "0.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		zp := NewZoneParser(f, "example.org.", f.Name())
+		zp.SetIncludeAllowed(true)
+		zp.SetMaxIncludeDepth(depth)
+		n := 0
+		for _, ok := zp.Next(); ok; _, ok = zp.Next() {
+			n++
+		}
+		return n, zp.Err()
+	}
+
+	n, err := parse(1)
+	if n != 2 || err == nil || !strings.Contains(err.Error(), "too deeply nested $INCLUDE") {
+		t.Errorf("expected 2 records and a nesting error, got %d and %v", n, err)
+	}
+
+	n, err = parse(0)
+	if n != 1 || err == nil {
+		t.Errorf("expected 1 record and a nesting error, got %d and %v", n, err)
+	}
+
+	n, err = parse(2)
+	pe, ok := err.(*ParseError)
+	if n != 3 || !ok {
+		t.Fatalf("expected 3 records and a parse error, got %d and %v", n, err)
+	}
+	file, line, column := pe.Position()
+	if file != filepath.Join(dir, "2.db") || line != 3 || column != 11 {
+		t.Errorf("expected the error at %s:3:11, got %s:%d:%d", filepath.Join(dir, "2.db"), file, line, column)
+	}
+}
+
 func TestParseTA(t *testing.T) {
 	rr, err := NewRR(` Ta 0 0 0`)
 	if err != nil {
@@ -216,3 +273,34 @@ func BenchmarkZoneParser(b *testing.B) {
 		}
 	}
 }
+
+func TestScanGenerateStable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+	gocmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	dir, err := ioutil.TempDir("", "dns")
+	if err != nil {
+		t.Fatalf("could not create tmpdir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "zscan.go")
+	if b, err := exec.Command(gocmd, "run", "scan_generate.go", "-o", out).CombinedOutput(); err != nil {
+		t.Fatalf("failed to run scan_generate.go: %v\n%s", err, b)
+	}
+	generated, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkedIn, err := ioutil.ReadFile("zscan.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(generated) != string(checkedIn) {
+		t.Errorf("zscan.go is not the output of scan_generate.go, run go generate")
+	}
+}
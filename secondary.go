@@ -0,0 +1,353 @@
+package dns
+
+import (
+	"crypto/tls"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Primary is a primary name server secondary zones are transferred from.
+type Primary struct {
+	Addr string      // address of the server, in host:port form
+	Tsig *TsigKey    // key to sign the queries to the server with, nil if they aren't signed
+	TLS  *tls.Config // if set, the SOA queries and the transfers to the server are made over TLS with this configuration, e.g. with the client certificate it requires (XoT, RFC 9103)
+}
+
+// A TransferManager keeps secondary zones up to date, the core loop of a
+// secondary name server. Each zone is refreshed on the timers of its SOA record
+// (RFC 1035, Section 4.3.5): every refresh interval the serial of the zone is
+// asked from its primaries, and the zone is transferred with AXFR when it
+// changed. A zone that can't be refreshed is retried every retry interval, and
+// expires when that lasts longer than its expire interval.
+//
+// Zones are refreshed concurrently, up to MaxTransfers at once. A refresh can
+// be triggered early with Notify, which fits NotifyHandler.Notify.
+type TransferManager struct {
+	Net          string        // network of the SOA queries, defaults to UDP, transfers are made over TCP; both are made over TLS with the primaries that have TLS set
+	Timeout      time.Duration // timeout of the SOA queries, and of the reads and writes of the transfers, defaults to 2 seconds
+	MaxTransfers int           // maximum number of zones refreshed at once, defaults to 10
+	MinRefresh   time.Duration // lower bound of the refresh and retry intervals, defaults to 1 minute
+
+	// Transferred is called with the records of a zone after it was
+	// transferred, starting with its SOA record. It isn't called concurrently
+	// for the same zone by Run. If it returns an error, the zone is rejected
+	// and the refresh failed.
+	Transferred func(zone string, rrs []RR) error
+	// Expired, if not nil, is called when a zone expires, the zone should
+	// not be served anymore until it is transferred again.
+	Expired func(zone string)
+	// Error, if not nil, is called when a zone can't be refreshed.
+	Error func(zone string, err error)
+
+	mu    sync.Mutex
+	zones map[string]*secondaryZone
+	wake  chan struct{} // signals Run that the zones changed
+}
+
+type secondaryZone struct {
+	primaries []Primary
+	soa       *SOA      // SOA record of the zone, nil before it is transferred
+	due       time.Time // when to refresh
+	expire    time.Time // when the zone expires, zero without a SOA record
+	expired   bool
+	running   bool // the zone is being refreshed by Run
+}
+
+var errUnknownZone error = &Error{err: "unknown zone"}
+
+// Add adds zone, or replaces the primaries of it, to be transferred from
+// primaries, tried in order. If soa isn't nil it is the SOA record of the
+// data of the zone the caller already has, e.g. from disk, and the zone is
+// refreshed after its refresh interval, otherwise it is transferred at once.
+func (tm *TransferManager) Add(zone string, primaries []Primary, soa *SOA) {
+	zone = strings.ToLower(Fqdn(zone))
+	primaries = append([]Primary(nil), primaries...)
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.zones == nil {
+		tm.zones = make(map[string]*secondaryZone)
+	}
+	if z := tm.zones[zone]; z != nil {
+		z.primaries = primaries
+		return
+	}
+	z := &secondaryZone{primaries: primaries, soa: soa}
+	now := time.Now()
+	if soa != nil {
+		z.due = now.Add(tm.interval(soa.Refresh))
+		z.expire = now.Add(time.Duration(soa.Expire) * time.Second)
+	}
+	tm.zones[zone] = z
+	tm.notify()
+}
+
+// Remove removes zone.
+func (tm *TransferManager) Remove(zone string) {
+	tm.mu.Lock()
+	delete(tm.zones, strings.ToLower(Fqdn(zone)))
+	tm.mu.Unlock()
+}
+
+// SOA returns the SOA record of zone, nil if it wasn't transferred yet.
+func (tm *TransferManager) SOA(zone string) *SOA {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if z := tm.zones[strings.ToLower(Fqdn(zone))]; z != nil {
+		return z.soa
+	}
+	return nil
+}
+
+// Notify makes Run refresh zone at once, unless soa isn't nil and its serial
+// isn't newer than the one of the zone.
+func (tm *TransferManager) Notify(zone string, soa *SOA) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	z := tm.zones[strings.ToLower(Fqdn(zone))]
+	if z == nil {
+		return
+	}
+	if soa != nil && z.soa != nil && SerialCompare(soa.Serial, z.soa.Serial) <= 0 {
+		return
+	}
+	z.due = time.Now()
+	tm.notify()
+}
+
+// Refresh refreshes zone now: it asks the primaries of the zone for its
+// serial, and transfers the zone from the first primary that has a newer one.
+// The error is the one of the last primary tried when none could be asked.
+func (tm *TransferManager) Refresh(zone string) error {
+	zone = strings.ToLower(Fqdn(zone))
+	tm.mu.Lock()
+	z := tm.zones[zone]
+	if z == nil {
+		tm.mu.Unlock()
+		return errUnknownZone
+	}
+	primaries, cur := z.primaries, z.soa
+	tm.mu.Unlock()
+
+	err := errUnknownZone
+	for _, p := range primaries {
+		var soa *SOA
+		if soa, err = tm.querySOA(zone, p); err != nil {
+			continue
+		}
+		if cur != nil && SerialCompare(soa.Serial, cur.Serial) <= 0 {
+			tm.refreshed(zone, cur)
+			return nil
+		}
+		var rrs []RR
+		if rrs, err = tm.axfr(zone, p); err != nil {
+			continue
+		}
+		if tm.Transferred != nil {
+			if err = tm.Transferred(zone, rrs); err != nil {
+				continue
+			}
+		}
+		tm.refreshed(zone, rrs[0].(*SOA))
+		return nil
+	}
+	tm.failed(zone, err)
+	return err
+}
+
+// Run refreshes the zones when they are due, until done is closed.
+func (tm *TransferManager) Run(done <-chan struct{}) {
+	tm.mu.Lock()
+	if tm.wake == nil {
+		tm.wake = make(chan struct{}, 1)
+	}
+	wake := tm.wake
+	tm.mu.Unlock()
+
+	max := tm.MaxTransfers
+	if max <= 0 {
+		max = 10
+	}
+	sem := make(chan struct{}, max)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		now := time.Now()
+		var (
+			due  []string
+			next time.Time
+		)
+		tm.mu.Lock()
+		for name, z := range tm.zones {
+			switch {
+			case z.running:
+			case !z.due.After(now):
+				z.running = true
+				due = append(due, name)
+			case next.IsZero() || z.due.Before(next):
+				next = z.due
+			}
+		}
+		tm.mu.Unlock()
+
+		for _, name := range due {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				tm.Refresh(name)
+				<-sem
+				tm.mu.Lock()
+				if z := tm.zones[name]; z != nil {
+					z.running = false
+				}
+				tm.notify()
+				tm.mu.Unlock()
+			}(name)
+		}
+
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			fire = timer.C
+		}
+		select {
+		case <-done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-wake:
+		case <-fire:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// refreshed records that zone is up to date with soa.
+func (tm *TransferManager) refreshed(zone string, soa *SOA) {
+	tm.mu.Lock()
+	z := tm.zones[zone]
+	if z == nil {
+		tm.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	z.soa = soa
+	z.due = now.Add(tm.interval(soa.Refresh))
+	z.expire = now.Add(time.Duration(soa.Expire) * time.Second)
+	z.expired = false
+	tm.mu.Unlock()
+}
+
+// failed records that zone couldn't be refreshed, and calls Error, and
+// Expired when the zone expires.
+func (tm *TransferManager) failed(zone string, err error) {
+	tm.mu.Lock()
+	z := tm.zones[zone]
+	if z == nil {
+		tm.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	var retry uint32
+	if z.soa != nil {
+		retry = z.soa.Retry
+	}
+	z.due = now.Add(tm.interval(retry))
+	expired := false
+	if z.soa != nil && !z.expired && !now.Before(z.expire) {
+		z.expired = true
+		expired = true
+	}
+	tm.mu.Unlock()
+
+	if tm.Error != nil {
+		tm.Error(zone, err)
+	}
+	if expired && tm.Expired != nil {
+		tm.Expired(zone)
+	}
+}
+
+// querySOA returns the SOA record of zone of the primary p.
+func (tm *TransferManager) querySOA(zone string, p Primary) (*SOA, error) {
+	c := &Client{Net: tm.Net, Timeout: tm.Timeout}
+	if p.TLS != nil {
+		c.Net, c.TLSConfig = "tcp-tls", XoTConfig(p.TLS)
+	}
+	m := new(Msg)
+	m.SetQuestion(zone, TypeSOA)
+	if p.Tsig != nil {
+		c.TsigProvider = tsigHMACProvider(p.Tsig.Secret)
+		m.SetTsig(p.Tsig.Name, p.Tsig.Algorithm, 300, time.Now().Unix())
+	}
+	r, _, err := c.Exchange(m, p.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if r.Rcode != RcodeSuccess {
+		return nil, &Error{err: "soa query failed with rcode " + RcodeToString[r.Rcode]}
+	}
+	for _, rr := range r.Answer {
+		if soa, ok := rr.(*SOA); ok && equal(soa.Hdr.Name, zone) {
+			return soa, nil
+		}
+	}
+	return nil, ErrSoa
+}
+
+// axfr transfers zone from the primary p, and returns its records without
+// the SOA record that ends the transfer.
+func (tm *TransferManager) axfr(zone string, p Primary) ([]RR, error) {
+	t := &Transfer{DialTimeout: tm.Timeout, ReadTimeout: tm.Timeout, WriteTimeout: tm.Timeout, TLSConfig: p.TLS}
+	m := new(Msg)
+	m.SetAxfr(zone)
+	if p.Tsig != nil {
+		t.TsigProvider = tsigHMACProvider(p.Tsig.Secret)
+		m.SetTsig(p.Tsig.Name, p.Tsig.Algorithm, 300, time.Now().Unix())
+	}
+	env, err := t.In(m, p.Addr)
+	if err != nil {
+		return nil, err
+	}
+	var rrs []RR
+	for e := range env {
+		if e.Error != nil {
+			err = e.Error
+			continue
+		}
+		rrs = append(rrs, e.RR...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rrs) < 2 || !equal(rrs[0].Header().Name, zone) {
+		return nil, ErrSoa
+	}
+	return rrs[:len(rrs)-1], nil
+}
+
+// interval returns the refresh or retry interval of secs seconds, at least
+// MinRefresh.
+func (tm *TransferManager) interval(secs uint32) time.Duration {
+	min := tm.MinRefresh
+	if min <= 0 {
+		min = time.Minute
+	}
+	if d := time.Duration(secs) * time.Second; d > min {
+		return d
+	}
+	return min
+}
+
+// notify wakes Run up, tm.mu must be held.
+func (tm *TransferManager) notify() {
+	select {
+	case tm.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,113 @@
+package dns
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTransferManager(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		serial uint32 = 1
+	)
+	zone := func() []RR {
+		mu.Lock()
+		defer mu.Unlock()
+		soa := testRR("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 60").(*SOA)
+		soa.Serial = serial
+		return []RR{soa, testRR("example. 3600 IN NS ns.example."), testRR("ns.example. 3600 IN A 192.0.2.1")}
+	}
+	HandleFunc("example.", func(w ResponseWriter, r *Msg) {
+		if r.Question[0].Qtype == TypeAXFR {
+			TransferOut(w, r, SliceRRIterator(zone()))
+			return
+		}
+		m := new(Msg)
+		m.SetReply(r)
+		m.Answer = zone()[:1]
+		if ts := r.IsTsig(); ts != nil {
+			m.SetTsig(ts.Hdr.Name, ts.Algorithm, ts.Fudge, time.Now().Unix())
+		}
+		w.WriteMsg(m)
+	})
+	defer HandleRemove("example.")
+
+	key := &TsigKey{Name: "xfr.", Algorithm: HmacSHA256, Secret: "so6ZGir4GPAqINNh9U5c3A=="}
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.TsigSecret = map[string]string{key.Name: key.Secret}
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	down := l.Addr().String()
+	l.Close()
+
+	transferred := make(chan []RR, 1)
+	tm := &TransferManager{
+		Net:         "tcp",
+		MinRefresh:  time.Hour,
+		Transferred: func(zone string, rrs []RR) error { transferred <- rrs; return nil },
+	}
+	tm.Add("Example", []Primary{{Addr: down}, {Addr: addrstr, Tsig: key}}, nil)
+	if err := tm.Refresh("example."); err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	if rrs := <-transferred; len(rrs) != 3 || tm.SOA("example.").Serial != 1 {
+		t.Fatalf("expected 3 records with serial 1, got %v", rrs)
+	}
+	if err := tm.Refresh("example."); err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	select {
+	case rrs := <-transferred:
+		t.Fatalf("expected no transfer of an unchanged zone, got %v", rrs)
+	default:
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go tm.Run(done)
+	mu.Lock()
+	serial = 2
+	mu.Unlock()
+	tm.Notify("example.", zone()[0].(*SOA))
+	select {
+	case rrs := <-transferred:
+		if rrs[0].(*SOA).Serial != 2 {
+			t.Errorf("expected serial 2, got %v", rrs[0])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a transfer after the notify")
+	}
+}
+
+func TestTransferManagerExpire(t *testing.T) {
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	down := l.Addr().String()
+	l.Close()
+
+	var expired, errors int
+	tm := &TransferManager{
+		Net:     "tcp",
+		Expired: func(zone string) { expired++ },
+		Error:   func(zone string, err error) { errors++ },
+	}
+	soa := testRR("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 0 60").(*SOA)
+	tm.Add("example.", []Primary{{Addr: down}}, soa)
+	for i := 0; i < 2; i++ {
+		if err := tm.Refresh("example."); err == nil {
+			t.Fatal("expected the refresh to fail")
+		}
+	}
+	if expired != 1 || errors != 2 {
+		t.Errorf("expected the zone to expire once after 2 errors, got %d and %d", expired, errors)
+	}
+	if err := tm.Refresh("example.org."); err != errUnknownZone {
+		t.Errorf("expected %v, got %v", errUnknownZone, err)
+	}
+}
@@ -0,0 +1,50 @@
+package dns
+
+import "time"
+
+// Strategies of NextSerial.
+const (
+	SerialIncrement = iota // add one
+	SerialDate             // the date, as YYYYMMDDnn, with nn counting the changes of a day
+	SerialUnixTime         // the current time in seconds since 1970
+)
+
+// SerialCompare compares the SOA serials a and b in serial number arithmetic
+// (RFC 1982), and returns -1, 0 or 1. The comparison of serials 2^31 apart
+// is undefined, SerialCompare returns 1 for it.
+func SerialCompare(a, b uint32) int {
+	switch {
+	case a == b:
+		return 0
+	case int32(b-a) > 0:
+		return -1
+	}
+	return 1
+}
+
+// SerialAdd returns serial plus n in serial number arithmetic (RFC 1982,
+// Section 3.1). The result is only larger than serial when n is lower than
+// 2^31.
+func SerialAdd(serial, n uint32) uint32 { return serial + n }
+
+// NextSerial returns the serial that follows serial at t with the strategy,
+// SerialIncrement, SerialDate or SerialUnixTime. The new serial is always
+// larger than serial: when the one of the strategy isn't, e.g. after more
+// than 99 changes in a day with SerialDate, serial plus one is returned.
+func NextSerial(serial uint32, strategy int, t time.Time) uint32 {
+	next := serial + 1
+	var s uint32
+	switch strategy {
+	case SerialDate:
+		y, m, d := t.UTC().Date()
+		s = uint32(y*1000000 + int(m)*10000 + d*100)
+	case SerialUnixTime:
+		s = uint32(t.Unix())
+	default:
+		return next
+	}
+	if serialLess(next, s) {
+		return s
+	}
+	return next
+}
@@ -0,0 +1,52 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSerialCompare(t *testing.T) {
+	tests := []struct {
+		a, b uint32
+		want int
+	}{
+		{1, 1, 0},
+		{1, 2, -1},
+		{2, 1, 1},
+		{0xFFFFFFFF, 0, -1}, // wraps
+		{0, 0xFFFFFFFF, 1},
+		{0, 1<<31 - 1, -1},
+		{0, 1 << 31, 1}, // undefined
+	}
+	for _, tc := range tests {
+		if got := SerialCompare(tc.a, tc.b); got != tc.want {
+			t.Errorf("SerialCompare(%d, %d): expected %d, got %d", tc.a, tc.b, tc.want, got)
+		}
+	}
+	if s := SerialAdd(0xFFFFFFFF, 2); s != 1 || SerialCompare(0xFFFFFFFF, s) != -1 {
+		t.Errorf("expected 1 after 0xFFFFFFFF, got %d", s)
+	}
+}
+
+func TestNextSerial(t *testing.T) {
+	day := time.Date(2020, 3, 14, 15, 9, 26, 0, time.UTC)
+	tests := []struct {
+		serial   uint32
+		strategy int
+		want     uint32
+	}{
+		{41, SerialIncrement, 42},
+		{1, SerialDate, 2020031400},
+		{2020031400, SerialDate, 2020031401},
+		{2020031399, SerialDate, 2020031400},
+		{2020031499, SerialDate, 2020031500}, // the next day's
+		{2021010100, SerialDate, 2021010101}, // serial in the future
+		{1, SerialUnixTime, uint32(day.Unix())},
+		{uint32(day.Unix()), SerialUnixTime, uint32(day.Unix()) + 1},
+	}
+	for _, tc := range tests {
+		if got := NextSerial(tc.serial, tc.strategy, day); got != tc.want {
+			t.Errorf("NextSerial(%d, %d): expected %d, got %d", tc.serial, tc.strategy, tc.want, got)
+		}
+	}
+}
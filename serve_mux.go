@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"strings"
 	"sync"
 )
@@ -13,12 +14,62 @@ import (
 // redirected to the parent zone (if that is also registered), otherwise
 // the child gets the query.
 //
+// A pattern "*.example.org." matches the names below example.org., but not
+// example.org. itself. With MatchExact, set with SetMatch, the other patterns
+// match their name only. Handlers registered with HandleClass only get the
+// queries of their class, they take precedence over the ones for all
+// classes.
+//
 // ServeMux is also safe for concurrent access from multiple goroutines.
 //
+// Middlewares added with Use wrap every handler, including the one that
+// returns SERVFAIL when no pattern matches.
+//
 // The zero ServeMux is empty and ready for use.
 type ServeMux struct {
-	z map[string]Handler
-	m sync.RWMutex
+	z      map[muxKey]*muxEntry // by pattern
+	wild   map[muxKey]*muxEntry // wildcard patterns, by the name below which they match
+	exact  bool
+	mw     []Middleware
+	failed Handler // HandleFailed wrapped by mw, nil without middlewares
+	m      sync.RWMutex
+}
+
+// Matching modes of a ServeMux.
+const (
+	MatchSuffix = iota // patterns match their name and the names below it, the default
+	MatchExact         // patterns match their name only
+)
+
+// A Route is a pattern of a ServeMux and its handler.
+type Route struct {
+	Pattern string
+	Class   uint16 // class of the queries handled, 0 for all
+	Handler Handler
+}
+
+// muxKey is the key of a pattern, the name is lower cased and without the
+// "*." of wildcards.
+type muxKey struct {
+	name  string
+	class uint16
+}
+
+// muxEntry is the handler of a pattern. Entries are replaced, not changed,
+// when the middlewares of the ServeMux change.
+type muxEntry struct {
+	h     Handler
+	chain Handler // h wrapped by the middlewares, nil when h is a HandlerContext
+}
+
+// newEntry returns the entry of h, with its chain of middlewares mw built.
+// A HandlerContext gets its context per request, so its chain is built when
+// the request is served.
+func newEntry(h Handler, mw []Middleware) *muxEntry {
+	if _, ok := h.(HandlerContext); ok {
+		return &muxEntry{h: h}
+	}
+	return &muxEntry{h: h, chain: Chain(h, mw...)}
 }
 
 // NewServeMux allocates and returns a new ServeMux.
@@ -30,13 +81,26 @@ func NewServeMux() *ServeMux {
 var DefaultServeMux = NewServeMux()
 
 func (mux *ServeMux) match(q string, t uint16) Handler {
+	return mux.route(q, t, ClassINET)
+}
+
+func (mux *ServeMux) route(q string, t, class uint16) Handler {
+	if e := mux.entry(q, t, class); e != nil {
+		return e.h
+	}
+	return nil
+}
+
+// entry returns the entry of the pattern that most closely matches q, for
+// the type t and class class.
+func (mux *ServeMux) entry(q string, t, class uint16) *muxEntry {
 	mux.m.RLock()
 	defer mux.m.RUnlock()
-	if mux.z == nil {
+	if mux.z == nil && mux.wild == nil {
 		return nil
 	}
 
-	var handler Handler
+	var handler *muxEntry
 
 	// TODO(tmthrgd): Once https://go-review.googlesource.com/c/go/+/137575
 	// lands in a go release, replace the following with strings.ToLower.
@@ -64,7 +128,14 @@ func (mux *ServeMux) match(q string, t uint16) Handler {
 	}
 
 	for off, end := 0, false; !end; off, end = NextLabel(q, off) {
-		if h, ok := mux.z[q[off:]]; ok {
+		h := muxLookup(mux.wild, q[off:], class)
+		if off == 0 || h == nil {
+			h = nil
+			if off == 0 || !mux.exact {
+				h = muxLookup(mux.z, q[off:], class)
+			}
+		}
+		if h != nil {
 			if t != TypeDS {
 				return h
 			}
@@ -74,38 +145,139 @@ func (mux *ServeMux) match(q string, t uint16) Handler {
 	}
 
 	// Wildcard match, if we have found nothing try the root zone as a last resort.
-	if h, ok := mux.z["."]; ok {
-		return h
+	if q != "." {
+		if h := muxLookup(mux.wild, ".", class); h != nil {
+			return h
+		}
+		if !mux.exact {
+			if h := muxLookup(mux.z, ".", class); h != nil {
+				return h
+			}
+		}
 	}
 
 	return handler
 }
 
+// muxLookup returns the entry of name for class in z, or the one for all
+// classes.
+func muxLookup(z map[muxKey]*muxEntry, name string, class uint16) *muxEntry {
+	if h, ok := z[muxKey{name, class}]; ok {
+		return h
+	}
+	return z[muxKey{name, 0}]
+}
+
 // Handle adds a handler to the ServeMux for pattern.
 func (mux *ServeMux) Handle(pattern string, handler Handler) {
+	mux.HandleClass(pattern, 0, handler)
+}
+
+// HandleClass adds a handler to the ServeMux for pattern, for the queries of
+// class only.
+func (mux *ServeMux) HandleClass(pattern string, class uint16, handler Handler) {
 	if pattern == "" {
 		panic("dns: invalid pattern " + pattern)
 	}
 	mux.m.Lock()
 	if mux.z == nil {
-		mux.z = make(map[string]Handler)
+		mux.z = make(map[muxKey]*muxEntry)
+		mux.wild = make(map[muxKey]*muxEntry)
 	}
-	mux.z[Fqdn(pattern)] = handler
+	mux.add(Route{pattern, class, handler})
 	mux.m.Unlock()
 }
 
+// add adds r to the patterns, mux.m must be held.
+func (mux *ServeMux) add(r Route) {
+	name, wild := muxPattern(r.Pattern)
+	if wild {
+		mux.wild[muxKey{name, r.Class}] = newEntry(r.Handler, mux.mw)
+		return
+	}
+	mux.z[muxKey{name, r.Class}] = newEntry(r.Handler, mux.mw)
+}
+
+// muxPattern returns the name of pattern, and whether it is a wildcard.
+func muxPattern(pattern string) (string, bool) {
+	name := strings.ToLower(Fqdn(pattern))
+	switch {
+	case name == "*.":
+		return ".", true
+	case strings.HasPrefix(name, "*."):
+		return name[2:], true
+	}
+	return name, false
+}
+
+// Replace replaces all the patterns of the ServeMux with routes at once, the
+// queries are routed with either the old or the new patterns, e.g. while a
+// configuration is reloaded.
+func (mux *ServeMux) Replace(routes []Route) {
+	for _, r := range routes {
+		if r.Pattern == "" {
+			panic("dns: invalid pattern " + r.Pattern)
+		}
+	}
+	z, wild := make(map[muxKey]*muxEntry), make(map[muxKey]*muxEntry)
+	mux.m.Lock()
+	mux.z, mux.wild = z, wild
+	for _, r := range routes {
+		mux.add(r)
+	}
+	mux.m.Unlock()
+}
+
+// SetMatch sets how the patterns that aren't wildcards match, to
+// MatchSuffix or MatchExact.
+func (mux *ServeMux) SetMatch(mode int) {
+	mux.m.Lock()
+	mux.exact = mode == MatchExact
+	mux.m.Unlock()
+}
+
+// HandleName adds a handler to the ServeMux for the name n.
+func (mux *ServeMux) HandleName(n WireName, handler Handler) {
+	mux.Handle(n.Canonical().String(), handler)
+}
+
 // HandleFunc adds a handler function to the ServeMux for pattern.
 func (mux *ServeMux) HandleFunc(pattern string, handler func(ResponseWriter, *Msg)) {
 	mux.Handle(pattern, HandlerFunc(handler))
 }
 
-// HandleRemove deregisters the handler specific for pattern from the ServeMux.
+// Use appends the middlewares mw to the chain of the ServeMux. The chain
+// wraps the handlers in the order the middlewares are added, it is built
+// when a handler is added or Use is called, not for every request.
+func (mux *ServeMux) Use(mw ...Middleware) {
+	mux.m.Lock()
+	mux.mw = append(mux.mw, mw...)
+	mux.failed = Chain(HandlerFunc(HandleFailed), mux.mw...)
+	for _, z := range []map[muxKey]*muxEntry{mux.z, mux.wild} {
+		for k, e := range z {
+			z[k] = newEntry(e.h, mux.mw)
+		}
+	}
+	mux.m.Unlock()
+}
+
+// HandleRemove deregisters the handlers specific for pattern from the ServeMux,
+// of all classes.
 func (mux *ServeMux) HandleRemove(pattern string) {
 	if pattern == "" {
 		panic("dns: invalid pattern " + pattern)
 	}
+	name, wild := muxPattern(pattern)
 	mux.m.Lock()
-	delete(mux.z, Fqdn(pattern))
+	z := mux.z
+	if wild {
+		z = mux.wild
+	}
+	for k := range z {
+		if k.name == name {
+			delete(z, k)
+		}
+	}
 	mux.m.Unlock()
 }
 
@@ -119,15 +291,35 @@ func (mux *ServeMux) HandleRemove(pattern string) {
 // If no handler is found, or there is no question, a standard SERVFAIL
 // message is returned
 func (mux *ServeMux) ServeDNS(w ResponseWriter, req *Msg) {
-	var h Handler
+	mux.ServeDNSContext(context.Background(), w, req)
+}
+
+// ServeDNSContext is like ServeDNS, but passes ctx to the handler when it
+// implements HandlerContext.
+func (mux *ServeMux) ServeDNSContext(ctx context.Context, w ResponseWriter, req *Msg) {
+	var e *muxEntry
 	if len(req.Question) >= 1 { // allow more than one question
-		h = mux.match(req.Question[0].Name, req.Question[0].Qtype)
+		q := req.Question[0]
+		e = mux.entry(q.Name, q.Qtype, q.Qclass)
 	}
 
-	if h != nil {
+	switch {
+	case e == nil:
+		mux.m.RLock()
+		h := mux.failed
+		mux.m.RUnlock()
+		if h == nil {
+			h = HandlerFunc(HandleFailed)
+		}
 		h.ServeDNS(w, req)
-	} else {
-		HandleFailed(w, req)
+	case e.chain != nil:
+		e.chain.ServeDNS(w, req)
+	default:
+		hc := e.h.(HandlerContext)
+		mux.m.RLock()
+		mw := mux.mw
+		mux.m.RUnlock()
+		Chain(HandlerFunc(func(w ResponseWriter, r *Msg) { hc.ServeDNSContext(ctx, w, r) }), mw...).ServeDNS(w, req)
 	}
 }
 
@@ -1,6 +1,9 @@
 package dns
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestDotAsCatchAllWildcard(t *testing.T) {
 	mux := NewServeMux()
@@ -70,3 +73,72 @@ func BenchmarkMuxMatch(b *testing.B) {
 	b.Run("lowercase", bench("_dns._udp.example.com."))
 	b.Run("uppercase", bench("_DNS._UDP.EXAMPLE.COM."))
 }
+
+func TestHandleName(t *testing.T) {
+	mux := NewServeMux()
+	n, _ := NewWireName("Example.COM.")
+	mux.HandleName(n, HandlerFunc(HelloServer))
+	if mux.match("www.example.com.", TypeA) == nil {
+		t.Error("example.com match failed")
+	}
+}
+
+func TestServeMuxClass(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("example.com.", HandlerFunc(HelloServer))
+	mux.HandleClass("example.com.", ClassCHAOS, HandlerFunc(AnotherHelloServer))
+
+	if h := mux.route("www.example.com.", TypeTXT, ClassCHAOS); h == nil || !sameHandler(h, AnotherHelloServer) {
+		t.Error("expected the CHAOS handler")
+	}
+	if h := mux.route("www.example.com.", TypeTXT, ClassINET); h == nil || !sameHandler(h, HelloServer) {
+		t.Error("expected the handler for all classes")
+	}
+
+	mux.HandleRemove("example.com.")
+	if h := mux.route("www.example.com.", TypeTXT, ClassCHAOS); h != nil {
+		t.Error("expected the handlers of all classes removed")
+	}
+}
+
+func TestServeMuxWildcard(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("*.example.com.", HandlerFunc(HelloServer))
+	mux.Handle("example.com.", HandlerFunc(AnotherHelloServer))
+	mux.Handle("example.net.", HandlerFunc(AnotherHelloServer))
+
+	if h := mux.match("a.b.example.com.", TypeA); h == nil || !sameHandler(h, HelloServer) {
+		t.Error("expected the wildcard handler below example.com.")
+	}
+	if h := mux.match("example.com.", TypeA); h == nil || !sameHandler(h, AnotherHelloServer) {
+		t.Error("expected the example.com. handler")
+	}
+
+	mux.SetMatch(MatchExact)
+	if mux.match("www.example.net.", TypeA) != nil {
+		t.Error("expected no match below example.net.")
+	}
+	if mux.match("example.net.", TypeA) == nil || mux.match("www.example.com.", TypeA) == nil {
+		t.Error("expected exact and wildcard matches")
+	}
+}
+
+func TestServeMuxReplace(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("example.com.", HandlerFunc(HelloServer))
+	mux.Replace([]Route{
+		{Pattern: "example.net.", Handler: HandlerFunc(HelloServer)},
+		{Pattern: "*.", Class: ClassINET, Handler: HandlerFunc(AnotherHelloServer)},
+	})
+	if h := mux.match("example.com.", TypeA); h == nil || !sameHandler(h, AnotherHelloServer) {
+		t.Error("expected the old pattern replaced by the root wildcard")
+	}
+	if h := mux.match("www.example.net.", TypeA); h == nil || !sameHandler(h, HelloServer) {
+		t.Error("expected the example.net. handler")
+	}
+}
+
+// sameHandler reports whether h is f.
+func sameHandler(h Handler, f func(ResponseWriter, *Msg)) bool {
+	return reflect.ValueOf(h).Pointer() == reflect.ValueOf(f).Pointer()
+}
@@ -52,6 +52,29 @@ func (f HandlerFunc) ServeDNS(w ResponseWriter, r *Msg) {
 	f(w, r)
 }
 
+// HandlerContext is implemented by handlers that take a context. The server
+// calls ServeDNSContext instead of ServeDNS for a Handler that implements it.
+// The context has a deadline of the server's ReadTimeout after the query was
+// read, and for TCP it is canceled when the client closes the connection.
+type HandlerContext interface {
+	ServeDNSContext(ctx context.Context, w ResponseWriter, r *Msg)
+}
+
+// The HandlerContextFunc type is an adapter to allow the use of ordinary
+// functions as context-aware DNS handlers. HandlerContextFunc(f) is both a
+// Handler and a HandlerContext that calls f.
+type HandlerContextFunc func(context.Context, ResponseWriter, *Msg)
+
+// ServeDNS calls f(context.Background(), w, r).
+func (f HandlerContextFunc) ServeDNS(w ResponseWriter, r *Msg) {
+	f(context.Background(), w, r)
+}
+
+// ServeDNSContext calls f(ctx, w, r).
+func (f HandlerContextFunc) ServeDNSContext(ctx context.Context, w ResponseWriter, r *Msg) {
+	f(ctx, w, r)
+}
+
 // A ResponseWriter interface is used by an DNS handler to
 // construct an DNS response.
 type ResponseWriter interface {
@@ -87,12 +110,28 @@ type response struct {
 	tsigTimersOnly bool
 	tsigStatus     error
 	tsigRequestMAC string
-	tsigSecret     map[string]string // the tsig secrets
-	udp            *net.UDPConn      // i/o connection if UDP was used
-	tcp            net.Conn          // i/o connection if TCP was used
-	udpSession     *SessionUDP       // oob data to get egress interface right
-	writer         Writer            // writer to output the raw DNS bits
-	wg             *sync.WaitGroup   // for gracefull shutdown
+	tsigProvider   TsigProvider
+	dnstapSink     DnstapSink      // logs the queries and responses, if set
+	queryTime      time.Time       // time the query was read, for dnstap and metrics
+	rcode          int             // rcode of the message WriteMsg writes, for metrics
+	queryLog       *QueryLogEntry  // query log entry of the query being handled, if it is logged
+	udp            *net.UDPConn    // i/o connection if UDP was used
+	tcp            net.Conn        // i/o connection if TCP was used
+	udpSession     *SessionUDP     // oob data to get egress interface right
+	proxyAddr      net.Addr        // client address of the PROXY protocol header of a udp query, if any
+	keepalive      bool            // query carried the edns-tcp-keepalive option
+	keepaliveIdle  *int64          // idle timeout advertised on tcp with edns-tcp-keepalive, accessed atomically, if set
+	nsid           bool            // query carried the NSID option, and the server has an NSID
+	edns           *ednsRequest    // EDNS0 of the query, to normalize the response with the EDNS policy of the server, if set
+	udpSize        int             // UDP size of the query, at least MinMsgSize
+	srv            *Server         // server the query was received on
+	sock           *serverSocket   // socket the query was received on
+	conn           *tcpConnState   // state shared by the concurrently handled queries of tcp, if set
+	dso            *dsoState       // DSO session of tcp, if set
+	pending        []byte          // data read from tcp while watching it, not yet consumed
+	stopWatch      func()          // stops watching tcp for the client closing it, if set
+	writer         Writer          // writer to output the raw DNS bits
+	wg             *sync.WaitGroup // for gracefull shutdown
 }
 
 // HandleFailed returns a HandlerFunc that returns SERVFAIL for every request it gets.
@@ -201,8 +240,15 @@ type Server struct {
 	WriteTimeout time.Duration
 	// TCP idle timeout for multiple queries, if nil, defaults to 8 * time.Second (RFC 5966).
 	IdleTimeout func() time.Duration
+	// If TCPKeepalive is set, responses on TCP to queries with the edns-tcp-keepalive option (RFC 7828)
+	// advertise the idle timeout it returns, which then replaces IdleTimeout for the connection. It is
+	// called with the number of open TCP connections, so the timeout can be lowered under load. A zero
+	// timeout asks the client to close the connection. Responses without an OPT record are left alone.
+	TCPKeepalive func(conns int) time.Duration
 	// Secret(s) for Tsig map[<zonename>]<base64 secret>. The zonename must be in canonical form (lowercase, fqdn, see RFC 4034 Section 6.2).
 	TsigSecret map[string]string
+	// An implementation of the TsigProvider interface. If defined it replaces TsigSecret and is used for all TSIG operations.
+	TsigProvider TsigProvider
 	// If NotifyStartedFunc is set it is called once the server has started listening.
 	NotifyStartedFunc func()
 	// DecorateReader is optional, allows customization of the process that reads raw DNS messages.
@@ -211,17 +257,67 @@ type Server struct {
 	DecorateWriter DecorateWriter
 	// Maximum number of TCP queries before we close the socket. Default is maxTCPQueries (unlimited if -1).
 	MaxTCPQueries int
+	// Maximum number of queries handled concurrently per TCP connection. When larger than 1 the queries
+	// of a connection are read while earlier ones are being handled, and the responses are written as
+	// they complete, possibly out of order (RFC 7766, Section 6.2.1.1). Defaults to 1, serial handling.
+	// A handler that hijacks the connection must set its own read deadline before reading from it.
+	TCPConcurrency int
 	// Whether to set the SO_REUSEPORT socket option, allowing multiple listeners to be bound to a single address.
 	// It is only supported on go1.11+ and when using ListenAndServe.
 	ReusePort bool
+	// Number of sockets ListenAndServe opens when ReusePort is set, each with its own read loop so
+	// the kernel spreads the queries over them. Defaults to 1.
+	ReusePortSockets int
+	// If SocketOptions is set, ListenAndServe sets them on its sockets.
+	SocketOptions *SocketOptions
 	// AcceptMsgFunc will check the incoming message and will reject it early in the process.
 	// By default DefaultMsgAcceptFunc will be used.
 	MsgAcceptFunc MsgAcceptFunc
+	// If Dnstap is set the queries and responses are logged to it as CLIENT_QUERY and CLIENT_RESPONSE messages.
+	Dnstap DnstapSink
+	// If Metrics is set every query read and every response written is reported to it.
+	Metrics ServerMetrics
+	// If QueryLog is set every query is logged to it, with its response, once the handler returned.
+	QueryLog QueryLogger
+	// If QueryLogSample is larger than 1, only one in every QueryLogSample queries is logged.
+	QueryLogSample int
+	// If DSOHandler is set, DNS Stateful Operations (RFC 8490) are enabled on TCP: the server answers the
+	// KeepAlive requests that establish sessions itself, and passes the other DSO messages to DSOHandler.
+	DSOHandler DSOHandler
+	// KeepAlive TLV sent to clients that establish a DSO session, defaults to an inactivity timeout
+	// of 15 seconds and a keepalive interval of 1 hour. The connection is closed when the client sends
+	// nothing for twice the keepalive interval.
+	DSOKeepAlive *DSOKeepAlive
+	// If ProxyProtocol is set, TCP connections from the proxies of ProxyTrusted must start with a PROXY
+	// protocol header, version 1 or 2, as load balancers send it, and ResponseWriter.RemoteAddr returns
+	// the client address it holds. With ActivateAndServe and DNS over TLS, build the TLS listener on
+	// NewProxyListener instead.
+	ProxyProtocol bool
+	// If ProxyProtocolUDP is set, UDP queries from the proxies of ProxyTrusted must start with a PROXY
+	// protocol version 2 header, which is removed, and ResponseWriter.RemoteAddr returns the client
+	// address it holds. Responses are sent to the proxy, without header. Queries without a valid header
+	// are dropped.
+	ProxyProtocolUDP bool
+	// ProxyTrusted holds the networks of the proxies whose PROXY protocol headers are believed, it is
+	// required with ProxyProtocol and ProxyProtocolUDP. Connections and queries from other peers are
+	// served as they come, without header, so they can't pass as another client.
+	ProxyTrusted []*net.IPNet
+	// If NSID is set, responses to queries with the NSID option carry it as the identifier of the server
+	// (RFC 5001), useful to tell the nodes of an anycast service apart. An OPT record is added if needed.
+	NSID string
+	// If UnpackLimits is set, queries beyond its limits are answered with FORMERR before their records
+	// are unpacked, see UnpackLimited.
+	UnpackLimits *UnpackLimits
+	// If EDNS is set, the EDNS0 of queries and responses is normalized as it describes, so handlers don't
+	// have to: queries it rejects get a FORMERR or BADVERS response without calling the handler.
+	EDNS *EDNSPolicy
 
 	// UDP packet or TCP connection queue
 	queue chan *response
 	// Workers count
 	workersCount int32
+	// Sockets being served
+	sockets []*serverSocket
 
 	// Shutdown handling
 	lock     sync.RWMutex
@@ -231,6 +327,51 @@ type Server struct {
 
 	// A pool for UDP message buffers.
 	udpPool sync.Pool
+
+	// Queries seen for sampling the query log, accessed atomically.
+	queryLogCount uint32
+}
+
+// serverSocket is a socket the server reads queries from.
+type serverSocket struct {
+	// Accessed atomically, first for alignment on 32-bit platforms.
+	queries     uint64
+	connections uint64
+
+	pc net.PacketConn // set for UDP
+	l  net.Listener   // set for TCP
+}
+
+// SocketStats holds the statistics of a socket of a Server.
+type SocketStats struct {
+	LocalAddr   net.Addr
+	Queries     uint64 // number of queries read
+	Connections uint64 // number of TCP connections accepted
+}
+
+// SocketStats returns the statistics of each of the sockets the server is
+// serving.
+func (srv *Server) SocketStats() []SocketStats {
+	srv.lock.RLock()
+	defer srv.lock.RUnlock()
+
+	stats := make([]SocketStats, len(srv.sockets))
+	for i, s := range srv.sockets {
+		stats[i].Queries = atomic.LoadUint64(&s.queries)
+		stats[i].Connections = atomic.LoadUint64(&s.connections)
+		if s.pc != nil {
+			stats[i].LocalAddr = s.pc.LocalAddr()
+		} else {
+			stats[i].LocalAddr = s.l.Addr()
+		}
+	}
+	return stats
+}
+
+// Workers returns the number of worker goroutines the server keeps to handle
+// queries.
+func (srv *Server) Workers() int {
+	return int(atomic.LoadInt32(&srv.workersCount))
 }
 
 func (srv *Server) isStarted() bool {
@@ -296,6 +437,7 @@ func (srv *Server) init() {
 
 	srv.shutdown = make(chan struct{})
 	srv.conns = make(map[net.Conn]struct{})
+	srv.sockets = nil
 
 	if srv.UDPSize == 0 {
 		srv.UDPSize = MinMsgSize
@@ -321,6 +463,9 @@ func (srv *Server) ListenAndServe() error {
 	if srv.started {
 		return &Error{err: "server already started"}
 	}
+	if (srv.ProxyProtocol || srv.ProxyProtocolUDP) && len(srv.ProxyTrusted) == 0 {
+		return errProxyTrusted
+	}
 
 	addr := srv.Addr
 	if addr == "" {
@@ -330,45 +475,56 @@ func (srv *Server) ListenAndServe() error {
 	srv.init()
 	defer close(srv.queue)
 
+	n := 1
+	if srv.ReusePort && supportsReusePort && srv.ReusePortSockets > 1 {
+		n = srv.ReusePortSockets
+	}
+
 	switch srv.Net {
-	case "tcp", "tcp4", "tcp6":
-		l, err := listenTCP(srv.Net, addr, srv.ReusePort)
-		if err != nil {
-			return err
-		}
-		srv.Listener = l
-		srv.started = true
-		unlock()
-		return srv.serveTCP(l)
-	case "tcp-tls", "tcp4-tls", "tcp6-tls":
-		if srv.TLSConfig == nil || (len(srv.TLSConfig.Certificates) == 0 && srv.TLSConfig.GetCertificate == nil) {
+	case "tcp", "tcp4", "tcp6", "tcp-tls", "tcp4-tls", "tcp6-tls":
+		tlsNet := strings.HasSuffix(srv.Net, "-tls")
+		if tlsNet && (srv.TLSConfig == nil || (len(srv.TLSConfig.Certificates) == 0 && srv.TLSConfig.GetCertificate == nil)) {
 			return errors.New("dns: neither Certificates nor GetCertificate set in Config")
 		}
 		network := strings.TrimSuffix(srv.Net, "-tls")
-		l, err := listenTCP(network, addr, srv.ReusePort)
-		if err != nil {
-			return err
+		for i := 0; i < n; i++ {
+			l, err := listenTCP(network, addr, srv.ReusePort, srv.SocketOptions)
+			if err != nil {
+				srv.closeSockets()
+				return err
+			}
+			// The other sockets bind the port the first one got.
+			addr = l.Addr().String()
+			if srv.ProxyProtocol {
+				l = NewProxyListener(l, srv.ProxyTrusted)
+			}
+			if tlsNet {
+				l = tls.NewListener(l, srv.TLSConfig)
+			}
+			srv.sockets = append(srv.sockets, &serverSocket{l: l})
 		}
-		l = tls.NewListener(l, srv.TLSConfig)
-		srv.Listener = l
-		srv.started = true
-		unlock()
-		return srv.serveTCP(l)
+		srv.Listener = srv.sockets[0].l
 	case "udp", "udp4", "udp6":
-		l, err := listenUDP(srv.Net, addr, srv.ReusePort)
-		if err != nil {
-			return err
-		}
-		u := l.(*net.UDPConn)
-		if e := setUDPSocketOptions(u); e != nil {
-			return e
+		for i := 0; i < n; i++ {
+			l, err := listenUDP(srv.Net, addr, srv.ReusePort, srv.SocketOptions)
+			if err == nil {
+				addr = l.LocalAddr().String()
+				err = setUDPSocketOptions(l.(*net.UDPConn))
+				srv.sockets = append(srv.sockets, &serverSocket{pc: l})
+			}
+			if err != nil {
+				srv.closeSockets()
+				return err
+			}
 		}
-		srv.PacketConn = l
-		srv.started = true
-		unlock()
-		return srv.serveUDP(u)
+		srv.PacketConn = srv.sockets[0].pc
+	default:
+		return &Error{err: "bad network"}
 	}
-	return &Error{err: "bad network"}
+
+	srv.started = true
+	unlock()
+	return srv.serveSockets()
 }
 
 // ActivateAndServe starts a nameserver with the PacketConn or Listener
@@ -381,6 +537,9 @@ func (srv *Server) ActivateAndServe() error {
 	if srv.started {
 		return &Error{err: "server already started"}
 	}
+	if (srv.ProxyProtocol || srv.ProxyProtocolUDP) && len(srv.ProxyTrusted) == 0 {
+		return errProxyTrusted
+	}
 
 	srv.init()
 	defer close(srv.queue)
@@ -394,15 +553,20 @@ func (srv *Server) ActivateAndServe() error {
 			if e := setUDPSocketOptions(t); e != nil {
 				return e
 			}
+			srv.sockets = []*serverSocket{{pc: t}}
 			srv.started = true
 			unlock()
-			return srv.serveUDP(t)
+			return srv.serveSockets()
 		}
 	}
 	if l != nil {
+		if srv.ProxyProtocol {
+			l = NewProxyListener(l, srv.ProxyTrusted)
+		}
+		srv.sockets = []*serverSocket{{l: l}}
 		srv.started = true
 		unlock()
-		return srv.serveTCP(l)
+		return srv.serveSockets()
 	}
 	return &Error{err: "bad listeners"}
 }
@@ -416,8 +580,13 @@ func (srv *Server) Shutdown() error {
 // ShutdownContext shuts down a server. After a call to ShutdownContext,
 // ListenAndServe and ActivateAndServe will return.
 //
+// The server stops accepting new connections and queries, and idle TCP
+// connections are closed. Queries that are being handled are allowed to
+// finish: their responses are written, with an edns-tcp-keepalive timeout of 0
+// on TCP when the query asked for keepalive, before the connection is closed.
+//
 // A context.Context may be passed to limit how long to wait for connections
-// to terminate.
+// to terminate. When it is done, the connections that are left are closed.
 func (srv *Server) ShutdownContext(ctx context.Context) error {
 	srv.lock.Lock()
 	if !srv.started {
@@ -427,12 +596,13 @@ func (srv *Server) ShutdownContext(ctx context.Context) error {
 
 	srv.started = false
 
-	if srv.PacketConn != nil {
-		srv.PacketConn.SetReadDeadline(aLongTimeAgo) // Unblock reads
-	}
-
-	if srv.Listener != nil {
-		srv.Listener.Close()
+	for _, s := range srv.sockets {
+		if s.pc != nil {
+			s.pc.SetReadDeadline(aLongTimeAgo) // Unblock reads
+		}
+		if s.l != nil {
+			s.l.Close()
+		}
 	}
 
 	for rw := range srv.conns {
@@ -450,10 +620,18 @@ func (srv *Server) ShutdownContext(ctx context.Context) error {
 	case <-srv.shutdown:
 	case <-ctx.Done():
 		ctxErr = ctx.Err()
+
+		srv.lock.Lock()
+		for rw := range srv.conns {
+			rw.Close()
+		}
+		srv.lock.Unlock()
 	}
 
-	if srv.PacketConn != nil {
-		srv.PacketConn.Close()
+	for _, s := range srv.sockets {
+		if s.pc != nil {
+			s.pc.Close()
+		}
 	}
 
 	return ctxErr
@@ -470,20 +648,62 @@ func (srv *Server) getReadTimeout() time.Duration {
 	return rtimeout
 }
 
-// serveTCP starts a TCP listener for the server.
-func (srv *Server) serveTCP(l net.Listener) error {
-	defer l.Close()
-
+// serveSockets runs the read loops of the sockets of the server, and returns
+// when they are all done and the queries they read have been handled.
+func (srv *Server) serveSockets() error {
 	if srv.NotifyStartedFunc != nil {
 		srv.NotifyStartedFunc()
 	}
 
 	var wg sync.WaitGroup
 	defer func() {
+		// UDP sockets stay open until the responses have been written.
 		wg.Wait()
+		srv.closeSockets()
 		close(srv.shutdown)
 	}()
 
+	serve := func(s *serverSocket) error {
+		var err error
+		if s.pc != nil {
+			err = srv.serveUDP(s.pc.(*net.UDPConn), s, &wg)
+		} else {
+			err = srv.serveTCP(s.l, s, &wg)
+		}
+		if err != nil {
+			// The server is broken, stop the other read loops as well.
+			srv.closeSockets()
+		}
+		return err
+	}
+
+	errs := make(chan error, len(srv.sockets))
+	for _, s := range srv.sockets[1:] {
+		go func(s *serverSocket) { errs <- serve(s) }(s)
+	}
+	err := serve(srv.sockets[0])
+	for range srv.sockets[1:] {
+		if e := <-errs; err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// closeSockets closes all the sockets of the server.
+func (srv *Server) closeSockets() {
+	for _, s := range srv.sockets {
+		if s.pc != nil {
+			s.pc.Close()
+		}
+		if s.l != nil {
+			s.l.Close()
+		}
+	}
+}
+
+// serveTCP starts a TCP listener for the server.
+func (srv *Server) serveTCP(l net.Listener, sock *serverSocket, wg *sync.WaitGroup) error {
 	for srv.isStarted() {
 		rw, err := l.Accept()
 		if err != nil {
@@ -495,40 +715,44 @@ func (srv *Server) serveTCP(l net.Listener) error {
 			}
 			return err
 		}
+		atomic.AddUint64(&sock.connections, 1)
 		srv.lock.Lock()
 		// Track the connection to allow unblocking reads on shutdown.
 		srv.conns[rw] = struct{}{}
 		srv.lock.Unlock()
 		wg.Add(1)
 		srv.spawnWorker(&response{
-			tsigSecret: srv.TsigSecret,
-			tcp:        rw,
-			wg:         &wg,
+			tsigProvider: srv.tsigProvider(),
+			dnstapSink:   srv.Dnstap,
+			srv:          srv,
+			sock:         sock,
+			tcp:          rw,
+			wg:           wg,
 		})
 	}
 
 	return nil
 }
 
-// serveUDP starts a UDP listener for the server.
-func (srv *Server) serveUDP(l *net.UDPConn) error {
-	defer l.Close()
-
-	if srv.NotifyStartedFunc != nil {
-		srv.NotifyStartedFunc()
+// tsigProvider returns the TsigProvider of srv, one wrapping its TsigSecret,
+// or nil when neither is set.
+func (srv *Server) tsigProvider() TsigProvider {
+	if srv.TsigProvider != nil {
+		return srv.TsigProvider
 	}
+	if srv.TsigSecret != nil {
+		return tsigSecretProvider(srv.TsigSecret)
+	}
+	return nil
+}
 
+// serveUDP starts a UDP listener for the server.
+func (srv *Server) serveUDP(l *net.UDPConn, sock *serverSocket, wg *sync.WaitGroup) error {
 	reader := Reader(&defaultReader{srv})
 	if srv.DecorateReader != nil {
 		reader = srv.DecorateReader(reader)
 	}
 
-	var wg sync.WaitGroup
-	defer func() {
-		wg.Wait()
-		close(srv.shutdown)
-	}()
-
 	rtimeout := srv.getReadTimeout()
 	// deadline is not used here
 	for srv.isStarted() {
@@ -542,6 +766,19 @@ func (srv *Server) serveUDP(l *net.UDPConn) error {
 			}
 			return err
 		}
+		var proxyAddr net.Addr
+		if srv.ProxyProtocolUDP && proxyTrusted(srv.ProxyTrusted, s.RemoteAddr()) {
+			ip, port, n, err := parseProxyV2(m)
+			if err == nil {
+				// Keep the start of the buffer, it goes back to the pool.
+				m = m[:copy(m, m[n:])]
+				if ip != nil {
+					proxyAddr = &net.UDPAddr{IP: ip, Port: port}
+				}
+			} else {
+				m = m[:0]
+			}
+		}
 		if len(m) < headerSize {
 			if cap(m) == srv.UDPSize {
 				srv.udpPool.Put(m[:srv.UDPSize])
@@ -550,11 +787,15 @@ func (srv *Server) serveUDP(l *net.UDPConn) error {
 		}
 		wg.Add(1)
 		srv.spawnWorker(&response{
-			msg:        m,
-			tsigSecret: srv.TsigSecret,
-			udp:        l,
-			udpSession: s,
-			wg:         &wg,
+			msg:          m,
+			tsigProvider: srv.tsigProvider(),
+			dnstapSink:   srv.Dnstap,
+			srv:          srv,
+			sock:         sock,
+			udp:          l,
+			udpSession:   s,
+			proxyAddr:    proxyAddr,
+			wg:           wg,
 		})
 	}
 
@@ -599,15 +840,31 @@ func (srv *Server) serve(w *response) {
 	}
 
 	timeout := srv.getReadTimeout()
+	if srv.DSOHandler != nil {
+		w.dso = new(dsoState)
+	}
+	if srv.TCPKeepalive != nil {
+		w.keepaliveIdle = new(int64)
+	}
 
 	limit := srv.MaxTCPQueries
 	if limit == 0 {
 		limit = maxTCPQueries
 	}
 
-	for q := 0; (q < limit || limit == -1) && srv.isStarted(); q++ {
+	if srv.TCPConcurrency > 1 {
+		srv.serveTCPConcurrent(w, reader, timeout, idleTimeout, limit)
+		return
+	}
+
+	for q := 0; (q < limit || limit == -1 || w.dso.isEstablished()) && srv.isStarted(); q++ {
 		var err error
-		w.msg, err = reader.ReadTCP(w.tcp, timeout)
+		conn := w.tcp
+		if len(w.pending) > 0 {
+			conn = &pendingConn{Conn: w.tcp, pending: w.pending}
+			w.pending = nil
+		}
+		w.msg, err = reader.ReadTCP(conn, timeout)
 		if err != nil {
 			// TODO(tmthrgd): handle error
 			break
@@ -621,8 +878,75 @@ func (srv *Server) serve(w *response) {
 		}
 		// The first read uses the read timeout, the rest use the
 		// idle timeout.
-		timeout = idleTimeout
+		timeout = w.dso.idleTimeout(w.idleTimeout(idleTimeout))
+	}
+}
+
+// tcpConnState is the state shared by the queries of a TCP connection that are
+// handled concurrently.
+type tcpConnState struct {
+	mu       sync.Mutex      // serializes the writes of responses
+	ctx      context.Context // canceled when the client closes the connection
+	hijacked int32           // accessed atomically
+}
+
+// serveTCPConcurrent reads the queries of the TCP connection of w, and handles
+// up to srv.TCPConcurrency of them at the same time, each with its own
+// response. It returns when the connection is done and the handlers returned.
+func (srv *Server) serveTCPConcurrent(w *response, reader Reader, timeout, idleTimeout time.Duration, limit int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := &tcpConnState{ctx: ctx}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, srv.TCPConcurrency)
+	for q := 0; (q < limit || limit == -1 || w.dso.isEstablished()) && srv.isStarted(); q++ {
+		m, err := reader.ReadTCP(w.tcp, timeout)
+		if err != nil {
+			// Queries in flight are still answered when the connection is
+			// idle or the server shuts down, but not when the client is gone.
+			if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+				cancel()
+			}
+			break
+		}
+		if atomic.LoadInt32(&conn.hijacked) != 0 {
+			break
+		}
+
+		sem <- struct{}{}
+		rw := &response{
+			msg:           m,
+			tsigProvider:  w.tsigProvider,
+			dnstapSink:    w.dnstapSink,
+			srv:           srv,
+			sock:          w.sock,
+			tcp:           w.tcp,
+			conn:          conn,
+			dso:           w.dso,
+			keepaliveIdle: w.keepaliveIdle,
+			wg:            w.wg,
+		}
+		rw.writer = rw
+		if srv.DecorateWriter != nil {
+			rw.writer = srv.DecorateWriter(rw)
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			srv.serveDNS(rw)
+		}()
+
+		// The first read uses the read timeout, the rest use the
+		// idle timeout.
+		timeout = w.dso.idleTimeout(w.idleTimeout(idleTimeout))
 	}
+
+	wg.Wait()
+	w.hijacked = atomic.LoadInt32(&conn.hijacked) != 0
 }
 
 func (srv *Server) disposeBuffer(w *response) {
@@ -638,9 +962,30 @@ func (srv *Server) serveDNS(w *response) {
 		// Let client hang, they are sending crap; any reply can be used to amplify.
 		return
 	}
+	if w.sock != nil {
+		atomic.AddUint64(&w.sock.queries, 1)
+	}
+	if w.dnstapSink != nil || srv.Metrics != nil || srv.QueryLog != nil {
+		w.queryTime = time.Now()
+	}
+	if w.dnstapSink != nil {
+		w.dnstap(w.msg, true)
+	}
+	if srv.Metrics != nil {
+		w.queryMetric(dh, off)
+	}
+
+	if isDSO(dh) && w.dso != nil {
+		srv.serveDSO(w)
+		return
+	}
 
 	req := new(Msg)
 	req.setHdr(dh)
+	if srv.QueryLog != nil && srv.sampleQuery() {
+		w.queryLog = &QueryLogEntry{Rcode: -1}
+		defer w.logQuery(req)
+	}
 
 	switch srv.MsgAcceptFunc(dh) {
 	case MsgAccept:
@@ -656,7 +1001,18 @@ func (srv *Server) serveDNS(w *response) {
 		return
 	}
 
-	if err := req.unpack(dh, w.msg, off); err != nil {
+	if srv.UnpackLimits != nil {
+		if err := checkLimits(w.msg, srv.UnpackLimits); err != nil {
+			req.SetRcodeFormatError(req)
+			req.Ns, req.Answer, req.Extra = nil, nil, nil
+
+			w.WriteMsg(req)
+			srv.disposeBuffer(w)
+			return
+		}
+	}
+
+	if err := req.unpack(dh, w.msg, off, false); err != nil {
 		req.SetRcodeFormatError(req)
 		req.Ns, req.Answer, req.Extra = nil, nil, nil
 
@@ -665,14 +1021,36 @@ func (srv *Server) serveDNS(w *response) {
 		return
 	}
 
+	w.edns = nil
+	if srv.EDNS != nil {
+		var m *Msg
+		if w.edns, m = srv.EDNS.request(req); m != nil {
+			w.WriteMsg(m)
+			srv.disposeBuffer(w)
+			return
+		}
+	}
+
+	w.keepalive, w.nsid = false, false
+	w.udpSize = MinMsgSize
+	if opt := req.IsEdns0(); opt != nil {
+		if size := int(opt.UDPSize()); size > MinMsgSize {
+			w.udpSize = size
+		}
+		for _, o := range opt.Option {
+			switch o.Option() {
+			case EDNS0TCPKEEPALIVE:
+				w.keepalive = w.tcp != nil
+			case EDNS0NSID:
+				w.nsid = srv.NSID != ""
+			}
+		}
+	}
+
 	w.tsigStatus = nil
-	if w.tsigSecret != nil {
+	if w.tsigProvider != nil {
 		if t := req.IsTsig(); t != nil {
-			if secret, ok := w.tsigSecret[t.Hdr.Name]; ok {
-				w.tsigStatus = TsigVerify(w.msg, secret, "", false)
-			} else {
-				w.tsigStatus = ErrSecret
-			}
+			w.tsigStatus = TsigVerifyWithProvider(w.msg, w.tsigProvider, "", false)
 			w.tsigTimersOnly = false
 			w.tsigRequestMAC = req.Extra[len(req.Extra)-1].(*TSIG).MAC
 		}
@@ -685,9 +1063,93 @@ func (srv *Server) serveDNS(w *response) {
 		handler = DefaultServeMux
 	}
 
+	if hc, ok := handler.(HandlerContext); ok {
+		ctx, cancel := srv.handlerContext(w)
+		hc.ServeDNSContext(ctx, w, req) // Writes back to the client
+		cancel()
+		return
+	}
+
 	handler.ServeDNS(w, req) // Writes back to the client
 }
 
+// handlerContext returns the context for the handler serving the query just
+// read by w. For TCP the connection is watched while the handler runs, and the
+// context is canceled when the client closes it.
+func (srv *Server) handlerContext(w *response) (context.Context, context.CancelFunc) {
+	if w.conn != nil {
+		// The connection is already read while the handler runs.
+		return context.WithTimeout(w.conn.ctx, srv.getReadTimeout())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), srv.getReadTimeout())
+	if w.tcp == nil {
+		return ctx, cancel
+	}
+
+	// As with readTCP, don't override a deadline set by ShutdownContext.
+	srv.lock.RLock()
+	started := srv.started
+	if started {
+		w.tcp.SetReadDeadline(time.Time{})
+	}
+	srv.lock.RUnlock()
+	if !started {
+		return ctx, cancel
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b := make([]byte, 1)
+		n, err := w.tcp.Read(b)
+		if n > 0 {
+			// The client sent the next query, keep it for the next read.
+			w.pending = b[:n]
+			return
+		}
+		if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+			return // stopped
+		}
+		cancel()
+	}()
+
+	var once sync.Once
+	w.stopWatch = func() {
+		once.Do(func() {
+			srv.lock.RLock()
+			if srv.started {
+				w.tcp.SetReadDeadline(aLongTimeAgo)
+			}
+			srv.lock.RUnlock()
+			<-done
+		})
+	}
+	return ctx, func() {
+		w.stopWatch()
+		w.stopWatch = nil
+		cancel()
+	}
+}
+
+// pendingConn is a net.Conn that first returns the data already read from it.
+type pendingConn struct {
+	net.Conn
+	pending []byte
+}
+
+func (c *pendingConn) Read(b []byte) (int, error) {
+	if len(c.pending) == 0 {
+		return c.Conn.Read(b)
+	}
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	if n == len(b) {
+		return n, nil
+	}
+	m, err := c.Conn.Read(b[n:])
+	return n + m, err
+}
+
 func (srv *Server) readTCP(conn net.Conn, timeout time.Duration) ([]byte, error) {
 	// If we race with ShutdownContext, the read deadline may
 	// have been set in the distant past to unblock the read
@@ -755,11 +1217,27 @@ func (w *response) WriteMsg(m *Msg) (err error) {
 	if w.closed {
 		return &Error{err: "WriteMsg called after Close"}
 	}
+	if w.edns != nil {
+		w.srv.EDNS.response(m, w.edns)
+	}
+	if w.keepalive && !w.srv.isStarted() {
+		keepaliveClose(m)
+	} else if w.keepalive && w.keepaliveIdle != nil {
+		w.advertiseKeepalive(m)
+	}
+	if w.nsid {
+		setNSID(m, w.srv.NSID)
+	}
+	w.rcode = m.Rcode
 
 	var data []byte
-	if w.tsigSecret != nil { // if no secrets, dont check for the tsig (which is a longer check)
+	if w.tsigProvider != nil { // if no provider, dont check for the tsig (which is a longer check)
 		if t := m.IsTsig(); t != nil {
-			data, w.tsigRequestMAC, err = TsigGenerate(m, w.tsigSecret[t.Hdr.Name], w.tsigRequestMAC, w.tsigTimersOnly)
+			if t.MACSize == 0 && w.tsigRequestMAC != "" {
+				// Truncate the MAC as much as the one of the request.
+				t.MACSize = uint16(len(w.tsigRequestMAC) / 2)
+			}
+			data, w.tsigRequestMAC, err = TsigGenerateWithProvider(m, w.tsigProvider, w.tsigRequestMAC, w.tsigTimersOnly)
 			if err != nil {
 				return err
 			}
@@ -775,11 +1253,78 @@ func (w *response) WriteMsg(m *Msg) (err error) {
 	return err
 }
 
+// advertiseKeepalive sets the edns-tcp-keepalive option in m to the idle
+// timeout the TCPKeepalive function of the server returns, and uses it as
+// the idle timeout of the connection.
+func (w *response) advertiseKeepalive(m *Msg) {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return
+	}
+	w.srv.lock.RLock()
+	conns := len(w.srv.conns)
+	w.srv.lock.RUnlock()
+	idle := w.srv.TCPKeepalive(conns)
+	timeout := idle / (100 * time.Millisecond)
+	if timeout > 0xFFFF {
+		timeout = 0xFFFF
+	}
+	atomic.StoreInt64(w.keepaliveIdle, int64(timeout*100*time.Millisecond))
+	for _, o := range opt.Option {
+		if e, ok := o.(*EDNS0_TCP_KEEPALIVE); ok {
+			e.Length, e.Timeout = 2, uint16(timeout)
+			return
+		}
+	}
+	opt.Option = append(opt.Option, &EDNS0_TCP_KEEPALIVE{Code: EDNS0TCPKEEPALIVE, Length: 2, Timeout: uint16(timeout)})
+}
+
+// idleTimeout returns the idle timeout advertised on the connection of w with
+// edns-tcp-keepalive, or def if none was.
+func (w *response) idleTimeout(def time.Duration) time.Duration {
+	if w.keepaliveIdle == nil {
+		return def
+	}
+	if idle := atomic.LoadInt64(w.keepaliveIdle); idle > 0 {
+		return time.Duration(idle)
+	}
+	return def
+}
+
+// keepaliveClose sets the edns-tcp-keepalive option in m to a timeout of 0,
+// which signals the client to close the connection (RFC 7828, Section 3.3.2).
+// Responses without an OPT record are left alone.
+func keepaliveClose(m *Msg) {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return
+	}
+	for _, o := range opt.Option {
+		if e, ok := o.(*EDNS0_TCP_KEEPALIVE); ok {
+			e.Length, e.Timeout = 2, 0
+			return
+		}
+	}
+	opt.Option = append(opt.Option, &EDNS0_TCP_KEEPALIVE{Code: EDNS0TCPKEEPALIVE, Length: 2})
+}
+
 // Write implements the ResponseWriter.Write method.
 func (w *response) Write(m []byte) (int, error) {
 	if w.closed {
 		return 0, &Error{err: "Write called after Close"}
 	}
+	if w.dnstapSink != nil {
+		w.dnstap(m, false)
+	}
+	if w.srv.Metrics != nil || w.queryLog != nil {
+		rcode := w.responseRcode(m)
+		if w.srv.Metrics != nil {
+			w.responseMetric(m, rcode)
+		}
+		if w.queryLog != nil {
+			w.logResponse(m, rcode)
+		}
+	}
 
 	switch {
 	case w.udp != nil:
@@ -797,6 +1342,11 @@ func (w *response) Write(m []byte) (int, error) {
 		binary.BigEndian.PutUint16(l, uint16(lm))
 		m = append(l, m...)
 
+		if w.conn != nil {
+			w.conn.mu.Lock()
+			defer w.conn.mu.Unlock()
+		}
+
 		n, err := io.Copy(w.tcp, bytes.NewReader(m))
 		return int(n), err
 	default:
@@ -819,6 +1369,8 @@ func (w *response) LocalAddr() net.Addr {
 // RemoteAddr implements the ResponseWriter.RemoteAddr method.
 func (w *response) RemoteAddr() net.Addr {
 	switch {
+	case w.proxyAddr != nil:
+		return w.proxyAddr
 	case w.udpSession != nil:
 		return w.udpSession.RemoteAddr()
 	case w.tcp != nil:
@@ -835,7 +1387,18 @@ func (w *response) TsigStatus() error { return w.tsigStatus }
 func (w *response) TsigTimersOnly(b bool) { w.tsigTimersOnly = b }
 
 // Hijack implements the ResponseWriter.Hijack method.
-func (w *response) Hijack() { w.hijacked = true }
+func (w *response) Hijack() {
+	if w.stopWatch != nil {
+		w.stopWatch()
+	}
+	if w.conn != nil {
+		// Unblock the read of the next query, the connection is no
+		// longer read by the server.
+		atomic.StoreInt32(&w.conn.hijacked, 1)
+		w.tcp.SetReadDeadline(aLongTimeAgo)
+	}
+	w.hijacked = true
+}
 
 // Close implements the ResponseWriter.Close method
 func (w *response) Close() error {
@@ -855,6 +1418,20 @@ func (w *response) Close() error {
 	}
 }
 
+// MaxMsgSize implements the MsgStreamer.MaxMsgSize() interface.
+func (w *response) MaxMsgSize() int {
+	if w.tcp != nil {
+		return MaxMsgSize
+	}
+	if w.udpSize < MinMsgSize {
+		return MinMsgSize
+	}
+	return w.udpSize
+}
+
+// CanStream implements the MsgStreamer.CanStream() interface.
+func (w *response) CanStream() bool { return w.tcp != nil }
+
 // ConnectionState() implements the ConnectionStater.ConnectionState() interface.
 func (w *response) ConnectionState() *tls.ConnectionState {
 	type tlsConnectionStater interface {
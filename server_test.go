@@ -95,7 +95,7 @@ func RunLocalTCPServer(laddr string) (*Server, string, error) {
 	return server, l, err
 }
 
-func RunLocalTCPServerWithFinChan(laddr string) (*Server, string, chan error, error) {
+func RunLocalTCPServerWithFinChan(laddr string, opts ...func(*Server)) (*Server, string, chan error, error) {
 	l, err := net.Listen("tcp", laddr)
 	if err != nil {
 		return nil, "", nil, err
@@ -111,6 +111,10 @@ func RunLocalTCPServerWithFinChan(laddr string) (*Server, string, chan error, er
 	// why fin must be buffered.
 	fin := make(chan error, 1)
 
+	for _, opt := range opts {
+		opt(server)
+	}
+
 	go func() {
 		fin <- server.ActivateAndServe()
 		l.Close()
@@ -958,6 +962,37 @@ func TestServerRoundtripTsig(t *testing.T) {
 	}
 }
 
+func TestServerRoundtripTsigProvider(t *testing.T) {
+	provider := xorTsigProvider(7)
+
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan(":0", func(srv *Server) {
+		srv.TsigProvider = provider
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	HandleFunc("example.net.", func(w ResponseWriter, r *Msg) {
+		m := new(Msg)
+		m.SetReply(r)
+		if status := w.TsigStatus(); r.IsTsig() == nil || status != nil {
+			t.Errorf("invalid TSIG: %v", status)
+		}
+		m.SetTsig("test.", "xor.example.", 300, time.Now().Unix())
+		w.WriteMsg(m)
+	})
+	defer HandleRemove("example.net.")
+
+	c := &Client{TsigProvider: provider}
+	m := new(Msg)
+	m.SetQuestion("example.net.", TypeSOA)
+	m.SetTsig("test.", "xor.example.", 300, time.Now().Unix())
+	if _, _, err := c.Exchange(m, addrstr); err != nil {
+		t.Fatal("failed to exchange", err)
+	}
+}
+
 func TestResponseAfterClose(t *testing.T) {
 	testError := func(name string, err error) {
 		t.Helper()
@@ -1092,3 +1127,363 @@ zDCJkckCgYEAndqM5KXGk5xYo+MAA1paZcbTUXwaWwjLU+XSRSSoyBEi5xMtfvUb
 kFsxKCqxAnBVGEWAvVZAiiTOxleQFjz5RnL0BQp9Lg2cQe+dvuUmIAA=
 -----END RSA PRIVATE KEY-----`)
 )
+
+func TestServingHandlerContext(t *testing.T) {
+	handler := HandlerContextFunc(func(ctx context.Context, w ResponseWriter, req *Msg) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected a deadline on the handler context")
+		}
+		HelloServer(w, req)
+	})
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = handler
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	r, err := Exchange(m, addrstr)
+	if err != nil || len(r.Extra) == 0 {
+		t.Fatal("failed to exchange miek.nl", err)
+	}
+	if txt := r.Extra[0].(*TXT).Txt[0]; txt != "Hello world" {
+		t.Error("unexpected result for miek.nl", txt, "!= Hello world")
+	}
+}
+
+func TestServingHandlerContextCanceled(t *testing.T) {
+	canceled := make(chan error, 1)
+	mux := NewServeMux()
+	mux.Handle("miek.nl.", HandlerContextFunc(func(ctx context.Context, w ResponseWriter, req *Msg) {
+		<-ctx.Done()
+		canceled <- ctx.Err()
+	}))
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = mux
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	co, err := DialTimeout("tcp", addrstr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	if err := co.WriteMsg(m); err != nil {
+		t.Fatal(err)
+	}
+	co.Close()
+
+	select {
+	case err := <-canceled:
+		if err != context.Canceled {
+			t.Errorf("expected context to be canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("context not canceled after the client closed the connection")
+	}
+}
+
+func TestServingHandlerContextPipelined(t *testing.T) {
+	handler := HandlerContextFunc(func(ctx context.Context, w ResponseWriter, req *Msg) {
+		time.Sleep(10 * time.Millisecond) // let the next query arrive
+		HelloServer(w, req)
+	})
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = handler
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	co, err := DialTimeout("tcp", addrstr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer co.Close()
+	co.SetDeadline(time.Now().Add(5 * time.Second))
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	for i := uint16(1); i <= 2; i++ {
+		m.Id = i
+		if err := co.WriteMsg(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := uint16(1); i <= 2; i++ {
+		r, err := co.ReadMsg()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Id != i {
+			t.Errorf("expected response %d, got %d", i, r.Id)
+		}
+	}
+}
+
+func TestShutdownKeepaliveClose(t *testing.T) {
+	handling := make(chan struct{})
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = HandlerFunc(func(w ResponseWriter, req *Msg) {
+			close(handling)
+			for srv.isStarted() {
+				time.Sleep(time.Millisecond)
+			}
+			m := new(Msg)
+			m.SetReply(req)
+			m.SetEdns0(4096, false)
+			w.WriteMsg(m)
+		})
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+
+	co, err := DialTimeout("tcp", addrstr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer co.Close()
+	co.SetDeadline(time.Now().Add(5 * time.Second))
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	m.SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &EDNS0_TCP_KEEPALIVE{Code: EDNS0TCPKEEPALIVE})
+	if err := co.WriteMsg(m); err != nil {
+		t.Fatal(err)
+	}
+
+	<-handling
+	done := make(chan error)
+	go func() { done <- s.Shutdown() }()
+
+	r, err := co.ReadMsg()
+	if err != nil {
+		t.Fatalf("in-flight query not answered: %v", err)
+	}
+	var ka *EDNS0_TCP_KEEPALIVE
+	if opt := r.IsEdns0(); opt != nil && len(opt.Option) == 1 {
+		ka, _ = opt.Option[0].(*EDNS0_TCP_KEEPALIVE)
+	}
+	if ka == nil || ka.Length != 2 || ka.Timeout != 0 {
+		t.Errorf("expected keepalive close signal, got %v", r.Extra)
+	}
+	if _, err := co.ReadMsg(); err != io.EOF {
+		t.Errorf("expected connection to be closed, got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("could not shutdown test TCP server, %v", err)
+	}
+}
+
+func TestServerTCPKeepalive(t *testing.T) {
+	conns := make(chan int, 1)
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = HandlerFunc(func(w ResponseWriter, req *Msg) {
+			m := new(Msg)
+			m.SetReply(req)
+			m.SetEdns0(4096, false)
+			w.WriteMsg(m)
+		})
+		srv.TCPKeepalive = func(n int) time.Duration {
+			conns <- n
+			return 200 * time.Millisecond
+		}
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	co, err := DialTimeout("tcp", addrstr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer co.Close()
+	co.SetDeadline(time.Now().Add(5 * time.Second))
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	m.SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &EDNS0_TCP_KEEPALIVE{Code: EDNS0TCPKEEPALIVE})
+	if err := co.WriteMsg(m); err != nil {
+		t.Fatal(err)
+	}
+	r, err := co.ReadMsg()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := <-conns; n != 1 {
+		t.Errorf("expected 1 open connection, got %d", n)
+	}
+	var ka *EDNS0_TCP_KEEPALIVE
+	if opt := r.IsEdns0(); opt != nil && len(opt.Option) == 1 {
+		ka, _ = opt.Option[0].(*EDNS0_TCP_KEEPALIVE)
+	}
+	if ka == nil || ka.Length != 2 || ka.Timeout != 2 {
+		t.Errorf("expected keepalive timeout of 200ms, got %v", r.Extra)
+	}
+
+	// The advertised timeout replaces the default idle timeout of 8 seconds.
+	start := time.Now()
+	if _, err := co.ReadMsg(); err != io.EOF {
+		t.Errorf("expected connection to be closed, got %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("expected connection to be closed after 200ms, took %v", d)
+	}
+}
+
+func TestShutdownContextDeadline(t *testing.T) {
+	handling, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = HandlerFunc(func(w ResponseWriter, req *Msg) {
+			close(handling)
+			<-release
+		})
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+
+	co, err := DialTimeout("tcp", addrstr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer co.Close()
+	co.SetDeadline(time.Now().Add(5 * time.Second))
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	if err := co.WriteMsg(m); err != nil {
+		t.Fatal(err)
+	}
+	<-handling
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.ShutdownContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if _, err := co.ReadMsg(); err != io.EOF {
+		t.Errorf("expected connection to be closed, got %v", err)
+	}
+}
+
+func TestReusePortSockets(t *testing.T) {
+	if !supportsReusePort {
+		t.Skip("reuseport is not supported")
+	}
+	HandleFunc("miek.nl.", HelloServer)
+	defer HandleRemove("miek.nl.")
+
+	for _, network := range []string{"udp", "tcp"} {
+		srv := &Server{Addr: "127.0.0.1:0", Net: network, ReusePort: true, ReusePortSockets: 4}
+		waitLock := sync.Mutex{}
+		waitLock.Lock()
+		srv.NotifyStartedFunc = waitLock.Unlock
+		fin := make(chan error, 1)
+		go func() { fin <- srv.ListenAndServe() }()
+		waitLock.Lock()
+
+		stats := srv.SocketStats()
+		if len(stats) != 4 {
+			t.Fatalf("%s: expected 4 sockets, got %d", network, len(stats))
+		}
+		addr := stats[0].LocalAddr.String()
+		for _, s := range stats[1:] {
+			if s.LocalAddr.String() != addr {
+				t.Errorf("%s: expected all sockets on %s, got %s", network, addr, s.LocalAddr)
+			}
+		}
+
+		const queries = 20
+		c := &Client{Net: network}
+		m := new(Msg)
+		m.SetQuestion("miek.nl.", TypeTXT)
+		for i := 0; i < queries; i++ {
+			if _, _, err := c.Exchange(m, addr); err != nil {
+				t.Fatalf("%s: failed to exchange: %v", network, err)
+			}
+		}
+
+		var total, conns uint64
+		for _, s := range srv.SocketStats() {
+			total += s.Queries
+			conns += s.Connections
+		}
+		if total != queries {
+			t.Errorf("%s: expected %d queries, got %d", network, queries, total)
+		}
+		if network == "tcp" && conns != queries {
+			t.Errorf("%s: expected %d connections, got %d", network, queries, conns)
+		}
+		if srv.Workers() < 0 {
+			t.Errorf("%s: bad worker count %d", network, srv.Workers())
+		}
+
+		if err := srv.Shutdown(); err != nil {
+			t.Fatalf("%s: could not shutdown: %v", network, err)
+		}
+		if err := <-fin; err != nil {
+			t.Errorf("%s: error returned from ListenAndServe: %v", network, err)
+		}
+	}
+}
+
+func TestServingTCPConcurrency(t *testing.T) {
+	handler := HandlerFunc(func(w ResponseWriter, req *Msg) {
+		// The first query is answered last.
+		if req.Id == 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		HelloServer(w, req)
+	})
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = handler
+		srv.TCPConcurrency = 4
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	co, err := DialTimeout("tcp", addrstr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer co.Close()
+	co.SetDeadline(time.Now().Add(5 * time.Second))
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	for i := uint16(1); i <= 3; i++ {
+		m.Id = i
+		if err := co.WriteMsg(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var ids []uint16
+	for i := 0; i < 3; i++ {
+		r, err := co.ReadMsg()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, r.Id)
+	}
+	if ids[2] != 1 {
+		t.Errorf("expected the response to the first query last, got %v", ids)
+	}
+}
@@ -2,11 +2,7 @@ package dns
 
 import (
 	"crypto"
-	"crypto/dsa"
-	"crypto/ecdsa"
-	"crypto/rsa"
 	"encoding/binary"
-	"math/big"
 	"strings"
 	"time"
 )
@@ -43,18 +39,16 @@ func (rr *SIG) Sign(k crypto.Signer, m *Msg) ([]byte, error) {
 	}
 	buf = buf[:off:cap(buf)]
 
-	hash, ok := AlgorithmToHash[rr.Algorithm]
+	alg, ok := signatureAlgorithms[rr.Algorithm]
 	if !ok {
 		return nil, ErrAlg
 	}
 
-	hasher := hash.New()
-	// Write SIG rdata
-	hasher.Write(buf[len(mbuf)+1+2+2+4+2:])
-	// Write message
-	hasher.Write(buf[:len(mbuf)])
+	// Sign the SIG rdata followed by the message
+	signdata := append([]byte(nil), buf[len(mbuf)+1+2+2+4+2:]...)
+	signdata = append(signdata, buf[:len(mbuf)]...)
 
-	signature, err := sign(k, hasher.Sum(nil), hash, rr.Algorithm)
+	signature, err := alg.sign(k, signdata)
 	if err != nil {
 		return nil, err
 	}
@@ -87,20 +81,13 @@ func (rr *SIG) Verify(k *KEY, buf []byte) error {
 		return ErrKey
 	}
 
-	var hash crypto.Hash
-	switch rr.Algorithm {
-	case DSA, RSASHA1:
-		hash = crypto.SHA1
-	case RSASHA256, ECDSAP256SHA256:
-		hash = crypto.SHA256
-	case ECDSAP384SHA384:
-		hash = crypto.SHA384
-	case RSASHA512:
-		hash = crypto.SHA512
-	default:
+	alg, ok := signatureAlgorithms[rr.Algorithm]
+	if !ok {
 		return ErrAlg
 	}
-	hasher := hash.New()
+	if k.Algorithm != rr.Algorithm {
+		return ErrKeyAlg
+	}
 
 	buflen := len(buf)
 	qdc := binary.BigEndian.Uint16(buf[4:])
@@ -171,47 +158,11 @@ func (rr *SIG) Verify(k *KEY, buf []byte) error {
 		return &Error{err: "signer name doesn't match key name"}
 	}
 	sigend := offset
-	hasher.Write(buf[sigstart:sigend])
-	hasher.Write(buf[:10])
-	hasher.Write([]byte{
-		byte((adc - 1) << 8),
-		byte(adc - 1),
-	})
-	hasher.Write(buf[12:bodyend])
+	signeddata := make([]byte, 0, sigend-sigstart+bodyend)
+	signeddata = append(signeddata, buf[sigstart:sigend]...)
+	signeddata = append(signeddata, buf[:10]...)
+	signeddata = append(signeddata, byte((adc-1)>>8), byte(adc-1))
+	signeddata = append(signeddata, buf[12:bodyend]...)
 
-	hashed := hasher.Sum(nil)
-	sig := buf[sigend:]
-	switch k.Algorithm {
-	case DSA:
-		pk := k.publicKeyDSA()
-		sig = sig[1:]
-		r := big.NewInt(0)
-		r.SetBytes(sig[:len(sig)/2])
-		s := big.NewInt(0)
-		s.SetBytes(sig[len(sig)/2:])
-		if pk != nil {
-			if dsa.Verify(pk, hashed, r, s) {
-				return nil
-			}
-			return ErrSig
-		}
-	case RSASHA1, RSASHA256, RSASHA512:
-		pk := k.publicKeyRSA()
-		if pk != nil {
-			return rsa.VerifyPKCS1v15(pk, hash, hashed, sig)
-		}
-	case ECDSAP256SHA256, ECDSAP384SHA384:
-		pk := k.publicKeyECDSA()
-		r := big.NewInt(0)
-		r.SetBytes(sig[:len(sig)/2])
-		s := big.NewInt(0)
-		s.SetBytes(sig[len(sig)/2:])
-		if pk != nil {
-			if ecdsa.Verify(pk, hashed, r, s) {
-				return nil
-			}
-			return ErrSig
-		}
-	}
-	return ErrKeyAlg
+	return alg.verify(&k.DNSKEY, signeddata, buf[sigend:])
 }
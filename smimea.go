@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/hex"
+	"strings"
 )
 
 // Sign creates a SMIMEA record from an SSL certificate.
@@ -45,3 +46,40 @@ func SMIMEAName(email, domain string) (string, error) {
 	// left-most label in the prepared domain name"
 	return hex.EncodeToString(hasher.Sum(nil)[:28]) + "." + "_smimecert." + domain, nil
 }
+
+// SMIMEAAddressName returns the ownername of the SMIMEA resource records of
+// the email address, e.g. "hugh@example.com", see RFC 8162, Section 3.
+func SMIMEAAddressName(address string) (string, error) {
+	local, domain, err := splitAddress(address)
+	if err != nil {
+		return "", err
+	}
+	return SMIMEAName(local, domain)
+}
+
+// NewSMIMEA returns the SMIMEA record of cert for the email address.
+func NewSMIMEA(address string, usage, selector, matchingType int, cert *x509.Certificate) (*SMIMEA, error) {
+	name, err := SMIMEAAddressName(address)
+	if err != nil {
+		return nil, err
+	}
+	r := &SMIMEA{Hdr: RR_Header{Name: name, Class: ClassINET}}
+	if err := r.Sign(usage, selector, matchingType, cert); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// splitAddress splits the email address in its local-part and its domain,
+// which is made fully qualified.
+func splitAddress(address string) (local, domain string, err error) {
+	i := strings.LastIndexByte(address, '@')
+	if i <= 0 || i == len(address)-1 {
+		return "", "", &Error{err: "bad email address: " + address}
+	}
+	domain = Fqdn(address[i+1:])
+	if _, ok := IsDomainName(domain); !ok {
+		return "", "", &Error{err: "bad email address: " + address}
+	}
+	return address[:i], domain, nil
+}
@@ -0,0 +1,23 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSMIMEA(t *testing.T) {
+	cert, _ := newTestCert(t, "hugh@example.com", false, time.Now().Add(time.Hour), nil, nil)
+	rr, err := NewSMIMEA("hugh@example.com", 3, 1, 1, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._smimecert.example.com."; rr.Hdr.Name != want {
+		t.Errorf("expected %s, got %s", want, rr.Hdr.Name)
+	}
+	if err := rr.Verify(cert); err != nil {
+		t.Errorf("failed to verify: %v", err)
+	}
+	if _, err := NewSMIMEA("example.com", 3, 1, 1, cert); err == nil {
+		t.Error("expected an error for an address without local-part")
+	}
+}
@@ -0,0 +1,19 @@
+package dns
+
+// SocketOptions are IP options of the sockets of a Client or a Server, for
+// QoS and policy routing. The zero value leaves the sockets as they are.
+//
+// The options are set by the Control method, which can also be used as the
+// Control function of a net.Dialer or a net.ListenConfig. Only the systems
+// ListenAndServe supports ReusePort on (go1.11+, Linux and the BSDs) support
+// socket options, and Freebind, BindAddressNoPort and Device are Linux only.
+type SocketOptions struct {
+	DSCP              uint8  // DiffServ code point of the packets sent (IP_TOS, IPV6_TCLASS), 0 for the default
+	TTL               int    // TTL, or hop limit, of the packets sent (IP_TTL, IPV6_UNICAST_HOPS), 0 for the default
+	Freebind          bool   // allow binding to addresses that aren't (yet) configured (IP_FREEBIND)
+	BindAddressNoPort bool   // leave the choice of the local port to connect when dialing from an address (IP_BIND_ADDRESS_NO_PORT)
+	Device            string // bind to this network interface (SO_BINDTODEVICE)
+}
+
+// errSocketOptions is returned when socket options aren't supported.
+var errSocketOptions error = &Error{err: "socket options not supported"}
@@ -0,0 +1,11 @@
+// +build aix darwin dragonfly freebsd netbsd openbsd
+
+package dns
+
+// setLinux returns an error if o has options that only Linux has.
+func (o *SocketOptions) setLinux(fd int, v4only bool) error {
+	if o.Freebind || o.BindAddressNoPort || o.Device != "" {
+		return errSocketOptions
+	}
+	return nil
+}
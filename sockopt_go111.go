@@ -0,0 +1,75 @@
+// +build go1.11
+// +build aix darwin dragonfly freebsd linux netbsd openbsd
+
+package dns
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Control sets the options of o on the socket c, for address on network. It
+// has the signature of the Control function of a net.Dialer.
+func (o *SocketOptions) Control(network, address string, c syscall.RawConn) error {
+	// A socket for an IPv6 address, or a wildcard one, may be dual-stack:
+	// the IPv4 options are set too, ignoring the errors.
+	v4only := network == "tcp4" || network == "udp4"
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+			v4only = true
+		}
+	}
+
+	var opErr error
+	err := c.Control(func(fd uintptr) {
+		opErr = o.set(int(fd), v4only)
+	})
+	if err != nil {
+		return err
+	}
+	return opErr
+}
+
+// set sets the options of o on the socket fd.
+func (o *SocketOptions) set(fd int, v4only bool) error {
+	// setIP sets the option of IPv4, and of IPv6 unless v4only.
+	setIP := func(opt4, opt6, value int) error {
+		err4 := unix.SetsockoptInt(fd, unix.IPPROTO_IP, opt4, value)
+		if v4only {
+			return err4
+		}
+		return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, opt6, value)
+	}
+	if o.DSCP != 0 {
+		if err := setIP(unix.IP_TOS, unix.IPV6_TCLASS, int(o.DSCP)<<2); err != nil {
+			return err
+		}
+	}
+	if o.TTL != 0 {
+		if err := setIP(unix.IP_TTL, unix.IPV6_UNICAST_HOPS, o.TTL); err != nil {
+			return err
+		}
+	}
+	return o.setLinux(fd, v4only)
+}
+
+// controlDialer adds the options of o to the Control function of d.
+func (o *SocketOptions) controlDialer(d *net.Dialer) error {
+	d.Control = chainControl(d.Control, o.Control)
+	return nil
+}
+
+// chainControl returns a Control function calling f and then g, f may be nil.
+func chainControl(f, g func(network, address string, c syscall.RawConn) error) func(network, address string, c syscall.RawConn) error {
+	if f == nil {
+		return g
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		if err := f(network, address, c); err != nil {
+			return err
+		}
+		return g(network, address, c)
+	}
+}
@@ -0,0 +1,19 @@
+// +build !go1.11 !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package dns
+
+import (
+	"net"
+	"syscall"
+)
+
+// Control sets the options of o on the socket c, for address on network. It
+// has the signature of the Control function of a net.Dialer.
+func (o *SocketOptions) Control(network, address string, c syscall.RawConn) error {
+	return errSocketOptions
+}
+
+// controlDialer adds the options of o to the Control function of d.
+func (o *SocketOptions) controlDialer(d *net.Dialer) error {
+	return errSocketOptions
+}
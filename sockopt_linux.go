@@ -0,0 +1,32 @@
+package dns
+
+import "golang.org/x/sys/unix"
+
+// ipv6Freebind is IPV6_FREEBIND, which golang.org/x/sys/unix lacks on some
+// architectures.
+const ipv6Freebind = 0x4e
+
+// setLinux sets the options of o that only Linux has on the socket fd.
+func (o *SocketOptions) setLinux(fd int, v4only bool) error {
+	if o.Freebind {
+		err := unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_FREEBIND, 1)
+		if !v4only {
+			err = unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, ipv6Freebind, 1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if o.BindAddressNoPort {
+		// The option is of IPPROTO_IP on IPv6 sockets too.
+		if err := unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_BIND_ADDRESS_NO_PORT, 1); err != nil {
+			return err
+		}
+	}
+	if o.Device != "" {
+		if err := unix.BindToDevice(fd, o.Device); err != nil {
+			return err
+		}
+	}
+	return nil
+}
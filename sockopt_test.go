@@ -0,0 +1,46 @@
+// +build go1.11,linux
+
+package dns
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSocketOptions(t *testing.T) {
+	opts := &SocketOptions{DSCP: 46, TTL: 7, Freebind: true}
+	pc, err := listenUDP("udp4", "127.0.0.1:0", false, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	checkSockopt(t, pc.(syscall.Conn), unix.IPPROTO_IP, unix.IP_TOS, 46<<2)
+	checkSockopt(t, pc.(syscall.Conn), unix.IPPROTO_IP, unix.IP_TTL, 7)
+	checkSockopt(t, pc.(syscall.Conn), unix.IPPROTO_IP, unix.IP_FREEBIND, 1)
+
+	c := &Client{SocketOptions: &SocketOptions{TTL: 9}}
+	conn, err := c.Dial(pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	checkSockopt(t, conn.Conn.(*net.UDPConn), unix.IPPROTO_IP, unix.IP_TTL, 9)
+}
+
+func checkSockopt(t *testing.T, c syscall.Conn, level, opt, want int) {
+	t.Helper()
+	rc, err := c.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got int
+	rc.Control(func(fd uintptr) {
+		got, err = unix.GetsockoptInt(int(fd), level, opt)
+	})
+	if err != nil || got != want {
+		t.Errorf("expected option %d to be %d, got %d, %v", opt, want, got, err)
+	}
+}
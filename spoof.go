@@ -0,0 +1,102 @@
+package dns
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"net"
+	"strings"
+)
+
+// ErrSpoofSuspected is the error of the responses a Client with SpoofCheck
+// set rejects, see SpoofError.
+var ErrSpoofSuspected error = &Error{err: "response doesn't match the query, spoofing suspected"}
+
+// A SpoofError is returned by a Client with SpoofCheck set when the response to
+// a query doesn't match it, and given to its SpoofMonitor.
+type SpoofError struct {
+	Mismatch string   // what doesn't match: "source", "id" or "question"
+	From     net.Addr // source of the response
+	Query    *Msg     // the query, with the ID it was sent with
+	Response *Msg     // the response, nil when its source doesn't match
+}
+
+func (e *SpoofError) Error() string {
+	return ErrSpoofSuspected.Error() + ": " + e.Mismatch + " mismatch"
+}
+
+// Unwrap returns ErrSpoofSuspected.
+func (e *SpoofError) Unwrap() error { return ErrSpoofSuspected }
+
+// secureId returns a message ID from crypto/rand.
+func secureId() uint16 {
+	var b [2]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return id()
+	}
+	return binary.BigEndian.Uint16(b[:])
+}
+
+// readChecked reads the response to m from co. UDP responses that don't come
+// from the server, or don't match the ID or question of m, are given to
+// c.SpoofMonitor and skipped: the response of the server may follow. Over TCP
+// a mismatch ends the exchange. When no matching response comes, the error is
+// the *SpoofError of the last one skipped.
+func (c *Client) readChecked(co *Conn, m *Msg) (*Msg, error) {
+	co.spoofCheck = true
+	_, udp := co.Conn.(net.PacketConn)
+	var spoofed *SpoofError
+	for {
+		r, err := co.ReadMsg()
+		serr, ok := err.(*SpoofError)
+		switch {
+		case ok:
+		case err != nil:
+			if ne, ok := err.(net.Error); ok && ne.Timeout() && spoofed != nil {
+				return nil, spoofed
+			}
+			return r, err
+		default:
+			if serr = checkResponse(m, r); serr == nil {
+				return r, nil
+			}
+			serr.From = co.RemoteAddr()
+		}
+		serr.Query = m
+		if c.SpoofMonitor != nil {
+			c.SpoofMonitor(serr)
+		}
+		if !udp {
+			return nil, serr
+		}
+		spoofed = serr
+	}
+}
+
+// checkResponse returns a *SpoofError if r doesn't match the ID and question
+// of the query m, or nil.
+func checkResponse(m, r *Msg) *SpoofError {
+	if r.Id != m.Id {
+		return &SpoofError{Mismatch: "id", Response: r}
+	}
+	if len(r.Question) != len(m.Question) {
+		return &SpoofError{Mismatch: "question", Response: r}
+	}
+	for i, q := range m.Question {
+		rq := r.Question[i]
+		if rq.Qtype != q.Qtype || rq.Qclass != q.Qclass || !strings.EqualFold(rq.Name, q.Name) {
+			return &SpoofError{Mismatch: "question", Response: r}
+		}
+	}
+	return nil
+}
+
+// checkSource returns a *SpoofError if the UDP response from isn't from the
+// server co is connected to, or nil.
+func (co *Conn) checkSource(from net.Addr) error {
+	a, ok := from.(*net.UDPAddr)
+	b, ok1 := co.RemoteAddr().(*net.UDPAddr)
+	if !ok || !ok1 || a.Port != b.Port || !a.IP.Equal(b.IP) {
+		return &SpoofError{Mismatch: "source", From: from}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package dns
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientSpoofCheck(t *testing.T) {
+	HandleFunc("example.", func(w ResponseWriter, req *Msg) {
+		m := new(Msg)
+		m.SetReply(req)
+		switch req.Question[0].Name {
+		case "id.example.":
+			// A forged response first, the real one after it.
+			forged := m.Copy()
+			forged.Id++
+			w.WriteMsg(forged)
+		case "question.example.":
+			m.Question[0].Name = "other.example."
+		}
+		w.WriteMsg(m)
+	})
+	defer HandleRemove("example.")
+
+	s, addrstr, err := RunLocalUDPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	var (
+		mu      sync.Mutex
+		spoofed []*SpoofError
+	)
+	c := &Client{
+		SpoofCheck: true,
+		Timeout:    500 * time.Millisecond,
+		SpoofMonitor: func(e *SpoofError) {
+			mu.Lock()
+			spoofed = append(spoofed, e)
+			mu.Unlock()
+		},
+	}
+	m := new(Msg)
+	m.SetQuestion("id.example.", TypeA)
+	m.Id = 1234
+	r, _, err := c.Exchange(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if r.Id != 1234 || m.Id != 1234 {
+		t.Errorf("expected the ID of the query restored, got %d and %d", r.Id, m.Id)
+	}
+	mu.Lock()
+	if len(spoofed) != 1 || spoofed[0].Mismatch != "id" || spoofed[0].From == nil {
+		t.Errorf("expected the forged response reported, got %v", spoofed)
+	}
+	mu.Unlock()
+
+	m.SetQuestion("question.example.", TypeA)
+	_, _, err = c.Exchange(m, addrstr)
+	if serr, ok := err.(*SpoofError); !ok || serr.Mismatch != "question" || serr.Unwrap() != ErrSpoofSuspected {
+		t.Errorf("expected a question mismatch, got %v", err)
+	}
+}
+
+func TestConnCheckSource(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	co := &Conn{Conn: conn}
+	if err := co.checkSource(pc.LocalAddr()); err != nil {
+		t.Errorf("expected the server accepted, got %v", err)
+	}
+	other := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 53}
+	if err, ok := co.checkSource(other).(*SpoofError); !ok || err.Mismatch != "source" || err.From != other {
+		t.Errorf("expected a source mismatch, got %v", err)
+	}
+}
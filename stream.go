@@ -0,0 +1,120 @@
+package dns
+
+import (
+	"net"
+	"time"
+)
+
+// A MsgStreamer is a ResponseWriter that tells handlers how large their
+// messages may be, and whether a response can be written in several
+// messages, as zone transfers (RFC 5936) are. The ResponseWriter of a Server
+// implements it. ResponseStream uses it to pack records in messages.
+type MsgStreamer interface {
+	// MaxMsgSize returns the maximum size of a message to the client: 65535
+	// on TCP, and the UDP size of the query, at least 512, on UDP.
+	MaxMsgSize() int
+	// CanStream reports whether a response can be written in several
+	// messages, which is only the case on TCP.
+	CanStream() bool
+}
+
+// msgStreamer returns the maximum message size of w and whether it can
+// stream responses, guessed from its remote address when w isn't a
+// MsgStreamer.
+func msgStreamer(w ResponseWriter) (int, bool) {
+	if s, ok := w.(MsgStreamer); ok {
+		return s.MaxMsgSize(), s.CanStream()
+	}
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		return MaxMsgSize, true
+	}
+	return MinMsgSize, false
+}
+
+// streamReserve is the room left in the messages of a ResponseStream for the
+// header, the question and the TSIG record.
+const streamReserve = 1024
+
+// A ResponseStream sends the records of the response to a query in as few
+// messages as they fit in, each as large as the ResponseWriter allows, so
+// handlers can send zone transfers or other large responses. When the query
+// has a valid TSIG, every message is signed (RFC 8945, Section 5.3.1). When
+// the ResponseWriter can't write several messages, the records that don't
+// fit in the first one are left out and it has the TC bit set.
+type ResponseStream struct {
+	Authoritative bool // if true, the messages have the AA bit set
+
+	w      ResponseWriter
+	q      *Msg
+	tsig   *TSIG // TSIG of q, if it was valid
+	max    int   // maximum length of the records in a message
+	stream bool  // w can write several messages
+	r      *Msg  // message being filled
+	size   int   // uncompressed length of the records in r
+	sent   bool  // a message was written
+}
+
+// NewResponseStream returns a ResponseStream for the response to q on w.
+func NewResponseStream(w ResponseWriter, q *Msg) *ResponseStream {
+	s := &ResponseStream{w: w, q: q}
+	if t := q.IsTsig(); t != nil && w.TsigStatus() == nil {
+		s.tsig = t
+	}
+	size, stream := msgStreamer(w)
+	s.stream = stream
+	s.max = size - streamReserve
+	if size < 4*streamReserve {
+		// Small UDP messages: reserve what the header, question and TSIG
+		// record of the query take.
+		s.max = size - q.Len()
+	}
+	return s
+}
+
+// Add adds rr to the current message, and sends it first when rr doesn't
+// fit. Once a message is truncated no more records are added to it, so the
+// records it has are the first ones in the order they were added.
+func (s *ResponseStream) Add(rr RR) error {
+	if s.r != nil && s.r.Truncated {
+		return nil
+	}
+	l := rr.len(0, nil)
+	if s.r != nil && s.size+l > s.max {
+		if !s.stream {
+			s.r.Truncated = true
+			return nil
+		}
+		if err := s.Flush(); err != nil {
+			return err
+		}
+	}
+	if s.r == nil {
+		if s.sent && !s.stream {
+			return nil
+		}
+		s.r = new(Msg)
+		s.r.SetReply(s.q)
+		s.r.Authoritative = s.Authoritative
+		s.r.Compress = true
+		s.size = 0
+	}
+	s.r.Answer = append(s.r.Answer, rr)
+	s.size += l
+	return nil
+}
+
+// Flush sends the current message, if it has records.
+func (s *ResponseStream) Flush() error {
+	if s.r == nil {
+		return nil
+	}
+	if s.tsig != nil {
+		s.r.SetTsig(s.tsig.Hdr.Name, s.tsig.Algorithm, s.tsig.Fudge, time.Now().Unix())
+	}
+	if err := s.w.WriteMsg(s.r); err != nil {
+		return err
+	}
+	s.w.TsigTimersOnly(true) // the next messages are signed as part of a sequence
+	s.r, s.sent = nil, true
+	return nil
+}
@@ -0,0 +1,65 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestResponseStreamUDP(t *testing.T) {
+	sizes := make(chan int, 1)
+	HandleFunc("stream.example.", func(w ResponseWriter, req *Msg) {
+		sizes <- w.(MsgStreamer).MaxMsgSize()
+		s := NewResponseStream(w, req)
+		for i := 0; i < 100; i++ {
+			if err := s.Add(testRR(fmt.Sprintf("stream.example. 3600 IN TXT %q", strings.Repeat("x", 100)))); err != nil {
+				t.Errorf("failed to add: %v", err)
+			}
+		}
+		if err := s.Flush(); err != nil {
+			t.Errorf("failed to flush: %v", err)
+		}
+	})
+	defer HandleRemove("stream.example.")
+
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	m := new(Msg)
+	m.SetQuestion("stream.example.", TypeTXT)
+	m.SetEdns0(1232, false)
+	c := &Client{UDPSize: 4096}
+	r, _, err := c.Exchange(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if size := <-sizes; size != 1232 {
+		t.Errorf("expected a maximum message size of 1232, got %d", size)
+	}
+	if !r.Truncated || len(r.Answer) == 0 || r.Len() > 1232 {
+		t.Errorf("expected a truncated response of at most 1232 octets, got %d records in %d octets", len(r.Answer), r.Len())
+	}
+}
+
+func TestResponseStreamTruncated(t *testing.T) {
+	q := new(Msg)
+	q.SetQuestion("stream.example.", TypeTXT)
+	w := new(recordWriter)
+	s := NewResponseStream(w, q)
+	for _, txt := range []string{strings.Repeat("x", 250), strings.Repeat("x", 250), "y"} {
+		if err := s.Add(testRR(fmt.Sprintf("stream.example. 3600 IN TXT %q", txt))); err != nil {
+			t.Fatalf("failed to add: %v", err)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	// The last record would fit, but records after the first one left out
+	// aren't added.
+	if len(w.msgs) != 1 || !w.msgs[0].Truncated || len(w.msgs[0].Answer) != 1 {
+		t.Errorf("expected a truncated message with 1 record, got %v", w.msgs)
+	}
+}
@@ -0,0 +1,657 @@
+package dns
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TKEY modes, see RFC 2930, section 2.5.
+const (
+	TkeyModeServer   = 1
+	TkeyModeDH       = 2
+	TkeyModeGSSAPI   = 3
+	TkeyModeResolver = 4
+	TkeyModeDelete   = 5
+)
+
+// DefaultTkeyLifetime is the lifetime requested for negotiated keys when none is given.
+const DefaultTkeyLifetime = time.Hour
+
+// TkeyGSS is implemented by TSIG providers that can establish GSS-API security
+// contexts with TKEY, see RFC 3645. The GSS provider, available with the gssapi
+// build tag, implements it.
+type TkeyGSS interface {
+	TsigProvider
+	// InitSecContext is used by a client to create or continue a security
+	// context for keyname with the service target, e.g. "DNS@ns.example.org".
+	// The input token is nil on the first call.
+	InitSecContext(keyname, target string, input []byte) (output []byte, complete bool, err error)
+	// AcceptSecContext is used by a server to create or continue a security
+	// context for keyname.
+	AcceptSecContext(keyname string, input []byte) (output []byte, complete bool, err error)
+}
+
+// TsigKeyring is a TsigProvider holding HMAC secrets that is safe for
+// concurrent use. Keys negotiated with TKEY are added to it, so it is
+// typically used as the TsigProvider of a Client or Server.
+type TsigKeyring struct {
+	// GSS, when set, is used for messages signed with the gss-tsig algorithm.
+	// It must be set before the keyring is used.
+	GSS TsigProvider
+
+	mu   sync.RWMutex
+	keys map[string]tsigKey
+}
+
+type tsigKey struct {
+	secret string
+	expire time.Time // zero when the key doesn't expire
+}
+
+// NewTsigKeyring returns a keyring holding secrets, a map of key names to base64
+// encoded secrets, as used in the TsigSecret fields. secrets may be nil.
+func NewTsigKeyring(secrets map[string]string) *TsigKeyring {
+	k := &TsigKeyring{keys: make(map[string]tsigKey)}
+	for name, secret := range secrets {
+		k.keys[strings.ToLower(name)] = tsigKey{secret: secret}
+	}
+	return k
+}
+
+// Add adds the base64 encoded secret for key name to the keyring. The key is
+// no longer used after expire, unless expire is the zero time.
+func (k *TsigKeyring) Add(name, secret string, expire time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[strings.ToLower(name)] = tsigKey{secret: secret, expire: expire}
+}
+
+// Remove removes the key name from the keyring.
+func (k *TsigKeyring) Remove(name string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.keys, strings.ToLower(name))
+}
+
+// Secret returns the secret of key name.
+func (k *TsigKeyring) Secret(name string) (string, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[strings.ToLower(name)]
+	if !ok || (!key.expire.IsZero() && time.Now().After(key.expire)) {
+		return "", false
+	}
+	return key.secret, true
+}
+
+// Generate implements the TsigProvider.Generate method.
+func (k *TsigKeyring) Generate(msg []byte, t *TSIG) ([]byte, error) {
+	if strings.ToLower(t.Algorithm) == GssTsig && k.GSS != nil {
+		return k.GSS.Generate(msg, t)
+	}
+	secret, ok := k.Secret(t.Hdr.Name)
+	if !ok {
+		return nil, ErrSecret
+	}
+	return tsigHMACProvider(secret).Generate(msg, t)
+}
+
+// Verify implements the TsigProvider.Verify method.
+func (k *TsigKeyring) Verify(msg []byte, t *TSIG) error {
+	if strings.ToLower(t.Algorithm) == GssTsig && k.GSS != nil {
+		return k.GSS.Verify(msg, t)
+	}
+	secret, ok := k.Secret(t.Hdr.Name)
+	if !ok {
+		return ErrSecret
+	}
+	return tsigHMACProvider(secret).Verify(msg, t)
+}
+
+// TkeyDH negotiates a shared secret for the TSIG key keyname with the server
+// at address, using Diffie-Hellman exchanged keying (RFC 2930, section 4.1).
+// The algorithm is the TSIG algorithm the key is to be used with, e.g.
+// HmacSHA256. The query is signed with tsig, a TSIG key the server knows, as
+// servers only accept authenticated Diffie-Hellman queries, and the response
+// must be signed with it too. The TKEY record of the server and the base64
+// encoded secret are returned. When the TsigProvider of c is a *TsigKeyring
+// the secret is added to it, so subsequent exchanges can be signed with it; c
+// itself is not modified, add the secret to its TsigSecret map before using c
+// otherwise.
+func (c *Client) TkeyDH(keyname, algorithm string, lifetime time.Duration, address string, tsig *TsigKey) (*TKEY, string, error) {
+	keyname = Fqdn(strings.ToLower(keyname))
+	priv, pub, err := dhGenerateKey(dhOakleyGroup2)
+	if err != nil {
+		return nil, "", err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+
+	m := newTkeyQuery(keyname, algorithm, TkeyModeDH, lifetime, nonce)
+	m.Extra = append(m.Extra, &KEY{DNSKEY{
+		Hdr:       RR_Header{Name: keyname, Rrtype: TypeKEY, Class: ClassANY},
+		Protocol:  3,
+		Algorithm: DH,
+		PublicKey: toBase64(packDHKey(dhOakleyGroup2, pub)),
+	}})
+	m.SetTsig(tsig.Name, tsig.Algorithm, 300, time.Now().Unix())
+
+	co, err := c.Dial(address)
+	if err != nil {
+		return nil, "", err
+	}
+	defer co.Close()
+	co.TsigProvider = tsigHMACProvider(tsig.Secret)
+	co.SetWriteDeadline(time.Now().Add(c.getTimeoutForRequest(c.writeTimeout())))
+	if err := co.WriteMsg(m); err != nil {
+		return nil, "", err
+	}
+	co.SetReadDeadline(time.Now().Add(c.getTimeoutForRequest(c.readTimeout())))
+	r, err := co.ReadMsg()
+	if err != nil {
+		return nil, "", err
+	}
+	if r.Id != m.Id {
+		return nil, "", ErrId
+	}
+	tkey, err := tkeyFromReply(r, TkeyModeDH)
+	if err != nil {
+		return nil, "", err
+	}
+	if r.IsTsig() == nil {
+		return nil, "", ErrSig
+	}
+
+	var serverKey *KEY
+	for _, rr := range r.Answer {
+		if k, ok := rr.(*KEY); ok && k.Algorithm == DH {
+			serverKey = k
+			break
+		}
+	}
+	if serverKey == nil {
+		return nil, "", ErrKey
+	}
+	serverData, err := hexToBytes(tkey.Key)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := dhSecret(serverKey, priv, nonce, serverData)
+	if err != nil {
+		return nil, "", err
+	}
+
+	b64 := toBase64(secret)
+	if k, ok := c.TsigProvider.(*TsigKeyring); ok {
+		k.Add(tkey.Hdr.Name, b64, time.Unix(int64(tkey.Expiration), 0))
+	}
+	return tkey, b64, nil
+}
+
+// TkeyGSS establishes a GSS-API security context for the TSIG key keyname with
+// the server at address (RFC 3645, section 4.1). The target is the service
+// principal of the server, e.g. "DNS@ns.example.org". The exchange should
+// use TCP. The final TKEY record of the server is returned; on success gss
+// can sign and verify messages with keyname, e.g. as the TsigProvider, or the
+// GSS of the TsigKeyring, of a Client.
+func (c *Client) TkeyGSS(keyname, target string, gss TkeyGSS, address string) (*TKEY, error) {
+	keyname = strings.ToLower(Fqdn(keyname))
+	co, err := c.Dial(address)
+	if err != nil {
+		return nil, err
+	}
+	defer co.Close()
+
+	var (
+		input  []byte
+		signed []byte // the last reply when it carries a TSIG
+		tkey   *TKEY
+	)
+	for {
+		output, complete, err := gss.InitSecContext(keyname, target, input)
+		if err != nil {
+			return nil, err
+		}
+		if complete && len(output) == 0 {
+			break
+		}
+		if len(output) == 0 {
+			return nil, &Error{err: "TKEY negotiation failed: no GSS-API token"}
+		}
+
+		m := newTkeyQuery(keyname, GssTsig, TkeyModeGSSAPI, DefaultTkeyLifetime, output)
+		co.SetWriteDeadline(time.Now().Add(c.getTimeoutForRequest(c.writeTimeout())))
+		if err := co.WriteMsg(m); err != nil {
+			return nil, err
+		}
+		co.SetReadDeadline(time.Now().Add(c.getTimeoutForRequest(c.readTimeout())))
+		p, err := co.ReadMsgHeader(nil)
+		if err != nil {
+			return nil, err
+		}
+		r := new(Msg)
+		if err := r.Unpack(p); err != nil {
+			return nil, err
+		}
+		if r.Id != m.Id {
+			return nil, ErrId
+		}
+		if tkey, err = tkeyFromReply(r, TkeyModeGSSAPI); err != nil {
+			return nil, err
+		}
+		if input, err = hexToBytes(tkey.Key); err != nil {
+			return nil, err
+		}
+		signed = nil
+		if r.IsTsig() != nil {
+			signed = p
+		}
+		if complete {
+			break
+		}
+	}
+
+	// RFC 3645, section 4.1.3: the final response of the server is signed, this
+	// can only be checked now the security context is established.
+	if signed != nil {
+		if err := TsigVerifyWithProvider(signed, gss, "", false); err != nil {
+			return nil, err
+		}
+	}
+	return tkey, nil
+}
+
+// TkeyDelete asks the server at address to delete the TSIG key keyname, see
+// RFC 2930, section 4.2. The request is signed with the key itself, after
+// which it is removed from the TsigProvider of c when that is a *TsigKeyring.
+func (c *Client) TkeyDelete(keyname, algorithm, address string) error {
+	keyname = strings.ToLower(Fqdn(keyname))
+	m := newTkeyQuery(keyname, algorithm, TkeyModeDelete, 0, nil)
+	m.SetTsig(keyname, algorithm, 300, time.Now().Unix())
+
+	r, _, err := c.Exchange(m, address)
+	if err != nil {
+		return err
+	}
+	if _, err := tkeyFromReply(r, TkeyModeDelete); err != nil {
+		return err
+	}
+
+	if k, ok := c.TsigProvider.(*TsigKeyring); ok {
+		k.Remove(keyname)
+	}
+	return nil
+}
+
+// TkeyHandler is a Handler that answers TKEY queries (RFC 2930) and passes
+// all other queries on to Next. Keys negotiated with Diffie-Hellman are added
+// to Keyring, the queries must be signed with a TSIG key of the Server and
+// can't replace a key that exists. GSS-API security contexts (RFC 3645) are
+// established with GSS. Use Keyring, with its GSS field set to GSS when that
+// is used, as the TsigProvider of the Server, and TkeyMsgAcceptFunc as its
+// MsgAcceptFunc.
+type TkeyHandler struct {
+	Keyring     *TsigKeyring
+	GSS         TkeyGSS       // If nil GSS-API negotiation is refused.
+	MaxLifetime time.Duration // Maximum lifetime of negotiated keys, defaults to DefaultTkeyLifetime.
+	Next        Handler       // Handler for other queries, defaults to DefaultServeMux.
+}
+
+// TkeyMsgAcceptFunc is like DefaultMsgAcceptFunc, but accepts queries with 3
+// RRs in the Additional section, as signed Diffie-Hellman TKEY queries have:
+// the TKEY, KEY and TSIG records (RFC 2930, Section 4.1).
+var TkeyMsgAcceptFunc MsgAcceptFunc = func(dh Header) MsgAcceptAction {
+	if dh.Arcount == 3 {
+		dh.Arcount = 2
+	}
+	return defaultMsgAcceptFunc(dh)
+}
+
+// ServeDNS implements the Handler interface.
+func (h *TkeyHandler) ServeDNS(w ResponseWriter, r *Msg) {
+	var tkey *TKEY
+	if len(r.Question) == 1 && r.Question[0].Qtype == TypeTKEY {
+		for _, rr := range r.Extra {
+			if t, ok := rr.(*TKEY); ok {
+				tkey = t
+				break
+			}
+		}
+	}
+	if tkey == nil {
+		next := h.Next
+		if next == nil {
+			next = DefaultServeMux
+		}
+		next.ServeDNS(w, r)
+		return
+	}
+
+	m := new(Msg)
+	m.SetReply(r)
+	reply := &TKEY{
+		Hdr:        RR_Header{Name: strings.ToLower(tkey.Hdr.Name), Rrtype: TypeTKEY, Class: ClassANY},
+		Algorithm:  tkey.Algorithm,
+		Inception:  tkey.Inception,
+		Expiration: tkey.Expiration,
+		Mode:       tkey.Mode,
+	}
+	m.Answer = append(m.Answer, reply)
+
+	lifetime := h.MaxLifetime
+	if lifetime == 0 {
+		lifetime = DefaultTkeyLifetime
+	}
+	now := time.Now()
+	if reply.Expiration == 0 || time.Unix(int64(reply.Expiration), 0).Sub(now) > lifetime {
+		reply.Inception, reply.Expiration = uint32(now.Unix()), uint32(now.Add(lifetime).Unix())
+	}
+
+	switch tkey.Mode {
+	case TkeyModeDH:
+		// RFC 2930, section 4.1: the query must be authenticated, or anyone
+		// could set the secret of a key.
+		t := r.IsTsig()
+		if t == nil || w.TsigStatus() != nil {
+			reply.Error = RcodeBadKey
+			break
+		}
+		h.serveDH(m, r, tkey, reply)
+		m.SetTsig(t.Hdr.Name, t.Algorithm, 300, now.Unix())
+	case TkeyModeGSSAPI:
+		h.serveGSS(m, tkey, reply)
+	case TkeyModeDelete:
+		t := r.IsTsig()
+		if t == nil || w.TsigStatus() != nil || !strings.EqualFold(t.Hdr.Name, tkey.Hdr.Name) {
+			reply.Error = RcodeBadKey
+			break
+		}
+		m.SetTsig(t.Hdr.Name, t.Algorithm, 300, now.Unix())
+		w.WriteMsg(m)
+		if h.Keyring != nil {
+			h.Keyring.Remove(tkey.Hdr.Name)
+		}
+		return
+	default:
+		reply.Error = RcodeBadMode
+	}
+	w.WriteMsg(m)
+}
+
+func (h *TkeyHandler) serveDH(m, r *Msg, tkey, reply *TKEY) {
+	if h.Keyring == nil {
+		reply.Error = RcodeBadMode
+		return
+	}
+	if _, ok := tsigHMACAlgorithms[strings.ToLower(tkey.Algorithm)]; !ok {
+		reply.Error = RcodeBadAlg
+		return
+	}
+	// Keys that exist aren't replaced (RFC 2930, section 2.1).
+	if _, ok := h.Keyring.Secret(reply.Hdr.Name); ok {
+		reply.Error = RcodeBadName
+		return
+	}
+
+	var clientKey *KEY
+	for _, rr := range r.Extra {
+		if k, ok := rr.(*KEY); ok && k.Algorithm == DH {
+			clientKey = k
+			break
+		}
+	}
+	if clientKey == nil {
+		reply.Error = RcodeBadKey
+		return
+	}
+	group, _, _, err := unpackDHKey(clientKey.PublicKey)
+	if err != nil {
+		reply.Error = RcodeBadKey
+		return
+	}
+	priv, pub, err := dhGenerateKey(group)
+	if err != nil {
+		reply.Error = RcodeServerFailure
+		return
+	}
+	queryData, err := hexToBytes(tkey.Key)
+	if err != nil {
+		reply.Error = RcodeBadKey
+		return
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		reply.Error = RcodeServerFailure
+		return
+	}
+	secret, err := dhSecret(clientKey, priv, queryData, nonce)
+	if err != nil {
+		reply.Error = RcodeBadKey
+		return
+	}
+
+	h.Keyring.Add(reply.Hdr.Name, toBase64(secret), time.Unix(int64(reply.Expiration), 0))
+	reply.Key, reply.KeySize = hex.EncodeToString(nonce), uint16(len(nonce))
+	m.Answer = append(m.Answer, &KEY{DNSKEY{
+		Hdr:       RR_Header{Name: reply.Hdr.Name, Rrtype: TypeKEY, Class: ClassANY},
+		Protocol:  3,
+		Algorithm: DH,
+		PublicKey: toBase64(packDHKey(group, pub)),
+	}})
+}
+
+func (h *TkeyHandler) serveGSS(m *Msg, tkey, reply *TKEY) {
+	if h.GSS == nil || strings.ToLower(tkey.Algorithm) != GssTsig {
+		reply.Error = RcodeBadAlg
+		return
+	}
+	input, err := hexToBytes(tkey.Key)
+	if err != nil {
+		reply.Error = RcodeBadKey
+		return
+	}
+	output, complete, err := h.GSS.AcceptSecContext(reply.Hdr.Name, input)
+	if err != nil {
+		reply.Error = RcodeBadKey
+		return
+	}
+	reply.Key, reply.KeySize = hex.EncodeToString(output), uint16(len(output))
+	if complete {
+		// RFC 3645, section 4.1.3: the final response is signed.
+		m.SetTsig(reply.Hdr.Name, GssTsig, 300, time.Now().Unix())
+	}
+}
+
+// tsigHMACAlgorithms are the TSIG algorithms a DH negotiated key can be used with.
+var tsigHMACAlgorithms = map[string]struct{}{
+	HmacMD5:    {},
+	HmacSHA1:   {},
+	HmacSHA256: {},
+	HmacSHA512: {},
+}
+
+func newTkeyQuery(keyname, algorithm string, mode uint16, lifetime time.Duration, key []byte) *Msg {
+	m := new(Msg)
+	m.SetQuestion(keyname, TypeTKEY)
+	m.Question[0].Qclass = ClassANY
+
+	t := &TKEY{
+		Hdr:       RR_Header{Name: keyname, Rrtype: TypeTKEY, Class: ClassANY},
+		Algorithm: algorithm,
+		Mode:      mode,
+		Key:       hex.EncodeToString(key),
+		KeySize:   uint16(len(key)),
+	}
+	if lifetime > 0 {
+		now := time.Now()
+		t.Inception, t.Expiration = uint32(now.Unix()), uint32(now.Add(lifetime).Unix())
+	}
+	m.Extra = append(m.Extra, t)
+	return m
+}
+
+// tkeyFromReply returns the TKEY record from the answer section of r, or an
+// error when r, or the TKEY record, signals a failure.
+func tkeyFromReply(r *Msg, mode uint16) (*TKEY, error) {
+	if r.Rcode != RcodeSuccess {
+		return nil, &Error{err: "TKEY negotiation failed: " + RcodeToString[r.Rcode]}
+	}
+	for _, rr := range r.Answer {
+		t, ok := rr.(*TKEY)
+		if !ok {
+			continue
+		}
+		if t.Error != RcodeSuccess {
+			return nil, &Error{err: "TKEY negotiation failed: " + RcodeToString[int(t.Error)]}
+		}
+		if t.Mode != mode {
+			return nil, &Error{err: "TKEY negotiation failed: mode mismatch"}
+		}
+		return t, nil
+	}
+	return nil, &Error{err: "TKEY negotiation failed: no TKEY in answer"}
+}
+
+// Well-known Diffie-Hellman groups, RFC 2539, section 2 and RFC 2409, section 6.2.
+var (
+	dhOakleyGroup2 = dhGroup{index: 2, p: dhPrime("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1" +
+		"29024E088A67CC74020BBEA63B139B22514A08798E3404DD" +
+		"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245" +
+		"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED" +
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE65381" +
+		"FFFFFFFFFFFFFFFF"), g: big.NewInt(2)}
+	dhOakleyGroup1 = dhGroup{index: 1, p: dhPrime("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1" +
+		"29024E088A67CC74020BBEA63B139B22514A08798E3404DD" +
+		"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245" +
+		"E485B576625E7EC6F44C42E9A63A3620FFFFFFFFFFFFFFFF"), g: big.NewInt(2)}
+)
+
+type dhGroup struct {
+	index int // well-known prime index
+	p, g  *big.Int
+}
+
+func dhPrime(s string) *big.Int {
+	p, _ := new(big.Int).SetString(s, 16)
+	return p
+}
+
+func dhGenerateKey(group dhGroup) (priv, pub *big.Int, err error) {
+	// Private values are taken from [2, p-2].
+	max := new(big.Int).Sub(group.p, big.NewInt(3))
+	priv, err = rand.Int(rand.Reader, max)
+	if err != nil {
+		return nil, nil, err
+	}
+	priv.Add(priv, big.NewInt(2))
+	return priv, new(big.Int).Exp(group.g, priv, group.p), nil
+}
+
+// packDHKey returns the public key data of a DH KEY record, see RFC 2539,
+// section 2.
+func packDHKey(group dhGroup, pub *big.Int) []byte {
+	b := []byte{0, 1, byte(group.index), 0, 0}
+	y := pub.Bytes()
+	b = append(b, byte(len(y)>>8), byte(len(y)))
+	return append(b, y...)
+}
+
+// unpackDHKey parses the base64 encoded public key data of a DH KEY record.
+// Only the well-known groups are accepted, by their index or by their prime
+// and generator: the modular exponentiation with a group a peer chooses is
+// as expensive as it likes.
+func unpackDHKey(s string) (group dhGroup, pub *big.Int, n int, err error) {
+	b, err := fromBase64([]byte(s))
+	if err != nil {
+		return group, nil, 0, err
+	}
+	field := func() ([]byte, bool) {
+		if len(b) < 2 {
+			return nil, false
+		}
+		l := int(binary.BigEndian.Uint16(b))
+		if len(b) < 2+l {
+			return nil, false
+		}
+		f := b[2 : 2+l]
+		b = b[2+l:]
+		return f, true
+	}
+
+	p, ok := field()
+	if !ok {
+		return group, nil, 0, ErrKey
+	}
+	g, ok := field()
+	if !ok {
+		return group, nil, 0, ErrKey
+	}
+	switch {
+	case len(p) == 1 && p[0] == 1 && len(g) == 0:
+		group = dhOakleyGroup1
+	case len(p) == 1 && p[0] == 2 && len(g) == 0:
+		group = dhOakleyGroup2
+	case len(p) > 2 && len(g) > 0:
+		pi, gi := new(big.Int).SetBytes(p), new(big.Int).SetBytes(g)
+		for _, known := range []dhGroup{dhOakleyGroup1, dhOakleyGroup2} {
+			if pi.Cmp(known.p) == 0 && gi.Cmp(known.g) == 0 {
+				group = known
+			}
+		}
+		if group.p == nil {
+			return group, nil, 0, ErrKey
+		}
+	default:
+		return group, nil, 0, ErrKey
+	}
+	y, ok := field()
+	if !ok || len(y) == 0 {
+		return group, nil, 0, ErrKey
+	}
+	return group, new(big.Int).SetBytes(y), len(group.p.Bytes()), nil
+}
+
+// dhSecret computes the keying material from the peer's DH KEY record and our
+// private value, see RFC 2930, section 4.1:
+//
+//	keying material = XOR(DH value, MD5(query data | DH value) | MD5(server data | DH value))
+func dhSecret(peer *KEY, priv *big.Int, queryData, serverData []byte) ([]byte, error) {
+	group, pub, n, err := unpackDHKey(peer.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if pub.Cmp(big.NewInt(1)) <= 0 || pub.Cmp(new(big.Int).Sub(group.p, big.NewInt(1))) >= 0 {
+		return nil, ErrKey
+	}
+	dh := intToBytes(new(big.Int).Exp(pub, priv, group.p), n)
+
+	h1 := md5.Sum(append(append([]byte(nil), queryData...), dh...))
+	h2 := md5.Sum(append(append([]byte(nil), serverData...), dh...))
+	hashes := append(h1[:], h2[:]...)
+
+	// The shorter operand is left justified and padded with zeros.
+	secret := make([]byte, len(dh))
+	if len(hashes) > len(secret) {
+		secret = make([]byte, len(hashes))
+	}
+	copy(secret, dh)
+	for i := range hashes {
+		secret[i] ^= hashes[i]
+	}
+	return secret, nil
+}
+
+func hexToBytes(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return hex.DecodeString(s)
+}
@@ -0,0 +1,234 @@
+package dns
+
+import (
+	"bytes"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTkeyDHPrimes(t *testing.T) {
+	for _, g := range []struct {
+		group dhGroup
+		bits  int
+	}{{dhOakleyGroup1, 768}, {dhOakleyGroup2, 1024}} {
+		if g.group.p.BitLen() != g.bits || !g.group.p.ProbablyPrime(20) {
+			t.Errorf("bad prime for Oakley group %d", g.group.index)
+		}
+	}
+}
+
+func TestTkeyDH(t *testing.T) {
+	const keyname = "key.example.org."
+
+	mux := NewServeMux()
+	mux.HandleFunc("example.org.", func(w ResponseWriter, r *Msg) {
+		m := new(Msg)
+		m.SetReply(r)
+		if t1 := r.IsTsig(); t1 == nil || w.TsigStatus() != nil {
+			t.Errorf("invalid TSIG: %v", w.TsigStatus())
+		} else {
+			m.SetTsig(t1.Hdr.Name, t1.Algorithm, 300, time.Now().Unix())
+		}
+		w.WriteMsg(m)
+	})
+	admin := &TsigKey{Name: "admin.example.org.", Algorithm: HmacSHA256, Secret: "so6ZGir4GPAqINNh9U5c3A=="}
+	keyring := NewTsigKeyring(map[string]string{admin.Name: admin.Secret})
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan(":0", func(srv *Server) {
+		srv.TsigProvider = keyring
+		srv.Handler = &TkeyHandler{Keyring: keyring, Next: mux}
+		srv.MsgAcceptFunc = TkeyMsgAcceptFunc
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	c := &Client{TsigProvider: NewTsigKeyring(nil)}
+	tkey, secret, err := c.TkeyDH(keyname, HmacSHA256, time.Hour, addrstr, admin)
+	if err != nil {
+		t.Fatalf("failed to negotiate key: %v", err)
+	}
+	if tkey.Hdr.Name != keyname || tkey.Mode != TkeyModeDH {
+		t.Errorf("unexpected TKEY record: %s", tkey)
+	}
+	if s, ok := c.TsigProvider.(*TsigKeyring).Secret(keyname); !ok || s != secret {
+		t.Fatal("expected negotiated key in the client keyring")
+	}
+	if s, _ := keyring.Secret(keyname); s != secret {
+		t.Fatalf("expected client and server secrets to match, got %q and %q", secret, s)
+	}
+
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeSOA)
+	m.SetTsig(keyname, HmacSHA256, 300, time.Now().Unix())
+	if _, _, err := c.Exchange(m, addrstr); err != nil {
+		t.Fatalf("failed to exchange signed query: %v", err)
+	}
+
+	if err := c.TkeyDelete(keyname, HmacSHA256, addrstr); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, ok := keyring.Secret(keyname); ok {
+		t.Error("expected key to be deleted from the server keyring")
+	}
+	if _, ok := c.TsigProvider.(*TsigKeyring).Secret(keyname); ok {
+		t.Error("expected key to be deleted from the client keyring")
+	}
+
+	// A client without a keyring is not modified.
+	c = new(Client)
+	if _, secret, err = c.TkeyDH(keyname, HmacSHA256, time.Hour, addrstr, admin); err != nil {
+		t.Fatalf("failed to negotiate key: %v", err)
+	}
+	if s, _ := keyring.Secret(keyname); s != secret || c.TsigSecret != nil || c.TsigProvider != nil {
+		t.Error("expected the secret to be returned only")
+	}
+
+	// Keys that exist can't be replaced, and unsigned queries are refused.
+	if _, _, err := c.TkeyDH(admin.Name, HmacSHA256, time.Hour, addrstr, admin); err == nil {
+		t.Error("expected a key that exists not to be negotiated")
+	}
+	for _, name := range []string{admin.Name, "new.example.org."} {
+		m = newTkeyQuery(name, HmacSHA256, TkeyModeDH, time.Hour, []byte{1})
+		m.Extra = append(m.Extra, &KEY{DNSKEY{
+			Hdr:       RR_Header{Name: name, Rrtype: TypeKEY, Class: ClassANY},
+			Protocol:  3,
+			Algorithm: DH,
+			PublicKey: toBase64(packDHKey(dhOakleyGroup2, big.NewInt(2))),
+		}})
+		r, _, err := c.Exchange(m, addrstr)
+		if err != nil {
+			t.Fatalf("failed to exchange unsigned query: %v", err)
+		}
+		if _, err := tkeyFromReply(r, TkeyModeDH); err == nil {
+			t.Errorf("expected an unsigned query for %s to be refused", name)
+		}
+	}
+	if s, _ := keyring.Secret(admin.Name); s != admin.Secret {
+		t.Errorf("expected the secret of %s to survive, got %q", admin.Name, s)
+	}
+	if _, ok := keyring.Secret("new.example.org."); ok {
+		t.Error("expected no key to be negotiated by an unsigned query")
+	}
+}
+
+func TestTkeyDHGroups(t *testing.T) {
+	explicit := func(p, g []byte) string {
+		b := append([]byte{byte(len(p) >> 8), byte(len(p))}, p...)
+		b = append(append(b, byte(len(g)>>8), byte(len(g))), g...)
+		return toBase64(append(b, 0, 1, 5))
+	}
+	for _, tc := range []struct {
+		key   string
+		index int
+	}{
+		{toBase64([]byte{0, 1, 2, 0, 0, 0, 1, 5}), 2},
+		{explicit(dhOakleyGroup1.p.Bytes(), []byte{2}), 1},
+		{explicit(dhOakleyGroup2.p.Bytes(), []byte{5}), 0},
+		{explicit(append(dhOakleyGroup2.p.Bytes(), 0xff), []byte{2}), 0},
+		{toBase64([]byte{0, 1, 3, 0, 0, 0, 1, 5}), 0},
+	} {
+		group, _, _, err := unpackDHKey(tc.key)
+		if tc.index == 0 {
+			if err == nil {
+				t.Errorf("expected group %d to be refused", group.index)
+			}
+			continue
+		}
+		if err != nil || group.index != tc.index {
+			t.Errorf("expected Oakley group %d, got %d: %v", tc.index, group.index, err)
+		}
+	}
+}
+
+// fakeTkeyGSS establishes a security context in a single round trip, messages
+// are signed with xorTsigProvider once it is established.
+type fakeTkeyGSS struct {
+	xorTsigProvider
+
+	mu  sync.Mutex
+	ctx map[string]bool
+}
+
+func (f *fakeTkeyGSS) established(keyname string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ctx == nil {
+		f.ctx = make(map[string]bool)
+	}
+	f.ctx[keyname] = true
+}
+
+func (f *fakeTkeyGSS) Generate(msg []byte, t *TSIG) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.ctx[t.Hdr.Name] {
+		return nil, ErrSecret
+	}
+	return f.xorTsigProvider.Generate(msg, t)
+}
+
+func (f *fakeTkeyGSS) Verify(msg []byte, t *TSIG) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.ctx[t.Hdr.Name] {
+		return ErrSecret
+	}
+	return f.xorTsigProvider.Verify(msg, t)
+}
+
+func (f *fakeTkeyGSS) InitSecContext(keyname, target string, input []byte) ([]byte, bool, error) {
+	if input == nil {
+		return []byte("client:" + target), false, nil
+	}
+	if !bytes.Equal(input, []byte("server")) {
+		return nil, false, ErrKey
+	}
+	f.established(keyname)
+	return nil, true, nil
+}
+
+func (f *fakeTkeyGSS) AcceptSecContext(keyname string, input []byte) ([]byte, bool, error) {
+	if !bytes.Equal(input, []byte("client:DNS@ns.example.org")) {
+		return nil, false, ErrKey
+	}
+	f.established(keyname)
+	return []byte("server"), true, nil
+}
+
+func TestTkeyGSS(t *testing.T) {
+	const keyname = "1234.sig-ns.example.org."
+
+	server := &fakeTkeyGSS{xorTsigProvider: 3}
+	keyring := NewTsigKeyring(nil)
+	keyring.GSS = server
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan(":0", func(srv *Server) {
+		srv.TsigProvider = keyring
+		srv.Handler = &TkeyHandler{Keyring: keyring, GSS: server}
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	client := &fakeTkeyGSS{xorTsigProvider: 3}
+	c := &Client{Net: "tcp"}
+	tkey, err := c.TkeyGSS(keyname, "DNS@ns.example.org", client, addrstr)
+	if err != nil {
+		t.Fatalf("failed to establish security context: %v", err)
+	}
+	if tkey.Mode != TkeyModeGSSAPI || tkey.Algorithm != GssTsig {
+		t.Errorf("unexpected TKEY record: %s", tkey)
+	}
+	if c.TsigProvider != nil {
+		t.Error("expected the client not to be modified")
+	}
+
+	// A final response signed with a different key is detected.
+	c = &Client{Net: "tcp"}
+	if _, err := c.TkeyGSS(keyname, "DNS@ns.example.org", &fakeTkeyGSS{xorTsigProvider: 4}, addrstr); err != ErrSig {
+		t.Errorf("expected %v, got %v", ErrSig, err)
+	}
+}
@@ -0,0 +1,303 @@
+package dns
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TrustAnchorState is the state of a key as tracked by a TrustAnchor, see
+// RFC 5011, section 4.
+type TrustAnchorState uint8
+
+// RFC 5011 key states.
+const (
+	TrustAnchorStart TrustAnchorState = iota
+	TrustAnchorAddPend
+	TrustAnchorValid
+	TrustAnchorMissing
+	TrustAnchorRevoked
+	TrustAnchorRemoved
+)
+
+// TrustAnchorStateToString maps trust anchor states to their RFC 5011 names.
+var TrustAnchorStateToString = map[TrustAnchorState]string{
+	TrustAnchorStart:   "Start",
+	TrustAnchorAddPend: "AddPend",
+	TrustAnchorValid:   "Valid",
+	TrustAnchorMissing: "Missing",
+	TrustAnchorRevoked: "Revoked",
+	TrustAnchorRemoved: "Removed",
+}
+
+func (s TrustAnchorState) String() string {
+	if s1, ok := TrustAnchorStateToString[s]; ok {
+		return s1
+	}
+	return "TrustAnchorState" + strconv.Itoa(int(s))
+}
+
+// The default hold-down times of RFC 5011, section 2.4.1 and 2.4.2.
+const (
+	DefaultAddHoldDown    = 30 * 24 * time.Hour
+	DefaultRemoveHoldDown = 30 * 24 * time.Hour
+)
+
+// TrustAnchorKey is a key tracked by a TrustAnchor.
+type TrustAnchorKey struct {
+	Key     *DNSKEY
+	State   TrustAnchorState
+	Changed time.Time // Time of the last state change.
+	Seen    time.Time // Time the key was last seen in the DNSKEY RRset.
+}
+
+// TrustAnchorStore persists the keys of a TrustAnchor between restarts.
+type TrustAnchorStore interface {
+	// Load returns the keys stored for zone. It returns no keys and a nil
+	// error when nothing has been stored yet.
+	Load(zone string) ([]TrustAnchorKey, error)
+	// Save stores the keys for zone, replacing what was stored before.
+	Save(zone string, keys []TrustAnchorKey) error
+}
+
+// TrustAnchor manages the trust anchors of a zone as described in RFC 5011.
+// Feed it the DNSKEY RRset of the zone with Update, each time it is fetched,
+// and use Anchors to get the keys that should be trusted during validation.
+type TrustAnchor struct {
+	Zone           string
+	AddHoldDown    time.Duration // Defaults to DefaultAddHoldDown.
+	RemoveHoldDown time.Duration // Defaults to DefaultRemoveHoldDown.
+
+	store TrustAnchorStore
+
+	mu   sync.RWMutex
+	keys []TrustAnchorKey
+}
+
+// NewTrustAnchor returns a TrustAnchor for zone. The keys are loaded from
+// store; when the store holds no keys, the initial keys are used and marked
+// Valid. The store may be nil, in which case nothing is persisted.
+func NewTrustAnchor(zone string, store TrustAnchorStore, initial ...*DNSKEY) (*TrustAnchor, error) {
+	ta := &TrustAnchor{Zone: Fqdn(zone), store: store}
+	if store != nil {
+		keys, err := store.Load(ta.Zone)
+		if err != nil {
+			return nil, err
+		}
+		ta.keys = keys
+	}
+	if len(ta.keys) == 0 {
+		now := time.Now()
+		for _, k := range initial {
+			ta.keys = append(ta.keys, TrustAnchorKey{Key: k, State: TrustAnchorValid, Changed: now, Seen: now})
+		}
+	}
+	return ta, nil
+}
+
+// Anchors returns the keys that are currently trusted, these are the keys in
+// the Valid and Missing states.
+func (ta *TrustAnchor) Anchors() []*DNSKEY {
+	ta.mu.RLock()
+	defer ta.mu.RUnlock()
+
+	var anchors []*DNSKEY
+	for _, k := range ta.keys {
+		if k.State == TrustAnchorValid || k.State == TrustAnchorMissing {
+			anchors = append(anchors, k.Key.copy().(*DNSKEY))
+		}
+	}
+	return anchors
+}
+
+// Keys returns all tracked keys with their state.
+func (ta *TrustAnchor) Keys() []TrustAnchorKey {
+	ta.mu.RLock()
+	defer ta.mu.RUnlock()
+
+	keys := make([]TrustAnchorKey, len(ta.keys))
+	copy(keys, ta.keys)
+	return keys
+}
+
+// Verify checks that the DNSKEY RRset in rrs is signed by one of the current
+// anchors at time now. The RRSIGs covering the RRset must be included in rrs.
+func (ta *TrustAnchor) Verify(rrs []RR, now time.Time) error {
+	ta.mu.RLock()
+	defer ta.mu.RUnlock()
+
+	keys, sigs := splitKeySet(rrs)
+	return ta.verify(keys, sigs, now)
+}
+
+// Update processes a freshly fetched DNSKEY RRset, including the RRSIGs that
+// cover it, and advances the state of all keys as described in RFC 5011,
+// section 4. The RRset must validate against the current anchors, otherwise
+// nothing is changed and an error is returned. The new state is saved in the
+// store.
+func (ta *TrustAnchor) Update(rrs []RR, now time.Time) error {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	keys, sigs := splitKeySet(rrs)
+	if err := ta.verify(keys, sigs, now); err != nil {
+		return err
+	}
+	rrset := make([]RR, len(keys))
+	for i, k := range keys {
+		rrset[i] = k
+	}
+
+	addHoldDown, removeHoldDown := ta.AddHoldDown, ta.RemoveHoldDown
+	if addHoldDown == 0 {
+		addHoldDown = DefaultAddHoldDown
+	}
+	if removeHoldDown == 0 {
+		removeHoldDown = DefaultRemoveHoldDown
+	}
+
+	seen := make(map[int]bool)
+	for _, k := range keys {
+		if k.Flags&SEP == 0 {
+			continue
+		}
+
+		i := ta.index(k)
+		if i >= 0 {
+			seen[i] = true
+			ta.keys[i].Seen = now
+		}
+
+		if k.Flags&REVOKE != 0 {
+			// A revoked key must sign the RRset itself, RFC 5011, section 2.1.
+			if i < 0 || ta.keys[i].State == TrustAnchorRevoked || ta.keys[i].State == TrustAnchorRemoved {
+				continue
+			}
+			if !signedBy(k, rrset, sigs, now) {
+				continue
+			}
+			ta.keys[i].Key = k
+			ta.keys[i].State, ta.keys[i].Changed = TrustAnchorRevoked, now
+			continue
+		}
+
+		if i < 0 {
+			ta.keys = append(ta.keys, TrustAnchorKey{Key: k, State: TrustAnchorAddPend, Changed: now, Seen: now})
+			seen[len(ta.keys)-1] = true
+			continue
+		}
+
+		switch ta.keys[i].State {
+		case TrustAnchorStart:
+			ta.keys[i].State, ta.keys[i].Changed = TrustAnchorAddPend, now
+		case TrustAnchorAddPend:
+			if now.Sub(ta.keys[i].Changed) >= addHoldDown {
+				ta.keys[i].State, ta.keys[i].Changed = TrustAnchorValid, now
+			}
+		case TrustAnchorMissing:
+			ta.keys[i].State, ta.keys[i].Changed = TrustAnchorValid, now
+		}
+	}
+
+	keep := ta.keys[:0]
+	for i, k := range ta.keys {
+		if !seen[i] {
+			switch k.State {
+			case TrustAnchorValid:
+				k.State, k.Changed = TrustAnchorMissing, now
+			case TrustAnchorAddPend:
+				// Key disappeared before the hold-down timer expired.
+				k.State, k.Changed = TrustAnchorStart, now
+			}
+		}
+		if k.State == TrustAnchorRevoked && now.Sub(k.Changed) >= removeHoldDown {
+			k.State, k.Changed = TrustAnchorRemoved, now
+		}
+		if k.State == TrustAnchorStart || k.State == TrustAnchorRemoved {
+			continue
+		}
+		keep = append(keep, k)
+	}
+	ta.keys = keep
+
+	if ta.store == nil {
+		return nil
+	}
+	return ta.store.Save(ta.Zone, ta.keys)
+}
+
+// verify checks that one of the RRSIGs in sigs is made by a key in keys that
+// is a current anchor.
+func (ta *TrustAnchor) verify(keys []*DNSKEY, sigs []*RRSIG, now time.Time) error {
+	if len(keys) == 0 {
+		return ErrKey
+	}
+	if len(sigs) == 0 {
+		return ErrNoSig
+	}
+	if !strings.EqualFold(keys[0].Hdr.Name, ta.Zone) {
+		return ErrKey
+	}
+
+	rrset := make([]RR, len(keys))
+	for i, k := range keys {
+		rrset[i] = k
+	}
+	for _, k := range keys {
+		i := ta.index(k)
+		if i < 0 || (ta.keys[i].State != TrustAnchorValid && ta.keys[i].State != TrustAnchorMissing) {
+			continue
+		}
+		if signedBy(k, rrset, sigs, now) {
+			return nil
+		}
+	}
+	return ErrSig
+}
+
+// index returns the index of the tracked key matching k, ignoring the REVOKE
+// flag, or -1 if k is not tracked.
+func (ta *TrustAnchor) index(k *DNSKEY) int {
+	for i, t := range ta.keys {
+		if t.Key.Algorithm == k.Algorithm && t.Key.Protocol == k.Protocol && t.Key.PublicKey == k.PublicKey {
+			return i
+		}
+	}
+	return -1
+}
+
+// signedBy returns true if one of the sigs is a valid signature of k over
+// rrset at time now.
+func signedBy(k *DNSKEY, rrset []RR, sigs []*RRSIG, now time.Time) bool {
+	tag := k.KeyTag()
+	for _, sig := range sigs {
+		if sig.KeyTag != tag || sig.Algorithm != k.Algorithm || sig.TypeCovered != TypeDNSKEY {
+			continue
+		}
+		if !sig.ValidityPeriod(now) {
+			continue
+		}
+		if sig.Verify(k, rrset) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// splitKeySet splits rrs into the DNSKEY records and the RRSIGs.
+func splitKeySet(rrs []RR) ([]*DNSKEY, []*RRSIG) {
+	var (
+		keys []*DNSKEY
+		sigs []*RRSIG
+	)
+	for _, r := range rrs {
+		switch r := r.(type) {
+		case *DNSKEY:
+			keys = append(keys, r)
+		case *RRSIG:
+			sigs = append(sigs, r)
+		}
+	}
+	return keys, sigs
+}
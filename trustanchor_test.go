@@ -0,0 +1,161 @@
+package dns
+
+import (
+	"crypto"
+	"testing"
+	"time"
+)
+
+type memTrustAnchorStore map[string][]TrustAnchorKey
+
+func (m memTrustAnchorStore) Load(zone string) ([]TrustAnchorKey, error) { return m[zone], nil }
+
+func (m memTrustAnchorStore) Save(zone string, keys []TrustAnchorKey) error {
+	m[zone] = append([]TrustAnchorKey(nil), keys...)
+	return nil
+}
+
+func newTestKSK(t *testing.T, zone string) (*DNSKEY, crypto.Signer) {
+	key := &DNSKEY{
+		Hdr:       RR_Header{Name: zone, Rrtype: TypeDNSKEY, Class: ClassINET, Ttl: 3600},
+		Flags:     ZONE | SEP,
+		Protocol:  3,
+		Algorithm: ED25519,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, priv.(crypto.Signer)
+}
+
+// signKeySet returns the keys and the RRSIGs made by each of the signers.
+func signKeySet(t *testing.T, now time.Time, keys []*DNSKEY, signers map[*DNSKEY]crypto.Signer) []RR {
+	var rrset []RR
+	for _, k := range keys {
+		rrset = append(rrset, k)
+	}
+	rrs := append([]RR(nil), rrset...)
+	for k, s := range signers {
+		sig := &RRSIG{
+			Hdr:        RR_Header{Rrtype: TypeRRSIG, Class: ClassINET, Ttl: 3600},
+			Inception:  uint32(now.Add(-time.Hour).Unix()),
+			Expiration: uint32(now.Add(time.Hour).Unix()),
+			KeyTag:     k.KeyTag(),
+			SignerName: k.Hdr.Name,
+			Algorithm:  k.Algorithm,
+		}
+		if err := sig.Sign(s, rrset); err != nil {
+			t.Fatal(err)
+		}
+		rrs = append(rrs, sig)
+	}
+	return rrs
+}
+
+func TestTrustAnchorRollover(t *testing.T) {
+	const zone = "example.org."
+	store := memTrustAnchorStore{}
+
+	key1, signer1 := newTestKSK(t, zone)
+	key2, signer2 := newTestKSK(t, zone)
+
+	ta, err := NewTrustAnchor(zone, store, key1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := func(k *DNSKEY) TrustAnchorState {
+		for _, tk := range ta.Keys() {
+			if tk.Key.PublicKey == k.PublicKey {
+				return tk.State
+			}
+		}
+		return TrustAnchorStart
+	}
+
+	now := time.Now()
+
+	// An RRset not signed by an anchor is refused.
+	if err := ta.Update(signKeySet(t, now, []*DNSKEY{key1, key2}, map[*DNSKEY]crypto.Signer{key2: signer2}), now); err == nil {
+		t.Fatal("expected error for RRset not signed by an anchor")
+	}
+
+	// A new key is added, and becomes valid after the hold-down time.
+	if err := ta.Update(signKeySet(t, now, []*DNSKEY{key1, key2}, map[*DNSKEY]crypto.Signer{key1: signer1}), now); err != nil {
+		t.Fatal(err)
+	}
+	if s := state(key2); s != TrustAnchorAddPend {
+		t.Fatalf("expected new key to be %s, got %s", TrustAnchorAddPend, s)
+	}
+	if len(ta.Anchors()) != 1 {
+		t.Fatalf("expected 1 anchor, got %d", len(ta.Anchors()))
+	}
+
+	now = now.Add(DefaultAddHoldDown)
+	if err := ta.Update(signKeySet(t, now, []*DNSKEY{key1, key2}, map[*DNSKEY]crypto.Signer{key1: signer1}), now); err != nil {
+		t.Fatal(err)
+	}
+	if s := state(key2); s != TrustAnchorValid {
+		t.Fatalf("expected new key to be %s, got %s", TrustAnchorValid, s)
+	}
+	if len(ta.Anchors()) != 2 {
+		t.Fatalf("expected 2 anchors, got %d", len(ta.Anchors()))
+	}
+
+	// The old key is revoked.
+	key1r := key1.copy().(*DNSKEY)
+	key1r.Flags |= REVOKE
+	rrs := signKeySet(t, now, []*DNSKEY{key1r, key2}, map[*DNSKEY]crypto.Signer{key1r: signer1, key2: signer2})
+	if err := ta.Update(rrs, now); err != nil {
+		t.Fatal(err)
+	}
+	if s := state(key1); s != TrustAnchorRevoked {
+		t.Fatalf("expected old key to be %s, got %s", TrustAnchorRevoked, s)
+	}
+	if a := ta.Anchors(); len(a) != 1 || a[0].PublicKey != key2.PublicKey {
+		t.Fatalf("expected only the new key as anchor, got %v", a)
+	}
+
+	// After the remove hold-down time it is forgotten.
+	now = now.Add(DefaultRemoveHoldDown)
+	if err := ta.Update(signKeySet(t, now, []*DNSKEY{key2}, map[*DNSKEY]crypto.Signer{key2: signer2}), now); err != nil {
+		t.Fatal(err)
+	}
+	if len(ta.Keys()) != 1 {
+		t.Fatalf("expected 1 tracked key, got %d", len(ta.Keys()))
+	}
+
+	// The state survives a restart.
+	ta1, err := NewTrustAnchor(zone, store, key1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := ta1.Anchors(); len(a) != 1 || a[0].PublicKey != key2.PublicKey {
+		t.Fatalf("expected the new key as anchor after reload, got %v", a)
+	}
+}
+
+func TestTrustAnchorMissing(t *testing.T) {
+	const zone = "example.org."
+	key1, signer1 := newTestKSK(t, zone)
+	key2, signer2 := newTestKSK(t, zone)
+
+	ta, err := NewTrustAnchor(zone, nil, key1, key2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := ta.Update(signKeySet(t, now, []*DNSKEY{key2}, map[*DNSKEY]crypto.Signer{key2: signer2}), now); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range ta.Keys() {
+		if k.Key.PublicKey == key1.PublicKey && k.State != TrustAnchorMissing {
+			t.Fatalf("expected absent key to be %s, got %s", TrustAnchorMissing, k.State)
+		}
+	}
+	// Missing keys are still trusted.
+	if err := ta.Verify(signKeySet(t, now, []*DNSKEY{key1}, map[*DNSKEY]crypto.Signer{key1: signer1}), now); err != nil {
+		t.Fatalf("expected missing key to still be trusted: %v", err)
+	}
+}
@@ -20,6 +20,8 @@ const (
 	HmacSHA1   = "hmac-sha1."
 	HmacSHA256 = "hmac-sha256."
 	HmacSHA512 = "hmac-sha512."
+
+	GssTsig = "gss-tsig." // RFC 3645, see the build-tagged (gssapi) GSS TsigProvider.
 )
 
 // TSIG is the RR the holds the transaction signature of a message.
@@ -83,6 +85,93 @@ type timerWireFmt struct {
 	Fudge      uint16
 }
 
+// TsigProvider provides the API to plug-in a custom TSIG implementation, for
+// instance GSS-TSIG (RFC 3645). Use it instead of a map of secrets with the
+// TsigProvider fields of Client, Server, Transfer and Conn.
+type TsigProvider interface {
+	// Generate is passed the DNS message to be signed and the partial TSIG
+	// RR. It returns the signature and nil, otherwise an error.
+	Generate(msg []byte, t *TSIG) ([]byte, error)
+	// Verify is passed the DNS message to be verified and the TSIG RR. If the
+	// signature is valid it will return nil, otherwise an error.
+	Verify(msg []byte, t *TSIG) error
+}
+
+// tsigHMACProvider implements TsigProvider for the HMAC algorithms with a
+// single base64 encoded secret.
+type tsigHMACProvider string
+
+func (key tsigHMACProvider) Generate(msg []byte, t *TSIG) ([]byte, error) {
+	// If we barf here, the caller is to blame
+	rawsecret, err := fromBase64([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	var h hash.Hash
+	switch strings.ToLower(t.Algorithm) {
+	case HmacMD5:
+		h = hmac.New(md5.New, rawsecret)
+	case HmacSHA1:
+		h = hmac.New(sha1.New, rawsecret)
+	case HmacSHA256:
+		h = hmac.New(sha256.New, rawsecret)
+	case HmacSHA512:
+		h = hmac.New(sha512.New, rawsecret)
+	default:
+		return nil, ErrKeyAlg
+	}
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+func (key tsigHMACProvider) Verify(msg []byte, t *TSIG) error {
+	b, err := key.Generate(msg, t)
+	if err != nil {
+		return err
+	}
+	mac, err := hex.DecodeString(t.MAC)
+	if err != nil {
+		return err
+	}
+	if len(mac) < len(b) {
+		// A truncated MAC (RFC 4635, section 3.1).
+		if !tsigTruncOK(len(mac), len(b)) {
+			return ErrBadTrunc
+		}
+		b = b[:len(mac)]
+	}
+	if !hmac.Equal(b, mac) {
+		return ErrSig
+	}
+	return nil
+}
+
+// tsigTruncOK reports whether a MAC of n bytes is an acceptable truncation of
+// a MAC of size bytes: at least 10 bytes, and half of the full size.
+func tsigTruncOK(n, size int) bool {
+	return n >= 10 && n >= (size+1)/2
+}
+
+// tsigSecretProvider implements TsigProvider for a map of key names to base64
+// encoded secrets, as used in the TsigSecret fields.
+type tsigSecretProvider map[string]string
+
+func (ts tsigSecretProvider) Generate(msg []byte, t *TSIG) ([]byte, error) {
+	key, ok := ts[t.Hdr.Name]
+	if !ok {
+		return nil, ErrSecret
+	}
+	return tsigHMACProvider(key).Generate(msg, t)
+}
+
+func (ts tsigSecretProvider) Verify(msg []byte, t *TSIG) error {
+	key, ok := ts[t.Hdr.Name]
+	if !ok {
+		return ErrSecret
+	}
+	return tsigHMACProvider(key).Verify(msg, t)
+}
+
 // TsigGenerate fills out the TSIG record attached to the message.
 // The message should contain
 // a "stub" TSIG RR with the algorithm, key name (owner name of the RR),
@@ -90,16 +179,18 @@ type timerWireFmt struct {
 // The TSIG MAC is saved in that Tsig RR.
 // When TsigGenerate is called for the first time requestMAC is set to the empty string and
 // timersOnly is false.
+// When the MACSize of the stub is set, and smaller than the size of the MAC,
+// the MAC is truncated to MACSize bytes (RFC 4635, section 3.1).
 // If something goes wrong an error is returned, otherwise it is nil.
 func TsigGenerate(m *Msg, secret, requestMAC string, timersOnly bool) ([]byte, string, error) {
+	return TsigGenerateWithProvider(m, tsigHMACProvider(secret), requestMAC, timersOnly)
+}
+
+// TsigGenerateWithProvider is similar to TsigGenerate, but allows for a custom TsigProvider.
+func TsigGenerateWithProvider(m *Msg, provider TsigProvider, requestMAC string, timersOnly bool) ([]byte, string, error) {
 	if m.IsTsig() == nil {
 		panic("dns: TSIG not last RR in additional")
 	}
-	// If we barf here, the caller is to blame
-	rawsecret, err := fromBase64([]byte(secret))
-	if err != nil {
-		return nil, "", err
-	}
 
 	rr := m.Extra[len(m.Extra)-1].(*TSIG)
 	m.Extra = m.Extra[0 : len(m.Extra)-1] // kill the TSIG from the msg
@@ -107,30 +198,25 @@ func TsigGenerate(m *Msg, secret, requestMAC string, timersOnly bool) ([]byte, s
 	if err != nil {
 		return nil, "", err
 	}
-	buf := tsigBuffer(mbuf, rr, requestMAC, timersOnly)
+	buf := tsigBuffer(nil, mbuf, rr, requestMAC, timersOnly)
 
 	t := new(TSIG)
-	var h hash.Hash
-	switch strings.ToLower(rr.Algorithm) {
-	case HmacMD5:
-		h = hmac.New(md5.New, []byte(rawsecret))
-	case HmacSHA1:
-		h = hmac.New(sha1.New, []byte(rawsecret))
-	case HmacSHA256:
-		h = hmac.New(sha256.New, []byte(rawsecret))
-	case HmacSHA512:
-		h = hmac.New(sha512.New, []byte(rawsecret))
-	default:
-		return nil, "", ErrKeyAlg
+	// Copy all TSIG fields except MAC and its size, which are filled using the computed digest.
+	*t = *rr
+	mac, err := provider.Generate(buf, rr)
+	if err != nil {
+		return nil, "", err
+	}
+	if n := int(rr.MACSize); n > 0 && n < len(mac) {
+		if !tsigTruncOK(n, len(mac)) {
+			return nil, "", ErrBadTrunc
+		}
+		mac = mac[:n]
 	}
-	h.Write(buf)
-	t.MAC = hex.EncodeToString(h.Sum(nil))
+	t.MAC = hex.EncodeToString(mac)
 	t.MACSize = uint16(len(t.MAC) / 2) // Size is half!
 
 	t.Hdr = RR_Header{Name: rr.Hdr.Name, Rrtype: TypeTSIG, Class: ClassANY, Ttl: 0}
-	t.Fudge = rr.Fudge
-	t.TimeSigned = rr.TimeSigned
-	t.Algorithm = rr.Algorithm
 	t.OrigId = m.Id
 
 	tbuf := make([]byte, Len(t))
@@ -150,22 +236,24 @@ func TsigGenerate(m *Msg, secret, requestMAC string, timersOnly bool) ([]byte, s
 // If the signature does not validate err contains the
 // error, otherwise it is nil.
 func TsigVerify(msg []byte, secret, requestMAC string, timersOnly bool) error {
-	rawsecret, err := fromBase64([]byte(secret))
-	if err != nil {
-		return err
-	}
+	return TsigVerifyWithProvider(msg, tsigHMACProvider(secret), requestMAC, timersOnly)
+}
+
+// TsigVerifyWithProvider is similar to TsigVerify, but allows for a custom TsigProvider.
+func TsigVerifyWithProvider(msg []byte, provider TsigProvider, requestMAC string, timersOnly bool) error {
+	return tsigVerify(nil, msg, provider, requestMAC, timersOnly)
+}
+
+// tsigVerify verifies the TSIG on msg, which signs the unsigned messages in
+// prior too, as the intermediate messages of a zone transfer do.
+func tsigVerify(prior, msg []byte, provider TsigProvider, requestMAC string, timersOnly bool) error {
 	// Strip the TSIG from the incoming msg
 	stripped, tsig, err := stripTsig(msg)
 	if err != nil {
 		return err
 	}
 
-	msgMAC, err := hex.DecodeString(tsig.MAC)
-	if err != nil {
-		return err
-	}
-
-	buf := tsigBuffer(stripped, tsig, requestMAC, timersOnly)
+	buf := tsigBuffer(prior, stripped, tsig, requestMAC, timersOnly)
 
 	// Fudge factor works both ways. A message can arrive before it was signed because
 	// of clock skew.
@@ -178,28 +266,12 @@ func TsigVerify(msg []byte, secret, requestMAC string, timersOnly bool) error {
 		return ErrTime
 	}
 
-	var h hash.Hash
-	switch strings.ToLower(tsig.Algorithm) {
-	case HmacMD5:
-		h = hmac.New(md5.New, rawsecret)
-	case HmacSHA1:
-		h = hmac.New(sha1.New, rawsecret)
-	case HmacSHA256:
-		h = hmac.New(sha256.New, rawsecret)
-	case HmacSHA512:
-		h = hmac.New(sha512.New, rawsecret)
-	default:
-		return ErrKeyAlg
-	}
-	h.Write(buf)
-	if !hmac.Equal(h.Sum(nil), msgMAC) {
-		return ErrSig
-	}
-	return nil
+	return provider.Verify(buf, tsig)
 }
 
-// Create a wiredata buffer for the MAC calculation.
-func tsigBuffer(msgbuf []byte, rr *TSIG, requestMAC string, timersOnly bool) []byte {
+// Create a wiredata buffer for the MAC calculation. The unsigned messages in
+// prior, if any, go between the request MAC and msgbuf.
+func tsigBuffer(prior, msgbuf []byte, rr *TSIG, requestMAC string, timersOnly bool) []byte {
 	var buf []byte
 	if rr.TimeSigned == 0 {
 		rr.TimeSigned = uint64(time.Now().Unix())
@@ -242,8 +314,8 @@ func tsigBuffer(msgbuf []byte, rr *TSIG, requestMAC string, timersOnly bool) []b
 		tsigvar = tsigvar[:n]
 	}
 
-	if requestMAC != "" {
-		x := append(buf, msgbuf...)
+	if requestMAC != "" || len(prior) > 0 {
+		x := append(append(buf, prior...), msgbuf...)
 		buf = append(x, tsigvar...)
 	} else {
 		buf = append(msgbuf, tsigvar...)
@@ -273,7 +345,7 @@ func stripTsig(msg []byte) ([]byte, *TSIG, error) {
 	}
 
 	for i := 0; i < int(dh.Qdcount); i++ {
-		_, off, err = unpackQuestion(msg, off)
+		_, off, err = unpackQuestion(msg, off, nil)
 		if err != nil {
 			return nil, nil, err
 		}
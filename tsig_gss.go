@@ -0,0 +1,277 @@
+// +build gssapi,cgo
+
+package dns
+
+/*
+#cgo LDFLAGS: -lgssapi_krb5
+#include <stdlib.h>
+#include <gssapi/gssapi.h>
+*/
+import "C"
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// GSS implements TsigProvider for GSS-TSIG, see RFC 3645. It is used for
+// signing dynamic updates towards, for instance, Active Directory DNS servers.
+// Each TSIG key name maps to an established GSS-API security context.
+//
+// This provider uses the system's GSS-API library and is only available when
+// building with cgo and the gssapi build tag.
+//
+// Security contexts are established with TKEY, see (*Client).TkeyGSS and
+// TkeyHandler.
+type GSS struct {
+	mu      sync.RWMutex
+	ctx     map[string]C.gss_ctx_id_t
+	pending map[string]C.gss_ctx_id_t // contexts being established
+}
+
+var _ TkeyGSS = (*GSS)(nil)
+
+// NewGSS returns a GSS TsigProvider without any security contexts.
+func NewGSS() *GSS {
+	return &GSS{ctx: make(map[string]C.gss_ctx_id_t), pending: make(map[string]C.gss_ctx_id_t)}
+}
+
+// Generate implements the TsigProvider.Generate method.
+func (g *GSS) Generate(msg []byte, t *TSIG) ([]byte, error) {
+	if strings.ToLower(t.Algorithm) != GssTsig {
+		return nil, ErrKeyAlg
+	}
+	ctx, ok := g.context(t.Hdr.Name)
+	if !ok {
+		return nil, ErrSecret
+	}
+
+	in := C.gss_buffer_desc{length: C.size_t(len(msg)), value: C.CBytes(msg)}
+	defer C.free(in.value)
+
+	var (
+		minor C.OM_uint32
+		out   C.gss_buffer_desc
+	)
+	if major := C.gss_get_mic(&minor, ctx, 0, &in, &out); major != C.GSS_S_COMPLETE {
+		return nil, gssError("gss_get_mic", major, minor)
+	}
+	defer C.gss_release_buffer(&minor, &out)
+
+	return C.GoBytes(out.value, C.int(out.length)), nil
+}
+
+// Verify implements the TsigProvider.Verify method.
+func (g *GSS) Verify(msg []byte, t *TSIG) error {
+	if strings.ToLower(t.Algorithm) != GssTsig {
+		return ErrKeyAlg
+	}
+	ctx, ok := g.context(t.Hdr.Name)
+	if !ok {
+		return ErrSecret
+	}
+	mac, err := hex.DecodeString(t.MAC)
+	if err != nil {
+		return err
+	}
+
+	in := C.gss_buffer_desc{length: C.size_t(len(msg)), value: C.CBytes(msg)}
+	defer C.free(in.value)
+	token := C.gss_buffer_desc{length: C.size_t(len(mac)), value: C.CBytes(mac)}
+	defer C.free(token.value)
+
+	var minor C.OM_uint32
+	if major := C.gss_verify_mic(&minor, ctx, &in, &token, nil); major != C.GSS_S_COMPLETE {
+		return ErrSig
+	}
+	return nil
+}
+
+// InitSecContext implements the TkeyGSS.InitSecContext method. The default
+// credentials of the process are used, e.g. from the Kerberos ticket cache.
+func (g *GSS) InitSecContext(keyname, target string, input []byte) ([]byte, bool, error) {
+	keyname = strings.ToLower(keyname)
+
+	var minor C.OM_uint32
+	tbuf := C.gss_buffer_desc{length: C.size_t(len(target)), value: unsafe.Pointer(C.CString(target))}
+	defer C.free(tbuf.value)
+	var name C.gss_name_t
+	if major := C.gss_import_name(&minor, &tbuf, C.GSS_C_NT_HOSTBASED_SERVICE, &name); major != C.GSS_S_COMPLETE {
+		return nil, false, gssError("gss_import_name", major, minor)
+	}
+	defer C.gss_release_name(&minor, &name)
+
+	in, free := gssInputBuffer(input)
+	defer free()
+
+	g.mu.Lock()
+	ctx := g.pending[keyname]
+	g.mu.Unlock()
+
+	var out C.gss_buffer_desc
+	major := C.gss_init_sec_context(&minor, nil, &ctx, name, nil,
+		C.GSS_C_MUTUAL_FLAG|C.GSS_C_REPLAY_FLAG|C.GSS_C_SEQUENCE_FLAG|C.GSS_C_INTEG_FLAG,
+		0, nil, in, nil, &out, nil, nil)
+	return g.step(keyname, ctx, "gss_init_sec_context", major, minor, &out)
+}
+
+// AcceptSecContext implements the TkeyGSS.AcceptSecContext method. The default
+// credentials of the process are used, e.g. from the Kerberos keytab.
+func (g *GSS) AcceptSecContext(keyname string, input []byte) ([]byte, bool, error) {
+	keyname = strings.ToLower(keyname)
+
+	in, free := gssInputBuffer(input)
+	defer free()
+
+	g.mu.Lock()
+	ctx := g.pending[keyname]
+	g.mu.Unlock()
+
+	var (
+		minor C.OM_uint32
+		out   C.gss_buffer_desc
+	)
+	major := C.gss_accept_sec_context(&minor, &ctx, nil, in, nil, nil, nil, &out, nil, nil, nil)
+	return g.step(keyname, ctx, "gss_accept_sec_context", major, minor, &out)
+}
+
+// step handles the result of a context establishment call, it returns the
+// output token and whether the context is complete.
+func (g *GSS) step(keyname string, ctx C.gss_ctx_id_t, call string, major, minor C.OM_uint32, out *C.gss_buffer_desc) ([]byte, bool, error) {
+	var output []byte
+	if out.length > 0 {
+		output = C.GoBytes(out.value, C.int(out.length))
+		var m C.OM_uint32
+		C.gss_release_buffer(&m, out)
+	}
+
+	switch major {
+	case C.GSS_S_COMPLETE:
+		g.mu.Lock()
+		delete(g.pending, keyname)
+		g.mu.Unlock()
+		g.setContext(keyname, ctx)
+		return output, true, nil
+	case C.GSS_S_CONTINUE_NEEDED:
+		g.mu.Lock()
+		g.pending[keyname] = ctx
+		g.mu.Unlock()
+		return output, false, nil
+	}
+
+	g.mu.Lock()
+	delete(g.pending, keyname)
+	g.mu.Unlock()
+	if ctx != nil {
+		var m C.OM_uint32
+		C.gss_delete_sec_context(&m, &ctx, nil)
+	}
+	return nil, false, gssError(call, major, minor)
+}
+
+// gssInputBuffer returns a GSS-API buffer holding a copy of b, or nil when b is
+// empty, and a function that frees it.
+func gssInputBuffer(b []byte) (C.gss_buffer_t, func()) {
+	if len(b) == 0 {
+		return nil, func() {}
+	}
+	buf := (*C.gss_buffer_desc)(C.malloc(C.size_t(unsafe.Sizeof(C.gss_buffer_desc{}))))
+	buf.length, buf.value = C.size_t(len(b)), C.CBytes(b)
+	return buf, func() {
+		C.free(buf.value)
+		C.free(unsafe.Pointer(buf))
+	}
+}
+
+// DeleteContext deletes the security context for the TSIG key name keyname.
+func (g *GSS) DeleteContext(keyname string) error {
+	keyname = strings.ToLower(keyname)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ctx, ok := g.ctx[keyname]
+	if !ok {
+		return ErrSecret
+	}
+	delete(g.ctx, keyname)
+
+	var minor C.OM_uint32
+	if major := C.gss_delete_sec_context(&minor, &ctx, nil); major != C.GSS_S_COMPLETE {
+		return gssError("gss_delete_sec_context", major, minor)
+	}
+	return nil
+}
+
+// Close deletes all security contexts.
+func (g *GSS) Close() error {
+	g.mu.RLock()
+	names := make([]string, 0, len(g.ctx))
+	for name := range g.ctx {
+		names = append(names, name)
+	}
+	g.mu.RUnlock()
+
+	var err error
+	for _, name := range names {
+		if e := g.DeleteContext(name); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (g *GSS) context(keyname string) (C.gss_ctx_id_t, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	ctx, ok := g.ctx[strings.ToLower(keyname)]
+	return ctx, ok
+}
+
+// setContext stores an established security context for keyname, an existing
+// context for the same key is deleted.
+func (g *GSS) setContext(keyname string, ctx C.gss_ctx_id_t) {
+	keyname = strings.ToLower(keyname)
+
+	g.mu.Lock()
+	old, ok := g.ctx[keyname]
+	g.ctx[keyname] = ctx
+	g.mu.Unlock()
+
+	if ok {
+		var minor C.OM_uint32
+		C.gss_delete_sec_context(&minor, &old, nil)
+	}
+}
+
+// gssError turns the major and minor status codes of a failed GSS-API call
+// into an error.
+func gssError(call string, major, minor C.OM_uint32) error {
+	return &Error{err: fmt.Sprintf("gss-tsig: %s failed: %s (minor status %d)", call, gssDisplayStatus(major), uint32(minor))}
+}
+
+func gssDisplayStatus(status C.OM_uint32) string {
+	var (
+		minor C.OM_uint32
+		more  C.OM_uint32
+		out   C.gss_buffer_desc
+		msgs  []string
+	)
+	for {
+		if C.gss_display_status(&minor, status, C.GSS_C_GSS_CODE, nil, &more, &out) != C.GSS_S_COMPLETE {
+			break
+		}
+		msgs = append(msgs, C.GoStringN((*C.char)(unsafe.Pointer(out.value)), C.int(out.length)))
+		C.gss_release_buffer(&minor, &out)
+		if more == 0 {
+			break
+		}
+	}
+	if len(msgs) == 0 {
+		return fmt.Sprintf("major status %#x", uint32(status))
+	}
+	return strings.Join(msgs, ", ")
+}
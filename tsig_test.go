@@ -2,6 +2,7 @@ package dns
 
 import (
 	"encoding/binary"
+	"encoding/hex"
 	"testing"
 	"time"
 )
@@ -50,3 +51,90 @@ func TestTsigCase(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// xorTsigProvider is a toy TsigProvider that "signs" by xor-ing the message.
+type xorTsigProvider byte
+
+func (x xorTsigProvider) Generate(msg []byte, t *TSIG) ([]byte, error) {
+	var sum byte
+	for _, b := range msg {
+		sum ^= b
+	}
+	return []byte{sum ^ byte(x)}, nil
+}
+
+func (x xorTsigProvider) Verify(msg []byte, t *TSIG) error {
+	b, _ := x.Generate(msg, t)
+	if t.MAC != hex.EncodeToString(b) {
+		return ErrSig
+	}
+	return nil
+}
+
+func TestTsigProvider(t *testing.T) {
+	m := newTsig("xor.example.")
+	buf, mac, err := TsigGenerateWithProvider(m, xorTsigProvider(42), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mac) != 2 {
+		t.Fatalf("expected a one byte MAC, got %q", mac)
+	}
+	// TsigVerify modifies the buffer.
+	buf1 := append([]byte(nil), buf...)
+	if err := TsigVerifyWithProvider(buf, xorTsigProvider(42), "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := TsigVerifyWithProvider(buf1, xorTsigProvider(43), "", false); err != ErrSig {
+		t.Fatalf("expected %v, got %v", ErrSig, err)
+	}
+
+	// A map of secrets without the key name.
+	m = newTsig(HmacSHA256)
+	if _, _, err := TsigGenerateWithProvider(m, tsigSecretProvider{"other.": "pRZgBrBvI4NAHZYhxmhs/Q=="}, "", false); err != ErrSecret {
+		t.Fatalf("expected %v, got %v", ErrSecret, err)
+	}
+}
+
+func TestTsigTruncated(t *testing.T) {
+	const secret = "pRZgBrBvI4NAHZYhxmhs/Q=="
+	m := newTsig(HmacSHA256)
+	m.Extra[0].(*TSIG).MACSize = 16
+	buf, mac, err := TsigGenerate(m, secret, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mac) != 32 {
+		t.Fatalf("expected a 16 byte MAC, got %q", mac)
+	}
+	if err := TsigVerify(buf, secret, "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Less than half of the 32 bytes of SHA-256.
+	m = newTsig(HmacSHA256)
+	m.Extra[0].(*TSIG).MACSize = 15
+	if _, _, err := TsigGenerate(m, secret, "", false); err != ErrBadTrunc {
+		t.Fatalf("expected %v, got %v", ErrBadTrunc, err)
+	}
+
+	// A MAC cut short after it was generated.
+	m = newTsig(HmacSHA256)
+	buf, _, err = TsigGenerate(m, secret, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := new(Msg)
+	if err := r.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	ts := r.IsTsig()
+	ts.MAC = ts.MAC[:18]
+	ts.MACSize = 9
+	if buf, err = r.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := TsigVerify(buf, secret, "", false); err != ErrBadTrunc {
+		t.Fatalf("expected %v, got %v", ErrBadTrunc, err)
+	}
+}
@@ -0,0 +1,57 @@
+package dns
+
+import "time"
+
+// ClampTTL returns ttl limited to the range from min to max, a max of zero
+// is no limit.
+func ClampTTL(ttl, min, max uint32) uint32 {
+	if max != 0 && ttl > max {
+		ttl = max
+	}
+	if ttl < min {
+		ttl = min
+	}
+	return ttl
+}
+
+// MinTTL returns the lowest TTL of the records in rrs, OPT records aside,
+// or 0 if there are none.
+func MinTTL(rrs []RR) uint32 {
+	var min uint32
+	found := false
+	for _, rr := range rrs {
+		h := rr.Header()
+		if h.Rrtype == TypeOPT {
+			continue
+		}
+		if !found || h.Ttl < min {
+			min, found = h.Ttl, true
+		}
+	}
+	return min
+}
+
+// DecrementTTL lowers the TTLs of the records in rrs, OPT records aside, by
+// elapsed, rounded down to seconds, as a cache does with the records it
+// hands out. TTLs don't go below zero. It reports whether all TTLs are still
+// positive.
+func DecrementTTL(rrs []RR, elapsed time.Duration) bool {
+	age := uint32(elapsed / time.Second)
+	if elapsed >= time.Duration(1<<32)*time.Second {
+		age = 1<<32 - 1
+	}
+	live := true
+	for _, rr := range rrs {
+		h := rr.Header()
+		if h.Rrtype == TypeOPT {
+			continue
+		}
+		if h.Ttl <= age {
+			h.Ttl = 0
+			live = false
+			continue
+		}
+		h.Ttl -= age
+	}
+	return live
+}
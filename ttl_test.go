@@ -0,0 +1,39 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClampTTL(t *testing.T) {
+	if ttl := ClampTTL(10, 60, 3600); ttl != 60 {
+		t.Errorf("expected 60, got %d", ttl)
+	}
+	if ttl := ClampTTL(86400, 60, 3600); ttl != 3600 {
+		t.Errorf("expected 3600, got %d", ttl)
+	}
+	if ttl := ClampTTL(86400, 0, 0); ttl != 86400 {
+		t.Errorf("expected 86400, got %d", ttl)
+	}
+}
+
+func TestDecrementTTL(t *testing.T) {
+	m := new(Msg)
+	m.Answer = []RR{
+		testRR("example.org. 300 IN A 192.0.2.1"),
+		testRR("example.org. 60 IN A 192.0.2.2"),
+	}
+	m.SetEdns0(4096, true)
+	if ttl := MinTTL(append(m.Answer, m.Extra...)); ttl != 60 {
+		t.Errorf("expected a minimum TTL of 60, got %d", ttl)
+	}
+	if !DecrementTTL(m.Answer, 59500*time.Millisecond) || m.Answer[0].Header().Ttl != 241 || m.Answer[1].Header().Ttl != 1 {
+		t.Errorf("expected TTLs of 241 and 1, got %v", m.Answer)
+	}
+	if DecrementTTL(append(m.Answer, m.Extra...), 2*time.Second) || m.Answer[1].Header().Ttl != 0 {
+		t.Errorf("expected an expired TTL, got %v", m.Answer)
+	}
+	if opt := m.IsEdns0(); !opt.Do() || opt.UDPSize() != 4096 {
+		t.Errorf("expected the OPT record untouched, got %v", opt)
+	}
+}
@@ -21,91 +21,9 @@ type (
 
 // Wire constants and supported types.
 const (
-	// valid RR_Header.Rrtype and Question.qtype
-
-	TypeNone       uint16 = 0
-	TypeA          uint16 = 1
-	TypeNS         uint16 = 2
-	TypeMD         uint16 = 3
-	TypeMF         uint16 = 4
-	TypeCNAME      uint16 = 5
-	TypeSOA        uint16 = 6
-	TypeMB         uint16 = 7
-	TypeMG         uint16 = 8
-	TypeMR         uint16 = 9
-	TypeNULL       uint16 = 10
-	TypePTR        uint16 = 12
-	TypeHINFO      uint16 = 13
-	TypeMINFO      uint16 = 14
-	TypeMX         uint16 = 15
-	TypeTXT        uint16 = 16
-	TypeRP         uint16 = 17
-	TypeAFSDB      uint16 = 18
-	TypeX25        uint16 = 19
-	TypeISDN       uint16 = 20
-	TypeRT         uint16 = 21
-	TypeNSAPPTR    uint16 = 23
-	TypeSIG        uint16 = 24
-	TypeKEY        uint16 = 25
-	TypePX         uint16 = 26
-	TypeGPOS       uint16 = 27
-	TypeAAAA       uint16 = 28
-	TypeLOC        uint16 = 29
-	TypeNXT        uint16 = 30
-	TypeEID        uint16 = 31
-	TypeNIMLOC     uint16 = 32
-	TypeSRV        uint16 = 33
-	TypeATMA       uint16 = 34
-	TypeNAPTR      uint16 = 35
-	TypeKX         uint16 = 36
-	TypeCERT       uint16 = 37
-	TypeDNAME      uint16 = 39
-	TypeOPT        uint16 = 41 // EDNS
-	TypeDS         uint16 = 43
-	TypeSSHFP      uint16 = 44
-	TypeRRSIG      uint16 = 46
-	TypeNSEC       uint16 = 47
-	TypeDNSKEY     uint16 = 48
-	TypeDHCID      uint16 = 49
-	TypeNSEC3      uint16 = 50
-	TypeNSEC3PARAM uint16 = 51
-	TypeTLSA       uint16 = 52
-	TypeSMIMEA     uint16 = 53
-	TypeHIP        uint16 = 55
-	TypeNINFO      uint16 = 56
-	TypeRKEY       uint16 = 57
-	TypeTALINK     uint16 = 58
-	TypeCDS        uint16 = 59
-	TypeCDNSKEY    uint16 = 60
-	TypeOPENPGPKEY uint16 = 61
-	TypeCSYNC      uint16 = 62
-	TypeSPF        uint16 = 99
-	TypeUINFO      uint16 = 100
-	TypeUID        uint16 = 101
-	TypeGID        uint16 = 102
-	TypeUNSPEC     uint16 = 103
-	TypeNID        uint16 = 104
-	TypeL32        uint16 = 105
-	TypeL64        uint16 = 106
-	TypeLP         uint16 = 107
-	TypeEUI48      uint16 = 108
-	TypeEUI64      uint16 = 109
-	TypeURI        uint16 = 256
-	TypeCAA        uint16 = 257
-	TypeAVC        uint16 = 258
-
-	TypeTKEY uint16 = 249
-	TypeTSIG uint16 = 250
-
-	// valid Question.Qtype only
-	TypeIXFR  uint16 = 251
-	TypeAXFR  uint16 = 252
-	TypeMAILB uint16 = 253
-	TypeMAILA uint16 = 254
-	TypeANY   uint16 = 255
-
-	TypeTA       uint16 = 32768
-	TypeDLV      uint16 = 32769
+	// The type codes of the RR types are generated from the IANA registry,
+	// see ziana.go.
+	TypeNone     uint16 = 0
 	TypeReserved uint16 = 65535
 
 	// valid Question.Qclass
@@ -117,33 +35,35 @@ const (
 	ClassANY    = 255
 
 	// Message Response Codes, see https://www.iana.org/assignments/dns-parameters/dns-parameters.xhtml
-	RcodeSuccess        = 0  // NoError   - No Error                          [DNS]
-	RcodeFormatError    = 1  // FormErr   - Format Error                      [DNS]
-	RcodeServerFailure  = 2  // ServFail  - Server Failure                    [DNS]
-	RcodeNameError      = 3  // NXDomain  - Non-Existent Domain               [DNS]
-	RcodeNotImplemented = 4  // NotImp    - Not Implemented                   [DNS]
-	RcodeRefused        = 5  // Refused   - Query Refused                     [DNS]
-	RcodeYXDomain       = 6  // YXDomain  - Name Exists when it should not    [DNS Update]
-	RcodeYXRrset        = 7  // YXRRSet   - RR Set Exists when it should not  [DNS Update]
-	RcodeNXRrset        = 8  // NXRRSet   - RR Set that should exist does not [DNS Update]
-	RcodeNotAuth        = 9  // NotAuth   - Server Not Authoritative for zone [DNS Update]
-	RcodeNotZone        = 10 // NotZone   - Name not contained in zone        [DNS Update/TSIG]
-	RcodeBadSig         = 16 // BADSIG    - TSIG Signature Failure            [TSIG]
-	RcodeBadVers        = 16 // BADVERS   - Bad OPT Version                   [EDNS0]
-	RcodeBadKey         = 17 // BADKEY    - Key not recognized                [TSIG]
-	RcodeBadTime        = 18 // BADTIME   - Signature out of time window      [TSIG]
-	RcodeBadMode        = 19 // BADMODE   - Bad TKEY Mode                     [TKEY]
-	RcodeBadName        = 20 // BADNAME   - Duplicate key name                [TKEY]
-	RcodeBadAlg         = 21 // BADALG    - Algorithm not supported           [TKEY]
-	RcodeBadTrunc       = 22 // BADTRUNC  - Bad Truncation                    [TSIG]
-	RcodeBadCookie      = 23 // BADCOOKIE - Bad/missing Server Cookie         [DNS Cookies]
+	RcodeSuccess                    = 0  // NoError   - No Error                          [DNS]
+	RcodeFormatError                = 1  // FormErr   - Format Error                      [DNS]
+	RcodeServerFailure              = 2  // ServFail  - Server Failure                    [DNS]
+	RcodeNameError                  = 3  // NXDomain  - Non-Existent Domain               [DNS]
+	RcodeNotImplemented             = 4  // NotImp    - Not Implemented                   [DNS]
+	RcodeRefused                    = 5  // Refused   - Query Refused                     [DNS]
+	RcodeYXDomain                   = 6  // YXDomain  - Name Exists when it should not    [DNS Update]
+	RcodeYXRrset                    = 7  // YXRRSet   - RR Set Exists when it should not  [DNS Update]
+	RcodeNXRrset                    = 8  // NXRRSet   - RR Set that should exist does not [DNS Update]
+	RcodeNotAuth                    = 9  // NotAuth   - Server Not Authoritative for zone [DNS Update]
+	RcodeNotZone                    = 10 // NotZone   - Name not contained in zone        [DNS Update/TSIG]
+	RcodeStatefulTypeNotImplemented = 11 // DSOTYPENI - DSO-TYPE Not Implemented          [DSO]
+	RcodeBadSig                     = 16 // BADSIG    - TSIG Signature Failure            [TSIG]
+	RcodeBadVers                    = 16 // BADVERS   - Bad OPT Version                   [EDNS0]
+	RcodeBadKey                     = 17 // BADKEY    - Key not recognized                [TSIG]
+	RcodeBadTime                    = 18 // BADTIME   - Signature out of time window      [TSIG]
+	RcodeBadMode                    = 19 // BADMODE   - Bad TKEY Mode                     [TKEY]
+	RcodeBadName                    = 20 // BADNAME   - Duplicate key name                [TKEY]
+	RcodeBadAlg                     = 21 // BADALG    - Algorithm not supported           [TKEY]
+	RcodeBadTrunc                   = 22 // BADTRUNC  - Bad Truncation                    [TSIG]
+	RcodeBadCookie                  = 23 // BADCOOKIE - Bad/missing Server Cookie         [DNS Cookies]
 
 	// Message Opcodes. There is no 3.
-	OpcodeQuery  = 0
-	OpcodeIQuery = 1
-	OpcodeStatus = 2
-	OpcodeNotify = 4
-	OpcodeUpdate = 5
+	OpcodeQuery    = 0
+	OpcodeIQuery   = 1
+	OpcodeStatus   = 2
+	OpcodeNotify   = 4
+	OpcodeUpdate   = 5
+	OpcodeStateful = 6
 )
 
 // Header is the wire format for the DNS packet header.
@@ -176,6 +96,16 @@ const (
 	LOC_ALTITUDEBASE  = 100000
 )
 
+// Relay types of the AMTRELAY RR, see RFC 8777, Section 4.2.3.
+const (
+	AMTRELAYNone = iota // no relay
+	AMTRELAYIPv4        // relay is an IPv4 address
+	AMTRELAYIPv6        // relay is an IPv6 address
+	AMTRELAYHost        // relay is a domain name
+
+	amtrelayDiscovery = 0x80 // the D bit, in the AMTRELAY GatewayType
+)
+
 // Different Certificate Types, see RFC 4398, Section 2.1
 const (
 	CertPKIX = 1 + iota
@@ -208,6 +138,7 @@ var CertTypeToString = map[uint16]string{
 // StringToCertType is the reverseof CertTypeToString.
 var StringToCertType = reverseInt16(CertTypeToString)
 
+//go:generate go run types_generate.go -iana dns-parameters-4.csv
 //go:generate go run types_generate.go
 
 // Question holds a DNS question. There can be multiple questions in the
@@ -388,7 +319,7 @@ type RP struct {
 }
 
 func (rr *RP) String() string {
-	return rr.Hdr.String() + rr.Mbox + " " + sprintTxt([]string{rr.Txt})
+	return rr.Hdr.String() + sprintName(rr.Mbox) + " " + sprintName(rr.Txt)
 }
 
 // SOA RR. See RFC 1035.
@@ -461,6 +392,9 @@ func sprintTxtOctet(s string) string {
 			i++ // dangling back slash
 		case b == '.':
 			dst.WriteByte('.')
+		case b == '"' || b == '\\':
+			dst.WriteByte('\\')
+			dst.WriteByte(b)
 		case b < ' ' || b > '~':
 			dst.WriteString(escapeByte(b))
 		default:
@@ -835,20 +769,6 @@ func (rr *NSEC) String() string {
 	return s
 }
 
-func (rr *NSEC) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.NextDomain, off+l, compression, false)
-	lastwindow := uint32(2 ^ 32 + 1)
-	for _, t := range rr.TypeBitMap {
-		window := t / 256
-		if uint32(window) != lastwindow {
-			l += 1 + 32
-		}
-		lastwindow = uint32(window)
-	}
-	return l
-}
-
 // DLV RR. See RFC 4431.
 type DLV struct{ DS }
 
@@ -1001,20 +921,6 @@ func (rr *NSEC3) String() string {
 	return s
 }
 
-func (rr *NSEC3) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 6 + len(rr.Salt)/2 + 1 + len(rr.NextDomain) + 1
-	lastwindow := uint32(2 ^ 32 + 1)
-	for _, t := range rr.TypeBitMap {
-		window := t / 256
-		if uint32(window) != lastwindow {
-			l += 1 + 32
-		}
-		lastwindow = uint32(window)
-	}
-	return l
-}
-
 // NSEC3PARAM RR. See RFC 5155.
 type NSEC3PARAM struct {
 	Hdr        RR_Header
@@ -1248,6 +1154,58 @@ type EUI64 struct {
 
 func (rr *EUI64) String() string { return rr.Hdr.String() + euiToString(rr.Address, 64) }
 
+// DOA RR. See https://datatracker.ietf.org/doc/draft-durand-doa-over-dns/.
+type DOA struct {
+	Hdr        RR_Header
+	Enterprise uint32
+	Type       uint32
+	Location   uint8
+	MediaType  string
+	Data       string `dns:"base64"`
+}
+
+func (rr *DOA) String() string {
+	data := rr.Data
+	if data == "" {
+		data = "-"
+	}
+	return rr.Hdr.String() + strconv.FormatUint(uint64(rr.Enterprise), 10) +
+		" " + strconv.FormatUint(uint64(rr.Type), 10) +
+		" " + strconv.Itoa(int(rr.Location)) +
+		" " + sprintTxt([]string{rr.MediaType}) + " " + data
+}
+
+// AMTRELAY RR. See RFC 8777.
+type AMTRELAY struct {
+	Hdr         RR_Header
+	Precedence  uint8
+	GatewayType uint8  // relay type, one of AMTRELAYNone, AMTRELAYIPv4, AMTRELAYIPv6 or AMTRELAYHost, with 0x80 set for the D bit
+	GatewayAddr net.IP `dns:"-"` // packed with GatewayHost
+	GatewayHost string `dns:"amtrelayhost"`
+}
+
+// Discovery returns true if the D bit of rr is set: the relay can be found
+// with the anycast discovery of RFC 7450 as well.
+func (rr *AMTRELAY) Discovery() bool { return rr.GatewayType&amtrelayDiscovery != 0 }
+
+func (rr *AMTRELAY) String() string {
+	d := "0"
+	if rr.Discovery() {
+		d = "1"
+	}
+	var relay string
+	switch rr.GatewayType &^ amtrelayDiscovery {
+	case AMTRELAYNone:
+		relay = "."
+	case AMTRELAYIPv4, AMTRELAYIPv6:
+		relay = rr.GatewayAddr.String()
+	case AMTRELAYHost:
+		relay = sprintName(rr.GatewayHost)
+	}
+	return rr.Hdr.String() + strconv.Itoa(int(rr.Precedence)) + " " + d +
+		" " + strconv.Itoa(int(rr.GatewayType&^amtrelayDiscovery)) + " " + relay
+}
+
 // CAA RR. See RFC 6844.
 type CAA struct {
 	Hdr   RR_Header
@@ -1325,20 +1283,6 @@ func (rr *CSYNC) String() string {
 	return s
 }
 
-func (rr *CSYNC) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 4 + 2
-	lastwindow := uint32(2 ^ 32 + 1)
-	for _, t := range rr.TypeBitMap {
-		window := t / 256
-		if uint32(window) != lastwindow {
-			l += 1 + 32
-		}
-		lastwindow = uint32(window)
-	}
-	return l
-}
-
 // TimeToString translates the RRSIG's incep. and expir. times to the
 // string representation used when printing the record.
 // It takes serial arithmetic (RFC 1982) into account.
@@ -1395,6 +1339,13 @@ func copyIP(ip net.IP) net.IP {
 	return p
 }
 
+// copyBytes returns a copy of b.
+func copyBytes(b []byte) []byte {
+	p := make([]byte, len(b))
+	copy(p, b)
+	return p
+}
+
 // SplitN splits a string into N sized string chunks.
 // This might become an exported function once.
 func splitN(s string, n int) []string {
@@ -2,40 +2,37 @@
 
 // types_generate.go is meant to run with go generate. It will use
 // go/{importer,types} to track down all the RR struct types. Then for each type
-// it will generate conversion tables (TypeToRR and TypeToString) and banal
-// methods (len, Header, copy) based on the struct tags. The generated source is
-// written to ztypes.go, and is meant to be checked into git.
+// it will generate conversion tables (TypeToRR) and banal methods (Header, copy,
+// MarshalJSON and UnmarshalJSON) based on the struct tags. The generated source is written to ztypes.go, and is
+// meant to be checked into git.
+//
+// With -iana it reads the Resource Record (RR) TYPEs registry as published by
+// IANA in CSV format, and generates the type code constants, TypeToString and
+// StringToType into ziana.go. Types in the registry that have no RR struct get
+// a type code, but no mnemonic, and are reported. The CSV is downloaded from
+// https://www.iana.org/assignments/dns-parameters/dns-parameters-4.csv.
 package main
 
 import (
 	"bytes"
+	"encoding/csv"
+	"flag"
 	"fmt"
 	"go/format"
 	"go/importer"
 	"go/types"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"text/template"
 )
 
-var skipLen = map[string]struct{}{
-	"NSEC":  {},
-	"NSEC3": {},
-	"OPT":   {},
-	"CSYNC": {},
-}
-
 var packageHdr = `
 // Code generated by "go run types_generate.go"; DO NOT EDIT.
 
 package dns
 
-import (
-	"encoding/base64"
-	"net"
-)
-
 `
 
 var TypeToRR = template.Must(template.New("TypeToRR").Parse(`
@@ -46,17 +43,26 @@ var TypeToRR = map[uint16]func() RR{
 
 `))
 
-var typeToString = template.Must(template.New("typeToString").Parse(`
-// TypeToString is a map of strings for each RR type.
-var TypeToString = map[uint16]string{
-{{range .}}{{if ne . "NSAPPTR"}}  Type{{.}}: "{{.}}",
-{{end}}{{end}}                    TypeNSAPPTR:    "NSAP-PTR",
-}
+var headerFunc = template.Must(template.New("headerFunc").Parse(`
+{{range .}}  func (rr *{{.}}) Header() *RR_Header { return &rr.Hdr }
+{{end}}
 
 `))
 
-var headerFunc = template.Must(template.New("headerFunc").Parse(`
-{{range .}}  func (rr *{{.}}) Header() *RR_Header { return &rr.Hdr }
+var jsonFunc = template.Must(template.New("jsonFunc").Parse(`
+{{range .}}  func (rr *{{.}}) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *{{.}}) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*{{.}})
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
 {{end}}
 
 `))
@@ -82,32 +88,19 @@ func getTypeStruct(t types.Type, scope *types.Scope) (*types.Struct, bool) {
 }
 
 func main() {
+	iana := flag.String("iana", "", "generate the type codes from the IANA registry in `csv`")
+	flag.Parse()
+
+	if *iana != "" {
+		generateIANA(*iana)
+		return
+	}
+
 	// Import and type-check the package
 	pkg, err := importer.Default().Import("github.com/miekg/dns")
 	fatalIfErr(err)
 	scope := pkg.Scope()
 
-	// Collect constants like TypeX
-	var numberedTypes []string
-	for _, name := range scope.Names() {
-		o := scope.Lookup(name)
-		if o == nil || !o.Exported() {
-			continue
-		}
-		b, ok := o.Type().(*types.Basic)
-		if !ok || b.Kind() != types.Uint16 {
-			continue
-		}
-		if !strings.HasPrefix(o.Name(), "Type") {
-			continue
-		}
-		name := strings.TrimPrefix(o.Name(), "Type")
-		if name == "PrivateRR" {
-			continue
-		}
-		numberedTypes = append(numberedTypes, name)
-	}
-
 	// Collect actual types (*X)
 	var namedTypes []string
 	for _, name := range scope.Names() {
@@ -136,92 +129,11 @@ func main() {
 	// Generate TypeToRR
 	fatalIfErr(TypeToRR.Execute(b, namedTypes))
 
-	// Generate typeToString
-	fatalIfErr(typeToString.Execute(b, numberedTypes))
-
 	// Generate headerFunc
 	fatalIfErr(headerFunc.Execute(b, namedTypes))
 
-	// Generate len()
-	fmt.Fprint(b, "// len() functions\n")
-	for _, name := range namedTypes {
-		if _, ok := skipLen[name]; ok {
-			continue
-		}
-		o := scope.Lookup(name)
-		st, isEmbedded := getTypeStruct(o.Type(), scope)
-		if isEmbedded {
-			continue
-		}
-		fmt.Fprintf(b, "func (rr *%s) len(off int, compression map[string]struct{}) int {\n", name)
-		fmt.Fprintf(b, "l := rr.Hdr.len(off, compression)\n")
-		for i := 1; i < st.NumFields(); i++ {
-			o := func(s string) { fmt.Fprintf(b, s, st.Field(i).Name()) }
-
-			if _, ok := st.Field(i).Type().(*types.Slice); ok {
-				switch st.Tag(i) {
-				case `dns:"-"`:
-					// ignored
-				case `dns:"cdomain-name"`:
-					o("for _, x := range rr.%s { l += domainNameLen(x, off+l, compression, true) }\n")
-				case `dns:"domain-name"`:
-					o("for _, x := range rr.%s { l += domainNameLen(x, off+l, compression, false) }\n")
-				case `dns:"txt"`:
-					o("for _, x := range rr.%s { l += len(x) + 1 }\n")
-				default:
-					log.Fatalln(name, st.Field(i).Name(), st.Tag(i))
-				}
-				continue
-			}
-
-			switch {
-			case st.Tag(i) == `dns:"-"`:
-				// ignored
-			case st.Tag(i) == `dns:"cdomain-name"`:
-				o("l += domainNameLen(rr.%s, off+l, compression, true)\n")
-			case st.Tag(i) == `dns:"domain-name"`:
-				o("l += domainNameLen(rr.%s, off+l, compression, false)\n")
-			case st.Tag(i) == `dns:"octet"`:
-				o("l += len(rr.%s)\n")
-			case strings.HasPrefix(st.Tag(i), `dns:"size-base64`):
-				fallthrough
-			case st.Tag(i) == `dns:"base64"`:
-				o("l += base64.StdEncoding.DecodedLen(len(rr.%s))\n")
-			case strings.HasPrefix(st.Tag(i), `dns:"size-hex:`): // this has an extra field where the length is stored
-				o("l += len(rr.%s)/2\n")
-			case strings.HasPrefix(st.Tag(i), `dns:"size-hex`):
-				fallthrough
-			case st.Tag(i) == `dns:"hex"`:
-				o("l += len(rr.%s)/2 + 1\n")
-			case st.Tag(i) == `dns:"a"`:
-				o("l += net.IPv4len // %s\n")
-			case st.Tag(i) == `dns:"aaaa"`:
-				o("l += net.IPv6len // %s\n")
-			case st.Tag(i) == `dns:"txt"`:
-				o("for _, t := range rr.%s { l += len(t) + 1 }\n")
-			case st.Tag(i) == `dns:"uint48"`:
-				o("l += 6 // %s\n")
-			case st.Tag(i) == "":
-				switch st.Field(i).Type().(*types.Basic).Kind() {
-				case types.Uint8:
-					o("l++ // %s\n")
-				case types.Uint16:
-					o("l += 2 // %s\n")
-				case types.Uint32:
-					o("l += 4 // %s\n")
-				case types.Uint64:
-					o("l += 8 // %s\n")
-				case types.String:
-					o("l += len(rr.%s) + 1\n")
-				default:
-					log.Fatalln(name, st.Field(i).Name())
-				}
-			default:
-				log.Fatalln(name, st.Field(i).Name(), st.Tag(i))
-			}
-		}
-		fmt.Fprintf(b, "return l }\n")
-	}
+	// Generate MarshalJSON and UnmarshalJSON
+	fatalIfErr(jsonFunc.Execute(b, namedTypes))
 
 	// Generate copy()
 	fmt.Fprint(b, "// copy() functions\n")
@@ -242,6 +154,13 @@ func main() {
 					splits := strings.Split(t, ".")
 					t = splits[len(splits)-1]
 				}
+				if st.Tag(i) == `dns:"opt"` {
+					// The options are pointers, copy what they point to.
+					fmt.Fprintf(b, "%s := make([]%s, len(rr.%s)); for i, e := range rr.%s { %s[i] = e.copy() }\n",
+						f, t, f, f, f)
+					fields = append(fields, f)
+					continue
+				}
 				fmt.Fprintf(b, "%s := make([]%s, len(rr.%s)); copy(%s, rr.%s)\n",
 					f, t, f, f, f)
 				fields = append(fields, f)
@@ -257,6 +176,120 @@ func main() {
 		fmt.Fprintf(b, "}\n")
 	}
 
+	writeSource("ztypes.go", b)
+}
+
+var ianaPackageHdr = `
+// Code generated by "go run types_generate.go -iana dns-parameters-4.csv"; DO NOT EDIT.
+
+package dns
+
+`
+
+// ianaType is a single type code from the IANA registry.
+type ianaType struct {
+	name    string // Go identifier, without the Type prefix
+	mnem    string // mnemonic used in the presentation format
+	value   uint16
+	meaning string
+}
+
+// readIANA reads the assigned type codes from the IANA registry in CSV format.
+// Unassigned, private use and reserved codes are skipped.
+func readIANA(file string) []ianaType {
+	f, err := os.Open(file)
+	fatalIfErr(err)
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	fatalIfErr(err)
+	if len(records) == 0 || records[0][0] != "TYPE" || records[0][1] != "Value" {
+		log.Fatalf("%s is not the IANA RR TYPEs registry", file)
+	}
+
+	var codes []ianaType
+	for _, r := range records[1:] {
+		mnem := r[0]
+		switch mnem {
+		case "Unassigned", "Reserved", "Private use":
+			continue
+		}
+		v, err := strconv.ParseUint(r[1], 10, 16)
+		if err != nil {
+			log.Fatalf("bad value %q for type %s", r[1], mnem)
+		}
+
+		name := strings.Replace(mnem, "-", "", -1)
+		if mnem == "*" {
+			mnem, name = "ANY", "ANY"
+		}
+		codes = append(codes, ianaType{name: name, mnem: mnem, value: uint16(v), meaning: r[2]})
+	}
+	return codes
+}
+
+// obsoleteTypes have no RR struct, but keep the mnemonic they always had in
+// TypeToString.
+var obsoleteTypes = map[string]bool{"NULL": true, "ISDN": true, "NXT": true, "ATMA": true, "UNSPEC": true}
+
+func generateIANA(file string) {
+	codes := readIANA(file)
+
+	// Import and type-check the package, to find the types with an RR struct.
+	pkg, err := importer.Default().Import("github.com/miekg/dns")
+	fatalIfErr(err)
+	scope := pkg.Scope()
+
+	// Only the data types with an RR struct, and the meta types and QTYPEs in
+	// 128-255, get a mnemonic: the others can only be handled as RFC3597
+	// records, with the TYPEnnn mnemonic.
+	var named []ianaType
+	for _, c := range codes {
+		if c.value >= 128 && c.value <= 255 || obsoleteTypes[c.name] {
+			named = append(named, c)
+			continue
+		}
+		if o := scope.Lookup(c.name); o == nil {
+			log.Printf("Type%s (%d) is in the IANA registry, but has no RR struct.", c.name, c.value)
+		} else if st, _ := getTypeStruct(o.Type(), scope); st == nil {
+			log.Printf("Type%s (%d) is in the IANA registry, but %s is not an RR struct.", c.name, c.value, c.name)
+		} else {
+			named = append(named, c)
+		}
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteString(ianaPackageHdr)
+
+	fmt.Fprint(b, "// Type codes from the IANA Resource Record (RR) TYPEs registry.\nconst (\n")
+	for _, c := range codes {
+		fmt.Fprintf(b, "Type%s uint16 = %d", c.name, c.value)
+		if c.meaning != "" {
+			fmt.Fprintf(b, " // %s", c.meaning)
+		}
+		fmt.Fprint(b, "\n")
+	}
+	fmt.Fprint(b, ")\n\n")
+
+	fmt.Fprint(b, "// TypeToString is a map of strings for each RR type.\nvar TypeToString = map[uint16]string{\n")
+	fmt.Fprint(b, "TypeNone: \"None\",\n")
+	for _, c := range named {
+		fmt.Fprintf(b, "Type%s: %q,\n", c.name, c.mnem)
+	}
+	fmt.Fprint(b, "TypeReserved: \"Reserved\",\n}\n\n")
+
+	fmt.Fprint(b, "// StringToType is the reverse of TypeToString, needed for string parsing.\nvar StringToType = map[string]uint16{\n")
+	fmt.Fprint(b, "\"None\": TypeNone,\n")
+	for _, c := range named {
+		fmt.Fprintf(b, "%q: Type%s,\n", c.mnem, c.name)
+	}
+	fmt.Fprint(b, "\"Reserved\": TypeReserved,\n}\n")
+
+	writeSource("ziana.go", b)
+}
+
+// writeSource formats the source in b and writes it to the file name.
+func writeSource(name string, b *bytes.Buffer) {
 	// gofmt
 	res, err := format.Source(b.Bytes())
 	if err != nil {
@@ -265,7 +298,7 @@ func main() {
 	}
 
 	// write result
-	f, err := os.Create("ztypes.go")
+	f, err := os.Create(name)
 	fatalIfErr(err)
 	defer f.Close()
 	f.Write(res)
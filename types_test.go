@@ -84,7 +84,7 @@ func TestSprintName(t *testing.T) {
 func TestSprintTxtOctet(t *testing.T) {
 	got := sprintTxtOctet("abc\\.def\007\"\127@\255\x05\xef\\")
 
-	if want := "\"abc\\.def\\007\"W@\\173\\005\\239\""; got != want {
+	if want := "\"abc\\.def\\007\\\"W@\\173\\005\\239\""; got != want {
 		t.Errorf("expected %q, got %q", got, want)
 	}
 }
@@ -134,3 +134,32 @@ func BenchmarkSprintTxt(b *testing.B) {
 		}
 	}
 }
+
+func TestTypeToString(t *testing.T) {
+	if len(TypeToString) != len(StringToType) {
+		t.Errorf("expected TypeToString and StringToType to have the same length, got %d and %d", len(TypeToString), len(StringToType))
+	}
+	for rrtype, s := range TypeToString {
+		if StringToType[s] != rrtype {
+			t.Errorf("expected StringToType[%q] to be %d, got %d", s, rrtype, StringToType[s])
+		}
+	}
+	for rrtype := range TypeToRR {
+		if _, ok := TypeToString[rrtype]; !ok {
+			t.Errorf("expected type %d to have a mnemonic", rrtype)
+		}
+	}
+	if TypeToString[TypeANY] != "ANY" || TypeToString[TypeNSAPPTR] != "NSAP-PTR" {
+		t.Errorf("unexpected mnemonics %q and %q", TypeToString[TypeANY], TypeToString[TypeNSAPPTR])
+	}
+	// Types without an RR struct have no mnemonic, they are only parsed as TYPEnnn.
+	if _, ok := StringToType["SVCB"]; ok {
+		t.Error("expected SVCB not to have a mnemonic")
+	}
+	if _, err := NewRR("example. 3600 IN SVCB 1 . alpn=h2"); err == nil {
+		t.Error("expected SVCB to fail to parse")
+	}
+	if rr, err := NewRR("example. 3600 IN TYPE64 \\# 3 000100"); err != nil || rr.Header().Rrtype != TypeSVCB {
+		t.Errorf("expected a TYPE64 record, got %v, %v", rr, err)
+	}
+}
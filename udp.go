@@ -12,17 +12,14 @@ import (
 // This is the required size of the OOB buffer to pass to ReadMsgUDP.
 var udpOOBSize = func() int {
 	// We can't know whether we'll get an IPv4 control message or an
-	// IPv6 control message ahead of time. To get around this, we size
-	// the buffer equal to the largest of the two.
+	// IPv6 control message ahead of time, and a dual-stack socket gets
+	// both for IPv4 packets. To get around this, we size the buffer for
+	// the two.
 
 	oob4 := ipv4.NewControlMessage(ipv4.FlagDst | ipv4.FlagInterface)
 	oob6 := ipv6.NewControlMessage(ipv6.FlagDst | ipv6.FlagInterface)
 
-	if len(oob4) > len(oob6) {
-		return len(oob4)
-	}
-
-	return len(oob6)
+	return len(oob4) + len(oob6)
 }()
 
 // SessionUDP holds the remote address and the associated
@@ -47,6 +44,9 @@ func ReadFromSessionUDP(conn *net.UDPConn, b []byte) (int, *SessionUDP, error) {
 }
 
 // WriteToSessionUDP acts just like net.UDPConn.WriteTo(), but uses a *SessionUDP instead of a net.Addr.
+// The packet is sent from the local address the session's query was sent to,
+// so the responses of a server listening on a wildcard address on a
+// multi-homed or anycast host come from the address the client expects.
 func WriteToSessionUDP(conn *net.UDPConn, b []byte, session *SessionUDP) (int, error) {
 	oob := correctSource(session.context)
 	n, _, err := conn.WriteMsgUDP(b, oob, session.raddr)
@@ -80,10 +80,26 @@ func parseDstFromOOB(oob []byte) net.IP {
 	return nil
 }
 
+// parseIfIndexFromOOB takes oob data and returns the index of the interface
+// the packet was received on, or 0.
+func parseIfIndexFromOOB(oob []byte) int {
+	cm6 := new(ipv6.ControlMessage)
+	if cm6.Parse(oob) == nil && cm6.Dst != nil {
+		return cm6.IfIndex
+	}
+	cm4 := new(ipv4.ControlMessage)
+	if cm4.Parse(oob) == nil && cm4.Dst != nil {
+		return cm4.IfIndex
+	}
+	return 0
+}
+
 // correctSource takes oob data and returns new oob data with the Src equal to the Dst
 func correctSource(oob []byte) []byte {
 	dst := parseDstFromOOB(oob)
-	if dst == nil {
+	if dst == nil || dst.IsMulticast() {
+		// Responses to multicast queries, as in LLMNR, are sent from a
+		// unicast address chosen by the kernel.
 		return nil
 	}
 	// If the dst is definitely an IPv6, then use ipv6's ControlMessage to
@@ -92,6 +108,10 @@ func correctSource(oob []byte) []byte {
 	if dst.To4() == nil {
 		cm := new(ipv6.ControlMessage)
 		cm.Src = dst
+		if dst.IsLinkLocalUnicast() {
+			// A link-local address is only valid with its interface.
+			cm.IfIndex = parseIfIndexFromOOB(oob)
+		}
 		oob = cm.Marshal()
 	} else {
 		cm := new(ipv4.ControlMessage)
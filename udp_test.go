@@ -143,3 +143,54 @@ func TestCorrectSource(t *testing.T) {
 		t.Errorf("unexpected oob for IPv6 address: %v", soob)
 	}
 }
+
+func TestCorrectSourceLinkLocal(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		// See comment above in TestParseDstFromOOB.
+		t.Skip("skipping test on unsupported architecture")
+	}
+
+	// dst is fe80::1 on interface 2
+	oob := []byte{36, 0, 0, 0, 0, 0, 0, 0, 41, 0, 0, 0, 50, 0, 0, 0, 254, 128, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 0, 0, 0, 0, 0, 0, 0}
+	soob := correctSource(oob)
+	cm6 := new(ipv6.ControlMessage)
+	cm6.Src = net.ParseIP("fe80::1")
+	cm6.IfIndex = 2
+	if !bytes.Equal(soob, cm6.Marshal()) {
+		t.Errorf("expected the interface of the link-local address, got %v", soob)
+	}
+}
+
+func TestUDPSourceAddress(t *testing.T) {
+	s, addrstr, _, err := RunLocalUDPServerWithFinChan("0.0.0.0:0", func(srv *Server) {
+		srv.Handler = HandlerFunc(HelloServer)
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+	_, port, _ := net.SplitHostPort(addrstr)
+
+	// All of 127.0.0.0/8 is local on Linux, 127.0.0.2 isn't the address
+	// the kernel would pick to respond to 127.0.0.1.
+	c, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeTXT)
+	buf, _ := m.Pack()
+	dst, _ := net.ResolveUDPAddr("udp4", net.JoinHostPort("127.0.0.2", port))
+	if _, err := c.WriteTo(buf, dst); err != nil {
+		t.Fatal(err)
+	}
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, from, err := c.ReadFrom(make([]byte, MinMsgSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := from.(*net.UDPAddr).IP; !ip.Equal(dst.IP) {
+		t.Errorf("expected the response from %v, got %v", dst.IP, ip)
+	}
+}
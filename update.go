@@ -104,3 +104,46 @@ func (u *Msg) Remove(rr []RR) {
 		u.Ns = append(u.Ns, r)
 	}
 }
+
+// The methods below add a single prerequisite each, from the name and type it
+// applies to, and don't modify the records they are given.
+
+// PrereqNameInUse adds the prerequisite that name has records, see RFC 2136
+// section 2.4.4.
+func (u *Msg) PrereqNameInUse(name string) {
+	u.Answer = append(u.Answer, &ANY{Hdr: RR_Header{Name: name, Rrtype: TypeANY, Class: ClassANY}})
+}
+
+// PrereqNameNotInUse adds the prerequisite that name has no records, see RFC
+// 2136 section 2.4.5.
+func (u *Msg) PrereqNameNotInUse(name string) {
+	u.Answer = append(u.Answer, &ANY{Hdr: RR_Header{Name: name, Rrtype: TypeANY, Class: ClassNONE}})
+}
+
+// PrereqRRsetExists adds the prerequisite that name has records of type rrtype,
+// see RFC 2136 section 2.4.1.
+func (u *Msg) PrereqRRsetExists(name string, rrtype uint16) {
+	u.Answer = append(u.Answer, &ANY{Hdr: RR_Header{Name: name, Rrtype: rrtype, Class: ClassANY}})
+}
+
+// PrereqRRsetNotExists adds the prerequisite that name has no records of type
+// rrtype, see RFC 2136 section 2.4.3.
+func (u *Msg) PrereqRRsetNotExists(name string, rrtype uint16) {
+	u.Answer = append(u.Answer, &ANY{Hdr: RR_Header{Name: name, Rrtype: rrtype, Class: ClassNONE}})
+}
+
+// PrereqRRsetEquals adds the prerequisite that the RRset of the records in rrset
+// is exactly rrset, see RFC 2136 section 2.4.2. The records in rrset must
+// all have the same name and type. They are copied with the class of the zone
+// and a zero TTL.
+func (u *Msg) PrereqRRsetEquals(rrset ...RR) {
+	if len(u.Question) == 0 {
+		panic("dns: empty question section")
+	}
+	for _, r := range rrset {
+		r = r.copy()
+		r.Header().Class = u.Question[0].Qclass
+		r.Header().Ttl = 0
+		u.Answer = append(u.Answer, r)
+	}
+}
@@ -0,0 +1,278 @@
+package dns
+
+import "strings"
+
+// An UpdatePolicy reports whether the update rr, from the update section of u,
+// is allowed, e.g. depending on the TSIG key u is signed with.
+type UpdatePolicy func(u *Msg, rr RR) bool
+
+// ApplyUpdate applies the dynamic update u to zone, the records of the zone in
+// its zone section, with the semantics of RFC 2136: the prerequisites are
+// checked, the updates are checked, and then applied. It returns the updated
+// zone and the rcode of the response to u. When the rcode isn't RcodeSuccess
+// zone is returned unchanged. The records of zone aren't modified.
+//
+// When the update changes the zone without updating the SOA record, its serial
+// is incremented.
+//
+// Servers that accept updates need a MsgAcceptFunc that lets them through, the
+// DefaultMsgAcceptFunc rejects them.
+func ApplyUpdate(zone []RR, u *Msg) ([]RR, int) {
+	return ApplyUpdateWithPolicy(zone, u, nil)
+}
+
+// ApplyUpdateWithPolicy is like ApplyUpdate, but the updates are refused when
+// policy doesn't allow them.
+func ApplyUpdateWithPolicy(zone []RR, u *Msg, policy UpdatePolicy) ([]RR, int) {
+	if u.Opcode != OpcodeUpdate || len(u.Question) != 1 || u.Question[0].Qtype != TypeSOA {
+		return zone, RcodeFormatError
+	}
+	q := u.Question[0]
+	z := newUpdateZone(zone)
+	if z.soa == nil || !equal(z.soa.Hdr.Name, q.Name) || z.soa.Hdr.Class != q.Qclass {
+		return zone, RcodeNotAuth
+	}
+	if rcode := z.prereqs(q, u.Answer); rcode != RcodeSuccess {
+		return zone, rcode
+	}
+	for _, rr := range u.Ns {
+		if rcode := prescanUpdate(q, rr); rcode != RcodeSuccess {
+			return zone, rcode
+		}
+		if policy != nil && !policy(u, rr) {
+			return zone, RcodeRefused
+		}
+	}
+	for _, rr := range u.Ns {
+		z.update(q, rr)
+	}
+	return z.records(), RcodeSuccess
+}
+
+// updateZone is a zone being updated.
+type updateZone struct {
+	apex    string
+	soa     *SOA
+	names   []string        // owner names, lower cased, in the order of the zone
+	rrs     map[string][]RR // by owner name, lower cased
+	changed bool            // records other than the SOA record were changed
+	serial  bool            // the SOA record was updated
+}
+
+func newUpdateZone(zone []RR) *updateZone {
+	z := &updateZone{rrs: make(map[string][]RR)}
+	for _, rr := range zone {
+		if soa, ok := rr.(*SOA); ok && z.soa == nil {
+			z.soa = soa
+			z.apex = strings.ToLower(soa.Hdr.Name)
+		}
+		z.add(rr)
+	}
+	return z
+}
+
+func (z *updateZone) add(rr RR) {
+	name := strings.ToLower(rr.Header().Name)
+	if _, ok := z.rrs[name]; !ok {
+		z.names = append(z.names, name)
+	}
+	z.rrs[name] = append(z.rrs[name], rr)
+}
+
+// rrset returns the records of name with type rrtype, or all of them for
+// TypeANY.
+func (z *updateZone) rrset(name string, rrtype uint16) []RR {
+	var rrset []RR
+	for _, rr := range z.rrs[strings.ToLower(name)] {
+		if rrtype == TypeANY || rr.Header().Rrtype == rrtype {
+			rrset = append(rrset, rr)
+		}
+	}
+	return rrset
+}
+
+// remove removes the records of name for which f returns true.
+func (z *updateZone) remove(name string, f func(RR) bool) {
+	name = strings.ToLower(name)
+	rrs := z.rrs[name]
+	kept := rrs[:0:0]
+	for _, rr := range rrs {
+		if !f(rr) {
+			kept = append(kept, rr)
+		}
+	}
+	if len(kept) != len(rrs) {
+		z.rrs[name] = kept
+		z.changed = true
+	}
+}
+
+// records returns the records of z, SOA record first.
+func (z *updateZone) records() []RR {
+	soa := z.soa
+	if z.changed && !z.serial {
+		soa = soa.copy().(*SOA)
+		soa.Serial++
+	}
+	rrs := []RR{soa}
+	for _, name := range z.names {
+		for _, rr := range z.rrs[name] {
+			if rr.Header().Rrtype != TypeSOA || name != z.apex {
+				rrs = append(rrs, rr)
+			}
+		}
+	}
+	return rrs
+}
+
+// prereqs checks the prerequisites of an update (RFC 2136, Section 3.2).
+func (z *updateZone) prereqs(q Question, prereqs []RR) int {
+	type rrsetKey struct {
+		name   string
+		rrtype uint16
+	}
+	values := make(map[rrsetKey]map[string]bool)
+	for _, rr := range prereqs {
+		h := rr.Header()
+		if h.Ttl != 0 {
+			return RcodeFormatError
+		}
+		if !IsSubDomain(q.Name, h.Name) {
+			return RcodeNotZone
+		}
+		switch h.Class {
+		case ClassANY:
+			if h.Rdlength != 0 {
+				return RcodeFormatError
+			}
+			if len(z.rrset(h.Name, h.Rrtype)) == 0 {
+				if h.Rrtype == TypeANY {
+					return RcodeNameError
+				}
+				return RcodeNXRrset
+			}
+		case ClassNONE:
+			if h.Rdlength != 0 {
+				return RcodeFormatError
+			}
+			if len(z.rrset(h.Name, h.Rrtype)) > 0 {
+				if h.Rrtype == TypeANY {
+					return RcodeYXDomain
+				}
+				return RcodeYXRrset
+			}
+		case q.Qclass:
+			k := rrsetKey{strings.ToLower(h.Name), h.Rrtype}
+			if values[k] == nil {
+				values[k] = make(map[string]bool)
+			}
+			values[k][rrKey(rr)] = true
+		default:
+			return RcodeFormatError
+		}
+	}
+
+	for k, want := range values {
+		have := make(map[string]bool)
+		for _, rr := range z.rrset(k.name, k.rrtype) {
+			have[rrKey(rr)] = true
+		}
+		if len(have) != len(want) {
+			return RcodeNXRrset
+		}
+		for key := range want {
+			if !have[key] {
+				return RcodeNXRrset
+			}
+		}
+	}
+	return RcodeSuccess
+}
+
+// prescanUpdate checks an update (RFC 2136, Section 3.4.1).
+func prescanUpdate(q Question, rr RR) int {
+	h := rr.Header()
+	if !IsSubDomain(q.Name, h.Name) {
+		return RcodeNotZone
+	}
+	switch h.Class {
+	case q.Qclass:
+		if isMetaType(h.Rrtype) {
+			return RcodeFormatError
+		}
+	case ClassANY:
+		if h.Ttl != 0 || h.Rdlength != 0 || (h.Rrtype != TypeANY && isMetaType(h.Rrtype)) {
+			return RcodeFormatError
+		}
+	case ClassNONE:
+		if h.Ttl != 0 || isMetaType(h.Rrtype) {
+			return RcodeFormatError
+		}
+	default:
+		return RcodeFormatError
+	}
+	return RcodeSuccess
+}
+
+func isMetaType(t uint16) bool {
+	switch t {
+	case TypeANY, TypeAXFR, TypeIXFR, TypeMAILA, TypeMAILB, TypeOPT, TypeTSIG, TypeTKEY:
+		return true
+	}
+	return false
+}
+
+// update applies an update (RFC 2136, Section 3.4.2).
+func (z *updateZone) update(q Question, rr RR) {
+	h := rr.Header()
+	apex := equal(h.Name, z.apex)
+	switch h.Class {
+	case q.Qclass:
+		switch h.Rrtype {
+		case TypeSOA:
+			if apex && serialLess(z.soa.Serial, rr.(*SOA).Serial) {
+				z.soa = rr.(*SOA)
+				z.serial = true
+			}
+			return
+		case TypeCNAME:
+			for _, old := range z.rrset(h.Name, TypeANY) {
+				if t := old.Header().Rrtype; t != TypeCNAME && !isDNSSECType(t) {
+					return
+				}
+			}
+			z.remove(h.Name, func(old RR) bool { return old.Header().Rrtype == TypeCNAME })
+		default:
+			if !isDNSSECType(h.Rrtype) && len(z.rrset(h.Name, TypeCNAME)) > 0 {
+				return
+			}
+			key := rrKey(rr)
+			z.remove(h.Name, func(old RR) bool { return rrKey(old) == key })
+		}
+		z.add(rr)
+		z.changed = true
+
+	case ClassANY:
+		z.remove(h.Name, func(old RR) bool {
+			t := old.Header().Rrtype
+			if apex && (t == TypeSOA || t == TypeNS) {
+				return false
+			}
+			return h.Rrtype == TypeANY || t == h.Rrtype
+		})
+
+	case ClassNONE:
+		if h.Rrtype == TypeSOA || (apex && h.Rrtype == TypeNS && len(z.rrset(h.Name, TypeNS)) <= 1) {
+			return
+		}
+		// Compare as if rr was in the zone class.
+		r := rr.copy()
+		r.Header().Class = q.Qclass
+		key := rrKey(r)
+		z.remove(h.Name, func(old RR) bool { return rrKey(old) == key })
+	}
+}
+
+func isDNSSECType(t uint16) bool {
+	return t == TypeRRSIG || t == TypeNSEC || t == TypeNSEC3
+}
@@ -137,3 +137,133 @@ insert.	3600	IN	A	127.0.0.1
 		t.Errorf("actual msg:\n%v", m.String())
 	}
 }
+
+func TestApplyUpdate(t *testing.T) {
+	zone := []RR{
+		testRR("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 60"),
+		testRR("example.org. 3600 IN NS ns.example.org."),
+		testRR("ns.example.org. 3600 IN A 192.0.2.1"),
+		testRR("www.example.org. 3600 IN A 192.0.2.2"),
+		testRR("www.example.org. 3600 IN A 192.0.2.3"),
+		testRR("alias.example.org. 3600 IN CNAME www.example.org."),
+	}
+
+	tests := []struct {
+		name   string
+		build  func(m *Msg)
+		rcode  int
+		expect []string // records after the update, besides the SOA record
+	}{
+		{
+			name: "name in use",
+			build: func(m *Msg) {
+				m.PrereqNameInUse("www.example.org.")
+				m.Insert([]RR{testRR("www.example.org. 300 IN AAAA 2001:db8::1")})
+			},
+			expect: []string{"www.example.org.\t300\tIN\tAAAA\t2001:db8::1"},
+		},
+		{
+			name:  "name not in use",
+			build: func(m *Msg) { m.PrereqNameInUse("new.example.org.") },
+			rcode: RcodeNameError,
+		},
+		{
+			name:  "name in use unexpectedly",
+			build: func(m *Msg) { m.PrereqNameNotInUse("www.example.org.") },
+			rcode: RcodeYXDomain,
+		},
+		{
+			name:  "rrset missing",
+			build: func(m *Msg) { m.PrereqRRsetExists("www.example.org.", TypeAAAA) },
+			rcode: RcodeNXRrset,
+		},
+		{
+			name:  "rrset exists unexpectedly",
+			build: func(m *Msg) { m.PrereqRRsetNotExists("www.example.org.", TypeA) },
+			rcode: RcodeYXRrset,
+		},
+		{
+			name: "rrset equals",
+			build: func(m *Msg) {
+				m.PrereqRRsetEquals(testRR("www.example.org. 60 IN A 192.0.2.3"), testRR("WWW.example.org. 60 IN A 192.0.2.2"))
+				m.Remove([]RR{testRR("www.example.org. 3600 IN A 192.0.2.2")})
+			},
+			expect: []string{"-www.example.org.\t3600\tIN\tA\t192.0.2.2"},
+		},
+		{
+			name:  "rrset differs",
+			build: func(m *Msg) { m.PrereqRRsetEquals(testRR("www.example.org. 60 IN A 192.0.2.3")) },
+			rcode: RcodeNXRrset,
+		},
+		{
+			name:  "outside of zone",
+			build: func(m *Msg) { m.Insert([]RR{testRR("www.example.com. 300 IN A 192.0.2.4")}) },
+			rcode: RcodeNotZone,
+		},
+		{
+			name: "cname conflict ignored",
+			build: func(m *Msg) {
+				m.Insert([]RR{testRR("alias.example.org. 300 IN A 192.0.2.4")})
+			},
+		},
+		{
+			name:  "apex ns and soa kept",
+			build: func(m *Msg) { m.RemoveName([]RR{testRR("example.org. 0 IN A 192.0.2.1")}) },
+		},
+		{
+			name:   "remove rrset",
+			build:  func(m *Msg) { m.RemoveRRset([]RR{testRR("www.example.org. 0 IN A 192.0.2.1")}) },
+			expect: []string{"-www.example.org.\t3600\tIN\tA\t192.0.2.2", "-www.example.org.\t3600\tIN\tA\t192.0.2.3"},
+		},
+	}
+
+	for _, tc := range tests {
+		m := new(Msg)
+		m.SetUpdate("example.org.")
+		tc.build(m)
+		// Apply the update as a server gets it.
+		buf, err := m.Pack()
+		if err != nil {
+			t.Fatalf("%s: failed to pack: %v", tc.name, err)
+		}
+		m = new(Msg)
+		if err := m.Unpack(buf); err != nil {
+			t.Fatalf("%s: failed to unpack: %v", tc.name, err)
+		}
+
+		updated, rcode := ApplyUpdate(zone, m)
+		if rcode != tc.rcode {
+			t.Errorf("%s: expected rcode %s, got %s", tc.name, RcodeToString[tc.rcode], RcodeToString[rcode])
+			continue
+		}
+		if tc.expect == nil {
+			if len(updated) != len(zone) || updated[0] != zone[0] {
+				t.Errorf("%s: expected the zone to be unchanged, got %v", tc.name, updated)
+			}
+			continue
+		}
+
+		if soa := updated[0].(*SOA); soa.Serial != 2 {
+			t.Errorf("%s: expected serial 2, got %d", tc.name, soa.Serial)
+		}
+		want := make(map[string]bool)
+		for _, rr := range zone[1:] {
+			want[rr.String()] = true
+		}
+		for _, s := range tc.expect {
+			if s[0] == '-' {
+				delete(want, s[1:])
+			} else {
+				want[s] = true
+			}
+		}
+		if len(updated)-1 != len(want) {
+			t.Errorf("%s: expected %d records, got %v", tc.name, len(want), updated)
+		}
+		for _, rr := range updated[1:] {
+			if !want[rr.String()] {
+				t.Errorf("%s: unexpected record %s", tc.name, rr)
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package dns
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// UpstreamStrategy is the way Upstreams spreads queries over its servers.
+type UpstreamStrategy int
+
+// Strategies of Upstreams.
+const (
+	// UpstreamRoundRobin sends each query to the next server in turn, and fails
+	// over to the following ones.
+	UpstreamRoundRobin UpstreamStrategy = iota
+	// UpstreamFastest sends queries to the server with the lowest smoothed
+	// round trip time, and fails over to the next fastest ones.
+	UpstreamFastest
+	// UpstreamRace sends each query to all servers at once and returns the
+	// first good response.
+	UpstreamRace
+)
+
+// Upstreams sends queries to a set of servers, and fails over to another one
+// when a server doesn't give a good response: on errors, and on SERVFAIL and
+// REFUSED responses. Servers that fail MaxFails queries in a row are marked
+// down, and are only sent a single query every DownInterval to check their
+// health until they answer again. When all servers are down, they are all
+// tried.
+//
+// An Upstreams is safe for concurrent use, its fields should not be changed
+// once it is used.
+type Upstreams struct {
+	Addrs        []string         // addresses of the servers, in host:port form
+	Client       *Client          // client used to send queries, a zero Client is used when nil
+	Strategy     UpstreamStrategy // how queries are spread over the servers
+	MaxFails     int              // failed queries in a row before a server is marked down, defaults to 3
+	DownInterval time.Duration    // interval at which a server that is down is checked, defaults to 10 seconds
+
+	mu     sync.Mutex
+	state  map[string]*upstreamState
+	next   int
+	client *Client
+}
+
+type upstreamState struct {
+	fails   int
+	rtt     time.Duration // smoothed, 0 until measured
+	checked time.Time     // time of the last check of a server that is down
+}
+
+// errNoUpstreams is returned by Upstreams without servers.
+var errNoUpstreams = &Error{err: "no upstream servers"}
+
+// UpstreamStatus is the health of a server of Upstreams.
+type UpstreamStatus struct {
+	Addr string
+	Up   bool
+	Rtt  time.Duration // smoothed round trip time
+}
+
+// NewUpstreams returns Upstreams that spreads queries over addrs with strategy.
+func NewUpstreams(strategy UpstreamStrategy, addrs ...string) *Upstreams {
+	return &Upstreams{Addrs: addrs, Strategy: strategy}
+}
+
+func (u *Upstreams) maxFails() int {
+	if u.MaxFails > 0 {
+		return u.MaxFails
+	}
+	return 3
+}
+
+func (u *Upstreams) downInterval() time.Duration {
+	if u.DownInterval > 0 {
+		return u.DownInterval
+	}
+	return 10 * time.Second
+}
+
+// init sets up the state of u, it must be called with u.mu held.
+func (u *Upstreams) init() {
+	if u.state != nil {
+		return
+	}
+	u.state = make(map[string]*upstreamState, len(u.Addrs))
+	for _, a := range u.Addrs {
+		u.state[a] = new(upstreamState)
+	}
+	u.client = u.Client
+	if u.client == nil {
+		u.client = new(Client)
+	}
+}
+
+// Status returns the health of the servers of u.
+func (u *Upstreams) Status() []UpstreamStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.init()
+
+	st := make([]UpstreamStatus, len(u.Addrs))
+	for i, a := range u.Addrs {
+		s := u.state[a]
+		st[i] = UpstreamStatus{Addr: a, Up: s.fails < u.maxFails(), Rtt: s.rtt}
+	}
+	return st
+}
+
+// Exchange sends m to the servers of u according to its strategy, and returns
+// the first good response. When no server gives a good response the last
+// response or error is returned. The rtt is the one of the server that gave
+// the returned response.
+func (u *Upstreams) Exchange(m *Msg) (r *Msg, rtt time.Duration, err error) {
+	addrs := u.pick()
+	if len(addrs) == 0 {
+		return nil, 0, errNoUpstreams
+	}
+	if u.Strategy == UpstreamRace {
+		return u.race(m, addrs)
+	}
+
+	for _, a := range addrs {
+		r, rtt, err = u.client.Exchange(m, a)
+		if u.report(a, r, rtt, err) {
+			return r, rtt, err
+		}
+	}
+	return r, rtt, err
+}
+
+// race sends m to all addrs at once and returns the first good response.
+func (u *Upstreams) race(m *Msg, addrs []string) (r *Msg, rtt time.Duration, err error) {
+	type result struct {
+		r   *Msg
+		rtt time.Duration
+		err error
+		ok  bool
+	}
+	results := make(chan result, len(addrs))
+	for _, a := range addrs {
+		go func(a string) {
+			// Each query needs its own copy, Exchange may change m.
+			r, rtt, err := u.client.Exchange(m.Copy(), a)
+			results <- result{r, rtt, err, u.report(a, r, rtt, err)}
+		}(a)
+	}
+	for range addrs {
+		res := <-results
+		r, rtt, err = res.r, res.rtt, res.err
+		if res.ok {
+			break
+		}
+	}
+	return r, rtt, err
+}
+
+// pick returns the addresses to try, in order. Servers that are down come
+// last, and only when it is time to check them again.
+func (u *Upstreams) pick() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.init()
+
+	n := len(u.Addrs)
+	if n == 0 {
+		return nil
+	}
+	addrs := make([]string, 0, n)
+	switch u.Strategy {
+	case UpstreamRoundRobin:
+		for i := 0; i < n; i++ {
+			addrs = append(addrs, u.Addrs[(u.next+i)%n])
+		}
+		u.next = (u.next + 1) % n
+	case UpstreamFastest:
+		addrs = append(addrs, u.Addrs...)
+		sort.SliceStable(addrs, func(i, j int) bool { return u.state[addrs[i]].rtt < u.state[addrs[j]].rtt })
+	default:
+		addrs = append(addrs, u.Addrs...)
+	}
+
+	now := time.Now()
+	up, down := addrs[:0:0], addrs[:0:0]
+	for _, a := range addrs {
+		s := u.state[a]
+		switch {
+		case s.fails < u.maxFails():
+			up = append(up, a)
+		case now.Sub(s.checked) >= u.downInterval():
+			s.checked = now
+			down = append(down, a)
+		}
+	}
+	if len(up) == 0 && len(down) == 0 {
+		return addrs // everything is down, try anyway
+	}
+	return append(up, down...)
+}
+
+// report records the result of a query to addr, and reports whether it is a
+// good response.
+func (u *Upstreams) report(addr string, r *Msg, rtt time.Duration, err error) bool {
+	ok := err == nil && r.Rcode != RcodeServerFailure && r.Rcode != RcodeRefused
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	s := u.state[addr]
+	if !ok {
+		s.fails++
+		if s.fails == u.maxFails() {
+			s.checked = time.Now()
+		}
+		return false
+	}
+	s.fails = 0
+	if s.rtt == 0 {
+		s.rtt = rtt
+	} else {
+		s.rtt = (7*s.rtt + rtt) / 8
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package dns
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runUpstream runs a UDP server that answers with rcode after delay, and
+// counts the queries it gets.
+func runUpstream(t *testing.T, rcode int, delay time.Duration, queries *int32) (*Server, string) {
+	h := HandlerFunc(func(w ResponseWriter, req *Msg) {
+		atomic.AddInt32(queries, 1)
+		time.Sleep(delay)
+		m := new(Msg)
+		m.SetRcode(req, rcode)
+		w.WriteMsg(m)
+	})
+	s, addr, _, err := RunLocalUDPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.Handler = h
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	return s, addr
+}
+
+func TestUpstreamsRoundRobin(t *testing.T) {
+	var q1, q2, q3 int32
+	s1, a1 := runUpstream(t, RcodeSuccess, 0, &q1)
+	defer s1.Shutdown()
+	s2, a2 := runUpstream(t, RcodeSuccess, 0, &q2)
+	defer s2.Shutdown()
+	s3, a3 := runUpstream(t, RcodeServerFailure, 0, &q3)
+	defer s3.Shutdown()
+
+	u := NewUpstreams(UpstreamRoundRobin, a1, a2, a3)
+	u.MaxFails = 1
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	for i := 0; i < 6; i++ {
+		r, _, err := u.Exchange(m)
+		if err != nil || r.Rcode != RcodeSuccess {
+			t.Fatalf("query %d: expected good response, got %v, %v", i, r, err)
+		}
+	}
+	// The failing server got a single query, and was then marked down. That
+	// query, and the next one in its turn, went to the first server.
+	n1, n2, n3 := atomic.LoadInt32(&q1), atomic.LoadInt32(&q2), atomic.LoadInt32(&q3)
+	if n1 != 4 || n2 != 2 || n3 != 1 {
+		t.Errorf("expected 4, 2 and 1 queries, got %d, %d and %d", n1, n2, n3)
+	}
+	if st := u.Status(); !st[0].Up || !st[1].Up || st[2].Up {
+		t.Errorf("expected only the last server to be down, got %v", st)
+	}
+}
+
+func TestUpstreamsFastest(t *testing.T) {
+	var q1, q2 int32
+	s1, a1 := runUpstream(t, RcodeSuccess, 50*time.Millisecond, &q1)
+	defer s1.Shutdown()
+	s2, a2 := runUpstream(t, RcodeSuccess, 0, &q2)
+	defer s2.Shutdown()
+
+	u := NewUpstreams(UpstreamFastest, a1, a2)
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	for i := 0; i < 5; i++ {
+		if _, _, err := u.Exchange(m); err != nil {
+			t.Fatalf("query %d: failed to exchange: %v", i, err)
+		}
+	}
+	// The first query measures the slow server, all others go to the other.
+	if n1, n2 := atomic.LoadInt32(&q1), atomic.LoadInt32(&q2); n1 != 1 || n2 != 4 {
+		t.Errorf("expected 1 and 4 queries, got %d and %d", n1, n2)
+	}
+}
+
+func TestUpstreamsRace(t *testing.T) {
+	var q1, q2, q3 int32
+	s1, a1 := runUpstream(t, RcodeSuccess, 200*time.Millisecond, &q1)
+	defer s1.Shutdown()
+	s2, a2 := runUpstream(t, RcodeRefused, 0, &q2)
+	defer s2.Shutdown()
+	s3, a3 := runUpstream(t, RcodeSuccess, 10*time.Millisecond, &q3)
+	defer s3.Shutdown()
+
+	u := NewUpstreams(UpstreamRace, a1, a2, a3)
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	start := time.Now()
+	r, _, err := u.Exchange(m)
+	if err != nil || r.Rcode != RcodeSuccess {
+		t.Fatalf("expected good response, got %v, %v", r, err)
+	}
+	if d := time.Since(start); d >= 200*time.Millisecond {
+		t.Errorf("expected the fastest good response, took %v", d)
+	}
+}
@@ -0,0 +1,126 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// A View is a named group of clients, and the handler of their queries,
+// usually a ServeMux with the zones as those clients see them.
+type View struct {
+	Name     string
+	Prefixes []*net.IPNet // addresses of the clients, if empty all clients match
+	ECS      bool         // if true, match the address of the EDNS0 client subnet option when the query has one
+	Handler  Handler
+}
+
+// matches reports whether the query r from the client at ip is for v.
+func (v *View) matches(ip net.IP, r *Msg) bool {
+	if len(v.Prefixes) == 0 {
+		return true
+	}
+	if v.ECS {
+		if ecs := clientSubnet(r); ecs != nil {
+			ip = ecs
+		}
+	}
+	if ip == nil {
+		return false
+	}
+	for _, n := range v.Prefixes {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientSubnet returns the address of the EDNS0 client subnet option of r, or
+// nil.
+func clientSubnet(r *Msg) net.IP {
+	opt := r.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	for _, o := range opt.Option {
+		if e, ok := o.(*EDNS0_SUBNET); ok && len(e.Address) > 0 {
+			return e.Address
+		}
+	}
+	return nil
+}
+
+// Views is a Handler for split-horizon DNS: it routes each query to the first
+// of its views that matches the client, so the same zone can be answered
+// differently for internal and external clients. A query that matches no view
+// is refused.
+//
+// The views can be replaced at once with Replace while queries are served,
+// e.g. when a configuration is reloaded. The name of the view of a query is
+// passed to the handlers that implement HandlerContext, see ViewName.
+//
+// The zero Views has no views and is ready for use.
+type Views struct {
+	mu    sync.RWMutex
+	views []*View
+}
+
+type viewKey struct{}
+
+// ViewName returns the name of the view of the query the context ctx was
+// given for by Views, and whether there is one.
+func ViewName(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(viewKey{}).(string)
+	return name, ok
+}
+
+// Add appends v to the views.
+func (vs *Views) Add(v *View) {
+	vs.mu.Lock()
+	vs.views = append(vs.views, v)
+	vs.mu.Unlock()
+}
+
+// Replace replaces all the views with views.
+func (vs *Views) Replace(views []*View) {
+	views = append([]*View(nil), views...)
+	vs.mu.Lock()
+	vs.views = views
+	vs.mu.Unlock()
+}
+
+// Match returns the view of the query r from the client at addr, or nil if
+// there is none.
+func (vs *Views) Match(addr net.Addr, r *Msg) *View {
+	ip := addrIP(addr)
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	for _, v := range vs.views {
+		if v.matches(ip, r) {
+			return v
+		}
+	}
+	return nil
+}
+
+// ServeDNS implements the Handler interface.
+func (vs *Views) ServeDNS(w ResponseWriter, r *Msg) {
+	vs.ServeDNSContext(context.Background(), w, r)
+}
+
+// ServeDNSContext implements the HandlerContext interface.
+func (vs *Views) ServeDNSContext(ctx context.Context, w ResponseWriter, r *Msg) {
+	v := vs.Match(w.RemoteAddr(), r)
+	if v == nil {
+		m := new(Msg)
+		m.SetRcode(r, RcodeRefused)
+		w.WriteMsg(m)
+		return
+	}
+	if hc, ok := v.Handler.(HandlerContext); ok {
+		hc.ServeDNSContext(context.WithValue(ctx, viewKey{}, v.Name), w, r)
+		return
+	}
+	v.Handler.ServeDNS(w, r)
+}
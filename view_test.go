@@ -0,0 +1,57 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestViews(t *testing.T) {
+	internal, _ := ParseACLPrefixes("192.0.2.0/24")
+	var got string
+	handler := HandlerContextFunc(func(ctx context.Context, w ResponseWriter, r *Msg) {
+		got, _ = ViewName(ctx)
+		m := new(Msg)
+		m.SetReply(r)
+		w.WriteMsg(m)
+	})
+	vs := new(Views)
+	vs.Add(&View{Name: "internal", Prefixes: internal, ECS: true, Handler: handler})
+	vs.Add(&View{Name: "external", Handler: handler})
+
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	w := new(recordWriter) // from 192.0.2.1
+	vs.ServeDNS(w, m)
+	if got != "internal" {
+		t.Errorf("expected the internal view, got %q", got)
+	}
+
+	m.SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &EDNS0_SUBNET{Code: EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.IPv4(198, 51, 100, 0)})
+	vs.ServeDNS(w, m)
+	if got != "external" {
+		t.Errorf("expected the external view for the client subnet, got %q", got)
+	}
+
+	vs.Replace([]*View{{Name: "internal", Prefixes: internal, Handler: handler}})
+	vs.ServeDNS(w, m)
+	if got != "internal" {
+		t.Errorf("expected the internal view without ECS, got %q", got)
+	}
+	if v := vs.Match(&net.UDPAddr{IP: net.IPv4(198, 51, 100, 1)}, m); v != nil {
+		t.Errorf("expected no view, got %q", v.Name)
+	}
+	vs.ServeDNS(&viewWriter{w}, m)
+	if len(w.msgs) != 4 || w.msgs[3].Rcode != RcodeRefused {
+		t.Errorf("expected the query refused, got %v", w.msgs[len(w.msgs)-1])
+	}
+}
+
+// viewWriter is a recordWriter for a client outside 192.0.2.0/24.
+type viewWriter struct{ *recordWriter }
+
+func (w *viewWriter) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(198, 51, 100, 1), Port: 5353}
+}
@@ -0,0 +1,156 @@
+package dns
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// Default bounds of the time between the lookups of a Watcher.
+const (
+	DefaultWatchMinInterval = 5 * time.Second
+	DefaultWatchMaxInterval = time.Hour
+)
+
+// A Watcher looks up the records of Type of Name again each time their TTL
+// expires, and reports when they change, e.g. the SRV records of a service
+// its clients discover with DNS. The time between lookups is the TTL of the
+// records, or the negative TTL of the SOA record when there are none, kept
+// between MinInterval and MaxInterval, minus a random Jitter so that not all
+// the clients of a service ask at once.
+type Watcher struct {
+	Lookup      *Lookup       // does the lookups
+	Name        string        // name looked up, made fully qualified by Lookup's search list if it isn't
+	Type        uint16        // type of the records
+	MinInterval time.Duration // minimum time between lookups, defaults to DefaultWatchMinInterval
+	MaxInterval time.Duration // maximum time between lookups, defaults to DefaultWatchMaxInterval
+	Jitter      time.Duration // maximum random time to look up earlier, at most half the interval, defaults to none
+}
+
+// A WatchUpdate is a change reported by a Watcher.
+type WatchUpdate struct {
+	RRs  []RR        // records found, none when the name has no records of the type or doesn't exist
+	Info *LookupInfo // description of the response, nil on errors without response
+	Err  error       // error of the lookup, RRs are those of the last successful one
+}
+
+// Run looks up the records, and calls f with the first result and then each
+// time the records change or a lookup fails, until done is closed. After a
+// failure the lookup is retried after MinInterval, and f is called with the
+// next result, changed or not. Records are compared without their TTL and
+// regardless of their order.
+func (w *Watcher) Run(done <-chan struct{}, f func(*WatchUpdate)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var (
+		last   []RR
+		first  = true
+		failed bool
+	)
+	for {
+		rrs, info, err := w.Lookup.Query(ctx, w.Name, w.Type)
+		if e, ok := err.(*net.DNSError); ok && e.IsNotFound {
+			rrs, err = nil, nil
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		var interval time.Duration
+		switch {
+		case err != nil:
+			f(&WatchUpdate{RRs: last, Info: info, Err: err})
+			failed = true
+			interval = w.minInterval()
+		default:
+			if first || failed || !sameRRs(last, rrs) {
+				f(&WatchUpdate{RRs: rrs, Info: info})
+			}
+			last, first, failed = rrs, false, false
+			interval = w.interval(rrs, info)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (w *Watcher) minInterval() time.Duration {
+	if w.MinInterval > 0 {
+		return w.MinInterval
+	}
+	return DefaultWatchMinInterval
+}
+
+// interval returns the time until the next lookup after the one that found
+// rrs.
+func (w *Watcher) interval(rrs []RR, info *LookupInfo) time.Duration {
+	var ttl uint32
+	switch {
+	case info == nil:
+	case len(rrs) > 0:
+		ttl = info.TTL
+	case info.Msg != nil:
+		for _, rr := range info.Msg.Ns {
+			if soa, ok := rr.(*SOA); ok {
+				ttl = soa.Hdr.Ttl
+				if soa.Minttl < ttl {
+					ttl = soa.Minttl
+				}
+			}
+		}
+	}
+	interval := time.Duration(ttl) * time.Second
+	max := w.MaxInterval
+	if max <= 0 {
+		max = DefaultWatchMaxInterval
+	}
+	if interval > max {
+		interval = max
+	}
+	if min := w.minInterval(); interval < min {
+		interval = min
+	}
+	if jitter := w.Jitter; jitter > 0 {
+		if jitter > interval/2 {
+			jitter = interval / 2
+		}
+		if jitter > 0 {
+			interval -= time.Duration(rand.Int63n(int64(jitter)))
+		}
+	}
+	return interval
+}
+
+// sameRRs reports whether a and b hold the same records, without their TTL,
+// in any order.
+func sameRRs(a, b []RR) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, rr := range a {
+		if !containsRR(b, rr) {
+			return false
+		}
+	}
+	for _, rr := range b {
+		if !containsRR(a, rr) {
+			return false
+		}
+	}
+	return true
+}
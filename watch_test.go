@@ -0,0 +1,95 @@
+package dns
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		answer []RR
+		fail   bool
+	)
+	set := func(rrs []RR, f bool) {
+		mu.Lock()
+		answer, fail = rrs, f
+		mu.Unlock()
+	}
+	ex := exchangerFunc(func(m *Msg, address string) (*Msg, time.Duration, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return nil, 0, errors.New("timeout")
+		}
+		r := new(Msg)
+		r.SetReply(m)
+		r.Answer = answer
+		return r, 0, nil
+	})
+
+	srv1 := testRR("_sip._udp.example.org. 1 IN SRV 10 5 5060 sip1.example.org.")
+	srv2 := testRR("_sip._udp.example.org. 1 IN SRV 10 5 5060 sip2.example.org.")
+	set([]RR{srv1, srv2}, false)
+
+	w := &Watcher{
+		Lookup:      &Lookup{Exchanger: ex},
+		Name:        "_sip._udp.example.org.",
+		Type:        TypeSRV,
+		MinInterval: 10 * time.Millisecond,
+		MaxInterval: 20 * time.Millisecond,
+		Jitter:      5 * time.Millisecond,
+	}
+	updates := make(chan *WatchUpdate, 10)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		w.Run(done, func(u *WatchUpdate) { updates <- u })
+		close(finished)
+	}()
+
+	next := func() *WatchUpdate {
+		select {
+		case u := <-updates:
+			return u
+		case <-time.After(time.Second):
+			t.Fatal("no update")
+		}
+		return nil
+	}
+	if u := next(); u.Err != nil || len(u.RRs) != 2 || u.Info.TTL != 1 {
+		t.Fatalf("unexpected first update %+v", u)
+	}
+
+	// The same records in another order aren't a change.
+	set([]RR{srv2, srv1}, false)
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case u := <-updates:
+		t.Fatalf("unexpected update %+v", u)
+	default:
+	}
+
+	set([]RR{srv2}, false)
+	if u := next(); u.Err != nil || len(u.RRs) != 1 {
+		t.Fatalf("expected the removal of a record, got %+v", u)
+	}
+
+	set(nil, true)
+	if u := next(); u.Err == nil || len(u.RRs) != 1 {
+		t.Fatalf("expected an error with the last records, got %+v", u)
+	}
+	set([]RR{srv2}, false)
+	u := next()
+	for u.Err != nil {
+		u = next() // failed again before the fix
+	}
+	if len(u.RRs) != 1 {
+		t.Fatalf("expected the records again after the error, got %+v", u)
+	}
+
+	close(done)
+	<-finished
+}
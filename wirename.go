@@ -0,0 +1,221 @@
+package dns
+
+// A WireName is a domain name in wire format, with the case of its letters
+// preserved. Comparing and sorting WireNames, and walking their labels, needs
+// no parsing of the presentation format and doesn't allocate.
+//
+// The zero WireName is the root name.
+type WireName struct {
+	w string // wire format without the final root label
+}
+
+// NewWireName returns the WireName of the domain name s, which is made fully
+// qualified.
+func NewWireName(s string) (WireName, error) {
+	var buf [maxDomainNameWireOctets + 1]byte
+	off, err := PackDomainName(Fqdn(s), buf[:], 0, nil, false)
+	if err != nil {
+		return WireName{}, err
+	}
+	return WireName{string(buf[:off-1])}, nil
+}
+
+// UnpackWireName unpacks the domain name at off in msg, following compression
+// pointers, and returns it with the offset after it.
+func UnpackWireName(msg []byte, off int) (WireName, int, error) {
+	var buf [maxDomainNameWireOctets]byte
+	s := buf[:0]
+	off1 := 0
+	ptr := 0 // number of pointers followed
+Loop:
+	for {
+		if off >= len(msg) {
+			return WireName{}, len(msg), ErrBuf
+		}
+		c := int(msg[off])
+		off++
+		switch c & 0xC0 {
+		case 0x00:
+			if c == 0x00 {
+				break Loop
+			}
+			if off+c > len(msg) {
+				return WireName{}, len(msg), ErrBuf
+			}
+			if len(s)+c+1 >= maxDomainNameWireOctets {
+				return WireName{}, len(msg), ErrLongDomain
+			}
+			s = append(s, byte(c))
+			s = append(s, msg[off:off+c]...)
+			off += c
+		case 0xC0:
+			if off >= len(msg) {
+				return WireName{}, len(msg), ErrBuf
+			}
+			if ptr == 0 {
+				off1 = off + 1
+			}
+			if ptr++; ptr > maxCompressionPointers {
+				return WireName{}, len(msg), &Error{err: "too many compression pointers"}
+			}
+			off = (c^0xC0)<<8 | int(msg[off])
+		default:
+			// 0x80 and 0x40 are reserved
+			return WireName{}, len(msg), ErrRdata
+		}
+	}
+	if ptr == 0 {
+		off1 = off
+	}
+	return WireName{string(s)}, off1, nil
+}
+
+// String returns the presentation format of n.
+func (n WireName) String() string {
+	s, _, _ := UnpackDomainName(n.Wire(), 0)
+	return s
+}
+
+// Wire returns the wire format of n.
+func (n WireName) Wire() []byte {
+	return append([]byte(n.w), 0)
+}
+
+// IsRoot reports whether n is the root name.
+func (n WireName) IsRoot() bool { return n.w == "" }
+
+// CountLabel returns the number of labels of n, 0 for the root name.
+func (n WireName) CountLabel() int {
+	l := 0
+	for i := 0; i < len(n.w); i += 1 + int(n.w[i]) {
+		l++
+	}
+	return l
+}
+
+// FirstLabel returns the first label of n, in wire format without its length
+// octet, and "" for the root name.
+func (n WireName) FirstLabel() string {
+	if n.w == "" {
+		return ""
+	}
+	return n.w[1 : 1+int(n.w[0])]
+}
+
+// Parent returns n without its first label, the root name returns itself.
+// Together with FirstLabel this iterates over the labels of n:
+//
+//	for ; !n.IsRoot(); n = n.Parent() {
+//		label := n.FirstLabel()
+//	}
+func (n WireName) Parent() WireName {
+	if n.w == "" {
+		return n
+	}
+	return WireName{n.w[1+int(n.w[0]):]}
+}
+
+// Canonical returns n with its letters lowered, the canonical form of RFC
+// 4034, Section 6.2. Canonical WireNames are equal when they are ==, and can be
+// used as map keys.
+func (n WireName) Canonical() WireName {
+	for i := 0; i < len(n.w); i++ {
+		if isUpper(n.w[i]) {
+			b := []byte(n.w)
+			for ; i < len(b); i++ {
+				if isUpper(b[i]) {
+					b[i] += 'a' - 'A'
+				}
+			}
+			return WireName{string(b)}
+		}
+	}
+	return n
+}
+
+// Equal reports whether n and o are the same name, ignoring case.
+func (n WireName) Equal(o WireName) bool {
+	return len(n.w) == len(o.w) && equalFold(n.w, o.w)
+}
+
+// IsSubDomain reports whether n is parent, or a name below it.
+func (n WireName) IsSubDomain(parent WireName) bool {
+	i := 0
+	for len(n.w)-i > len(parent.w) {
+		i += 1 + int(n.w[i])
+	}
+	return len(n.w)-i == len(parent.w) && equalFold(n.w[i:], parent.w)
+}
+
+// Compare compares n and o in the canonical order of RFC 4034, Section 6.1,
+// and returns -1, 0 or 1 when n sorts before, is the same as, or sorts after o.
+func (n WireName) Compare(o WireName) int {
+	var offn, offo [maxDomainNameWireOctets / 2]int
+	ln, lo := n.labelOffsets(offn[:0]), o.labelOffsets(offo[:0])
+	for i, j := len(ln)-1, len(lo)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		a := n.w[ln[i]+1 : ln[i]+1+int(n.w[ln[i]])]
+		b := o.w[lo[j]+1 : lo[j]+1+int(o.w[lo[j]])]
+		if c := compareFold(a, b); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(ln) < len(lo):
+		return -1
+	case len(ln) > len(lo):
+		return 1
+	}
+	return 0
+}
+
+// labelOffsets appends the offsets of the labels of n to offs.
+func (n WireName) labelOffsets(offs []int) []int {
+	for i := 0; i < len(n.w); i += 1 + int(n.w[i]) {
+		offs = append(offs, i)
+	}
+	return offs
+}
+
+func isUpper(b byte) bool { return b >= 'A' && b <= 'Z' }
+
+func toLower(b byte) byte {
+	if isUpper(b) {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// equalFold reports whether a and b, of the same length, are equal ignoring
+// the case of ASCII letters.
+func equalFold(a, b string) bool {
+	for i := 0; i < len(a); i++ {
+		if toLower(a[i]) != toLower(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareFold compares a and b as octet strings with their ASCII letters
+// lowered.
+func compareFold(a, b string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ca, cb := toLower(a[i]), toLower(b[i])
+		switch {
+		case ca < cb:
+			return -1
+		case ca > cb:
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// WireName returns the WireName of n.
+func (n Name) WireName() (WireName, error) { return NewWireName(string(n)) }
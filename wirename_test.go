@@ -0,0 +1,85 @@
+package dns
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestWireName(t *testing.T) {
+	n, err := NewWireName("WWW.Example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.String() != "WWW.Example.org." || n.CountLabel() != 3 || n.FirstLabel() != "WWW" {
+		t.Errorf("unexpected name %s with %d labels", n, n.CountLabel())
+	}
+	p := n.Parent()
+	if p.String() != "Example.org." || !n.IsSubDomain(p) || p.IsSubDomain(n) {
+		t.Errorf("unexpected parent %s", p)
+	}
+	if !n.IsSubDomain(WireName{}) || !n.IsSubDomain(n) || !n.Parent().Parent().Parent().IsRoot() {
+		t.Error("expected the root name to be the parent of all names")
+	}
+
+	o, _ := Name("www.example.ORG.").WireName()
+	if !n.Equal(o) || n == o || n.Canonical() != o.Canonical() || n.Compare(o) != 0 {
+		t.Errorf("expected %s and %s to be equal but for case", n, o)
+	}
+	if n.Canonical().String() != "www.example.org." {
+		t.Errorf("expected the canonical name in lower case, got %s", n.Canonical())
+	}
+	fake, _ := NewWireName("xexample.org.")
+	if fake.IsSubDomain(p) {
+		t.Errorf("expected %s not to be below %s", fake, p)
+	}
+	if (WireName{}).String() != "." || len((WireName{}).Wire()) != 1 {
+		t.Error("expected the zero name to be the root name")
+	}
+}
+
+func TestWireNameCompare(t *testing.T) {
+	// The example of RFC 4034, Section 6.1.
+	want := []string{
+		"example.",
+		"a.example.",
+		"yljkjljk.a.example.",
+		"Z.a.example.",
+		"zABC.a.EXAMPLE.",
+		"z.example.",
+		"\\001.z.example.",
+		"*.z.example.",
+		"\\200.z.example.",
+	}
+	names := make([]WireName, len(want))
+	for i := range want {
+		var err error
+		if names[i], err = NewWireName(want[len(want)-1-i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].Compare(names[j]) < 0 })
+	for i := range names {
+		if names[i].String() != want[i] {
+			t.Errorf("expected %s at %d, got %s", want[i], i, names[i])
+		}
+	}
+}
+
+func TestUnpackWireName(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	m.Answer = []RR{testRR("www.example.org. 3600 IN A 192.0.2.1")}
+	m.Compress = true
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, off, err := UnpackWireName(buf, 12)
+	if err != nil || q.String() != "example.org." || off != 12+13 {
+		t.Fatalf("unexpected question name %s at %d: %v", q, off, err)
+	}
+	a, _, err := UnpackWireName(buf, off+4)
+	if err != nil || a.String() != "www.example.org." || !a.IsSubDomain(q) {
+		t.Errorf("unexpected owner name %s: %v", a, err)
+	}
+}
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"crypto/tls"
 	"fmt"
 	"time"
 )
@@ -18,9 +19,17 @@ type Transfer struct {
 	ReadTimeout    time.Duration     // net.Conn.SetReadTimeout value for connections, defaults to 2 seconds
 	WriteTimeout   time.Duration     // net.Conn.SetWriteTimeout value for connections, defaults to 2 seconds
 	TsigSecret     map[string]string // Secret(s) for Tsig map[<zonename>]<base64 secret>, zonename must be in canonical form (lowercase, fqdn, see RFC 4034 Section 6.2)
+	TsigProvider   TsigProvider      // An implementation of the TsigProvider interface. If defined it replaces TsigSecret and is used for all TSIG operations.
+	TLSConfig      *tls.Config       // if set, In connects over TLS with this configuration, see XoTConfig (XoT, RFC 9103)
 	tsigTimersOnly bool
+	tsigPrior      []byte // unsigned messages since the last signed one
+	tsigUnsigned   int    // number of messages in tsigPrior
 }
 
+// tsigMaxUnsigned is the maximum number of consecutive unsigned messages in a
+// signed transfer (RFC 8945, section 5.3.1).
+const tsigMaxUnsigned = 99
+
 // Think we need to away to stop the transfer
 
 // In performs an incoming transfer with the server in a.
@@ -39,6 +48,12 @@ func (t *Transfer) In(q *Msg, a string) (env chan *Envelope, err error) {
 	if t.DialTimeout != 0 {
 		timeout = t.DialTimeout
 	}
+	if t.Conn == nil && t.TLSConfig != nil {
+		t.Conn, err = DialTimeoutWithTLS("tcp", a, XoTConfig(t.TLSConfig), timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if t.Conn == nil {
 		t.Conn, err = DialTimeout("tcp", a, timeout)
 		if err != nil {
@@ -102,6 +117,10 @@ func (t *Transfer) inAxfr(q *Msg, c chan *Envelope) {
 		if !first {
 			t.tsigTimersOnly = true // Subsequent envelopes use this.
 			if isSOALast(in) {
+				if t.tsigUnsigned > 0 {
+					c <- &Envelope{in.Answer, ErrNoSig}
+					return
+				}
 				c <- &Envelope{in.Answer, nil}
 				return
 			}
@@ -145,7 +164,7 @@ func (t *Transfer) inIxfr(q *Msg, c chan *Envelope) {
 			// This serial is important
 			serial = in.Answer[0].(*SOA).Serial
 			// Check if there are no changes in zone
-			if qser >= serial {
+			if !serialLess(qser, serial) {
 				c <- &Envelope{in.Answer, nil}
 				return
 			}
@@ -158,6 +177,10 @@ func (t *Transfer) inIxfr(q *Msg, c chan *Envelope) {
 					n++
 					// quit if it's a full axfr or the the servers' SOA is repeated the third time
 					if axfr && n == 2 || n == 3 {
+						if t.tsigUnsigned > 0 {
+							c <- &Envelope{in.Answer, ErrNoSig}
+							return
+						}
 						c <- &Envelope{in.Answer, nil}
 						return
 					}
@@ -183,8 +206,12 @@ func (t *Transfer) inIxfr(q *Msg, c chan *Envelope) {
 //	// w.Close() // Client closes connection
 //
 // The server is responsible for sending the correct sequence of RRs through the
-// channel ch.
+// channel ch. When q has a valid TSIG, every message is signed.
 func (t *Transfer) Out(w ResponseWriter, q *Msg, ch chan *Envelope) error {
+	ts := q.IsTsig()
+	if ts != nil && w.TsigStatus() != nil {
+		ts = nil
+	}
 	for x := range ch {
 		r := new(Msg)
 		// Compress?
@@ -192,11 +219,14 @@ func (t *Transfer) Out(w ResponseWriter, q *Msg, ch chan *Envelope) error {
 		r.Authoritative = true
 		// assume it fits TODO(miek): fix
 		r.Answer = append(r.Answer, x.RR...)
+		if ts != nil {
+			r.SetTsig(ts.Hdr.Name, ts.Algorithm, ts.Fudge, time.Now().Unix())
+		}
 		if err := w.WriteMsg(r); err != nil {
 			return err
 		}
+		w.TsigTimersOnly(true) // the next messages are signed as part of a sequence
 	}
-	w.TsigTimersOnly(true)
 	return nil
 }
 
@@ -212,25 +242,48 @@ func (t *Transfer) ReadMsg() (*Msg, error) {
 	if err := m.Unpack(p); err != nil {
 		return nil, err
 	}
-	if ts := m.IsTsig(); ts != nil && t.TsigSecret != nil {
-		if _, ok := t.TsigSecret[ts.Hdr.Name]; !ok {
-			return m, ErrSecret
+	tp := t.tsigProvider()
+	if tp == nil {
+		return m, err
+	}
+	ts := m.IsTsig()
+	if ts == nil {
+		if t.tsigRequestMAC == "" {
+			return m, err
 		}
-		// Need to work on the original message p, as that was used to calculate the tsig.
-		err = TsigVerify(p, t.TsigSecret[ts.Hdr.Name], t.tsigRequestMAC, t.tsigTimersOnly)
-		t.tsigRequestMAC = ts.MAC
+		// The query was signed: the first and the last messages must be signed,
+		// the ones in between at least every 100 messages.
+		if !t.tsigTimersOnly || t.tsigUnsigned >= tsigMaxUnsigned {
+			return m, ErrNoSig
+		}
+		t.tsigPrior = append(t.tsigPrior, p...)
+		t.tsigUnsigned++
+		return m, err
 	}
+	// Need to work on the original message p, as that was used to calculate the tsig.
+	err = tsigVerify(t.tsigPrior, p, tp, t.tsigRequestMAC, t.tsigTimersOnly)
+	t.tsigRequestMAC = ts.MAC
+	t.tsigPrior, t.tsigUnsigned = nil, 0
 	return m, err
 }
 
+// tsigProvider returns the TsigProvider of t, one wrapping its TsigSecret, or
+// nil when neither is set.
+func (t *Transfer) tsigProvider() TsigProvider {
+	if t.TsigProvider != nil {
+		return t.TsigProvider
+	}
+	if t.TsigSecret != nil {
+		return tsigSecretProvider(t.TsigSecret)
+	}
+	return nil
+}
+
 // WriteMsg writes a message through the transfer connection t.
 func (t *Transfer) WriteMsg(m *Msg) (err error) {
 	var out []byte
-	if ts := m.IsTsig(); ts != nil && t.TsigSecret != nil {
-		if _, ok := t.TsigSecret[ts.Hdr.Name]; !ok {
-			return ErrSecret
-		}
-		out, t.tsigRequestMAC, err = TsigGenerate(m, t.TsigSecret[ts.Hdr.Name], t.tsigRequestMAC, t.tsigTimersOnly)
+	if ts, tp := m.IsTsig(), t.tsigProvider(); ts != nil && tp != nil {
+		out, t.tsigRequestMAC, err = TsigGenerateWithProvider(m, tp, t.tsigRequestMAC, t.tsigTimersOnly)
 	} else {
 		out, err = m.Pack()
 	}
@@ -258,3 +311,65 @@ func isSOALast(in *Msg) bool {
 }
 
 const errXFR = "bad xfr rcode: %d"
+
+// An RRIterator returns the records of a zone one at a time, and nil when
+// there are no more records.
+type RRIterator func() (RR, error)
+
+// SliceRRIterator returns an RRIterator for rrs.
+func SliceRRIterator(rrs []RR) RRIterator {
+	return func() (RR, error) {
+		if len(rrs) == 0 {
+			return nil, nil
+		}
+		rr := rrs[0]
+		rrs = rrs[1:]
+		return rr, nil
+	}
+}
+
+// TransferOut sends the records of a zone to the client in w as the response
+// to the AXFR query q (RFC 5936). The records come from next, which must
+// return the SOA record of the zone first; TransferOut sends it again after
+// the last record. The records are sent with a ResponseStream, packed in as
+// few messages as they fit in. When q has a valid TSIG, every message is
+// signed.
+//
+// When next doesn't start with an SOA record, a SERVFAIL response is sent and
+// ErrSoa is returned. When next fails, its error is returned and the client is
+// left with an incomplete transfer, the caller should close the connection.
+func TransferOut(w ResponseWriter, q *Msg, next RRIterator) error {
+	first, err := next()
+	if err != nil {
+		return err
+	}
+	soa, ok := first.(*SOA)
+	if !ok {
+		r := new(Msg)
+		r.SetRcode(q, RcodeServerFailure)
+		w.WriteMsg(r)
+		return ErrSoa
+	}
+
+	x := NewResponseStream(w, q)
+	x.Authoritative = true
+	if err := x.Add(soa); err != nil {
+		return err
+	}
+	for {
+		rr, err := next()
+		if err != nil {
+			return err
+		}
+		if rr == nil {
+			break
+		}
+		if err := x.Add(rr); err != nil {
+			return err
+		}
+	}
+	if err := x.Add(soa); err != nil {
+		return err
+	}
+	return x.Flush()
+}
@@ -0,0 +1,171 @@
+package dns
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransferOut(t *testing.T) {
+	soa, _ := NewRR("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 60")
+	zone := []RR{soa}
+	for i := 0; i < 2000; i++ {
+		rr, _ := NewRR(fmt.Sprintf("host%d.example. 3600 IN TXT %q", i, strings.Repeat("x", 100)))
+		zone = append(zone, rr)
+	}
+	messages := 0
+	HandleFunc("example.", func(w ResponseWriter, req *Msg) {
+		err := TransferOut(&countingWriter{ResponseWriter: w, n: &messages}, req, SliceRRIterator(zone))
+		if err != nil {
+			t.Errorf("failed to transfer: %v", err)
+		}
+	})
+	defer HandleRemove("example.")
+
+	secret := map[string]string{"axfr.": "so6ZGir4GPAqINNh9U5c3A=="}
+	s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+		srv.TsigSecret = secret
+	})
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	m := new(Msg)
+	m.SetAxfr("example.")
+	m.SetTsig("axfr.", HmacSHA256, 300, time.Now().Unix())
+	tr := &Transfer{TsigSecret: secret}
+	ch, err := tr.In(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to start transfer: %v", err)
+	}
+	var rrs []RR
+	for env := range ch {
+		if env.Error != nil {
+			t.Fatalf("failed to transfer: %v", env.Error)
+		}
+		rrs = append(rrs, env.RR...)
+	}
+	if len(rrs) != len(zone)+1 || rrs[0].Header().Rrtype != TypeSOA || rrs[len(rrs)-1].Header().Rrtype != TypeSOA {
+		t.Errorf("expected %d records framed by the SOA, got %d", len(zone)+1, len(rrs))
+	}
+	if messages < 2 {
+		t.Errorf("expected the zone to need several messages, got %d", messages)
+	}
+}
+
+func TestTransferOutNoSOA(t *testing.T) {
+	m := new(Msg)
+	m.SetAxfr("example.")
+	a, _ := NewRR("example. 3600 IN A 192.0.2.1")
+	w := &recordWriter{}
+	if err := TransferOut(w, m, SliceRRIterator([]RR{a})); err != ErrSoa {
+		t.Errorf("expected ErrSoa, got %v", err)
+	}
+	if len(w.msgs) != 1 || w.msgs[0].Rcode != RcodeServerFailure {
+		t.Errorf("expected SERVFAIL, got %v", w.msgs)
+	}
+}
+
+// countingWriter counts the messages written.
+type countingWriter struct {
+	ResponseWriter
+	n *int
+}
+
+func (w *countingWriter) WriteMsg(m *Msg) error {
+	*w.n++
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+func TestTransferInUnsigned(t *testing.T) {
+	const secret = "so6ZGir4GPAqINNh9U5c3A=="
+	soa, _ := NewRR("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 60")
+	a, _ := NewRR("example. 3600 IN A 192.0.2.1")
+
+	tests := []struct {
+		signed func(i, n int) bool // whether message i of n is signed
+		n      int
+		err    error
+	}{
+		{func(i, n int) bool { return true }, 3, nil},
+		{func(i, n int) bool { return i == 0 || i == n-1 || i == 50 }, 150, nil},
+		{func(i, n int) bool { return i == 0 || i == n-1 }, 101, nil},
+		{func(i, n int) bool { return i == 0 || i == n-1 }, 102, ErrNoSig},
+		{func(i, n int) bool { return i < n-1 }, 3, ErrNoSig},
+		{func(i, n int) bool { return i > 0 }, 3, ErrNoSig},
+	}
+	for i, tc := range tests {
+		HandleFunc("example.", func(w ResponseWriter, req *Msg) {
+			mac := req.IsTsig().MAC
+			var prior []byte
+			for j := 0; j < tc.n; j++ {
+				r := new(Msg)
+				r.SetReply(req)
+				r.Extra = nil
+				r.Answer = []RR{a}
+				if j == 0 || j == tc.n-1 {
+					r.Answer = []RR{soa}
+				}
+				if !tc.signed(j, tc.n) {
+					out, _ := r.Pack()
+					prior = append(prior, out...)
+					w.Write(out)
+					continue
+				}
+				var out []byte
+				out, mac = signTransferMsg(r, secret, prior, mac, j > 0)
+				prior = nil
+				w.Write(out)
+			}
+		})
+
+		s, addrstr, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0", func(srv *Server) {
+			srv.TsigSecret = map[string]string{"axfr.": secret}
+		})
+		if err != nil {
+			t.Fatalf("unable to run test server: %v", err)
+		}
+
+		m := new(Msg)
+		m.SetAxfr("example.")
+		m.SetTsig("axfr.", HmacSHA256, 300, time.Now().Unix())
+		tr := &Transfer{TsigSecret: map[string]string{"axfr.": secret}}
+		ch, err := tr.In(m, addrstr)
+		if err != nil {
+			t.Fatalf("failed to start transfer: %v", err)
+		}
+		var last error
+		for env := range ch {
+			if env.Error != nil {
+				last = env.Error
+			}
+		}
+		if last != tc.err {
+			t.Errorf("test %d: expected %v, got %v", i, tc.err, last)
+		}
+		s.Shutdown()
+		HandleRemove("example.")
+	}
+}
+
+// signTransferMsg signs m, a message of a transfer after the unsigned
+// messages in prior, and returns it packed with its MAC.
+func signTransferMsg(m *Msg, secret string, prior []byte, requestMAC string, timersOnly bool) ([]byte, string) {
+	mbuf, _ := m.Pack()
+	ts := &TSIG{
+		Hdr:        RR_Header{Name: "axfr.", Rrtype: TypeTSIG, Class: ClassANY},
+		Algorithm:  HmacSHA256,
+		TimeSigned: uint64(time.Now().Unix()),
+		Fudge:      300,
+		OrigId:     m.Id,
+	}
+	mac, _ := tsigHMACProvider(secret).Generate(tsigBuffer(prior, mbuf, ts, requestMAC, timersOnly), ts)
+	ts.MAC = hex.EncodeToString(mac)
+	ts.MACSize = uint16(len(mac))
+	m.Extra = append(m.Extra, ts)
+	out, _ := m.Pack()
+	return out, ts.MAC
+}
@@ -0,0 +1,66 @@
+package dns
+
+import "crypto/tls"
+
+// ALPNDoT is the ALPN protocol ID of DNS over TLS, that zone transfers over
+// TLS (XoT) must negotiate (RFC 9103, Section 7.1).
+const ALPNDoT = "dot"
+
+// XoTConfig returns a copy of config with ALPNDoT in its NextProtos, for the
+// clients and the servers of zone transfers over TLS (RFC 9103). Servers
+// should also require the certificates of their secondaries, with ClientAuth
+// set to tls.RequireAndVerifyClientCert and their CAs in ClientCAs, and
+// secondaries should present one in Certificates.
+func XoTConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = new(tls.Config)
+	}
+	config = config.Clone()
+	for _, p := range config.NextProtos {
+		if p == ALPNDoT {
+			return config
+		}
+	}
+	config.NextProtos = append(config.NextProtos, ALPNDoT)
+	return config
+}
+
+// An XoTPolicy decides whether the client of the connection cs may transfer
+// the zone of the AXFR or IXFR query q, e.g. from the names of its verified
+// certificate, cs.VerifiedChains[0][0].
+type XoTPolicy func(cs *tls.ConnectionState, q *Msg) bool
+
+// XoTMiddleware only serves zone transfers over TLS (RFC 9103): AXFR and IXFR
+// queries are refused unless they come over a TLS connection that negotiated
+// ALPNDoT, from a client with a verified certificate that policy allows. A
+// nil policy allows any verified certificate. Other queries are passed on.
+func XoTMiddleware(policy XoTPolicy) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Msg) {
+			if len(r.Question) == 0 || r.Question[0].Qtype != TypeAXFR && r.Question[0].Qtype != TypeIXFR {
+				next.ServeDNS(w, r)
+				return
+			}
+			if !xotAllowed(w, r, policy) {
+				m := new(Msg)
+				m.SetRcode(r, RcodeRefused)
+				w.WriteMsg(m)
+				return
+			}
+			next.ServeDNS(w, r)
+		})
+	}
+}
+
+// xotAllowed reports whether the transfer query r on w is allowed by policy.
+func xotAllowed(w ResponseWriter, r *Msg, policy XoTPolicy) bool {
+	stater, ok := w.(ConnectionStater)
+	if !ok {
+		return false
+	}
+	cs := stater.ConnectionState()
+	if cs == nil || cs.NegotiatedProtocol != ALPNDoT || len(cs.VerifiedChains) == 0 {
+		return false
+	}
+	return policy == nil || policy(cs, r)
+}
@@ -0,0 +1,143 @@
+package dns
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestXoT(t *testing.T) {
+	ca, caKey := newTestCert(t, "ca.example", true, time.Now().Add(time.Hour), nil, nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	tlsCert := func(name string) tls.Certificate {
+		cert, key := newTestCert(t, name, false, time.Now().Add(time.Hour), ca, caKey)
+		return tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key}
+	}
+
+	soa := testRR("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 60")
+	zone := []RR{soa, testRR("www.example. 3600 IN A 192.0.2.1")}
+	h := Chain(HandlerFunc(func(w ResponseWriter, req *Msg) {
+		TransferOut(w, req, SliceRRIterator(zone))
+	}), XoTMiddleware(func(cs *tls.ConnectionState, q *Msg) bool {
+		return cs.VerifiedChains[0][0].Subject.CommonName == "secondary.example"
+	}))
+	HandleFunc("example.", h.ServeDNS)
+	defer HandleRemove("example.")
+
+	s, addrstr, err := RunLocalTLSServer("127.0.0.1:0", XoTConfig(&tls.Config{
+		Certificates: []tls.Certificate{tlsCert("localhost")},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}))
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	transfer := func(addr string, config *tls.Config) ([]RR, error) {
+		m := new(Msg)
+		m.SetAxfr("example.")
+		tr := &Transfer{TLSConfig: config}
+		ch, err := tr.In(m, addr)
+		if err != nil {
+			return nil, err
+		}
+		var rrs []RR
+		for env := range ch {
+			if env.Error != nil {
+				err = env.Error
+			}
+			rrs = append(rrs, env.RR...)
+		}
+		return rrs, err
+	}
+
+	rrs, err := transfer(addrstr, &tls.Config{ServerName: "localhost", RootCAs: pool, Certificates: []tls.Certificate{tlsCert("secondary.example")}})
+	if err != nil {
+		t.Fatalf("failed to transfer: %v", err)
+	}
+	if len(rrs) != 3 {
+		t.Errorf("expected 3 records, got %d", len(rrs))
+	}
+
+	// The policy refuses other secondaries.
+	if _, err := transfer(addrstr, &tls.Config{ServerName: "localhost", RootCAs: pool, Certificates: []tls.Certificate{tlsCert("other.example")}}); err == nil {
+		t.Error("expected the transfer to be refused")
+	}
+
+	// Transfers without TLS are refused.
+	s2, addr2, _, err := RunLocalTCPServerWithFinChan("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s2.Shutdown()
+	if _, err := transfer(addr2, nil); err == nil {
+		t.Error("expected the transfer to be refused")
+	}
+}
+
+func TestXoTRefresh(t *testing.T) {
+	ca, caKey := newTestCert(t, "ca.example", true, time.Now().Add(time.Hour), nil, nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	cert, key := newTestCert(t, "localhost", false, time.Now().Add(time.Hour), ca, caKey)
+
+	zone := []RR{
+		testRR("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 60"),
+		testRR("www.example.org. 3600 IN A 192.0.2.1"),
+	}
+	h := Chain(HandlerFunc(func(w ResponseWriter, req *Msg) {
+		if req.Question[0].Qtype == TypeAXFR {
+			TransferOut(w, req, SliceRRIterator(zone))
+			return
+		}
+		m := new(Msg)
+		m.SetReply(req)
+		m.Answer = zone[:1]
+		w.WriteMsg(m)
+	}), XoTMiddleware(nil))
+	HandleFunc("example.org.", h.ServeDNS)
+	defer HandleRemove("example.org.")
+
+	// The primary only serves over TLS.
+	s, addrstr, err := RunLocalTLSServer("127.0.0.1:0", XoTConfig(&tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    pool,
+	}))
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	client, clientKey := newTestCert(t, "secondary.example", false, time.Now().Add(time.Hour), ca, caKey)
+	transferred := make(chan []RR, 1)
+	tm := &TransferManager{
+		Timeout:     time.Second,
+		Transferred: func(zone string, rrs []RR) error { transferred <- rrs; return nil },
+	}
+	tm.Add("example.org.", []Primary{{Addr: addrstr, TLS: &tls.Config{
+		ServerName:   "localhost",
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{client.Raw}, PrivateKey: clientKey}},
+	}}}, nil)
+	if err := tm.Refresh("example.org."); err != nil {
+		t.Fatalf("failed to refresh over TLS: %v", err)
+	}
+	if rrs := <-transferred; len(rrs) != 2 {
+		t.Errorf("expected 2 records, got %v", rrs)
+	}
+}
+
+func TestXoTConfig(t *testing.T) {
+	config := &tls.Config{NextProtos: []string{"h2"}}
+	x := XoTConfig(config)
+	if len(x.NextProtos) != 2 || x.NextProtos[1] != ALPNDoT || len(config.NextProtos) != 1 {
+		t.Errorf("unexpected protocols %v", x.NextProtos)
+	}
+	if x = XoTConfig(x); len(x.NextProtos) != 2 {
+		t.Errorf("expected %q once, got %v", ALPNDoT, x.NextProtos)
+	}
+}
@@ -0,0 +1,268 @@
+// Code generated by "go run types_generate.go -iana dns-parameters-4.csv"; DO NOT EDIT.
+
+package dns
+
+// Type codes from the IANA Resource Record (RR) TYPEs registry.
+const (
+	TypeA          uint16 = 1  // a host address
+	TypeNS         uint16 = 2  // an authoritative name server
+	TypeMD         uint16 = 3  // a mail destination (OBSOLETE - use MX)
+	TypeMF         uint16 = 4  // a mail forwarder (OBSOLETE - use MX)
+	TypeCNAME      uint16 = 5  // the canonical name for an alias
+	TypeSOA        uint16 = 6  // marks the start of a zone of authority
+	TypeMB         uint16 = 7  // a mailbox domain name (EXPERIMENTAL)
+	TypeMG         uint16 = 8  // a mail group member (EXPERIMENTAL)
+	TypeMR         uint16 = 9  // a mail rename domain name (EXPERIMENTAL)
+	TypeNULL       uint16 = 10 // a null RR (EXPERIMENTAL)
+	TypeWKS        uint16 = 11 // a well known service description
+	TypePTR        uint16 = 12 // a domain name pointer
+	TypeHINFO      uint16 = 13 // host information
+	TypeMINFO      uint16 = 14 // mailbox or mail list information
+	TypeMX         uint16 = 15 // mail exchange
+	TypeTXT        uint16 = 16 // text strings
+	TypeRP         uint16 = 17 // for Responsible Person
+	TypeAFSDB      uint16 = 18 // for AFS Data Base location
+	TypeX25        uint16 = 19 // for X.25 PSDN address
+	TypeISDN       uint16 = 20 // for ISDN address
+	TypeRT         uint16 = 21 // for Route Through
+	TypeNSAP       uint16 = 22 // for NSAP address, NSAP style A record (DEPRECATED)
+	TypeNSAPPTR    uint16 = 23 // for domain name pointer, NSAP style (DEPRECATED)
+	TypeSIG        uint16 = 24 // for security signature
+	TypeKEY        uint16 = 25 // for security key
+	TypePX         uint16 = 26 // X.400 mail mapping information
+	TypeGPOS       uint16 = 27 // Geographical Position
+	TypeAAAA       uint16 = 28 // IP6 Address
+	TypeLOC        uint16 = 29 // Location Information
+	TypeNXT        uint16 = 30 // Next Domain (OBSOLETE)
+	TypeEID        uint16 = 31 // Endpoint Identifier
+	TypeNIMLOC     uint16 = 32 // Nimrod Locator
+	TypeSRV        uint16 = 33 // Server Selection
+	TypeATMA       uint16 = 34 // ATM Address
+	TypeNAPTR      uint16 = 35 // Naming Authority Pointer
+	TypeKX         uint16 = 36 // Key Exchanger
+	TypeCERT       uint16 = 37 // CERT
+	TypeA6         uint16 = 38 // A6 (OBSOLETE - use AAAA)
+	TypeDNAME      uint16 = 39 // DNAME
+	TypeSINK       uint16 = 40 // SINK
+	TypeOPT        uint16 = 41 // OPT
+	TypeAPL        uint16 = 42 // APL
+	TypeDS         uint16 = 43 // Delegation Signer
+	TypeSSHFP      uint16 = 44 // SSH Key Fingerprint
+	TypeIPSECKEY   uint16 = 45 // IPSECKEY
+	TypeRRSIG      uint16 = 46 // RRSIG
+	TypeNSEC       uint16 = 47 // NSEC
+	TypeDNSKEY     uint16 = 48 // DNSKEY
+	TypeDHCID      uint16 = 49 // DHCID
+	TypeNSEC3      uint16 = 50 // NSEC3
+	TypeNSEC3PARAM uint16 = 51 // NSEC3PARAM
+	TypeTLSA       uint16 = 52 // TLSA
+	TypeSMIMEA     uint16 = 53 // S/MIME cert association
+	TypeHIP        uint16 = 55 // Host Identity Protocol
+	TypeNINFO      uint16 = 56 // NINFO
+	TypeRKEY       uint16 = 57 // RKEY
+	TypeTALINK     uint16 = 58 // Trust Anchor LINK
+	TypeCDS        uint16 = 59 // Child DS
+	TypeCDNSKEY    uint16 = 60 // DNSKEY(s) the Child wants reflected in DS
+	TypeOPENPGPKEY uint16 = 61 // OpenPGP Key
+	TypeCSYNC      uint16 = 62 // Child-To-Parent Synchronization
+	TypeZONEMD     uint16 = 63 // Message Digest Over Zone Data
+	TypeSVCB       uint16 = 64 // General-purpose service binding
+	TypeHTTPS      uint16 = 65 // SVCB-compatible type for use with HTTP
+	TypeSPF        uint16 = 99
+	TypeUINFO      uint16 = 100
+	TypeUID        uint16 = 101
+	TypeGID        uint16 = 102
+	TypeUNSPEC     uint16 = 103
+	TypeNID        uint16 = 104
+	TypeL32        uint16 = 105
+	TypeL64        uint16 = 106
+	TypeLP         uint16 = 107
+	TypeEUI48      uint16 = 108   // an EUI-48 address
+	TypeEUI64      uint16 = 109   // an EUI-64 address
+	TypeTKEY       uint16 = 249   // Transaction Key
+	TypeTSIG       uint16 = 250   // Transaction Signature
+	TypeIXFR       uint16 = 251   // incremental transfer
+	TypeAXFR       uint16 = 252   // transfer of an entire zone
+	TypeMAILB      uint16 = 253   // mailbox-related RRs (MB, MG or MR)
+	TypeMAILA      uint16 = 254   // mail agent RRs (OBSOLETE - see MX)
+	TypeANY        uint16 = 255   // A request for some or all records the server has available
+	TypeURI        uint16 = 256   // URI
+	TypeCAA        uint16 = 257   // Certification Authority Restriction
+	TypeAVC        uint16 = 258   // Application Visibility and Control
+	TypeDOA        uint16 = 259   // Digital Object Architecture
+	TypeAMTRELAY   uint16 = 260   // Automatic Multicast Tunneling Relay
+	TypeTA         uint16 = 32768 // DNSSEC Trust Authorities
+	TypeDLV        uint16 = 32769 // DNSSEC Lookaside Validation (OBSOLETE)
+)
+
+// TypeToString is a map of strings for each RR type.
+var TypeToString = map[uint16]string{
+	TypeNone:       "None",
+	TypeA:          "A",
+	TypeNS:         "NS",
+	TypeMD:         "MD",
+	TypeMF:         "MF",
+	TypeCNAME:      "CNAME",
+	TypeSOA:        "SOA",
+	TypeMB:         "MB",
+	TypeMG:         "MG",
+	TypeMR:         "MR",
+	TypeNULL:       "NULL",
+	TypePTR:        "PTR",
+	TypeHINFO:      "HINFO",
+	TypeMINFO:      "MINFO",
+	TypeMX:         "MX",
+	TypeTXT:        "TXT",
+	TypeRP:         "RP",
+	TypeAFSDB:      "AFSDB",
+	TypeX25:        "X25",
+	TypeISDN:       "ISDN",
+	TypeRT:         "RT",
+	TypeNSAPPTR:    "NSAP-PTR",
+	TypeSIG:        "SIG",
+	TypeKEY:        "KEY",
+	TypePX:         "PX",
+	TypeGPOS:       "GPOS",
+	TypeAAAA:       "AAAA",
+	TypeLOC:        "LOC",
+	TypeNXT:        "NXT",
+	TypeEID:        "EID",
+	TypeNIMLOC:     "NIMLOC",
+	TypeSRV:        "SRV",
+	TypeATMA:       "ATMA",
+	TypeNAPTR:      "NAPTR",
+	TypeKX:         "KX",
+	TypeCERT:       "CERT",
+	TypeDNAME:      "DNAME",
+	TypeOPT:        "OPT",
+	TypeDS:         "DS",
+	TypeSSHFP:      "SSHFP",
+	TypeRRSIG:      "RRSIG",
+	TypeNSEC:       "NSEC",
+	TypeDNSKEY:     "DNSKEY",
+	TypeDHCID:      "DHCID",
+	TypeNSEC3:      "NSEC3",
+	TypeNSEC3PARAM: "NSEC3PARAM",
+	TypeTLSA:       "TLSA",
+	TypeSMIMEA:     "SMIMEA",
+	TypeHIP:        "HIP",
+	TypeNINFO:      "NINFO",
+	TypeRKEY:       "RKEY",
+	TypeTALINK:     "TALINK",
+	TypeCDS:        "CDS",
+	TypeCDNSKEY:    "CDNSKEY",
+	TypeOPENPGPKEY: "OPENPGPKEY",
+	TypeCSYNC:      "CSYNC",
+	TypeSPF:        "SPF",
+	TypeUINFO:      "UINFO",
+	TypeUID:        "UID",
+	TypeGID:        "GID",
+	TypeUNSPEC:     "UNSPEC",
+	TypeNID:        "NID",
+	TypeL32:        "L32",
+	TypeL64:        "L64",
+	TypeLP:         "LP",
+	TypeEUI48:      "EUI48",
+	TypeEUI64:      "EUI64",
+	TypeTKEY:       "TKEY",
+	TypeTSIG:       "TSIG",
+	TypeIXFR:       "IXFR",
+	TypeAXFR:       "AXFR",
+	TypeMAILB:      "MAILB",
+	TypeMAILA:      "MAILA",
+	TypeANY:        "ANY",
+	TypeURI:        "URI",
+	TypeCAA:        "CAA",
+	TypeAVC:        "AVC",
+	TypeDOA:        "DOA",
+	TypeAMTRELAY:   "AMTRELAY",
+	TypeTA:         "TA",
+	TypeDLV:        "DLV",
+	TypeReserved:   "Reserved",
+}
+
+// StringToType is the reverse of TypeToString, needed for string parsing.
+var StringToType = map[string]uint16{
+	"None":       TypeNone,
+	"A":          TypeA,
+	"NS":         TypeNS,
+	"MD":         TypeMD,
+	"MF":         TypeMF,
+	"CNAME":      TypeCNAME,
+	"SOA":        TypeSOA,
+	"MB":         TypeMB,
+	"MG":         TypeMG,
+	"MR":         TypeMR,
+	"NULL":       TypeNULL,
+	"PTR":        TypePTR,
+	"HINFO":      TypeHINFO,
+	"MINFO":      TypeMINFO,
+	"MX":         TypeMX,
+	"TXT":        TypeTXT,
+	"RP":         TypeRP,
+	"AFSDB":      TypeAFSDB,
+	"X25":        TypeX25,
+	"ISDN":       TypeISDN,
+	"RT":         TypeRT,
+	"NSAP-PTR":   TypeNSAPPTR,
+	"SIG":        TypeSIG,
+	"KEY":        TypeKEY,
+	"PX":         TypePX,
+	"GPOS":       TypeGPOS,
+	"AAAA":       TypeAAAA,
+	"LOC":        TypeLOC,
+	"NXT":        TypeNXT,
+	"EID":        TypeEID,
+	"NIMLOC":     TypeNIMLOC,
+	"SRV":        TypeSRV,
+	"ATMA":       TypeATMA,
+	"NAPTR":      TypeNAPTR,
+	"KX":         TypeKX,
+	"CERT":       TypeCERT,
+	"DNAME":      TypeDNAME,
+	"OPT":        TypeOPT,
+	"DS":         TypeDS,
+	"SSHFP":      TypeSSHFP,
+	"RRSIG":      TypeRRSIG,
+	"NSEC":       TypeNSEC,
+	"DNSKEY":     TypeDNSKEY,
+	"DHCID":      TypeDHCID,
+	"NSEC3":      TypeNSEC3,
+	"NSEC3PARAM": TypeNSEC3PARAM,
+	"TLSA":       TypeTLSA,
+	"SMIMEA":     TypeSMIMEA,
+	"HIP":        TypeHIP,
+	"NINFO":      TypeNINFO,
+	"RKEY":       TypeRKEY,
+	"TALINK":     TypeTALINK,
+	"CDS":        TypeCDS,
+	"CDNSKEY":    TypeCDNSKEY,
+	"OPENPGPKEY": TypeOPENPGPKEY,
+	"CSYNC":      TypeCSYNC,
+	"SPF":        TypeSPF,
+	"UINFO":      TypeUINFO,
+	"UID":        TypeUID,
+	"GID":        TypeGID,
+	"UNSPEC":     TypeUNSPEC,
+	"NID":        TypeNID,
+	"L32":        TypeL32,
+	"L64":        TypeL64,
+	"LP":         TypeLP,
+	"EUI48":      TypeEUI48,
+	"EUI64":      TypeEUI64,
+	"TKEY":       TypeTKEY,
+	"TSIG":       TypeTSIG,
+	"IXFR":       TypeIXFR,
+	"AXFR":       TypeAXFR,
+	"MAILB":      TypeMAILB,
+	"MAILA":      TypeMAILA,
+	"ANY":        TypeANY,
+	"URI":        TypeURI,
+	"CAA":        TypeCAA,
+	"AVC":        TypeAVC,
+	"DOA":        TypeDOA,
+	"AMTRELAY":   TypeAMTRELAY,
+	"TA":         TypeTA,
+	"DLV":        TypeDLV,
+	"Reserved":   TypeReserved,
+}
@@ -44,6 +44,26 @@ func (rr *AFSDB) pack(msg []byte, off int, compression compressionMap, compress
 	return headerEnd, off, nil
 }
 
+func (rr *AMTRELAY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, int, error) {
+	headerEnd, off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return headerEnd, off, err
+	}
+	off, err = packUint8(rr.Precedence, msg, off)
+	if err != nil {
+		return headerEnd, off, err
+	}
+	off, err = packUint8(rr.GatewayType, msg, off)
+	if err != nil {
+		return headerEnd, off, err
+	}
+	off, err = packAMTRELAYGateway(rr.GatewayType, rr.GatewayAddr, rr.GatewayHost, msg, off, compression)
+	if err != nil {
+		return headerEnd, off, err
+	}
+	return headerEnd, off, nil
+}
+
 func (rr *ANY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, int, error) {
 	headerEnd, off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
@@ -260,6 +280,34 @@ func (rr *DNSKEY) pack(msg []byte, off int, compression compressionMap, compress
 	return headerEnd, off, nil
 }
 
+func (rr *DOA) pack(msg []byte, off int, compression compressionMap, compress bool) (int, int, error) {
+	headerEnd, off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return headerEnd, off, err
+	}
+	off, err = packUint32(rr.Enterprise, msg, off)
+	if err != nil {
+		return headerEnd, off, err
+	}
+	off, err = packUint32(rr.Type, msg, off)
+	if err != nil {
+		return headerEnd, off, err
+	}
+	off, err = packUint8(rr.Location, msg, off)
+	if err != nil {
+		return headerEnd, off, err
+	}
+	off, err = packString(rr.MediaType, msg, off)
+	if err != nil {
+		return headerEnd, off, err
+	}
+	off, err = packStringBase64(rr.Data, msg, off)
+	if err != nil {
+		return headerEnd, off, err
+	}
+	return headerEnd, off, nil
+}
+
 func (rr *DS) pack(msg []byte, off int, compression compressionMap, compress bool) (int, int, error) {
 	headerEnd, off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
@@ -1410,6 +1458,34 @@ func unpackAFSDB(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackAMTRELAY(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(AMTRELAY)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Precedence, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.GatewayType, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	rr.GatewayAddr, rr.GatewayHost, off, err = unpackAMTRELAYGateway(rr.GatewayType, msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 func unpackANY(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rr := new(ANY)
 	rr.Hdr = h
@@ -1743,6 +1819,51 @@ func unpackDNSKEY(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackDOA(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(DOA)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Enterprise, off, err = unpackUint32(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Type, off, err = unpackUint32(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Location, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.MediaType, off, err = unpackString(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Data, off, err = unpackStringBase64(msg, off, rdStart+int(rr.Hdr.Rdlength))
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 func unpackDS(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rr := new(DS)
 	rr.Hdr = h
@@ -3406,6 +3527,7 @@ var typeToUnpack = map[uint16]func(RR_Header, []byte, int) (RR, int, error){
 	TypeA:          unpackA,
 	TypeAAAA:       unpackAAAA,
 	TypeAFSDB:      unpackAFSDB,
+	TypeAMTRELAY:   unpackAMTRELAY,
 	TypeANY:        unpackANY,
 	TypeAVC:        unpackAVC,
 	TypeCAA:        unpackCAA,
@@ -3418,6 +3540,7 @@ var typeToUnpack = map[uint16]func(RR_Header, []byte, int) (RR, int, error){
 	TypeDLV:        unpackDLV,
 	TypeDNAME:      unpackDNAME,
 	TypeDNSKEY:     unpackDNSKEY,
+	TypeDOA:        unpackDOA,
 	TypeDS:         unpackDS,
 	TypeEID:        unpackEID,
 	TypeEUI48:      unpackEUI48,
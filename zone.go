@@ -0,0 +1,373 @@
+package dns
+
+import (
+	"strings"
+	"sync"
+)
+
+// maxChase is the maximum number of CNAME and DNAME records followed by a
+// lookup in a Zone.
+const maxChase = 16
+
+// A Zone holds the records of a zone indexed by name and type, and answers
+// lookups in it. A Zone isn't changed once made, a new one is made when the
+// zone changes, see ZoneStore.
+type Zone struct {
+	origin string
+	soa    *SOA
+	negSOA *SOA // soa with the TTL of negative answers (RFC 2308, Section 5)
+	rrs    []RR
+	nodes  map[string]*zoneNode // by lower cased name, including the empty non-terminals
+	hashed map[string]*zoneNode // NSEC3 records and their RRSIGs, by lower cased name
+
+	indexOnce sync.Once
+	index     *zoneDenial // NSEC and NSEC3 records, built when first needed
+}
+
+type zoneNode struct {
+	rrsets map[uint16][]RR // by type
+	sigs   map[uint16][]RR // RRSIGs, by type covered
+}
+
+// NewZone returns the zone of origin with the records rrs, that must hold the
+// SOA record of origin. Records outside of the zone are an error.
+func NewZone(origin string, rrs []RR) (*Zone, error) {
+	z := &Zone{
+		origin: strings.ToLower(Fqdn(origin)),
+		rrs:    rrs,
+		nodes:  make(map[string]*zoneNode),
+		hashed: make(map[string]*zoneNode),
+	}
+	for _, rr := range rrs {
+		h := rr.Header()
+		name := strings.ToLower(h.Name)
+		if !IsSubDomain(z.origin, name) {
+			return nil, &Error{err: "record outside of zone: " + h.Name}
+		}
+		var node *zoneNode
+		switch sig, _ := rr.(*RRSIG); {
+		case h.Rrtype == TypeNSEC3 || sig != nil && sig.TypeCovered == TypeNSEC3:
+			// The owner names of NSEC3 records aren't names of the zone.
+			if node = z.hashed[name]; node == nil {
+				node = newZoneNode()
+				z.hashed[name] = node
+			}
+		default:
+			node = z.node(name)
+		}
+		if sig, ok := rr.(*RRSIG); ok {
+			node.sigs[sig.TypeCovered] = append(node.sigs[sig.TypeCovered], rr)
+			continue
+		}
+		node.rrsets[h.Rrtype] = append(node.rrsets[h.Rrtype], rr)
+		if soa, ok := rr.(*SOA); ok && name == z.origin && z.soa == nil {
+			z.soa = soa
+		}
+	}
+	if z.soa == nil {
+		return nil, ErrSoa
+	}
+	z.negSOA = z.soa.copy().(*SOA)
+	if z.negSOA.Minttl < z.negSOA.Hdr.Ttl {
+		z.negSOA.Hdr.Ttl = z.negSOA.Minttl
+	}
+	return z, nil
+}
+
+// node returns the node of name, after adding it and the empty non-terminals
+// above it if it doesn't exist.
+func (z *Zone) node(name string) *zoneNode {
+	if node := z.nodes[name]; node != nil {
+		return node
+	}
+	node := newZoneNode()
+	z.nodes[name] = node
+	if name != z.origin {
+		i, end := NextLabel(name, 0)
+		if end {
+			z.node(".")
+		} else {
+			z.node(name[i:])
+		}
+	}
+	return node
+}
+
+func newZoneNode() *zoneNode {
+	return &zoneNode{rrsets: make(map[uint16][]RR), sigs: make(map[uint16][]RR)}
+}
+
+// Origin returns the origin of z, lower cased.
+func (z *Zone) Origin() string { return z.origin }
+
+// SOA returns the SOA record of z.
+func (z *Zone) SOA() *SOA { return z.soa }
+
+// Records returns the records z was made with.
+func (z *Zone) Records() []RR { return z.rrs }
+
+// RRset returns the records of name and rrtype.
+func (z *Zone) RRset(name string, rrtype uint16) []RR {
+	if node := z.lookupNode(name, rrtype); node != nil {
+		return node.rrsets[rrtype]
+	}
+	return nil
+}
+
+// Signatures returns the RRSIGs of name that cover rrtype.
+func (z *Zone) Signatures(name string, rrtype uint16) []RR {
+	if node := z.lookupNode(name, rrtype); node != nil {
+		return node.sigs[rrtype]
+	}
+	return nil
+}
+
+// lookupNode returns the node of name that holds the records of rrtype.
+func (z *Zone) lookupNode(name string, rrtype uint16) *zoneNode {
+	name = strings.ToLower(Fqdn(name))
+	if rrtype == TypeNSEC3 {
+		return z.hashed[name]
+	}
+	return z.nodes[name]
+}
+
+// Exists reports whether name exists in z, it may be an empty non-terminal.
+func (z *Zone) Exists(name string) bool {
+	return z.nodes[strings.ToLower(Fqdn(name))] != nil
+}
+
+// A LookupResult is the kind of a ZoneLookup.
+type LookupResult int
+
+// The results of a lookup in a Zone.
+const (
+	LookupSuccess    LookupResult = iota // the records are in Answer
+	LookupNoData                         // the name exists, but has no records of the type
+	LookupNameError                      // the name doesn't exist
+	LookupDelegation                     // the name is at or below a zone cut, Ns holds its NS records
+	LookupNotInZone                      // the name isn't in the zone
+)
+
+// A ZoneLookup is the result of Zone.Lookup. Its records are shared with the
+// Zone, except the ones synthesized from a wildcard or a DNAME record, and
+// must not be modified.
+type ZoneLookup struct {
+	Result LookupResult
+	Answer []RR // the records found, and the CNAME and DNAME records followed to them
+	Ns     []RR // the NS records of a delegation, or the SOA record of a negative answer
+	Extra  []RR // the glue of a delegation
+
+	Name            string // the name the lookup ended with, after following CNAME and DNAME records
+	Wildcard        string // the wildcard the answer of Name was synthesized from, if any
+	ClosestEncloser string // the closest encloser of Name, for name errors and wildcard answers
+}
+
+// Lookup looks up the records of name and qtype in z. CNAME records are
+// followed within z, DNAME records are followed with a synthesized CNAME
+// record (RFC 6672), and answers are synthesized from wildcards (RFC 4592).
+// Queries for the DS records of a zone cut are answered from z.
+func (z *Zone) Lookup(name string, qtype uint16) *ZoneLookup {
+	l := new(ZoneLookup)
+	name = strings.ToLower(Fqdn(name))
+	if !IsSubDomain(z.origin, name) {
+		l.Result = LookupNotInZone
+		return l
+	}
+	z.lookup(l, name, qtype, 0)
+	return l
+}
+
+func (z *Zone) lookup(l *ZoneLookup, name string, qtype uint16, depth int) {
+	l.Name, l.Wildcard, l.ClosestEncloser = name, "", ""
+
+	// Walk down from the origin to find zone cuts, DNAME records and the
+	// closest encloser.
+	ce := z.origin
+	labels := Split(name)
+	for i := len(labels) - CountLabel(z.origin); i >= 0; i-- {
+		cur := z.origin
+		if i < len(labels) {
+			cur = name[labels[i]:]
+		}
+		node := z.nodes[cur]
+		if node == nil {
+			break
+		}
+		ce = cur
+		if ns := node.rrsets[TypeNS]; ns != nil && cur != z.origin && (i > 0 || qtype != TypeDS) {
+			l.Result = LookupDelegation
+			l.Ns = ns
+			l.Extra = z.glue(ns)
+			return
+		}
+		if dname, ok := firstRR(node.rrsets[TypeDNAME]).(*DNAME); ok && i > 0 {
+			l.Answer = append(l.Answer, dname)
+			target := name[:labels[i]]
+			if dname.Target != "." {
+				target += strings.ToLower(dname.Target)
+			}
+			if _, ok := IsDomainName(target); !ok {
+				l.Result = LookupSuccess // no CNAME can be synthesized
+				return
+			}
+			l.Answer = append(l.Answer, &CNAME{
+				Hdr:    RR_Header{Name: name, Rrtype: TypeCNAME, Class: dname.Hdr.Class, Ttl: dname.Hdr.Ttl},
+				Target: target,
+			})
+			z.chase(l, target, qtype, depth)
+			return
+		}
+	}
+	if node := z.nodes[name]; node != nil {
+		z.answer(l, name, node, qtype, depth)
+		return
+	}
+
+	l.ClosestEncloser = ce
+	wildcard := wildcardOf(ce)
+	if node := z.nodes[wildcard]; node != nil {
+		l.Wildcard = wildcard
+		z.answer(l, name, node, qtype, depth)
+		return
+	}
+	l.Result = LookupNameError
+	l.Ns = []RR{z.negSOA}
+}
+
+// answer adds the records of qtype of node, the node of name or of the
+// wildcard it matches, to l.
+func (z *Zone) answer(l *ZoneLookup, name string, node *zoneNode, qtype uint16, depth int) {
+	var rrs []RR
+	switch qtype {
+	case TypeANY:
+		for _, rrset := range node.rrsets {
+			rrs = append(rrs, rrset...)
+		}
+	case TypeRRSIG:
+		for _, sigs := range node.sigs {
+			rrs = append(rrs, sigs...)
+		}
+	default:
+		rrs = node.rrsets[qtype]
+	}
+	if len(rrs) > 0 {
+		l.Result = LookupSuccess
+		l.Answer = append(l.Answer, l.synthesize(name, rrs)...)
+		return
+	}
+	if cname, ok := firstRR(node.rrsets[TypeCNAME]).(*CNAME); ok {
+		l.Answer = append(l.Answer, l.synthesize(name, []RR{cname})...)
+		z.chase(l, strings.ToLower(cname.Target), qtype, depth)
+		return
+	}
+	l.Result = LookupNoData
+	l.Ns = []RR{z.negSOA}
+}
+
+// chase continues the lookup l at target, the target of a CNAME record.
+func (z *Zone) chase(l *ZoneLookup, target string, qtype uint16, depth int) {
+	if depth >= maxChase || !IsSubDomain(z.origin, target) {
+		l.Name, l.Wildcard, l.ClosestEncloser = target, "", ""
+		l.Result = LookupSuccess
+		return
+	}
+	z.lookup(l, target, qtype, depth+1)
+}
+
+// synthesize returns rrs with name as owner name when l has a wildcard
+// answer, otherwise rrs itself.
+func (l *ZoneLookup) synthesize(name string, rrs []RR) []RR {
+	if l.Wildcard == "" {
+		return rrs
+	}
+	syn := make([]RR, len(rrs))
+	for i, rr := range rrs {
+		syn[i] = rr.copy()
+		syn[i].Header().Name = name
+	}
+	return syn
+}
+
+// glue returns the address records in z of the name servers in ns.
+func (z *Zone) glue(ns []RR) []RR {
+	var glue []RR
+	for _, rr := range ns {
+		node := z.nodes[strings.ToLower(rr.(*NS).Ns)]
+		if node == nil {
+			continue
+		}
+		glue = append(glue, node.rrsets[TypeA]...)
+		glue = append(glue, node.rrsets[TypeAAAA]...)
+	}
+	return glue
+}
+
+// firstRR returns the first record of rrs, or nil.
+func firstRR(rrs []RR) RR {
+	if len(rrs) == 0 {
+		return nil
+	}
+	return rrs[0]
+}
+
+// A ZoneStore holds the zones of a server. The zones are replaced at once,
+// so lookups never see a zone half updated. A ZoneStore is safe for
+// concurrent use, its zero value is empty and ready for use.
+type ZoneStore struct {
+	mu    sync.RWMutex
+	zones map[string]*Zone // by origin
+}
+
+// Set adds z, replacing the zone of the same origin.
+func (s *ZoneStore) Set(z *Zone) {
+	s.mu.Lock()
+	if s.zones == nil {
+		s.zones = make(map[string]*Zone)
+	}
+	s.zones[z.origin] = z
+	s.mu.Unlock()
+}
+
+// Remove removes the zone of origin.
+func (s *ZoneStore) Remove(origin string) {
+	s.mu.Lock()
+	delete(s.zones, strings.ToLower(Fqdn(origin)))
+	s.mu.Unlock()
+}
+
+// Zone returns the zone of origin, or nil.
+func (s *ZoneStore) Zone(origin string) *Zone {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.zones[strings.ToLower(Fqdn(origin))]
+}
+
+// Match returns the zone closest to name that name is in, or nil.
+func (s *ZoneStore) Match(name string) *Zone {
+	name = strings.ToLower(Fqdn(name))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for off := 0; ; {
+		if z := s.zones[name[off:]]; z != nil {
+			return z
+		}
+		i, end := NextLabel(name, off)
+		if end {
+			break
+		}
+		off = i
+	}
+	return s.zones["."]
+}
+
+// Transferred replaces the zone with the records rrs, that were transferred.
+// It fits TransferManager.Transferred, and so does Remove
+// TransferManager.Expired.
+func (s *ZoneStore) Transferred(zone string, rrs []RR) error {
+	z, err := NewZone(zone, rrs)
+	if err != nil {
+		return err
+	}
+	s.Set(z)
+	return nil
+}
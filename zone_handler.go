@@ -0,0 +1,359 @@
+package dns
+
+import (
+	"net"
+	"sort"
+	"strings"
+)
+
+// A ZoneHandler is a Handler that answers queries authoritatively from the
+// zones of Zones: with the records found, NXDOMAIN when the name doesn't
+// exist, or the SOA record when it has no records of the type; following
+// CNAME and DNAME records, synthesizing answers from wildcards, and referring
+// to the name servers of delegations with their glue.
+//
+// When a zone is signed, which it is when it has DNSKEY records, and the query
+// has the DO bit set, the RRSIGs of the records are added, and the NSEC or
+// NSEC3 records that prove names or types don't exist (RFC 4035, Section 3.1
+// and RFC 5155, Section 7.2). The zone must hold them, ZoneHandler doesn't
+// sign.
+//
+// Queries for names outside of the zones are refused, and so are zone
+// transfers, see IXFRHandler and TransferOut for those.
+type ZoneHandler struct {
+	Zones *ZoneStore
+}
+
+// ServeDNS implements the Handler interface.
+func (h *ZoneHandler) ServeDNS(w ResponseWriter, r *Msg) {
+	m := h.Response(r)
+	if _, ok := w.LocalAddr().(*net.UDPAddr); ok {
+		size := MinMsgSize
+		if opt := r.IsEdns0(); opt != nil {
+			size = int(opt.UDPSize())
+		}
+		m.Truncate(size)
+	}
+	w.WriteMsg(m)
+}
+
+// Response returns the response to the query r, without truncating it.
+func (h *ZoneHandler) Response(r *Msg) *Msg {
+	m := new(Msg)
+	switch {
+	case r.Opcode != OpcodeQuery:
+		m.SetRcode(r, RcodeNotImplemented)
+		return m
+	case len(r.Question) != 1:
+		m.SetRcode(r, RcodeFormatError)
+		return m
+	}
+	q := r.Question[0]
+	z := h.Zones.Match(q.Name)
+	if z == nil || q.Qtype == TypeAXFR || q.Qtype == TypeIXFR {
+		m.SetRcode(r, RcodeRefused)
+		return m
+	}
+
+	l := z.Lookup(q.Name, q.Qtype)
+	m.SetReply(r)
+	m.Authoritative = l.Result != LookupDelegation || len(l.Answer) > 0
+	if l.Result == LookupNameError {
+		m.Rcode = RcodeNameError
+	}
+	do := false
+	if opt := r.IsEdns0(); opt != nil {
+		do = opt.Do()
+	}
+	if !do || !z.Signed() {
+		m.Answer, m.Ns, m.Extra = l.Answer, l.Ns, l.Extra
+		if l.Result == LookupSuccess {
+			m.Extra = z.additional(m.Answer, false)
+		}
+	} else {
+		z.signedResponse(m, l)
+	}
+	if opt := r.IsEdns0(); opt != nil {
+		m.SetEdns0(DefaultMsgSize, do)
+	}
+	return m
+}
+
+// Signed reports whether z is signed, that is whether it has DNSKEY records.
+func (z *Zone) Signed() bool {
+	return len(z.RRset(z.origin, TypeDNSKEY)) > 0
+}
+
+// signedResponse adds the records of l, with their RRSIGs and the NSEC or
+// NSEC3 records needed, to m.
+func (z *Zone) signedResponse(m *Msg, l *ZoneLookup) {
+	// The answer, each RRset followed by its RRSIGs.
+	dname := false
+	for _, rrset := range SplitRRsets(l.Answer) {
+		m.Answer = append(m.Answer, rrset...)
+		if dname && rrset[0].Header().Rrtype == TypeCNAME {
+			dname = false // synthesized from the DNAME record, it isn't signed
+			continue
+		}
+		dname = rrset[0].Header().Rrtype == TypeDNAME
+		name := rrset[0].Header().Name
+		if z.Exists(name) {
+			m.Answer = append(m.Answer, z.Signatures(name, rrset[0].Header().Rrtype)...)
+			continue
+		}
+		// Synthesized from a wildcard, prove there is no closer match.
+		ce := z.closestEncloser(name)
+		m.Answer = append(m.Answer, z.wildcardSignatures(name, wildcardOf(ce), rrset[0].Header().Rrtype)...)
+		m.Ns = append(m.Ns, z.noCloserMatch(name, ce)...)
+	}
+
+	switch l.Result {
+	case LookupSuccess:
+		m.Extra = z.additional(m.Answer, true)
+	case LookupNoData:
+		m.Ns = append(m.Ns, l.Ns...)
+		m.Ns = append(m.Ns, z.Signatures(z.origin, TypeSOA)...)
+		if l.Wildcard != "" {
+			m.Ns = append(m.Ns, z.noCloserMatch(l.Name, l.ClosestEncloser)...)
+			m.Ns = append(m.Ns, z.denial(l.Wildcard, false)...)
+		} else {
+			m.Ns = append(m.Ns, z.denial(l.Name, false)...)
+		}
+	case LookupNameError:
+		m.Ns = append(m.Ns, l.Ns...)
+		m.Ns = append(m.Ns, z.Signatures(z.origin, TypeSOA)...)
+		m.Ns = append(m.Ns, z.noCloserMatch(l.Name, l.ClosestEncloser)...)
+		m.Ns = append(m.Ns, z.denial(wildcardOf(l.ClosestEncloser), true)...)
+	case LookupDelegation:
+		cut := l.Ns[0].Header().Name
+		m.Ns = append(m.Ns, l.Ns...)
+		if ds := z.RRset(cut, TypeDS); len(ds) > 0 {
+			m.Ns = append(m.Ns, ds...)
+			m.Ns = append(m.Ns, z.Signatures(cut, TypeDS)...)
+		} else {
+			m.Ns = append(m.Ns, z.denial(cut, false)...)
+		}
+		m.Extra = l.Extra
+	}
+	m.Ns = dedupRRs(m.Ns)
+}
+
+// additional returns the address records in z of the targets of the MX, SRV
+// and NS records in answer, with their RRSIGs if signed is true.
+func (z *Zone) additional(answer []RR, signed bool) []RR {
+	var extra []RR
+	seen := make(map[string]bool)
+	for _, rr := range answer {
+		var target string
+		switch rr := rr.(type) {
+		case *MX:
+			target = rr.Mx
+		case *SRV:
+			target = rr.Target
+		case *NS:
+			target = rr.Ns
+		default:
+			continue
+		}
+		target = strings.ToLower(target)
+		if seen[target] || !IsSubDomain(z.origin, target) {
+			continue
+		}
+		seen[target] = true
+		for _, t := range []uint16{TypeA, TypeAAAA} {
+			extra = append(extra, z.RRset(target, t)...)
+			if signed {
+				extra = append(extra, z.Signatures(target, t)...)
+			}
+		}
+	}
+	return extra
+}
+
+// closestEncloser returns the closest encloser of name in z.
+func (z *Zone) closestEncloser(name string) string {
+	name = strings.ToLower(name)
+	for !z.Exists(name) && name != z.origin {
+		i, end := NextLabel(name, 0)
+		if end {
+			return "."
+		}
+		name = name[i:]
+	}
+	return name
+}
+
+// wildcardSignatures returns the RRSIGs of wildcard covering rrtype, with name
+// as owner name.
+func (z *Zone) wildcardSignatures(name, wildcard string, rrtype uint16) []RR {
+	sigs := z.Signatures(wildcard, rrtype)
+	syn := make([]RR, len(sigs))
+	for i, sig := range sigs {
+		syn[i] = sig.copy()
+		syn[i].Header().Name = name
+	}
+	return syn
+}
+
+// wildcardOf returns the wildcard name of the closest encloser ce.
+func wildcardOf(ce string) string {
+	if ce == "." {
+		return "*."
+	}
+	return "*." + ce
+}
+
+// nextCloser returns the name one label longer than ce that name is below.
+func nextCloser(name, ce string) string {
+	labels := Split(name)
+	return name[labels[len(labels)-CountLabel(ce)-1]:]
+}
+
+// noCloserMatch returns the NSEC or NSEC3 records that prove there is no
+// closer match for name than its closest encloser ce.
+func (z *Zone) noCloserMatch(name, ce string) []RR {
+	if z.nsec3Param() == nil {
+		return z.denial(name, true)
+	}
+	rrs := z.nsec3Proof(ce, false)
+	return append(rrs, z.nsec3Proof(nextCloser(name, ce), true)...)
+}
+
+// denial returns the NSEC or NSEC3 record, with its RRSIGs, that matches
+// name, or that covers it when cover is true or there is none that matches.
+// For an NSEC3 record that matches no name, the closest provable encloser of
+// name is proven instead (RFC 5155, Section 7.2.4).
+func (z *Zone) denial(name string, cover bool) []RR {
+	if z.nsec3Param() == nil {
+		nsec := z.nsecFor(name)
+		if nsec == nil {
+			return nil
+		}
+		return append([]RR{nsec}, z.Signatures(nsec.Hdr.Name, TypeNSEC)...)
+	}
+	if !cover {
+		if rrs := z.nsec3Proof(name, false); rrs != nil {
+			return rrs
+		}
+		// Opt-out, or an empty non-terminal without an NSEC3 record: prove
+		// the closest provable encloser.
+		ce := name
+		for ce != z.origin {
+			i, end := NextLabel(ce, 0)
+			if end {
+				ce = "."
+			} else {
+				ce = ce[i:]
+			}
+			if rrs := z.nsec3Proof(ce, false); rrs != nil {
+				return append(rrs, z.nsec3Proof(nextCloser(name, ce), true)...)
+			}
+		}
+		return nil
+	}
+	return z.nsec3Proof(name, true)
+}
+
+// nsecFor returns the NSEC record of z that matches name, or the one that
+// covers it, or nil.
+func (z *Zone) nsecFor(name string) *NSEC {
+	d := z.denialIndex()
+	i := sort.Search(len(d.nsec), func(i int) bool {
+		return canonicalNameCompare(d.nsec[i].Hdr.Name, name) > 0
+	})
+	// d.nsec[i-1] is the last NSEC record at or before name.
+	if i == 0 {
+		return nil
+	}
+	return d.nsec[i-1]
+}
+
+// nsec3Proof returns the NSEC3 record, with its RRSIGs, that matches name, or
+// the one that covers it when cover is true. It returns nil if there is none.
+func (z *Zone) nsec3Proof(name string, cover bool) []RR {
+	d := z.denialIndex()
+	if len(d.nsec3) == 0 {
+		return nil
+	}
+	p := d.param
+	hash := HashName(name, p.Hash, p.Iterations, p.Salt)
+	i := sort.Search(len(d.nsec3), func(i int) bool { return d.hashes[i] > hash })
+	if !cover {
+		if i > 0 && d.hashes[i-1] == hash {
+			return append([]RR{d.nsec3[i-1]}, z.Signatures(d.nsec3[i-1].Hdr.Name, TypeNSEC3)...)
+		}
+		return nil
+	}
+	if i == 0 {
+		i = len(d.nsec3)
+	}
+	return append([]RR{d.nsec3[i-1]}, z.Signatures(d.nsec3[i-1].Hdr.Name, TypeNSEC3)...)
+}
+
+// nsec3Param returns the NSEC3 parameters of z, or nil if it isn't signed
+// with NSEC3.
+func (z *Zone) nsec3Param() *NSEC3 {
+	return z.denialIndex().param
+}
+
+// zoneDenial indexes the NSEC and NSEC3 records of a zone.
+type zoneDenial struct {
+	nsec   []*NSEC  // in canonical order
+	nsec3  []*NSEC3 // of the hash parameters of param, by hash
+	hashes []string // the hashes of nsec3
+	param  *NSEC3   // first NSEC3 record of the zone, for its hash parameters
+}
+
+// denialIndex returns the index of the NSEC and NSEC3 records of z.
+func (z *Zone) denialIndex() *zoneDenial {
+	z.indexOnce.Do(func() {
+		d := new(zoneDenial)
+		for _, rr := range z.rrs {
+			switch rr := rr.(type) {
+			case *NSEC:
+				d.nsec = append(d.nsec, rr)
+			case *NSEC3:
+				if d.param == nil {
+					d.param = rr
+				}
+				if rr.Hash == d.param.Hash && rr.Iterations == d.param.Iterations && rr.Salt == d.param.Salt {
+					d.nsec3 = append(d.nsec3, rr)
+				}
+			}
+		}
+		sort.Slice(d.nsec, func(i, j int) bool {
+			return canonicalNameCompare(d.nsec[i].Hdr.Name, d.nsec[j].Hdr.Name) < 0
+		})
+		for _, rr := range d.nsec3 {
+			i, _ := NextLabel(rr.Hdr.Name, 0)
+			d.hashes = append(d.hashes, strings.ToUpper(rr.Hdr.Name[:i-1]))
+		}
+		sort.Sort(nsec3Sorter{d})
+		z.index = d
+	})
+	return z.index
+}
+
+type nsec3Sorter struct{ d *zoneDenial }
+
+func (s nsec3Sorter) Len() int           { return len(s.d.nsec3) }
+func (s nsec3Sorter) Less(i, j int) bool { return s.d.hashes[i] < s.d.hashes[j] }
+func (s nsec3Sorter) Swap(i, j int) {
+	s.d.nsec3[i], s.d.nsec3[j] = s.d.nsec3[j], s.d.nsec3[i]
+	s.d.hashes[i], s.d.hashes[j] = s.d.hashes[j], s.d.hashes[i]
+}
+
+// dedupRRs returns rrs without the records that are in it more than once.
+func dedupRRs(rrs []RR) []RR {
+	seen := make(map[RR]bool, len(rrs))
+	j := 0
+	for _, rr := range rrs {
+		if seen[rr] {
+			continue
+		}
+		seen[rr] = true
+		rrs[j] = rr
+		j++
+	}
+	return rrs[:j]
+}
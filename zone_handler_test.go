@@ -0,0 +1,277 @@
+package dns
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testHandlerZone = `$ORIGIN example.
+@        3600 IN SOA   ns.example. hostmaster.example. 1 3600 600 86400 300
+@        3600 IN NS    ns.example.
+@        3600 IN MX    10 mail.example.
+ns       3600 IN A     192.0.2.1
+mail     3600 IN A     192.0.2.2
+www      3600 IN A     192.0.2.3
+*.wild   3600 IN TXT   "wildcard"
+a.b.c    3600 IN A     192.0.2.4
+sub      3600 IN NS    ns.sub
+sub      3600 IN DS    60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118
+ns.sub   3600 IN A     192.0.2.5
+insecure 3600 IN NS    ns.sub
+`
+
+// newTestZoneStore returns a store with testHandlerZone, signed with NSEC or
+// NSEC3 records if signed is true.
+func newTestZoneStore(t *testing.T, signed, nsec3 bool) (*ZoneStore, *DNSKEY) {
+	var rrs []RR
+	zp := NewZoneParser(strings.NewReader(testHandlerZone), "", "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var key *DNSKEY
+	if signed {
+		r := newResigner(t)
+		key = r.Key
+		rrs = signTestZone(t, r, append(rrs, key), nsec3)
+	}
+	z, err := NewZone("example.", rrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := new(ZoneStore)
+	s.Set(z)
+	return s, key
+}
+
+// signTestZone adds the NSEC or NSEC3 chain of the zone example. with the
+// records rrs, and signs it with r.
+func signTestZone(t *testing.T, r *Resigner, rrs []RR, nsec3 bool) []RR {
+	types := make(map[string][]uint16)
+	for _, rr := range rrs {
+		name := rr.Header().Name
+		if strings.HasSuffix(name, ".sub.example.") {
+			continue // glue
+		}
+		if !typeIn(types[name], rr.Header().Rrtype) {
+			types[name] = append(types[name], rr.Header().Rrtype)
+		}
+		if nsec3 {
+			for n := name; n != "example."; {
+				i, _ := NextLabel(n, 0)
+				n = n[i:]
+				if _, ok := types[n]; !ok {
+					types[n] = nil // empty non-terminal
+				}
+			}
+		}
+	}
+	var names []string
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return canonicalNameCompare(names[i], names[j]) < 0 })
+
+	var chain []RR
+	if !nsec3 {
+		for i, name := range names {
+			next := names[(i+1)%len(names)]
+			chain = append(chain, &NSEC{
+				Hdr:        RR_Header{Name: name, Rrtype: TypeNSEC, Class: ClassINET, Ttl: 300},
+				NextDomain: next,
+				TypeBitMap: sortTypes(append(types[name], TypeNSEC, TypeRRSIG)),
+			})
+		}
+	} else {
+		var hashes []string
+		hashed := make(map[string]string)
+		for _, name := range names {
+			h := HashName(name, SHA1, 0, "")
+			hashes = append(hashes, h)
+			hashed[h] = name
+		}
+		sort.Strings(hashes)
+		for i, h := range hashes {
+			bitmap := types[hashed[h]]
+			if len(bitmap) > 0 {
+				bitmap = append(bitmap, TypeRRSIG)
+			}
+			chain = append(chain, &NSEC3{
+				Hdr:        RR_Header{Name: strings.ToLower(h) + ".example.", Rrtype: TypeNSEC3, Class: ClassINET, Ttl: 300},
+				Hash:       SHA1,
+				HashLength: 20,
+				NextDomain: hashes[(i+1)%len(hashes)],
+				TypeBitMap: sortTypes(bitmap),
+			})
+		}
+	}
+	rrs = append(rrs, chain...)
+
+	signed := rrs
+	for _, rrset := range SplitRRsets(rrs) {
+		h := rrset[0].Header()
+		if strings.HasSuffix(h.Name, ".sub.example.") || h.Rrtype == TypeNS && h.Name != "example." {
+			continue // glue and delegations aren't signed
+		}
+		sig, err := r.sign(rrset, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		signed = append(signed, sig)
+	}
+	return signed
+}
+
+func testZoneQuery(name string, qtype uint16, do bool) *Msg {
+	m := new(Msg)
+	m.SetQuestion(name, qtype)
+	if do {
+		m.SetEdns0(4096, true)
+	}
+	return m
+}
+
+func TestZoneHandler(t *testing.T) {
+	s, _ := newTestZoneStore(t, false, false)
+	h := &ZoneHandler{Zones: s}
+
+	m := h.Response(testZoneQuery("www.example.", TypeA, false))
+	if !m.Authoritative || m.Rcode != RcodeSuccess || len(m.Answer) != 1 {
+		t.Errorf("expected an authoritative answer, got %v", m)
+	}
+	m = h.Response(testZoneQuery("example.", TypeMX, false))
+	if len(m.Extra) != 1 || m.Extra[0].Header().Name != "mail.example." {
+		t.Errorf("expected the address of mail.example. in the additional section, got %v", m.Extra)
+	}
+	m = h.Response(testZoneQuery("nope.example.", TypeA, false))
+	if m.Rcode != RcodeNameError || len(m.Ns) != 1 || m.Ns[0].Header().Rrtype != TypeSOA {
+		t.Errorf("expected NXDOMAIN with the SOA record, got %v", m)
+	}
+	m = h.Response(testZoneQuery("www.example.", TypeAAAA, false))
+	if m.Rcode != RcodeSuccess || len(m.Answer) != 0 || len(m.Ns) != 1 {
+		t.Errorf("expected NODATA with the SOA record, got %v", m)
+	}
+	m = h.Response(testZoneQuery("x.sub.example.", TypeA, false))
+	if m.Authoritative || len(m.Ns) != 1 || len(m.Extra) != 1 {
+		t.Errorf("expected a referral with glue, got %v", m)
+	}
+	for _, q := range []*Msg{testZoneQuery("example.", TypeAXFR, false), testZoneQuery("example.org.", TypeA, false)} {
+		if m := h.Response(q); m.Rcode != RcodeRefused {
+			t.Errorf("expected %s to be refused, got %v", q.Question[0].String(), m)
+		}
+	}
+}
+
+func TestZoneHandlerNSEC(t *testing.T) {
+	s, key := newTestZoneStore(t, true, false)
+	h := &ZoneHandler{Zones: s}
+
+	if m := h.Response(testZoneQuery("www.example.", TypeA, false)); len(m.Answer) != 1 {
+		t.Errorf("expected no RRSIGs without DO, got %v", m.Answer)
+	}
+	m := h.Response(testZoneQuery("www.example.", TypeA, true))
+	if len(m.Answer) != 2 || !m.IsEdns0().Do() {
+		t.Fatalf("expected an A record with its RRSIG, got %v", m)
+	}
+	if err := m.Answer[1].(*RRSIG).Verify(key, m.Answer[:1]); err != nil {
+		t.Errorf("failed to verify the answer: %v", err)
+	}
+
+	m = h.Response(testZoneQuery("x.wild.example.", TypeTXT, true))
+	if len(m.Answer) != 2 || m.Answer[1].Header().Name != "x.wild.example." {
+		t.Fatalf("expected a synthesized answer with its RRSIG, got %v", m.Answer)
+	}
+	if err := m.Answer[1].(*RRSIG).Verify(key, m.Answer[:1]); err != nil {
+		t.Errorf("failed to verify the wildcard answer: %v", err)
+	}
+	if !coveredByNSEC(m.Ns, "x.wild.example.") {
+		t.Errorf("expected an NSEC record covering x.wild.example., got %v", m.Ns)
+	}
+
+	m = h.Response(testZoneQuery("nope.example.", TypeA, true))
+	if m.Rcode != RcodeNameError || !coveredByNSEC(m.Ns, "nope.example.") || !coveredByNSEC(m.Ns, "*.example.") {
+		t.Errorf("expected NSEC records covering nope.example. and *.example., got %v", m.Ns)
+	}
+
+	m = h.Response(testZoneQuery("b.c.example.", TypeA, true))
+	if m.Rcode != RcodeSuccess || !coveredByNSEC(m.Ns, "b.c.example.") {
+		t.Errorf("expected an NSEC record covering the empty non-terminal, got %v", m.Ns)
+	}
+
+	m = h.Response(testZoneQuery("www.sub.example.", TypeA, true))
+	if len(m.Ns) != 3 || m.Ns[1].Header().Rrtype != TypeDS || m.Ns[2].Header().Rrtype != TypeRRSIG {
+		t.Errorf("expected a referral with the signed DS record, got %v", m.Ns)
+	}
+	m = h.Response(testZoneQuery("www.insecure.example.", TypeA, true))
+	if len(m.Ns) != 3 || m.Ns[1].Header().Rrtype != TypeNSEC || m.Ns[1].Header().Name != "insecure.example." {
+		t.Errorf("expected a referral with the NSEC record of insecure.example., got %v", m.Ns)
+	}
+}
+
+func TestZoneHandlerNSEC3(t *testing.T) {
+	s, _ := newTestZoneStore(t, true, true)
+	h := &ZoneHandler{Zones: s}
+
+	m := h.Response(testZoneQuery("nope.example.", TypeA, true))
+	if m.Rcode != RcodeNameError {
+		t.Fatalf("expected NXDOMAIN, got %v", m)
+	}
+	var match, cover, wildcard bool
+	for _, rr := range m.Ns {
+		if n, ok := rr.(*NSEC3); ok {
+			match = match || n.Match("example.")
+			cover = cover || n.Cover("nope.example.")
+			wildcard = wildcard || n.Cover("*.example.")
+		}
+	}
+	if !match || !cover || !wildcard {
+		t.Errorf("expected the closest encloser proof and the wildcard denied, got %v", m.Ns)
+	}
+
+	for _, name := range []string{"www.example.", "b.c.example."} {
+		m = h.Response(testZoneQuery(name, TypeAAAA, true))
+		if !matchedByNSEC3(m.Ns, name) {
+			t.Errorf("expected an NSEC3 record matching %s, got %v", name, m.Ns)
+		}
+	}
+	m = h.Response(testZoneQuery("www.insecure.example.", TypeA, true))
+	if !matchedByNSEC3(m.Ns, "insecure.example.") {
+		t.Errorf("expected an NSEC3 record matching insecure.example., got %v", m.Ns)
+	}
+}
+
+func coveredByNSEC(rrs []RR, name string) bool {
+	for _, rr := range rrs {
+		if n, ok := rr.(*NSEC); ok && (n.Cover(name) || n.Match(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchedByNSEC3(rrs []RR, name string) bool {
+	for _, rr := range rrs {
+		if n, ok := rr.(*NSEC3); ok && n.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func typeIn(types []uint16, t uint16) bool {
+	for _, t1 := range types {
+		if t1 == t {
+			return true
+		}
+	}
+	return false
+}
+
+func sortTypes(types []uint16) []uint16 {
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
@@ -0,0 +1,148 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+)
+
+const testZone = `$ORIGIN example.
+@        3600 IN SOA   ns.example. hostmaster.example. 1 3600 600 86400 300
+@        3600 IN NS    ns.example.
+ns       3600 IN A     192.0.2.1
+www      3600 IN A     192.0.2.2
+alias    3600 IN CNAME www
+outside  3600 IN CNAME www.example.org.
+*.wild   3600 IN TXT   "wildcard"
+a.b.c    3600 IN A     192.0.2.3
+sub      3600 IN NS    ns.sub
+sub      3600 IN DS    60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118
+ns.sub   3600 IN A     192.0.2.4
+dn       3600 IN DNAME example.
+`
+
+func newTestZone(t *testing.T) *Zone {
+	var rrs []RR
+	zp := NewZoneParser(strings.NewReader(testZone), "", "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatal(err)
+	}
+	z, err := NewZone("Example.", rrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+func TestZoneLookup(t *testing.T) {
+	z := newTestZone(t)
+	for _, tc := range []struct {
+		name   string
+		qtype  uint16
+		result LookupResult
+		answer int
+		ns     int
+		extra  int
+	}{
+		{"WWW.example.", TypeA, LookupSuccess, 1, 0, 0},
+		{"www.example.", TypeAAAA, LookupNoData, 0, 1, 0},
+		{"nope.example.", TypeA, LookupNameError, 0, 1, 0},
+		{"alias.example.", TypeA, LookupSuccess, 2, 0, 0},
+		{"alias.example.", TypeCNAME, LookupSuccess, 1, 0, 0},
+		{"outside.example.", TypeA, LookupSuccess, 1, 0, 0},
+		{"x.wild.example.", TypeTXT, LookupSuccess, 1, 0, 0},
+		{"x.wild.example.", TypeA, LookupNoData, 0, 1, 0},
+		{"b.c.example.", TypeA, LookupNoData, 0, 1, 0}, // empty non-terminal
+		{"x.b.c.example.", TypeA, LookupNameError, 0, 1, 0},
+		{"sub.example.", TypeA, LookupDelegation, 0, 1, 1},
+		{"x.sub.example.", TypeA, LookupDelegation, 0, 1, 1},
+		{"sub.example.", TypeDS, LookupSuccess, 1, 0, 0},
+		{"www.dn.example.", TypeA, LookupSuccess, 3, 0, 0},
+		{"example.org.", TypeA, LookupNotInZone, 0, 0, 0},
+	} {
+		l := z.Lookup(tc.name, tc.qtype)
+		if l.Result != tc.result || len(l.Answer) != tc.answer || len(l.Ns) != tc.ns || len(l.Extra) != tc.extra {
+			t.Errorf("%s %s: expected %d with %d/%d/%d records, got %d with %v/%v/%v", tc.name, Type(tc.qtype),
+				tc.result, tc.answer, tc.ns, tc.extra, l.Result, l.Answer, l.Ns, l.Extra)
+		}
+	}
+
+	l := z.Lookup("x.wild.example.", TypeTXT)
+	if l.Wildcard != "*.wild.example." || l.ClosestEncloser != "wild.example." || l.Answer[0].Header().Name != "x.wild.example." {
+		t.Errorf("expected an answer synthesized from *.wild.example., got %v", l)
+	}
+	if z.RRset("*.wild.example.", TypeTXT)[0].Header().Name != "*.wild.example." {
+		t.Error("expected the wildcard record unchanged")
+	}
+	if l := z.Lookup("nope.example.", TypeA); l.Ns[0].Header().Ttl != 300 {
+		t.Errorf("expected the negative TTL of the SOA, got %v", l.Ns[0])
+	}
+	if l := z.Lookup("www.dn.example.", TypeA); l.Answer[1].(*CNAME).Target != "www.example." || l.Name != "www.example." {
+		t.Errorf("expected a CNAME synthesized from the DNAME, got %v", l.Answer)
+	}
+}
+
+func TestZoneLookupApexDNAME(t *testing.T) {
+	z, err := NewZone("example.org.", []RR{
+		testRR("example.org. 3600 IN SOA ns.example.net. hostmaster.example.net. 1 3600 600 86400 300"),
+		testRR("example.org. 3600 IN NS ns.example.net."),
+		testRR("example.org. 3600 IN DNAME example.net."),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := z.Lookup("www.example.org.", TypeA)
+	if l.Result != LookupSuccess || len(l.Answer) != 2 || l.Name != "www.example.net." {
+		t.Fatalf("expected a redirection by the DNAME of the apex, got %d with %v", l.Result, l.Answer)
+	}
+	if cname, ok := l.Answer[1].(*CNAME); !ok || cname.Target != "www.example.net." {
+		t.Errorf("expected a CNAME synthesized from the DNAME, got %v", l.Answer[1])
+	}
+	// The DNAME doesn't redirect its owner, nor its NS records.
+	if l := z.Lookup("example.org.", TypeNS); l.Result != LookupSuccess || len(l.Answer) != 1 {
+		t.Errorf("expected the NS records of the apex, got %d with %v", l.Result, l.Answer)
+	}
+	if l := z.Lookup("example.org.", TypeDNAME); l.Result != LookupSuccess || len(l.Answer) != 1 {
+		t.Errorf("expected the DNAME record of the apex, got %d with %v", l.Result, l.Answer)
+	}
+}
+
+func TestNewZone(t *testing.T) {
+	if _, err := NewZone("example.", []RR{testRR("www.example. 3600 IN A 192.0.2.1")}); err != ErrSoa {
+		t.Errorf("expected %v, got %v", ErrSoa, err)
+	}
+	rrs := []RR{
+		testRR("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 300"),
+		testRR("www.example.org. 3600 IN A 192.0.2.1"),
+	}
+	if _, err := NewZone("example.", rrs); err == nil {
+		t.Error("expected an error for a record outside of the zone")
+	}
+}
+
+func TestZoneStore(t *testing.T) {
+	s := new(ZoneStore)
+	s.Set(newTestZone(t))
+	if z := s.Match("a.b.c.Example."); z == nil || z.Origin() != "example." {
+		t.Errorf("expected example., got %v", z)
+	}
+	if z := s.Match("example.org."); z != nil {
+		t.Errorf("expected no zone, got %s", z.Origin())
+	}
+
+	err := s.Transferred("example.", []RR{
+		testRR("example. 3600 IN SOA ns.example. hostmaster.example. 2 3600 600 86400 300"),
+	})
+	if err != nil || s.Zone("example.").SOA().Serial != 2 {
+		t.Errorf("expected the zone replaced, got %v", err)
+	}
+	if err := s.Transferred("example.", nil); err == nil || s.Zone("example.") == nil {
+		t.Errorf("expected the zone kept, got %v", err)
+	}
+	s.Remove("example.")
+	if s.Match("example.") != nil {
+		t.Error("expected the zone removed")
+	}
+}
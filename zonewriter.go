@@ -0,0 +1,229 @@
+package dns
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ZoneWriter writes records as an RFC 1035 style zone file, the reverse of
+// ZoneParser. The zone file starts with an $ORIGIN directive, and a $TTL
+// directive if a default TTL is set. Owner names, and the names in the rdata
+// of the common record types, are written relative to the origin, an owner
+// name the same as the previous one is left out, and the columns are
+// aligned. DNSKEY, RRSIG and SOA records are written over several lines, with
+// comments, unless that is disabled.
+//
+// Basic usage pattern:
+//
+//	zw := NewZoneWriter(w, "example.org.")
+//	for _, rr := range rrs {
+//		if err := zw.Write(rr); err != nil {
+//			// handle err
+//		}
+//	}
+//	if err := zw.Flush(); err != nil {
+//		// handle err
+//	}
+type ZoneWriter struct {
+	w   *bufio.Writer
+	err error
+
+	origin    string
+	ttl       uint32
+	ttlSet    bool
+	multiLine bool
+	nameWidth int
+
+	started bool
+	owner   string // previous owner name
+}
+
+// NewZoneWriter returns a ZoneWriter that writes to w. Names are written
+// relative to origin, unless it is empty or the root.
+func NewZoneWriter(w io.Writer, origin string) *ZoneWriter {
+	if origin != "" {
+		origin = Fqdn(origin)
+	}
+	return &ZoneWriter{
+		w:         bufio.NewWriter(w),
+		origin:    origin,
+		multiLine: true,
+		nameWidth: 24,
+	}
+}
+
+// SetDefaultTTL writes a $TTL directive with ttl, and leaves the TTL of
+// records that have it out.
+func (zw *ZoneWriter) SetDefaultTTL(ttl uint32) {
+	zw.ttl, zw.ttlSet = ttl, true
+}
+
+// SetMultiLine controls whether DNSKEY, RRSIG and SOA records are written over
+// several lines. They are by default.
+func (zw *ZoneWriter) SetMultiLine(v bool) {
+	zw.multiLine = v
+}
+
+// Write writes rr. Errors are sticky, once Write or Flush failed Write returns
+// the same error.
+func (zw *ZoneWriter) Write(rr RR) error {
+	if zw.err != nil {
+		return zw.err
+	}
+	if !zw.started {
+		zw.started = true
+		if zw.origin != "" {
+			zw.w.WriteString("$ORIGIN " + zw.origin + "\n")
+		}
+		if zw.ttlSet {
+			zw.w.WriteString("$TTL " + strconv.FormatInt(int64(zw.ttl), 10) + "\n")
+		}
+	}
+
+	h := rr.Header()
+	owner := ""
+	if !strings.EqualFold(h.Name, zw.owner) {
+		owner = zw.relative(h.Name)
+		zw.owner = h.Name
+	}
+	ttl := ""
+	if !zw.ttlSet || h.Ttl != zw.ttl {
+		ttl = strconv.FormatInt(int64(h.Ttl), 10)
+	}
+	hdr := pad(owner, zw.nameWidth) + " " + pad(ttl, 7) + " " + pad(Class(h.Class).String(), 3) + " " + pad(Type(h.Rrtype).String(), 7) + " "
+
+	zw.w.WriteString(hdr)
+	zw.w.WriteString(zw.rdata(rr, strings.Repeat(" ", len(hdr))))
+	_, zw.err = zw.w.WriteString("\n")
+	return zw.err
+}
+
+// WriteAll writes rrs and flushes the ZoneWriter.
+func (zw *ZoneWriter) WriteAll(rrs []RR) error {
+	for _, rr := range rrs {
+		if err := zw.Write(rr); err != nil {
+			return err
+		}
+	}
+	return zw.Flush()
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (zw *ZoneWriter) Flush() error {
+	if zw.err != nil {
+		return zw.err
+	}
+	zw.err = zw.w.Flush()
+	return zw.err
+}
+
+// relative returns name relative to the origin, if it is below it.
+func (zw *ZoneWriter) relative(name string) string {
+	if zw.origin == "" || zw.origin == "." || !IsSubDomain(zw.origin, name) {
+		return name
+	}
+	if len(name) == len(zw.origin) {
+		return "@"
+	}
+	return name[:len(name)-len(zw.origin)-1]
+}
+
+// rdata returns the rdata of rr, the lines after the first one are indented
+// with indent.
+func (zw *ZoneWriter) rdata(rr RR, indent string) string {
+	switch r := rr.(type) {
+	case *NS:
+		return zw.relative(r.Ns)
+	case *CNAME:
+		return zw.relative(r.Target)
+	case *DNAME:
+		return zw.relative(r.Target)
+	case *PTR:
+		return zw.relative(r.Ptr)
+	case *MX:
+		return strconv.Itoa(int(r.Preference)) + " " + zw.relative(r.Mx)
+	case *SRV:
+		return strconv.Itoa(int(r.Priority)) + " " + strconv.Itoa(int(r.Weight)) + " " +
+			strconv.Itoa(int(r.Port)) + " " + zw.relative(r.Target)
+	case *SOA:
+		names := zw.relative(r.Ns) + " " + zw.relative(r.Mbox)
+		if !zw.multiLine {
+			return names + strings.TrimPrefix(r.String(), r.Hdr.String()+sprintName(r.Ns)+" "+sprintName(r.Mbox))
+		}
+		fields := []struct {
+			v       uint32
+			comment string
+		}{
+			{r.Serial, "serial"},
+			{r.Refresh, "refresh"},
+			{r.Retry, "retry"},
+			{r.Expire, "expire"},
+			{r.Minttl, "minimum"},
+		}
+		s := names + " ("
+		for i, f := range fields {
+			v := strconv.FormatInt(int64(f.v), 10)
+			if i == len(fields)-1 {
+				v += " )"
+			}
+			s += "\n" + indent + "    " + pad(v, 12) + " ; " + f.comment
+		}
+		return s
+	case *DNSKEY:
+		if zw.multiLine {
+			return multiLineKey(r, indent)
+		}
+	case *CDNSKEY:
+		if zw.multiLine {
+			return multiLineKey(&r.DNSKEY, indent)
+		}
+	case *RRSIG:
+		if !zw.multiLine {
+			break
+		}
+		return Type(r.TypeCovered).String() + " " + strconv.Itoa(int(r.Algorithm)) + " " +
+			strconv.Itoa(int(r.Labels)) + " " + strconv.FormatInt(int64(r.OrigTtl), 10) + " (\n" +
+			indent + "    " + TimeToString(r.Expiration) + " " + TimeToString(r.Inception) + " " +
+			strconv.Itoa(int(r.KeyTag)) + " " + zw.relative(r.SignerName) +
+			base64Lines(r.Signature, indent+"    ") + " )"
+	}
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+func multiLineKey(k *DNSKEY, indent string) string {
+	kind := "ZSK"
+	if k.Flags&SEP != 0 {
+		kind = "KSK"
+	}
+	alg := AlgorithmToString[k.Algorithm]
+	if alg == "" {
+		alg = strconv.Itoa(int(k.Algorithm))
+	}
+	return strconv.Itoa(int(k.Flags)) + " " + strconv.Itoa(int(k.Protocol)) + " " + strconv.Itoa(int(k.Algorithm)) + " (" +
+		base64Lines(k.PublicKey, indent+"    ") + " ) ; " + kind + "; alg = " + alg + " ; key id = " + strconv.Itoa(int(k.KeyTag()))
+}
+
+// base64Lines splits s in lines of 56 characters, each starting with a
+// newline and indent.
+func base64Lines(s, indent string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		n := 56
+		if n > len(s) {
+			n = len(s)
+		}
+		b.WriteString("\n" + indent + s[:n])
+		s = s[n:]
+	}
+	return b.String()
+}
+
+// pad pads s with spaces to width.
+func pad(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
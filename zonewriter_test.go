@@ -0,0 +1,67 @@
+package dns
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestZoneWriter(t *testing.T) {
+	const zone = `example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 2019010101 7200 3600 1209600 300
+example.org. 3600 IN NS ns.example.org.
+example.org. 3600 IN NS ns.example.net.
+example.org. 3600 IN MX 10 mail.example.org.
+example.org. 3600 IN DNSKEY 257 3 8 AwEAAaetidLzsKWUt4swWR8yu0wPHPiUi8LU sAD0QPWU+wzt89epO6tHzkMBVDkC7qphQO2hTY4hHn9npWFRw5BYubE=
+example.org. 3600 IN RRSIG SOA 8 2 3600 20190201000000 20190101000000 12345 example.org. c2lnbmF0dXJlc2lnbmF0dXJlc2lnbmF0dXJlc2lnbmF0dXJlc2lnbmF0dXJlc2lnbmF0dXJlc2lnbmF0dXJl
+www.example.org. 300 IN A 192.0.2.1
+www.example.org. 3600 IN TXT "hello world"
+alias.example.org. 3600 IN CNAME www.example.org.
+_sip._tcp.example.org. 3600 IN SRV 0 5 5060 sip.example.net.
+`
+	var rrs []RR
+	for tok := range ParseZone(strings.NewReader(zone), "", "") {
+		if tok.Error != nil {
+			t.Fatal(tok.Error)
+		}
+		rrs = append(rrs, tok.RR)
+	}
+
+	for _, multiLine := range []bool{true, false} {
+		buf := new(bytes.Buffer)
+		zw := NewZoneWriter(buf, "example.org.")
+		zw.SetDefaultTTL(3600)
+		zw.SetMultiLine(multiLine)
+		if err := zw.WriteAll(rrs); err != nil {
+			t.Fatalf("failed to write the zone: %v", err)
+		}
+		out := buf.String()
+
+		if !strings.HasPrefix(out, "$ORIGIN example.org.\n$TTL 3600\n@ ") {
+			t.Errorf("expected the directives and a relative apex, got:\n%s", out)
+		}
+		if !strings.Contains(out, "\nwww                      300     IN  A       192.0.2.1\n") {
+			t.Errorf("expected an aligned relative A record, got:\n%s", out)
+		}
+		if !strings.Contains(out, "\n                                 IN  TXT     \"hello world\"\n") {
+			t.Errorf("expected the repeated owner and the default TTL to be left out, got:\n%s", out)
+		}
+		if multiLine && !strings.Contains(out, "; KSK; alg = RSASHA256 ; key id = ") {
+			t.Errorf("expected a multi-line DNSKEY with comments, got:\n%s", out)
+		}
+		if !multiLine && strings.Count(out, "\n") != len(rrs)+2 {
+			t.Errorf("expected one line per record, got:\n%s", out)
+		}
+
+		zp := NewZoneParser(strings.NewReader(out), "", "")
+		i := 0
+		for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+			if i >= len(rrs) || rr.String() != rrs[i].String() {
+				t.Errorf("record %d doesn't round trip: got %v", i, rr)
+			}
+			i++
+		}
+		if err := zp.Err(); err != nil || i != len(rrs) {
+			t.Errorf("failed to parse the written zone, %d records: %v\n%s", i, err, out)
+		}
+	}
+}
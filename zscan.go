@@ -0,0 +1,783 @@
+// Code generated by "go run scan_generate.go"; DO NOT EDIT.
+
+package dns
+
+import (
+	"net"
+	"strconv"
+)
+
+func setA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(A)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	rr.A = net.ParseIP(l.token)
+	if rr.A == nil || l.err {
+		return nil, &ParseError{f, "bad A A", l}, ""
+	}
+	return rr, nil, ""
+}
+
+func setAAAA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(AAAA)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	rr.AAAA = net.ParseIP(l.token)
+	if rr.AAAA == nil || l.err {
+		return nil, &ParseError{f, "bad AAAA AAAA", l}, ""
+	}
+	return rr, nil, ""
+}
+
+func setAFSDB(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(AFSDB)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad AFSDB Subtype", l}, ""
+	}
+	rr.Subtype = uint16(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad AFSDB Hostname", l}, ""
+	}
+	rr.Hostname = name
+	return rr, nil, ""
+}
+
+func setAVC(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(AVC)
+	rr.Hdr = h
+
+	s, e1, c1 := endingToTxtSlice(c, "bad AVC Txt", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.Txt = s
+	return rr, nil, c1
+}
+
+func setCNAME(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(CNAME)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad CNAME Target", l}, ""
+	}
+	rr.Target = name
+	return rr, nil, ""
+}
+
+func setDHCID(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(DHCID)
+	rr.Hdr = h
+
+	s, e1, c1 := endingToString(c, "bad DHCID Digest", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.Digest = s
+	return rr, nil, c1
+}
+
+func setDNAME(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(DNAME)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad DNAME Target", l}, ""
+	}
+	rr.Target = name
+	return rr, nil, ""
+}
+
+func setEID(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(EID)
+	rr.Hdr = h
+
+	s, e1, c1 := endingToString(c, "bad EID Endpoint", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.Endpoint = s
+	return rr, nil, c1
+}
+
+func setGID(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(GID)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 32)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad GID Gid", l}, ""
+	}
+	rr.Gid = uint32(i)
+	return rr, nil, ""
+}
+
+func setKX(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(KX)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad KX Pref", l}, ""
+	}
+	rr.Preference = uint16(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad KX Exchanger", l}, ""
+	}
+	rr.Exchanger = name
+	return rr, nil, ""
+}
+
+func setL32(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(L32)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad L32 Preference", l}, ""
+	}
+	rr.Preference = uint16(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	rr.Locator32 = net.ParseIP(l.token)
+	if rr.Locator32 == nil || l.err {
+		return nil, &ParseError{f, "bad L32 Locator", l}, ""
+	}
+	return rr, nil, ""
+}
+
+func setLP(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(LP)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad LP Preference", l}, ""
+	}
+	rr.Preference = uint16(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad LP Fqdn", l}, ""
+	}
+	rr.Fqdn = name
+	return rr, nil, ""
+}
+
+func setMB(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(MB)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad MB Mb", l}, ""
+	}
+	rr.Mb = name
+	return rr, nil, ""
+}
+
+func setMD(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(MD)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad MD Md", l}, ""
+	}
+	rr.Md = name
+	return rr, nil, ""
+}
+
+func setMF(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(MF)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad MF Mf", l}, ""
+	}
+	rr.Mf = name
+	return rr, nil, ""
+}
+
+func setMG(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(MG)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad MG Mg", l}, ""
+	}
+	rr.Mg = name
+	return rr, nil, ""
+}
+
+func setMINFO(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(MINFO)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad MINFO Rmail", l}, ""
+	}
+	rr.Rmail = name
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk = toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad MINFO Email", l}, ""
+	}
+	rr.Email = name
+	return rr, nil, ""
+}
+
+func setMR(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(MR)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad MR Mr", l}, ""
+	}
+	rr.Mr = name
+	return rr, nil, ""
+}
+
+func setMX(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(MX)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad MX Pref", l}, ""
+	}
+	rr.Preference = uint16(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad MX Mx", l}, ""
+	}
+	rr.Mx = name
+	return rr, nil, ""
+}
+
+func setNIMLOC(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(NIMLOC)
+	rr.Hdr = h
+
+	s, e1, c1 := endingToString(c, "bad NIMLOC Locator", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.Locator = s
+	return rr, nil, c1
+}
+
+func setNINFO(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(NINFO)
+	rr.Hdr = h
+
+	s, e1, c1 := endingToTxtSlice(c, "bad NINFO ZSData", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.ZSData = s
+	return rr, nil, c1
+}
+
+func setNS(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(NS)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad NS Ns", l}, ""
+	}
+	rr.Ns = name
+	return rr, nil, ""
+}
+
+func setNSAPPTR(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(NSAPPTR)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad NSAP-PTR Ptr", l}, ""
+	}
+	rr.Ptr = name
+	return rr, nil, ""
+}
+
+func setOPENPGPKEY(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(OPENPGPKEY)
+	rr.Hdr = h
+
+	s, e1, c1 := endingToString(c, "bad OPENPGPKEY PublicKey", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.PublicKey = s
+	return rr, nil, c1
+}
+
+func setPTR(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(PTR)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad PTR Ptr", l}, ""
+	}
+	rr.Ptr = name
+	return rr, nil, ""
+}
+
+func setPX(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(PX)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad PX Preference", l}, ""
+	}
+	rr.Preference = uint16(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad PX Map822", l}, ""
+	}
+	rr.Map822 = name
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk = toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad PX Mapx400", l}, ""
+	}
+	rr.Mapx400 = name
+	return rr, nil, ""
+}
+
+func setRP(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(RP)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad RP Mbox", l}, ""
+	}
+	rr.Mbox = name
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk = toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad RP Txt", l}, ""
+	}
+	rr.Txt = name
+	return rr, nil, ""
+}
+
+func setRT(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(RT)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad RT Preference", l}, ""
+	}
+	rr.Preference = uint16(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad RT Host", l}, ""
+	}
+	rr.Host = name
+	return rr, nil, ""
+}
+
+func setSMIMEA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(SMIMEA)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad SMIMEA Usage", l}, ""
+	}
+	rr.Usage = uint8(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad SMIMEA Selector", l}, ""
+	}
+	rr.Selector = uint8(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad SMIMEA MatchingType", l}, ""
+	}
+	rr.MatchingType = uint8(i)
+	s, e1, c1 := endingToString(c, "bad SMIMEA Certificate", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.Certificate = s
+	return rr, nil, c1
+}
+
+func setSPF(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(SPF)
+	rr.Hdr = h
+
+	s, e1, c1 := endingToTxtSlice(c, "bad SPF Txt", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.Txt = s
+	return rr, nil, c1
+}
+
+func setSRV(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(SRV)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad SRV Priority", l}, ""
+	}
+	rr.Priority = uint16(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	i, e = strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad SRV Weight", l}, ""
+	}
+	rr.Weight = uint16(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	i, e = strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad SRV Port", l}, ""
+	}
+	rr.Port = uint16(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad SRV Target", l}, ""
+	}
+	rr.Target = name
+	return rr, nil, ""
+}
+
+func setSSHFP(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(SSHFP)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad SSHFP Algorithm", l}, ""
+	}
+	rr.Algorithm = uint8(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad SSHFP Type", l}, ""
+	}
+	rr.Type = uint8(i)
+	s, e1, c1 := endingToString(c, "bad SSHFP Fingerprint", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.FingerPrint = s
+	return rr, nil, c1
+}
+
+func setTALINK(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(TALINK)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad TALINK PreviousName", l}, ""
+	}
+	rr.PreviousName = name
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	name, nameOk = toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad TALINK NextName", l}, ""
+	}
+	rr.NextName = name
+	return rr, nil, ""
+}
+
+func setTLSA(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(TLSA)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad TLSA Usage", l}, ""
+	}
+	rr.Usage = uint8(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad TLSA Selector", l}, ""
+	}
+	rr.Selector = uint8(i)
+
+	c.Next()        // zBlank
+	l, _ = c.Next() // zString
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad TLSA MatchingType", l}, ""
+	}
+	rr.MatchingType = uint8(i)
+	s, e1, c1 := endingToString(c, "bad TLSA Certificate", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.Certificate = s
+	return rr, nil, c1
+}
+
+func setTXT(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(TXT)
+	rr.Hdr = h
+
+	s, e1, c1 := endingToTxtSlice(c, "bad TXT Txt", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.Txt = s
+	return rr, nil, c1
+}
+
+func setUID(h RR_Header, c *zlexer, o, f string) (RR, *ParseError, string) {
+	rr := new(UID)
+	rr.Hdr = h
+
+	l, _ := c.Next()
+	if len(l.token) == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 32)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad UID Uid", l}, ""
+	}
+	rr.Uid = uint32(i)
+	return rr, nil, ""
+}
+
+var typeToGeneratedParserFunc = map[uint16]parserFunc{
+	TypeA:          {setA, false},
+	TypeAAAA:       {setAAAA, false},
+	TypeAFSDB:      {setAFSDB, false},
+	TypeAVC:        {setAVC, true},
+	TypeCNAME:      {setCNAME, false},
+	TypeDHCID:      {setDHCID, true},
+	TypeDNAME:      {setDNAME, false},
+	TypeEID:        {setEID, true},
+	TypeGID:        {setGID, false},
+	TypeKX:         {setKX, false},
+	TypeL32:        {setL32, false},
+	TypeLP:         {setLP, false},
+	TypeMB:         {setMB, false},
+	TypeMD:         {setMD, false},
+	TypeMF:         {setMF, false},
+	TypeMG:         {setMG, false},
+	TypeMINFO:      {setMINFO, false},
+	TypeMR:         {setMR, false},
+	TypeMX:         {setMX, false},
+	TypeNIMLOC:     {setNIMLOC, true},
+	TypeNINFO:      {setNINFO, true},
+	TypeNS:         {setNS, false},
+	TypeNSAPPTR:    {setNSAPPTR, false},
+	TypeOPENPGPKEY: {setOPENPGPKEY, true},
+	TypePTR:        {setPTR, false},
+	TypePX:         {setPX, false},
+	TypeRP:         {setRP, false},
+	TypeRT:         {setRT, false},
+	TypeSMIMEA:     {setSMIMEA, true},
+	TypeSPF:        {setSPF, true},
+	TypeSRV:        {setSRV, false},
+	TypeSSHFP:      {setSSHFP, true},
+	TypeTALINK:     {setTALINK, false},
+	TypeTLSA:       {setTLSA, true},
+	TypeTXT:        {setTXT, true},
+	TypeUID:        {setUID, false},
+}
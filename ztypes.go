@@ -2,16 +2,12 @@
 
 package dns
 
-import (
-	"encoding/base64"
-	"net"
-)
-
 // TypeToRR is a map of constructors for each RR type.
 var TypeToRR = map[uint16]func() RR{
 	TypeA:          func() RR { return new(A) },
 	TypeAAAA:       func() RR { return new(AAAA) },
 	TypeAFSDB:      func() RR { return new(AFSDB) },
+	TypeAMTRELAY:   func() RR { return new(AMTRELAY) },
 	TypeANY:        func() RR { return new(ANY) },
 	TypeAVC:        func() RR { return new(AVC) },
 	TypeCAA:        func() RR { return new(CAA) },
@@ -24,6 +20,7 @@ var TypeToRR = map[uint16]func() RR{
 	TypeDLV:        func() RR { return new(DLV) },
 	TypeDNAME:      func() RR { return new(DNAME) },
 	TypeDNSKEY:     func() RR { return new(DNSKEY) },
+	TypeDOA:        func() RR { return new(DOA) },
 	TypeDS:         func() RR { return new(DS) },
 	TypeEID:        func() RR { return new(EID) },
 	TypeEUI48:      func() RR { return new(EUI48) },
@@ -80,93 +77,10 @@ var TypeToRR = map[uint16]func() RR{
 	TypeX25:        func() RR { return new(X25) },
 }
 
-// TypeToString is a map of strings for each RR type.
-var TypeToString = map[uint16]string{
-	TypeA:          "A",
-	TypeAAAA:       "AAAA",
-	TypeAFSDB:      "AFSDB",
-	TypeANY:        "ANY",
-	TypeATMA:       "ATMA",
-	TypeAVC:        "AVC",
-	TypeAXFR:       "AXFR",
-	TypeCAA:        "CAA",
-	TypeCDNSKEY:    "CDNSKEY",
-	TypeCDS:        "CDS",
-	TypeCERT:       "CERT",
-	TypeCNAME:      "CNAME",
-	TypeCSYNC:      "CSYNC",
-	TypeDHCID:      "DHCID",
-	TypeDLV:        "DLV",
-	TypeDNAME:      "DNAME",
-	TypeDNSKEY:     "DNSKEY",
-	TypeDS:         "DS",
-	TypeEID:        "EID",
-	TypeEUI48:      "EUI48",
-	TypeEUI64:      "EUI64",
-	TypeGID:        "GID",
-	TypeGPOS:       "GPOS",
-	TypeHINFO:      "HINFO",
-	TypeHIP:        "HIP",
-	TypeISDN:       "ISDN",
-	TypeIXFR:       "IXFR",
-	TypeKEY:        "KEY",
-	TypeKX:         "KX",
-	TypeL32:        "L32",
-	TypeL64:        "L64",
-	TypeLOC:        "LOC",
-	TypeLP:         "LP",
-	TypeMAILA:      "MAILA",
-	TypeMAILB:      "MAILB",
-	TypeMB:         "MB",
-	TypeMD:         "MD",
-	TypeMF:         "MF",
-	TypeMG:         "MG",
-	TypeMINFO:      "MINFO",
-	TypeMR:         "MR",
-	TypeMX:         "MX",
-	TypeNAPTR:      "NAPTR",
-	TypeNID:        "NID",
-	TypeNIMLOC:     "NIMLOC",
-	TypeNINFO:      "NINFO",
-	TypeNS:         "NS",
-	TypeNSEC:       "NSEC",
-	TypeNSEC3:      "NSEC3",
-	TypeNSEC3PARAM: "NSEC3PARAM",
-	TypeNULL:       "NULL",
-	TypeNXT:        "NXT",
-	TypeNone:       "None",
-	TypeOPENPGPKEY: "OPENPGPKEY",
-	TypeOPT:        "OPT",
-	TypePTR:        "PTR",
-	TypePX:         "PX",
-	TypeRKEY:       "RKEY",
-	TypeRP:         "RP",
-	TypeRRSIG:      "RRSIG",
-	TypeRT:         "RT",
-	TypeReserved:   "Reserved",
-	TypeSIG:        "SIG",
-	TypeSMIMEA:     "SMIMEA",
-	TypeSOA:        "SOA",
-	TypeSPF:        "SPF",
-	TypeSRV:        "SRV",
-	TypeSSHFP:      "SSHFP",
-	TypeTA:         "TA",
-	TypeTALINK:     "TALINK",
-	TypeTKEY:       "TKEY",
-	TypeTLSA:       "TLSA",
-	TypeTSIG:       "TSIG",
-	TypeTXT:        "TXT",
-	TypeUID:        "UID",
-	TypeUINFO:      "UINFO",
-	TypeUNSPEC:     "UNSPEC",
-	TypeURI:        "URI",
-	TypeX25:        "X25",
-	TypeNSAPPTR:    "NSAP-PTR",
-}
-
 func (rr *A) Header() *RR_Header          { return &rr.Hdr }
 func (rr *AAAA) Header() *RR_Header       { return &rr.Hdr }
 func (rr *AFSDB) Header() *RR_Header      { return &rr.Hdr }
+func (rr *AMTRELAY) Header() *RR_Header   { return &rr.Hdr }
 func (rr *ANY) Header() *RR_Header        { return &rr.Hdr }
 func (rr *AVC) Header() *RR_Header        { return &rr.Hdr }
 func (rr *CAA) Header() *RR_Header        { return &rr.Hdr }
@@ -179,6 +93,7 @@ func (rr *DHCID) Header() *RR_Header      { return &rr.Hdr }
 func (rr *DLV) Header() *RR_Header        { return &rr.Hdr }
 func (rr *DNAME) Header() *RR_Header      { return &rr.Hdr }
 func (rr *DNSKEY) Header() *RR_Header     { return &rr.Hdr }
+func (rr *DOA) Header() *RR_Header        { return &rr.Hdr }
 func (rr *DS) Header() *RR_Header         { return &rr.Hdr }
 func (rr *EID) Header() *RR_Header        { return &rr.Hdr }
 func (rr *EUI48) Header() *RR_Header      { return &rr.Hdr }
@@ -235,416 +150,941 @@ func (rr *UINFO) Header() *RR_Header      { return &rr.Hdr }
 func (rr *URI) Header() *RR_Header        { return &rr.Hdr }
 func (rr *X25) Header() *RR_Header        { return &rr.Hdr }
 
-// len() functions
-func (rr *A) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += net.IPv4len // A
-	return l
-}
-func (rr *AAAA) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += net.IPv6len // AAAA
-	return l
-}
-func (rr *AFSDB) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Subtype
-	l += domainNameLen(rr.Hostname, off+l, compression, false)
-	return l
-}
-func (rr *ANY) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	return l
-}
-func (rr *AVC) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	for _, x := range rr.Txt {
-		l += len(x) + 1
-	}
-	return l
-}
-func (rr *CAA) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l++ // Flag
-	l += len(rr.Tag) + 1
-	l += len(rr.Value)
-	return l
-}
-func (rr *CERT) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Type
-	l += 2 // KeyTag
-	l++    // Algorithm
-	l += base64.StdEncoding.DecodedLen(len(rr.Certificate))
-	return l
-}
-func (rr *CNAME) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Target, off+l, compression, true)
-	return l
-}
-func (rr *DHCID) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += base64.StdEncoding.DecodedLen(len(rr.Digest))
-	return l
-}
-func (rr *DNAME) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Target, off+l, compression, false)
-	return l
-}
-func (rr *DNSKEY) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Flags
-	l++    // Protocol
-	l++    // Algorithm
-	l += base64.StdEncoding.DecodedLen(len(rr.PublicKey))
-	return l
-}
-func (rr *DS) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // KeyTag
-	l++    // Algorithm
-	l++    // DigestType
-	l += len(rr.Digest)/2 + 1
-	return l
-}
-func (rr *EID) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += len(rr.Endpoint)/2 + 1
-	return l
-}
-func (rr *EUI48) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 6 // Address
-	return l
-}
-func (rr *EUI64) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 8 // Address
-	return l
-}
-func (rr *GID) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 4 // Gid
-	return l
-}
-func (rr *GPOS) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += len(rr.Longitude) + 1
-	l += len(rr.Latitude) + 1
-	l += len(rr.Altitude) + 1
-	return l
-}
-func (rr *HINFO) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += len(rr.Cpu) + 1
-	l += len(rr.Os) + 1
-	return l
-}
-func (rr *HIP) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l++    // HitLength
-	l++    // PublicKeyAlgorithm
-	l += 2 // PublicKeyLength
-	l += len(rr.Hit) / 2
-	l += base64.StdEncoding.DecodedLen(len(rr.PublicKey))
-	for _, x := range rr.RendezvousServers {
-		l += domainNameLen(x, off+l, compression, false)
-	}
-	return l
-}
-func (rr *KX) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Preference
-	l += domainNameLen(rr.Exchanger, off+l, compression, false)
-	return l
-}
-func (rr *L32) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2           // Preference
-	l += net.IPv4len // Locator32
-	return l
-}
-func (rr *L64) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Preference
-	l += 8 // Locator64
-	return l
-}
-func (rr *LOC) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l++    // Version
-	l++    // Size
-	l++    // HorizPre
-	l++    // VertPre
-	l += 4 // Latitude
-	l += 4 // Longitude
-	l += 4 // Altitude
-	return l
-}
-func (rr *LP) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Preference
-	l += domainNameLen(rr.Fqdn, off+l, compression, false)
-	return l
-}
-func (rr *MB) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Mb, off+l, compression, true)
-	return l
-}
-func (rr *MD) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Md, off+l, compression, true)
-	return l
-}
-func (rr *MF) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Mf, off+l, compression, true)
-	return l
-}
-func (rr *MG) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Mg, off+l, compression, true)
-	return l
-}
-func (rr *MINFO) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Rmail, off+l, compression, true)
-	l += domainNameLen(rr.Email, off+l, compression, true)
-	return l
-}
-func (rr *MR) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Mr, off+l, compression, true)
-	return l
-}
-func (rr *MX) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Preference
-	l += domainNameLen(rr.Mx, off+l, compression, true)
-	return l
-}
-func (rr *NAPTR) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Order
-	l += 2 // Preference
-	l += len(rr.Flags) + 1
-	l += len(rr.Service) + 1
-	l += len(rr.Regexp) + 1
-	l += domainNameLen(rr.Replacement, off+l, compression, false)
-	return l
-}
-func (rr *NID) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Preference
-	l += 8 // NodeID
-	return l
-}
-func (rr *NIMLOC) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += len(rr.Locator)/2 + 1
-	return l
-}
-func (rr *NINFO) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	for _, x := range rr.ZSData {
-		l += len(x) + 1
-	}
-	return l
-}
-func (rr *NS) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Ns, off+l, compression, true)
-	return l
-}
-func (rr *NSAPPTR) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Ptr, off+l, compression, false)
-	return l
-}
-func (rr *NSEC3PARAM) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l++    // Hash
-	l++    // Flags
-	l += 2 // Iterations
-	l++    // SaltLength
-	l += len(rr.Salt) / 2
-	return l
-}
-func (rr *OPENPGPKEY) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += base64.StdEncoding.DecodedLen(len(rr.PublicKey))
-	return l
-}
-func (rr *PTR) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Ptr, off+l, compression, true)
-	return l
-}
-func (rr *PX) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Preference
-	l += domainNameLen(rr.Map822, off+l, compression, false)
-	l += domainNameLen(rr.Mapx400, off+l, compression, false)
-	return l
-}
-func (rr *RFC3597) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += len(rr.Rdata)/2 + 1
-	return l
-}
-func (rr *RKEY) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Flags
-	l++    // Protocol
-	l++    // Algorithm
-	l += base64.StdEncoding.DecodedLen(len(rr.PublicKey))
-	return l
-}
-func (rr *RP) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Mbox, off+l, compression, false)
-	l += domainNameLen(rr.Txt, off+l, compression, false)
-	return l
-}
-func (rr *RRSIG) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // TypeCovered
-	l++    // Algorithm
-	l++    // Labels
-	l += 4 // OrigTtl
-	l += 4 // Expiration
-	l += 4 // Inception
-	l += 2 // KeyTag
-	l += domainNameLen(rr.SignerName, off+l, compression, false)
-	l += base64.StdEncoding.DecodedLen(len(rr.Signature))
-	return l
-}
-func (rr *RT) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Preference
-	l += domainNameLen(rr.Host, off+l, compression, false)
-	return l
-}
-func (rr *SMIMEA) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l++ // Usage
-	l++ // Selector
-	l++ // MatchingType
-	l += len(rr.Certificate)/2 + 1
-	return l
-}
-func (rr *SOA) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Ns, off+l, compression, true)
-	l += domainNameLen(rr.Mbox, off+l, compression, true)
-	l += 4 // Serial
-	l += 4 // Refresh
-	l += 4 // Retry
-	l += 4 // Expire
-	l += 4 // Minttl
-	return l
-}
-func (rr *SPF) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	for _, x := range rr.Txt {
-		l += len(x) + 1
-	}
-	return l
-}
-func (rr *SRV) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Priority
-	l += 2 // Weight
-	l += 2 // Port
-	l += domainNameLen(rr.Target, off+l, compression, false)
-	return l
-}
-func (rr *SSHFP) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l++ // Algorithm
-	l++ // Type
-	l += len(rr.FingerPrint)/2 + 1
-	return l
-}
-func (rr *TA) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // KeyTag
-	l++    // Algorithm
-	l++    // DigestType
-	l += len(rr.Digest)/2 + 1
-	return l
-}
-func (rr *TALINK) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.PreviousName, off+l, compression, false)
-	l += domainNameLen(rr.NextName, off+l, compression, false)
-	return l
-}
-func (rr *TKEY) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Algorithm, off+l, compression, false)
-	l += 4 // Inception
-	l += 4 // Expiration
-	l += 2 // Mode
-	l += 2 // Error
-	l += 2 // KeySize
-	l += len(rr.Key) / 2
-	l += 2 // OtherLen
-	l += len(rr.OtherData) / 2
-	return l
-}
-func (rr *TLSA) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l++ // Usage
-	l++ // Selector
-	l++ // MatchingType
-	l += len(rr.Certificate)/2 + 1
-	return l
-}
-func (rr *TSIG) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += domainNameLen(rr.Algorithm, off+l, compression, false)
-	l += 6 // TimeSigned
-	l += 2 // Fudge
-	l += 2 // MACSize
-	l += len(rr.MAC) / 2
-	l += 2 // OrigId
-	l += 2 // Error
-	l += 2 // OtherLen
-	l += len(rr.OtherData) / 2
-	return l
-}
-func (rr *TXT) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	for _, x := range rr.Txt {
-		l += len(x) + 1
-	}
-	return l
-}
-func (rr *UID) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 4 // Uid
-	return l
-}
-func (rr *UINFO) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += len(rr.Uinfo) + 1
-	return l
-}
-func (rr *URI) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += 2 // Priority
-	l += 2 // Weight
-	l += len(rr.Target)
-	return l
-}
-func (rr *X25) len(off int, compression map[string]struct{}) int {
-	l := rr.Hdr.len(off, compression)
-	l += len(rr.PSDNAddress) + 1
-	return l
+func (rr *A) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *A) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*A)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *AAAA) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *AAAA) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*AAAA)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *AFSDB) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *AFSDB) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*AFSDB)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *AMTRELAY) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *AMTRELAY) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*AMTRELAY)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *ANY) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *ANY) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*ANY)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *AVC) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *AVC) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*AVC)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *CAA) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *CAA) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*CAA)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *CDNSKEY) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *CDNSKEY) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*CDNSKEY)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *CDS) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *CDS) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*CDS)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *CERT) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *CERT) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*CERT)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *CNAME) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *CNAME) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*CNAME)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *CSYNC) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *CSYNC) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*CSYNC)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *DHCID) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *DHCID) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*DHCID)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *DLV) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *DLV) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*DLV)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *DNAME) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *DNAME) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*DNAME)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *DNSKEY) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *DNSKEY) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*DNSKEY)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *DOA) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *DOA) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*DOA)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *DS) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *DS) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*DS)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *EID) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *EID) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*EID)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *EUI48) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *EUI48) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*EUI48)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *EUI64) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *EUI64) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*EUI64)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *GID) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *GID) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*GID)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *GPOS) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *GPOS) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*GPOS)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *HINFO) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *HINFO) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*HINFO)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *HIP) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *HIP) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*HIP)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *KEY) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *KEY) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*KEY)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *KX) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *KX) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*KX)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *L32) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *L32) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*L32)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *L64) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *L64) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*L64)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *LOC) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *LOC) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*LOC)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *LP) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *LP) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*LP)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *MB) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *MB) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*MB)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *MD) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *MD) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*MD)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *MF) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *MF) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*MF)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *MG) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *MG) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*MG)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *MINFO) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *MINFO) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*MINFO)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *MR) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *MR) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*MR)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *MX) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *MX) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*MX)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *NAPTR) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *NAPTR) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*NAPTR)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *NID) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *NID) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*NID)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *NIMLOC) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *NIMLOC) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*NIMLOC)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *NINFO) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *NINFO) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*NINFO)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *NS) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *NS) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*NS)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *NSAPPTR) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *NSAPPTR) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*NSAPPTR)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *NSEC) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *NSEC) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*NSEC)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *NSEC3) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *NSEC3) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*NSEC3)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *NSEC3PARAM) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *NSEC3PARAM) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*NSEC3PARAM)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *OPENPGPKEY) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *OPENPGPKEY) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*OPENPGPKEY)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *OPT) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *OPT) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*OPT)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *PTR) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *PTR) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*PTR)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *PX) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *PX) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*PX)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *RFC3597) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *RFC3597) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*RFC3597)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *RKEY) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *RKEY) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*RKEY)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *RP) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *RP) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*RP)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *RRSIG) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *RRSIG) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*RRSIG)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *RT) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *RT) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*RT)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *SIG) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *SIG) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*SIG)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *SMIMEA) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *SMIMEA) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*SMIMEA)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *SOA) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *SOA) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*SOA)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *SPF) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *SPF) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*SPF)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *SRV) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *SRV) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*SRV)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *SSHFP) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *SSHFP) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*SSHFP)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *TA) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *TA) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*TA)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *TALINK) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *TALINK) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*TALINK)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *TKEY) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *TKEY) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*TKEY)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *TLSA) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *TLSA) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*TLSA)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *TSIG) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *TSIG) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*TSIG)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *TXT) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *TXT) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*TXT)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *UID) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *UID) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*UID)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *UINFO) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *UINFO) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*UINFO)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *URI) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *URI) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*URI)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
+}
+func (rr *X25) MarshalJSON() ([]byte, error) { return marshalRRJSON(rr) }
+func (rr *X25) UnmarshalJSON(b []byte) error {
+	r, err := unmarshalRRJSON(b)
+	if err != nil {
+		return err
+	}
+	r1, ok := r.(*X25)
+	if !ok {
+		return &Error{err: "JSON type mismatch"}
+	}
+	*rr = *r1
+	return nil
 }
 
 // copy() functions
@@ -657,6 +1097,9 @@ func (rr *AAAA) copy() RR {
 func (rr *AFSDB) copy() RR {
 	return &AFSDB{rr.Hdr, rr.Subtype, rr.Hostname}
 }
+func (rr *AMTRELAY) copy() RR {
+	return &AMTRELAY{rr.Hdr, rr.Precedence, rr.GatewayType, copyIP(rr.GatewayAddr), rr.GatewayHost}
+}
 func (rr *ANY) copy() RR {
 	return &ANY{rr.Hdr}
 }
@@ -688,6 +1131,9 @@ func (rr *DNAME) copy() RR {
 func (rr *DNSKEY) copy() RR {
 	return &DNSKEY{rr.Hdr, rr.Flags, rr.Protocol, rr.Algorithm, rr.PublicKey}
 }
+func (rr *DOA) copy() RR {
+	return &DOA{rr.Hdr, rr.Enterprise, rr.Type, rr.Location, rr.MediaType, rr.Data}
+}
 func (rr *DS) copy() RR {
 	return &DS{rr.Hdr, rr.KeyTag, rr.Algorithm, rr.DigestType, rr.Digest}
 }
@@ -788,7 +1234,9 @@ func (rr *OPENPGPKEY) copy() RR {
 }
 func (rr *OPT) copy() RR {
 	Option := make([]EDNS0, len(rr.Option))
-	copy(Option, rr.Option)
+	for i, e := range rr.Option {
+		Option[i] = e.copy()
+	}
 	return &OPT{rr.Hdr, Option}
 }
 func (rr *PTR) copy() RR {